@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/raaihank/llm-sentinel/internal/artifact"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/etl"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+)
+
+// exportFilters selects which stored vectors an export includes
+type exportFilters struct {
+	label         string // label_text
+	embeddingType string
+	since, until  string // RFC 3339 timestamps or dates
+}
+
+// pageOptions converts the filters to a vector query
+func (f exportFilters) pageOptions() (vector.PageOptions, error) {
+	options := vector.PageOptions{
+		PageSize:            1000,
+		LabelTextFilter:     f.label,
+		EmbeddingTypeFilter: f.embeddingType,
+	}
+	var err error
+	if options.CreatedAfter, err = parseExportTime("since", f.since); err != nil {
+		return options, err
+	}
+	if options.CreatedBefore, err = parseExportTime("until", f.until); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// parseExportTime parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseExportTime(flagName, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid --%s: %q (use RFC 3339 or YYYY-MM-DD)", flagName, value)
+}
+
+// exportDataset dumps matching vectors, with embeddings, to outputFile in
+// the format its extension names. Exports are encrypted when artifact
+// encryption is enabled.
+func exportDataset(ctx context.Context, cfg *config.Config, outputFile string, filters exportFilters, log *logger.Logger) error {
+	options, err := filters.pageOptions()
+	if err != nil {
+		return err
+	}
+
+	vectorStore, err := vector.OpenFromConfig(cfg.Security.VectorSecurity, log.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+	defer vectorStore.Close()
+
+	var encrypter *artifact.Encrypter
+	if cfg.Artifacts.Encryption.Enabled {
+		if encrypter, err = artifact.NewEncrypter(cfg.Artifacts.Encryption.Recipients); err != nil {
+			return err
+		}
+	}
+	format := etl.DetectFileFormat(outputFile)
+	outputFile = encrypter.Path(outputFile)
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	file, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	out, err := encrypter.Wrap(file)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt export: %w", err)
+	}
+
+	log.Info("Exporting vectors",
+		zap.String("file", outputFile),
+		zap.String("format", string(format)),
+		zap.String("label", filters.label),
+		zap.String("embedding_type", filters.embeddingType),
+		zap.String("since", filters.since),
+		zap.String("until", filters.until))
+
+	start := time.Now()
+	exported, err := etl.Export(ctx, vectorStore, out, format, options)
+	if err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt export: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	log.Info("Export completed",
+		zap.String("file", outputFile),
+		zap.Int64("vectors", exported),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
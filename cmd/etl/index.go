@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+)
+
+// indexUsage describes the index subcommand
+const indexUsage = `Usage: %s index [options] <show|build|rebuild|drop>
+
+  show     List the indexes on security_vectors with size and scan counts
+  build    Create the configured embedding index if none exists
+  rebuild  Replace the embedding index with the configured type and parameters
+  drop     Drop the embedding index (searches fall back to exact scans)
+
+Options:
+`
+
+// runIndex implements `llm-sentinel-etl index`, managing the embedding index
+// as configured under security.vector_security.index. It returns the exit code.
+func runIndex(args []string) int {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	configPath := flags.String("config", "configs/default.yaml", "Configuration file path")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, indexUsage, os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	action := "show"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		action = flags.Arg(0)
+	default:
+		flags.Usage()
+		return 1
+	}
+	if action != "show" && action != "build" && action != "rebuild" && action != "drop" {
+		flags.Usage()
+		return 1
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	if action != "show" && cfg.Server.ReadOnly {
+		fmt.Fprintln(os.Stderr, "Read-only mode is enabled; disable server.read_only to change indexes")
+		return 1
+	}
+
+	log, err := logger.New(logger.Config{Level: cfg.Logging.Level, Format: cfg.Logging.Format})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+
+	store, err := vector.OpenFromConfig(cfg.Security.VectorSecurity, log.Logger)
+	if err != nil {
+		log.Error("Failed to initialize vector store", zap.Error(err))
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	index := cfg.Security.VectorSecurity.Index
+	switch action {
+	case "build":
+		err = store.CreateIndex(ctx)
+	case "rebuild":
+		err = store.ReindexVectors(ctx)
+	case "drop":
+		err = store.DropIndex(ctx)
+	}
+	if err != nil {
+		log.Error("Index "+action+" failed", zap.Error(err))
+		return 1
+	}
+
+	indexes, err := store.Indexes(ctx)
+	if err != nil {
+		log.Error("Failed to list indexes", zap.Error(err))
+		return 1
+	}
+	printIndexes(index, indexes)
+	return 0
+}
+
+func printIndexes(configured config.IndexConfig, indexes []vector.IndexInfo) {
+	fmt.Printf("\n=== security_vectors indexes ===\n")
+	switch configured.Type {
+	case "hnsw":
+		fmt.Printf("Configured: hnsw (m=%d, ef_construction=%d, ef_search=%s)\n\n",
+			configured.M, configured.EfConstruction, settingOrDefault(configured.EfSearch))
+	default:
+		lists := "auto"
+		if configured.Lists > 0 {
+			lists = fmt.Sprint(configured.Lists)
+		}
+		fmt.Printf("Configured: ivfflat (lists=%s, probes=%s)\n\n", lists, settingOrDefault(configured.Probes))
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tMETHOD\tSIZE\tVALID\tSCANS")
+	for _, index := range indexes {
+		fmt.Fprintf(table, "%s\t%s\t%.1f MB\t%t\t%d\n", index.Name, index.Method, float64(index.SizeBytes)/(1<<20), index.Valid, index.Scans)
+	}
+	table.Flush()
+
+	for _, index := range indexes {
+		fmt.Printf("\n%s:\n  %s\n", index.Name, index.Definition)
+	}
+}
+
+// settingOrDefault renders a search parameter where 0 keeps the server default
+func settingOrDefault(value int) string {
+	if value == 0 {
+		return "server default"
+	}
+	return fmt.Sprint(value)
+}
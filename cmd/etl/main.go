@@ -6,23 +6,33 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/raaihank/llm-sentinel/internal/artifact"
 	"github.com/raaihank/llm-sentinel/internal/cache"
 	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/egress"
 	"github.com/raaihank/llm-sentinel/internal/embeddings"
 	"github.com/raaihank/llm-sentinel/internal/etl"
 	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 	"github.com/raaihank/llm-sentinel/internal/vector"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "index" {
+		os.Exit(runIndex(os.Args[2:]))
+	}
+
 	var (
 		configPath   = flag.String("config", "configs/default.yaml", "Configuration file path")
-		inputFile    = flag.String("input", "", "Input dataset file (CSV, Parquet, or JSON)")
+		inputFile    = flag.String("input", "", "Input dataset file (CSV, Parquet, or JSON) or HuggingFace dataset (hf://owner/name)")
+		nearDups     = flag.String("near-duplicates", "", "Drop near-duplicate records: simhash or embedding (default etl.near_duplicates.method)")
+		schemaSpec   = flag.String("schema", "", "Dataset column mapping over etl.schema, as field=column pairs (e.g. text=prompt,label=is_attack,metadata=category); columns are names or 0-based CSV positions")
 		batchSize    = flag.Int("batch-size", 1000, "Batch size for processing")
 		workers      = flag.Int("workers", 4, "Number of worker goroutines")
 		skipCache    = flag.Bool("skip-cache", false, "Skip updating Redis cache")
@@ -30,19 +40,39 @@ func main() {
 		validateOnly = flag.Bool("validate-only", false, "Only validate data, don't process")
 		dryRun       = flag.Bool("dry-run", false, "Dry run - don't write to database")
 		rebuildCache = flag.Bool("rebuild-cache", false, "Rebuild Redis cache from database")
+		cacheLabel   = flag.String("label", "", "With --rebuild-cache or --export, only include vectors with this label_text")
+		exportFile   = flag.String("export", "", "Export stored vectors with embeddings to this file (CSV, Parquet, or JSON lines by extension)")
+		embedType    = flag.String("embedding-type", "", "With --export, only include vectors with this embedding_type")
+		since        = flag.String("since", "", "With --export, only include vectors created at or after this time (RFC 3339 or YYYY-MM-DD)")
+		until        = flag.String("until", "", "With --export, only include vectors created before this time (RFC 3339 or YYYY-MM-DD)")
 		showStats    = flag.Bool("stats", false, "Show database statistics and exit")
+		rejectsFile  = flag.String("rejects", "", "File for records that cannot be ingested (default etl.rejects_file)")
+		resume       = flag.Bool("resume", false, "Resume an interrupted run from its checkpoint, skipping records already ingested")
+		checkpoint   = flag.String("checkpoint", "", "File recording ingestion progress (default etl.checkpoint_file)")
+		fitPCA       = flag.Bool("fit-pca", false, "Fit a PCA projection on a sample of the input and save it to reduction.pca_path")
+		evalReduce   = flag.Bool("evaluate-reduction", false, "Report the accuracy cost of the configured dimension reduction on a sample of the input")
+		sampleSize   = flag.Int("sample", 2000, "Records to sample for --fit-pca and --evaluate-reduction")
 	)
 	flag.Parse()
 
-	if *inputFile == "" && !*rebuildCache && !*showStats {
+	if *inputFile == "" && *exportFile == "" && !*rebuildCache && !*showStats {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s --input dataset.csv --batch-size 500\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --input dataset.parquet --workers 8\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --input dataset.parquet --resume\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --input noisy.csv --near-duplicates simhash\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --input attacks.tsv --schema text=prompt,label=is_attack,metadata=category\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --input 'hf://deepset/prompt-injections?split=test'\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --rebuild-cache\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --rebuild-cache --label jailbreak\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --stats\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --export corpus.parquet --label jailbreak --since 2025-01-01\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --input dataset.csv --fit-pca --sample 5000\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --input dataset.csv --evaluate-reduction\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s index rebuild\n", os.Args[0])
 		os.Exit(1)
 	}
 
@@ -52,6 +82,18 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
+	schema, err := datasetSchema(cfg, *schemaSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --schema: %v\n", err)
+		os.Exit(1)
+	}
+	if *nearDups != "" {
+		if *nearDups != etl.NearDuplicateSimHash && *nearDups != etl.NearDuplicateEmbedding {
+			fmt.Fprintf(os.Stderr, "Invalid --near-duplicates: %q (must be simhash or embedding)\n", *nearDups)
+			os.Exit(1)
+		}
+		cfg.ETL.NearDuplicates.Method = *nearDups
+	}
 
 	// Initialize logger
 	log, err := logger.New(logger.Config{
@@ -71,6 +113,7 @@ func main() {
 	log.Info("Starting LLM-Sentinel ETL Pipeline",
 		zap.String("version", "0.1.0"),
 		zap.String("config", *configPath))
+	log.Info("Effective configuration", zap.Any("config", config.Summary(cfg)))
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -85,6 +128,40 @@ func main() {
 		cancel()
 	}()
 
+	// Reduction tooling only needs embeddings; the store may not open until
+	// the PCA projection exists
+	if *fitPCA || *evalReduce {
+		embeddingService, err := initializeEmbeddingService(cfg, log)
+		if err != nil {
+			log.Fatal("Failed to initialize embedding service", zap.Error(err))
+		}
+		defer embeddingService.Close()
+
+		huggingFace, err := huggingFaceOptions(cfg)
+		if err != nil {
+			log.Fatal("Failed to configure HuggingFace datasets", zap.Error(err))
+		}
+		pipeline := etl.NewPipeline(nil, embeddingService, nil, &etl.Config{BatchSize: *batchSize, Schema: schema, HuggingFace: huggingFace}, log.Logger)
+		if *fitPCA {
+			err = fitReduction(ctx, pipeline, cfg.Security.VectorSecurity.Reduction, *inputFile, *sampleSize, log)
+		} else {
+			err = evaluateReduction(ctx, pipeline, cfg.Security.VectorSecurity.Reduction, *inputFile, *sampleSize, log)
+		}
+		if err != nil {
+			log.Fatal("Dimension reduction failed", zap.Error(err))
+		}
+		return
+	}
+
+	// Exports only read the store
+	if *exportFile != "" {
+		filters := exportFilters{label: *cacheLabel, embeddingType: *embedType, since: *since, until: *until}
+		if err := exportDataset(ctx, cfg, *exportFile, filters, log); err != nil {
+			log.Fatal("Export failed", zap.Error(err))
+		}
+		return
+	}
+
 	// Initialize services
 	services, err := initializeServices(cfg, log)
 	if err != nil {
@@ -99,10 +176,20 @@ func main() {
 			log.Fatal("Failed to show stats", zap.Error(err))
 		}
 	case *rebuildCache:
-		if err := rebuildCacheFromDB(ctx, services, log); err != nil {
+		if err := rebuildCacheFromDB(ctx, services, *cacheLabel, log); err != nil {
 			log.Fatal("Failed to rebuild cache", zap.Error(err))
 		}
 	default:
+		// Corpus writes are refused while the deployment is read-only
+		if cfg.Server.ReadOnly && !*validateOnly && !*dryRun {
+			log.Fatal("Read-only mode is enabled; use --validate-only or --dry-run, or disable server.read_only")
+		}
+
+		huggingFace, err := huggingFaceOptions(cfg)
+		if err != nil {
+			log.Fatal("Failed to configure HuggingFace datasets", zap.Error(err))
+		}
+
 		// Process input file
 		etlConfig := &etl.Config{
 			BatchSize:      *batchSize,
@@ -114,9 +201,34 @@ func main() {
 			CreateIndex:    !*skipIndex,
 			UpdateCache:    !*skipCache,
 			ProgressReport: 1000,
+
+			LabelMapping:         cfg.ETL.Labels.Mapping,
+			RejectUnmappedLabels: cfg.ETL.Labels.RejectUnmapped,
+			TextHash:             textnorm.Options(cfg.Security.VectorSecurity.Normalization.Dedup),
+			CopyThreshold:        cfg.ETL.CopyThreshold,
+			Schema:               schema,
+			NearDuplicates: etl.NearDuplicateOptions{
+				Method:        cfg.ETL.NearDuplicates.Method,
+				MaxDistance:   cfg.ETL.NearDuplicates.SimHashDistance,
+				MinSimilarity: cfg.ETL.NearDuplicates.Similarity,
+			},
+			HuggingFace: huggingFace,
+		}
+
+		if *rejectsFile == "" {
+			*rejectsFile = cfg.ETL.RejectsFile
+		}
+		if *checkpoint == "" {
+			*checkpoint = cfg.ETL.CheckpointFile
+		}
+		if *resume && *checkpoint == "" {
+			log.Fatal("--resume needs a checkpoint file; set etl.checkpoint_file or --checkpoint")
+		}
+		if *validateOnly || *dryRun {
+			*checkpoint = ""
 		}
 
-		if err := processDataset(ctx, services, etlConfig, *inputFile, *validateOnly, *dryRun, log); err != nil {
+		if err := processDataset(ctx, services, etlConfig, cfg.Artifacts.Encryption, *inputFile, *rejectsFile, *checkpoint, *resume, *validateOnly, *dryRun, log); err != nil {
 			log.Fatal("ETL processing failed", zap.Error(err))
 		}
 	}
@@ -126,9 +238,10 @@ func main() {
 
 // services holds all initialized services
 type services struct {
-	vectorStore      *vector.Store
+	vectorStore      vector.Backend
 	embeddingService embeddings.EmbeddingService
 	vectorCache      *cache.VectorCache
+	model            string // embedding model recorded with the corpus canaries
 }
 
 func (s *services) cleanup() {
@@ -149,34 +262,97 @@ func initializeServices(cfg *config.Config, log *logger.Logger) (*services, erro
 
 	// Initialize vector store
 	log.Info("Initializing vector store...")
-	vectorStore, err := vector.NewStore(&vector.Config{
-		DatabaseURL:     cfg.Security.VectorSecurity.Database.DatabaseURL,
-		MaxOpenConns:    cfg.Security.VectorSecurity.Database.MaxOpenConns,
-		MaxIdleConns:    cfg.Security.VectorSecurity.Database.MaxIdleConns,
-		ConnMaxLifetime: cfg.Security.VectorSecurity.Database.ConnMaxLifetime,
-		ConnMaxIdleTime: cfg.Security.VectorSecurity.Database.ConnMaxIdleTime,
-	}, log.Logger)
+	vectorStore, err := vector.OpenFromConfig(cfg.Security.VectorSecurity, log.Logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize vector store: %w", err)
 	}
 	services.vectorStore = vectorStore
 
-	// Initialize embedding service using factory
+	embeddingService, err := initializeEmbeddingService(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	services.embeddingService = embeddingService
+	services.model = embeddings.CorpusModel(cfg.Security.VectorSecurity.Embedding.ServiceType, cfg.Security.VectorSecurity.Embedding.Model.ModelName)
+
+	// Embedding lookups are cached by the embedding service itself; this
+	// cache holds known malicious vectors for the ETL to populate
+	if cfg.Security.VectorSecurity.Embedding.RedisEnabled {
+		verdictCache := cfg.Security.VectorSecurity.VerdictCache
+		vectorCache, err := cache.NewVectorCache(&cache.Config{
+			RedisURL:       cfg.Security.VectorSecurity.Embedding.RedisURL,
+			MaxConnections: 10,
+			MinIdleConns:   1,
+			DefaultTTL:     time.Hour,
+			KeyPrefix:      "llm-sentinel:vectors",
+			Lookup:         verdictCache.Lookup,
+			MinSimilarity:  verdictCache.MinSimilarity,
+			LSHTables:      verdictCache.LSHTables,
+			LSHBits:        verdictCache.LSHBits,
+			Dimensions:     verdictCache.Dimensions,
+			LocalSize:      verdictCache.LocalSize,
+			LocalTTL:       verdictCache.LocalTTL,
+			TTLTiers:       ttlTiers(verdictCache.TTLTiers),
+		}, log.Logger)
+		if err != nil {
+			log.Warn("Vector cache unavailable; continuing without cache updates", zap.Error(err))
+		} else {
+			services.vectorCache = vectorCache
+		}
+	}
+
+	return services, nil
+}
+
+// ttlTiers converts configured verdict TTL tiers for the cache. None keeps
+// the cache's defaults.
+func ttlTiers(configured []config.VerdictTTLTier) []cache.TTLTier {
+	if len(configured) == 0 {
+		return nil
+	}
+	tiers := make([]cache.TTLTier, len(configured))
+	for i, tier := range configured {
+		tiers[i] = cache.TTLTier{
+			Name:          tier.Name,
+			Label:         tier.Label,
+			MinConfidence: tier.MinConfidence,
+			TTL:           tier.TTL,
+		}
+	}
+	return tiers
+}
+
+// initializeEmbeddingService creates the configured embedding service
+func initializeEmbeddingService(cfg *config.Config, log *logger.Logger) (embeddings.EmbeddingService, error) {
 	log.Info("Initializing embedding service...")
 	factory := embeddings.NewFactory(log.Logger)
 
+	downloadProxy, err := egress.ProxyFunc(cfg.Upstream.Egress, config.EgressDownloadRoute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure egress proxy for model downloads: %w", err)
+	}
+
 	serviceConfig := embeddings.ServiceConfig{
 		Type: embeddings.ServiceType(cfg.Security.VectorSecurity.Embedding.ServiceType),
 		ModelConfig: embeddings.ModelConfig{
-			ModelName:     cfg.Security.VectorSecurity.Embedding.Model.ModelName,
-			ModelPath:     cfg.Security.VectorSecurity.Embedding.Model.ModelPath,
-			TokenizerPath: cfg.Security.VectorSecurity.Embedding.Model.TokenizerPath,
-			VocabPath:     cfg.Security.VectorSecurity.Embedding.Model.VocabPath,
-			CacheDir:      cfg.Security.VectorSecurity.Embedding.Model.CacheDir,
-			AutoDownload:  cfg.Security.VectorSecurity.Embedding.Model.AutoDownload,
-			MaxLength:     cfg.Security.VectorSecurity.Embedding.Model.MaxLength,
-			BatchSize:     cfg.Security.VectorSecurity.Embedding.Model.BatchSize,
-			ModelTimeout:  cfg.Security.VectorSecurity.Embedding.Model.ModelTimeout,
+			ModelName:       cfg.Security.VectorSecurity.Embedding.Model.ModelName,
+			ModelPath:       cfg.Security.VectorSecurity.Embedding.Model.ModelPath,
+			TokenizerPath:   cfg.Security.VectorSecurity.Embedding.Model.TokenizerPath,
+			VocabPath:       cfg.Security.VectorSecurity.Embedding.Model.VocabPath,
+			CacheDir:        cfg.Security.VectorSecurity.Embedding.Model.CacheDir,
+			AutoDownload:    cfg.Security.VectorSecurity.Embedding.Model.AutoDownload,
+			MaxLength:       cfg.Security.VectorSecurity.Embedding.Model.MaxLength,
+			BatchSize:       cfg.Security.VectorSecurity.Embedding.Model.BatchSize,
+			ModelTimeout:    cfg.Security.VectorSecurity.Embedding.Model.ModelTimeout,
+			MemoSize:        cfg.Security.VectorSecurity.Embedding.Model.MemoSize,
+			LocalCacheSize:  cfg.Security.VectorSecurity.Embedding.Model.LocalCacheSize,
+			LocalCacheTTL:   cfg.Security.VectorSecurity.Embedding.Model.LocalCacheTTL,
+			Revision:        cfg.Security.VectorSecurity.Embedding.Model.Revision,
+			ModelSHA256:     cfg.Security.VectorSecurity.Embedding.Model.ModelSHA256,
+			TokenizerSHA256: cfg.Security.VectorSecurity.Embedding.Model.TokenizerSHA256,
+			VocabSHA256:     cfg.Security.VectorSecurity.Embedding.Model.VocabSHA256,
+			CacheKey:        textnorm.Options(cfg.Security.VectorSecurity.Normalization.Cache),
+			Proxy:           downloadProxy,
 		},
 		RedisEnabled: cfg.Security.VectorSecurity.Embedding.RedisEnabled,
 		RedisURL:     cfg.Security.VectorSecurity.Embedding.RedisURL,
@@ -186,23 +362,60 @@ func initializeServices(cfg *config.Config, log *logger.Logger) (*services, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize embedding service: %w", err)
 	}
-	services.embeddingService = embeddingService
 	log.Info("Using embedding service type", zap.String("type", cfg.Security.VectorSecurity.Embedding.ServiceType))
 
-	// Vector caching is now handled by the embedding service itself
+	return embeddingService, nil
+}
 
-	return services, nil
+// huggingFaceOptions configures hf:// inputs from etl.huggingface. Dataset
+// downloads use the same egress route as model downloads.
+func huggingFaceOptions(cfg *config.Config) (etl.HuggingFaceOptions, error) {
+	proxy, err := egress.ProxyFunc(cfg.Upstream.Egress, config.EgressDownloadRoute)
+	if err != nil {
+		return etl.HuggingFaceOptions{}, fmt.Errorf("failed to configure egress proxy for dataset downloads: %w", err)
+	}
+
+	hf := cfg.ETL.HuggingFace
+	var token string
+	if hf.TokenEnv != "" {
+		token = os.Getenv(hf.TokenEnv)
+	}
+	return etl.HuggingFaceOptions{
+		Endpoint: hf.Endpoint,
+		Token:    token,
+		Config:   hf.Config,
+		Split:    hf.Split,
+		CacheDir: hf.CacheDir,
+		Proxy:    proxy,
+	}, nil
+}
+
+// datasetSchema applies the --schema spec over etl.schema
+func datasetSchema(cfg *config.Config, spec string) (etl.Schema, error) {
+	s := cfg.ETL.Schema
+	return etl.ParseSchema(spec, etl.Schema{
+		Text:           s.Text,
+		LabelText:      s.LabelText,
+		Label:          s.Label,
+		Source:         s.Source,
+		Language:       s.Language,
+		Metadata:       s.Metadata,
+		NoHeader:       !s.Header,
+		MaliciousLabel: s.MaliciousLabel,
+		SafeLabel:      s.SafeLabel,
+	})
 }
 
 // processDataset processes the input dataset file
-func processDataset(ctx context.Context, services *services, etlConfig *etl.Config, inputFile string, validateOnly, dryRun bool, log *logger.Logger) error {
+func processDataset(ctx context.Context, services *services, etlConfig *etl.Config, encryption config.ArtifactEncryptionConfig, inputFile, rejectsFile, checkpointFile string, resume, validateOnly, dryRun bool, log *logger.Logger) error {
 	log.Info("Processing dataset",
 		zap.String("file", inputFile),
+		zap.Bool("resume", resume),
 		zap.Bool("validate_only", validateOnly),
 		zap.Bool("dry_run", dryRun))
 
-	// Check if file exists
-	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
+	// Check if file exists; hf:// datasets are looked up when read
+	if _, err := os.Stat(inputFile); os.IsNotExist(err) && !etl.IsHuggingFaceURI(inputFile) {
 		return fmt.Errorf("input file does not exist: %s", inputFile)
 	}
 
@@ -215,9 +428,43 @@ func processDataset(ctx context.Context, services *services, etlConfig *etl.Conf
 		log.Logger,
 	)
 
+	// Encrypted inputs are decrypted with the configured identity
+	decrypter, err := artifact.NewDecrypter(encryption.IdentityFile)
+	if err != nil {
+		return err
+	}
+	pipeline.SetDecrypter(decrypter)
+
+	if rejectsFile != "" {
+		var encrypter *artifact.Encrypter
+		if encryption.Enabled {
+			if encrypter, err = artifact.NewEncrypter(encryption.Recipients); err != nil {
+				return err
+			}
+		}
+		rejects, err := etl.NewRejectWriter(rejectsFile, encrypter)
+		if err != nil {
+			return err
+		}
+		defer rejects.Close()
+		pipeline.SetRejectWriter(rejects)
+		rejectsFile = rejects.Path()
+	}
+
+	var checkpoints *etl.Checkpoints
+	if checkpointFile != "" {
+		if checkpoints, err = etl.OpenCheckpoints(checkpointFile); err != nil {
+			return err
+		}
+		pipeline.SetCheckpoints(checkpoints, resume)
+	}
+
 	var result *etl.ProcessingResult
-	var err error
 	for attempt := 0; attempt < 3; attempt++ {
+		// Retries pick up where the failed attempt's checkpoint left off
+		if attempt > 0 && checkpoints != nil {
+			pipeline.SetCheckpoints(checkpoints, true)
+		}
 		result, err = pipeline.ProcessFile(ctx, inputFile)
 		if err == nil {
 			break
@@ -235,17 +482,125 @@ func processDataset(ctx context.Context, services *services, etlConfig *etl.Conf
 		zap.Int64("total_records", result.TotalRecords),
 		zap.Int64("processed_ok", result.ProcessedOK),
 		zap.Int64("processed_failed", result.ProcessedFailed),
+		zap.Int64("rejected", result.Rejected),
 		zap.Int64("duplicates", result.Duplicates),
+		zap.Int64("near_duplicates", result.NearDuplicates),
+		zap.Int64("resumed", result.Resumed),
 		zap.Duration("total_duration", result.Duration),
 		zap.Duration("embedding_time", result.EmbeddingTime),
 		zap.Duration("database_time", result.DatabaseTime),
 		zap.Duration("cache_time", result.CacheTime),
-		zap.Float64("records_per_second", float64(result.TotalRecords)/result.Duration.Seconds()))
+		zap.Duration("hash_time", result.HashTime),
+		zap.Float64("records_per_second", float64(result.TotalRecords)/result.Duration.Seconds()),
+		zap.Any("stages", result.Stages))
+
+	if result.Rejected > 0 {
+		log.Warn("Some records were rejected", zap.Int64("rejected", result.Rejected), zap.String("rejects_file", rejectsFile))
+	}
+
+	if len(result.UnmappedLabels) > 0 {
+		log.Warn("Labels with no taxonomy mapping; add them to etl.labels.mapping",
+			zap.Any("unmapped_labels", result.UnmappedLabels))
+	}
 
 	if len(result.Errors) > 0 {
 		log.Warn("Processing completed with errors", zap.Strings("errors", result.Errors))
 	}
 
+	if !validateOnly && !dryRun {
+		if err := saveCanaries(ctx, services, log); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveCanaries stores reference embeddings of the canary texts next to the
+// corpus so the proxy can detect a different model at startup
+func saveCanaries(ctx context.Context, services *services, log *logger.Logger) error {
+	canaries, err := embeddings.EmbedCanaries(ctx, services.embeddingService, services.model)
+	if err != nil {
+		return err
+	}
+	if err := services.vectorStore.SaveCanaries(ctx, canaries); err != nil {
+		return err
+	}
+	log.Info("Stored corpus canary embeddings", zap.String("model", services.model), zap.Int("canaries", len(canaries)))
+	return nil
+}
+
+// sampleEmbeddings embeds the first n records of inputFile
+func sampleEmbeddings(ctx context.Context, pipeline *etl.Pipeline, inputFile string, n int, log *logger.Logger) ([][]float32, []int, error) {
+	log.Info("Sampling input", zap.String("file", inputFile), zap.Int("sample", n))
+	sample, err := pipeline.SampleFile(ctx, inputFile, n)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sample input: %w", err)
+	}
+
+	embeddings := make([][]float32, len(sample))
+	labels := make([]int, len(sample))
+	for i, vec := range sample {
+		embeddings[i] = vec.Embedding
+		labels[i] = vec.Label
+	}
+	return embeddings, labels, nil
+}
+
+// fitReduction fits a PCA projection on a sample of the input and saves it
+// where the store will load it
+func fitReduction(ctx context.Context, pipeline *etl.Pipeline, reduction config.ReductionConfig, inputFile string, n int, log *logger.Logger) error {
+	if reduction.PCAPath == "" || reduction.Dimensions <= 0 {
+		return fmt.Errorf("set security.vector_security.reduction.dimensions and pca_path before fitting")
+	}
+
+	embeddings, _, err := sampleEmbeddings(ctx, pipeline, inputFile, n, log)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	pca, err := vector.FitPCA(embeddings, reduction.Dimensions)
+	if err != nil {
+		return err
+	}
+	if err := pca.Save(reduction.PCAPath); err != nil {
+		return err
+	}
+
+	log.Info("PCA projection saved",
+		zap.String("path", reduction.PCAPath),
+		zap.Int("samples", len(embeddings)),
+		zap.Int("dimensions", reduction.Dimensions),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// evaluateReduction reports how the configured reduction changes
+// nearest-neighbour results on a sample of the input
+func evaluateReduction(ctx context.Context, pipeline *etl.Pipeline, reduction config.ReductionConfig, inputFile string, n int, log *logger.Logger) error {
+	reducer, err := vector.NewReducer(reduction.Method, reduction.Dimensions, reduction.PCAPath)
+	if err != nil {
+		return err
+	}
+	if reducer == nil {
+		return fmt.Errorf("no dimension reduction configured; set security.vector_security.reduction.method")
+	}
+
+	embeddings, labels, err := sampleEmbeddings(ctx, pipeline, inputFile, n, log)
+	if err != nil {
+		return err
+	}
+
+	const k = 5
+	report := etl.EvaluateReduction(embeddings, labels, reducer, k)
+
+	fmt.Printf("\n=== Dimension Reduction Evaluation (%s) ===\n", reduction.Method)
+	fmt.Printf("Samples:            %d\n", report.Samples)
+	fmt.Printf("Dimensions:         %d -> %d (%.0f%% less storage)\n", report.FullDimensions, report.ReducedDims, report.StorageReduction*100)
+	fmt.Printf("Neighbour Recall@%d: %.1f%%\n", report.K, report.RecallAtK*100)
+	fmt.Printf("kNN Accuracy:       %.1f%% full, %.1f%% reduced (%+.1f pts)\n",
+		report.FullAccuracy*100, report.ReducedAccuracy*100, (report.ReducedAccuracy-report.FullAccuracy)*100)
 	return nil
 }
 
@@ -266,6 +621,9 @@ func showDatabaseStats(ctx context.Context, services *services, log *logger.Logg
 		float64(stats.SafeCount)/float64(stats.TotalVectors)*100)
 	fmt.Printf("Avg Search Time:    %.2f ms\n", stats.AvgSearchTimeMs)
 	fmt.Printf("Cache Hit Rate:     %.1f%%\n", stats.CacheHitRate)
+	printCounts("Vectors by Label", stats.LabelTexts)
+	printCounts("Vectors by Source", stats.Sources)
+	printCounts("Vectors by Language", stats.Languages)
 
 	// Get cache stats if available
 	if services.vectorCache != nil {
@@ -274,6 +632,7 @@ func showDatabaseStats(ctx context.Context, services *services, log *logger.Logg
 			fmt.Printf("\n=== Cache Statistics ===\n")
 			fmt.Printf("Cache Hits:         %d\n", cacheStats.Hits)
 			fmt.Printf("Cache Misses:       %d\n", cacheStats.Misses)
+			fmt.Printf("Local Hits:         %d\n", cacheStats.LocalHits)
 			fmt.Printf("Hit Rate:           %.1f%%\n", cacheStats.HitRate)
 			fmt.Printf("Total Keys:         %d\n", cacheStats.TotalKeys)
 			fmt.Printf("Memory Usage:       %.2f MB\n", float64(cacheStats.MemoryUsage)/1024/1024)
@@ -288,28 +647,57 @@ func showDatabaseStats(ctx context.Context, services *services, log *logger.Logg
 	fmt.Printf("Avg Inference Time: %v\n", embeddingStats.AvgInferenceTime)
 	fmt.Printf("Avg Tokens/Text:    %.1f\n", embeddingStats.AvgTokensPerText)
 	fmt.Printf("Model Load Time:    %v\n", embeddingStats.ModelLoadTime)
+	if lookups := embeddingStats.LocalCacheHits + embeddingStats.RedisCacheHits + embeddingStats.CacheMisses; lookups > 0 {
+		fmt.Printf("Cache Hits:         %d local, %d Redis of %d\n", embeddingStats.LocalCacheHits, embeddingStats.RedisCacheHits, lookups)
+		fmt.Printf("Cache Hit Ratio:    %.1f%%\n", embeddingStats.CacheHitRatio*100)
+	}
 
 	return nil
 }
 
-// rebuildCacheFromDB rebuilds the Redis cache from database vectors
-func rebuildCacheFromDB(ctx context.Context, services *services, log *logger.Logger) error {
+// printCounts prints a breakdown of vector counts, largest first
+func printCounts(title string, counts map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n=== %s ===\n", title)
+	for _, key := range keys {
+		fmt.Printf("%-20s %d\n", key+":", counts[key])
+	}
+}
+
+// rebuildCacheFromDB repopulates the Redis vector cache with the corpus's
+// malicious vectors. Without a label the cache is cleared first; with one,
+// only that category's entries are rewritten.
+func rebuildCacheFromDB(ctx context.Context, services *services, label string, log *logger.Logger) error {
 	if services.vectorCache == nil {
 		return fmt.Errorf("vector cache is not enabled")
 	}
 
-	log.Info("Rebuilding cache from database...")
+	log.Info("Rebuilding cache from database...", zap.String("label", label))
+	start := time.Now()
 
-	// Clear existing cache
-	if err := services.vectorCache.Clear(ctx); err != nil {
-		return fmt.Errorf("failed to clear cache: %w", err)
+	if label == "" {
+		if err := services.vectorCache.Clear(ctx); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
 	}
 
-	// New implementation:
 	const batchSize = 1000
-	var offset int64 = 0
+	var afterID, cached int64
 	for {
-		vectors, err := services.vectorStore.GetMaliciousVectors(ctx, batchSize, offset)
+		vectors, err := services.vectorStore.GetMaliciousVectors(ctx, afterID, batchSize, label)
 		if err != nil {
 			return fmt.Errorf("failed to query malicious vectors: %w", err)
 		}
@@ -317,24 +705,36 @@ func rebuildCacheFromDB(ctx context.Context, services *services, log *logger.Log
 			break
 		}
 
-		log.Debug("Processing vector batch", zap.Int("count", len(vectors)))
+		cachedEmbeddings := make([][]float32, 0, len(vectors))
+		cachedVectors := make([]*cache.CachedVector, 0, len(vectors))
 		for _, vec := range vectors {
-			cacheKey := fmt.Sprintf("vector:%s", vec.TextHash)
-			var setErr error = nil // Temp
-			if setErr != nil {
-				log.Warn("Failed to cache vector", zap.String("text_hash", vec.TextHash), zap.Error(setErr))
-			} else {
-				log.Debug("Cached key", zap.String("key", cacheKey))
+			if len(vec.Embedding) == 0 {
+				continue
 			}
+			cachedEmbeddings = append(cachedEmbeddings, vec.Embedding)
+			cachedVectors = append(cachedVectors, &cache.CachedVector{
+				ID:         vec.ID,
+				Text:       vec.Text,
+				LabelText:  vec.LabelText,
+				Label:      vec.Label,
+				Embedding:  vec.Embedding,
+				Similarity: 1.0, // Perfect match for exact text
+			})
 		}
-
-		offset += int64(batchSize) // Use batchSize since mock
-		if offset%1000 == 0 {
-			log.Info("Cache rebuild progress", zap.Int64("processed", offset))
+		if err := services.vectorCache.StoreBatch(ctx, cachedEmbeddings, cachedVectors); err != nil {
+			return fmt.Errorf("failed to cache vectors after id %d: %w", afterID, err)
 		}
+
+		cached += int64(len(cachedVectors))
+		afterID = vectors[len(vectors)-1].ID
+		log.Info("Cache rebuild progress",
+			zap.Int64("cached", cached),
+			zap.Int64("last_id", afterID),
+			zap.Float64("vectors_per_second", float64(cached)/time.Since(start).Seconds()))
 	}
 
-	log.Info("Cache rebuild completed")
-	log.Warn("Cache rebuild is mocked; implement proper query in vector/store.go")
+	log.Info("Cache rebuild completed",
+		zap.Int64("cached", cached),
+		zap.Duration("duration", time.Since(start)))
 	return nil
 }
@@ -23,6 +23,17 @@ var (
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "soak" {
+		os.Exit(runSoak(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		os.Exit(runPolicy(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verdicts" {
+		os.Exit(runVerdicts(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	var (
 		configPath  = flag.String("config", "", "Path to configuration file")
@@ -84,12 +95,22 @@ func main() {
 		zap.Int("port", cfg.Server.Port),
 	)
 
+	// Log the effective configuration so misconfigurations can be diagnosed from logs alone
+	log.Info("Effective configuration", zap.Any("config", config.Summary(cfg)))
+
 	// Create proxy server
 	server, err := proxy.New(cfg, log)
 	if err != nil {
 		log.Fatal("Failed to create proxy server", zap.Error(err))
 	}
 
+	// Apply config file edits that do not need a restart
+	if err := config.Watch(cfg, server.Reload, func(err error) {
+		log.Error("Ignoring configuration change", zap.Error(err))
+	}); err != nil {
+		log.Warn("Configuration hot reload unavailable", zap.Error(err))
+	}
+
 	// Start server in goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
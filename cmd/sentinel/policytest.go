@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/proxy"
+	"go.yaml.in/yaml/v3"
+)
+
+// policyScenarioFile is a YAML file of policy test scenarios
+type policyScenarioFile struct {
+	Scenarios []policyScenario `yaml:"scenarios"`
+}
+
+// policyScenario sends one request as a client and states what the proxy
+// should do with it
+type policyScenario struct {
+	Name    string         `yaml:"name"`
+	Route   string         `yaml:"route"`   // provider route, e.g. openai
+	Path    string         `yaml:"path"`    // upstream path; defaults to the route's chat endpoint
+	Client  policyClient   `yaml:"client"`  // who sends the request
+	Request interface{}    `yaml:"request"` // JSON body, written as YAML
+	Body    string         `yaml:"body"`    // raw body, instead of request
+	Repeat  int            `yaml:"repeat"`  // send this many times; expectations apply to the last
+	Expect  policyExpected `yaml:"expect"`
+}
+
+// policyClient is the identity a scenario's request carries
+type policyClient struct {
+	IP          string            `yaml:"ip"`           // defaults to an address unique to the scenario
+	APIKey      string            `yaml:"api_key"`      // provider key, sent as a Bearer token
+	SentinelKey string            `yaml:"sentinel_key"` // client_auth key
+	Headers     map[string]string `yaml:"headers"`
+}
+
+// policyExpected is a scenario's expected outcome. Empty fields are not
+// checked.
+type policyExpected struct {
+	Action              string   `yaml:"action"`  // allow (forwarded upstream), block (security block) or reject (any other refusal)
+	Status              int      `yaml:"status"`  // HTTP status returned to the client
+	Verdict             string   `yaml:"verdict"` // allowed, flagged, skipped, pending or not_analyzed; forwarded requests only
+	AttackType          string   `yaml:"attack_type"`
+	Policy              string   `yaml:"policy"` // security policy name, "default" for the global settings; forwarded requests only
+	PII                 []string `yaml:"pii"`    // entity types masked before forwarding
+	UpstreamContains    []string `yaml:"upstream_contains"`
+	UpstreamNotContains []string `yaml:"upstream_not_contains"`
+}
+
+// policyActions are the accepted expect.action values
+var policyActions = []string{"allow", "block", "reject"}
+
+// defaultScenarioPaths are the upstream paths used when a scenario names
+// only a route
+var defaultScenarioPaths = map[string]string{
+	"openai":    "/v1/chat/completions",
+	"anthropic": "/v1/messages",
+	"ollama":    "/api/generate",
+}
+
+// policyOutcome is what the proxy did with a scenario's last request
+type policyOutcome struct {
+	status     int
+	action     string
+	verdict    string
+	attackType string
+	policy     string
+	pii        map[string]int
+	upstream   []byte // body forwarded upstream
+}
+
+// recordingUpstream answers upstream requests like the soak runner and keeps
+// the last one, so scenarios can check what was forwarded
+type recordingUpstream struct {
+	annotationHeader string
+	forwarded        bool
+	body             []byte
+	annotation       string
+}
+
+func (u *recordingUpstream) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	u.forwarded = true
+	u.body = body
+	u.annotation = req.Header.Get(u.annotationHeader)
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return soakUpstream{}.RoundTrip(req)
+}
+
+// reset forgets the last forwarded request
+func (u *recordingUpstream) reset() {
+	u.forwarded, u.body, u.annotation = false, nil, ""
+}
+
+// runPolicy implements `sentinel policy <command>`
+func runPolicy(args []string) int {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprintln(os.Stderr, "Usage: sentinel policy test [--config path] scenarios.yaml...")
+		return 2
+	}
+	return runPolicyTest(args[1:])
+}
+
+// runPolicyTest implements `sentinel policy test`: it sends each scenario's
+// request through the configured pipeline in process, with upstreams
+// answered locally, and compares what happened with the scenario's
+// expectations. Scenarios share one server and run in order, so rate limits
+// and conversation state carry over between them. It returns the exit code:
+// 1 when a scenario fails or cannot run.
+func runPolicyTest(args []string) int {
+	flags := flag.NewFlagSet("policy test", flag.ExitOnError)
+	var (
+		configPath = flags.String("config", "", "Path to configuration file")
+		logLevel   = flags.String("log-level", "error", "Log level for the server under test")
+		verbose    = flags.Bool("v", false, "Show the outcome of passing scenarios too")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "No scenario files given")
+		return 1
+	}
+
+	var scenarios []policyScenario
+	for _, path := range flags.Args() {
+		loaded, err := loadPolicyScenarios(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load scenarios: %v\n", err)
+			return 1
+		}
+		scenarios = append(scenarios, loaded...)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	// Annotations carry the verdict and policy to the upstream, where the
+	// runner reads them; they change nothing about how requests are handled
+	annotations := &cfg.Upstream.Annotations
+	annotations.Enabled = true
+	annotations.Routes = nil
+	if annotations.SigningKey == "" {
+		key := make([]byte, 32)
+		rand.Read(key)
+		annotations.SigningKey = hex.EncodeToString(key)
+	}
+
+	log, err := logger.New(logger.Config{Level: *logLevel, Format: cfg.Logging.Format})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+
+	server, err := proxy.New(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create proxy server: %v\n", err)
+		return 1
+	}
+	upstream := &recordingUpstream{annotationHeader: annotations.Header}
+	server.SetUpstreamTransport(upstream)
+	server.StartWorkers()
+	handler := server.Handler()
+
+	failed := 0
+	for i, scenario := range scenarios {
+		outcome := runPolicyScenario(handler, upstream, cfg, i, scenario)
+		failures := checkPolicyScenario(scenario.Expect, outcome)
+		if len(failures) == 0 {
+			fmt.Printf("PASS  %s\n", scenario.Name)
+			if *verbose {
+				fmt.Printf("      %s\n", describeOutcome(outcome))
+			}
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL  %s\n", scenario.Name)
+		for _, failure := range failures {
+			fmt.Printf("      %s\n", failure)
+		}
+		fmt.Printf("      got: %s\n", describeOutcome(outcome))
+	}
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stopCancel()
+	if err := server.Stop(stopCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop server: %v\n", err)
+	}
+
+	fmt.Printf("\n%d scenarios, %d passed, %d failed\n", len(scenarios), len(scenarios)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadPolicyScenarios reads and checks a scenario file
+func loadPolicyScenarios(path string) ([]policyScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file policyScenarioFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i := range file.Scenarios {
+		scenario := &file.Scenarios[i]
+		if scenario.Name == "" {
+			scenario.Name = fmt.Sprintf("%s #%d", path, i+1)
+		}
+		if scenario.Route == "" {
+			return nil, fmt.Errorf("%s: scenario %q has no route", path, scenario.Name)
+		}
+		if scenario.Path == "" {
+			scenario.Path = defaultScenarioPaths[scenario.Route]
+			if scenario.Path == "" {
+				return nil, fmt.Errorf("%s: scenario %q needs a path for route %s", path, scenario.Name, scenario.Route)
+			}
+		}
+		if scenario.Request != nil && scenario.Body != "" {
+			return nil, fmt.Errorf("%s: scenario %q sets both request and body", path, scenario.Name)
+		}
+		if action := scenario.Expect.Action; action != "" && !slices.Contains(policyActions, action) {
+			return nil, fmt.Errorf("%s: scenario %q expects unknown action %q (allow, block or reject)", path, scenario.Name, action)
+		}
+	}
+	return file.Scenarios, nil
+}
+
+// runPolicyScenario sends a scenario's request and observes the outcome
+func runPolicyScenario(handler http.Handler, upstream *recordingUpstream, cfg *config.Config, index int, scenario policyScenario) policyOutcome {
+	body := []byte(scenario.Body)
+	if scenario.Request != nil {
+		body, _ = json.Marshal(scenario.Request)
+	}
+	ip := scenario.Client.IP
+	if ip == "" {
+		ip = fmt.Sprintf("198.18.%d.%d", index>>8&0xff, index&0xff)
+	}
+
+	var recorder *httptest.ResponseRecorder
+	for n := 0; n < max(scenario.Repeat, 1); n++ {
+		req := httptest.NewRequest(http.MethodPost, "/"+scenario.Route+scenario.Path, bytes.NewReader(body))
+		req.RemoteAddr = ip + ":40000"
+		req.Header.Set("Content-Type", "application/json")
+		if scenario.Client.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+scenario.Client.APIKey)
+		}
+		if scenario.Client.SentinelKey != "" {
+			req.Header.Set(cfg.Security.ClientAuth.Header, scenario.Client.SentinelKey)
+		}
+		for name, value := range scenario.Client.Headers {
+			req.Header.Set(name, value)
+		}
+
+		upstream.reset()
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+	}
+
+	outcome := policyOutcome{status: recorder.Code}
+	switch {
+	case upstream.forwarded:
+		outcome.action = "allow"
+		outcome.upstream = upstream.body
+		readAnnotation(upstream.annotation, &outcome)
+	case recorder.Code == cfg.Security.BlockResponse.StatusCode:
+		outcome.action = "block"
+		outcome.attackType = blockedAttackType(recorder.Body.Bytes())
+	default:
+		outcome.action = "reject"
+	}
+	return outcome
+}
+
+// readAnnotation fills outcome from the claims of an annotation token. The
+// token was signed by the server under test, so its signature is not checked.
+func readAnnotation(token string, outcome *policyOutcome) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return
+	}
+	var claims struct {
+		Verdict struct {
+			Status     string `json:"status"`
+			AttackType string `json:"attack_type"`
+		} `json:"verdict"`
+		Findings struct {
+			Types map[string]int `json:"types"`
+		} `json:"findings"`
+		Policy struct {
+			Name string `json:"name"`
+		} `json:"policy"`
+	}
+	if json.Unmarshal(payload, &claims) != nil {
+		return
+	}
+
+	outcome.verdict = claims.Verdict.Status
+	outcome.attackType = claims.Verdict.AttackType
+	outcome.pii = claims.Findings.Types
+	outcome.policy = claims.Policy.Name
+	if outcome.policy == "" {
+		outcome.policy = "default"
+	}
+}
+
+// blockedAttackType reads the attack type from an OpenAI- or
+// Anthropic-format block response; text responses do not carry one
+func blockedAttackType(body []byte) string {
+	var response struct {
+		Error struct {
+			AttackType string `json:"attack_type"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &response) != nil {
+		return ""
+	}
+	return response.Error.AttackType
+}
+
+// checkPolicyScenario lists how outcome differs from expect
+func checkPolicyScenario(expect policyExpected, outcome policyOutcome) []string {
+	var failures []string
+	mismatch := func(field string, want, got interface{}) {
+		failures = append(failures, fmt.Sprintf("expected %s %v, got %v", field, want, got))
+	}
+
+	if expect.Action != "" && expect.Action != outcome.action {
+		mismatch("action", expect.Action, outcome.action)
+	}
+	if expect.Status != 0 && expect.Status != outcome.status {
+		mismatch("status", expect.Status, outcome.status)
+	}
+	if expect.Verdict != "" && expect.Verdict != outcome.verdict {
+		mismatch("verdict", expect.Verdict, orNone(outcome.verdict))
+	}
+	if expect.AttackType != "" && !strings.EqualFold(expect.AttackType, outcome.attackType) {
+		mismatch("attack type", expect.AttackType, orNone(outcome.attackType))
+	}
+	if expect.Policy != "" && expect.Policy != outcome.policy {
+		mismatch("policy", expect.Policy, orNone(outcome.policy))
+	}
+	for _, entity := range expect.PII {
+		if outcome.pii[entity] == 0 {
+			failures = append(failures, fmt.Sprintf("expected %s to be masked", entity))
+		}
+	}
+	for _, text := range expect.UpstreamContains {
+		if !bytes.Contains(outcome.upstream, []byte(text)) {
+			failures = append(failures, fmt.Sprintf("expected upstream request to contain %q", text))
+		}
+	}
+	for _, text := range expect.UpstreamNotContains {
+		if bytes.Contains(outcome.upstream, []byte(text)) {
+			failures = append(failures, fmt.Sprintf("expected upstream request not to contain %q", text))
+		}
+	}
+	return failures
+}
+
+func describeOutcome(outcome policyOutcome) string {
+	description := fmt.Sprintf("action=%s status=%d", outcome.action, outcome.status)
+	if outcome.verdict != "" {
+		description += " verdict=" + outcome.verdict
+	}
+	if outcome.attackType != "" {
+		description += " attack_type=" + outcome.attackType
+	}
+	if outcome.policy != "" {
+		description += " policy=" + outcome.policy
+	}
+	if len(outcome.pii) > 0 {
+		types := make([]string, 0, len(outcome.pii))
+		for entity := range outcome.pii {
+			types = append(types, entity)
+		}
+		slices.Sort(types)
+		description += " pii=" + strings.Join(types, ",")
+	}
+	return description
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/archive"
+	"github.com/raaihank/llm-sentinel/internal/artifact"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/verdicts"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+)
+
+// verdictStoreTimeout bounds one archive store request during an export
+const verdictStoreTimeout = 5 * time.Minute
+
+// runVerdicts implements `sentinel verdicts <command>`
+func runVerdicts(args []string) int {
+	usage := "Usage: sentinel verdicts export [--config path] --since date [--until date] --output file.csv|file.parquet\n" +
+		"       sentinel verdicts verify [--config path] file.manifest.json"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "export":
+		return runVerdictExport(args[1:])
+	case "verify":
+		return runVerdictVerify(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+}
+
+// runVerdictExport implements `sentinel verdicts export`: it reads the
+// vector security and PII decisions archived in a date range, writes them
+// redacted as CSV or Parquet, and writes a signed manifest with summary
+// statistics next to the file. It returns the exit code.
+func runVerdictExport(args []string) int {
+	flags := flag.NewFlagSet("verdicts export", flag.ExitOnError)
+	var (
+		configPath = flags.String("config", "", "Path to configuration file")
+		sinceFlag  = flags.String("since", "", "Export decisions from this time, RFC 3339 or YYYY-MM-DD (required)")
+		untilFlag  = flags.String("until", "", "Export decisions before this time, RFC 3339 or YYYY-MM-DD (default now)")
+		output     = flags.String("output", "", "Export file; .csv or .parquet (required)")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *sinceFlag == "" || *output == "" {
+		fmt.Fprintln(os.Stderr, "--since and --output are required")
+		return 1
+	}
+	since, err := parseVerdictTime("since", *sinceFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	until := time.Now().UTC()
+	if *untilFlag != "" {
+		if until, err = parseVerdictTime("until", *untilFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if !since.Before(until) {
+		fmt.Fprintln(os.Stderr, "--since must be before --until")
+		return 1
+	}
+	format, err := verdicts.FormatOf(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	archiveCfg := cfg.Events.Archive
+	exportCfg := archiveCfg.VerdictExport
+	if exportCfg.SigningKey == "" {
+		fmt.Fprintln(os.Stderr, "events.archive.verdict_export.signing_key is required to sign exports")
+		return 1
+	}
+	if len(exportCfg.Redact) > 0 && exportCfg.RedactMode == "hash" && exportCfg.HashKey == "" {
+		fmt.Fprintln(os.Stderr, "events.archive.verdict_export.hash_key is required to hash redacted columns")
+		return 1
+	}
+	if types := archiveCfg.EventTypes; len(types) > 0 {
+		for _, eventType := range []websocket.EventType{websocket.EventTypeVectorSecurity, websocket.EventTypePIIDetection} {
+			if !slices.Contains(types, string(eventType)) {
+				fmt.Fprintf(os.Stderr, "Warning: events.archive.event_types does not include %s; those decisions are not in the archive\n", eventType)
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	manifest, err := exportVerdicts(ctx, cfg, since, until, *output, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+
+	summary := manifest.Summary
+	fmt.Printf("Exported %d decisions from %s to %s\n", manifest.Rows, since.Format(time.RFC3339), until.Format(time.RFC3339))
+	for _, action := range sortedKeys(summary.ByAction) {
+		fmt.Printf("  %-10s %d\n", action, summary.ByAction[action])
+	}
+	if len(summary.ByAttackType) > 0 {
+		fmt.Printf("Block rate %.1f%%, mean confidence %.3f\n", summary.BlockRate*100, summary.MeanConfidence)
+	}
+	fmt.Printf("File:     %s\nManifest: %s\n", filepath.Join(filepath.Dir(*output), manifest.File), manifestPath(filepath.Join(filepath.Dir(*output), manifest.File)))
+	return 0
+}
+
+// exportVerdicts writes the export file, encrypted when artifact encryption
+// is enabled, then its signed manifest
+func exportVerdicts(ctx context.Context, cfg *config.Config, since, until time.Time, outputFile, format string) (*verdicts.Manifest, error) {
+	archiveCfg := cfg.Events.Archive
+	exportCfg := archiveCfg.VerdictExport
+	store, err := archive.OpenFromConfig(archiveCfg.Store, verdictStoreTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var encrypter *artifact.Encrypter
+	if cfg.Artifacts.Encryption.Enabled {
+		if encrypter, err = artifact.NewEncrypter(cfg.Artifacts.Encryption.Recipients); err != nil {
+			return nil, err
+		}
+	}
+	outputFile = encrypter.Path(outputFile)
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	file, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	out, err := encrypter.Wrap(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt export: %w", err)
+	}
+	writer, err := verdicts.NewWriter(out, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var redaction *verdicts.Redaction
+	if len(exportCfg.Redact) > 0 {
+		redaction = verdicts.NewRedaction(exportCfg.Redact, exportCfg.RedactMode, []byte(exportCfg.HashKey))
+	}
+	summary, err := verdicts.Export(ctx, store, archiveCfg.Store.Prefix, since, until, writer, redaction)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt export: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	digest, err := verdicts.FileSHA256(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash export file: %w", err)
+	}
+	hostname, _ := os.Hostname()
+	manifest := &verdicts.Manifest{
+		File:        filepath.Base(outputFile),
+		Format:      format,
+		Encrypted:   encrypter != nil,
+		SHA256:      digest,
+		Rows:        summary.Verdicts,
+		Since:       since.UTC(),
+		Until:       until.UTC(),
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		GeneratedBy: hostname,
+		Redaction:   redaction,
+		Summary:     summary,
+	}
+	if err := manifest.Sign([]byte(exportCfg.SigningKey)); err != nil {
+		return nil, err
+	}
+	if err := verdicts.WriteManifest(manifestPath(outputFile), manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// runVerdictVerify implements `sentinel verdicts verify`: it checks a
+// manifest's signature and that the export file next to it is the one it
+// was signed with. It returns the exit code.
+func runVerdictVerify(args []string) int {
+	flags := flag.NewFlagSet("verdicts verify", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: sentinel verdicts verify [--config path] file.manifest.json")
+		return 1
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	key := cfg.Events.Archive.VerdictExport.SigningKey
+	if key == "" {
+		fmt.Fprintln(os.Stderr, "events.archive.verdict_export.signing_key is required to verify exports")
+		return 1
+	}
+
+	path := flags.Arg(0)
+	manifest, err := verdicts.ReadManifest(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := manifest.Verify([]byte(key)); err != nil {
+		if errors.Is(err, verdicts.ErrSignature) {
+			fmt.Printf("FAIL  %s: signature does not match\n", path)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+	exportFile := filepath.Join(filepath.Dir(path), manifest.File)
+	digest, err := verdicts.FileSHA256(exportFile)
+	if err != nil {
+		fmt.Printf("FAIL  %s: %v\n", exportFile, err)
+		return 1
+	}
+	if digest != manifest.SHA256 {
+		fmt.Printf("FAIL  %s: contents do not match the manifest\n", exportFile)
+		return 1
+	}
+	fmt.Printf("OK    %s: %d decisions from %s to %s, signed %s\n", exportFile, manifest.Rows,
+		manifest.Since.Format(time.RFC3339), manifest.Until.Format(time.RFC3339), manifest.GeneratedAt.Format(time.RFC3339))
+	return 0
+}
+
+// parseVerdictTime parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseVerdictTime(flagName, value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s: %q (use RFC 3339 or YYYY-MM-DD)", flagName, value)
+}
+
+// manifestPath names the manifest of an export file
+func manifestPath(exportFile string) string {
+	return exportFile + verdicts.ManifestExt
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,350 @@
+// Package archive retains events long term in object storage. Events are
+// appended to local segment files; each interval the sealed segments are
+// compacted into one Parquet file per day and uploaded, so the local spool
+// only ever holds the current interval and any uploads still failing.
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Record is one archived event
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"`
+	Data      json.RawMessage `json:"data"` // event payload
+}
+
+// uploadBatchBytes bounds the segment bytes compacted into one upload, and
+// so the memory a cycle holds
+const uploadBatchBytes = 64 * 1024 * 1024
+
+const (
+	activeSegment = "active.jsonl"
+	sealedPrefix  = "sealed-"
+	sealedSuffix  = ".jsonl"
+)
+
+// Options configures an Archiver
+type Options struct {
+	Dir           string        // local spool
+	Interval      time.Duration // segments are sealed and uploaded this often
+	MaxLocalBytes int64         // sealed segments beyond this are dropped, oldest first
+	Prefix        string        // object key prefix
+	Instance      string        // distinguishes instances sharing a prefix; defaults to the hostname
+}
+
+// Archiver spools records to disk and periodically uploads them to a Store.
+// Object keys derive from the first segment of a batch, so a batch retried
+// after a partial failure replaces its earlier objects instead of
+// duplicating them.
+type Archiver struct {
+	store   Store
+	options Options
+	logger  *zap.Logger
+
+	mu     sync.Mutex
+	active *os.File
+	size   int64 // bytes in the active segment
+
+	flushMu sync.Mutex // one upload cycle at a time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates an archiver writing to store, spooling under options.Dir
+func New(store Store, options Options, logger *zap.Logger) (*Archiver, error) {
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive spool: %w", err)
+	}
+	if options.Instance == "" {
+		options.Instance, _ = os.Hostname()
+	}
+	options.Instance = sanitizeKeyPart(options.Instance)
+	options.Prefix = strings.Trim(options.Prefix, "/")
+
+	a := &Archiver{
+		store:   store,
+		options: options,
+		logger:  logger,
+		stop:    make(chan struct{}),
+	}
+
+	// Records left in the active segment by an earlier run go out with the
+	// first upload
+	if err := a.seal(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Append spools a record. Records are written straight to the segment file,
+// so one lost on a crash is at most a partial last line.
+func (a *Archiver) Append(record Record) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		a.logger.Error("Failed to encode archive record", zap.Error(err))
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.active == nil {
+		a.active, err = os.OpenFile(filepath.Join(a.options.Dir, activeSegment), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			a.logger.Error("Failed to open archive segment", zap.Error(err))
+			return
+		}
+		if info, err := a.active.Stat(); err == nil {
+			a.size = info.Size()
+		}
+	}
+	n, err := a.active.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		a.logger.Error("Failed to spool archive record", zap.Error(err))
+	}
+}
+
+// seal closes the active segment and renames it for upload
+func (a *Archiver) seal() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.active != nil {
+		a.active.Close()
+		a.active = nil
+	}
+	path := filepath.Join(a.options.Dir, activeSegment)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect archive segment: %w", err)
+	}
+
+	sealed := filepath.Join(a.options.Dir, sealedPrefix+strconv.FormatInt(time.Now().UnixNano(), 10)+sealedSuffix)
+	if err := os.Rename(path, sealed); err != nil {
+		return fmt.Errorf("failed to seal archive segment: %w", err)
+	}
+	a.size = 0
+	return nil
+}
+
+// segment is a sealed segment file
+type segment struct {
+	path string
+	id   string // sealing time in nanoseconds; orders segments and names objects
+	size int64
+}
+
+// sealedSegments returns the sealed segments, oldest first
+func (a *Archiver) sealedSegments() ([]segment, error) {
+	entries, err := os.ReadDir(a.options.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive spool: %w", err)
+	}
+	var segments []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, sealedPrefix) || !strings.HasSuffix(name, sealedSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, sealedPrefix), sealedSuffix)
+		segments = append(segments, segment{path: filepath.Join(a.options.Dir, name), id: id, size: info.Size()})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		if len(segments[i].id) != len(segments[j].id) {
+			return len(segments[i].id) < len(segments[j].id)
+		}
+		return segments[i].id < segments[j].id
+	})
+	return segments, nil
+}
+
+// Flush seals the active segment and uploads every sealed one. Segments are
+// deleted once their batch is uploaded; when uploads fail, the oldest are
+// dropped to keep the spool within MaxLocalBytes.
+func (a *Archiver) Flush(ctx context.Context) error {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	if err := a.seal(); err != nil {
+		return err
+	}
+	segments, err := a.sealedSegments()
+	if err != nil {
+		return err
+	}
+
+	for len(segments) > 0 {
+		batch := segments[:1]
+		size := segments[0].size
+		for len(batch) < len(segments) && size+segments[len(batch)].size <= uploadBatchBytes {
+			size += segments[len(batch)].size
+			batch = segments[:len(batch)+1]
+		}
+
+		if err := a.upload(ctx, batch); err != nil {
+			a.enforceLimit(segments)
+			return err
+		}
+		for _, seg := range batch {
+			os.Remove(seg.path)
+		}
+		segments = segments[len(batch):]
+	}
+	return nil
+}
+
+// upload compacts a batch of segments into one Parquet file per day
+func (a *Archiver) upload(ctx context.Context, batch []segment) error {
+	days := make(map[string][]Record)
+	skipped := 0
+	for _, seg := range batch {
+		n, err := readSegment(seg.path, func(record Record) {
+			day := record.Time.UTC().Format("2006-01-02")
+			days[day] = append(days[day], record)
+		})
+		if err != nil {
+			return err
+		}
+		skipped += n
+	}
+	if skipped > 0 {
+		a.logger.Warn("Skipped unreadable archive records", zap.Int("records", skipped))
+	}
+
+	for day, records := range days {
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+		body, err := encodeParquet(records)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("dt=%s/%s-%s.parquet", day, a.options.Instance, batch[0].id)
+		if a.options.Prefix != "" {
+			key = a.options.Prefix + "/" + key
+		}
+		if err := a.store.Put(ctx, key, body); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		a.logger.Info("Events archived",
+			zap.String("key", key),
+			zap.Int("records", len(records)),
+			zap.Int("bytes", len(body)))
+	}
+	return nil
+}
+
+// enforceLimit drops the oldest segments while the spool exceeds
+// MaxLocalBytes
+func (a *Archiver) enforceLimit(segments []segment) {
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+	dropped := 0
+	for _, seg := range segments {
+		if total <= a.options.MaxLocalBytes {
+			break
+		}
+		if err := os.Remove(seg.path); err == nil {
+			total -= seg.size
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		a.logger.Warn("Archive spool full; dropped oldest segments",
+			zap.Int("segments", dropped),
+			zap.Int64("max_local_bytes", a.options.MaxLocalBytes))
+	}
+}
+
+// readSegment calls fn for each record of a segment, returning how many
+// lines could not be decoded, such as one cut short by a crash
+func readSegment(path string, fn func(Record)) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive segment: %w", err)
+	}
+	defer file.Close()
+
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			skipped++
+			continue
+		}
+		fn(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return skipped, fmt.Errorf("failed to read archive segment: %w", err)
+	}
+	return skipped, nil
+}
+
+// Start begins periodic uploads
+func (a *Archiver) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(a.options.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), a.options.Interval)
+			if err := a.Flush(ctx); err != nil {
+				a.logger.Warn("Event archive upload failed; segments kept for the next cycle", zap.Error(err))
+			}
+			cancel()
+		}
+	}()
+}
+
+// Close stops uploading and seals the active segment, leaving it for the
+// next run to upload
+func (a *Archiver) Close() error {
+	close(a.stop)
+	a.wg.Wait()
+	return a.seal()
+}
+
+// sanitizeKeyPart keeps characters that need no escaping in object keys
+func sanitizeKeyPart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '-'
+	}, s)
+}
@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// failingStore refuses every upload
+type failingStore struct {
+	Store
+}
+
+func (failingStore) Put(context.Context, string, []byte) error {
+	return errors.New("bucket unavailable")
+}
+
+func testRecord(at time.Time, id string) Record {
+	return Record{Time: at, Type: "vector_security", RequestID: id, Data: json.RawMessage(`{"action":"blocked"}`)}
+}
+
+// TestArchiveRoundTrip checks records are uploaded as one Parquet file per
+// day and read back within the requested range
+func TestArchiveRoundTrip(t *testing.T) {
+	store := NewDirStore(t.TempDir())
+	archiver, err := New(store, Options{Dir: t.TempDir(), Interval: time.Hour, Prefix: "/events/", Instance: "proxy 1"}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	archiver.Append(testRecord(day.Add(23*time.Hour), "late"))
+	archiver.Append(testRecord(day.Add(time.Hour), "early"))
+	archiver.Append(testRecord(day.Add(25*time.Hour), "next-day"))
+	if err := archiver.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	keys, _ := store.List(context.Background(), "events/")
+	if len(keys) != 2 || !strings.HasPrefix(keys[0], "events/dt=2026-03-01/proxy-1-") || !strings.HasPrefix(keys[1], "events/dt=2026-03-02/") {
+		t.Fatalf("uploaded keys = %v", keys)
+	}
+
+	var ids []string
+	err = Read(context.Background(), store, "events", day, day.Add(24*time.Hour), func(record Record) error {
+		if string(record.Data) != `{"action":"blocked"}` || record.Type != "vector_security" {
+			t.Errorf("record read back as %+v", record)
+		}
+		ids = append(ids, record.RequestID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if strings.Join(ids, ",") != "early,late" {
+		t.Fatalf("Read = %v, want the first day's records in time order", ids)
+	}
+}
+
+// TestArchiveKeepsSegmentsOnFailure checks failed uploads stay spooled for
+// the next cycle and that records left by a previous run are picked up
+func TestArchiveKeepsSegmentsOnFailure(t *testing.T) {
+	spool := t.TempDir()
+	archiver, err := New(failingStore{}, Options{Dir: spool, Interval: time.Hour, MaxLocalBytes: 1 << 20, Instance: "a"}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	archiver.Append(testRecord(time.Now(), "kept"))
+	if err := archiver.Flush(context.Background()); err == nil {
+		t.Fatal("Flush succeeded against a failing store")
+	}
+	archiver.Append(testRecord(time.Now(), "after-restart"))
+	archiver.Close()
+
+	store := NewDirStore(t.TempDir())
+	restarted, err := New(store, Options{Dir: spool, Interval: time.Hour, Instance: "a"}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New after restart: %v", err)
+	}
+	if err := restarted.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush after restart: %v", err)
+	}
+
+	var ids []string
+	Read(context.Background(), store, "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), func(record Record) error {
+		ids = append(ids, record.RequestID)
+		return nil
+	})
+	if len(ids) != 2 {
+		t.Fatalf("records uploaded after restart = %v, want both", ids)
+	}
+	if entries, _ := os.ReadDir(spool); len(entries) != 0 {
+		t.Fatalf("spool not emptied after upload: %d files", len(entries))
+	}
+}
+
+// TestArchiveDropsOldestWhenFull checks the spool is held within
+// MaxLocalBytes while uploads fail
+func TestArchiveDropsOldestWhenFull(t *testing.T) {
+	spool := t.TempDir()
+	archiver, err := New(failingStore{}, Options{Dir: spool, Interval: time.Hour, MaxLocalBytes: 1, Instance: "a"}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	archiver.Append(testRecord(time.Now(), "dropped"))
+	archiver.Flush(context.Background())
+
+	segments, _ := archiver.sealedSegments()
+	if len(segments) != 0 {
+		t.Fatalf("%d segments kept beyond MaxLocalBytes", len(segments))
+	}
+}
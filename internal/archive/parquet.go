@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/segmentio/encoding/thrift"
+	"github.com/segmentio/parquet-go/deprecated"
+	"github.com/segmentio/parquet-go/format"
+)
+
+// parquetMagic opens and closes every Parquet file
+const parquetMagic = "PAR1"
+
+// rowGroupSize bounds the rows encoded into one row group
+const rowGroupSize = 50000
+
+// recordSchema is Record's Parquet schema. Every column is required and
+// flat, so pages carry no repetition or definition levels. data holds the
+// event payload as JSON for query engines' JSON functions.
+func recordSchema() []format.SchemaElement {
+	required := format.Required
+	byteArray, int64Type := format.ByteArray, format.Int64
+	utf8, timestamp := deprecated.UTF8, deprecated.TimestampMicros
+	str := func(name string) format.SchemaElement {
+		return format.SchemaElement{
+			Name:           name,
+			Type:           &byteArray,
+			RepetitionType: &required,
+			ConvertedType:  &utf8,
+			LogicalType:    &format.LogicalType{UTF8: &format.StringType{}},
+		}
+	}
+
+	return []format.SchemaElement{
+		{Name: "schema", NumChildren: 4},
+		{
+			Name:           "time",
+			Type:           &int64Type,
+			RepetitionType: &required,
+			ConvertedType:  &timestamp,
+			LogicalType: &format.LogicalType{Timestamp: &format.TimestampType{
+				IsAdjustedToUTC: true,
+				Unit:            format.TimeUnit{Micros: &format.MicroSeconds{}},
+			}},
+		},
+		str("type"),
+		str("request_id"),
+		str("data"),
+	}
+}
+
+// encodeParquet returns records as a Parquet file, PLAIN encoded and
+// uncompressed, like the ETL export. The Parquet writer of parquet-go does
+// not link with current Go toolchains, so pages are encoded here.
+func encodeParquet(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(parquetMagic)
+
+	var groups []format.RowGroup
+	for start := 0; start < len(records); start += rowGroupSize {
+		group, err := writeRowGroup(&buf, records[start:min(start+rowGroupSize, len(records))])
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	footer, err := thrift.Marshal(new(thrift.CompactProtocol), &format.FileMetaData{
+		Version:   1,
+		Schema:    recordSchema(),
+		NumRows:   int64(len(records)),
+		RowGroups: groups,
+		CreatedBy: "llm-sentinel",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parquet footer: %w", err)
+	}
+	buf.Write(footer)
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	buf.WriteString(parquetMagic)
+	return buf.Bytes(), nil
+}
+
+// writeRowGroup appends one row group of records to buf, one page per column
+func writeRowGroup(buf *bytes.Buffer, records []Record) (format.RowGroup, error) {
+	byteArrays := func(get func(*Record) []byte) []byte {
+		var body []byte
+		for i := range records {
+			value := get(&records[i])
+			body = binary.LittleEndian.AppendUint32(body, uint32(len(value)))
+			body = append(body, value...)
+		}
+		return body
+	}
+	times := make([]byte, 0, 8*len(records))
+	for i := range records {
+		times = binary.LittleEndian.AppendUint64(times, uint64(records[i].Time.UnixMicro()))
+	}
+
+	columns := []struct {
+		name string
+		kind format.Type
+		body []byte
+	}{
+		{"time", format.Int64, times},
+		{"type", format.ByteArray, byteArrays(func(r *Record) []byte { return []byte(r.Type) })},
+		{"request_id", format.ByteArray, byteArrays(func(r *Record) []byte { return []byte(r.RequestID) })},
+		{"data", format.ByteArray, byteArrays(func(r *Record) []byte { return r.Data })},
+	}
+
+	group := format.RowGroup{NumRows: int64(len(records))}
+	for _, column := range columns {
+		header, err := thrift.Marshal(new(thrift.CompactProtocol), &format.PageHeader{
+			Type:                 format.DataPage,
+			UncompressedPageSize: int32(len(column.body)),
+			CompressedPageSize:   int32(len(column.body)),
+			DataPageHeader: &format.DataPageHeader{
+				NumValues:               int32(len(records)),
+				Encoding:                format.Plain,
+				DefinitionLevelEncoding: format.RLE,
+				RepetitionLevelEncoding: format.RLE,
+			},
+		})
+		if err != nil {
+			return group, fmt.Errorf("failed to encode page header: %w", err)
+		}
+
+		offset := int64(buf.Len())
+		buf.Write(header)
+		buf.Write(column.body)
+
+		size := int64(len(header) + len(column.body))
+		group.TotalByteSize += size
+		group.Columns = append(group.Columns, format.ColumnChunk{
+			FileOffset: offset,
+			MetaData: format.ColumnMetaData{
+				Type:                  column.kind,
+				Encoding:              []format.Encoding{format.Plain},
+				PathInSchema:          []string{column.name},
+				Codec:                 format.Uncompressed,
+				NumValues:             int64(len(records)),
+				TotalUncompressedSize: size,
+				TotalCompressedSize:   size,
+				DataPageOffset:        offset,
+			},
+		})
+	}
+	return group, nil
+}
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/thrift"
+	"github.com/segmentio/parquet-go/format"
+)
+
+// Read calls fn with each archived record under prefix whose time is in
+// [since, until), file by file in key order. Only the day partitions
+// overlapping the range are fetched.
+func Read(ctx context.Context, store Store, prefix string, since, until time.Time, fn func(Record) error) error {
+	listPrefix := "dt="
+	if prefix != "" {
+		listPrefix = prefix + "/dt="
+	}
+	keys, err := store.List(ctx, listPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list archive: %w", err)
+	}
+
+	first := since.UTC().Format("2006-01-02")
+	last := until.UTC().Add(-time.Microsecond).Format("2006-01-02")
+	for _, key := range keys {
+		day, _, ok := strings.Cut(strings.TrimPrefix(key, listPrefix), "/")
+		if !ok || !strings.HasSuffix(key, ".parquet") || day < first || day > last {
+			continue
+		}
+		body, err := store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", key, err)
+		}
+		records, err := decodeParquet(body)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		for _, record := range records {
+			if record.Time.Before(since) || !record.Time.Before(until) {
+				continue
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeParquet reads the records of a file written by encodeParquet.
+// parquet-go's reader pulls in hashing code that current Go toolchains do
+// not link into the server, so like the writer this handles only the
+// layout encodeParquet produces: flat required columns, one PLAIN
+// uncompressed page per column chunk.
+func decodeParquet(body []byte) ([]Record, error) {
+	size := len(body)
+	if size < 2*len(parquetMagic)+4 || string(body[:len(parquetMagic)]) != parquetMagic || string(body[size-len(parquetMagic):]) != parquetMagic {
+		return nil, fmt.Errorf("not a parquet file")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(body[size-len(parquetMagic)-4:]))
+	footerStart := size - len(parquetMagic) - 4 - footerLen
+	if footerStart < len(parquetMagic) {
+		return nil, fmt.Errorf("invalid parquet footer length %d", footerLen)
+	}
+	var metadata format.FileMetaData
+	if err := thrift.Unmarshal(new(thrift.CompactProtocol), body[footerStart:footerStart+footerLen], &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode parquet footer: %w", err)
+	}
+
+	var records []Record
+	for _, group := range metadata.RowGroups {
+		rows := make([]Record, group.NumRows)
+		for _, chunk := range group.Columns {
+			page, err := chunkPage(body, chunk.MetaData)
+			if err != nil {
+				return nil, err
+			}
+			name := strings.Join(chunk.MetaData.PathInSchema, ".")
+			for i := range rows {
+				switch name {
+				case "time":
+					if len(page) < 8 {
+						return nil, fmt.Errorf("column %s: truncated page", name)
+					}
+					rows[i].Time = time.UnixMicro(int64(binary.LittleEndian.Uint64(page))).UTC()
+					page = page[8:]
+				case "type", "request_id", "data":
+					if len(page) < 4 || len(page)-4 < int(binary.LittleEndian.Uint32(page)) {
+						return nil, fmt.Errorf("column %s: truncated page", name)
+					}
+					n := int(binary.LittleEndian.Uint32(page))
+					value := page[4 : 4+n]
+					page = page[4+n:]
+					switch name {
+					case "type":
+						rows[i].Type = string(value)
+					case "request_id":
+						rows[i].RequestID = string(value)
+					default:
+						rows[i].Data = append([]byte(nil), value...)
+					}
+				}
+			}
+		}
+		records = append(records, rows...)
+	}
+	return records, nil
+}
+
+// chunkPage returns the values of a column chunk's only data page
+func chunkPage(body []byte, column format.ColumnMetaData) ([]byte, error) {
+	start, end := column.DataPageOffset, column.DataPageOffset+column.TotalCompressedSize
+	if start < 0 || end > int64(len(body)) || column.Codec != format.Uncompressed {
+		return nil, fmt.Errorf("unsupported parquet column chunk %s", strings.Join(column.PathInSchema, "."))
+	}
+	chunk := body[start:end]
+	var header format.PageHeader
+	if err := thrift.NewDecoder(new(thrift.CompactProtocol).NewReader(bytes.NewReader(chunk))).Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to decode page header: %w", err)
+	}
+	pageSize := int(header.CompressedPageSize)
+	if header.Type != format.DataPage || header.DataPageHeader == nil || header.DataPageHeader.Encoding != format.Plain || pageSize > len(chunk) {
+		return nil, fmt.Errorf("unsupported parquet page in column %s", strings.Join(column.PathInSchema, "."))
+	}
+	return chunk[len(chunk)-pageSize:], nil
+}
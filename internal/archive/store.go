@@ -0,0 +1,379 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/sigv4"
+)
+
+// Store holds archived files under keys such as
+// llm-sentinel/events/dt=2024-05-01/host-1714521600000000000.parquet.
+// Writing an existing key replaces it.
+type Store interface {
+	Put(ctx context.Context, key string, body []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]string, error) // keys under prefix, in lexical order
+}
+
+// DirStore writes archived files below a local directory, such as a mounted
+// network volume
+type DirStore struct {
+	root string
+}
+
+// NewDirStore creates a store rooted at root
+func NewDirStore(root string) *DirStore {
+	return &DirStore{root: root}
+}
+
+// Put writes body to root/key atomically
+func (s *DirStore) Put(ctx context.Context, key string, body []byte) error {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	return nil
+}
+
+// Get reads root/key
+func (s *DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	body, err := os.ReadFile(filepath.Join(s.root, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	return body, nil
+}
+
+// List returns the keys of the files under root that start with prefix,
+// like an object store listing. Files still being written are skipped.
+func (s *DirStore) List(ctx context.Context, prefix string) ([]string, error) {
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = filepath.Join(s.root, filepath.FromSlash(prefix[:i]))
+	}
+	var keys []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive files: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Object store providers
+const (
+	ProviderS3  = "s3"
+	ProviderGCS = "gcs"
+)
+
+// ObjectStoreOptions locates a bucket in S3, GCS or an S3-compatible store
+type ObjectStoreOptions struct {
+	Provider    string // ProviderS3 or ProviderGCS
+	Bucket      string
+	Region      string                  // S3 region; GCS ignores it
+	Endpoint    string                  // empty uses AWS or storage.googleapis.com
+	Credentials *sigv4.CredentialSource // HMAC keys for GCS
+	Client      *http.Client
+}
+
+// ObjectStore puts objects with SigV4-signed requests. GCS is reached
+// through its S3-compatible XML API, which accepts SigV4 with HMAC keys.
+// Objects are addressed path-style when an endpoint is set, and in the
+// bucket's virtual host on AWS.
+type ObjectStore struct {
+	options ObjectStoreOptions
+	base    *url.URL
+}
+
+// NewObjectStore creates a store for options' bucket
+func NewObjectStore(options ObjectStoreOptions) (*ObjectStore, error) {
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+	if options.Provider == ProviderGCS {
+		options.Region = "auto"
+	}
+
+	endpoint := options.Endpoint
+	switch {
+	case endpoint != "":
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(options.Bucket)
+	case options.Provider == ProviderGCS:
+		endpoint = "https://storage.googleapis.com/" + url.PathEscape(options.Bucket)
+	default:
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", options.Bucket, options.Region)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object store endpoint: %w", err)
+	}
+	return &ObjectStore{options: options, base: base}, nil
+}
+
+// Put uploads body as key
+func (s *ObjectStore) Put(ctx context.Context, key string, body []byte) error {
+	target := *s.base
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + key
+	_, err := s.do(ctx, http.MethodPut, &target, body, http.Header{"Content-Type": {"application/vnd.apache.parquet"}})
+	return err
+}
+
+// Get downloads key
+func (s *ObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	target := *s.base
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + key
+	return s.do(ctx, http.MethodGet, &target, nil, nil)
+}
+
+// s3ListResult is the S3 ListObjectsV2 response, which GCS's XML API
+// also returns
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the keys under prefix, following continuation tokens
+func (s *ObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		target := *s.base
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/"
+		target.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+		body, err := s.do(ctx, http.MethodGet, &target, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Lifecycle is a bucket lifecycle rule for the objects under Prefix
+type Lifecycle struct {
+	Prefix              string
+	TransitionAfterDays int // 0 never transitions
+	StorageClass        string
+	ExpireAfterDays     int // 0 never expires
+}
+
+// ApplyLifecycle sets rule as the bucket's lifecycle configuration,
+// replacing any rules it had
+func (s *ObjectStore) ApplyLifecycle(ctx context.Context, rule Lifecycle) error {
+	var document interface{}
+	if s.options.Provider == ProviderGCS {
+		document = gcsLifecycle(rule)
+	} else {
+		document = s3Lifecycle(rule)
+	}
+	body, err := xml.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to encode lifecycle configuration: %w", err)
+	}
+
+	sum := md5.Sum(body)
+	target := *s.base
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/"
+	target.RawQuery = "lifecycle="
+	_, err = s.do(ctx, http.MethodPut, &target, body, http.Header{
+		"Content-Type": {"application/xml"},
+		"Content-Md5":  {base64.StdEncoding.EncodeToString(sum[:])}, // required by S3 for lifecycle changes
+	})
+	return err
+}
+
+// do sends a signed request and returns the response body
+func (s *ObjectStore) do(ctx context.Context, method string, target *url.URL, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	creds, err := s.options.Credentials.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load object store credentials: %w", err)
+	}
+	sigv4.Sign(req, body, creds, s.options.Region, sigv4.S3, time.Now())
+
+	resp, err := s.options.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object store request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("object store %s %s returned %s: %s", method, target.Path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("object store %s %s: failed to read response: %w", method, target.Path, err)
+	}
+	return respBody, nil
+}
+
+// s3LifecycleConfiguration is the S3 PutBucketLifecycleConfiguration body
+type s3LifecycleConfiguration struct {
+	XMLName xml.Name          `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LifecycleConfiguration"`
+	Rules   []s3LifecycleRule `xml:"Rule"`
+}
+
+type s3LifecycleRule struct {
+	ID         string        `xml:"ID"`
+	Filter     s3Filter      `xml:"Filter"`
+	Status     string        `xml:"Status"`
+	Transition *s3Transition `xml:"Transition,omitempty"`
+	Expiration *s3Expiration `xml:"Expiration,omitempty"`
+}
+
+type s3Filter struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type s3Transition struct {
+	Days         int    `xml:"Days"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type s3Expiration struct {
+	Days int `xml:"Days"`
+}
+
+func s3Lifecycle(rule Lifecycle) s3LifecycleConfiguration {
+	s3Rule := s3LifecycleRule{ID: "llm-sentinel-archive", Filter: s3Filter{Prefix: rule.Prefix}, Status: "Enabled"}
+	if rule.TransitionAfterDays > 0 {
+		s3Rule.Transition = &s3Transition{Days: rule.TransitionAfterDays, StorageClass: rule.StorageClass}
+	}
+	if rule.ExpireAfterDays > 0 {
+		s3Rule.Expiration = &s3Expiration{Days: rule.ExpireAfterDays}
+	}
+	return s3LifecycleConfiguration{Rules: []s3LifecycleRule{s3Rule}}
+}
+
+// gcsLifecycleConfiguration is the GCS XML API lifecycle body
+type gcsLifecycleConfiguration struct {
+	XMLName xml.Name           `xml:"LifecycleConfiguration"`
+	Rules   []gcsLifecycleRule `xml:"Rule"`
+}
+
+type gcsLifecycleRule struct {
+	Action struct {
+		SetStorageClass string    `xml:"SetStorageClass,omitempty"`
+		Delete          *struct{} `xml:"Delete,omitempty"`
+	} `xml:"Action"`
+	Condition struct {
+		Age           int    `xml:"Age"`
+		MatchesPrefix string `xml:"MatchesPrefix,omitempty"`
+	} `xml:"Condition"`
+}
+
+func gcsLifecycle(rule Lifecycle) gcsLifecycleConfiguration {
+	var config gcsLifecycleConfiguration
+	if rule.TransitionAfterDays > 0 {
+		var transition gcsLifecycleRule
+		transition.Action.SetStorageClass = rule.StorageClass
+		transition.Condition.Age = rule.TransitionAfterDays
+		transition.Condition.MatchesPrefix = rule.Prefix
+		config.Rules = append(config.Rules, transition)
+	}
+	if rule.ExpireAfterDays > 0 {
+		var expiration gcsLifecycleRule
+		expiration.Action.Delete = &struct{}{}
+		expiration.Condition.Age = rule.ExpireAfterDays
+		expiration.Condition.MatchesPrefix = rule.Prefix
+		config.Rules = append(config.Rules, expiration)
+	}
+	return config
+}
+
+// OpenFromConfig creates the configured archive backend. Object store
+// requests time out after timeout.
+func OpenFromConfig(cfg config.ArchiveStoreConfig, timeout time.Duration) (Store, error) {
+	if cfg.Type == "dir" {
+		return NewDirStore(cfg.Path), nil
+	}
+
+	client := &http.Client{Timeout: timeout}
+	credentials := sigv4.NewCredentialSource(client, cfg.Region)
+	if cfg.AccessKeyID != "" {
+		credentials = sigv4.StaticCredentials(sigv4.Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		})
+	}
+	store, err := NewObjectStore(ObjectStoreOptions{
+		Provider:    cfg.Type,
+		Bucket:      cfg.Bucket,
+		Region:      cfg.Region,
+		Endpoint:    cfg.Endpoint,
+		Credentials: credentials,
+		Client:      client,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure event archive store: %w", err)
+	}
+	return store, nil
+}
@@ -0,0 +1,122 @@
+// Package artifact encrypts exported corpora and reports with age so prompt
+// text is not left readable on disk, and transparently decrypts them on import
+package artifact
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Extension marks encrypted artifacts
+const Extension = ".age"
+
+// ageHeader starts every binary age file
+var ageHeader = []byte("age-encryption.org/")
+
+// Options configures artifact encryption
+type Options struct {
+	Recipients   []string // age public keys (age1...) that can decrypt exports
+	IdentityFile string   // age identity file used to decrypt imports
+}
+
+// Encrypter wraps writers so their output is encrypted to the configured
+// recipients. A nil Encrypter writes plaintext.
+type Encrypter struct {
+	recipients []age.Recipient
+}
+
+// NewEncrypter parses recipients. It returns nil when none are configured.
+func NewEncrypter(recipients []string) (*Encrypter, error) {
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+	parsed := make([]age.Recipient, 0, len(recipients))
+	for _, recipient := range recipients {
+		r, err := age.ParseX25519Recipient(strings.TrimSpace(recipient))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient: %w", err)
+		}
+		parsed = append(parsed, r)
+	}
+	return &Encrypter{recipients: parsed}, nil
+}
+
+// Path returns the file name to use for an artifact, adding the age
+// extension when encrypting
+func (e *Encrypter) Path(path string) string {
+	if e == nil || strings.HasSuffix(path, Extension) {
+		return path
+	}
+	return path + Extension
+}
+
+// Wrap returns a writer that encrypts to w. Close must be called to flush
+// the final chunk; it does not close w.
+func (e *Encrypter) Wrap(w io.Writer) (io.WriteCloser, error) {
+	if e == nil {
+		return nopWriteCloser{w}, nil
+	}
+	return age.Encrypt(w, e.recipients...)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Decrypter opens artifacts that may or may not be encrypted
+type Decrypter struct {
+	identities []age.Identity
+}
+
+// NewDecrypter loads identities from an age identity file. An empty path
+// gives a Decrypter that only reads plaintext artifacts.
+func NewDecrypter(identityFile string) (*Decrypter, error) {
+	if identityFile == "" {
+		return &Decrypter{}, nil
+	}
+	file, err := os.Open(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age identity file: %w", err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identity file: %w", err)
+	}
+	return &Decrypter{identities: identities}, nil
+}
+
+// Open returns a reader over r's plaintext and whether r was encrypted
+func (d *Decrypter) Open(r io.Reader) (io.Reader, bool, error) {
+	buffered := bufio.NewReader(r)
+	peek, err := buffered.Peek(len(ageHeader))
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	if !bytes.Equal(peek, ageHeader) {
+		return buffered, false, nil
+	}
+
+	if len(d.identities) == 0 {
+		return nil, true, fmt.Errorf("artifact is age-encrypted but no identity file is configured")
+	}
+	plaintext, err := age.Decrypt(buffered, d.identities...)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to decrypt artifact: %w", err)
+	}
+	return plaintext, true, nil
+}
+
+// TrimExtension strips the age extension so the inner format can be detected
+func TrimExtension(path string) string {
+	return strings.TrimSuffix(path, Extension)
+}
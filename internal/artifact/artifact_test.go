@@ -0,0 +1,114 @@
+package artifact
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// TestEncryptRoundTrip checks an encrypted artifact decrypts with the
+// recipient's identity file and is unreadable without it
+func TestEncryptRoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(identityFile, []byte("# test key\n"+identity.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypter, err := NewEncrypter([]string{" " + identity.Recipient().String() + " "})
+	if err != nil {
+		t.Fatalf("NewEncrypter: %v", err)
+	}
+	plaintext := []byte(`{"prompt":"ignore previous instructions"}` + "\n")
+
+	var encrypted bytes.Buffer
+	w, err := encrypter.Wrap(&encrypted)
+	if err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+	w.Write(plaintext)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if bytes.Contains(encrypted.Bytes(), []byte("ignore previous")) {
+		t.Fatal("encrypted artifact contains plaintext")
+	}
+
+	decrypter, err := NewDecrypter(identityFile)
+	if err != nil {
+		t.Fatalf("NewDecrypter: %v", err)
+	}
+	r, wasEncrypted, err := decrypter.Open(bytes.NewReader(encrypted.Bytes()))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	if !wasEncrypted || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Open = %q (encrypted %v), want %q", got, wasEncrypted, plaintext)
+	}
+
+	noIdentity, _ := NewDecrypter("")
+	if _, wasEncrypted, err := noIdentity.Open(bytes.NewReader(encrypted.Bytes())); err == nil || !wasEncrypted {
+		t.Fatalf("Open without identity = %v (encrypted %v), want an error", err, wasEncrypted)
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	wrongKey := &Decrypter{identities: []age.Identity{other}}
+	if _, _, err := wrongKey.Open(bytes.NewReader(encrypted.Bytes())); err == nil {
+		t.Fatal("Open with the wrong identity succeeded")
+	}
+}
+
+// TestPlaintextPassthrough checks a nil Encrypter writes plaintext and the
+// Decrypter passes plaintext through untouched
+func TestPlaintextPassthrough(t *testing.T) {
+	encrypter, err := NewEncrypter(nil)
+	if err != nil || encrypter != nil {
+		t.Fatalf("NewEncrypter(nil) = %v, %v, want nil", encrypter, err)
+	}
+	if got := encrypter.Path("corpus.jsonl"); got != "corpus.jsonl" {
+		t.Fatalf("nil Path = %q", got)
+	}
+
+	var out bytes.Buffer
+	w, _ := encrypter.Wrap(&out)
+	w.Write([]byte("a,b\n"))
+	w.Close()
+
+	decrypter, _ := NewDecrypter("")
+	for _, input := range []string{out.String(), "", "age"} {
+		r, wasEncrypted, err := decrypter.Open(bytes.NewReader([]byte(input)))
+		if err != nil || wasEncrypted {
+			t.Fatalf("Open(%q) = %v (encrypted %v)", input, err, wasEncrypted)
+		}
+		if got, _ := io.ReadAll(r); string(got) != input {
+			t.Fatalf("Open(%q) read %q", input, got)
+		}
+	}
+}
+
+// TestPaths checks the age extension is added once and stripped for format
+// detection
+func TestPaths(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	encrypter, _ := NewEncrypter([]string{identity.Recipient().String()})
+	if got := encrypter.Path("report.csv"); got != "report.csv.age" {
+		t.Errorf("Path = %q, want report.csv.age", got)
+	}
+	if got := encrypter.Path("report.csv.age"); got != "report.csv.age" {
+		t.Errorf("Path added a second extension: %q", got)
+	}
+	if got := TrimExtension("report.csv.age"); got != "report.csv" {
+		t.Errorf("TrimExtension = %q", got)
+	}
+	if _, err := NewEncrypter([]string{"age1notakey"}); err == nil {
+		t.Error("NewEncrypter accepted an invalid recipient")
+	}
+}
@@ -0,0 +1,122 @@
+// Package bloom implements a compact bloom filter over SHA-256 digests
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Filter is a bloom filter. It is not safe for concurrent mutation; build it
+// fully and then share it read-only.
+type Filter struct {
+	bits  []uint64
+	m     uint64 // number of bits
+	k     uint32 // number of hash functions
+	count uint64 // number of items added
+}
+
+// New sizes a filter for the expected number of items at the given false
+// positive rate
+func New(expected int, falsePositiveRate float64) *Filter {
+	if expected < 1 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	n := float64(expected)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Filter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add inserts data into the filter
+func (f *Filter) Add(data []byte) {
+	h1, h2 := hashes(data)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// Test reports whether data may be in the filter. False means definitely not.
+func (f *Filter) Test(data []byte) bool {
+	h1, h2 := hashes(data)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of items added
+func (f *Filter) Count() uint64 {
+	return f.count
+}
+
+// SizeBytes returns the memory used by the bit array
+func (f *Filter) SizeBytes() int {
+	return len(f.bits) * 8
+}
+
+// hashes derives two independent 64-bit hashes for double hashing
+func hashes(data []byte) (uint64, uint64) {
+	sum := sha256.Sum256(data)
+	h1 := binary.LittleEndian.Uint64(sum[0:8])
+	h2 := binary.LittleEndian.Uint64(sum[8:16]) | 1 // odd, so probes never collapse
+	return h1, h2
+}
+
+// header is m, k and count
+const headerSize = 8 + 4 + 8
+
+// MarshalBinary encodes the filter for sharing between instances
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, headerSize+len(f.bits)*8)
+	binary.LittleEndian.PutUint64(buf[0:8], f.m)
+	binary.LittleEndian.PutUint32(buf[8:12], f.k)
+	binary.LittleEndian.PutUint64(buf[12:20], f.count)
+	for i, word := range f.bits {
+		binary.LittleEndian.PutUint64(buf[headerSize+i*8:], word)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a filter produced by MarshalBinary
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize {
+		return errors.New("bloom: encoded filter too short")
+	}
+	m := binary.LittleEndian.Uint64(data[0:8])
+	k := binary.LittleEndian.Uint32(data[8:12])
+	words := (m + 63) / 64
+	if m == 0 || k == 0 || uint64(len(data)-headerSize) != words*8 {
+		return errors.New("bloom: malformed encoded filter")
+	}
+
+	f.m = m
+	f.k = k
+	f.count = binary.LittleEndian.Uint64(data[12:20])
+	f.bits = make([]uint64, words)
+	for i := range f.bits {
+		f.bits[i] = binary.LittleEndian.Uint64(data[headerSize+i*8:])
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestFilterMembership checks added items always test positive and the false
+// positive rate stays near the configured target
+func TestFilterMembership(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add([]byte(fmt.Sprintf("attack-%d", i)))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.Test([]byte(fmt.Sprintf("attack-%d", i))) {
+			t.Fatalf("attack-%d missing after Add", i)
+		}
+	}
+	if f.Count() != 1000 {
+		t.Fatalf("Count = %d, want 1000", f.Count())
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Test([]byte(fmt.Sprintf("benign-%d", i))) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.03 {
+		t.Fatalf("false positive rate %.3f, want about 0.01", rate)
+	}
+}
+
+// TestMarshalRoundTrip checks a decoded filter answers exactly like the
+// original
+func TestMarshalRoundTrip(t *testing.T) {
+	f := New(100, 0.001)
+	for i := 0; i < 100; i++ {
+		f.Add([]byte(fmt.Sprintf("attack-%d", i)))
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var decoded Filter
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	if decoded.Count() != f.Count() || decoded.SizeBytes() != f.SizeBytes() {
+		t.Fatalf("decoded count %d size %d, want %d %d", decoded.Count(), decoded.SizeBytes(), f.Count(), f.SizeBytes())
+	}
+	for i := 0; i < 1000; i++ {
+		item := []byte(fmt.Sprintf("attack-%d", i))
+		if decoded.Test(item) != f.Test(item) {
+			t.Fatalf("decoded filter disagrees on %s", item)
+		}
+	}
+}
+
+// TestUnmarshalRejectsMalformed checks truncated or inconsistent encodings
+// are refused
+func TestUnmarshalRejectsMalformed(t *testing.T) {
+	data, _ := New(100, 0.01).MarshalBinary()
+
+	tests := map[string][]byte{
+		"empty":          nil,
+		"header only":    data[:headerSize-1],
+		"truncated bits": data[:len(data)-8],
+		"extra bits":     append(append([]byte{}, data...), make([]byte, 8)...),
+		"zero hashes":    append(append(append([]byte{}, data[:8]...), 0, 0, 0, 0), data[12:]...),
+	}
+	for name, encoded := range tests {
+		var f Filter
+		if err := f.UnmarshalBinary(encoded); err == nil {
+			t.Errorf("%s: UnmarshalBinary accepted malformed filter", name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// Lookup modes
+const (
+	LookupExact = "exact" // quantized embedding hash; only near bit-identical prompts hit
+	LookupLSH   = "lsh"   // random hyperplane buckets in plain Redis
+	LookupHNSW  = "hnsw"  // RediSearch vector index (Redis Stack)
+)
+
+// lshSeed fixes the hyperplanes, so every instance sharing a Redis
+// buckets embeddings the same way
+const lshSeed = 0x5e471e1
+
+// lshMaxCandidates bounds the entries fetched and compared per lookup
+const lshMaxCandidates = 64
+
+// lshPlanes are the random hyperplanes of one embedding dimension, bits per
+// table
+type lshPlanes struct {
+	planes [][]float32
+}
+
+// lshIndex buckets embeddings by the side of each hyperplane they fall on.
+// Embeddings with a high cosine similarity share a bucket in at least one
+// table with high probability.
+type lshIndex struct {
+	tables int
+	bits   int
+
+	mu    sync.Mutex
+	byDim map[int]*lshPlanes
+}
+
+func newLSHIndex(tables, bits int) *lshIndex {
+	return &lshIndex{tables: tables, bits: bits, byDim: make(map[int]*lshPlanes)}
+}
+
+// planesFor returns the hyperplanes for embeddings of dims dimensions,
+// generated on first use from the fixed seed
+func (l *lshIndex) planesFor(dims int) *lshPlanes {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if planes, ok := l.byDim[dims]; ok {
+		return planes
+	}
+	rng := rand.New(rand.NewSource(lshSeed + int64(dims)))
+	planes := &lshPlanes{planes: make([][]float32, l.tables*l.bits)}
+	for i := range planes.planes {
+		plane := make([]float32, dims)
+		for j := range plane {
+			plane[j] = float32(rng.NormFloat64())
+		}
+		planes.planes[i] = plane
+	}
+	l.byDim[dims] = planes
+	return planes
+}
+
+// buckets returns the bucket key of embedding in each table
+func (l *lshIndex) buckets(prefix string, embedding []float32) []string {
+	planes := l.planesFor(len(embedding))
+	keys := make([]string, l.tables)
+	for table := 0; table < l.tables; table++ {
+		var signature uint64
+		for bit := 0; bit < l.bits; bit++ {
+			var dot float32
+			for i, v := range planes.planes[table*l.bits+bit] {
+				dot += v * embedding[i]
+			}
+			if dot >= 0 {
+				signature |= 1 << bit
+			}
+		}
+		keys[table] = fmt.Sprintf("%s:lsh:%d:%d:%x", prefix, len(embedding), table, signature)
+	}
+	return keys
+}
+
+// lookupHit is a cached entry found for a query embedding
+type lookupHit struct {
+	key         string
+	vector      *CachedVector
+	similarity  float32 // of the query to the cached prompt; 1 for an exact key match
+	approximate bool
+	local       bool // served from the in-process tier
+}
+
+// lookupLSH compares the embedding with the entries sharing any of its
+// buckets and returns the most similar at or above MinSimilarity
+func (vc *VectorCache) lookupLSH(ctx context.Context, embedding []float32) (*lookupHit, error) {
+	buckets := vc.lsh.buckets(vc.config.KeyPrefix, embedding)
+	members, err := vc.client.SUnion(ctx, buckets...).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	if len(members) > lshMaxCandidates {
+		members = members[:lshMaxCandidates]
+	}
+
+	values, err := vc.client.MGet(ctx, members...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var best *lookupHit
+	var expired []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			expired = append(expired, members[i])
+			continue
+		}
+		var cached CachedVector
+		if err := json.Unmarshal([]byte(data), &cached); err != nil || len(cached.QueryEmbedding) != len(embedding) {
+			continue
+		}
+		similarity := cosineSimilarity(embedding, cached.QueryEmbedding)
+		if similarity >= vc.config.MinSimilarity && (best == nil || similarity > best.similarity) {
+			best = &lookupHit{key: members[i], vector: &cached, similarity: similarity, approximate: true}
+		}
+	}
+
+	// Buckets outlive their entries; drop members that expired
+	if len(expired) > 0 {
+		pipe := vc.client.Pipeline()
+		for _, bucket := range buckets {
+			pipe.SRem(ctx, bucket, expired...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			vc.logger.Debug("Failed to prune LSH buckets", zap.Error(err))
+		}
+	}
+	return best, nil
+}
+
+// queueLSH adds an entry to its buckets. Buckets live as long as the
+// longest TTL so entries stay reachable for their whole lifetime.
+func (vc *VectorCache) queueLSH(ctx context.Context, pipe redis.Pipeliner, key string, embedding []float32) {
+	for _, bucket := range vc.lsh.buckets(vc.config.KeyPrefix, embedding) {
+		pipe.SAdd(ctx, bucket, key)
+		pipe.Expire(ctx, bucket, vc.maxTTL())
+	}
+}
+
+// maxTTL is the longest TTL any entry can get
+func (vc *VectorCache) maxTTL() time.Duration {
+	longest := vc.config.DefaultTTL
+	for _, tier := range vc.config.TTLTiers {
+		if tier.TTL > longest {
+			longest = tier.TTL
+		}
+	}
+	return longest
+}
+
+// hnswIndex names the RediSearch index over the cache's hash entries
+func (vc *VectorCache) hnswIndex() string {
+	return vc.config.KeyPrefix + ":idx"
+}
+
+// createHNSWIndex creates the RediSearch vector index unless it exists
+func (vc *VectorCache) createHNSWIndex(ctx context.Context) error {
+	err := vc.client.Do(ctx, "FT.CREATE", vc.hnswIndex(),
+		"ON", "HASH", "PREFIX", "1", vc.config.KeyPrefix+":hnsw:",
+		"SCHEMA", "embedding", "VECTOR", "HNSW", "6",
+		"TYPE", "FLOAT32", "DIM", strconv.Itoa(vc.config.Dimensions), "DISTANCE_METRIC", "COSINE",
+	).Err()
+	if err != nil && !strings.Contains(err.Error(), "Index already exists") {
+		return fmt.Errorf("failed to create RediSearch vector index (requires Redis Stack): %w", err)
+	}
+	return nil
+}
+
+// lookupHNSW asks RediSearch for the nearest cached prompt and returns it
+// when it is at or above MinSimilarity
+func (vc *VectorCache) lookupHNSW(ctx context.Context, embedding []float32) (*lookupHit, error) {
+	if len(embedding) != vc.config.Dimensions {
+		return nil, fmt.Errorf("embedding has %d dimensions, the vector index %d", len(embedding), vc.config.Dimensions)
+	}
+	reply, err := vc.client.Do(ctx, "FT.SEARCH", vc.hnswIndex(),
+		"*=>[KNN 1 @embedding $vec AS distance]",
+		"PARAMS", "2", "vec", float32Bytes(embedding),
+		"SORTBY", "distance", "RETURN", "2", "distance", "data",
+		"LIMIT", "0", "1", "DIALECT", "2",
+	).Slice()
+	if err != nil {
+		return nil, err
+	}
+	// [total, key, [field, value, ...]]
+	if len(reply) < 3 {
+		return nil, nil
+	}
+	key, _ := reply[1].(string)
+	fields, _ := reply[2].([]interface{})
+	var distance float64 = -1
+	var data string
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+		switch name {
+		case "distance":
+			if d, err := strconv.ParseFloat(value, 64); err == nil {
+				distance = d
+			}
+		case "data":
+			data = value
+		}
+	}
+	if distance < 0 || data == "" {
+		return nil, nil
+	}
+
+	// COSINE distance is 1 - cosine similarity
+	similarity := float32(1 - distance)
+	if similarity < vc.config.MinSimilarity {
+		return nil, nil
+	}
+	var cached CachedVector
+	if err := json.Unmarshal([]byte(data), &cached); err != nil {
+		vc.client.Del(ctx, key)
+		return nil, fmt.Errorf("failed to unmarshal cached vector: %w", err)
+	}
+	return &lookupHit{key: key, vector: &cached, similarity: similarity, approximate: true}, nil
+}
+
+// queueHNSW writes an entry as a hash the vector index covers
+func (vc *VectorCache) queueHNSW(ctx context.Context, pipe redis.Pipeliner, embedding []float32, data []byte, ttl time.Duration) string {
+	key := vc.config.KeyPrefix + ":hnsw:" + embeddingHash(embedding)
+	pipe.HSet(ctx, key, "embedding", float32Bytes(embedding), "data", data)
+	pipe.Expire(ctx, key, ttl)
+	return key
+}
+
+// float32Bytes encodes an embedding as RediSearch's FLOAT32 blob
+func float32Bytes(embedding []float32) []byte {
+	buf := make([]byte, 0, 4*len(embedding))
+	for _, v := range embedding {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+	}
+	return buf
+}
+
+// cosineSimilarity of two embeddings of equal length
+func cosineSimilarity(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/lru"
 	"go.uber.org/zap"
 )
 
@@ -21,18 +23,60 @@ type VectorCache struct {
 	config *Config
 	logger *zap.Logger
 	stats  *cacheStats
+	lsh    *lshIndex // lsh lookup only
+
+	// local holds recent hits and stores in process, keyed by exact cache
+	// key, so hot prompts skip Redis; nil when LocalSize is 0
+	local *lru.Cache[string, lookupHit]
 }
 
 // cacheStats tracks cache performance metrics
 type cacheStats struct {
-	hits   int64
-	misses int64
+	hits            int64
+	misses          int64
+	approximateHits int64
+	localHits       int64
 }
 
 // NewVectorCache creates a new Redis-based vector cache
 func NewVectorCache(config *Config, logger *zap.Logger) (*VectorCache, error) {
-	// Parse Redis URL
-	opts, err := redis.ParseURL(config.RedisURL)
+	if config.TTLTiers == nil {
+		config.TTLTiers = DefaultTTLTiers()
+	}
+	for _, tier := range config.TTLTiers {
+		if tier.TTL <= 0 {
+			return nil, fmt.Errorf("invalid TTL for cache tier %s: %v (must be positive)", tier.Name, tier.TTL)
+		}
+	}
+	if config.Lookup == "" {
+		config.Lookup = LookupExact
+	}
+	switch config.Lookup {
+	case LookupExact:
+	case LookupLSH:
+		if config.LSHTables <= 0 || config.LSHBits <= 0 || config.LSHBits > 64 {
+			return nil, fmt.Errorf("invalid LSH parameters: %d tables of %d bits (need at least one table of 1-64 bits)", config.LSHTables, config.LSHBits)
+		}
+	case LookupHNSW:
+		if config.Dimensions <= 0 {
+			return nil, fmt.Errorf("invalid vector index dimensions: %d (must be positive)", config.Dimensions)
+		}
+	default:
+		return nil, fmt.Errorf("invalid cache lookup: %s (must be exact, lsh or hnsw)", config.Lookup)
+	}
+	if config.LocalSize < 0 || config.LocalTTL < 0 {
+		return nil, fmt.Errorf("invalid local cache: %d entries for %v (must not be negative)", config.LocalSize, config.LocalTTL)
+	}
+	if config.Lookup != LookupExact && (config.MinSimilarity <= 0 || config.MinSimilarity > 1) {
+		return nil, fmt.Errorf("invalid cache min similarity: %v (must be in (0, 1])", config.MinSimilarity)
+	}
+
+	// Parse Redis URL; a bare host:port is accepted like elsewhere in the config
+	redisURL := config.RedisURL
+	if !strings.Contains(redisURL, "://") {
+		redisURL = "redis://" + redisURL
+	}
+	opts, err := redis.ParseURL(redisURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
 	}
@@ -43,6 +87,7 @@ func NewVectorCache(config *Config, logger *zap.Logger) (*VectorCache, error) {
 	// Note: ConnMaxLifetime not available in this Redis client version
 
 	client := redis.NewClient(opts)
+	client.AddHook(chaos.RedisHook{})
 
 	cache := &VectorCache{
 		client: client,
@@ -50,6 +95,12 @@ func NewVectorCache(config *Config, logger *zap.Logger) (*VectorCache, error) {
 		logger: logger,
 		stats:  &cacheStats{},
 	}
+	if config.Lookup == LookupLSH {
+		cache.lsh = newLSHIndex(config.LSHTables, config.LSHBits)
+	}
+	if config.LocalSize > 0 {
+		cache.local = lru.NewWithTTL[string, lookupHit](config.LocalSize, config.LocalTTL)
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -58,11 +109,20 @@ func NewVectorCache(config *Config, logger *zap.Logger) (*VectorCache, error) {
 	if err := cache.ping(ctx); err != nil {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
+	if config.Lookup == LookupHNSW {
+		if err := cache.createHNSWIndex(ctx); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
 
 	logger.Info("Vector cache initialized successfully",
 		zap.String("redis_url", maskRedisURL(config.RedisURL)),
 		zap.Int("max_connections", config.MaxConnections),
-		zap.Duration("default_ttl", config.DefaultTTL))
+		zap.Duration("default_ttl", config.DefaultTTL),
+		zap.Int("ttl_tiers", len(config.TTLTiers)),
+		zap.String("lookup", config.Lookup),
+		zap.Int("local_size", config.LocalSize))
 
 	return cache, nil
 }
@@ -84,29 +144,11 @@ func (vc *VectorCache) SearchSimilar(ctx context.Context, embedding []float32, o
 
 	start := time.Now()
 
-	// Generate cache key from embedding hash
-	cacheKey := vc.generateEmbeddingKey(embedding)
-
-	// Try to get from cache
-	cachedData, err := vc.client.Get(ctx, cacheKey).Result()
-	if err == redis.Nil {
-		// Cache miss
-		vc.stats.misses++
-		vc.logger.Debug("Cache miss", zap.String("key", cacheKey))
-		return &SearchResult{CacheHit: false}, nil
-	} else if err != nil {
-		vc.logger.Error("Cache lookup failed", zap.Error(err))
-		return &SearchResult{CacheHit: false}, nil
-	}
-
-	// Parse cached vector
-	var cachedVector CachedVector
-	if err := json.Unmarshal([]byte(cachedData), &cachedVector); err != nil {
-		vc.logger.Error("Failed to unmarshal cached vector", zap.Error(err))
-		// Delete corrupted cache entry
-		vc.client.Del(ctx, cacheKey)
+	hit := vc.lookup(ctx, embedding)
+	if hit == nil {
 		return &SearchResult{CacheHit: false}, nil
 	}
+	cachedVector := hit.vector
 
 	// Check if cached result meets criteria
 	if cachedVector.Similarity < options.MinSimilarity {
@@ -127,36 +169,128 @@ func (vc *VectorCache) SearchSimilar(ctx context.Context, embedding []float32, o
 
 	// Cache hit!
 	vc.stats.hits++
+	if hit.approximate {
+		vc.stats.approximateHits++
+	}
+	if hit.local {
+		vc.stats.localHits++
+	}
 	duration := time.Since(start)
 
 	vc.logger.Debug("Cache hit",
-		zap.String("key", cacheKey),
+		zap.String("key", hit.key),
 		zap.Float32("similarity", cachedVector.Similarity),
+		zap.Float32("query_similarity", hit.similarity),
+		zap.Bool("local", hit.local),
 		zap.Duration("duration", duration))
 
 	return &SearchResult{
-		Vector:   &cachedVector,
-		CacheHit: true,
+		Vector:          cachedVector,
+		CacheHit:        true,
+		Approximate:     hit.approximate,
+		QuerySimilarity: hit.similarity,
 	}, nil
 }
 
-// Store caches a vector with its similarity score
-func (vc *VectorCache) Store(ctx context.Context, embedding []float32, vector *CachedVector) error {
+// lookup finds the cached entry for an embedding in the local tier, then
+// in Redis, and keeps Redis hits locally
+func (vc *VectorCache) lookup(ctx context.Context, embedding []float32) *lookupHit {
+	if vc.local == nil {
+		return vc.lookupRedis(ctx, embedding)
+	}
+	localKey := vc.generateEmbeddingKey(embedding)
+	if hit, ok := vc.local.Get(localKey); ok {
+		vector := *hit.vector
+		hit.vector, hit.local = &vector, true
+		return &hit
+	}
+	hit := vc.lookupRedis(ctx, embedding)
+	if hit != nil {
+		vc.keepLocal(localKey, *hit)
+	}
+	return hit
+}
+
+// keepLocal adds a hit to the local tier until the earlier of LocalTTL and
+// the Redis entry's expiry, so it never outlives the entry it copies
+func (vc *VectorCache) keepLocal(localKey string, hit lookupHit) {
+	ttl := time.Until(hit.vector.CachedAt.Add(time.Duration(hit.vector.TTL) * time.Second))
+	if ttl <= 0 {
+		return
+	}
+	if vc.config.LocalTTL > 0 && vc.config.LocalTTL < ttl {
+		ttl = vc.config.LocalTTL
+	}
+	vector := *hit.vector
+	hit.vector = &vector
+	vc.local.AddWithTTL(localKey, hit, ttl)
+}
+
+// lookupRedis finds the cached entry for an embedding: by its exact key
+// first, then, in an approximate mode, the nearest cached prompt at or
+// above MinSimilarity. It returns nil on a miss, which it counts, and when
+// Redis fails.
+func (vc *VectorCache) lookupRedis(ctx context.Context, embedding []float32) *lookupHit {
+	if vc.config.Lookup == LookupHNSW {
+		hit, err := vc.lookupHNSW(ctx, embedding)
+		if err != nil {
+			vc.logger.Error("Cache lookup failed", zap.Error(err))
+			return nil
+		}
+		if hit == nil {
+			vc.stats.misses++
+			vc.logger.Debug("Cache miss", zap.String("lookup", vc.config.Lookup))
+			return nil
+		}
+		return hit
+	}
+
+	// Generate cache key from embedding hash
 	cacheKey := vc.generateEmbeddingKey(embedding)
 
-	// Set cache timestamp and TTL
-	vector.CachedAt = time.Now()
-	vector.TTL = int64(vc.config.DefaultTTL.Seconds())
+	// Try to get from cache
+	cachedData, err := vc.client.Get(ctx, cacheKey).Result()
+	if err == redis.Nil {
+		if vc.config.Lookup == LookupLSH {
+			hit, err := vc.lookupLSH(ctx, embedding)
+			if err != nil {
+				vc.logger.Error("Cache lookup failed", zap.Error(err))
+				return nil
+			}
+			if hit != nil {
+				return hit
+			}
+		}
+		// Cache miss
+		vc.stats.misses++
+		vc.logger.Debug("Cache miss", zap.String("key", cacheKey))
+		return nil
+	} else if err != nil {
+		vc.logger.Error("Cache lookup failed", zap.Error(err))
+		return nil
+	}
 
-	// Serialize vector
-	data, err := json.Marshal(vector)
+	// Parse cached vector
+	var cachedVector CachedVector
+	if err := json.Unmarshal([]byte(cachedData), &cachedVector); err != nil {
+		vc.logger.Error("Failed to unmarshal cached vector", zap.Error(err))
+		// Delete corrupted cache entry
+		vc.client.Del(ctx, cacheKey)
+		return nil
+	}
+	return &lookupHit{key: cacheKey, vector: &cachedVector, similarity: 1}
+}
+
+// Store caches a vector with its similarity score
+func (vc *VectorCache) Store(ctx context.Context, embedding []float32, vector *CachedVector) error {
+	pipe := vc.client.Pipeline()
+	cacheKey, tier, err := vc.queueStore(ctx, pipe, embedding, vector)
 	if err != nil {
-		return fmt.Errorf("failed to marshal vector for caching: %w", err)
+		return err
 	}
 
 	// Store in Redis with TTL
-	err = vc.client.Set(ctx, cacheKey, data, vc.config.DefaultTTL).Err()
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
 		vc.logger.Error("Failed to cache vector", zap.Error(err))
 		return fmt.Errorf("failed to cache vector: %w", err)
 	}
@@ -164,8 +298,12 @@ func (vc *VectorCache) Store(ctx context.Context, embedding []float32, vector *C
 	vc.logger.Debug("Vector cached successfully",
 		zap.String("key", cacheKey),
 		zap.String("label_text", vector.LabelText),
-		zap.Float32("similarity", vector.Similarity))
+		zap.Float32("similarity", vector.Similarity),
+		zap.String("ttl_tier", tier))
 
+	if vc.local != nil {
+		vc.keepLocal(vc.generateEmbeddingKey(embedding), lookupHit{key: cacheKey, vector: vector, similarity: 1})
+	}
 	return nil
 }
 
@@ -181,21 +319,14 @@ func (vc *VectorCache) StoreBatch(ctx context.Context, embeddings [][]float32, v
 
 	pipe := vc.client.Pipeline()
 
+	keys := make([]string, len(vectors))
 	for i, vector := range vectors {
-		cacheKey := vc.generateEmbeddingKey(embeddings[i])
-
-		// Set cache timestamp and TTL
-		vector.CachedAt = time.Now()
-		vector.TTL = int64(vc.config.DefaultTTL.Seconds())
-
-		// Serialize vector
-		data, err := json.Marshal(vector)
+		key, _, err := vc.queueStore(ctx, pipe, embeddings[i], vector)
 		if err != nil {
 			vc.logger.Error("Failed to marshal vector for batch caching", zap.Error(err))
 			continue
 		}
-
-		pipe.Set(ctx, cacheKey, data, vc.config.DefaultTTL)
+		keys[i] = key
 	}
 
 	// Execute pipeline
@@ -208,9 +339,47 @@ func (vc *VectorCache) StoreBatch(ctx context.Context, embeddings [][]float32, v
 	vc.logger.Debug("Batch cache operation completed",
 		zap.Int("cached_vectors", len(vectors)))
 
+	if vc.local != nil {
+		for i, key := range keys {
+			if key != "" {
+				vc.keepLocal(vc.generateEmbeddingKey(embeddings[i]), lookupHit{key: key, vector: vectors[i], similarity: 1})
+			}
+		}
+	}
+
 	return nil
 }
 
+// queueStore adds the commands caching one vector to pipe and returns its
+// key and TTL tier. Approximate modes keep the query embedding with the
+// entry so lookups can compare against it.
+func (vc *VectorCache) queueStore(ctx context.Context, pipe redis.Pipeliner, embedding []float32, vector *CachedVector) (string, string, error) {
+	// Set cache timestamp and TTL by verdict severity
+	ttl, tier := vc.ttlFor(vector)
+	vector.CachedAt = time.Now()
+	vector.TTL = int64(ttl.Seconds())
+	vector.TTLTier = tier
+	if vc.config.Lookup == LookupLSH {
+		vector.QueryEmbedding = embedding
+	}
+
+	// Serialize vector
+	data, err := json.Marshal(vector)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal vector for caching: %w", err)
+	}
+
+	if vc.config.Lookup == LookupHNSW {
+		return vc.queueHNSW(ctx, pipe, embedding, data, ttl), tier, nil
+	}
+	cacheKey := vc.generateEmbeddingKey(embedding)
+	pipe.Set(ctx, cacheKey, data, ttl)
+	if vc.config.Lookup == LookupLSH {
+		vc.queueLSH(ctx, pipe, cacheKey, embedding)
+	}
+	return cacheKey, tier, nil
+}
+
 // GetStats returns cache performance statistics
 func (vc *VectorCache) GetStats(ctx context.Context) (*CacheStats, error) {
 	// Get Redis info
@@ -220,11 +389,16 @@ func (vc *VectorCache) GetStats(ctx context.Context) (*CacheStats, error) {
 	}
 
 	stats := &CacheStats{
-		Hits:   vc.stats.hits,
-		Misses: vc.stats.misses,
+		Hits:            vc.stats.hits,
+		Misses:          vc.stats.misses,
+		ApproximateHits: vc.stats.approximateHits,
+		LocalHits:       vc.stats.localHits,
+	}
+	if vc.local != nil {
+		stats.LocalEntries = vc.local.Len()
 	}
 
-	// Calculate hit rate
+	// Calculate hit rate across both tiers
 	total := stats.Hits + stats.Misses
 	if total > 0 {
 		stats.HitRate = float64(stats.Hits) / float64(total) * 100
@@ -253,6 +427,9 @@ func (vc *VectorCache) GetStats(ctx context.Context) (*CacheStats, error) {
 
 // Clear removes all cached vectors
 func (vc *VectorCache) Clear(ctx context.Context) error {
+	if vc.local != nil {
+		vc.local.Purge()
+	}
 	pattern := vc.config.KeyPrefix + "*"
 
 	// Use SCAN to find all keys with our prefix
@@ -299,6 +476,11 @@ func (vc *VectorCache) Close() error {
 
 // generateEmbeddingKey creates a cache key from an embedding vector
 func (vc *VectorCache) generateEmbeddingKey(embedding []float32) string {
+	return fmt.Sprintf("%s:emb:%s", vc.config.KeyPrefix, embeddingHash(embedding))
+}
+
+// embeddingHash hashes a quantized embedding
+func embeddingHash(embedding []float32) string {
 	// Create a hash of the embedding for consistent cache keys
 	hasher := sha256.New()
 
@@ -310,7 +492,7 @@ func (vc *VectorCache) generateEmbeddingKey(embedding []float32) string {
 	}
 
 	hash := hex.EncodeToString(hasher.Sum(nil))
-	return fmt.Sprintf("%s:emb:%s", vc.config.KeyPrefix, hash[:16]) // Use first 16 chars
+	return hash[:16] // Use first 16 chars
 }
 
 // maskRedisURL masks sensitive information in Redis URL for logging
@@ -0,0 +1,34 @@
+package cache
+
+import "time"
+
+// TTLTier assigns a TTL to cached verdicts of one label at or above a
+// confidence. Tiers are checked in order; the first match wins.
+type TTLTier struct {
+	Name          string        `yaml:"name" mapstructure:"name"`
+	Label         int           `yaml:"label" mapstructure:"label"`                   // 1 = malicious, 0 = safe
+	MinConfidence float32       `yaml:"min_confidence" mapstructure:"min_confidence"` // compared to the cached similarity
+	TTL           time.Duration `yaml:"ttl" mapstructure:"ttl"`
+}
+
+// DefaultTTLTiers keeps confident malicious verdicts for a day while
+// borderline and safe verdicts expire quickly, so a corpus update changes
+// risky decisions without waiting out a long TTL
+func DefaultTTLTiers() []TTLTier {
+	return []TTLTier{
+		{Name: "malicious_high", Label: 1, MinConfidence: 0.9, TTL: 24 * time.Hour},
+		{Name: "malicious_borderline", Label: 1, MinConfidence: 0, TTL: time.Hour},
+		{Name: "safe", Label: 0, MinConfidence: 0, TTL: 10 * time.Minute},
+	}
+}
+
+// ttlFor returns the TTL for a cached verdict, falling back to DefaultTTL
+// when no tier matches
+func (vc *VectorCache) ttlFor(vector *CachedVector) (time.Duration, string) {
+	for _, tier := range vc.config.TTLTiers {
+		if vector.Label == tier.Label && vector.Similarity >= tier.MinConfidence {
+			return tier.TTL, tier.Name
+		}
+	}
+	return vc.config.DefaultTTL, "default"
+}
@@ -17,19 +17,29 @@ type CachedVector struct {
 	Distance   float32   `json:"distance"`
 	CachedAt   time.Time `json:"cached_at"`
 	TTL        int64     `json:"ttl"`
+	TTLTier    string    `json:"ttl_tier,omitempty"`
+
+	// QueryEmbedding is the embedding of the prompt the verdict was cached
+	// for, kept by lsh lookup to compare later prompts against
+	QueryEmbedding []float32 `json:"query_embedding,omitempty"`
 }
 
 // SearchResult represents a cache search result
 type SearchResult struct {
-	Vector   *CachedVector `json:"vector"`
-	CacheHit bool          `json:"cache_hit"`
+	Vector          *CachedVector `json:"vector"`
+	CacheHit        bool          `json:"cache_hit"`
+	Approximate     bool          `json:"approximate,omitempty"`      // found by similarity rather than exact key
+	QuerySimilarity float32       `json:"query_similarity,omitempty"` // to the cached prompt; 1 for an exact key hit
 }
 
 // CacheStats represents cache performance statistics
 type CacheStats struct {
 	Hits            int64   `json:"hits"`
 	Misses          int64   `json:"misses"`
-	HitRate         float64 `json:"hit_rate"`
+	ApproximateHits int64   `json:"approximate_hits"` // hits by similarity, included in hits
+	LocalHits       int64   `json:"local_hits"`       // hits served in process without Redis, included in hits
+	LocalEntries    int     `json:"local_entries"`
+	HitRate         float64 `json:"hit_rate"` // across the local tier and Redis
 	TotalKeys       int64   `json:"total_keys"`
 	MemoryUsage     int64   `json:"memory_usage_bytes"`
 	AvgResponseTime float64 `json:"avg_response_time_ms"`
@@ -41,9 +51,23 @@ type Config struct {
 	MaxConnections  int           `yaml:"max_connections" mapstructure:"max_connections"`
 	MinIdleConns    int           `yaml:"min_idle_conns" mapstructure:"min_idle_conns"`
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`
-	DefaultTTL      time.Duration `yaml:"default_ttl" mapstructure:"default_ttl"`
+	DefaultTTL      time.Duration `yaml:"default_ttl" mapstructure:"default_ttl"` // used when no TTL tier matches
+	TTLTiers        []TTLTier     `yaml:"ttl_tiers" mapstructure:"ttl_tiers"`
 	MaxCacheSize    int           `yaml:"max_cache_size" mapstructure:"max_cache_size"`
 	KeyPrefix       string        `yaml:"key_prefix" mapstructure:"key_prefix"`
+
+	// Approximate lookup lets semantically near prompts reuse a cached
+	// verdict. Writers and readers sharing a Redis must use the same mode.
+	Lookup        string  `yaml:"lookup" mapstructure:"lookup"`                 // exact (default), lsh or hnsw
+	MinSimilarity float32 `yaml:"min_similarity" mapstructure:"min_similarity"` // cosine similarity to a cached prompt needed for an approximate hit
+	LSHTables     int     `yaml:"lsh_tables" mapstructure:"lsh_tables"`         // lsh: more tables find more near neighbours
+	LSHBits       int     `yaml:"lsh_bits" mapstructure:"lsh_bits"`             // lsh: hyperplanes per table; more bits make smaller buckets
+	Dimensions    int     `yaml:"dimensions" mapstructure:"dimensions"`         // hnsw: embedding dimensions of the vector index
+
+	// The local tier keeps recent entries in process in front of Redis. An
+	// entry lives at most LocalTTL and never past its Redis expiry.
+	LocalSize int           `yaml:"local_size" mapstructure:"local_size"` // 0 disables the local tier
+	LocalTTL  time.Duration `yaml:"local_ttl" mapstructure:"local_ttl"`
 }
 
 // SearchOptions contains options for cache search
@@ -0,0 +1,219 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/jsonduration"
+)
+
+// Dependency identifies an external system faults can be injected into
+type Dependency string
+
+const (
+	Redis    Dependency = "redis"
+	Postgres Dependency = "postgres"
+	Upstream Dependency = "upstream"
+)
+
+// KnownDependencies lists every dependency with an injection hook
+var KnownDependencies = []Dependency{Redis, Postgres, Upstream}
+
+var (
+	// ErrBlackout is returned for every call to a blacked-out dependency
+	ErrBlackout = errors.New("chaos: dependency blackout")
+	// ErrInjected is returned for calls failed by the configured error rate
+	ErrInjected = errors.New("chaos: injected failure")
+)
+
+// Settings describes the faults currently being injected
+type Settings struct {
+	Enabled   bool          `json:"enabled"`
+	Latency   time.Duration `json:"latency"`    // added before every targeted call
+	Jitter    time.Duration `json:"jitter"`     // random extra latency up to this value
+	ErrorRate float64       `json:"error_rate"` // 0-1 probability a targeted call fails
+	Targets   []Dependency  `json:"targets"`    // dependencies latency/errors apply to; empty means all
+	Blackouts []Dependency  `json:"blackouts"`  // dependencies that always fail
+}
+
+// settingsJSON is Settings with latencies as duration strings such as "250ms"
+type settingsJSON struct {
+	Enabled   bool                  `json:"enabled"`
+	Latency   jsonduration.Duration `json:"latency"`
+	Jitter    jsonduration.Duration `json:"jitter"`
+	ErrorRate float64               `json:"error_rate"`
+	Targets   []Dependency          `json:"targets"`
+	Blackouts []Dependency          `json:"blackouts"`
+}
+
+// MarshalJSON writes latencies as duration strings
+func (s Settings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(settingsJSON{
+		Enabled:   s.Enabled,
+		Latency:   jsonduration.Duration(s.Latency),
+		Jitter:    jsonduration.Duration(s.Jitter),
+		ErrorRate: s.ErrorRate,
+		Targets:   s.Targets,
+		Blackouts: s.Blackouts,
+	})
+}
+
+// UnmarshalJSON reads latencies as duration strings such as "250ms"
+func (s *Settings) UnmarshalJSON(data []byte) error {
+	var raw settingsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = Settings{
+		Enabled:   raw.Enabled,
+		Latency:   time.Duration(raw.Latency),
+		Jitter:    time.Duration(raw.Jitter),
+		ErrorRate: raw.ErrorRate,
+		Targets:   raw.Targets,
+		Blackouts: raw.Blackouts,
+	}
+	return nil
+}
+
+// Validate checks that settings are usable
+func (s Settings) Validate() error {
+	if s.Latency < 0 || s.Jitter < 0 {
+		return fmt.Errorf("invalid chaos latency: %v/%v (must not be negative)", s.Latency, s.Jitter)
+	}
+	if s.ErrorRate < 0 || s.ErrorRate > 1 {
+		return fmt.Errorf("invalid chaos error rate: %f (must be between 0 and 1)", s.ErrorRate)
+	}
+	for _, dep := range append(append([]Dependency{}, s.Targets...), s.Blackouts...) {
+		if !isKnown(dep) {
+			return fmt.Errorf("invalid chaos dependency: %s (must be redis, postgres, or upstream)", dep)
+		}
+	}
+	return nil
+}
+
+// Injector applies fault settings to dependency calls. Settings can be
+// swapped at runtime, e.g. from the admin API.
+type Injector struct {
+	mu       sync.RWMutex
+	settings Settings
+	rng      *rand.Rand
+	rngMu    sync.Mutex
+}
+
+// New creates an injector with the given initial settings
+func New(settings Settings) (*Injector, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	return &Injector{
+		settings: settings,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Settings returns a copy of the current settings
+func (i *Injector) Settings() Settings {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.settings
+}
+
+// Update replaces the current settings
+func (i *Injector) Update(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.settings = settings
+	i.mu.Unlock()
+	return nil
+}
+
+// Inject applies the configured faults for a call to dep. It returns a
+// non-nil error when the call should fail.
+func (i *Injector) Inject(ctx context.Context, dep Dependency) error {
+	settings := i.Settings()
+	if !settings.Enabled {
+		return nil
+	}
+
+	if contains(settings.Blackouts, dep) {
+		return fmt.Errorf("%w: %s", ErrBlackout, dep)
+	}
+
+	if len(settings.Targets) > 0 && !contains(settings.Targets, dep) {
+		return nil
+	}
+
+	if delay := settings.Latency + i.jitter(settings.Jitter); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if settings.ErrorRate > 0 && i.float() < settings.ErrorRate {
+		return fmt.Errorf("%w: %s", ErrInjected, dep)
+	}
+
+	return nil
+}
+
+func (i *Injector) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	i.rngMu.Lock()
+	defer i.rngMu.Unlock()
+	return time.Duration(i.rng.Int63n(int64(max)))
+}
+
+func (i *Injector) float() float64 {
+	i.rngMu.Lock()
+	defer i.rngMu.Unlock()
+	return i.rng.Float64()
+}
+
+// global is the process-wide injector consulted by dependency hooks
+var global atomic.Pointer[Injector]
+
+// SetGlobal installs the injector used by Inject and the dependency hooks
+func SetGlobal(i *Injector) {
+	global.Store(i)
+}
+
+// Global returns the installed injector, or nil when chaos is not configured
+func Global() *Injector {
+	return global.Load()
+}
+
+// Inject applies the global injector's faults for a call to dep. It is a
+// no-op unless an injector has been installed.
+func Inject(ctx context.Context, dep Dependency) error {
+	if i := global.Load(); i != nil {
+		return i.Inject(ctx, dep)
+	}
+	return nil
+}
+
+func contains(deps []Dependency, dep Dependency) bool {
+	for _, d := range deps {
+		if d == dep {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnown(dep Dependency) bool {
+	return contains(KnownDependencies, dep)
+}
@@ -0,0 +1,94 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestInject checks blackouts, targeting, error rates and latency
+func TestInject(t *testing.T) {
+	ctx := context.Background()
+	injector, err := New(Settings{Enabled: true, ErrorRate: 1, Targets: []Dependency{Redis}, Blackouts: []Dependency{Postgres}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := injector.Inject(ctx, Postgres); !errors.Is(err, ErrBlackout) {
+		t.Errorf("blacked-out dependency = %v, want ErrBlackout", err)
+	}
+	if err := injector.Inject(ctx, Redis); !errors.Is(err, ErrInjected) {
+		t.Errorf("targeted dependency = %v, want ErrInjected", err)
+	}
+	if err := injector.Inject(ctx, Upstream); err != nil {
+		t.Errorf("untargeted dependency = %v, want nil", err)
+	}
+
+	settings := injector.Settings()
+	settings.Enabled = false
+	injector.Update(settings)
+	if err := injector.Inject(ctx, Postgres); err != nil {
+		t.Errorf("disabled injector = %v, want nil", err)
+	}
+
+	injector.Update(Settings{Enabled: true, Latency: time.Hour})
+	canceled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := injector.Inject(canceled, Upstream); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("latency past the deadline = %v, want DeadlineExceeded", err)
+	}
+}
+
+// TestSettings checks validation and the duration-string JSON encoding
+func TestSettings(t *testing.T) {
+	invalid := []Settings{
+		{Latency: -time.Second},
+		{ErrorRate: 1.5},
+		{Targets: []Dependency{"kafka"}},
+		{Blackouts: []Dependency{"kafka"}},
+	}
+	for _, settings := range invalid {
+		if _, err := New(settings); err == nil {
+			t.Errorf("New(%+v) accepted invalid settings", settings)
+		}
+	}
+
+	var settings Settings
+	if err := json.Unmarshal([]byte(`{"enabled":true,"latency":"250ms","jitter":"1s","error_rate":0.1,"targets":["redis"]}`), &settings); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if settings.Latency != 250*time.Millisecond || settings.Jitter != time.Second || settings.Targets[0] != Redis {
+		t.Fatalf("decoded settings = %+v", settings)
+	}
+	data, _ := json.Marshal(settings)
+	var roundTrip map[string]interface{}
+	json.Unmarshal(data, &roundTrip)
+	if roundTrip["latency"] != "250ms" {
+		t.Fatalf("encoded latency = %v, want 250ms", roundTrip["latency"])
+	}
+}
+
+// TestRoundTripper checks upstream calls fail through the global injector
+// only while one is installed
+func TestRoundTripper(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	client := &http.Client{Transport: RoundTripper(http.DefaultTransport)}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("request without injector: %v", err)
+	}
+	resp.Body.Close()
+
+	injector, _ := New(Settings{Enabled: true, Blackouts: []Dependency{Upstream}})
+	SetGlobal(injector)
+	defer SetGlobal(nil)
+	if _, err := client.Get(upstream.URL); !errors.Is(err, ErrBlackout) {
+		t.Fatalf("request during blackout = %v, want ErrBlackout", err)
+	}
+}
@@ -0,0 +1,46 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisHook injects faults into every Redis command and pipeline
+type RedisHook struct{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, Inject(ctx, Redis)
+}
+
+func (RedisHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, Inject(ctx, Redis)
+}
+
+func (RedisHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+// RoundTripper wraps an upstream transport with fault injection
+func RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripper{next: next}
+}
+
+type roundTripper struct {
+	next http.RoundTripper
+}
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := Inject(req.Context(), Upstream); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return rt.next.RoundTrip(req)
+}
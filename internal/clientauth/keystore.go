@@ -0,0 +1,150 @@
+// Package clientauth maps Sentinel-issued API keys to the named clients
+// that present them
+package clientauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// keyTable holds the issued keys. Only hashes are stored, so a leaked table
+// does not leak usable keys.
+const keyTable = `
+	CREATE TABLE IF NOT EXISTS client_api_keys (
+		key_sha256 TEXT PRIMARY KEY,
+		client TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW(),
+		revoked_at TIMESTAMP
+	)`
+
+// HashKey returns the hex SHA-256 of key, the form keys are configured and
+// stored in
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyStore serves the unrevoked keys of the client_api_keys table from
+// memory, reloading them periodically. A failed reload keeps the previous
+// keys, so a database outage does not lock out existing clients.
+type KeyStore struct {
+	db       *sqlx.DB
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu     sync.RWMutex
+	keys   map[string]string // key hash -> client
+	loaded bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// OpenKeyStore connects to the key database and loads the keys. The
+// database need not be reachable yet; keys load on the first successful
+// refresh.
+func OpenKeyStore(databaseURL string, interval time.Duration, logger *zap.Logger) (*KeyStore, error) {
+	db, err := sqlx.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open client key database: %w", err)
+	}
+
+	s := &KeyStore{
+		db:       db,
+		interval: interval,
+		logger:   logger,
+		keys:     make(map[string]string),
+		stop:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, keyTable); err != nil {
+		logger.Warn("Failed to create client key table", zap.Error(err))
+	}
+	if err := s.Refresh(ctx); err != nil {
+		logger.Warn("Client keys not loaded; database keys are rejected until a refresh succeeds", zap.Error(err))
+	}
+	return s, nil
+}
+
+// Refresh reloads the unrevoked keys
+func (s *KeyStore) Refresh(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT key_sha256, client FROM client_api_keys WHERE revoked_at IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to load client keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[string]string)
+	for rows.Next() {
+		var hash, client string
+		if err := rows.Scan(&hash, &client); err != nil {
+			return fmt.Errorf("failed to scan client key: %w", err)
+		}
+		keys[strings.ToLower(hash)] = client
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load client keys: %w", err)
+	}
+
+	s.mu.Lock()
+	changed := !s.loaded || len(keys) != len(s.keys)
+	s.keys = keys
+	s.loaded = true
+	s.mu.Unlock()
+
+	if changed {
+		s.logger.Info("Client keys loaded", zap.Int("keys", len(keys)))
+	}
+	return nil
+}
+
+// Lookup returns the client a key hash belongs to
+func (s *KeyStore) Lookup(hash string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.keys[hash]
+	return client, ok
+}
+
+// Start begins periodic refreshes
+func (s *KeyStore) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+			if err := s.Refresh(ctx); err != nil {
+				s.logger.Warn("Client key refresh failed; keeping previous keys", zap.Error(err))
+			}
+			cancel()
+		}
+	}()
+}
+
+// Close stops refreshing and closes the database
+func (s *KeyStore) Close() error {
+	close(s.stop)
+	s.wg.Wait()
+	return s.db.Close()
+}
@@ -0,0 +1,24 @@
+package clientauth
+
+import "testing"
+
+// TestHashKey checks keys hash to the hex SHA-256 operators configure, as
+// printed by `echo -n "$KEY" | sha256sum`
+func TestHashKey(t *testing.T) {
+	const want = "1ef33e7e69eb165e271f839855241e284acb0e81d83590c83ddf0b26e20aee03"
+	if got := HashKey("sk-sentinel-test"); got != want {
+		t.Fatalf("HashKey = %s, want %s", got, want)
+	}
+}
+
+// TestKeyStoreLookup checks only loaded key hashes identify a client
+func TestKeyStoreLookup(t *testing.T) {
+	s := &KeyStore{keys: map[string]string{HashKey("sk-sentinel-test"): "ci-bot"}}
+
+	if client, ok := s.Lookup(HashKey("sk-sentinel-test")); !ok || client != "ci-bot" {
+		t.Fatalf("Lookup = %q, %v, want ci-bot", client, ok)
+	}
+	if client, ok := s.Lookup(HashKey("revoked")); ok {
+		t.Fatalf("unknown key identified client %q", client)
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net/netip"
 	"net/url"
+	"path"
+	"regexp"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
@@ -60,11 +64,593 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid server port: %d", config.Server.Port)
 	}
 
+	if config.Server.MaintenanceRetryAfter < 0 {
+		return fmt.Errorf("invalid server maintenance retry after: %v (must not be negative)", config.Server.MaintenanceRetryAfter)
+	}
+
+	for name, token := range config.Server.AdminTokens {
+		if len(token) < 16 {
+			return fmt.Errorf("invalid admin token for %s: too short (must be at least 16 characters)", name)
+		}
+	}
+
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				return fmt.Errorf("invalid trusted proxy: %s (must be a CIDR or IP address)", proxy)
+			}
+		}
+	}
+
+	if history := config.Server.ConfigHistory; history.Enabled {
+		if len(config.Server.AdminTokens) == 0 {
+			return fmt.Errorf("admin tokens are required when config history is enabled")
+		}
+		if history.Dir == "" {
+			return fmt.Errorf("config history dir is required when config history is enabled")
+		}
+		if len(history.SigningKey) < 32 {
+			return fmt.Errorf("invalid config history signing key: too short (must be at least 32 characters)")
+		}
+		if history.MaxVersions <= 0 {
+			return fmt.Errorf("invalid config history max versions: %d (must be positive)", history.MaxVersions)
+		}
+	}
+
+	// Custom PII rule validation
+	seenRules := make(map[string]bool, len(config.Privacy.CustomRules))
+	for _, rule := range config.Privacy.CustomRules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return fmt.Errorf("invalid custom PII rule: name and pattern are required")
+		}
+		if seenRules[rule.Name] {
+			return fmt.Errorf("invalid custom PII rule: duplicate name %s", rule.Name)
+		}
+		seenRules[rule.Name] = true
+
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid custom PII rule pattern for %s: %v", rule.Name, err)
+		}
+		if rule.Severity != "" && !containsString(PIISeverities, rule.Severity) {
+			return fmt.Errorf("invalid custom PII rule severity for %s: %s (must be one of %s)", rule.Name, rule.Severity, strings.Join(PIISeverities, ", "))
+		}
+	}
+
+	if limits := config.Privacy.RuleLimits; limits.MaxProgramSize < 0 || limits.MaxNesting < 0 || limits.MaxMatchTimePerKB < 0 || limits.SuspendAfter < 0 || limits.SuspendFor < 0 {
+		return fmt.Errorf("invalid privacy rule limits: %+v (must not be negative)", limits)
+	}
+
 	// Security validation
 	if config.Security.Mode != "block" && config.Security.Mode != "log" && config.Security.Mode != "passthrough" {
 		return fmt.Errorf("invalid security mode: %s (must be block, log, or passthrough)", config.Security.Mode)
 	}
 
+	if blockResponse := config.Security.BlockResponse; !containsString([]string{"text", "openai", "anthropic", "auto"}, blockResponse.Format) {
+		return fmt.Errorf("invalid block response format: %s (must be text, openai, anthropic, or auto)", blockResponse.Format)
+	} else if blockResponse.StatusCode < 400 || blockResponse.StatusCode > 599 {
+		return fmt.Errorf("invalid block response status code: %d (must be 400-599)", blockResponse.StatusCode)
+	}
+
+	// Security policy validation
+	for i, policy := range config.Security.Policies {
+		name := policy.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if policy.Mode != "" && policy.Mode != "block" && policy.Mode != "log" && policy.Mode != "passthrough" {
+			return fmt.Errorf("invalid mode for security policy %s: %s (must be block, log, or passthrough)", name, policy.Mode)
+		}
+		if policy.BlockThreshold < 0 || policy.BlockThreshold > 1 {
+			return fmt.Errorf("invalid block threshold for security policy %s: %v (must be between 0 and 1)", name, policy.BlockThreshold)
+		}
+		for _, route := range policy.Upstreams {
+			if !containsString(PipelineRoutes, route) {
+				return fmt.Errorf("invalid upstream for security policy %s: %s (must be one of %s)", name, route, strings.Join(PipelineRoutes, ", "))
+			}
+		}
+		for _, pattern := range policy.Paths {
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), "/"); err != nil || !strings.HasPrefix(pattern, "/") {
+				return fmt.Errorf("invalid path pattern for security policy %s: %q (must be a glob starting with /)", name, pattern)
+			}
+		}
+		for _, hash := range policy.APIKeySHA256 {
+			if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+				return fmt.Errorf("invalid API key hash for security policy %s: must be 64 hex characters", name)
+			}
+		}
+	}
+
+	// Client auth validation
+	if clientAuth := config.Security.ClientAuth; clientAuth.Enabled {
+		if clientAuth.Header == "" {
+			return fmt.Errorf("client auth header is required")
+		}
+		if len(clientAuth.Clients) == 0 && !clientAuth.Database.Enabled {
+			return fmt.Errorf("client auth requires clients or the key database")
+		}
+		seen := make(map[string]bool)
+		for _, client := range clientAuth.Clients {
+			if client.Name == "" {
+				return fmt.Errorf("client auth client name is required")
+			}
+			if seen[client.Name] {
+				return fmt.Errorf("duplicate client auth client: %s", client.Name)
+			}
+			seen[client.Name] = true
+			for _, hash := range client.KeySHA256 {
+				if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+					return fmt.Errorf("invalid key hash for client %s: must be 64 hex characters", client.Name)
+				}
+			}
+			if client.RequestsPerMin < 0 || client.BurstLimit < 0 {
+				return fmt.Errorf("invalid rate limit for client %s: must not be negative", client.Name)
+			}
+		}
+		if database := clientAuth.Database; database.Enabled {
+			if database.DatabaseURL == "" && config.Security.VectorSecurity.Database.DatabaseURL == "" {
+				return fmt.Errorf("client key database requires a database URL")
+			}
+			if database.RefreshInterval <= 0 {
+				return fmt.Errorf("invalid client key database refresh interval: %v (must be positive)", database.RefreshInterval)
+			}
+		}
+	}
+
+	// Conversation validation
+	if config.Security.Conversations.Enabled {
+		policy := config.Security.Conversations.Policy
+		if policy != "none" && policy != "review" {
+			return fmt.Errorf("invalid conversation policy: %s (must be none or review)", policy)
+		}
+		if config.Security.Conversations.TTL < 0 || config.Security.Conversations.MaxConversations < 1 {
+			return fmt.Errorf("invalid conversation tracking: ttl %s, max_conversations %d (max_conversations must be positive)",
+				config.Security.Conversations.TTL, config.Security.Conversations.MaxConversations)
+		}
+	}
+
+	// Anomaly validation
+	if config.Security.Anomaly.Enabled {
+		if config.Security.Anomaly.TightenFactor <= 0 || config.Security.Anomaly.TightenFactor > 1 {
+			return fmt.Errorf("invalid anomaly tighten factor: %f (must be between 0 and 1)", config.Security.Anomaly.TightenFactor)
+		}
+		if config.Security.Anomaly.OffHoursThreshold < 0 || config.Security.Anomaly.OffHoursThreshold > 1 {
+			return fmt.Errorf("invalid anomaly off hours threshold: %f (must be between 0 and 1)", config.Security.Anomaly.OffHoursThreshold)
+		}
+		if config.Security.Anomaly.MaxClients < 1 {
+			return fmt.Errorf("invalid anomaly max clients: %d (must be positive)", config.Security.Anomaly.MaxClients)
+		}
+	}
+
+	// Template fingerprinting validation
+	if templates := config.Security.Templates; templates.Enabled {
+		if templates.TightenFactor <= 0 || templates.TightenFactor > 1 {
+			return fmt.Errorf("invalid template tighten factor: %f (must be between 0 and 1)", templates.TightenFactor)
+		}
+		if templates.MinKnownShare < 0 || templates.MinKnownShare > 1 {
+			return fmt.Errorf("invalid template min known share: %f (must be between 0 and 1)", templates.MinKnownShare)
+		}
+		if templates.MinObservations < 0 || templates.KnownAfter < 1 || templates.MaxTemplates < 1 || templates.MaxClients < 1 {
+			return fmt.Errorf("invalid template tracking: min_observations %d, known_after %d, max_templates %d, max_clients %d (known_after, max_templates and max_clients must be positive)",
+				templates.MinObservations, templates.KnownAfter, templates.MaxTemplates, templates.MaxClients)
+		}
+	}
+
+	// Rate alert validation
+	if ra := config.Security.RateAlerts; ra.Enabled {
+		if ra.Method != "ewma" && ra.Method != "holt_winters" {
+			return fmt.Errorf("invalid rate alert method: %s (must be ewma or holt_winters)", ra.Method)
+		}
+		if ra.Interval <= 0 {
+			return fmt.Errorf("invalid rate alert interval: %v (must be positive)", ra.Interval)
+		}
+		if ra.Window < ra.Interval {
+			return fmt.Errorf("invalid rate alert window: %v (must be at least the interval %v)", ra.Window, ra.Interval)
+		}
+		if ra.Factor <= 1 {
+			return fmt.Errorf("invalid rate alert factor: %f (must be greater than 1)", ra.Factor)
+		}
+		for name, v := range map[string]float64{"alpha": ra.Alpha, "beta": ra.Beta, "gamma": ra.Gamma} {
+			if v <= 0 || v > 1 {
+				return fmt.Errorf("invalid rate alert %s: %f (must be in (0, 1])", name, v)
+			}
+		}
+		if ra.Method == "holt_winters" && ra.SeasonLength < ra.Window {
+			return fmt.Errorf("invalid rate alert season length: %v (must be at least the window %v)", ra.SeasonLength, ra.Window)
+		}
+	}
+
+	// Rate limit validation
+	if config.Security.RateLimit.Enabled {
+		if config.Security.RateLimit.IPv4Prefix < 0 || config.Security.RateLimit.IPv4Prefix > 32 {
+			return fmt.Errorf("invalid rate limit ipv4 prefix: %d (must be between 0 and 32)", config.Security.RateLimit.IPv4Prefix)
+		}
+		if config.Security.RateLimit.IPv6Prefix < 0 || config.Security.RateLimit.IPv6Prefix > 128 {
+			return fmt.Errorf("invalid rate limit ipv6 prefix: %d (must be between 0 and 128)", config.Security.RateLimit.IPv6Prefix)
+		}
+	}
+
+	// Dedup validation
+	if config.Dedup.Enabled {
+		if config.Dedup.Window < 0 {
+			return fmt.Errorf("invalid dedup window: %v (must not be negative)", config.Dedup.Window)
+		}
+		if config.Dedup.MaxResponseBytes <= 0 {
+			return fmt.Errorf("invalid dedup max response bytes: %d (must be positive)", config.Dedup.MaxResponseBytes)
+		}
+	}
+
+	// Response cache validation
+	if rc := config.ResponseCache; rc.Enabled {
+		if rc.TTL <= 0 {
+			return fmt.Errorf("invalid response cache ttl: %v (must be positive)", rc.TTL)
+		}
+		if rc.MaxResponseBytes <= 0 {
+			return fmt.Errorf("invalid response cache max response bytes: %d (must be positive)", rc.MaxResponseBytes)
+		}
+		if rc.RedisURL == "" && config.Security.VectorSecurity.Embedding.RedisURL == "" {
+			return fmt.Errorf("response cache requires redis_url or security.vector_security.embedding.redis_url")
+		}
+		for _, route := range rc.Routes {
+			if !containsString(PipelineRoutes, route) {
+				return fmt.Errorf("invalid response cache route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+			}
+		}
+	}
+
+	// Cost estimation validation
+	if cost := config.Cost; cost.Enabled {
+		if cost.Action != "reject" && cost.Action != "warn" {
+			return fmt.Errorf("invalid cost action: %s (must be reject or warn)", cost.Action)
+		}
+		if cost.MaxRequestUSD < 0 {
+			return fmt.Errorf("invalid cost max request usd: %v (must not be negative)", cost.MaxRequestUSD)
+		}
+		if cost.DefaultCompletionTokens < 0 {
+			return fmt.Errorf("invalid cost default completion tokens: %d (must not be negative)", cost.DefaultCompletionTokens)
+		}
+		if cost.CharsPerToken <= 0 {
+			return fmt.Errorf("invalid cost chars per token: %v (must be positive)", cost.CharsPerToken)
+		}
+		for model, price := range cost.Pricing {
+			if price.Input < 0 || price.Output < 0 {
+				return fmt.Errorf("invalid cost pricing for %s: prices must not be negative", model)
+			}
+		}
+		for _, route := range cost.Routes {
+			if !containsString(PipelineRoutes, route) {
+				return fmt.Errorf("invalid cost route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+			}
+		}
+	}
+
+	// Analyze API validation
+	if config.API.Analyze.Enabled && config.API.Analyze.MaxTextLength <= 0 {
+		return fmt.Errorf("invalid analyze API max text length: %d (must be positive)", config.API.Analyze.MaxTextLength)
+	}
+
+	// Embeddings API validation
+	if embeddingsAPI := config.API.Embeddings; embeddingsAPI.Enabled {
+		if embeddingsAPI.Model == "" {
+			return fmt.Errorf("embeddings API model name is required")
+		}
+		if embeddingsAPI.MaxInputs <= 0 {
+			return fmt.Errorf("invalid embeddings API max inputs: %d (must be positive)", embeddingsAPI.MaxInputs)
+		}
+	}
+
+	// Upstream auth validation
+	for route := range config.Upstream.Auth.Headers {
+		if !containsString(PipelineRoutes, route) {
+			return fmt.Errorf("invalid upstream auth route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+		}
+	}
+
+	// Failover group validation
+	for route, group := range config.Upstream.Failover {
+		if !containsString(PipelineRoutes, route) {
+			return fmt.Errorf("invalid failover route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+		}
+		if route == "bedrock" {
+			return fmt.Errorf("invalid failover route: bedrock (requests are signed for one region)")
+		}
+		if len(group.Targets) < 2 {
+			return fmt.Errorf("invalid failover group for %s: %d targets (must have at least 2)", route, len(group.Targets))
+		}
+		names := make(map[string]bool, len(group.Targets))
+		for _, target := range group.Targets {
+			if target.Name == "" {
+				return fmt.Errorf("invalid failover target for %s: name is required", route)
+			}
+			if names[target.Name] {
+				return fmt.Errorf("invalid failover group for %s: target %s listed more than once", route, target.Name)
+			}
+			names[target.Name] = true
+			if u, err := url.Parse(target.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid failover target URL for %s/%s: %q (must be an http or https URL)", route, target.Name, target.URL)
+			}
+		}
+		if group.MaxErrorRate < 0 || group.MaxErrorRate > 1 {
+			return fmt.Errorf("invalid failover max error rate for %s: %v (must be between 0 and 1)", route, group.MaxErrorRate)
+		}
+		if group.MaxLatency < 0 || group.MinRequests < 0 || group.Window < 0 || group.ProbeInterval < 0 || group.RecoverAfter < 0 {
+			return fmt.Errorf("invalid failover group for %s: thresholds and intervals must not be negative", route)
+		}
+		if group.ProbePath != "" && !strings.HasPrefix(group.ProbePath, "/") {
+			return fmt.Errorf("invalid failover probe path for %s: %q (must start with /)", route, group.ProbePath)
+		}
+	}
+
+	// Upstream retry validation
+	if retry := config.Upstream.Retry; retry.Enabled {
+		if retry.MaxAttempts < 1 {
+			return fmt.Errorf("invalid upstream retry max attempts: %d (must be at least 1)", retry.MaxAttempts)
+		}
+		if retry.InitialBackoff <= 0 || retry.MaxBackoff < retry.InitialBackoff {
+			return fmt.Errorf("invalid upstream retry backoff: %v to %v (must be positive and increasing)", retry.InitialBackoff, retry.MaxBackoff)
+		}
+		for _, status := range retry.Statuses {
+			if status < 400 || status > 599 {
+				return fmt.Errorf("invalid upstream retry status: %d (must be a 4xx or 5xx status)", status)
+			}
+		}
+		for _, route := range retry.Routes {
+			if !containsString(PipelineRoutes, route) {
+				return fmt.Errorf("invalid upstream retry route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+			}
+		}
+	}
+
+	// Circuit breaker validation
+	if breaker := config.Upstream.CircuitBreaker; breaker.Enabled {
+		if breaker.FailureThreshold < 1 {
+			return fmt.Errorf("invalid circuit breaker failure threshold: %d (must be at least 1)", breaker.FailureThreshold)
+		}
+		if breaker.OpenDuration <= 0 {
+			return fmt.Errorf("invalid circuit breaker open duration: %v (must be positive)", breaker.OpenDuration)
+		}
+		if breaker.HalfOpenRequests < 1 {
+			return fmt.Errorf("invalid circuit breaker half-open requests: %d (must be at least 1)", breaker.HalfOpenRequests)
+		}
+		for _, route := range breaker.Routes {
+			if !containsString(PipelineRoutes, route) {
+				return fmt.Errorf("invalid circuit breaker route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+			}
+		}
+	}
+
+	// Upstream path policy validation
+	for route, policy := range config.Upstream.Paths {
+		if !containsString(PipelineRoutes, route) {
+			return fmt.Errorf("invalid upstream path policy route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+		}
+		for _, pattern := range append(append([]string{}, policy.Allow...), policy.Deny...) {
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), "/"); err != nil || !strings.HasPrefix(pattern, "/") {
+				return fmt.Errorf("invalid upstream path pattern for %s: %q (must be a glob starting with /)", route, pattern)
+			}
+		}
+	}
+
+	// Streaming validation
+	if config.Upstream.Streaming.Enabled && config.Upstream.Streaming.MaxDuration <= 0 {
+		return fmt.Errorf("invalid upstream streaming max duration: %v (must be positive)", config.Upstream.Streaming.MaxDuration)
+	}
+	if audit := config.Upstream.Streaming.LatencyAudit; audit.Enabled {
+		if audit.SLO <= 0 {
+			return fmt.Errorf("invalid latency audit slo: %v (must be positive)", audit.SLO)
+		}
+		if audit.Percentile <= 0 || audit.Percentile > 100 {
+			return fmt.Errorf("invalid latency audit percentile: %v (must be between 0 and 100)", audit.Percentile)
+		}
+		if audit.ReportInterval <= 0 {
+			return fmt.Errorf("invalid latency audit report interval: %v (must be positive)", audit.ReportInterval)
+		}
+		if audit.MaxSamples <= 0 {
+			return fmt.Errorf("invalid latency audit max samples: %d (must be positive)", audit.MaxSamples)
+		}
+	}
+
+	// Request annotation validation
+	if annotations := config.Upstream.Annotations; annotations.Enabled {
+		if annotations.Header == "" {
+			return fmt.Errorf("annotation header is required when annotations are enabled")
+		}
+		if len(annotations.SigningKey) < 32 {
+			return fmt.Errorf("invalid annotation signing key: too short (must be at least 32 characters)")
+		}
+		if annotations.TTL <= 0 {
+			return fmt.Errorf("invalid annotation ttl: %v (must be positive)", annotations.TTL)
+		}
+		for _, route := range annotations.Routes {
+			if !containsString(PipelineRoutes, route) {
+				return fmt.Errorf("invalid annotation route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+			}
+		}
+	}
+
+	// Artifact encryption validation
+	if config.Artifacts.Encryption.Enabled && len(config.Artifacts.Encryption.Recipients) == 0 {
+		return fmt.Errorf("invalid artifact encryption: no recipients (must list at least one age public key)")
+	}
+
+	// Metrics validation
+	if config.Metrics.Enabled && !strings.HasPrefix(config.Metrics.Path, "/") {
+		return fmt.Errorf("invalid metrics path: %q (must start with /)", config.Metrics.Path)
+	}
+
+	// ETL label mapping validation
+	for variant, category := range config.ETL.Labels.Mapping {
+		if strings.TrimSpace(category) == "" {
+			return fmt.Errorf("invalid etl label mapping for %s: empty category (must name a category)", variant)
+		}
+	}
+
+	if config.ETL.CopyThreshold < -1 {
+		return fmt.Errorf("invalid etl copy_threshold: %d (must be -1, 0 or a size in bytes)", config.ETL.CopyThreshold)
+	}
+
+	schema := config.ETL.Schema
+	if strings.TrimSpace(schema.Text) == "" {
+		return fmt.Errorf("invalid etl schema: text must name a dataset column")
+	}
+	if strings.TrimSpace(schema.Label) == "" && strings.TrimSpace(schema.LabelText) == "" {
+		return fmt.Errorf("invalid etl schema: label or label_text must name a dataset column")
+	}
+
+	nearDups := config.ETL.NearDuplicates
+	if nearDups.Method != "" && !containsString(NearDuplicateMethods, nearDups.Method) {
+		return fmt.Errorf("invalid etl near_duplicates method: %q (must be empty, %s)", nearDups.Method, strings.Join(NearDuplicateMethods, " or "))
+	}
+	if nearDups.SimHashDistance < 0 || nearDups.SimHashDistance > 10 {
+		return fmt.Errorf("invalid etl near_duplicates simhash_distance: %d (must be 0-10)", nearDups.SimHashDistance)
+	}
+	if nearDups.Similarity <= 0 || nearDups.Similarity > 1 {
+		return fmt.Errorf("invalid etl near_duplicates similarity: %g (must be in (0, 1])", nearDups.Similarity)
+	}
+
+	hf := config.ETL.HuggingFace
+	if hf.Endpoint != "" {
+		if u, err := url.Parse(hf.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid etl huggingface endpoint: %q (must be an http or https URL)", hf.Endpoint)
+		}
+	}
+
+	// Pipeline validation
+	if err := validatePipeline("default", config.Pipeline.Default); err != nil {
+		return err
+	}
+	for route, stages := range config.Pipeline.Routes {
+		if !containsString(PipelineRoutes, route) {
+			return fmt.Errorf("invalid pipeline route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+		}
+		if err := validatePipeline(route, stages); err != nil {
+			return err
+		}
+	}
+
+	// CORS validation
+	if config.CORS.Enabled {
+		if len(config.CORS.AllowedOrigins) == 0 {
+			return fmt.Errorf("invalid cors allowed origins: none (must list at least one origin)")
+		}
+		if config.CORS.AllowCredentials && containsString(config.CORS.AllowedOrigins, "*") {
+			return fmt.Errorf("invalid cors allowed origins: * (must list explicit origins when credentials are allowed)")
+		}
+	}
+
+	// Response header validation
+	for route := range config.ResponseHeaders.Routes {
+		if !containsString(PipelineRoutes, route) {
+			return fmt.Errorf("invalid response headers route: %s (must be one of %s)", route, strings.Join(PipelineRoutes, ", "))
+		}
+	}
+
+	// Event sink validation
+	for _, sink := range config.Events.Sinks {
+		if sink.Name == "" {
+			return fmt.Errorf("invalid event sink: name is required")
+		}
+		if sink.Type != "http" {
+			return fmt.Errorf("invalid event sink type: %s (must be http)", sink.Type)
+		}
+		if sink.URL == "" {
+			return fmt.Errorf("invalid event sink %s: url is required", sink.Name)
+		}
+	}
+
+	// Webhook validation
+	for _, hook := range config.Events.Webhooks {
+		if hook.Name == "" {
+			return fmt.Errorf("invalid webhook: name is required")
+		}
+		if hook.URL == "" {
+			return fmt.Errorf("invalid webhook %s: url is required", hook.Name)
+		}
+		for _, event := range hook.Events {
+			if !containsString(WebhookEvents, event) {
+				return fmt.Errorf("invalid webhook %s event: %s (must be one of %s)", hook.Name, event, strings.Join(WebhookEvents, ", "))
+			}
+		}
+		if hook.MinSeverity != "" && !containsString(PIISeverities, hook.MinSeverity) {
+			return fmt.Errorf("invalid webhook %s min severity: %s (must be one of %s)", hook.Name, hook.MinSeverity, strings.Join(PIISeverities, ", "))
+		}
+		if hook.Retry.MaxAttempts < 0 {
+			return fmt.Errorf("invalid webhook %s max attempts: %d (must be non-negative)", hook.Name, hook.Retry.MaxAttempts)
+		}
+	}
+
+	// Event archive validation
+	if archive := config.Events.Archive; archive.Enabled {
+		if archive.Dir == "" {
+			return fmt.Errorf("event archive dir is required")
+		}
+		if archive.Interval <= 0 {
+			return fmt.Errorf("invalid event archive interval: %v (must be positive)", archive.Interval)
+		}
+		if archive.MaxLocalBytes <= 0 {
+			return fmt.Errorf("invalid event archive max local bytes: %d (must be positive)", archive.MaxLocalBytes)
+		}
+		store := archive.Store
+		if !containsString(ArchiveStoreTypes, store.Type) {
+			return fmt.Errorf("invalid event archive store type: %s (must be one of %s)", store.Type, strings.Join(ArchiveStoreTypes, ", "))
+		}
+		if store.Type == "dir" && store.Path == "" {
+			return fmt.Errorf("event archive dir store requires a path")
+		}
+		if store.Type != "dir" && store.Bucket == "" {
+			return fmt.Errorf("event archive %s store requires a bucket", store.Type)
+		}
+		if store.Type == "gcs" && (store.AccessKeyID == "" || store.SecretAccessKey == "") {
+			return fmt.Errorf("event archive gcs store requires HMAC access_key_id and secret_access_key")
+		}
+		if (store.AccessKeyID == "") != (store.SecretAccessKey == "") {
+			return fmt.Errorf("event archive store access_key_id and secret_access_key must be set together")
+		}
+		if lifecycle := archive.Lifecycle; lifecycle.Apply {
+			if store.Type == "dir" {
+				return fmt.Errorf("event archive lifecycle rules need an s3 or gcs store")
+			}
+			if lifecycle.TransitionAfterDays < 0 || lifecycle.ExpireAfterDays < 0 {
+				return fmt.Errorf("invalid event archive lifecycle: days must not be negative")
+			}
+			if lifecycle.TransitionAfterDays > 0 && lifecycle.StorageClass == "" {
+				return fmt.Errorf("event archive lifecycle transition requires a storage class")
+			}
+			if lifecycle.ExpireAfterDays > 0 && lifecycle.ExpireAfterDays <= lifecycle.TransitionAfterDays {
+				return fmt.Errorf("event archive lifecycle must expire objects after they transition")
+			}
+			if lifecycle.TransitionAfterDays == 0 && lifecycle.ExpireAfterDays == 0 {
+				return fmt.Errorf("event archive lifecycle applies no rule: set transition_after_days or expire_after_days")
+			}
+		}
+	}
+
+	// Verdict export validation
+	verdictExport := config.Events.Archive.VerdictExport
+	if verdictExport.SigningKey != "" && len(verdictExport.SigningKey) < 32 {
+		return fmt.Errorf("invalid verdict export signing key: too short (must be at least 32 characters)")
+	}
+	if verdictExport.HashKey != "" && len(verdictExport.HashKey) < 32 {
+		return fmt.Errorf("invalid verdict export hash key: too short (must be at least 32 characters)")
+	}
+	if verdictExport.HashKey != "" && verdictExport.HashKey == verdictExport.SigningKey {
+		return fmt.Errorf("invalid verdict export hash key: must differ from the signing key")
+	}
+	if verdictExport.RedactMode != "hash" && verdictExport.RedactMode != "remove" {
+		return fmt.Errorf("invalid verdict export redact mode: %s (must be hash or remove)", verdictExport.RedactMode)
+	}
+	for _, column := range verdictExport.Redact {
+		if !containsString(VerdictRedactableColumns, column) {
+			return fmt.Errorf("invalid verdict export redact column: %s (must be one of %s)", column, strings.Join(VerdictRedactableColumns, ", "))
+		}
+	}
+
+	// Chaos validation
+	if config.Chaos.Enabled {
+		if config.Chaos.ErrorRate < 0 || config.Chaos.ErrorRate > 1 {
+			return fmt.Errorf("invalid chaos error rate: %f (must be between 0 and 1)", config.Chaos.ErrorRate)
+		}
+	}
+
 	// Vector security validation
 	if config.Security.VectorSecurity.Enabled {
 		if config.Security.VectorSecurity.BlockThreshold < 0 || config.Security.VectorSecurity.BlockThreshold > 1 {
@@ -75,6 +661,10 @@ func validateConfig(config *Config) error {
 			return fmt.Errorf("invalid vector security max batch size: %d (must be positive)", config.Security.VectorSecurity.MaxBatchSize)
 		}
 
+		if config.Security.VectorSecurity.FastPathMaxLength < 0 {
+			return fmt.Errorf("invalid vector security fast path max length: %d (must not be negative)", config.Security.VectorSecurity.FastPathMaxLength)
+		}
+
 		// Embedding configuration validation
 		if config.Security.VectorSecurity.Embedding.ServiceType == "" {
 			return fmt.Errorf("embedding service type is required")
@@ -115,6 +705,179 @@ func validateConfig(config *Config) error {
 		if config.Security.VectorSecurity.Database.MaxIdleConns <= 0 {
 			return fmt.Errorf("invalid database max idle connections: %d (must be positive)", config.Security.VectorSecurity.Database.MaxIdleConns)
 		}
+
+		// Message selection validation
+		if messages := config.Security.VectorSecurity.Messages; messages.Scope != "last" && messages.Scope != "all" {
+			return fmt.Errorf("invalid messages scope: %s (must be last or all)", messages.Scope)
+		} else if messages.Scope == "all" && len(messages.Roles) == 0 {
+			return fmt.Errorf("message roles are required when messages scope is all")
+		}
+
+		// Embedding input sampling validation
+		if inputs := config.Security.VectorSecurity.EmbeddingInputs; inputs.MaxInputs <= 0 {
+			return fmt.Errorf("invalid embedding inputs max_inputs: %d (must be positive)", inputs.MaxInputs)
+		} else if !containsString(EmbeddingSamplingMethods, inputs.Sampling) {
+			return fmt.Errorf("invalid embedding inputs sampling: %s (must be %s)", inputs.Sampling, strings.Join(EmbeddingSamplingMethods, ", "))
+		}
+
+		// Canary check validation
+		if canary := config.Security.VectorSecurity.CanaryCheck; canary.Enabled {
+			if canary.MinSimilarity <= 0 || canary.MinSimilarity > 1 {
+				return fmt.Errorf("invalid canary min similarity: %f (must be between 0 and 1)", canary.MinSimilarity)
+			}
+
+			if canary.OnMismatch != "warn" && canary.OnMismatch != "refuse" {
+				return fmt.Errorf("invalid canary on_mismatch: %s (must be warn or refuse)", canary.OnMismatch)
+			}
+		}
+
+		// Degradation validation
+		if degradation := config.Security.VectorSecurity.Degradation; degradation.Enabled {
+			if degradation.DegradeCPU < 0 || degradation.DegradeCPU > 1 || degradation.RestoreCPU < 0 || degradation.RestoreCPU > degradation.DegradeCPU {
+				return fmt.Errorf("invalid degradation cpu thresholds: %f/%f (must be 0 <= restore <= degrade <= 1)", degradation.RestoreCPU, degradation.DegradeCPU)
+			}
+
+			if degradation.DegradeQueueDepth < 0 || degradation.RestoreQueueDepth < 0 || degradation.RestoreQueueDepth > degradation.DegradeQueueDepth {
+				return fmt.Errorf("invalid degradation queue depths: %d/%d (must be 0 <= restore <= degrade)", degradation.RestoreQueueDepth, degradation.DegradeQueueDepth)
+			}
+		}
+
+		// Known attack filter validation
+		if knownAttacks := config.Security.VectorSecurity.KnownAttacks; knownAttacks.Enabled {
+			if knownAttacks.FalsePositiveRate <= 0 || knownAttacks.FalsePositiveRate >= 1 {
+				return fmt.Errorf("invalid known attacks false positive rate: %g (must be between 0 and 1)", knownAttacks.FalsePositiveRate)
+			}
+
+			if knownAttacks.RefreshInterval <= 0 {
+				return fmt.Errorf("invalid known attacks refresh interval: %v (must be positive)", knownAttacks.RefreshInterval)
+			}
+		}
+
+		// Pool monitor validation
+		if poolMonitor := config.Security.VectorSecurity.PoolMonitor; poolMonitor.Enabled {
+			if poolMonitor.SampleInterval <= 0 {
+				return fmt.Errorf("invalid pool monitor sample interval: %v (must be positive)", poolMonitor.SampleInterval)
+			}
+
+			if poolMonitor.MaxWaits < 0 || poolMonitor.MaxWaitTime < 0 {
+				return fmt.Errorf("invalid pool monitor thresholds: %d/%v (must not be negative)", poolMonitor.MaxWaits, poolMonitor.MaxWaitTime)
+			}
+		}
+
+		// Corpus snapshot validation
+		if snapshot := config.Security.VectorSecurity.Snapshot; snapshot.Enabled {
+			if snapshot.Path == "" {
+				return fmt.Errorf("corpus snapshot path is required when snapshots are enabled")
+			}
+
+			if snapshot.Interval <= 0 {
+				return fmt.Errorf("invalid corpus snapshot interval: %v (must be positive)", snapshot.Interval)
+			}
+		}
+
+		// Replay validation
+		if replay := config.Security.VectorSecurity.Replay; replay.Enabled && replay.Capacity <= 0 {
+			return fmt.Errorf("invalid replay capacity: %d (must be positive)", replay.Capacity)
+		}
+
+		// Verdict cache validation
+		switch verdictCache := config.Security.VectorSecurity.VerdictCache; verdictCache.Lookup {
+		case "exact":
+		case "lsh", "hnsw":
+			if verdictCache.MinSimilarity <= 0 || verdictCache.MinSimilarity > 1 {
+				return fmt.Errorf("invalid verdict cache min similarity: %f (must be in (0, 1])", verdictCache.MinSimilarity)
+			}
+			if verdictCache.Lookup == "lsh" && (verdictCache.LSHTables <= 0 || verdictCache.LSHBits <= 0 || verdictCache.LSHBits > 64) {
+				return fmt.Errorf("invalid verdict cache LSH parameters: %d tables of %d bits (need at least one table of 1-64 bits)", verdictCache.LSHTables, verdictCache.LSHBits)
+			}
+			if verdictCache.Lookup == "hnsw" && verdictCache.Dimensions <= 0 {
+				return fmt.Errorf("invalid verdict cache dimensions: %d (must be positive)", verdictCache.Dimensions)
+			}
+		default:
+			return fmt.Errorf("invalid verdict cache lookup: %s (must be exact, lsh or hnsw)", verdictCache.Lookup)
+		}
+		if verdictCache := config.Security.VectorSecurity.VerdictCache; verdictCache.LocalSize < 0 || verdictCache.LocalTTL < 0 {
+			return fmt.Errorf("invalid verdict cache local tier: %d entries for %v (must not be negative)", verdictCache.LocalSize, verdictCache.LocalTTL)
+		}
+		for _, tier := range config.Security.VectorSecurity.VerdictCache.TTLTiers {
+			if tier.Name == "" {
+				return fmt.Errorf("verdict cache TTL tier name is required")
+			}
+			if tier.Label != 0 && tier.Label != 1 {
+				return fmt.Errorf("invalid label %d for verdict cache TTL tier %s (must be 0 or 1)", tier.Label, tier.Name)
+			}
+			if tier.MinConfidence < 0 || tier.MinConfidence > 1 {
+				return fmt.Errorf("invalid min confidence %f for verdict cache TTL tier %s (must be in [0, 1])", tier.MinConfidence, tier.Name)
+			}
+			if tier.TTL <= 0 {
+				return fmt.Errorf("invalid TTL %v for verdict cache TTL tier %s (must be positive)", tier.TTL, tier.Name)
+			}
+		}
+		if model := config.Security.VectorSecurity.Embedding.Model; model.LocalCacheSize < 0 || model.LocalCacheTTL < 0 {
+			return fmt.Errorf("invalid embedding local cache: %d entries for %v (must not be negative)", model.LocalCacheSize, model.LocalCacheTTL)
+		}
+		if model := config.Security.VectorSecurity.Embedding.Model; model.Revision != "" && !commitPattern.MatchString(model.Revision) {
+			return fmt.Errorf("invalid model revision: %s (must be a 40-character commit hash)", model.Revision)
+		}
+		model := config.Security.VectorSecurity.Embedding.Model
+		for file, digest := range map[string]string{"model": model.ModelSHA256, "tokenizer": model.TokenizerSHA256, "vocab": model.VocabSHA256} {
+			if _, err := hex.DecodeString(digest); digest != "" && (err != nil || len(digest) != 64) {
+				return fmt.Errorf("invalid %s SHA-256: must be 64 hex characters", file)
+			}
+		}
+
+		// Dimension reduction validation
+		switch reduction := config.Security.VectorSecurity.Reduction; reduction.Method {
+		case "", "none":
+		case "truncate", "pca":
+			if reduction.Dimensions <= 0 || reduction.Dimensions >= 384 {
+				return fmt.Errorf("invalid reduction dimensions: %d (must be between 1 and 383)", reduction.Dimensions)
+			}
+
+			if reduction.Method == "pca" && reduction.PCAPath == "" {
+				return fmt.Errorf("PCA reduction requires a pca_path")
+			}
+		default:
+			return fmt.Errorf("invalid reduction method: %s (must be none, truncate or pca)", reduction.Method)
+		}
+
+		// Vector index validation
+		switch index := config.Security.VectorSecurity.Index; index.Type {
+		case "ivfflat":
+			if index.Lists < 0 || index.Lists > 32768 {
+				return fmt.Errorf("invalid ivfflat lists: %d (must be between 0 and 32768)", index.Lists)
+			}
+			if index.Probes < 0 {
+				return fmt.Errorf("invalid ivfflat probes: %d (must not be negative)", index.Probes)
+			}
+		case "hnsw":
+			if index.M < 2 || index.M > 100 {
+				return fmt.Errorf("invalid hnsw m: %d (must be between 2 and 100)", index.M)
+			}
+			if index.EfConstruction < 2*index.M || index.EfConstruction > 1000 {
+				return fmt.Errorf("invalid hnsw ef_construction: %d (must be between 2*m and 1000)", index.EfConstruction)
+			}
+			if index.EfSearch < 0 || index.EfSearch > 1000 {
+				return fmt.Errorf("invalid hnsw ef_search: %d (must be between 0 and 1000)", index.EfSearch)
+			}
+		default:
+			return fmt.Errorf("invalid vector index type: %s (must be ivfflat or hnsw)", index.Type)
+		}
+
+		// Dual-write migration validation
+		if migration := config.Security.VectorSecurity.Migration; migration.Enabled {
+			if migration.Secondary.DatabaseURL == "" {
+				return fmt.Errorf("secondary database URL is required when migration is enabled")
+			}
+
+			if migration.Secondary.MaxOpenConns <= 0 {
+				return fmt.Errorf("invalid secondary database max open connections: %d (must be positive)", migration.Secondary.MaxOpenConns)
+			}
+
+			if migration.CompareSampleRate < 0 || migration.CompareSampleRate > 1 {
+				return fmt.Errorf("invalid migration compare sample rate: %f (must be between 0 and 1)", migration.CompareSampleRate)
+			}
+		}
 	}
 
 	// Rate limiting validation
@@ -127,9 +890,23 @@ func validateConfig(config *Config) error {
 			return fmt.Errorf("invalid rate limit max request size: %d (must be positive)", config.Security.RateLimit.MaxRequestSize)
 		}
 
+		if status := config.Security.RateLimit.OversizeStatusCode; status < 400 || status > 599 {
+			return fmt.Errorf("invalid rate limit oversize status code: %d (must be 400-599)", status)
+		}
+
 		if config.Security.RateLimit.BurstLimit <= 0 {
 			return fmt.Errorf("invalid rate limit burst limit: %d (must be positive)", config.Security.RateLimit.BurstLimit)
 		}
+
+		switch config.Security.RateLimit.Backend {
+		case "memory":
+		case "redis":
+			if config.Security.RateLimit.RedisURL == "" && config.Security.VectorSecurity.Embedding.RedisURL == "" {
+				return fmt.Errorf("rate limit backend redis requires redis_url or security.vector_security.embedding.redis_url")
+			}
+		default:
+			return fmt.Errorf("invalid rate limit backend: %s (must be memory or redis)", config.Security.RateLimit.Backend)
+		}
 	}
 
 	// Logging validation
@@ -154,6 +931,10 @@ func validateConfig(config *Config) error {
 		if config.WebSocket.WriteBufferSize <= 0 {
 			return fmt.Errorf("invalid websocket write buffer size: %d (must be positive)", config.WebSocket.WriteBufferSize)
 		}
+
+		if config.WebSocket.CompressionLevel < -2 || config.WebSocket.CompressionLevel > 9 {
+			return fmt.Errorf("invalid websocket compression level: %d (must be between -2 and 9)", config.WebSocket.CompressionLevel)
+		}
 	}
 
 	// Upstream URLs validation
@@ -166,22 +947,74 @@ func validateConfig(config *Config) error {
 	if err := validateURL(config.Upstream.Ollama, "ollama"); err != nil {
 		return err
 	}
+	if err := validateURL(config.Upstream.Gemini, "gemini"); err != nil {
+		return err
+	}
+	if err := validateURL(config.Upstream.Vertex, "vertex"); err != nil {
+		return err
+	}
+	if err := validateEgressProxy("default", config.Upstream.Egress.Default); err != nil {
+		return err
+	}
+	for route, proxy := range config.Upstream.Egress.Routes {
+		if !containsString(PipelineRoutes, route) && route != EgressDownloadRoute {
+			return fmt.Errorf("invalid egress proxy route: %s (must be one of %s, %s)", route, strings.Join(PipelineRoutes, ", "), EgressDownloadRoute)
+		}
+		if err := validateEgressProxy(route, proxy); err != nil {
+			return err
+		}
+	}
+	if err := validateDNS(config.Upstream.DNS); err != nil {
+		return err
+	}
+	if bedrock := config.Upstream.Bedrock; bedrock.Region != "" {
+		if !awsRegionPattern.MatchString(bedrock.Region) {
+			return fmt.Errorf("invalid bedrock region: %s (e.g. us-east-1)", bedrock.Region)
+		}
+		// Requests are signed with the proxy's own AWS credentials, so an
+		// open route would lend them to anyone who can reach the proxy
+		if !config.Security.ClientAuth.Enabled {
+			return fmt.Errorf("bedrock route requires security.client_auth.enabled (requests are signed with the proxy's AWS credentials)")
+		}
+		if bedrock.Endpoint != "" {
+			if err := validateURL(bedrock.Endpoint, "bedrock"); err != nil {
+				return err
+			}
+		}
+	}
+	if azure := config.Upstream.AzureOpenAI; azure.Endpoint != "" {
+		if err := validateURL(azure.Endpoint, "azure-openai"); err != nil {
+			return err
+		}
+		if azure.APIVersion == "" {
+			return fmt.Errorf("azure openai api version is required when an endpoint is configured")
+		}
+		if !containsString(AzureAuthModes, azure.Auth) {
+			return fmt.Errorf("invalid azure openai auth: %s (must be one of %s)", azure.Auth, strings.Join(AzureAuthModes, ", "))
+		}
+		for model, deployment := range azure.Deployments {
+			if deployment == "" || strings.Contains(deployment, "/") {
+				return fmt.Errorf("invalid azure openai deployment for %s: %q (must be a non-empty name without /)", model, deployment)
+			}
+		}
+	}
 
 	return nil
 }
 
-// Watch starts watching the configuration file for changes
-func Watch(config *Config, callback func(*Config)) error {
+// Watch starts watching the configuration file for changes. Changes that
+// fail to parse or validate are reported to onError and otherwise ignored.
+func Watch(config *Config, callback func(*Config), onError func(error)) error {
 	viper.WatchConfig()
 	viper.OnConfigChange(func(e fsnotify.Event) {
-		newConfig := &Config{}
+		newConfig := GetDefaults()
 		if err := viper.Unmarshal(newConfig); err != nil {
-			// Log error but don't crash
+			onError(fmt.Errorf("failed to unmarshal config: %w", err))
 			return
 		}
 
 		if err := validateConfig(newConfig); err != nil {
-			// Log error but don't crash
+			onError(fmt.Errorf("invalid configuration: %w", err))
 			return
 		}
 
@@ -198,3 +1031,92 @@ func validateURL(urlStr string, name string) error {
 	}
 	return nil
 }
+
+// awsRegionPattern matches AWS region names such as us-east-1 or us-gov-west-1
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// commitPattern matches a full git commit hash
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// validateEgressProxy checks an egress proxy URL and its no-proxy list
+func validateEgressProxy(name string, proxy EgressProxyConfig) error {
+	if proxy.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(proxy.URL)
+	if err != nil || u.Host == "" || !containsString(EgressSchemes, u.Scheme) {
+		return fmt.Errorf("invalid egress proxy URL for %s: %s (must be %s://host:port)", name, proxy.URL, strings.Join(EgressSchemes, "|"))
+	}
+	if u.User != nil && proxy.Username != "" {
+		return fmt.Errorf("invalid egress proxy for %s: credentials set in both the URL and username", name)
+	}
+	for _, entry := range proxy.NoProxy {
+		if strings.TrimSpace(entry) == "" || strings.Contains(entry, ",") {
+			return fmt.Errorf("invalid egress no_proxy entry for %s: %q", name, entry)
+		}
+	}
+	return nil
+}
+
+// validateDNS checks DNS cache TTLs and that pins and allowlists parse
+func validateDNS(dns DNSConfig) error {
+	if dns.CacheEnabled {
+		if dns.MinTTL < 0 || dns.MaxTTL < dns.MinTTL {
+			return fmt.Errorf("invalid dns ttls: min %v, max %v (must be 0 <= min_ttl <= max_ttl)", dns.MinTTL, dns.MaxTTL)
+		}
+		if dns.ServeStale < 0 {
+			return fmt.Errorf("invalid dns serve stale: %v (must be non-negative)", dns.ServeStale)
+		}
+	}
+	seen := make(map[string]bool, len(dns.Hosts))
+	for _, host := range dns.Hosts {
+		name := strings.ToLower(strings.TrimSuffix(host.Host, "."))
+		if name == "" {
+			return fmt.Errorf("invalid dns host: host is required")
+		}
+		if seen[name] {
+			return fmt.Errorf("invalid dns host: %s listed more than once", host.Host)
+		}
+		seen[name] = true
+		if len(host.Pin) == 0 && len(host.Allow) == 0 {
+			return fmt.Errorf("invalid dns host %s: pin or allow is required", host.Host)
+		}
+		for _, ip := range host.Pin {
+			if _, err := netip.ParseAddr(ip); err != nil {
+				return fmt.Errorf("invalid dns pin for %s: %s (must be an IP address)", host.Host, ip)
+			}
+		}
+		for _, entry := range host.Allow {
+			if _, err := netip.ParsePrefix(entry); err != nil {
+				if _, err := netip.ParseAddr(entry); err != nil {
+					return fmt.Errorf("invalid dns allowlist entry for %s: %s (must be an IP address or CIDR)", host.Host, entry)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validatePipeline checks that a pipeline names known stages at most once
+func validatePipeline(route string, stages []string) error {
+	seen := make(map[string]bool, len(stages))
+	for _, stage := range stages {
+		if !containsString(PipelineStages, stage) {
+			return fmt.Errorf("invalid pipeline stage for %s: %s (must be one of %s)", route, stage, strings.Join(PipelineStages, ", "))
+		}
+		if seen[stage] {
+			return fmt.Errorf("invalid pipeline for %s: %s listed more than once", route, stage)
+		}
+		seen[stage] = true
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
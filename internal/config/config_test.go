@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDefaultConfigLoads checks the shipped configuration parses to the
+// same verdict TTL tiers as the built-in defaults
+func TestDefaultConfigLoads(t *testing.T) {
+	cfg, err := Load("../../configs/default.yaml")
+	if err != nil {
+		t.Fatalf("load default.yaml: %v", err)
+	}
+	want := GetDefaults().Security.VectorSecurity.VerdictCache.TTLTiers
+	if got := cfg.Security.VectorSecurity.VerdictCache.TTLTiers; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ttl_tiers = %+v, want %+v", got, want)
+	}
+}
+
+// TestValidateVerdictTTLTiers checks malformed TTL tiers are rejected
+func TestValidateVerdictTTLTiers(t *testing.T) {
+	tests := []struct {
+		name string
+		tier VerdictTTLTier
+		want string
+	}{
+		{"missing name", VerdictTTLTier{Label: 1, TTL: time.Hour}, "name is required"},
+		{"bad label", VerdictTTLTier{Name: "x", Label: 2, TTL: time.Hour}, "invalid label"},
+		{"bad confidence", VerdictTTLTier{Name: "x", Label: 1, MinConfidence: 1.5, TTL: time.Hour}, "invalid min confidence"},
+		{"zero ttl", VerdictTTLTier{Name: "x", Label: 0}, "invalid TTL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := GetDefaults()
+			cfg.Security.VectorSecurity.VerdictCache.TTLTiers = []VerdictTTLTier{tt.tier}
+			err := validateConfig(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("validateConfig = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateBedrockRequiresClientAuth checks the Bedrock route, signed with
+// the proxy's own AWS credentials, is refused without client authentication
+func TestValidateBedrockRequiresClientAuth(t *testing.T) {
+	cfg := GetDefaults()
+	cfg.Upstream.Bedrock.Region = "us-east-1"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "client_auth") {
+		t.Fatalf("validateConfig = %v, want the bedrock route refused without client auth", err)
+	}
+
+	cfg.Security.ClientAuth.Enabled = true
+	cfg.Security.ClientAuth.Clients = []ClientConfig{{Name: "app", KeySHA256: []string{strings.Repeat("ab", 32)}}}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig with client auth: %v", err)
+	}
+}
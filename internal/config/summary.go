@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// redacted replaces secret values in configuration summaries
+const redacted = "[REDACTED]"
+
+// sensitiveKeyParts mark a field, header or query parameter as secret
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "authorization", "credential", "cookie"}
+
+// sensitiveKeyNames mark a key as secret when they are its last word, so
+// signing_key is secret while key_prefix is not
+var sensitiveKeyNames = []string{"key", "signing_key"}
+
+// Summary returns the effective configuration (defaults, file and
+// environment overrides resolved) as a nested map with secrets redacted,
+// along with where it came from and which backends are active. It is
+// intended for startup logs.
+func Summary(cfg *Config) map[string]interface{} {
+	return map[string]interface{}{
+		"sources": map[string]interface{}{
+			"file":          configFileUsed(),
+			"env_overrides": envOverrides(),
+		},
+		"active":    activeModes(cfg),
+		"effective": summarizeValue("", reflect.ValueOf(*cfg), false),
+	}
+}
+
+// activeModes describes the backends and modes the configuration selects
+func activeModes(cfg *Config) map[string]interface{} {
+	vs := cfg.Security.VectorSecurity
+
+	store := "none"
+	cache := "none"
+	embedding := "none"
+	if vs.Enabled {
+		embedding = vs.Embedding.ServiceType
+		store = "postgres"
+		if vs.Migration.Enabled {
+			store = "postgres (dual-write)"
+			if vs.Migration.ReadFromSecondary {
+				store = "postgres (dual-write, reading secondary)"
+			}
+		}
+		if vs.Embedding.RedisEnabled {
+			cache = "redis"
+		}
+	}
+
+	var sinks []string
+	for _, sink := range cfg.Events.Sinks {
+		sinks = append(sinks, sink.Name+" ("+sink.Type+")")
+	}
+	var webhooks []string
+	for _, hook := range cfg.Events.Webhooks {
+		webhooks = append(webhooks, hook.Name)
+	}
+
+	return map[string]interface{}{
+		"security_mode":       cfg.Security.Mode,
+		"vector_security":     vs.Enabled,
+		"embedding_backend":   embedding,
+		"vector_store":        store,
+		"embedding_cache":     cache,
+		"hybrid_search":       vs.Enabled && vs.HybridSearch && vs.Embedding.ServiceType == "ml",
+		"concurrent_analysis": vs.Enabled && vs.ConcurrentAnalysis,
+		"rate_limiting":       cfg.Security.RateLimit.Enabled,
+		"anomaly_detection":   cfg.Security.Anomaly.Enabled,
+		"rate_alerts":         cfg.Security.RateAlerts.Enabled,
+		"conversations":       cfg.Security.Conversations.Enabled,
+		"read_only":           cfg.Server.ReadOnly,
+		"maintenance":         cfg.Server.Maintenance,
+		"chaos":               cfg.Chaos.Enabled,
+		"event_sinks":         sinks,
+		"webhooks":            webhooks,
+	}
+}
+
+// configFileUsed returns the file viper read, or "defaults" when none was found
+func configFileUsed() string {
+	if file := viper.ConfigFileUsed(); file != "" {
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return "defaults"
+}
+
+// envOverrides lists the SENTINEL_ environment variables that are set. Only
+// names are reported; values may be secrets.
+func envOverrides() []string {
+	var names []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "SENTINEL_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summarizeValue converts a config value into log-friendly form. A value
+// is redacted when secret is set, which its field's secret:"true" tag or a
+// sensitive key sets for everything beneath it, including every entry of a
+// map.
+func summarizeValue(key string, v reflect.Value, secret bool) interface{} {
+	secret = secret || isSensitiveKey(key)
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := yamlName(field)
+			out[name] = summarizeValue(name, v.Field(i), secret || field.Tag.Get("secret") == "true")
+		}
+		return out
+
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			name := fmt.Sprint(iter.Key().Interface())
+			out[name] = summarizeValue(name, iter.Value(), secret)
+		}
+		return out
+
+	case reflect.Slice:
+		out := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out[i] = summarizeValue(key, v.Index(i), secret)
+		}
+		return out
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return summarizeValue(key, v.Elem(), secret)
+
+	case reflect.String:
+		if secret && v.String() != "" {
+			return redacted
+		}
+		if strings.Contains(strings.ToLower(key), "url") {
+			return redactURL(v.String())
+		}
+		return v.String()
+
+	default:
+		return v.Interface()
+	}
+}
+
+// redactURL masks credentials embedded in a connection URL, in the same
+// style as url.URL.Redacted
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return raw
+	}
+
+	if query := u.Query(); len(query) > 0 {
+		for name := range query {
+			if isSensitiveKey(name) {
+				query.Set(name, "xxxxx")
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	for _, name := range sensitiveKeyNames {
+		if key == name || strings.HasSuffix(key, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+func yamlName(field reflect.StructField) string {
+	if tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	return field.Name
+}
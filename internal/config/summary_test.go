@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// secretMarker prefixes the values planted in secret fields
+const secretMarker = "planted-secret-"
+
+// TestSummaryRedactsSecrets plants values in secret fields, found by name
+// and by secret:"true" tag, and fails if any of them reaches the summary
+func TestSummaryRedactsSecrets(t *testing.T) {
+	cfg := GetDefaults()
+	cfg.Server.AdminTokens = map[string]string{"alice": secretMarker + "admin-token"}
+	cfg.Server.ConfigHistory.SigningKey = secretMarker + "history-key"
+	cfg.Upstream.Annotations.SigningKey = secretMarker + "annotation-key"
+	cfg.Events.Archive.VerdictExport.SigningKey = secretMarker + "verdict-key"
+	cfg.Events.Sinks = []EventSinkConfig{{
+		Name:    "siem",
+		URL:     "https://user:" + secretMarker + "sink-password@siem.example.com/ingest",
+		Headers: map[string]string{"Authorization": "Bearer " + secretMarker + "sink-header"},
+	}}
+	cfg.Security.VectorSecurity.Embedding.RedisURL = "redis://:" + secretMarker + "redis-password@localhost:6379"
+
+	planted := plantTaggedSecrets(t, reflect.ValueOf(cfg).Elem(), "")
+	if planted == 0 {
+		t.Fatal("no fields are tagged secret")
+	}
+
+	data, err := json.Marshal(Summary(cfg))
+	if err != nil {
+		t.Fatalf("marshal summary: %v", err)
+	}
+	summary := string(data)
+	for i := strings.Index(summary, secretMarker); i >= 0; i = strings.Index(summary, secretMarker) {
+		end := min(i+len(secretMarker)+32, len(summary))
+		t.Errorf("secret in summary: %s", summary[i:end])
+		summary = summary[i+len(secretMarker):]
+	}
+}
+
+// plantTaggedSecrets sets every field tagged secret:"true" beneath v to a
+// marked value, adding an element to empty slices of structs to reach
+// their fields, and returns the number of fields set
+func plantTaggedSecrets(t *testing.T, v reflect.Value, path string) int {
+	t.Helper()
+	planted := 0
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		name := path + "." + field.Name
+		if field.Tag.Get("secret") == "true" {
+			plantSecret(t, value, name)
+			planted++
+			continue
+		}
+		switch value.Kind() {
+		case reflect.Struct:
+			planted += plantTaggedSecrets(t, value, name)
+		case reflect.Slice:
+			if value.Type().Elem().Kind() != reflect.Struct {
+				continue
+			}
+			if value.Len() == 0 {
+				value.Set(reflect.Append(value, reflect.New(value.Type().Elem()).Elem()))
+			}
+			for j := 0; j < value.Len(); j++ {
+				planted += plantTaggedSecrets(t, value.Index(j), fmt.Sprintf("%s[%d]", name, j))
+			}
+		}
+	}
+	return planted
+}
+
+func plantSecret(t *testing.T, v reflect.Value, path string) {
+	t.Helper()
+	secret := secretMarker + path
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(secret)
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.String:
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(reflect.ValueOf("name").Convert(v.Type().Key()), reflect.ValueOf(secret))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		v.Set(reflect.ValueOf([]string{secret}).Convert(v.Type()))
+	default:
+		t.Fatalf("%s: cannot plant a secret in a %s", path, v.Type())
+	}
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"admin_tokens", true},
+		{"signing_key", true},
+		{"secret_access_key", true},
+		{"key", true},
+		{"X-API-Key", true},
+		{"Authorization", true},
+		{"key_prefix", false},
+		{"keyspace", false},
+		{"monkey", false},
+		{"header", false},
+	}
+	for _, tt := range tests {
+		if got := isSensitiveKey(tt.key); got != tt.want {
+			t.Errorf("isSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
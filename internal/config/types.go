@@ -10,6 +10,19 @@ type Config struct {
 	Logging   LoggingConfig   `yaml:"logging" mapstructure:"logging"`
 	Upstream  UpstreamConfig  `yaml:"upstream" mapstructure:"upstream"`
 	WebSocket WebSocketConfig `yaml:"websocket" mapstructure:"websocket"`
+	Chaos     ChaosConfig     `yaml:"chaos" mapstructure:"chaos"`
+	Events    EventsConfig    `yaml:"events" mapstructure:"events"`
+	Pipeline  PipelineConfig  `yaml:"pipeline" mapstructure:"pipeline"`
+	Dedup     DedupConfig     `yaml:"dedup" mapstructure:"dedup"`
+	API       APIConfig       `yaml:"api" mapstructure:"api"`
+	ETL       ETLConfig       `yaml:"etl" mapstructure:"etl"`
+	Metrics   MetricsConfig   `yaml:"metrics" mapstructure:"metrics"`
+	Artifacts ArtifactsConfig `yaml:"artifacts" mapstructure:"artifacts"`
+
+	ResponseHeaders ResponseHeadersConfig `yaml:"response_headers" mapstructure:"response_headers"`
+	ResponseCache   ResponseCacheConfig   `yaml:"response_cache" mapstructure:"response_cache"`
+	Cost            CostConfig            `yaml:"cost" mapstructure:"cost"`
+	CORS            CORSConfig            `yaml:"cors" mapstructure:"cors"`
 }
 
 // ServerConfig contains HTTP server configuration
@@ -18,6 +31,33 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout" mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout"`
+
+	// Operational modes, switchable at runtime via /admin/mode
+	ReadOnly              bool          `yaml:"read_only" mapstructure:"read_only"`     // reject corpus and config mutations
+	Maintenance           bool          `yaml:"maintenance" mapstructure:"maintenance"` // reject proxy traffic with 503
+	MaintenanceRetryAfter time.Duration `yaml:"maintenance_retry_after" mapstructure:"maintenance_retry_after"`
+
+	// Bearer tokens for /admin endpoints, keyed by the admin they identify in
+	// audit logs. Admin endpoints reject every request when empty, and the
+	// rule management API is not mounted.
+	AdminTokens map[string]string `yaml:"admin_tokens" mapstructure:"admin_tokens" secret:"true"`
+
+	// Proxies whose X-Forwarded-For and X-Real-IP headers are believed, as
+	// CIDRs or addresses. Empty trusts no forwarding headers, so clients are
+	// identified by their connection address.
+	TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
+
+	ConfigHistory ConfigHistoryConfig `yaml:"config_history" mapstructure:"config_history"`
+}
+
+// ConfigHistoryConfig keeps a signed, versioned snapshot of the runtime
+// configuration after every admin change, so any version can be restored
+// through /admin/api/config/versions
+type ConfigHistoryConfig struct {
+	Enabled     bool   `yaml:"enabled" mapstructure:"enabled"`
+	Dir         string `yaml:"dir" mapstructure:"dir"`                               // one JSON file per version
+	SigningKey  string `yaml:"signing_key" mapstructure:"signing_key" secret:"true"` // HMAC-SHA256 key; tampered snapshots cannot be restored
+	MaxVersions int    `yaml:"max_versions" mapstructure:"max_versions"`
 }
 
 // PrivacyConfig contains PII detection and masking configuration
@@ -33,32 +73,350 @@ type PrivacyConfig struct {
 		Headers              []string `yaml:"headers" mapstructure:"headers"`
 		PreserveUpstreamAuth bool     `yaml:"preserve_upstream_auth" mapstructure:"preserve_upstream_auth"`
 	} `yaml:"header_scrubbing" mapstructure:"header_scrubbing"`
+	CustomRules []CustomPIIRule  `yaml:"custom_rules" mapstructure:"custom_rules"` // reloaded when the config file changes
+	RuleLimits  RuleLimitsConfig `yaml:"rule_limits" mapstructure:"rule_limits"`
 }
 
+// RuleLimitsConfig bounds the cost of custom PII rules (config file and
+// admin API). Patterns are linted when they are loaded; at run time a rule
+// that keeps exceeding its time budget is suspended, and texts it would run
+// on are redacted whole until it resumes. The proxy refuses such requests
+// with a 503 rather than forward the placeholder.
+type RuleLimitsConfig struct {
+	MaxProgramSize    int           `yaml:"max_program_size" mapstructure:"max_program_size"`           // compiled instructions; larger patterns are rejected
+	MaxNesting        int           `yaml:"max_nesting" mapstructure:"max_nesting"`                     // nested repetitions, e.g. (a+)+ is 2; deeper patterns are rejected
+	Strict            bool          `yaml:"strict" mapstructure:"strict"`                               // reject patterns with lint warnings instead of loading them
+	MaxMatchTimePerKB time.Duration `yaml:"max_match_time_per_kb" mapstructure:"max_match_time_per_kb"` // per rule, per KB of text (at least 1); 0 disables suspension
+	SuspendAfter      int           `yaml:"suspend_after" mapstructure:"suspend_after"`                 // consecutive overruns before a rule is suspended
+	SuspendFor        time.Duration `yaml:"suspend_for" mapstructure:"suspend_for"`
+}
+
+// CustomPIIRule is a user-defined regex detector, applied after the built-ins
+type CustomPIIRule struct {
+	Name        string `yaml:"name" mapstructure:"name"`
+	Pattern     string `yaml:"pattern" mapstructure:"pattern"`         // Go regexp syntax
+	Replacement string `yaml:"replacement" mapstructure:"replacement"` // may reference groups ($1); defaults to [NAME_MASKED]
+	Severity    string `yaml:"severity" mapstructure:"severity"`       // low, medium, high, critical
+}
+
+// PIISeverities are the accepted custom rule severities
+var PIISeverities = []string{"low", "medium", "high", "critical"}
+
 // SecurityConfig contains basic security configuration
 type SecurityConfig struct {
 	Enabled        bool                 `yaml:"enabled" mapstructure:"enabled"`
 	Mode           string               `yaml:"mode" mapstructure:"mode"` // block, log, or passthrough
 	RateLimit      RateLimitConfig      `yaml:"rate_limit" mapstructure:"rate_limit"`
 	VectorSecurity VectorSecurityConfig `yaml:"vector_security" mapstructure:"vector_security"`
+	Conversations  ConversationConfig   `yaml:"conversations" mapstructure:"conversations"`
+	Anomaly        AnomalyConfig        `yaml:"anomaly" mapstructure:"anomaly"`
+	Templates      TemplateConfig       `yaml:"templates" mapstructure:"templates"`
+	RateAlerts     RateAlertConfig      `yaml:"rate_alerts" mapstructure:"rate_alerts"`
+	Policies       []SecurityPolicy     `yaml:"policies" mapstructure:"policies"` // first match overrides mode, threshold and detectors
+	BlockResponse  BlockResponseConfig  `yaml:"block_response" mapstructure:"block_response"`
+	ClientAuth     ClientAuthConfig     `yaml:"client_auth" mapstructure:"client_auth"`
+}
+
+// ClientAuthConfig requires proxy clients to present a Sentinel-issued API
+// key. Each key identifies a named client, which rate limiting, policy
+// selection and audit logs use in place of the client's IP or provider key.
+type ClientAuthConfig struct {
+	Enabled  bool                    `yaml:"enabled" mapstructure:"enabled"`
+	Header   string                  `yaml:"header" mapstructure:"header"` // carries the key; removed before forwarding
+	Clients  []ClientConfig          `yaml:"clients" mapstructure:"clients"`
+	Database ClientKeyDatabaseConfig `yaml:"database" mapstructure:"database"`
+}
+
+// ClientConfig is a named proxy client and the keys it authenticates with
+type ClientConfig struct {
+	Name           string   `yaml:"name" mapstructure:"name"`
+	KeySHA256      []string `yaml:"key_sha256" mapstructure:"key_sha256"`             // hex SHA-256 of the client's keys
+	RequestsPerMin int      `yaml:"requests_per_min" mapstructure:"requests_per_min"` // 0 keeps rate_limit.requests_per_min
+	BurstLimit     int      `yaml:"burst_limit" mapstructure:"burst_limit"`           // 0 keeps rate_limit.burst_limit
+}
+
+// ClientKeyDatabaseConfig loads client keys from the client_api_keys table,
+// so keys can be issued and revoked without a config change
+type ClientKeyDatabaseConfig struct {
+	Enabled         bool          `yaml:"enabled" mapstructure:"enabled"`
+	DatabaseURL     string        `yaml:"database_url" mapstructure:"database_url"` // empty uses vector_security.database.database_url
+	RefreshInterval time.Duration `yaml:"refresh_interval" mapstructure:"refresh_interval"`
+}
+
+// BlockResponseConfig shapes the response sent for a blocked request so
+// client SDKs can parse it like an upstream error
+type BlockResponseConfig struct {
+	Format     string `yaml:"format" mapstructure:"format"`           // "text", "openai", "anthropic", or "auto" (follow the route's provider)
+	StatusCode int    `yaml:"status_code" mapstructure:"status_code"` // HTTP status of the response
+}
+
+// SecurityPolicy overrides the global security settings for matching
+// requests. Every non-empty criterion must match; an empty one matches all.
+type SecurityPolicy struct {
+	Name           string   `yaml:"name" mapstructure:"name"`
+	Upstreams      []string `yaml:"upstreams" mapstructure:"upstreams"`             // provider routes (see PipelineRoutes)
+	Paths          []string `yaml:"paths" mapstructure:"paths"`                     // globs on the upstream path; trailing /** matches subpaths
+	APIKeySHA256   []string `yaml:"api_key_sha256" mapstructure:"api_key_sha256"`   // hex SHA-256 of client API keys, without any Bearer prefix
+	Clients        []string `yaml:"clients" mapstructure:"clients"`                 // client_auth client names
+	Mode           string   `yaml:"mode" mapstructure:"mode"`                       // empty keeps security.mode
+	BlockThreshold float32  `yaml:"block_threshold" mapstructure:"block_threshold"` // 0 keeps vector_security.block_threshold
+	Detectors      []string `yaml:"detectors" mapstructure:"detectors"`             // PII detectors; empty keeps privacy.detectors
+}
+
+// AnomalyConfig contains per-client behavioral baselining configuration
+type AnomalyConfig struct {
+	Enabled           bool          `yaml:"enabled" mapstructure:"enabled"`
+	BurstFactor       float64       `yaml:"burst_factor" mapstructure:"burst_factor"`             // x typical requests/minute
+	MinBurstRequests  int           `yaml:"min_burst_requests" mapstructure:"min_burst_requests"` // per minute
+	MinObservations   int           `yaml:"min_observations" mapstructure:"min_observations"`     // before baselines are trusted
+	OffHoursThreshold float64       `yaml:"off_hours_threshold" mapstructure:"off_hours_threshold"`
+	Window            time.Duration `yaml:"window" mapstructure:"window"`
+	MaxClients        int           `yaml:"max_clients" mapstructure:"max_clients"` // clients baselined; the least recently seen are forgotten
+	TightenThresholds bool          `yaml:"tighten_thresholds" mapstructure:"tighten_thresholds"`
+	TightenFactor     float32       `yaml:"tighten_factor" mapstructure:"tighten_factor"` // block threshold multiplier during an anomaly
+}
+
+// TemplateConfig contains prompt template fingerprinting configuration.
+// Prompts are reduced to templates by stripping variable segments, and each
+// client's templates are tracked; a new template from a client that mostly
+// reuses known ones is analyzed strictly with a lowered block threshold.
+type TemplateConfig struct {
+	Enabled         bool    `yaml:"enabled" mapstructure:"enabled"`
+	MinObservations int     `yaml:"min_observations" mapstructure:"min_observations"` // requests before a client's templates are trusted
+	MinKnownShare   float64 `yaml:"min_known_share" mapstructure:"min_known_share"`   // recent share of requests on known templates before new ones are unusual
+	KnownAfter      int     `yaml:"known_after" mapstructure:"known_after"`           // unblocked requests using a template before it is known
+	MaxTemplates    int     `yaml:"max_templates" mapstructure:"max_templates"`       // per client; the least recently used are forgotten
+	MaxClients      int     `yaml:"max_clients" mapstructure:"max_clients"`           // clients tracked; the least recently seen are forgotten
+	TightenFactor   float32 `yaml:"tighten_factor" mapstructure:"tighten_factor"`     // block threshold multiplier for a new template
+}
+
+// RateAlertConfig contains deployment-wide detection rate alerting. Each
+// series (all detections, detections per attack type, blocks, PII findings)
+// is baselined per interval, and a window whose count exceeds factor times
+// the forecast raises a detection_alert event.
+type RateAlertConfig struct {
+	Enabled         bool          `yaml:"enabled" mapstructure:"enabled"`
+	Method          string        `yaml:"method" mapstructure:"method"`                     // ewma or holt_winters
+	Interval        time.Duration `yaml:"interval" mapstructure:"interval"`                 // baseline bucket width
+	Window          time.Duration `yaml:"window" mapstructure:"window"`                     // trailing window compared to the baseline
+	Factor          float64       `yaml:"factor" mapstructure:"factor"`                     // x expected count
+	MinCount        int           `yaml:"min_count" mapstructure:"min_count"`               // per window
+	MinObservations int           `yaml:"min_observations" mapstructure:"min_observations"` // intervals before a baseline is trusted
+	Cooldown        time.Duration `yaml:"cooldown" mapstructure:"cooldown"`                 // per series
+	Alpha           float64       `yaml:"alpha" mapstructure:"alpha"`                       // level smoothing
+	Beta            float64       `yaml:"beta" mapstructure:"beta"`                         // holt_winters trend smoothing
+	Gamma           float64       `yaml:"gamma" mapstructure:"gamma"`                       // holt_winters seasonal smoothing
+	SeasonLength    time.Duration `yaml:"season_length" mapstructure:"season_length"`       // holt_winters season
+}
+
+// ConversationConfig contains conversation tracking and policy configuration
+type ConversationConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	Headers          []string      `yaml:"headers" mapstructure:"headers"`                     // checked first, in order
+	BodyPaths        []string      `yaml:"body_paths" mapstructure:"body_paths"`               // JSONPath, e.g. $.metadata.conversation_id
+	Policy           string        `yaml:"policy" mapstructure:"policy"`                       // none, review
+	TTL              time.Duration `yaml:"ttl" mapstructure:"ttl"`                             // conversations unseen this long are forgotten
+	MaxConversations int           `yaml:"max_conversations" mapstructure:"max_conversations"` // conversations tracked, and held for review; the least recently seen unheld are forgotten
 }
 
 // RateLimitConfig contains rate limiting configuration
 type RateLimitConfig struct {
-	Enabled        bool `yaml:"enabled" mapstructure:"enabled"`
-	RequestsPerMin int  `yaml:"requests_per_min" mapstructure:"requests_per_min"`
-	MaxRequestSize int  `yaml:"max_request_size" mapstructure:"max_request_size"` // bytes
-	BurstLimit     int  `yaml:"burst_limit" mapstructure:"burst_limit"`
+	Enabled            bool `yaml:"enabled" mapstructure:"enabled"`
+	RequestsPerMin     int  `yaml:"requests_per_min" mapstructure:"requests_per_min"`
+	MaxRequestSize     int  `yaml:"max_request_size" mapstructure:"max_request_size"`         // bytes of request body on provider routes
+	OversizeStatusCode int  `yaml:"oversize_status_code" mapstructure:"oversize_status_code"` // HTTP status of the response to larger bodies
+	BurstLimit         int  `yaml:"burst_limit" mapstructure:"burst_limit"`
+	IPv4Prefix         int  `yaml:"ipv4_prefix" mapstructure:"ipv4_prefix"` // clients sharing this prefix share a bucket
+	IPv6Prefix         int  `yaml:"ipv6_prefix" mapstructure:"ipv6_prefix"` // 64 groups a subscriber's rotating addresses
+	PerAPIKey          bool `yaml:"per_api_key" mapstructure:"per_api_key"` // also limit each provider API key across addresses
+
+	Backend        string `yaml:"backend" mapstructure:"backend"`                   // "memory" or "redis" (shared by all replicas)
+	RedisURL       string `yaml:"redis_url" mapstructure:"redis_url"`               // empty uses the embedding cache's Redis
+	RedisDB        int    `yaml:"redis_db" mapstructure:"redis_db"`                 // with redis_url
+	RedisKeyPrefix string `yaml:"redis_key_prefix" mapstructure:"redis_key_prefix"` // namespaces the buckets in Redis
 }
 
 // VectorSecurityConfig contains vector-based security configuration
 type VectorSecurityConfig struct {
-	Enabled        bool            `yaml:"enabled" mapstructure:"enabled"`
-	ServiceType    string          `yaml:"service_type" mapstructure:"service_type"` // "ml", "pattern", "hash"
-	BlockThreshold float32         `yaml:"block_threshold" mapstructure:"block_threshold"`
-	MaxBatchSize   int             `yaml:"max_batch_size" mapstructure:"max_batch_size"`
-	Embedding      EmbeddingConfig `yaml:"embedding" mapstructure:"embedding"`
-	Database       DatabaseConfig  `yaml:"database" mapstructure:"database"`
+	Enabled            bool                  `yaml:"enabled" mapstructure:"enabled"`
+	ServiceType        string                `yaml:"service_type" mapstructure:"service_type"` // "ml", "pattern", "hash"
+	BlockThreshold     float32               `yaml:"block_threshold" mapstructure:"block_threshold"`
+	MaxBatchSize       int                   `yaml:"max_batch_size" mapstructure:"max_batch_size"`
+	ConcurrentAnalysis bool                  `yaml:"concurrent_analysis" mapstructure:"concurrent_analysis"` // overlap analysis with upstream connect
+	StrictMode         bool                  `yaml:"strict_mode" mapstructure:"strict_mode"`                 // analyze every prompt, no fast path
+	FastPathMaxLength  int                   `yaml:"fast_path_max_length" mapstructure:"fast_path_max_length"`
+	HybridSearch       bool                  `yaml:"hybrid_search" mapstructure:"hybrid_search"`       // fuse vector and lexical rankings (needs pg_trgm)
+	AnalyzeOriginal    bool                  `yaml:"analyze_original" mapstructure:"analyze_original"` // analyze unmasked text in memory; upstream still gets masked text
+	Messages           MessagesConfig        `yaml:"messages" mapstructure:"messages"`
+	EmbeddingInputs    EmbeddingInputsConfig `yaml:"embedding_inputs" mapstructure:"embedding_inputs"`
+	CanaryCheck        CanaryCheckConfig     `yaml:"canary_check" mapstructure:"canary_check"`
+	Embedding          EmbeddingConfig       `yaml:"embedding" mapstructure:"embedding"`
+	Database           DatabaseConfig        `yaml:"database" mapstructure:"database"`
+	Migration          MigrationConfig       `yaml:"migration" mapstructure:"migration"`
+	KnownAttacks       KnownAttacksConfig    `yaml:"known_attacks" mapstructure:"known_attacks"`
+	Degradation        DegradationConfig     `yaml:"degradation" mapstructure:"degradation"`
+	PoolMonitor        PoolMonitorConfig     `yaml:"pool_monitor" mapstructure:"pool_monitor"`
+	Snapshot           SnapshotConfig        `yaml:"snapshot" mapstructure:"snapshot"`
+	Reduction          ReductionConfig       `yaml:"reduction" mapstructure:"reduction"`
+	Index              IndexConfig           `yaml:"index" mapstructure:"index"`
+	Normalization      NormalizationConfig   `yaml:"normalization" mapstructure:"normalization"`
+	Replay             ReplayConfig          `yaml:"replay" mapstructure:"replay"`
+	VerdictCache       VerdictCacheConfig    `yaml:"verdict_cache" mapstructure:"verdict_cache"`
+}
+
+// MessagesConfig selects which messages of a chat request are analyzed.
+// Each selected message gets its own verdict; the most severe one decides.
+type MessagesConfig struct {
+	Scope string   `yaml:"scope" mapstructure:"scope"` // "last" (latest message only) or "all"
+	Roles []string `yaml:"roles" mapstructure:"roles"` // roles analyzed when scope is all, e.g. user, system, tool
+}
+
+// EmbeddingInputsConfig bounds the analysis of requests batching texts in
+// an input array, such as embeddings and moderation requests. Identical
+// inputs are analyzed once; past MaxInputs only a sample is analyzed.
+type EmbeddingInputsConfig struct {
+	MaxInputs int    `yaml:"max_inputs" mapstructure:"max_inputs"` // inputs analyzed per request
+	Sampling  string `yaml:"sampling" mapstructure:"sampling"`     // head, stride or random
+}
+
+// EmbeddingSamplingMethods are the accepted embedding_inputs.sampling values
+var EmbeddingSamplingMethods = []string{"head", "stride", "random"}
+
+// CanaryCheckConfig compares the loaded embedding model against canary
+// embeddings stored when the corpus was ingested, catching a model or
+// tokenizer that differs from the one that produced the corpus
+type CanaryCheckConfig struct {
+	Enabled       bool    `yaml:"enabled" mapstructure:"enabled"`
+	MinSimilarity float32 `yaml:"min_similarity" mapstructure:"min_similarity"` // lowest canary cosine similarity accepted
+	OnMismatch    string  `yaml:"on_mismatch" mapstructure:"on_mismatch"`       // "warn" or "refuse" (fail startup)
+}
+
+// ReplayConfig keeps recent analyses so an investigator can re-run one and
+// check whether its verdict is reproducible. Analyzed prompts are held in
+// memory only.
+type ReplayConfig struct {
+	Enabled  bool `yaml:"enabled" mapstructure:"enabled"`
+	Capacity int  `yaml:"capacity" mapstructure:"capacity"` // most recent analyses kept
+}
+
+// VerdictCacheConfig selects how the Redis verdict cache matches prompt
+// embeddings. exact hits only near bit-identical embeddings; lsh and hnsw
+// let semantically near prompts reuse a cached verdict. The ETL, which
+// populates the cache, and the analyzers must use the same lookup.
+type VerdictCacheConfig struct {
+	Lookup        string  `yaml:"lookup" mapstructure:"lookup"`                 // exact, lsh (plain Redis) or hnsw (RediSearch, Redis Stack)
+	MinSimilarity float32 `yaml:"min_similarity" mapstructure:"min_similarity"` // cosine similarity to a cached prompt needed for an approximate hit
+	LSHTables     int     `yaml:"lsh_tables" mapstructure:"lsh_tables"`
+	LSHBits       int     `yaml:"lsh_bits" mapstructure:"lsh_bits"`
+	Dimensions    int     `yaml:"dimensions" mapstructure:"dimensions"` // hnsw: embedding dimensions of the vector index
+
+	// LocalSize recent verdicts are also kept in process so hot prompts
+	// skip Redis, each for at most LocalTTL and never past its Redis expiry
+	LocalSize int           `yaml:"local_size" mapstructure:"local_size"` // 0 disables
+	LocalTTL  time.Duration `yaml:"local_ttl" mapstructure:"local_ttl"`
+
+	// TTLTiers pick how long each verdict stays cached; empty uses the
+	// cache's built-in tiers
+	TTLTiers []VerdictTTLTier `yaml:"ttl_tiers" mapstructure:"ttl_tiers"`
+}
+
+// VerdictTTLTier caches verdicts of one label at or above a confidence for
+// TTL. Tiers are checked in order; the first match wins.
+type VerdictTTLTier struct {
+	Name          string        `yaml:"name" mapstructure:"name"`
+	Label         int           `yaml:"label" mapstructure:"label"`                   // 1 = malicious, 0 = safe
+	MinConfidence float32       `yaml:"min_confidence" mapstructure:"min_confidence"` // compared to the cached similarity
+	TTL           time.Duration `yaml:"ttl" mapstructure:"ttl"`
+}
+
+// ReductionConfig shrinks embeddings before they are stored and searched,
+// trading some detection accuracy for storage and search speed
+type ReductionConfig struct {
+	Method     string `yaml:"method" mapstructure:"method"`         // "none", "truncate" (Matryoshka), "pca"
+	Dimensions int    `yaml:"dimensions" mapstructure:"dimensions"` // must match the embedding column size
+	PCAPath    string `yaml:"pca_path" mapstructure:"pca_path"`     // projection written by llm-sentinel-etl --fit-pca
+}
+
+// IndexConfig selects the approximate nearest-neighbour index on the
+// embedding column. Build parameters take effect when the index is built or
+// rebuilt (llm-sentinel-etl index rebuild); search parameters are set on
+// every database connection.
+type IndexConfig struct {
+	Type           string `yaml:"type" mapstructure:"type"`                       // "ivfflat" or "hnsw"
+	M              int    `yaml:"m" mapstructure:"m"`                             // hnsw: links per node
+	EfConstruction int    `yaml:"ef_construction" mapstructure:"ef_construction"` // hnsw: candidate list while building, at least 2*m
+	EfSearch       int    `yaml:"ef_search" mapstructure:"ef_search"`             // hnsw: candidate list per search (0 = server default, 40)
+	Lists          int    `yaml:"lists" mapstructure:"lists"`                     // ivfflat: clusters (0 = rows/1000, at least 10)
+	Probes         int    `yaml:"probes" mapstructure:"probes"`                   // ivfflat: clusters scanned per search (0 = server default, 1)
+	MinVectors     int64  `yaml:"min_vectors" mapstructure:"min_vectors"`         // ingestion builds no index below this many rows
+}
+
+// NormalizationConfig canonicalizes text before it is hashed, so trivial
+// whitespace or Unicode differences do not defeat deduplication, caching or
+// exact matching. Changing dedup after ingestion leaves existing rows under
+// their old hashes until the corpus is re-ingested.
+type NormalizationConfig struct {
+	Dedup      TextNormalization `yaml:"dedup" mapstructure:"dedup"`             // corpus text_hash in the ETL and store
+	Cache      TextNormalization `yaml:"cache" mapstructure:"cache"`             // embedding cache keys
+	ExactMatch TextNormalization `yaml:"exact_match" mapstructure:"exact_match"` // known-attack filter
+}
+
+// TextNormalization selects the canonicalization steps for one use
+type TextNormalization struct {
+	Trim               bool `yaml:"trim" mapstructure:"trim"`
+	CollapseWhitespace bool `yaml:"collapse_whitespace" mapstructure:"collapse_whitespace"`
+	NFC                bool `yaml:"nfc" mapstructure:"nfc"`
+	Lowercase          bool `yaml:"lowercase" mapstructure:"lowercase"`
+}
+
+// PoolMonitorConfig controls sampling of the database connection pools and
+// the warnings logged when callers wait too long for a connection
+type PoolMonitorConfig struct {
+	Enabled        bool          `yaml:"enabled" mapstructure:"enabled"`
+	SampleInterval time.Duration `yaml:"sample_interval" mapstructure:"sample_interval"`
+	MaxWaits       int64         `yaml:"max_waits" mapstructure:"max_waits"`         // connection waits per interval before warning
+	MaxWaitTime    time.Duration `yaml:"max_wait_time" mapstructure:"max_wait_time"` // total time spent waiting per interval before warning
+}
+
+// SnapshotConfig keeps an in-memory copy of the corpus, saved to disk, that
+// serves similarity searches while the database is unreachable. A restarted
+// instance loads the snapshot in seconds and keeps full protection while it
+// reconnects and resyncs.
+type SnapshotConfig struct {
+	Enabled  bool          `yaml:"enabled" mapstructure:"enabled"`
+	Path     string        `yaml:"path" mapstructure:"path"`         // snapshot file, rewritten atomically
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"` // how often to resync (or reconnect) and save
+}
+
+// DegradationConfig controls stepping analysis quality down (ml, pattern,
+// keyword) under sustained load and back up when load drops
+type DegradationConfig struct {
+	Enabled           bool          `yaml:"enabled" mapstructure:"enabled"`
+	SampleInterval    time.Duration `yaml:"sample_interval" mapstructure:"sample_interval"`
+	SustainSamples    int           `yaml:"sustain_samples" mapstructure:"sustain_samples"` // consecutive samples before changing tier
+	DegradeCPU        float64       `yaml:"degrade_cpu" mapstructure:"degrade_cpu"`         // process CPU utilization, 0-1
+	RestoreCPU        float64       `yaml:"restore_cpu" mapstructure:"restore_cpu"`
+	DegradeQueueDepth int           `yaml:"degrade_queue_depth" mapstructure:"degrade_queue_depth"` // analyses in flight
+	RestoreQueueDepth int           `yaml:"restore_queue_depth" mapstructure:"restore_queue_depth"`
+}
+
+// KnownAttacksConfig controls the bloom filter of exact known-malicious
+// prompts checked before any other analysis
+type KnownAttacksConfig struct {
+	Enabled           bool          `yaml:"enabled" mapstructure:"enabled"`
+	RefreshInterval   time.Duration `yaml:"refresh_interval" mapstructure:"refresh_interval"`
+	FalsePositiveRate float64       `yaml:"false_positive_rate" mapstructure:"false_positive_rate"` // benign prompts wrongly blocked
+	RedisKey          string        `yaml:"redis_key" mapstructure:"redis_key"`                     // shared between instances when Redis is enabled
+}
+
+// MigrationConfig enables dual-write to a second store while migrating
+// between backends or schema versions
+type MigrationConfig struct {
+	Enabled           bool           `yaml:"enabled" mapstructure:"enabled"`
+	Secondary         DatabaseConfig `yaml:"secondary" mapstructure:"secondary"`
+	ReadFromSecondary bool           `yaml:"read_from_secondary" mapstructure:"read_from_secondary"` // rehearse cutover
+	CompareReads      bool           `yaml:"compare_reads" mapstructure:"compare_reads"`
+	CompareSampleRate float64        `yaml:"compare_sample_rate" mapstructure:"compare_sample_rate"`
 }
 
 // EmbeddingConfig contains embedding service configuration
@@ -81,6 +439,19 @@ type ModelConfig struct {
 	MaxLength     int           `yaml:"max_length" mapstructure:"max_length"`
 	BatchSize     int           `yaml:"batch_size" mapstructure:"batch_size"`
 	ModelTimeout  time.Duration `yaml:"model_timeout" mapstructure:"model_timeout"`
+	MemoSize      int           `yaml:"memo_size" mapstructure:"memo_size"` // in-process embeddings keyed by token IDs; 0 disables
+
+	// LocalCacheSize embeddings are kept in process by text in front of the
+	// Redis embedding cache, each for at most LocalCacheTTL
+	LocalCacheSize int           `yaml:"local_cache_size" mapstructure:"local_cache_size"` // 0 disables
+	LocalCacheTTL  time.Duration `yaml:"local_cache_ttl" mapstructure:"local_cache_ttl"`
+
+	// AutoDownload fetches the model at Revision, a full commit hash, and
+	// checks each file against its hex SHA-256
+	Revision        string `yaml:"revision" mapstructure:"revision"`
+	ModelSHA256     string `yaml:"model_sha256" mapstructure:"model_sha256"`         // onnx/model.onnx
+	TokenizerSHA256 string `yaml:"tokenizer_sha256" mapstructure:"tokenizer_sha256"` // tokenizer.json
+	VocabSHA256     string `yaml:"vocab_sha256" mapstructure:"vocab_sha256"`         // vocab.txt
 }
 
 // DatabaseConfig contains vector database configuration
@@ -110,22 +481,210 @@ type UpstreamConfig struct {
 	OpenAI    string        `yaml:"openai" mapstructure:"openai"`
 	Anthropic string        `yaml:"anthropic" mapstructure:"anthropic"`
 	Ollama    string        `yaml:"ollama" mapstructure:"ollama"`
+	Gemini    string        `yaml:"gemini" mapstructure:"gemini"` // Gemini API (generativelanguage.googleapis.com)
+	Vertex    string        `yaml:"vertex" mapstructure:"vertex"` // regional Vertex AI endpoint, e.g. https://us-central1-aiplatform.googleapis.com
 	Timeout   time.Duration `yaml:"timeout" mapstructure:"timeout"`
+
+	AzureOpenAI AzureOpenAIConfig `yaml:"azure_openai" mapstructure:"azure_openai"`
+	Bedrock     BedrockConfig     `yaml:"bedrock" mapstructure:"bedrock"`
+	Egress      EgressConfig      `yaml:"egress" mapstructure:"egress"`
+	DNS         DNSConfig         `yaml:"dns" mapstructure:"dns"`
+
+	Auth      UpstreamAuthConfig             `yaml:"auth" mapstructure:"auth"`
+	Paths     map[string]UpstreamPathPolicy  `yaml:"paths" mapstructure:"paths"`       // provider route -> proxied path policy
+	Failover  map[string]FailoverGroupConfig `yaml:"failover" mapstructure:"failover"` // provider route -> upstreams it fails over between
+	Streaming StreamingConfig                `yaml:"streaming" mapstructure:"streaming"`
+
+	Retry          UpstreamRetryConfig  `yaml:"retry" mapstructure:"retry"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker" mapstructure:"circuit_breaker"`
+
+	Annotations AnnotationConfig `yaml:"annotations" mapstructure:"annotations"`
+}
+
+// AnnotationConfig forwards the proxy's analysis of each request to the
+// upstream as a signed JWT, so gateways behind the proxy can act on it
+// without analyzing the prompt again
+type AnnotationConfig struct {
+	Enabled    bool          `yaml:"enabled" mapstructure:"enabled"`
+	Header     string        `yaml:"header" mapstructure:"header"`
+	SigningKey string        `yaml:"signing_key" mapstructure:"signing_key" secret:"true"` // HS256 key shared with the consumers
+	Issuer     string        `yaml:"issuer" mapstructure:"issuer"`
+	TTL        time.Duration `yaml:"ttl" mapstructure:"ttl"`       // token lifetime
+	Routes     []string      `yaml:"routes" mapstructure:"routes"` // provider routes that receive annotations; empty for all
+}
+
+// AzureOpenAIConfig configures the /azure-openai route. OpenAI-style requests
+// (/v1/chat/completions with a model in the body) are mapped onto the
+// resource's deployment paths; native Azure paths under /openai pass through.
+type AzureOpenAIConfig struct {
+	Endpoint        string            `yaml:"endpoint" mapstructure:"endpoint"`                   // https://<resource>.openai.azure.com; empty disables the route
+	APIVersion      string            `yaml:"api_version" mapstructure:"api_version"`             // api-version query parameter added when the client sends none
+	ForceAPIVersion bool              `yaml:"force_api_version" mapstructure:"force_api_version"` // replace a client-supplied api-version
+	Deployments     map[string]string `yaml:"deployments" mapstructure:"deployments"`             // model or deployment alias -> deployment name; unmapped names are used as is
+	Auth            string            `yaml:"auth" mapstructure:"auth"`                           // passthrough or bearer_to_api_key
+}
+
+// EgressConfig routes outbound connections through proxies. A route's proxy
+// replaces the default entirely, including its no-proxy list.
+type EgressConfig struct {
+	Default EgressProxyConfig            `yaml:"default" mapstructure:"default"`
+	Routes  map[string]EgressProxyConfig `yaml:"routes" mapstructure:"routes"` // provider route, or huggingface for model downloads
+}
+
+// EgressProxyConfig is one outbound proxy
+type EgressProxyConfig struct {
+	URL      string   `yaml:"url" mapstructure:"url"` // http://, https://, socks5:// or socks5h://host:port; empty connects directly
+	Username string   `yaml:"username" mapstructure:"username"`
+	Password string   `yaml:"password" mapstructure:"password" secret:"true"`
+	NoProxy  []string `yaml:"no_proxy" mapstructure:"no_proxy"` // hosts (matching subdomains), .suffixes, IPs or CIDRs reached directly
+}
+
+// EgressSchemes lists the supported proxy URL schemes. socks5h resolves
+// host names on the proxy rather than locally.
+var EgressSchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// EgressDownloadRoute selects the egress proxy for model downloads
+const EgressDownloadRoute = "huggingface"
+
+// DNSConfig caches upstream host lookups and restricts the addresses
+// upstream names may resolve to, so a changed DNS answer cannot redirect
+// traffic (DNS rebinding). With an egress proxy, names are resolved by the
+// proxy and only the proxy host is looked up here.
+type DNSConfig struct {
+	CacheEnabled bool            `yaml:"cache_enabled" mapstructure:"cache_enabled"`
+	MinTTL       time.Duration   `yaml:"min_ttl" mapstructure:"min_ttl"`         // floor for record TTLs; also used for answers without one (hosts file)
+	MaxTTL       time.Duration   `yaml:"max_ttl" mapstructure:"max_ttl"`         // cap for record TTLs
+	ServeStale   time.Duration   `yaml:"serve_stale" mapstructure:"serve_stale"` // keep using an expired answer this long while lookups fail
+	Hosts        []DNSHostConfig `yaml:"hosts" mapstructure:"hosts"`             // per-host pins and allowlists
+}
+
+// DNSHostConfig pins a host to fixed addresses, or restricts the addresses
+// it may resolve to
+type DNSHostConfig struct {
+	Host  string   `yaml:"host" mapstructure:"host"`
+	Pin   []string `yaml:"pin" mapstructure:"pin"`     // fixed IPs; DNS is never consulted
+	Allow []string `yaml:"allow" mapstructure:"allow"` // IPs or CIDRs resolved addresses must fall in
+}
+
+// BedrockConfig configures the /bedrock route. Forwarded requests are
+// re-signed with SigV4 using the proxy's own AWS credentials, from
+// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or an IRSA web identity token, so
+// the route is only allowed with client authentication enabled.
+type BedrockConfig struct {
+	Region   string `yaml:"region" mapstructure:"region"`     // e.g. us-east-1; empty disables the route
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"` // defaults to https://bedrock-runtime.<region>.amazonaws.com
+}
+
+// AzureAuthModes lists how client credentials are forwarded to Azure OpenAI:
+// unchanged (api-key or an Entra ID bearer token), or with an OpenAI-style
+// "Authorization: Bearer <key>" moved into the api-key header
+var AzureAuthModes = []string{"passthrough", "bearer_to_api_key"}
+
+// StreamingConfig controls pass-through of streamed (SSE or NDJSON) responses.
+// Streamed responses are flushed to the client as each chunk arrives and are
+// never held for deduplication replay.
+type StreamingConfig struct {
+	Enabled      bool               `yaml:"enabled" mapstructure:"enabled"`
+	MaxDuration  time.Duration      `yaml:"max_duration" mapstructure:"max_duration"` // replaces server.write_timeout for streamed responses
+	LatencyAudit LatencyAuditConfig `yaml:"latency_audit" mapstructure:"latency_audit"`
+}
+
+// LatencyAuditConfig measures the time to first byte the proxy adds to
+// streamed responses: the client's time to first byte less the upstream's.
+// Each stage's share is recorded in metrics, and a report per interval
+// compares the configured percentile of the overhead with the SLO.
+type LatencyAuditConfig struct {
+	Enabled        bool          `yaml:"enabled" mapstructure:"enabled"`
+	SLO            time.Duration `yaml:"slo" mapstructure:"slo"`               // added time to first byte the proxy should stay under
+	Percentile     float64       `yaml:"percentile" mapstructure:"percentile"` // percentile held to the SLO, e.g. 99
+	ReportInterval time.Duration `yaml:"report_interval" mapstructure:"report_interval"`
+	ReportFile     string        `yaml:"report_file" mapstructure:"report_file"` // reports appended as JSON lines; empty only logs them
+	MaxSamples     int           `yaml:"max_samples" mapstructure:"max_samples"` // per report; past this a uniform sample is kept
+}
+
+// UpstreamPathPolicy restricts which upstream paths a provider route proxies.
+// Patterns are path.Match globs on the path after the route prefix; a
+// trailing "/**" also matches everything below. Deny wins over allow, and an
+// empty allow list allows every path not denied.
+type UpstreamPathPolicy struct {
+	Allow []string `yaml:"allow" mapstructure:"allow"`
+	Deny  []string `yaml:"deny" mapstructure:"deny"`
+}
+
+// FailoverGroupConfig lists the upstreams of a provider route in priority
+// order. Traffic goes to the first healthy target; a target whose error rate
+// or latency over a window exceeds the thresholds is marked unhealthy, and
+// traffic stays on the next one until probes of the failed target succeed.
+// Zero values select a 50% error rate over at least 10 requests in a 1m
+// window, no latency limit, and recovery after 3 successful probes 30s apart.
+type FailoverGroupConfig struct {
+	Targets       []FailoverTarget `yaml:"targets" mapstructure:"targets"`
+	MaxErrorRate  float64          `yaml:"max_error_rate" mapstructure:"max_error_rate"` // share of 5xx, 429 and connection failures
+	MaxLatency    time.Duration    `yaml:"max_latency" mapstructure:"max_latency"`       // mean time to response headers
+	MinRequests   int              `yaml:"min_requests" mapstructure:"min_requests"`     // in a window before thresholds apply
+	Window        time.Duration    `yaml:"window" mapstructure:"window"`
+	ProbeInterval time.Duration    `yaml:"probe_interval" mapstructure:"probe_interval"`
+	ProbePath     string           `yaml:"probe_path" mapstructure:"probe_path"`       // GET on unhealthy targets; default /
+	RecoverAfter  int              `yaml:"recover_after" mapstructure:"recover_after"` // consecutive successful probes
+}
+
+// FailoverTarget is one upstream of a failover group
+type FailoverTarget struct {
+	Name      string            `yaml:"name" mapstructure:"name"`
+	URL       string            `yaml:"url" mapstructure:"url"`               // base URL; request paths are appended to its path
+	HeaderEnv map[string]string `yaml:"header_env" mapstructure:"header_env"` // header -> environment variable holding its value; replaces the client's credentials
+}
+
+// UpstreamRetryConfig repeats upstream requests that fail with a connection
+// error or a retryable status, backing off between attempts. Attempts happen
+// before any of the response reaches the client, so streamed requests are
+// retried too.
+type UpstreamRetryConfig struct {
+	Enabled        bool          `yaml:"enabled" mapstructure:"enabled"`
+	MaxAttempts    int           `yaml:"max_attempts" mapstructure:"max_attempts"` // including the first
+	InitialBackoff time.Duration `yaml:"initial_backoff" mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"` // also the longest upstream Retry-After waited for
+	Statuses       []int         `yaml:"statuses" mapstructure:"statuses"`       // upstream statuses retried
+	Routes         []string      `yaml:"routes" mapstructure:"routes"`           // provider routes retried; empty for all
+}
+
+// CircuitBreakerConfig stops sending requests to an upstream after
+// consecutive failures, answering 503 at once until it has been open for
+// OpenDuration. Then a few trial requests are let through: a success closes
+// the breaker, a failure opens it again. Each failover target has its own
+// breaker.
+type CircuitBreakerConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	FailureThreshold int           `yaml:"failure_threshold" mapstructure:"failure_threshold"` // consecutive 5xx or connection failures
+	OpenDuration     time.Duration `yaml:"open_duration" mapstructure:"open_duration"`
+	HalfOpenRequests int           `yaml:"half_open_requests" mapstructure:"half_open_requests"` // concurrent trial requests
+	Routes           []string      `yaml:"routes" mapstructure:"routes"`                         // provider routes guarded; empty for all
+}
+
+// UpstreamAuthConfig rejects proxy requests that carry none of a provider's
+// credential headers with a 401, before any analysis or forwarding
+type UpstreamAuthConfig struct {
+	Enabled bool                `yaml:"enabled" mapstructure:"enabled"`
+	Headers map[string][]string `yaml:"headers" mapstructure:"headers"` // provider route -> accepted headers (any one)
 }
 
 // WebSocketConfig contains WebSocket configuration
 type WebSocketConfig struct {
-	Enabled         bool          `yaml:"enabled" mapstructure:"enabled"`
-	Path            string        `yaml:"path" mapstructure:"path"`
-	MaxConnections  int           `yaml:"max_connections" mapstructure:"max_connections"`
-	ReadBufferSize  int           `yaml:"read_buffer_size" mapstructure:"read_buffer_size"`
-	WriteBufferSize int           `yaml:"write_buffer_size" mapstructure:"write_buffer_size"`
-	PingInterval    time.Duration `yaml:"ping_interval" mapstructure:"ping_interval"`
-	PongTimeout     time.Duration `yaml:"pong_timeout" mapstructure:"pong_timeout"`
-	WriteTimeout    time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
-	MaxMessageSize  int64         `yaml:"max_message_size" mapstructure:"max_message_size"`
-	AllowedOrigins  []string      `yaml:"allowed_origins" mapstructure:"allowed_origins"`
-	Events          struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	Path             string        `yaml:"path" mapstructure:"path"`
+	MaxConnections   int           `yaml:"max_connections" mapstructure:"max_connections"`
+	ReadBufferSize   int           `yaml:"read_buffer_size" mapstructure:"read_buffer_size"`
+	WriteBufferSize  int           `yaml:"write_buffer_size" mapstructure:"write_buffer_size"`
+	PingInterval     time.Duration `yaml:"ping_interval" mapstructure:"ping_interval"`
+	PongTimeout      time.Duration `yaml:"pong_timeout" mapstructure:"pong_timeout"`
+	WriteTimeout     time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
+	MaxMessageSize   int64         `yaml:"max_message_size" mapstructure:"max_message_size"`
+	AllowedOrigins   []string      `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+	Compression      bool          `yaml:"compression" mapstructure:"compression"`             // permessage-deflate for clients that offer it
+	CompressionLevel int           `yaml:"compression_level" mapstructure:"compression_level"` // -2..9; 0 uses the library default
+	AllowMessagePack bool          `yaml:"allow_msgpack" mapstructure:"allow_msgpack"`         // clients may request MessagePack binary frames
+	ReplayBuffer     int           `yaml:"replay_buffer" mapstructure:"replay_buffer"`         // recent events kept for live-tail replay_last
+	Events           struct {
 		BroadcastPIIDetections  bool `yaml:"broadcast_pii_detections" mapstructure:"broadcast_pii_detections"`
 		BroadcastVectorSecurity bool `yaml:"broadcast_vector_security" mapstructure:"broadcast_vector_security"`
 		BroadcastSystem         bool `yaml:"broadcast_system" mapstructure:"broadcast_system"`
@@ -133,6 +692,329 @@ type WebSocketConfig struct {
 	} `yaml:"events" mapstructure:"events"`
 }
 
+// PipelineConfig controls which middleware stages run on proxy routes and
+// in what order. Request logging and maintenance mode always run first.
+type PipelineConfig struct {
+	Default []string            `yaml:"default" mapstructure:"default"` // dedup, rate_limit, anomaly, conversation, privacy, vector_security
+	Routes  map[string][]string `yaml:"routes" mapstructure:"routes"`   // per provider route overrides (see PipelineRoutes)
+}
+
+// CORSConfig allows browser tools on other origins to call the non-proxy API
+// routes (health, info, metrics, admin). Disabled means same-origin only.
+type CORSConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	AllowedOrigins   []string      `yaml:"allowed_origins" mapstructure:"allowed_origins"` // exact origins, or "*"
+	AllowedMethods   []string      `yaml:"allowed_methods" mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" mapstructure:"allowed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" mapstructure:"max_age"` // how long browsers may cache a preflight
+}
+
+// ResponseHeadersConfig rewrites headers on proxy route responses, including
+// errors generated by the proxy itself. A route's policy is applied after the
+// default policy.
+type ResponseHeadersConfig struct {
+	Default HeaderPolicy            `yaml:"default" mapstructure:"default"`
+	Routes  map[string]HeaderPolicy `yaml:"routes" mapstructure:"routes"` // per provider route (see PipelineRoutes)
+}
+
+// HeaderPolicy removes headers, then sets fixed values
+type HeaderPolicy struct {
+	Remove []string          `yaml:"remove" mapstructure:"remove"` // names; a trailing * matches a prefix, e.g. x-ratelimit-*
+	Set    map[string]string `yaml:"set" mapstructure:"set"`       // overwrite or add
+}
+
+// PipelineStages lists the configurable middleware stages
+var PipelineStages = []string{"dedup", "rate_limit", "anomaly", "conversation", "privacy", "vector_security"}
+
+// PipelineRoutes lists the proxy routes whose pipeline can be overridden
+var PipelineRoutes = []string{"openai", "ollama", "anthropic", "azure-openai", "gemini", "vertex", "bedrock"}
+
+// DedupConfig controls sharing one upstream response between identical
+// requests from the same client, absorbing accidental double-submits
+type DedupConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	Window           time.Duration `yaml:"window" mapstructure:"window"`                         // how long a completed response is reused
+	MaxResponseBytes int           `yaml:"max_response_bytes" mapstructure:"max_response_bytes"` // larger responses are not shared
+}
+
+// ResponseCacheConfig controls replaying stored upstream responses for
+// identical requests, which then skip both the upstream and analysis
+type ResponseCacheConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	Routes           []string      `yaml:"routes" mapstructure:"routes"` // provider routes to cache; empty for all
+	TTL              time.Duration `yaml:"ttl" mapstructure:"ttl"`
+	MaxResponseBytes int           `yaml:"max_response_bytes" mapstructure:"max_response_bytes"` // larger responses are not cached
+	RedisURL         string        `yaml:"redis_url" mapstructure:"redis_url"`                   // empty uses the embedding cache's Redis
+	RedisDB          int           `yaml:"redis_db" mapstructure:"redis_db"`                     // with redis_url
+	KeyPrefix        string        `yaml:"key_prefix" mapstructure:"key_prefix"`
+}
+
+// CostConfig estimates what each provider request will cost before it is
+// forwarded: estimated prompt tokens plus the most completion tokens the
+// request allows, at the model's configured price. Requests over MaxRequestUSD
+// are rejected or, with action warn, forwarded and logged. Models without a
+// price are forwarded unestimated.
+type CostConfig struct {
+	Enabled                 bool                  `yaml:"enabled" mapstructure:"enabled"`
+	Pricing                 map[string]ModelPrice `yaml:"pricing" mapstructure:"pricing"`                                     // model or model prefix -> price; the longest matching prefix wins
+	MaxRequestUSD           float64               `yaml:"max_request_usd" mapstructure:"max_request_usd"`                     // 0 disables the cap
+	Action                  string                `yaml:"action" mapstructure:"action"`                                       // reject or warn
+	DefaultCompletionTokens int                   `yaml:"default_completion_tokens" mapstructure:"default_completion_tokens"` // assumed when a request sets no maximum
+	CharsPerToken           float64               `yaml:"chars_per_token" mapstructure:"chars_per_token"`                     // prompt text per estimated token
+	Routes                  []string              `yaml:"routes" mapstructure:"routes"`                                       // provider routes estimated; empty for all
+}
+
+// ModelPrice is a model's price in USD per million tokens
+type ModelPrice struct {
+	Input  float64 `yaml:"input" mapstructure:"input"`
+	Output float64 `yaml:"output" mapstructure:"output"`
+}
+
+// APIConfig controls the endpoints that use Sentinel as a service rather
+// than as a proxy
+type APIConfig struct {
+	Analyze    AnalyzeAPIConfig    `yaml:"analyze" mapstructure:"analyze"`
+	Embeddings EmbeddingsAPIConfig `yaml:"embeddings" mapstructure:"embeddings"`
+	Detectors  DetectorsAPIConfig  `yaml:"detectors" mapstructure:"detectors"`
+}
+
+// AnalyzeAPIConfig controls /v1/analyze, which runs PII detection and prompt
+// analysis on a text and returns the verdict without forwarding anything
+type AnalyzeAPIConfig struct {
+	Enabled       bool `yaml:"enabled" mapstructure:"enabled"`
+	MaxTextLength int  `yaml:"max_text_length" mapstructure:"max_text_length"` // in bytes; longer texts are rejected with 413
+}
+
+// EmbeddingsAPIConfig controls /v1/embeddings, an OpenAI-compatible
+// embeddings endpoint served by the ML embedding service used for analysis
+type EmbeddingsAPIConfig struct {
+	Enabled   bool     `yaml:"enabled" mapstructure:"enabled"`
+	Model     string   `yaml:"model" mapstructure:"model"`           // model name requests use and responses report
+	Aliases   []string `yaml:"aliases" mapstructure:"aliases"`       // other model names served by the same model, e.g. text-embedding-3-small
+	MaxInputs int      `yaml:"max_inputs" mapstructure:"max_inputs"` // texts per request
+}
+
+// DetectorsAPIConfig controls /admin/detectors, which lists every PII rule
+// and attack pattern with its category, severity and enabled state. It
+// reveals attack keywords, so it needs an admin token.
+type DetectorsAPIConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+}
+
+// ArtifactsConfig controls files written or read outside the database, such
+// as corpus exports, reports and ETL rejects
+type ArtifactsConfig struct {
+	Encryption ArtifactEncryptionConfig `yaml:"encryption" mapstructure:"encryption"`
+}
+
+// ArtifactEncryptionConfig encrypts exported artifacts with age. Imports are
+// decrypted whenever an identity file is configured, even if exports are not
+// encrypted.
+type ArtifactEncryptionConfig struct {
+	Enabled      bool     `yaml:"enabled" mapstructure:"enabled"`
+	Recipients   []string `yaml:"recipients" mapstructure:"recipients"`       // age public keys (age1...)
+	IdentityFile string   `yaml:"identity_file" mapstructure:"identity_file"` // age secret key file for imports
+}
+
+// MetricsConfig controls the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	Path    string `yaml:"path" mapstructure:"path"`
+}
+
+// ETLConfig contains dataset ingestion configuration
+type ETLConfig struct {
+	Labels      LabelConfig `yaml:"labels" mapstructure:"labels"`
+	RejectsFile string      `yaml:"rejects_file" mapstructure:"rejects_file"` // JSON lines, re-ingestible once fixed
+
+	// Progress through each input file, for resuming interrupted runs with
+	// --resume; empty disables checkpointing
+	CheckpointFile string `yaml:"checkpoint_file" mapstructure:"checkpoint_file"`
+
+	// Input files at least this large are loaded with COPY FROM STDIN
+	// instead of multi-row INSERTs; 0 always uses COPY, -1 never does
+	CopyThreshold int64 `yaml:"copy_threshold" mapstructure:"copy_threshold"` // bytes
+
+	// How dataset columns map onto record fields; --schema overrides it
+	Schema DatasetSchemaConfig `yaml:"schema" mapstructure:"schema"`
+
+	// Records nearly identical to one already kept are dropped before insert
+	NearDuplicates NearDuplicateConfig `yaml:"near_duplicates" mapstructure:"near_duplicates"`
+
+	// Datasets read straight from the HuggingFace Hub with --input hf://owner/name
+	HuggingFace HuggingFaceDatasetConfig `yaml:"huggingface" mapstructure:"huggingface"`
+}
+
+// DatasetSchemaConfig names the dataset columns read as a record's fields:
+// a CSV header, JSON key or Parquet column (dotted for nested columns), or a
+// 0-based CSV position. Text is required, and so is one of label and
+// label_text; the other is derived.
+type DatasetSchemaConfig struct {
+	Text      string   `yaml:"text" mapstructure:"text"`
+	LabelText string   `yaml:"label_text" mapstructure:"label_text"`
+	Label     string   `yaml:"label" mapstructure:"label"`       // 0/1, true/false or yes/no
+	Source    string   `yaml:"source" mapstructure:"source"`     // optional; defaults to the input file name
+	Language  string   `yaml:"language" mapstructure:"language"` // optional; defaults to detection from the text
+	Metadata  []string `yaml:"metadata" mapstructure:"metadata"` // extra columns stored with each vector
+	Header    bool     `yaml:"header" mapstructure:"header"`     // false for CSV files without a header row
+
+	// label_text for records with only a 0/1 label
+	MaliciousLabel string `yaml:"malicious_label" mapstructure:"malicious_label"`
+	SafeLabel      string `yaml:"safe_label" mapstructure:"safe_label"`
+}
+
+// NearDuplicateMethods are the accepted etl.near_duplicates.method values
+var NearDuplicateMethods = []string{"simhash", "embedding"}
+
+// NearDuplicateConfig filters near-duplicate records during ingestion.
+// simhash compares word fingerprints of the records in one run and is
+// cheap; embedding compares embeddings with the batch and with the stored
+// corpus, catching paraphrases at the cost of one similarity query per
+// record.
+type NearDuplicateConfig struct {
+	Method          string  `yaml:"method" mapstructure:"method"`                     // empty disables; simhash or embedding
+	SimHashDistance int     `yaml:"simhash_distance" mapstructure:"simhash_distance"` // differing bits of 64, 0-10
+	Similarity      float64 `yaml:"similarity" mapstructure:"similarity"`             // cosine similarity, embedding method
+}
+
+// HuggingFaceDatasetConfig controls hf:// inputs, which are read from the
+// Parquet conversion the Hub publishes for each dataset. A URI can override
+// the split, config and etl.schema columns with query parameters, as in
+// hf://owner/name?split=test&text=prompt.
+type HuggingFaceDatasetConfig struct {
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`   // empty uses HF_ENDPOINT, then https://huggingface.co
+	TokenEnv string `yaml:"token_env" mapstructure:"token_env"` // environment variable holding the access token for gated or private datasets
+	Config   string `yaml:"config" mapstructure:"config"`       // dataset config (subset)
+	Split    string `yaml:"split" mapstructure:"split"`
+	CacheDir string `yaml:"cache_dir" mapstructure:"cache_dir"` // Parquet shards are downloaded here one at a time and removed once read
+}
+
+// LabelConfig maps the label_text variants found in datasets onto one
+// taxonomy so filters and dashboards aggregate consistently. Keys and
+// incoming labels are compared after normalization (lowercase, separators
+// collapsed to "_"), so "Prompt-Injection" matches a "prompt_injection" key.
+type LabelConfig struct {
+	Mapping        map[string]string `yaml:"mapping" mapstructure:"mapping"`                 // variant -> canonical category
+	RejectUnmapped bool              `yaml:"reject_unmapped" mapstructure:"reject_unmapped"` // drop records whose label has no mapping
+}
+
+// EventsConfig contains downstream event sink configuration. Events for an
+// unreachable sink are buffered on disk and replayed with backoff.
+type EventsConfig struct {
+	QueueDir        string            `yaml:"queue_dir" mapstructure:"queue_dir"`
+	QueueMaxRecords int               `yaml:"queue_max_records" mapstructure:"queue_max_records"`
+	QueueMaxBytes   int64             `yaml:"queue_max_bytes" mapstructure:"queue_max_bytes"`
+	InitialBackoff  time.Duration     `yaml:"initial_backoff" mapstructure:"initial_backoff"`
+	MaxBackoff      time.Duration     `yaml:"max_backoff" mapstructure:"max_backoff"`
+	Sinks           []EventSinkConfig `yaml:"sinks" mapstructure:"sinks"`
+	Webhooks        []WebhookConfig   `yaml:"webhooks" mapstructure:"webhooks"`
+	Archive         ArchiveConfig     `yaml:"archive" mapstructure:"archive"`
+}
+
+// ArchiveStoreTypes are the accepted event archive backends
+var ArchiveStoreTypes = []string{"s3", "gcs", "dir"}
+
+// ArchiveConfig retains events long term in object storage. Events are
+// spooled to local segments, and each interval the sealed segments are
+// compacted into Parquet files, one per day, uploaded under
+// <prefix>/dt=YYYY-MM-DD/ and deleted locally.
+type ArchiveConfig struct {
+	Enabled       bool                   `yaml:"enabled" mapstructure:"enabled"`
+	Dir           string                 `yaml:"dir" mapstructure:"dir"`                 // local spool
+	EventTypes    []string               `yaml:"event_types" mapstructure:"event_types"` // empty archives every event type
+	Interval      time.Duration          `yaml:"interval" mapstructure:"interval"`
+	MaxLocalBytes int64                  `yaml:"max_local_bytes" mapstructure:"max_local_bytes"` // oldest segments are dropped beyond this while uploads fail
+	Store         ArchiveStoreConfig     `yaml:"store" mapstructure:"store"`
+	Lifecycle     ArchiveLifecycleConfig `yaml:"lifecycle" mapstructure:"lifecycle"`
+	VerdictExport VerdictExportConfig    `yaml:"verdict_export" mapstructure:"verdict_export"`
+}
+
+// ArchiveStoreConfig selects where archived Parquet files are written. GCS
+// is reached through its S3-compatible XML API with HMAC keys.
+type ArchiveStoreConfig struct {
+	Type            string `yaml:"type" mapstructure:"type"` // s3, gcs or dir
+	Bucket          string `yaml:"bucket" mapstructure:"bucket"`
+	Prefix          string `yaml:"prefix" mapstructure:"prefix"`
+	Region          string `yaml:"region" mapstructure:"region"`
+	Endpoint        string `yaml:"endpoint" mapstructure:"endpoint"`                                 // S3-compatible endpoint, e.g. MinIO; empty uses AWS or storage.googleapis.com
+	AccessKeyID     string `yaml:"access_key_id" mapstructure:"access_key_id"`                       // empty uses the AWS environment or IRSA
+	SecretAccessKey string `yaml:"secret_access_key" mapstructure:"secret_access_key" secret:"true"` // GCS HMAC secret for gcs
+	Path            string `yaml:"path" mapstructure:"path"`                                         // dir: root directory, e.g. a mounted volume
+}
+
+// ArchiveLifecycleConfig is the bucket lifecycle rule applied to the
+// archive prefix at startup. Applying it replaces the bucket's lifecycle
+// configuration, so leave apply off for buckets with rules of their own.
+type ArchiveLifecycleConfig struct {
+	Apply               bool   `yaml:"apply" mapstructure:"apply"`
+	TransitionAfterDays int    `yaml:"transition_after_days" mapstructure:"transition_after_days"` // 0 never transitions
+	StorageClass        string `yaml:"storage_class" mapstructure:"storage_class"`                 // e.g. GLACIER_IR on S3, COLDLINE on GCS
+	ExpireAfterDays     int    `yaml:"expire_after_days" mapstructure:"expire_after_days"`         // 0 keeps objects forever
+}
+
+// VerdictRedactableColumns are the verdict export columns that may be
+// redacted
+var VerdictRedactableColumns = []string{"request_id", "path", "client_ip", "user_agent", "matched_text"}
+
+// VerdictExportConfig controls `sentinel verdicts export`, which writes the
+// archived blocking and masking decisions over a date range as CSV or
+// Parquet with a signed manifest for compliance review. It reads the
+// archive, so vector_security and pii_detection events must be archived.
+type VerdictExportConfig struct {
+	SigningKey string   `yaml:"signing_key" mapstructure:"signing_key" secret:"true"` // HMAC-SHA256 key signing each export's manifest
+	HashKey    string   `yaml:"hash_key" mapstructure:"hash_key" secret:"true"`       // HMAC-SHA256 key pseudonymizing redacted columns in hash mode; separate from signing_key
+	Redact     []string `yaml:"redact" mapstructure:"redact"`                         // columns redacted in the export
+	RedactMode string   `yaml:"redact_mode" mapstructure:"redact_mode"`               // hash (keyed, so values can still be correlated) or remove
+}
+
+// EventSinkConfig describes one downstream event sink
+type EventSinkConfig struct {
+	Name       string            `yaml:"name" mapstructure:"name"`
+	Type       string            `yaml:"type" mapstructure:"type"` // http
+	URL        string            `yaml:"url" mapstructure:"url"`
+	Timeout    time.Duration     `yaml:"timeout" mapstructure:"timeout"`
+	Headers    map[string]string `yaml:"headers" mapstructure:"headers" secret:"true"` // often carry credentials
+	EventTypes []string          `yaml:"event_types" mapstructure:"event_types"`       // empty means security events only
+}
+
+// WebhookEvents are the detection outcomes a webhook can subscribe to
+var WebhookEvents = []string{"request_blocked", "high_severity_finding"}
+
+// WebhookConfig describes an outbound webhook fired when a request is
+// blocked or a finding reaches a severity, for ticketing and SOAR systems.
+// Deliveries are signed with HMAC-SHA256 when a secret is set.
+type WebhookConfig struct {
+	Name        string             `yaml:"name" mapstructure:"name"`
+	URL         string             `yaml:"url" mapstructure:"url"`
+	Headers     map[string]string  `yaml:"headers" mapstructure:"headers" secret:"true"` // often carry credentials
+	Secret      string             `yaml:"secret" mapstructure:"secret" secret:"true"`   // HMAC signing secret; empty sends unsigned
+	Timeout     time.Duration      `yaml:"timeout" mapstructure:"timeout"`
+	Events      []string           `yaml:"events" mapstructure:"events"`             // request_blocked, high_severity_finding; empty means both
+	MinSeverity string             `yaml:"min_severity" mapstructure:"min_severity"` // high_severity_finding threshold, low to critical (default high)
+	Retry       WebhookRetryConfig `yaml:"retry" mapstructure:"retry"`
+}
+
+// WebhookRetryConfig bounds redelivery of a failed webhook. Zero values
+// select 5 attempts backing off from 1s to 1m.
+type WebhookRetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts" mapstructure:"max_attempts"` // including the first
+	InitialBackoff time.Duration `yaml:"initial_backoff" mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"`
+}
+
+// ChaosConfig contains fault injection settings for resilience testing,
+// switchable at runtime through the token-protected /admin/chaos endpoint.
+// Never enable in production.
+type ChaosConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`
+	Latency   time.Duration `yaml:"latency" mapstructure:"latency"`
+	Jitter    time.Duration `yaml:"jitter" mapstructure:"jitter"`
+	ErrorRate float64       `yaml:"error_rate" mapstructure:"error_rate"`
+	Targets   []string      `yaml:"targets" mapstructure:"targets"`     // redis, postgres, upstream; empty means all
+	Blackouts []string      `yaml:"blackouts" mapstructure:"blackouts"` // dependencies that always fail
+}
+
 // GetDefaults returns a configuration with sensible defaults
 func GetDefaults() *Config {
 	return &Config{
@@ -141,6 +1023,13 @@ func GetDefaults() *Config {
 			ReadTimeout:  30 * time.Second,
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  60 * time.Second,
+
+			MaintenanceRetryAfter: 60 * time.Second,
+
+			ConfigHistory: ConfigHistoryConfig{
+				Dir:         "./data/config-history",
+				MaxVersions: 100,
+			},
 		},
 		Privacy: PrivacyConfig{
 			Enabled:   true,
@@ -158,39 +1047,121 @@ func GetDefaults() *Config {
 				PreserveUpstreamAuth bool     `yaml:"preserve_upstream_auth" mapstructure:"preserve_upstream_auth"`
 			}{
 				Enabled:              true,
-				Headers:              []string{"authorization", "x-api-key", "cookie"},
+				Headers:              []string{"authorization", "x-api-key", "api-key", "cookie"},
 				PreserveUpstreamAuth: true,
 			},
+			RuleLimits: RuleLimitsConfig{
+				MaxProgramSize:    5000,
+				MaxNesting:        2,
+				Strict:            false,
+				MaxMatchTimePerKB: 2 * time.Millisecond,
+				SuspendAfter:      5,
+				SuspendFor:        5 * time.Minute,
+			},
 		},
 		Security: SecurityConfig{
 			Enabled: true,
 			Mode:    "log", // Default to log mode, not block
+			BlockResponse: BlockResponseConfig{
+				Format:     "text",
+				StatusCode: 403,
+			},
+			ClientAuth: ClientAuthConfig{
+				Header: "X-Sentinel-Key",
+				Database: ClientKeyDatabaseConfig{
+					RefreshInterval: time.Minute,
+				},
+			},
 			RateLimit: RateLimitConfig{
-				Enabled:        true,
-				RequestsPerMin: 60,
-				MaxRequestSize: 1048576, // 1MB
-				BurstLimit:     10,
+				Enabled:            true,
+				RequestsPerMin:     60,
+				MaxRequestSize:     1048576, // 1MB
+				OversizeStatusCode: 413,
+				BurstLimit:         10,
+				IPv4Prefix:         32,
+				IPv6Prefix:         64,
+				Backend:            "memory",
+				RedisKeyPrefix:     "sentinel:ratelimit:",
+			},
+			Conversations: ConversationConfig{
+				Enabled:          false,
+				Headers:          []string{"X-Conversation-ID", "OpenAI-Conversation-ID"},
+				BodyPaths:        []string{"$.conversation_id", "$.thread_id", "$.metadata.conversation_id", "$.metadata.thread_id"},
+				Policy:           "review",
+				TTL:              24 * time.Hour,
+				MaxConversations: 10000,
+			},
+			Anomaly: AnomalyConfig{
+				Enabled:           false,
+				BurstFactor:       5,
+				MinBurstRequests:  30,
+				MinObservations:   200,
+				OffHoursThreshold: 0.01,
+				Window:            15 * time.Minute,
+				MaxClients:        10000,
+				TightenThresholds: false,
+				TightenFactor:     0.8,
+			},
+			Templates: TemplateConfig{
+				Enabled:         false,
+				MinObservations: 100,
+				MinKnownShare:   0.9,
+				KnownAfter:      3,
+				MaxTemplates:    500,
+				MaxClients:      10000,
+				TightenFactor:   0.8,
+			},
+			RateAlerts: RateAlertConfig{
+				Enabled:         false,
+				Method:          "ewma",
+				Interval:        time.Minute,
+				Window:          10 * time.Minute,
+				Factor:          5,
+				MinCount:        10,
+				MinObservations: 60,
+				Cooldown:        30 * time.Minute,
+				Alpha:           0.1,
+				Beta:            0.01,
+				Gamma:           0.1,
+				SeasonLength:    24 * time.Hour,
 			},
 			VectorSecurity: VectorSecurityConfig{
-				Enabled:        true,
-				ServiceType:    "ml",
-				BlockThreshold: 0.70,
-				MaxBatchSize:   32,
+				Enabled:           true,
+				ServiceType:       "ml",
+				BlockThreshold:    0.70,
+				MaxBatchSize:      32,
+				FastPathMaxLength: 64,
+				Messages: MessagesConfig{
+					Scope: "all",
+					Roles: []string{"user"},
+				},
+				EmbeddingInputs: EmbeddingInputsConfig{
+					MaxInputs: 32,
+					Sampling:  "random",
+				},
+				CanaryCheck: CanaryCheckConfig{
+					Enabled:       true,
+					MinSimilarity: 0.99,
+					OnMismatch:    "warn",
+				},
 				Embedding: EmbeddingConfig{
 					ServiceType:  "ml",
 					RedisEnabled: true,
 					RedisURL:     "redis://localhost:6379",
 					RedisDB:      0,
 					Model: ModelConfig{
-						ModelName:     "sentence-transformers/all-MiniLM-L6-v2",
-						ModelPath:     "./models/minilm-l6-v2.onnx",
-						TokenizerPath: "./models/tokenizer.json",
-						VocabPath:     "./models/vocab.txt",
-						CacheDir:      "./models/cache",
-						AutoDownload:  true,
-						MaxLength:     512,
-						BatchSize:     32,
-						ModelTimeout:  30 * time.Second,
+						ModelName:      "sentence-transformers/all-MiniLM-L6-v2",
+						ModelPath:      "./models/minilm-l6-v2.onnx",
+						TokenizerPath:  "./models/tokenizer.json",
+						VocabPath:      "./models/vocab.txt",
+						CacheDir:       "./models/cache",
+						AutoDownload:   true,
+						MaxLength:      512,
+						BatchSize:      32,
+						ModelTimeout:   30 * time.Second,
+						MemoSize:       10000,
+						LocalCacheSize: 10000,
+						LocalCacheTTL:  5 * time.Minute,
 					},
 				},
 				Database: DatabaseConfig{
@@ -200,6 +1171,73 @@ func GetDefaults() *Config {
 					ConnMaxLifetime: time.Hour,
 					ConnMaxIdleTime: 30 * time.Minute,
 				},
+				Degradation: DegradationConfig{
+					SampleInterval:    5 * time.Second,
+					SustainSamples:    3,
+					DegradeCPU:        0.85,
+					RestoreCPU:        0.5,
+					DegradeQueueDepth: 64,
+					RestoreQueueDepth: 8,
+				},
+				KnownAttacks: KnownAttacksConfig{
+					RefreshInterval:   10 * time.Minute,
+					FalsePositiveRate: 1e-6,
+					RedisKey:          "llm-sentinel:known-attacks",
+				},
+				PoolMonitor: PoolMonitorConfig{
+					Enabled:        true,
+					SampleInterval: 30 * time.Second,
+					MaxWaits:       10,
+					MaxWaitTime:    time.Second,
+				},
+				Snapshot: SnapshotConfig{
+					Path:     "./data/corpus.snapshot",
+					Interval: 5 * time.Minute,
+				},
+				Replay: ReplayConfig{
+					Capacity: 1000,
+				},
+				VerdictCache: VerdictCacheConfig{
+					Lookup:        "exact",
+					MinSimilarity: 0.95,
+					LSHTables:     4,
+					LSHBits:       16,
+					Dimensions:    384,
+					LocalSize:     10000,
+					LocalTTL:      5 * time.Minute,
+					TTLTiers: []VerdictTTLTier{
+						{Name: "malicious_high", Label: 1, MinConfidence: 0.9, TTL: 24 * time.Hour},
+						{Name: "malicious_borderline", Label: 1, MinConfidence: 0, TTL: time.Hour},
+						{Name: "safe", Label: 0, MinConfidence: 0, TTL: 10 * time.Minute},
+					},
+				},
+				Reduction: ReductionConfig{
+					Method:     "none",
+					Dimensions: 128,
+					PCAPath:    "./data/pca.json",
+				},
+				Index: IndexConfig{
+					Type:           "ivfflat",
+					M:              16,
+					EfConstruction: 64,
+					Lists:          100,
+					MinVectors:     1000,
+				},
+				Normalization: NormalizationConfig{
+					Dedup:      TextNormalization{Trim: true, CollapseWhitespace: true, NFC: true},
+					Cache:      TextNormalization{Trim: true, CollapseWhitespace: true, NFC: true, Lowercase: true},
+					ExactMatch: TextNormalization{Trim: true, CollapseWhitespace: true, NFC: true},
+				},
+				Migration: MigrationConfig{
+					Secondary: DatabaseConfig{
+						MaxOpenConns:    10,
+						MaxIdleConns:    5,
+						ConnMaxLifetime: time.Hour,
+						ConnMaxIdleTime: 30 * time.Minute,
+					},
+					CompareReads:      true,
+					CompareSampleRate: 0.1,
+				},
 			},
 		},
 		Logging: LoggingConfig{
@@ -223,19 +1261,72 @@ func GetDefaults() *Config {
 			OpenAI:    "https://api.openai.com",
 			Anthropic: "https://api.anthropic.com",
 			Ollama:    "http://localhost:11434",
+			Gemini:    "https://generativelanguage.googleapis.com",
+			Vertex:    "https://us-central1-aiplatform.googleapis.com",
 			Timeout:   30 * time.Second,
+			AzureOpenAI: AzureOpenAIConfig{
+				APIVersion:  "2024-10-21",
+				Deployments: map[string]string{},
+				Auth:        "passthrough",
+			},
+			Egress: EgressConfig{
+				Routes: map[string]EgressProxyConfig{},
+			},
+			DNS: DNSConfig{
+				MinTTL:     5 * time.Second,
+				MaxTTL:     5 * time.Minute,
+				ServeStale: time.Minute,
+			},
+			Auth: UpstreamAuthConfig{
+				Headers: map[string][]string{
+					"openai":       {"Authorization"},
+					"anthropic":    {"X-Api-Key", "Authorization"},
+					"azure-openai": {"Api-Key", "Authorization"},
+					"vertex":       {"Authorization"},
+				},
+			},
+			Retry: UpstreamRetryConfig{
+				MaxAttempts:    3,
+				InitialBackoff: 250 * time.Millisecond,
+				MaxBackoff:     2 * time.Second,
+				Statuses:       []int{502, 503, 504},
+			},
+			CircuitBreaker: CircuitBreakerConfig{
+				FailureThreshold: 5,
+				OpenDuration:     30 * time.Second,
+				HalfOpenRequests: 1,
+			},
+			Streaming: StreamingConfig{
+				Enabled:     true,
+				MaxDuration: 10 * time.Minute,
+				LatencyAudit: LatencyAuditConfig{
+					SLO:            50 * time.Millisecond,
+					Percentile:     99,
+					ReportInterval: time.Hour,
+					MaxSamples:     10000,
+				},
+			},
+			Annotations: AnnotationConfig{
+				Header: "X-Sentinel-Annotation",
+				Issuer: "llm-sentinel",
+				TTL:    5 * time.Minute,
+			},
 		},
 		WebSocket: WebSocketConfig{
-			Enabled:         true,
-			Path:            "/ws",
-			MaxConnections:  100,
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
-			PingInterval:    54 * time.Second,
-			PongTimeout:     60 * time.Second,
-			WriteTimeout:    10 * time.Second,
-			MaxMessageSize:  512,
-			AllowedOrigins:  []string{"*"}, // Allow all origins for development
+			Enabled:          true,
+			Path:             "/ws",
+			MaxConnections:   100,
+			ReadBufferSize:   1024,
+			WriteBufferSize:  1024,
+			PingInterval:     54 * time.Second,
+			PongTimeout:      60 * time.Second,
+			WriteTimeout:     10 * time.Second,
+			MaxMessageSize:   512,
+			AllowedOrigins:   []string{"*"}, // Allow all origins for development
+			Compression:      true,
+			CompressionLevel: 1, // flate.BestSpeed; events are small and frequent
+			AllowMessagePack: true,
+			ReplayBuffer:     500,
 			Events: struct {
 				BroadcastPIIDetections  bool `yaml:"broadcast_pii_detections" mapstructure:"broadcast_pii_detections"`
 				BroadcastVectorSecurity bool `yaml:"broadcast_vector_security" mapstructure:"broadcast_vector_security"`
@@ -248,5 +1339,89 @@ func GetDefaults() *Config {
 				BroadcastConnections:    true,
 			},
 		},
+		Dedup: DedupConfig{
+			Window:           2 * time.Second,
+			MaxResponseBytes: 1 << 20,
+		},
+		Cost: CostConfig{
+			Pricing:                 map[string]ModelPrice{},
+			Action:                  "reject",
+			DefaultCompletionTokens: 4096,
+			CharsPerToken:           4,
+		},
+		ResponseCache: ResponseCacheConfig{
+			TTL:              5 * time.Minute,
+			MaxResponseBytes: 1 << 20,
+			KeyPrefix:        "sentinel:response:",
+		},
+		API: APIConfig{
+			Analyze: AnalyzeAPIConfig{
+				MaxTextLength: 64 * 1024,
+			},
+			Embeddings: EmbeddingsAPIConfig{
+				Model:     "all-MiniLM-L6-v2",
+				MaxInputs: 2048,
+			},
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		ETL: ETLConfig{
+			RejectsFile:    "./data/etl-rejects.json",
+			CheckpointFile: "./data/etl-checkpoints.json",
+			CopyThreshold:  64 * 1024 * 1024, // 64MB
+			Schema: DatasetSchemaConfig{
+				Text:           "text",
+				LabelText:      "label_text",
+				Label:          "label",
+				Header:         true,
+				MaliciousLabel: "prompt_injection",
+				SafeLabel:      "safe",
+			},
+			NearDuplicates: NearDuplicateConfig{
+				SimHashDistance: 6,
+				Similarity:      0.98,
+			},
+			HuggingFace: HuggingFaceDatasetConfig{
+				TokenEnv: "HF_TOKEN",
+				Config:   "default",
+				Split:    "train",
+				CacheDir: "./data/hf-cache",
+			},
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+			MaxAge:         10 * time.Minute,
+		},
+		ResponseHeaders: ResponseHeadersConfig{
+			Default: HeaderPolicy{
+				Set: map[string]string{"X-Content-Type-Options": "nosniff"},
+			},
+		},
+		Pipeline: PipelineConfig{
+			Default: []string{"dedup", "rate_limit", "anomaly", "conversation", "privacy", "vector_security"},
+		},
+		Events: EventsConfig{
+			QueueDir:        "./data/event-queue",
+			QueueMaxRecords: 100000,
+			QueueMaxBytes:   256 * 1024 * 1024, // 256MB
+			InitialBackoff:  time.Second,
+			MaxBackoff:      5 * time.Minute,
+			Archive: ArchiveConfig{
+				Dir:           "./data/event-archive",
+				Interval:      time.Hour,
+				MaxLocalBytes: 512 * 1024 * 1024, // 512MB
+				Store: ArchiveStoreConfig{
+					Type:   "s3",
+					Prefix: "llm-sentinel/events",
+				},
+				VerdictExport: VerdictExportConfig{
+					Redact:     []string{"client_ip", "user_agent", "matched_text"},
+					RedactMode: "hash",
+				},
+			},
+		},
 	}
 }
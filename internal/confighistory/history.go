@@ -0,0 +1,309 @@
+// Package confighistory keeps signed, versioned snapshots of the runtime
+// configuration on disk, one file per version, with the change each one
+// made relative to the version before it.
+package confighistory
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const snapshotExt = ".json"
+
+var (
+	// ErrNotFound is returned for a version that does not exist or was pruned
+	ErrNotFound = errors.New("config snapshot not found")
+	// ErrSignature is returned for a snapshot whose signature does not verify
+	ErrSignature = errors.New("config snapshot signature mismatch")
+)
+
+// Snapshot is one version of the runtime configuration
+type Snapshot struct {
+	Version   int             `json:"version"`
+	Timestamp time.Time       `json:"timestamp"`
+	Author    string          `json:"author"`
+	Action    string          `json:"action"`
+	Diff      []Change        `json:"diff"` // relative to the previous version
+	Config    json.RawMessage `json:"config,omitempty"`
+	Signature string          `json:"signature"` // hex HMAC-SHA256 over the other fields
+}
+
+// Change is one configuration value that differs between versions. From is
+// absent for added values and To for removed ones.
+type Change struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Summary describes a version without its configuration, for listings
+type Summary struct {
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+	Author    string    `json:"author"`
+	Action    string    `json:"action"`
+	Diff      []Change  `json:"diff"`
+	Verified  bool      `json:"verified"` // signature checks out with the current key
+}
+
+// Store persists snapshots under a directory, keeping at most maxVersions
+type Store struct {
+	dir         string
+	key         []byte
+	maxVersions int
+
+	mu       sync.Mutex
+	versions []int
+	latest   *Snapshot // nil until the first snapshot
+}
+
+// Open opens (or creates) the history in dir, picking up versions recorded
+// by a previous run
+func Open(dir, signingKey string, maxVersions int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create config history directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config history directory: %w", err)
+	}
+
+	s := &Store{dir: dir, key: []byte(signingKey), maxVersions: maxVersions}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(name, snapshotExt))
+		if err != nil {
+			continue
+		}
+		s.versions = append(s.versions, version)
+	}
+	sort.Ints(s.versions)
+
+	if len(s.versions) > 0 {
+		latest, err := s.read(s.versions[len(s.versions)-1])
+		if err != nil {
+			return nil, err
+		}
+		s.latest = latest
+	}
+	return s, nil
+}
+
+// Record stores config as a new version when it differs from the latest
+// one, returning nil when nothing changed
+func (s *Store) Record(author, action string, config interface{}) (*Snapshot, error) {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := &Snapshot{
+		Version:   1,
+		Timestamp: time.Now().UTC(),
+		Author:    author,
+		Action:    action,
+		Diff:      []Change{},
+		Config:    raw,
+	}
+	if s.latest != nil {
+		snapshot.Version = s.latest.Version + 1
+		snapshot.Diff, err = Diff(s.latest.Config, raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshot.Diff) == 0 {
+			return nil, nil
+		}
+	}
+	if snapshot.Signature, err = s.sign(snapshot); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config snapshot: %w", err)
+	}
+	path := s.path(snapshot.Version)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write config snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to commit config snapshot: %w", err)
+	}
+
+	s.versions = append(s.versions, snapshot.Version)
+	s.latest = snapshot
+	for len(s.versions) > s.maxVersions {
+		if err := os.Remove(s.path(s.versions[0])); err != nil && !os.IsNotExist(err) {
+			return snapshot, fmt.Errorf("failed to prune config snapshot %d: %w", s.versions[0], err)
+		}
+		s.versions = s.versions[1:]
+	}
+	return snapshot, nil
+}
+
+// List returns every kept version, newest first
+func (s *Store) List() ([]Summary, error) {
+	s.mu.Lock()
+	versions := append([]int(nil), s.versions...)
+	s.mu.Unlock()
+
+	summaries := make([]Summary, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		snapshot, err := s.read(versions[i])
+		if errors.Is(err, ErrNotFound) {
+			continue // pruned meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, Summary{
+			Version:   snapshot.Version,
+			Timestamp: snapshot.Timestamp,
+			Author:    snapshot.Author,
+			Action:    snapshot.Action,
+			Diff:      snapshot.Diff,
+			Verified:  s.verify(snapshot) == nil,
+		})
+	}
+	return summaries, nil
+}
+
+// Get returns a version after checking its signature
+func (s *Store) Get(version int) (*Snapshot, error) {
+	snapshot, err := s.read(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verify(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *Store) read(version int) (*Snapshot, error) {
+	data, err := os.ReadFile(s.path(version))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: version %d", ErrNotFound, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config snapshot %d: %w", version, err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("malformed config snapshot %d: %w", version, err)
+	}
+	return &snapshot, nil
+}
+
+// sign computes the snapshot's signature over its canonical JSON encoding
+func (s *Store) sign(snapshot *Snapshot) (string, error) {
+	unsigned := *snapshot
+	unsigned.Signature = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config snapshot: %w", err)
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *Store) verify(snapshot *Snapshot) error {
+	expected, err := s.sign(snapshot)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(snapshot.Signature)) {
+		return fmt.Errorf("%w: version %d", ErrSignature, snapshot.Version)
+	}
+	return nil
+}
+
+func (s *Store) path(version int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%08d%s", version, snapshotExt))
+}
+
+// Diff lists the values that differ between two JSON documents, by dotted
+// path with array elements as [i]
+func Diff(from, to json.RawMessage) ([]Change, error) {
+	var before, after interface{}
+	if err := json.Unmarshal(from, &before); err != nil {
+		return nil, fmt.Errorf("malformed config: %w", err)
+	}
+	if err := json.Unmarshal(to, &after); err != nil {
+		return nil, fmt.Errorf("malformed config: %w", err)
+	}
+
+	changes := []Change{}
+	diffValues("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func diffValues(path string, before, after interface{}, changes *[]Change) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]bool, len(beforeMap)+len(afterMap))
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		for key := range keys {
+			diffValues(joinPath(path, key), beforeMap[key], afterMap[key], changes)
+		}
+		return
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		for i := 0; i < len(beforeList) || i < len(afterList); i++ {
+			var b, a interface{}
+			if i < len(beforeList) {
+				b = beforeList[i]
+			}
+			if i < len(afterList) {
+				a = afterList[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), b, a, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: path, From: before, To: after})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
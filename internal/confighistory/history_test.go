@@ -0,0 +1,125 @@
+package confighistory
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Mode   string   `json:"mode"`
+	Limits []int    `json:"limits"`
+	Hosts  []string `json:"hosts,omitempty"`
+}
+
+// TestRecordAndReopen checks versions are only recorded on change, diffed
+// against the previous version and picked up again after a restart
+func TestRecordAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, "signing-key", 10)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	first, err := store.Record("admin", "startup", testConfig{Mode: "warn", Limits: []int{1, 2}})
+	if err != nil || first.Version != 1 || len(first.Diff) != 0 {
+		t.Fatalf("first Record = %+v, %v", first, err)
+	}
+	if unchanged, err := store.Record("admin", "reload", testConfig{Mode: "warn", Limits: []int{1, 2}}); unchanged != nil || err != nil {
+		t.Fatalf("unchanged Record = %+v, %v, want nil", unchanged, err)
+	}
+	second, err := store.Record("ops", "update", testConfig{Mode: "block", Limits: []int{1}, Hosts: []string{"a"}})
+	if err != nil || second.Version != 2 {
+		t.Fatalf("second Record = %+v, %v", second, err)
+	}
+	want := []Change{
+		{Path: "hosts", To: []interface{}{"a"}},
+		{Path: "limits[1]", From: float64(2)},
+		{Path: "mode", From: "warn", To: "block"},
+	}
+	if len(second.Diff) != len(want) {
+		t.Fatalf("Diff = %+v, want %+v", second.Diff, want)
+	}
+	for i, change := range second.Diff {
+		if change.Path != want[i].Path {
+			t.Fatalf("Diff[%d] = %+v, want %+v", i, change, want[i])
+		}
+	}
+
+	reopened, err := Open(dir, "signing-key", 10)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	third, err := reopened.Record("ops", "update", testConfig{Mode: "log", Limits: []int{1}, Hosts: []string{"a"}})
+	if err != nil || third.Version != 3 || len(third.Diff) != 1 || third.Diff[0].Path != "mode" {
+		t.Fatalf("Record after reopen = %+v, %v", third, err)
+	}
+
+	summaries, err := reopened.List()
+	if err != nil || len(summaries) != 3 || summaries[0].Version != 3 || !summaries[2].Verified {
+		t.Fatalf("List = %+v, %v", summaries, err)
+	}
+}
+
+// TestSignatureVerification checks tampered snapshots and snapshots signed
+// with another key are refused by Get and flagged by List
+func TestSignatureVerification(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, "signing-key", 10)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	store.Record("admin", "startup", testConfig{Mode: "warn"})
+	store.Record("admin", "update", testConfig{Mode: "block"})
+
+	if _, err := store.Get(1); err != nil {
+		t.Fatalf("Get intact snapshot: %v", err)
+	}
+
+	path := store.path(1)
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), `"warn"`, `"off"`, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(1); !errors.Is(err, ErrSignature) {
+		t.Fatalf("Get tampered snapshot = %v, want ErrSignature", err)
+	}
+
+	summaries, _ := store.List()
+	if len(summaries) != 2 || !summaries[0].Verified || summaries[1].Verified {
+		t.Fatalf("List = %+v, want only version 2 verified", summaries)
+	}
+
+	otherKey, err := Open(dir, "other-key", 10)
+	if err != nil {
+		t.Fatalf("Open with other key: %v", err)
+	}
+	if _, err := otherKey.Get(2); !errors.Is(err, ErrSignature) {
+		t.Fatalf("Get with other key = %v, want ErrSignature", err)
+	}
+	if _, err := store.Get(9); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing version = %v, want ErrNotFound", err)
+	}
+}
+
+// TestPruning checks only the newest maxVersions snapshots are kept
+func TestPruning(t *testing.T) {
+	store, err := Open(t.TempDir(), "signing-key", 2)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, mode := range []string{"off", "warn", "block"} {
+		if _, err := store.Record("admin", "update", testConfig{Mode: mode}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	summaries, _ := store.List()
+	if len(summaries) != 2 || summaries[0].Version != 3 || summaries[1].Version != 2 {
+		t.Fatalf("List = %+v, want versions 3 and 2", summaries)
+	}
+	if _, err := store.Get(1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get pruned version = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package ctxkeys defines the typed keys used to carry per-request values
+// through the middleware chain. Keys are compared by identity, so values set
+// by different packages can never collide even if their names match.
+package ctxkeys
+
+import (
+	"context"
+	"net/http"
+)
+
+// Key is a typed context key for values of type T
+type Key[T any] struct {
+	name string
+}
+
+// New creates a key. The name is only used for debugging.
+func New[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String returns the key's name
+func (k *Key[T]) String() string {
+	return "ctxkeys." + k.name
+}
+
+// WithValue returns a copy of ctx carrying v under this key
+func (k *Key[T]) WithValue(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value returns the value stored under this key, if any
+func (k *Key[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// RequestPolicy is the security policy resolved for a request
+type RequestPolicy struct {
+	Name           string   // matching security policy; empty for the global settings
+	Mode           string   // block, log, or passthrough
+	BlockThreshold float32  // 0 uses the analyzer's threshold
+	Detectors      []string // PII detectors; nil uses the enabled set
+	Tightened      bool     // block threshold lowered, e.g. during a client anomaly
+	NewTemplate    bool     // prompt template new to a client that mostly reuses known ones; analyzed strictly with a lowered threshold
+}
+
+// AnalysisVerdict summarizes the outcome of prompt analysis for a request
+type AnalysisVerdict struct {
+	Analyzed   bool // false when analysis was skipped or failed
+	Malicious  bool // flagged, whether or not the mode blocked it
+	Blocked    bool
+	AttackType string
+	Scores     map[string]float32 // per attack category
+	Confidence float32
+	SkipReason string
+}
+
+// Keys for values set by the proxy middleware chain
+var (
+	RequestID        = New[string]("request_id")
+	OriginalHeaders  = New[http.Header]("original_headers")
+	ProcessedHeaders = New[http.Header]("processed_headers")
+	ClientIdentity   = New[string]("client_identity")
+	Client           = New[string]("client") // authenticated client_auth client
+	ConversationID   = New[string]("conversation_id")
+	Policy           = New[RequestPolicy]("policy")
+	Verdict          = New[AnalysisVerdict]("verdict")
+	Streaming        = New[bool]("streaming") // client asked for a streamed response
+)
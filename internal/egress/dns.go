@@ -0,0 +1,480 @@
+package egress
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsQueryTimeout bounds the direct nameserver queries of a lookup; the
+// system resolver fallback gets the rest of dnsLookupTimeout
+const (
+	dnsQueryTimeout  = 2 * time.Second
+	dnsLookupTimeout = 10 * time.Second
+)
+
+// systemLookup resolves names the nameservers cannot answer
+var systemLookup = net.DefaultResolver.LookupNetIP
+
+// ErrAddressNotAllowed is returned when a host resolves only to addresses
+// outside its allowlist
+var ErrAddressNotAllowed = errors.New("resolved addresses are outside the host's allowlist")
+
+// Resolver resolves and dials upstream hosts. Answers are cached for their
+// record TTL (clamped to the configured bounds), pinned hosts skip DNS, and
+// allowlisted hosts only connect to addresses in their allowlist.
+type Resolver struct {
+	cfg         config.DNSConfig
+	logger      *zap.Logger
+	nameservers []string
+	pins        map[string][]netip.Addr
+	allow       map[string][]netip.Prefix
+	dialer      net.Dialer
+
+	mu       sync.Mutex
+	entries  map[string]dnsEntry
+	inflight map[string]*dnsLookup
+}
+
+type dnsEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+// dnsLookup is a resolution in progress shared by concurrent callers
+type dnsLookup struct {
+	done  chan struct{}
+	addrs []netip.Addr
+	ttl   time.Duration
+	err   error
+}
+
+// NewResolver builds a resolver from cfg. It returns nil when caching is off
+// and no host is pinned or allowlisted, so connections use the system
+// resolver as before.
+func NewResolver(cfg config.DNSConfig, logger *zap.Logger) (*Resolver, error) {
+	if !cfg.CacheEnabled && len(cfg.Hosts) == 0 {
+		return nil, nil
+	}
+
+	r := &Resolver{
+		cfg:         cfg,
+		logger:      logger,
+		nameservers: systemNameservers("/etc/resolv.conf"),
+		pins:        make(map[string][]netip.Addr),
+		allow:       make(map[string][]netip.Prefix),
+		entries:     make(map[string]dnsEntry),
+		inflight:    make(map[string]*dnsLookup),
+	}
+	for _, host := range cfg.Hosts {
+		name := strings.ToLower(strings.TrimSuffix(host.Host, "."))
+		for _, ip := range host.Pin {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dns pin for %s: %w", host.Host, err)
+			}
+			r.pins[name] = append(r.pins[name], addr.Unmap())
+		}
+		for _, entry := range host.Allow {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(entry)
+				if addrErr != nil {
+					return nil, fmt.Errorf("invalid dns allowlist entry for %s: %w", host.Host, err)
+				}
+				prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+			}
+			r.allow[name] = append(r.allow[name], prefix.Masked())
+		}
+	}
+	return r, nil
+}
+
+// DialContext connects to address, resolving its host through the resolver
+// and trying each allowed address in turn. It fits http.Transport.DialContext.
+func (r *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return r.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := r.Lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// Lookup returns the addresses host may be reached at
+func (r *Resolver) Lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if pinned, ok := r.pins[host]; ok {
+		return pinned, nil
+	}
+	if !r.cfg.CacheEnabled {
+		addrs, _, err := r.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return r.filter(host, addrs)
+	}
+
+	r.mu.Lock()
+	entry, cached := r.entries[host]
+	if cached && time.Now().Before(entry.expires) {
+		r.mu.Unlock()
+		return entry.addrs, nil
+	}
+	lookup, running := r.inflight[host]
+	if !running {
+		lookup = &dnsLookup{done: make(chan struct{})}
+		r.inflight[host] = lookup
+	}
+	r.mu.Unlock()
+
+	if !running {
+		// Detached from the caller so one cancelled request does not fail
+		// everyone waiting on the same lookup
+		lookupCtx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+		addrs, ttl, err := r.resolve(lookupCtx, host)
+		cancel()
+		if err == nil {
+			addrs, err = r.filter(host, addrs)
+		}
+		lookup.addrs, lookup.ttl, lookup.err = addrs, r.clampTTL(ttl), err
+
+		r.mu.Lock()
+		if err == nil {
+			r.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(lookup.ttl)}
+		}
+		delete(r.inflight, host)
+		r.mu.Unlock()
+		close(lookup.done)
+	}
+
+	select {
+	case <-lookup.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if lookup.err != nil {
+		// Ride out resolver hiccups on the last good answer, but never past
+		// an allowlist rejection
+		if cached && !errors.Is(lookup.err, ErrAddressNotAllowed) && time.Since(entry.expires) < r.cfg.ServeStale {
+			r.logger.Warn("DNS lookup failed; using stale answer",
+				zap.String("host", host),
+				zap.Duration("expired_for", time.Since(entry.expires)),
+				zap.Error(lookup.err))
+			return entry.addrs, nil
+		}
+		return nil, lookup.err
+	}
+	return lookup.addrs, nil
+}
+
+// filter drops addresses outside host's allowlist
+func (r *Resolver) filter(host string, addrs []netip.Addr) ([]netip.Addr, error) {
+	prefixes, ok := r.allow[host]
+	if !ok {
+		return addrs, nil
+	}
+	allowed := make([]netip.Addr, 0, len(addrs))
+	for _, addr := range addrs {
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				allowed = append(allowed, addr)
+				break
+			}
+		}
+	}
+	if len(allowed) < len(addrs) {
+		r.logger.Warn("DNS answer outside allowlist",
+			zap.String("host", host),
+			zap.Stringers("addresses", addrs),
+			zap.Int("allowed", len(allowed)))
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAddressNotAllowed, host)
+	}
+	return allowed, nil
+}
+
+// clampTTL bounds a record TTL; answers without one get the minimum
+func (r *Resolver) clampTTL(ttl time.Duration) time.Duration {
+	if ttl < r.cfg.MinTTL {
+		return r.cfg.MinTTL
+	}
+	if r.cfg.MaxTTL > 0 && ttl > r.cfg.MaxTTL {
+		return r.cfg.MaxTTL
+	}
+	return ttl
+}
+
+// resolve looks host up with the nameservers directly to learn record TTLs.
+// Names they cannot answer (hosts file entries, search domains) fall back
+// to the system resolver, which reports no TTL.
+func (r *Resolver) resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	addrs, ttl, err := r.query(queryCtx, host)
+	cancel()
+	if err == nil && len(addrs) > 0 {
+		return addrs, ttl, nil
+	}
+	addrs, err = systemLookup(ctx, "ip", host)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range addrs {
+		addrs[i] = addrs[i].Unmap()
+	}
+	return addrs, 0, nil
+}
+
+// query asks each nameserver in turn for host's A and AAAA records until
+// one answers
+func (r *Resolver) query(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	type reply struct {
+		addrs []netip.Addr
+		ttl   uint32
+		err   error
+	}
+
+	lastErr := errors.New("no nameservers configured")
+	for _, server := range r.nameservers {
+		qtypes := []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+		replies := make([]reply, len(qtypes))
+		var wg sync.WaitGroup
+		for i, qtype := range qtypes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				addrs, ttl, err := exchange(ctx, server, name, qtype)
+				replies[i] = reply{addrs, ttl, err}
+			}()
+		}
+		wg.Wait()
+
+		var addrs []netip.Addr
+		var minTTL uint32
+		answered := false
+		for _, reply := range replies {
+			if reply.err != nil {
+				lastErr = reply.err
+				continue
+			}
+			answered = true
+			if len(reply.addrs) > 0 && (len(addrs) == 0 || reply.ttl < minTTL) {
+				minTTL = reply.ttl
+			}
+			addrs = append(addrs, reply.addrs...)
+		}
+		if answered {
+			return addrs, time.Duration(minTTL) * time.Second, nil
+		}
+	}
+	return nil, 0, lastErr
+}
+
+// errTruncated marks a UDP reply too large for a datagram
+var errTruncated = errors.New("truncated DNS response")
+
+// exchange sends one question to server and returns the addresses in the
+// answer with the lowest TTL along the (CNAME) chain. It asks over UDP and
+// retries over TCP when the reply is truncated.
+func exchange(ctx context.Context, server string, name dnsmessage.Name, qtype dnsmessage.Type) ([]netip.Addr, uint32, error) {
+	id := uint16(rand.Uint32())
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	msg, err := builder.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reply, err := exchangeUDP(ctx, server, id, msg)
+	if errors.Is(err, errTruncated) {
+		reply, err = exchangeTCP(ctx, server, id, msg)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(reply)
+	if err != nil {
+		return nil, 0, err
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DNS lookup of %s failed: %s", name, header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+	return parseAnswers(&parser)
+}
+
+// exchangeUDP sends msg in a datagram and returns the reply matching id
+func exchangeUDP(ctx context.Context, server string, id uint16, msg []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.ID != id || !header.Response {
+			continue // not our reply
+		}
+		if header.Truncated {
+			return nil, errTruncated
+		}
+		return buf[:n], nil
+	}
+}
+
+// exchangeTCP sends msg over a TCP connection, framed by its two-byte
+// length, and returns the reply
+func exchangeTCP(ctx context.Context, server string, id uint16, msg []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	framed := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(msg)), uint16(len(msg)))
+	if _, err := conn.Write(append(framed, msg...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(reply)
+	if err != nil {
+		return nil, err
+	}
+	if header.ID != id || !header.Response {
+		return nil, errors.New("mismatched DNS response over TCP")
+	}
+	return reply, nil
+}
+
+// parseAnswers collects A and AAAA records and the lowest TTL in the answer
+func parseAnswers(parser *dnsmessage.Parser) ([]netip.Addr, uint32, error) {
+	var addrs []netip.Addr
+	var minTTL uint32
+	first := true
+	for {
+		header, err := parser.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return addrs, minTTL, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if first || header.TTL < minTTL {
+			minTTL, first = header.TTL, false
+		}
+		switch header.Type {
+		case dnsmessage.TypeA:
+			record, err := parser.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			addrs = append(addrs, netip.AddrFrom4(record.A))
+		case dnsmessage.TypeAAAA:
+			record, err := parser.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			addrs = append(addrs, netip.AddrFrom16(record.AAAA).Unmap())
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+}
+
+// systemNameservers reads nameserver addresses from a resolv.conf file
+func systemNameservers(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(fields[1]); err == nil {
+			servers = append(servers, net.JoinHostPort(addr.String(), "53"))
+		}
+	}
+	return servers
+}
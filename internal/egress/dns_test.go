@@ -0,0 +1,267 @@
+package egress
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeNameserver answers A queries on UDP and TCP at the same address
+type fakeNameserver struct {
+	addr     string
+	queries  atomic.Int64
+	tcp      atomic.Int64
+	mu       sync.Mutex
+	answers  []netip.Addr
+	ttl      uint32
+	rcode    dnsmessage.RCode
+	truncate bool // UDP replies set TC=1 with no answers
+}
+
+func newFakeNameserver(t *testing.T, answers ...string) *fakeNameserver {
+	t.Helper()
+	ns := &fakeNameserver{ttl: 300}
+	for _, a := range answers {
+		ns.answers = append(ns.answers, netip.MustParseAddr(a))
+	}
+
+	var udp net.PacketConn
+	var tcp net.Listener
+	for attempt := 0; ; attempt++ {
+		var err error
+		udp, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tcp, err = net.Listen("tcp", udp.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		udp.Close()
+		if attempt == 10 {
+			t.Fatalf("no port free for both UDP and TCP: %v", err)
+		}
+	}
+	ns.addr = udp.LocalAddr().String()
+	t.Cleanup(func() { udp.Close(); tcp.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			udp.WriteTo(ns.reply(buf[:n], true), from)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					ns.tcp.Add(1)
+					reply := ns.reply(query, false)
+					conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(reply))), reply...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return ns
+}
+
+func (ns *fakeNameserver) set(rcode dnsmessage.RCode, ttl uint32) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.rcode, ns.ttl = rcode, ttl
+}
+
+func (ns *fakeNameserver) reply(query []byte, udp bool) []byte {
+	ns.queries.Add(1)
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil
+	}
+
+	truncated := udp && ns.truncate
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Truncated: truncated, RCode: ns.rcode})
+	builder.StartQuestions()
+	builder.Question(question)
+	builder.StartAnswers()
+	if !truncated && ns.rcode == dnsmessage.RCodeSuccess && question.Type == dnsmessage.TypeA {
+		for _, addr := range ns.answers {
+			builder.AResource(dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: ns.ttl},
+				dnsmessage.AResource{A: addr.As4()})
+		}
+	}
+	msg, _ := builder.Finish()
+	return msg
+}
+
+// newTestResolver points a resolver at ns and fails the system fallback
+func newTestResolver(t *testing.T, ns *fakeNameserver, cfg config.DNSConfig) *Resolver {
+	t.Helper()
+	original := systemLookup
+	systemLookup = func(context.Context, string, string) ([]netip.Addr, error) {
+		return nil, errors.New("system resolver disabled in tests")
+	}
+	t.Cleanup(func() { systemLookup = original })
+
+	r, err := NewResolver(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.nameservers = []string{ns.addr}
+	return r
+}
+
+func addrs(values ...string) []netip.Addr {
+	out := make([]netip.Addr, len(values))
+	for i, v := range values {
+		out[i] = netip.MustParseAddr(v)
+	}
+	return out
+}
+
+// TestResolverTCPFallback checks a truncated UDP reply is retried over TCP
+func TestResolverTCPFallback(t *testing.T) {
+	ns := newFakeNameserver(t, "203.0.113.10", "203.0.113.11")
+	ns.truncate = true
+	r := newTestResolver(t, ns, config.DNSConfig{CacheEnabled: true, MinTTL: time.Second})
+
+	got, err := r.Lookup(context.Background(), "api.example.test")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if !slices.Equal(got, addrs("203.0.113.10", "203.0.113.11")) {
+		t.Fatalf("Lookup = %v, want the TCP answer", got)
+	}
+	if ns.tcp.Load() == 0 {
+		t.Fatal("truncated reply not retried over TCP")
+	}
+}
+
+// TestResolverAllowlist checks answers outside a host's allowlist are
+// dropped, and a host with none left fails
+func TestResolverAllowlist(t *testing.T) {
+	ns := newFakeNameserver(t, "10.0.0.5", "203.0.113.10")
+	r := newTestResolver(t, ns, config.DNSConfig{CacheEnabled: true, MinTTL: time.Second, Hosts: []config.DNSHostConfig{
+		{Host: "api.example.test", Allow: []string{"203.0.113.0/24"}},
+		{Host: "blocked.example.test", Allow: []string{"198.51.100.7"}},
+	}})
+
+	got, err := r.Lookup(context.Background(), "API.example.test.")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if !slices.Equal(got, addrs("203.0.113.10")) {
+		t.Fatalf("Lookup = %v, want only the allowlisted address", got)
+	}
+	if _, err := r.Lookup(context.Background(), "blocked.example.test"); !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("Lookup outside allowlist = %v, want ErrAddressNotAllowed", err)
+	}
+}
+
+// TestResolverPin checks pinned hosts never reach DNS
+func TestResolverPin(t *testing.T) {
+	ns := newFakeNameserver(t, "203.0.113.10")
+	r := newTestResolver(t, ns, config.DNSConfig{Hosts: []config.DNSHostConfig{
+		{Host: "api.example.test", Pin: []string{"192.0.2.1", "::ffff:192.0.2.2"}},
+	}})
+
+	got, err := r.Lookup(context.Background(), "api.example.test")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if !slices.Equal(got, addrs("192.0.2.1", "192.0.2.2")) {
+		t.Fatalf("Lookup = %v, want the pinned addresses", got)
+	}
+	if n := ns.queries.Load(); n != 0 {
+		t.Fatalf("pinned host sent %d DNS queries", n)
+	}
+}
+
+// TestResolverServeStale checks an expired answer is used while lookups
+// fail, but only within serve_stale
+func TestResolverServeStale(t *testing.T) {
+	ns := newFakeNameserver(t, "203.0.113.10")
+	ns.set(dnsmessage.RCodeSuccess, 0)
+	r := newTestResolver(t, ns, config.DNSConfig{CacheEnabled: true, MinTTL: 20 * time.Millisecond, ServeStale: time.Hour})
+
+	if _, err := r.Lookup(context.Background(), "api.example.test"); err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	ns.set(dnsmessage.RCodeServerFailure, 0)
+	time.Sleep(40 * time.Millisecond)
+
+	got, err := r.Lookup(context.Background(), "api.example.test")
+	if err != nil {
+		t.Fatalf("Lookup during outage: %v", err)
+	}
+	if !slices.Equal(got, addrs("203.0.113.10")) {
+		t.Fatalf("Lookup during outage = %v, want the stale answer", got)
+	}
+
+	r.cfg.ServeStale = time.Millisecond
+	if _, err := r.Lookup(context.Background(), "api.example.test"); err == nil {
+		t.Fatal("stale answer served past serve_stale")
+	}
+}
+
+// TestResolverTTLClamp checks record TTLs are held within min_ttl and max_ttl
+func TestResolverTTLClamp(t *testing.T) {
+	cfg := config.DNSConfig{CacheEnabled: true, MinTTL: time.Minute, MaxTTL: time.Hour}
+	tests := []struct {
+		name string
+		ttl  uint32
+		want time.Duration
+	}{
+		{"below min", 5, time.Minute},
+		{"within bounds", 600, 10 * time.Minute},
+		{"above max", 86400, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := newFakeNameserver(t, "203.0.113.10")
+			ns.set(dnsmessage.RCodeSuccess, tt.ttl)
+			r := newTestResolver(t, ns, cfg)
+
+			before := time.Now()
+			if _, err := r.Lookup(context.Background(), "api.example.test"); err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			r.mu.Lock()
+			expires := r.entries["api.example.test"].expires
+			r.mu.Unlock()
+			if got := expires.Sub(before); got < tt.want || got > tt.want+time.Second {
+				t.Fatalf("cached for %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
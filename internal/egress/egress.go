@@ -0,0 +1,66 @@
+// Package egress controls outbound connections to upstream providers and the
+// model hub: the HTTP(S) or SOCKS5 proxies corporate networks require, and
+// cached, pinned DNS resolution of upstream hosts
+package egress
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyFunc returns the http.Transport Proxy function for route: the route's
+// own proxy when one is configured, otherwise the default. It returns nil
+// when connections should be made directly. Loopback destinations are never
+// proxied.
+func ProxyFunc(cfg config.EgressConfig, route string) (func(*http.Request) (*url.URL, error), error) {
+	proxy := cfg.Default
+	if routeProxy, ok := cfg.Routes[route]; ok {
+		proxy = routeProxy
+	}
+	if proxy.URL == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := parseProxyURL(proxy)
+	if err != nil {
+		return nil, err
+	}
+	forURL := (&httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    strings.Join(proxy.NoProxy, ","),
+	}).ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return forURL(req.URL)
+	}, nil
+}
+
+// parseProxyURL validates a proxy URL and attaches its credentials
+func parseProxyURL(proxy config.EgressProxyConfig) (*url.URL, error) {
+	u, err := url.Parse(proxy.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid egress proxy URL: %s", proxy.URL)
+	}
+	if !containsString(config.EgressSchemes, u.Scheme) {
+		return nil, fmt.Errorf("invalid egress proxy URL: %s (scheme must be one of %s)", proxy.URL, strings.Join(config.EgressSchemes, ", "))
+	}
+	if proxy.Username != "" {
+		u.User = url.UserPassword(proxy.Username, proxy.Password)
+	}
+	return u, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
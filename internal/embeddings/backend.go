@@ -17,5 +17,5 @@ type TransformerBackend interface {
 }
 
 // NewTransformerBackend creates a backend if supported by the current build.
-// The default (no build tags) returns nil to avoid CGO dependencies.
-// Note: Implementations are provided in build-tagged files, e.g., backend_onnx.go and backend_stub.go
+// Builds with cgo use ONNX Runtime (backend_onnx.go); CGO_ENABLED=0 builds or
+// the 'noonnx' tag get backend_stub.go, which returns nil.
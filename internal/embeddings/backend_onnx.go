@@ -1,5 +1,4 @@
-//go:build onnx
-// +build onnx
+//go:build cgo && !noonnx
 
 package embeddings
 
@@ -25,8 +24,16 @@ type OnnxBackend struct {
 	mu         sync.RWMutex
 }
 
-// NewTransformerBackend initializes the ONNX Runtime backend. Requires build tag 'onnx'.
+// NewTransformerBackend initializes the ONNX Runtime backend. It is built
+// whenever cgo is available (opt out with the 'noonnx' tag) and returns nil
+// when the ONNX Runtime shared library or the model cannot be loaded, so the
+// service falls back to simulated embeddings.
 func NewTransformerBackend(logger *zap.Logger, modelPath string, maxLength int) TransformerBackend {
+	if !strings.HasSuffix(strings.ToLower(modelPath), ".onnx") {
+		logger.Info("Model is not an ONNX file; skipping ONNX Runtime backend", zap.String("model", modelPath))
+		return nil
+	}
+
 	// Allow user to provide shared library path via environment variable.
 	if shlib := os.Getenv("ONNXRUNTIME_SHARED_LIB"); shlib != "" {
 		ort.SetSharedLibraryPath(shlib)
@@ -34,9 +41,12 @@ func NewTransformerBackend(logger *zap.Logger, modelPath string, maxLength int)
 		ort.SetSharedLibraryPath(shlib)
 	}
 
-	if err := ort.InitializeEnvironment(); err != nil {
-		logger.Error("ONNX Runtime environment init failed", zap.Error(err))
-		return nil
+	// The environment is process-wide and may already be up for another service
+	if !ort.IsInitialized() {
+		if err := ort.InitializeEnvironment(); err != nil {
+			logger.Warn("ONNX Runtime environment init failed; set ONNXRUNTIME_SHARED_LIB to libonnxruntime", zap.Error(err))
+			return nil
+		}
 	}
 
 	// Inspect model IO to determine names
@@ -251,7 +261,8 @@ func (b *OnnxBackend) EmbedBatch(ctx context.Context, tokensBatch []*TokenizedIn
 			copy(res[i], data[start:end])
 		}
 	} else if len(outShape) == 3 {
-		// [batch, seq, dims] -> mean pool over seq
+		// [batch, seq, dims] -> mean pool over attended tokens, as
+		// sentence-transformers does, so padding does not dilute the embedding
 		seq := int(outShape[1])
 		dims := int(outShape[2])
 		if dims != EmbeddingDimensions {
@@ -262,15 +273,22 @@ func (b *OnnxBackend) EmbedBatch(ctx context.Context, tokensBatch []*TokenizedIn
 		}
 		for b := 0; b < batch; b++ {
 			pooled := make([]float32, EmbeddingDimensions)
+			attended := 0
 			for s := 0; s < seq; s++ {
+				if s < seqLen && attention[b*seqLen+s] == 0 {
+					continue
+				}
+				attended++
 				offset := (b*seq + s) * dims
 				for d := 0; d < dims; d++ {
 					pooled[d] += data[offset+d]
 				}
 			}
-			inv := 1.0 / float32(seq)
-			for d := 0; d < dims; d++ {
-				pooled[d] *= inv
+			if attended > 0 {
+				inv := 1.0 / float32(attended)
+				for d := 0; d < dims; d++ {
+					pooled[d] *= inv
+				}
 			}
 			res[b] = pooled
 		}
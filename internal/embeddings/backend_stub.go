@@ -1,5 +1,4 @@
-//go:build !onnx
-// +build !onnx
+//go:build !cgo || noonnx
 
 package embeddings
 
@@ -7,7 +6,7 @@ import (
 	"go.uber.org/zap"
 )
 
-// Stub implementation used when the 'onnx' build tag is not set.
+// Stub implementation used when cgo is unavailable or the 'noonnx' build tag is set.
 func NewTransformerBackend(logger *zap.Logger, modelPath string, maxLength int) TransformerBackend {
 	return nil
 }
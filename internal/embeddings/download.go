@@ -0,0 +1,141 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// huggingFaceEndpoint is the default model hub; HF_ENDPOINT overrides it for mirrors
+const huggingFaceEndpoint = "https://huggingface.co"
+
+// defaultModelRepo is used when the configured model name is empty
+const defaultModelRepo = "sentence-transformers/all-MiniLM-L6-v2"
+
+// commitRevision matches a full git commit hash. Branches and tags can be
+// moved, so downloads only accept a commit.
+var commitRevision = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// modelFile is a file fetched from a HuggingFace model repository
+type modelFile struct {
+	remote string // path inside the repository
+	local  string // destination on disk
+	sha256 string // expected hex digest of the contents
+}
+
+// huggingFaceRepo resolves a model name to a repository ID. Bare names such as
+// "all-MiniLM-L6-v2" are assumed to be sentence-transformers models.
+func huggingFaceRepo(modelName string) string {
+	switch {
+	case modelName == "":
+		return defaultModelRepo
+	case strings.Contains(modelName, "/"):
+		return modelName
+	default:
+		return "sentence-transformers/" + modelName
+	}
+}
+
+// downloadHuggingFaceFiles fetches files from a model repository at a pinned
+// commit, skipping any that already exist. Each file is written to a
+// temporary path and only renamed into place once its SHA-256 matches, so an
+// interrupted or tampered download never leaves a model behind. A non-nil
+// proxy replaces the environment's proxy settings.
+func downloadHuggingFaceFiles(ctx context.Context, logger *zap.Logger, proxy func(*http.Request) (*url.URL, error), repo, revision string, files []modelFile) error {
+	if !commitRevision.MatchString(revision) {
+		return fmt.Errorf("%w: model revision %q is not a commit hash; pin model.revision and the model file SHA-256s to download", ErrModelDownloadFailed, revision)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.local); err != nil && f.sha256 == "" {
+			return fmt.Errorf("%w: no SHA-256 configured for %s", ErrModelDownloadFailed, f.remote)
+		}
+	}
+
+	endpoint := strings.TrimSuffix(os.Getenv("HF_ENDPOINT"), "/")
+	if endpoint == "" {
+		endpoint = huggingFaceEndpoint
+	}
+	client := &http.Client{Timeout: 10 * time.Minute}
+	if proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = proxy
+		client.Transport = transport
+	}
+
+	for _, f := range files {
+		if _, err := os.Stat(f.local); err == nil {
+			continue
+		}
+		url := fmt.Sprintf("%s/%s/resolve/%s/%s", endpoint, repo, revision, f.remote)
+		logger.Info("Downloading model file", zap.String("url", url), zap.String("path", f.local))
+
+		start := time.Now()
+		n, err := downloadFile(ctx, client, url, f.local, f.sha256)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrModelDownloadFailed, url, err)
+		}
+		logger.Info("Downloaded model file",
+			zap.String("path", f.local),
+			zap.Int64("bytes", n),
+			zap.Duration("duration", time.Since(start)))
+	}
+	return nil
+}
+
+// downloadFile streams url to path, checking the contents hash to
+// wantSHA256, and returns the number of bytes written
+func downloadFile(ctx context.Context, client *http.Client, url, path, wantSHA256 string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Gated or private repositories need a token
+	if token := os.Getenv("HF_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	digest := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, digest), resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if got := hex.EncodeToString(digest.Sum(nil)); !strings.EqualFold(got, wantSHA256) {
+		return 0, fmt.Errorf("SHA-256 mismatch: got %s, want %s", got, wantSHA256)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
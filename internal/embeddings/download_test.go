@@ -0,0 +1,81 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestDownloadPinsRevisionAndChecksum checks downloads are fetched at the
+// pinned commit and only kept when their SHA-256 matches
+func TestDownloadPinsRevisionAndChecksum(t *testing.T) {
+	const revision = "0123456789abcdef0123456789abcdef01234567"
+	body := []byte("vocab contents")
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:])
+
+	var paths []string
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write(body)
+	}))
+	defer hub.Close()
+	t.Setenv("HF_ENDPOINT", hub.URL)
+
+	dir := t.TempDir()
+	download := func(name, revision, digest string) (string, error) {
+		local := filepath.Join(dir, name)
+		err := downloadHuggingFaceFiles(context.Background(), zap.NewNop(), nil, "org/model", revision,
+			[]modelFile{{remote: "vocab.txt", local: local, sha256: digest}})
+		return local, err
+	}
+
+	t.Run("matching checksum", func(t *testing.T) {
+		local, err := download("vocab.txt", revision, digest)
+		if err != nil {
+			t.Fatalf("download: %v", err)
+		}
+		if got := paths[len(paths)-1]; got != "/org/model/resolve/"+revision+"/vocab.txt" {
+			t.Fatalf("fetched %s, want the pinned revision", got)
+		}
+		if data, err := os.ReadFile(local); err != nil || string(data) != string(body) {
+			t.Fatalf("downloaded file = %q, %v", data, err)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		wrong := strings.Repeat("ab", 32)
+		local, err := download("tampered.txt", revision, wrong)
+		if !errors.Is(err, ErrModelDownloadFailed) || !strings.Contains(err.Error(), "SHA-256 mismatch") {
+			t.Fatalf("download = %v, want a SHA-256 mismatch", err)
+		}
+		if _, err := os.Stat(local); !os.IsNotExist(err) {
+			t.Fatalf("mismatched download left at %s", local)
+		}
+		if parts, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(parts) > 0 {
+			t.Fatalf("temporary files left behind: %v", parts)
+		}
+	})
+
+	t.Run("unpinned", func(t *testing.T) {
+		fetched := len(paths)
+		if _, err := download("branch.txt", "main", digest); !errors.Is(err, ErrModelDownloadFailed) {
+			t.Fatalf("download at a branch = %v, want refused", err)
+		}
+		if _, err := download("unchecked.txt", revision, ""); !errors.Is(err, ErrModelDownloadFailed) {
+			t.Fatalf("download without a checksum = %v, want refused", err)
+		}
+		if len(paths) != fetched {
+			t.Fatal("unpinned download reached the hub")
+		}
+	})
+}
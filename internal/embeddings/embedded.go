@@ -0,0 +1,58 @@
+package embeddings
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// embeddedAssets holds a model bundled at build time by `make embed-model`.
+// Default builds only carry the directory's README.
+//
+//go:embed all:assets
+var embeddedAssets embed.FS
+
+// embeddedModelFiles are the bundled files, extracted side by side so the
+// tokenizer is found next to the model
+var embeddedModelFiles = []string{"model.onnx", "tokenizer.json", "vocab.txt"}
+
+// HasEmbeddedModel reports whether this binary carries a fallback model
+func HasEmbeddedModel() bool {
+	_, err := fs.Stat(embeddedAssets, "assets/model.onnx")
+	return err == nil
+}
+
+// extractEmbeddedModel writes the bundled model files into dir and returns
+// the model's path. ONNX Runtime loads models from disk, so the files are
+// extracted once and rewritten only when their size differs from the
+// binary's copy.
+func extractEmbeddedModel(dir string) (string, error) {
+	if !HasEmbeddedModel() {
+		return "", fmt.Errorf("%w: this binary has no embedded model", ErrModelNotLoaded)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create embedded model directory: %w", err)
+	}
+
+	for _, name := range embeddedModelFiles {
+		data, err := embeddedAssets.ReadFile("assets/" + name)
+		if err != nil {
+			continue // optional tokenizer file not bundled
+		}
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.Size() == int64(len(data)) {
+			continue
+		}
+
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o644); err != nil {
+			return "", fmt.Errorf("failed to extract embedded %s: %w", name, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return "", fmt.Errorf("failed to extract embedded %s: %w", name, err)
+		}
+	}
+	return filepath.Join(dir, "model.onnx"), nil
+}
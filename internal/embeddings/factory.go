@@ -7,6 +7,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 
+	"github.com/raaihank/llm-sentinel/internal/chaos"
 	"github.com/raaihank/llm-sentinel/internal/vector"
 )
 
@@ -68,12 +69,13 @@ func (f *Factory) CreateService(config ServiceConfig) (EmbeddingService, error)
 		var redisClient *redis.Client
 		if config.RedisEnabled {
 			redisClient = redis.NewClient(&redis.Options{Addr: config.RedisURL})
+			redisClient.AddHook(chaos.RedisHook{})
 			if err := redisClient.Ping(context.Background()).Err(); err != nil {
 				f.logger.Warn("Redis connection failed, disabling cache", zap.Error(err))
 				redisClient = nil
 			}
 		}
-		var vectorStore *vector.Store = nil
+		var vectorStore vector.Backend = nil
 		service, err := NewMLEmbeddingService(&config.ModelConfig, f.logger, redisClient, vectorStore)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,96 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Component health states
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // working with reduced quality, e.g. simulated embeddings
+	HealthDown     = "down"
+	HealthDisabled = "disabled" // not configured
+)
+
+// ComponentHealth is the readiness of one dependency of the embedding service
+type ComponentHealth struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Critical bool          `json:"critical"` // the service cannot embed while this is down
+	Error    string        `json:"error,omitempty"`
+	Latency  time.Duration `json:"latency_ns"`
+}
+
+// Healthy reports whether the component does not block serving
+func (c ComponentHealth) Healthy() bool {
+	return c.Status != HealthDown || !c.Critical
+}
+
+// CheckComponents reports tokenizer, model backend, Redis and vector store
+// readiness individually so a failure points at the broken piece
+func (s *MLEmbeddingService) CheckComponents(ctx context.Context) []ComponentHealth {
+	return []ComponentHealth{
+		checkComponent("tokenizer", true, func() (string, error) { return s.checkTokenizer() }),
+		checkComponent("model_backend", true, func() (string, error) { return s.checkBackend() }),
+		checkComponent("redis", false, func() (string, error) { return s.checkRedis(ctx) }),
+		checkComponent("vector_store", false, func() (string, error) { return s.checkVectorStore(ctx) }),
+	}
+}
+
+func checkComponent(name string, critical bool, check func() (string, error)) ComponentHealth {
+	start := time.Now()
+	status, err := check()
+	health := ComponentHealth{
+		Name:     name,
+		Status:   status,
+		Critical: critical,
+		Latency:  time.Since(start),
+	}
+	if err != nil {
+		health.Status = HealthDown
+		health.Error = err.Error()
+	}
+	return health
+}
+
+func (s *MLEmbeddingService) checkTokenizer() (string, error) {
+	if s.tokenizer == nil {
+		return "", fmt.Errorf("tokenizer not initialized")
+	}
+	if _, err := s.tokenizer.Tokenize("test"); err != nil {
+		return "", fmt.Errorf("tokenization failed: %w", err)
+	}
+	return HealthOK, nil
+}
+
+func (s *MLEmbeddingService) checkBackend() (string, error) {
+	if !s.IsModelLoaded() {
+		return "", ErrModelNotLoaded
+	}
+	if s.backend == nil || !s.backend.IsReady() {
+		return HealthDegraded, nil
+	}
+	return HealthOK, nil
+}
+
+func (s *MLEmbeddingService) checkRedis(ctx context.Context) (string, error) {
+	if s.redisClient == nil {
+		return HealthDisabled, nil
+	}
+	if err := s.redisClient.Ping(ctx).Err(); err != nil {
+		return "", err
+	}
+	return HealthOK, nil
+}
+
+func (s *MLEmbeddingService) checkVectorStore(ctx context.Context) (string, error) {
+	if s.vectorStore == nil {
+		return HealthDisabled, nil
+	}
+	if err := s.vectorStore.Ping(ctx); err != nil {
+		return "", err
+	}
+	return HealthOK, nil
+}
@@ -0,0 +1,43 @@
+package embeddings
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TestCheckComponents checks each component reports separately and that
+// unconfigured dependencies show as disabled rather than down
+func TestCheckComponents(t *testing.T) {
+	service, err := NewMLEmbeddingService(&ModelConfig{
+		ModelName:    "test-ml",
+		MaxLength:    512,
+		BatchSize:    16,
+		ModelTimeout: 30 * time.Second,
+		ModelPath:    placeholderModel(t),
+	}, zap.NewNop(), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create ML embedding service: %v", err)
+	}
+	defer service.Close()
+
+	components := service.CheckComponents(context.Background())
+	if len(components) != 4 {
+		t.Fatalf("Expected 4 components, got %d", len(components))
+	}
+	for _, c := range components {
+		if !c.Healthy() {
+			t.Errorf("Component %s unhealthy: %s", c.Name, c.Error)
+		}
+		if (c.Name == "redis" || c.Name == "vector_store") && c.Status != HealthDisabled {
+			t.Errorf("Expected %s disabled, got %s", c.Name, c.Status)
+		}
+	}
+
+	down := checkComponent("backend", true, func() (string, error) { return "", context.DeadlineExceeded })
+	if down.Healthy() || down.Status != HealthDown {
+		t.Errorf("Expected failing critical component down, got %+v", down)
+	}
+}
@@ -0,0 +1,60 @@
+package embeddings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// placeholderContent stands in for a model file. It is not a valid ONNX
+// model, so inference falls back to simulated embeddings.
+const placeholderContent = "# Placeholder ML model file\n"
+
+// TestMain seeds the cache directories the ML service tests use, so they load
+// a placeholder instead of downloading the real model
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "embeddings-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// CreateDefaultConfig caches under ./models
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, cacheDir := range []string{"/tmp/test-models", "/tmp/bench-models", "models"} {
+		if err := seedPlaceholder(cacheDir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// seedPlaceholder writes a placeholder model into cacheDir unless a model is
+// already there
+func seedPlaceholder(cacheDir string) error {
+	path := filepath.Join(cacheDir, "model.bin")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", cacheDir, err)
+	}
+	return os.WriteFile(path, []byte(placeholderContent), 0o644)
+}
+
+// placeholderModel writes a placeholder model under a temporary directory
+func placeholderModel(tb testing.TB) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "model.bin")
+	if err := os.WriteFile(path, []byte(placeholderContent), 0o644); err != nil {
+		tb.Fatalf("write placeholder model: %v", err)
+	}
+	return path
+}
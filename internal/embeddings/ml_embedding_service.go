@@ -3,6 +3,7 @@ package embeddings
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"os"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/raaihank/llm-sentinel/internal/lru"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 	"github.com/raaihank/llm-sentinel/internal/vector"
 	"go.uber.org/zap"
 	// Assume added to go.mod
@@ -26,13 +29,15 @@ type MLEmbeddingService struct {
 	shared      *SharedUtilities
 	stats       *ModelStats
 	redisClient *redis.Client
-	vectorStore *vector.Store
+	vectorStore vector.Backend
 	tokenizer   *Tokenizer
 	model       *TransformerModel
 	backend     TransformerBackend
 	mu          sync.RWMutex
 	startTime   time.Time
-	sem         chan struct{} // Semaphore to limit concurrent cache operations
+	sem         chan struct{}                   // Semaphore to limit concurrent cache operations
+	memo        *lru.Cache[[32]byte, []float32] // backend outputs keyed by token IDs
+	local       *lru.Cache[string, []float32]   // embeddings keyed by cache key, in front of Redis
 }
 
 // TransformerModel represents a loaded transformer model
@@ -52,15 +57,6 @@ type TransformerModel struct {
 	ModelMetadata map[string]interface{}
 }
 
-// Tokenizer handles text tokenization for ML models
-type Tokenizer struct {
-	Vocab         map[string]int
-	InverseVocab  map[int]string
-	SpecialTokens map[string]int
-	MaxLength     int
-	ModelType     string // "bert", "roberta", "distilbert", etc.
-}
-
 // TokenizedInput represents tokenized text ready for model inference
 type TokenizedInput struct {
 	InputIDs      []int32
@@ -69,10 +65,11 @@ type TokenizedInput struct {
 	Length        int
 	OriginalText  string
 	Truncated     bool
+	Offsets       [][2]int // byte range in OriginalText per token; zero for special and padding tokens
 }
 
 // NewMLEmbeddingService creates a new ML-based embedding service
-func NewMLEmbeddingService(config *ModelConfig, logger *zap.Logger, redisClient *redis.Client, vectorStore *vector.Store) (*MLEmbeddingService, error) {
+func NewMLEmbeddingService(config *ModelConfig, logger *zap.Logger, redisClient *redis.Client, vectorStore vector.Backend) (*MLEmbeddingService, error) {
 	start := time.Now()
 	logger.Info("Initializing ML embedding service with transformer model support",
 		zap.String("model", config.ModelName),
@@ -98,17 +95,14 @@ func NewMLEmbeddingService(config *ModelConfig, logger *zap.Logger, redisClient
 		},
 		sem: make(chan struct{}, 3), // Max 3 concurrent
 	}
-
-	// Initialize tokenizer
-	logger.Info("Initializing tokenizer")
-	tokenizer, err := service.initializeTokenizer()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize tokenizer: %w", err)
+	if config.MemoSize > 0 {
+		service.memo = lru.New[[32]byte, []float32](config.MemoSize)
+	}
+	if config.LocalCacheSize > 0 {
+		service.local = lru.NewWithTTL[string, []float32](config.LocalCacheSize, config.LocalCacheTTL)
 	}
-	service.tokenizer = tokenizer
-	logger.Info("Tokenizer initialization completed")
 
-	// Load or download model
+	// Load or download model (and its tokenizer files)
 	logger.Info("Loading transformer model")
 	model, err := service.loadModel()
 	if err != nil {
@@ -118,6 +112,15 @@ func NewMLEmbeddingService(config *ModelConfig, logger *zap.Logger, redisClient
 	service.stats.ModelLoadTime = time.Since(start)
 	logger.Info("Model loading completed")
 
+	// Initialize tokenizer
+	logger.Info("Initializing tokenizer")
+	tokenizer, err := service.initializeTokenizer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tokenizer: %w", err)
+	}
+	service.tokenizer = tokenizer
+	logger.Info("Tokenizer initialization completed")
+
 	// Initialize backend if available (build-tagged implementation)
 	service.backend = NewTransformerBackend(logger, model.ModelPath, service.config.MaxLength)
 	if service.backend != nil && service.backend.IsReady() {
@@ -150,18 +153,8 @@ func (s *MLEmbeddingService) GenerateEmbedding(ctx context.Context, text string)
 	// Do not fail immediately on pre-cancelled or ultra-short contexts; allow caller to pass a bounded context
 	// We will respect ctx in downstream calls (Redis, DB) without pre-emptive early return here.
 
-	// Check Redis cache first (if available)
-	var cached []float32
-	var cacheHit bool
-
-	if s.redisClient != nil {
-		s.logger.Debug("Checking cache")
-		if cachedResult, err := s.getCachedEmbedding(ctx, text); err == nil {
-			cached = cachedResult
-			cacheHit = true
-			s.logger.Debug("Retrieved embedding from Redis cache")
-		}
-	}
+	// Check the local and Redis caches first (if available)
+	cached, cacheHit := s.lookupCache(ctx, text)
 
 	var embedding []float32
 	var analysis *AttackAnalysisResult
@@ -221,6 +214,7 @@ func (s *MLEmbeddingService) GenerateEmbedding(ctx context.Context, text string)
 		}
 
 		// Cache in Redis if available (non-blocking)
+		s.keepLocal(text, embedding)
 		if s.redisClient != nil {
 			go func(parentCtx context.Context, keyText string, emb []float32) {
 				cacheCtx, cancel := context.WithTimeout(parentCtx, 500*time.Millisecond)
@@ -342,7 +336,7 @@ func (s *MLEmbeddingService) GenerateBatchEmbeddings(ctx context.Context, texts
 func (s *MLEmbeddingService) runBatchInference(ctx context.Context, tokensBatch []*TokenizedInput) ([][]float32, error) {
 	// Prefer real backend if available
 	if s.backend != nil && s.backend.IsReady() {
-		return s.backend.EmbedBatch(ctx, tokensBatch)
+		return s.embedMemoized(ctx, tokensBatch)
 	}
 	// Fallback simulation
 	embeddings := make([][]float32, len(tokensBatch))
@@ -377,13 +371,10 @@ func (s *MLEmbeddingService) processBatch(ctx context.Context, texts []string) (
 		}
 
 		// Check cache first
-		if s.redisClient != nil {
-			s.logger.Debug("Checking cache")
-			if cached, err := s.getCachedEmbedding(ctx, text); err == nil {
-				embeddings[i] = cached
-				cacheHits++
-				continue
-			}
+		if cached, ok := s.lookupCache(ctx, text); ok {
+			embeddings[i] = cached
+			cacheHits++
+			continue
 		}
 
 		// Generate analysis
@@ -399,6 +390,7 @@ func (s *MLEmbeddingService) processBatch(ctx context.Context, texts []string) (
 		embeddings[i] = embedding
 
 		// Cache asynchronously (non-blocking, short timeout)
+		s.keepLocal(text, embedding)
 		if s.redisClient != nil {
 			go func(parentCtx context.Context, keyText string, emb []float32) {
 				cacheCtx, cancel := context.WithTimeout(parentCtx, 500*time.Millisecond)
@@ -433,7 +425,7 @@ func (s *MLEmbeddingService) generateMLEmbedding(ctx context.Context, text strin
 	var embedding []float32
 	if s.backend != nil && s.backend.IsReady() {
 		// Use real backend for single input via batch of size 1
-		res, err := s.backend.EmbedBatch(timeoutCtx, []*TokenizedInput{tokens})
+		res, err := s.embedMemoized(timeoutCtx, []*TokenizedInput{tokens})
 		if err != nil || len(res) != 1 {
 			return nil, fmt.Errorf("transformer backend failed: %w", err)
 		}
@@ -454,7 +446,104 @@ func (s *MLEmbeddingService) generateMLEmbedding(ctx context.Context, text strin
 	return embedding, nil
 }
 
-// Redis caching methods
+// embedMemoized runs the backend only for inputs whose exact token ID
+// sequence has not been embedded recently. Prompts that differ only in
+// whitespace or case tokenize identically and share one model run.
+func (s *MLEmbeddingService) embedMemoized(ctx context.Context, tokensBatch []*TokenizedInput) ([][]float32, error) {
+	if s.memo == nil {
+		return s.backend.EmbedBatch(ctx, tokensBatch)
+	}
+
+	results := make([][]float32, len(tokensBatch))
+	keys := make([][32]byte, len(tokensBatch))
+	var missIndexes []int
+	var missTokens []*TokenizedInput
+	for i, tokens := range tokensBatch {
+		keys[i] = tokenKey(tokens)
+		if cached, ok := s.memo.Get(keys[i]); ok {
+			results[i] = append([]float32(nil), cached...)
+			continue
+		}
+		missIndexes = append(missIndexes, i)
+		missTokens = append(missTokens, tokens)
+	}
+	if len(missTokens) == 0 {
+		return results, nil
+	}
+
+	computed, err := s.backend.EmbedBatch(ctx, missTokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(computed) != len(missTokens) {
+		return nil, fmt.Errorf("transformer backend returned %d embeddings for %d inputs", len(computed), len(missTokens))
+	}
+	for j, i := range missIndexes {
+		results[i] = computed[j]
+		s.memo.Add(keys[i], append([]float32(nil), computed[j]...))
+	}
+	return results, nil
+}
+
+// tokenKey identifies a tokenized input by its token IDs after truncation,
+// ignoring padding
+func tokenKey(tokens *TokenizedInput) [32]byte {
+	ids := tokens.InputIDs
+	if tokens.Length > 0 && tokens.Length < len(ids) {
+		ids = ids[:tokens.Length]
+	}
+
+	buf := make([]byte, 4*len(ids))
+	for i, id := range ids {
+		binary.LittleEndian.PutUint32(buf[i*4:], uint32(id))
+	}
+	return sha256.Sum256(buf)
+}
+
+// Caching methods
+
+// lookupCache returns the cached embedding of text from the local tier,
+// then Redis, keeping Redis hits locally, and counts the outcome
+func (s *MLEmbeddingService) lookupCache(ctx context.Context, text string) ([]float32, bool) {
+	if s.local == nil && s.redisClient == nil {
+		return nil, false
+	}
+	if s.local != nil {
+		if embedding, ok := s.local.Get(s.getCacheKey(text)); ok {
+			s.countCacheLookup(&s.stats.LocalCacheHits)
+			s.logger.Debug("Retrieved embedding from local cache")
+			return embedding, true
+		}
+	}
+	if s.redisClient != nil {
+		s.logger.Debug("Checking cache")
+		if embedding, err := s.getCachedEmbedding(ctx, text); err == nil {
+			s.keepLocal(text, embedding)
+			s.countCacheLookup(&s.stats.RedisCacheHits)
+			s.logger.Debug("Retrieved embedding from Redis cache")
+			return embedding, true
+		}
+	}
+	s.countCacheLookup(&s.stats.CacheMisses)
+	return nil, false
+}
+
+// keepLocal adds an embedding to the local tier, if enabled
+func (s *MLEmbeddingService) keepLocal(text string, embedding []float32) {
+	if s.local != nil {
+		s.local.Add(s.getCacheKey(text), embedding)
+	}
+}
+
+// countCacheLookup increments one cache outcome counter and updates the
+// hit ratio across both tiers
+func (s *MLEmbeddingService) countCacheLookup(counter *int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*counter++
+	hits := s.stats.LocalCacheHits + s.stats.RedisCacheHits
+	s.stats.CacheHitRatio = float64(hits) / float64(hits+s.stats.CacheMisses)
+}
 
 func (s *MLEmbeddingService) getCachedEmbedding(ctx context.Context, text string) ([]float32, error) {
 	key := s.getCacheKey(text)
@@ -507,7 +596,7 @@ func (s *MLEmbeddingService) cacheEmbedding(ctx context.Context, text string, em
 }
 
 func (s *MLEmbeddingService) getCacheKey(text string) string {
-	hash := s.shared.CreateDeterministicHash(text)
+	hash := textnorm.Sum(text, s.config.CacheKey)
 	// Use 16 bytes (128-bit) to reduce collision risk
 	return fmt.Sprintf("embedding:ml:%x", hash[:16])
 }
@@ -531,20 +620,39 @@ func (s *MLEmbeddingService) loadModel() (*TransformerModel, error) {
 			}
 		}
 		if modelPath == "" {
-			// Nothing found; default to simulation placeholder path
-			modelPath = filepath.Join(s.config.CacheDir, "model.bin")
+			// Nothing found; auto-download fetches the ONNX model here
+			modelPath = filepath.Join(s.config.CacheDir, "model.onnx")
 		}
 	}
 
 	// Check if model exists
+	source := huggingFaceRepo(s.config.ModelName)
 	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+		var loadErr error
 		if s.config.AutoDownload {
 			s.logger.Info("Model not found, downloading...", zap.String("path", modelPath))
 			if err := s.downloadModel(modelPath); err != nil {
-				return nil, fmt.Errorf("failed to download model: %w", err)
+				loadErr = fmt.Errorf("failed to download model: %w", err)
 			}
 		} else {
-			return nil, fmt.Errorf("%w: model not found and auto-download disabled: %s", ErrModelNotLoaded, modelPath)
+			loadErr = fmt.Errorf("%w: model not found and auto-download disabled: %s", ErrModelNotLoaded, modelPath)
+		}
+
+		// Without a configured model path, a model bundled into the binary
+		// stands in for the missing files
+		if loadErr != nil {
+			if s.config.ModelPath != "" || !HasEmbeddedModel() {
+				return nil, loadErr
+			}
+			embeddedPath, err := extractEmbeddedModel(filepath.Join(s.config.CacheDir, "embedded"))
+			if err != nil {
+				return nil, fmt.Errorf("%v; embedded fallback model unavailable: %w", loadErr, err)
+			}
+			s.logger.Warn("Using the quantized fallback model embedded in this binary; for full-precision embeddings set model_path, place model.onnx in cache_dir, or enable auto_download",
+				zap.String("path", embeddedPath),
+				zap.NamedError("reason", loadErr))
+			modelPath = embeddedPath
+			source = "embedded"
 		}
 	}
 
@@ -562,7 +670,7 @@ func (s *MLEmbeddingService) loadModel() (*TransformerModel, error) {
 		ModelMetadata: map[string]interface{}{
 			"type":    "sentence-transformer",
 			"version": "1.0.0",
-			"source":  "placeholder",
+			"source":  source,
 		},
 	}
 
@@ -575,32 +683,63 @@ func (s *MLEmbeddingService) loadModel() (*TransformerModel, error) {
 	return model, nil
 }
 
+// downloadModel fetches the ONNX export of the configured model and its
+// tokenizer files from HuggingFace at the configured revision, checking each
+// against its configured SHA-256. Tokenizer files go to their configured
+// paths, or next to the model.
 func (s *MLEmbeddingService) downloadModel(modelPath string) error {
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
-		return fmt.Errorf("failed to create model directory: %w", err)
+	ctx := context.Background()
+	if s.config.ModelTimeout > 0 {
+		// Model downloads are much slower than inference
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 20*s.config.ModelTimeout)
+		defer cancel()
 	}
 
-	// For now, create a placeholder model file
-	// In a real implementation, this would download from HuggingFace Hub or other model repository
-	s.logger.Info("Creating placeholder model file (in production, this would download a real model)",
-		zap.String("path", modelPath))
-
-	modelData := []byte(`# Placeholder ML model file
-# In production, this would be a real transformer model (ONNX, PyTorch, etc.)
-# Model: ` + s.config.ModelName + `
-# Created: ` + time.Now().Format(time.RFC3339) + `
-`)
+	files := []modelFile{
+		{remote: "onnx/model.onnx", local: modelPath, sha256: s.config.ModelSHA256},
+		{remote: "tokenizer.json", local: s.tokenizerPath(modelPath), sha256: s.config.TokenizerSHA256},
+		{remote: "vocab.txt", local: s.vocabPath(modelPath), sha256: s.config.VocabSHA256},
+	}
+	return downloadHuggingFaceFiles(ctx, s.logger, s.config.Proxy, huggingFaceRepo(s.config.ModelName), s.config.Revision, files)
+}
 
-	if err := os.WriteFile(modelPath, modelData, 0644); err != nil {
-		return fmt.Errorf("failed to write model file: %w", err)
+// tokenizerPath returns where tokenizer.json is expected for modelPath
+func (s *MLEmbeddingService) tokenizerPath(modelPath string) string {
+	if s.config.TokenizerPath != "" {
+		return s.config.TokenizerPath
 	}
+	return filepath.Join(filepath.Dir(modelPath), "tokenizer.json")
+}
 
-	return nil
+// vocabPath returns where vocab.txt is expected for modelPath
+func (s *MLEmbeddingService) vocabPath(modelPath string) string {
+	if s.config.VocabPath != "" {
+		return s.config.VocabPath
+	}
+	return filepath.Join(filepath.Dir(modelPath), "vocab.txt")
 }
 
 func (s *MLEmbeddingService) initializeTokenizer() (*Tokenizer, error) {
-	// Initialize a tokenizer (placeholder for real tokenizer loading)
+	// Prefer the model's own tokenizer so token IDs match what the ONNX model
+	// expects: tokenizer.json first, then a plain WordPiece vocab.txt
+	if s.model != nil {
+		for _, path := range []string{s.tokenizerPath(s.model.ModelPath), s.vocabPath(s.model.ModelPath)} {
+			tokenizer, err := LoadTokenizer(path, s.config.MaxLength)
+			if err == nil {
+				s.logger.Info("Loaded tokenizer",
+					zap.String("path", path),
+					zap.String("algorithm", tokenizer.Algorithm),
+					zap.Int("vocab_size", len(tokenizer.Vocab)))
+				return tokenizer, nil
+			}
+			if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to load tokenizer %s: %w", path, err)
+			}
+		}
+	}
+
+	// Fall back to a small built-in vocabulary for simulated inference
 	vocab := make(map[string]int)
 	inverseVocab := make(map[int]string)
 
@@ -645,59 +784,7 @@ func (s *MLEmbeddingService) initializeTokenizer() (*Tokenizer, error) {
 		SpecialTokens: specialTokens,
 		MaxLength:     s.config.MaxLength,
 		ModelType:     "bert", // Default to BERT-style tokenization
-	}, nil
-}
-
-// Tokenize converts text to token IDs
-func (t *Tokenizer) Tokenize(text string) (*TokenizedInput, error) {
-	if text == "" {
-		return nil, fmt.Errorf("cannot tokenize empty text")
-	}
-
-	text = strings.ToLower(strings.TrimSpace(text))
-	words := strings.Fields(text)
-
-	// Start with [CLS]
-	tokenIDs := []int32{int32(t.SpecialTokens["[CLS]"])}
-	attentionMask := []int32{1}
-
-	// Add word tokens
-	for _, word := range words {
-		if id, exists := t.Vocab[word]; exists {
-			tokenIDs = append(tokenIDs, int32(id))
-		} else {
-			tokenIDs = append(tokenIDs, int32(t.SpecialTokens["[UNK]"]))
-		}
-		attentionMask = append(attentionMask, 1)
-
-		if len(tokenIDs) >= t.MaxLength-1 {
-			break
-		}
-	}
-
-	// Add [SEP]
-	tokenIDs = append(tokenIDs, int32(t.SpecialTokens["[SEP]"]))
-	attentionMask = append(attentionMask, 1)
-
-	originalLength := len(tokenIDs)
-	truncated := originalLength >= t.MaxLength
-
-	// Pad to max length
-	for len(tokenIDs) < t.MaxLength {
-		tokenIDs = append(tokenIDs, int32(t.SpecialTokens["[PAD]"]))
-		attentionMask = append(attentionMask, 0)
-	}
-
-	// Token type IDs (all 0 for single sentence)
-	tokenTypeIDs := make([]int32, t.MaxLength)
-
-	return &TokenizedInput{
-		InputIDs:      tokenIDs,
-		AttentionMask: attentionMask,
-		TokenTypeIDs:  tokenTypeIDs,
-		Length:        originalLength,
-		OriginalText:  text,
-		Truncated:     truncated,
+		Algorithm:     AlgorithmWhitespace,
 	}, nil
 }
 
@@ -717,7 +804,7 @@ func (s *MLEmbeddingService) GetStats() *ModelStats {
 }
 
 // SetVectorStore attaches a vector store to enable database similarity lookups
-func (s *MLEmbeddingService) SetVectorStore(store *vector.Store) {
+func (s *MLEmbeddingService) SetVectorStore(store vector.Backend) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.vectorStore = store
@@ -773,11 +860,6 @@ func (s *MLEmbeddingService) updateStats(requests int64, tokens int, duration ti
 	if s.stats.TotalInferences > 0 {
 		s.stats.AvgTokensPerText = float64(s.stats.TotalTokens) / float64(s.stats.TotalInferences)
 	}
-
-	// Update cache hit ratio (if using Redis)
-	if s.redisClient != nil {
-		s.stats.CacheHitRatio = 0.0 // Placeholder; calculate properly in prod
-	}
 }
 
 // IsModelLoaded returns whether the model is properly loaded
@@ -787,6 +869,16 @@ func (s *MLEmbeddingService) IsModelLoaded() bool {
 	return s.model != nil && s.model.Loaded
 }
 
+// CountTokens returns how many model tokens text is embedded from, special
+// tokens included and truncated to the model's max length
+func (s *MLEmbeddingService) CountTokens(text string) int {
+	tokens, err := s.tokenizer.Tokenize(text)
+	if err != nil {
+		return 0
+	}
+	return tokens.Length
+}
+
 // GetModelInfo returns information about the loaded model
 func (s *MLEmbeddingService) GetModelInfo() map[string]interface{} {
 	s.mu.RLock()
@@ -810,6 +902,13 @@ func (s *MLEmbeddingService) GetModelInfo() map[string]interface{} {
 		"load_time":   s.model.LoadTime,
 	}
 
+	if s.memo != nil {
+		hits, misses := s.memo.Stats()
+		info["memo_entries"] = s.memo.Len()
+		info["memo_hits"] = hits
+		info["memo_misses"] = misses
+	}
+
 	// Add metadata if available
 	if s.model.ModelMetadata != nil {
 		info["metadata"] = s.model.ModelMetadata
@@ -818,32 +917,19 @@ func (s *MLEmbeddingService) GetModelInfo() map[string]interface{} {
 	return info
 }
 
-// HealthCheck performs a basic health check on the service
+// HealthCheck fails if any component the service needs to embed is down.
+// Use CheckComponents to see which one.
 func (s *MLEmbeddingService) HealthCheck(ctx context.Context) error {
-	// Check model is loaded
-	if !s.IsModelLoaded() {
-		return fmt.Errorf("%w: model not loaded", ErrModelNotLoaded)
-	}
-
-	// Check tokenizer
-	if s.tokenizer == nil {
-		return fmt.Errorf("tokenizer not initialized")
-	}
-
-	// Test basic tokenization
-	_, err := s.tokenizer.Tokenize("test")
-	if err != nil {
-		return fmt.Errorf("tokenizer health check failed: %w", err)
-	}
-
-	// Test Redis connection if enabled
-	if s.redisClient != nil {
-		if err := s.redisClient.Ping(ctx).Err(); err != nil {
-			s.logger.Warn("Redis health check failed", zap.Error(err))
-			// Don't fail the health check for Redis issues
+	for _, component := range s.CheckComponents(ctx) {
+		if !component.Healthy() {
+			return fmt.Errorf("%s health check failed: %s", component.Name, component.Error)
+		}
+		if component.Status == HealthDown {
+			s.logger.Warn("Component health check failed",
+				zap.String("component", component.Name),
+				zap.String("error", component.Error))
 		}
 	}
-
 	return nil
 }
 
@@ -888,8 +974,9 @@ func (s *MLEmbeddingService) searchVectorDatabase(ctx context.Context, text stri
 		bestSim = results[0].Similarity
 	}
 
-	// Decide whether to use the top match
-	used := resultsCount > 0 && bestSim >= 0.85
+	// Decide whether to use the top match. A store with dimension reduction
+	// holds reduced vectors, which cannot stand in for a full embedding.
+	used := resultsCount > 0 && bestSim >= 0.85 && len(results[0].Vector.Embedding) == EmbeddingDimensions
 
 	s.logger.Info("Vector DB lookup result",
 		zap.Bool("used", used),
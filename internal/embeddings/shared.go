@@ -462,41 +462,12 @@ func (su *SharedUtilities) CreateDeterministicHash(text string) [32]byte {
 
 // NormalizeEmbedding normalizes a vector to unit length
 func (su *SharedUtilities) NormalizeEmbedding(embedding []float32) []float32 {
-	var norm float32
-	for _, val := range embedding {
-		norm += val * val
-	}
-	norm = float32(math.Sqrt(float64(norm)))
-
-	if norm == 0 {
-		return embedding
-	}
-
-	normalized := make([]float32, len(embedding))
-	for i, val := range embedding {
-		normalized[i] = val / norm
-	}
-	return normalized
+	return normalize(embedding)
 }
 
 // ComputeCosineSimilarity calculates cosine similarity between two vectors
 func (su *SharedUtilities) ComputeCosineSimilarity(vec1, vec2 []float32) float32 {
-	if len(vec1) != len(vec2) || len(vec1) == 0 {
-		return 0.0
-	}
-
-	var dotProduct, norm1, norm2 float64
-	for i := range vec1 {
-		dotProduct += float64(vec1[i] * vec2[i])
-		norm1 += float64(vec1[i] * vec1[i])
-		norm2 += float64(vec2[i] * vec2[i])
-	}
-
-	if norm1 == 0 || norm2 == 0 {
-		return 0.0
-	}
-
-	return float32(dotProduct / (math.Sqrt(norm1) * math.Sqrt(norm2)))
+	return cosineSimilarity(vec1, vec2)
 }
 
 // Helper methods for feature calculation
@@ -0,0 +1,68 @@
+package embeddings
+
+import "math"
+
+// Vector kernels used by the shared utilities. Architecture-specific files
+// provide dotNorms and sumSquares/scale; these generic versions are the
+// fallback and also handle the tails the vectorized loops leave behind.
+
+// cosineSimilarity calculates cosine similarity using the fastest available kernel
+func cosineSimilarity(vec1, vec2 []float32) float32 {
+	if len(vec1) != len(vec2) || len(vec1) == 0 {
+		return 0.0
+	}
+
+	dot, norm1, norm2 := dotNorms(vec1, vec2)
+	if norm1 == 0 || norm2 == 0 {
+		return 0.0
+	}
+
+	return float32(float64(dot) / (math.Sqrt(float64(norm1)) * math.Sqrt(float64(norm2))))
+}
+
+// normalize returns a unit-length copy of the vector using the fastest available kernel
+func normalize(embedding []float32) []float32 {
+	norm := float32(math.Sqrt(float64(sumSquares(embedding))))
+	if norm == 0 {
+		return embedding
+	}
+
+	normalized := make([]float32, len(embedding))
+	scale(normalized, embedding, 1/norm)
+	return normalized
+}
+
+func dotNormsGeneric(a, b []float32) (dot, norm1, norm2 float32) {
+	b = b[:len(a)]
+	var d0, d1, n10, n11, n20, n21 float32
+	i := 0
+	for ; i+2 <= len(a); i += 2 {
+		d0 += a[i] * b[i]
+		d1 += a[i+1] * b[i+1]
+		n10 += a[i] * a[i]
+		n11 += a[i+1] * a[i+1]
+		n20 += b[i] * b[i]
+		n21 += b[i+1] * b[i+1]
+	}
+	for ; i < len(a); i++ {
+		d0 += a[i] * b[i]
+		n10 += a[i] * a[i]
+		n20 += b[i] * b[i]
+	}
+	return d0 + d1, n10 + n11, n20 + n21
+}
+
+func sumSquaresGeneric(a []float32) float32 {
+	var sum float32
+	for _, v := range a {
+		sum += v * v
+	}
+	return sum
+}
+
+func scaleGeneric(dst, src []float32, s float32) {
+	dst = dst[:len(src)]
+	for i, v := range src {
+		dst[i] = v * s
+	}
+}
@@ -0,0 +1,47 @@
+package embeddings
+
+import "golang.org/x/sys/cpu"
+
+// useAVX2 selects the AVX2/FMA kernels when the CPU supports them
+var useAVX2 = cpu.X86.HasAVX2 && cpu.X86.HasFMA
+
+//go:noescape
+func dotNormsAVX2(a, b *float32, n int) (dot, norm1, norm2 float32)
+
+//go:noescape
+func sumSquaresAVX2(a *float32, n int) float32
+
+//go:noescape
+func scaleAVX2(dst, src *float32, n int, s float32)
+
+// avx2Block is the number of float32 lanes the assembly loops consume per step
+const avx2Block = 8
+
+func dotNorms(a, b []float32) (dot, norm1, norm2 float32) {
+	n := len(a) &^ (avx2Block - 1)
+	if !useAVX2 || n == 0 {
+		return dotNormsGeneric(a, b)
+	}
+	dot, norm1, norm2 = dotNormsAVX2(&a[0], &b[0], n)
+	d, n1, n2 := dotNormsGeneric(a[n:], b[n:len(a)])
+	return dot + d, norm1 + n1, norm2 + n2
+}
+
+func sumSquares(a []float32) float32 {
+	n := len(a) &^ (avx2Block - 1)
+	if !useAVX2 || n == 0 {
+		return sumSquaresGeneric(a)
+	}
+	return sumSquaresAVX2(&a[0], n) + sumSquaresGeneric(a[n:])
+}
+
+func scale(dst, src []float32, s float32) {
+	n := len(src) &^ (avx2Block - 1)
+	if !useAVX2 || n == 0 {
+		scaleGeneric(dst, src, s)
+		return
+	}
+	_ = dst[len(src)-1]
+	scaleAVX2(&dst[0], &src[0], n, s)
+	scaleGeneric(dst[n:], src[n:], s)
+}
@@ -0,0 +1,121 @@
+#include "textflag.h"
+
+// func dotNormsAVX2(a, b *float32, n int) (dot, norm1, norm2 float32)
+// n must be a positive multiple of 8.
+TEXT ·dotNormsAVX2(SB), NOSPLIT, $0-36
+	MOVQ a+0(FP), SI
+	MOVQ b+8(FP), DI
+	MOVQ n+16(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+	VXORPS Y4, Y4, Y4
+	VXORPS Y5, Y5, Y5
+
+dot16:
+	CMPQ CX, $16
+	JL   dot8
+	VMOVUPS (SI), Y6
+	VMOVUPS 32(SI), Y7
+	VMOVUPS (DI), Y8
+	VMOVUPS 32(DI), Y9
+	VFMADD231PS Y6, Y8, Y0
+	VFMADD231PS Y7, Y9, Y3
+	VFMADD231PS Y6, Y6, Y1
+	VFMADD231PS Y7, Y7, Y4
+	VFMADD231PS Y8, Y8, Y2
+	VFMADD231PS Y9, Y9, Y5
+	ADDQ $64, SI
+	ADDQ $64, DI
+	SUBQ $16, CX
+	JMP  dot16
+
+dot8:
+	CMPQ CX, $8
+	JL   dotdone
+	VMOVUPS (SI), Y6
+	VMOVUPS (DI), Y8
+	VFMADD231PS Y6, Y8, Y0
+	VFMADD231PS Y6, Y6, Y1
+	VFMADD231PS Y8, Y8, Y2
+
+dotdone:
+	VADDPS Y3, Y0, Y0
+	VADDPS Y4, Y1, Y1
+	VADDPS Y5, Y2, Y2
+
+	VEXTRACTF128 $1, Y0, X6
+	VADDPS X6, X0, X0
+	VHADDPS X0, X0, X0
+	VHADDPS X0, X0, X0
+
+	VEXTRACTF128 $1, Y1, X6
+	VADDPS X6, X1, X1
+	VHADDPS X1, X1, X1
+	VHADDPS X1, X1, X1
+
+	VEXTRACTF128 $1, Y2, X6
+	VADDPS X6, X2, X2
+	VHADDPS X2, X2, X2
+	VHADDPS X2, X2, X2
+
+	VZEROUPPER
+	MOVSS X0, dot+24(FP)
+	MOVSS X1, norm1+28(FP)
+	MOVSS X2, norm2+32(FP)
+	RET
+
+// func sumSquaresAVX2(a *float32, n int) float32
+// n must be a positive multiple of 8.
+TEXT ·sumSquaresAVX2(SB), NOSPLIT, $0-20
+	MOVQ a+0(FP), SI
+	MOVQ n+8(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+
+sq16:
+	CMPQ CX, $16
+	JL   sq8
+	VMOVUPS (SI), Y2
+	VMOVUPS 32(SI), Y3
+	VFMADD231PS Y2, Y2, Y0
+	VFMADD231PS Y3, Y3, Y1
+	ADDQ $64, SI
+	SUBQ $16, CX
+	JMP  sq16
+
+sq8:
+	CMPQ CX, $8
+	JL   sqdone
+	VMOVUPS (SI), Y2
+	VFMADD231PS Y2, Y2, Y0
+
+sqdone:
+	VADDPS Y1, Y0, Y0
+	VEXTRACTF128 $1, Y0, X2
+	VADDPS X2, X0, X0
+	VHADDPS X0, X0, X0
+	VHADDPS X0, X0, X0
+	VZEROUPPER
+	MOVSS X0, ret+16(FP)
+	RET
+
+// func scaleAVX2(dst, src *float32, n int, s float32)
+// n must be a positive multiple of 8.
+TEXT ·scaleAVX2(SB), NOSPLIT, $0-28
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+	VBROADCASTSS s+24(FP), Y0
+
+scaleloop:
+	VMULPS (SI), Y0, Y1
+	VMOVUPS Y1, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	SUBQ $8, CX
+	JNZ  scaleloop
+
+	VZEROUPPER
+	RET
@@ -0,0 +1,15 @@
+//go:build !amd64
+
+package embeddings
+
+func dotNorms(a, b []float32) (dot, norm1, norm2 float32) {
+	return dotNormsGeneric(a, b)
+}
+
+func sumSquares(a []float32) float32 {
+	return sumSquaresGeneric(a)
+}
+
+func scale(dst, src []float32, s float32) {
+	scaleGeneric(dst, src, s)
+}
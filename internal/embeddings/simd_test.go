@@ -0,0 +1,62 @@
+package embeddings
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestVectorKernels checks the vectorized kernels against the generic versions
+func TestVectorKernels(t *testing.T) {
+	for _, n := range []int{1, 7, 8, 15, 16, 17, 33, 384, 768, 1023} {
+		a, b := benchVectors(n)
+
+		dot, n1, n2 := dotNorms(a, b)
+		wantDot, wantN1, wantN2 := dotNormsGeneric(a, b)
+		if !closeEnough(dot, wantDot) || !closeEnough(n1, wantN1) || !closeEnough(n2, wantN2) {
+			t.Errorf("dotNorms(n=%d) = (%f, %f, %f), want (%f, %f, %f)", n, dot, n1, n2, wantDot, wantN1, wantN2)
+		}
+
+		normalized := normalize(a)
+		if got := sumSquaresGeneric(normalized); !closeEnough(got, 1) {
+			t.Errorf("normalize(n=%d) produced squared norm %f, want 1", n, got)
+		}
+	}
+}
+
+// BenchmarkCosineSimilarity compares the selected kernel with the generic loop
+func BenchmarkCosineSimilarity(b *testing.B) {
+	for _, n := range []int{384, 768} {
+		vec1, vec2 := benchVectors(n)
+
+		b.Run(fmt.Sprintf("Generic-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dotNormsGeneric(vec1, vec2)
+			}
+		})
+		b.Run(fmt.Sprintf("Selected-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cosineSimilarity(vec1, vec2)
+			}
+		})
+		b.Run(fmt.Sprintf("Normalize-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				normalize(vec1)
+			}
+		})
+	}
+}
+
+func benchVectors(n int) ([]float32, []float32) {
+	a := make([]float32, n)
+	b := make([]float32, n)
+	for i := range a {
+		a[i] = float32(math.Sin(float64(i + 1)))
+		b[i] = float32(math.Cos(float64(i) * 0.5))
+	}
+	return a, b
+}
+
+func closeEnough(got, want float32) bool {
+	return math.Abs(float64(got-want)) <= 1e-4*math.Max(1, math.Abs(float64(want)))
+}
@@ -0,0 +1,814 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tokenization algorithms
+const (
+	AlgorithmWhitespace = "whitespace" // whole words against a small built-in vocabulary
+	AlgorithmWordPiece  = "wordpiece"
+	AlgorithmBPE        = "bpe"
+)
+
+// Tokenizer handles text tokenization for ML models
+type Tokenizer struct {
+	Vocab         map[string]int
+	InverseVocab  map[int]string
+	SpecialTokens map[string]int
+	MaxLength     int
+	ModelType     string // "bert", "roberta", "distilbert", etc.
+	Algorithm     string // AlgorithmWordPiece, AlgorithmBPE or AlgorithmWhitespace
+
+	normalizer normalizerOptions
+	unkToken   string
+	padToken   string
+	prefix     []string // special tokens added before the sequence, e.g. [CLS]
+	suffix     []string // special tokens added after the sequence, e.g. [SEP]
+
+	// WordPiece
+	continuingPrefix string
+	maxCharsPerWord  int
+
+	// BPE
+	ranks           map[[2]string]int // merge pair -> priority (lower merges first)
+	byteLevel       bool
+	addPrefixSpace  bool
+	endOfWordSuffix string
+}
+
+// normalizerOptions mirrors the BERT normalizer settings
+type normalizerOptions struct {
+	cleanText    bool // drop control characters, map whitespace to spaces
+	lowercase    bool
+	stripAccents bool
+	chineseChars bool // split CJK ideographs into single-character words
+}
+
+// piece is one sub-word token with its byte range in the original text
+type piece struct {
+	token      string
+	start, end int
+}
+
+// word is a normalized pre-token. starts and ends hold the original byte
+// range for every byte of text, so pieces of a word can be mapped back to
+// offsets in the input even when normalization changed its length.
+type word struct {
+	text   string
+	starts []int
+	ends   []int
+}
+
+// LoadTokenizer loads a HuggingFace tokenizer.json, or a BERT vocab.txt
+// (one token per line, ID = line number) when path ends in .txt
+func LoadTokenizer(path string, maxLength int) (*Tokenizer, error) {
+	if strings.EqualFold(filepath.Ext(path), ".txt") {
+		vocab, err := loadVocab(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewWordPieceTokenizer(vocab, maxLength), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTokenizerJSON(data, maxLength)
+}
+
+// loadVocab reads a BERT vocab.txt, where a token's ID is its line number
+func loadVocab(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vocab := make(map[string]int)
+	for id, token := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		vocab[strings.TrimRight(token, "\r")] = id
+	}
+	return vocab, nil
+}
+
+// NewWordPieceTokenizer creates an uncased BERT WordPiece tokenizer over vocab
+func NewWordPieceTokenizer(vocab map[string]int, maxLength int) *Tokenizer {
+	t := newTokenizer(vocab, maxLength)
+	t.Algorithm = AlgorithmWordPiece
+	t.ModelType = "bert"
+	t.normalizer = normalizerOptions{cleanText: true, lowercase: true, stripAccents: true, chineseChars: true}
+	t.unkToken = "[UNK]"
+	t.padToken = "[PAD]"
+	t.prefix = []string{"[CLS]"}
+	t.suffix = []string{"[SEP]"}
+	t.continuingPrefix = "##"
+	t.maxCharsPerWord = 100
+	for _, token := range []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "[MASK]"} {
+		if id, ok := vocab[token]; ok {
+			t.SpecialTokens[token] = id
+		}
+	}
+	return t
+}
+
+func newTokenizer(vocab map[string]int, maxLength int) *Tokenizer {
+	inverseVocab := make(map[int]string, len(vocab))
+	for token, id := range vocab {
+		inverseVocab[id] = token
+	}
+	return &Tokenizer{
+		Vocab:         vocab,
+		InverseVocab:  inverseVocab,
+		SpecialTokens: make(map[string]int),
+		MaxLength:     maxLength,
+	}
+}
+
+// tokenizerFile is the subset of the HuggingFace tokenizer.json schema used here
+type tokenizerFile struct {
+	AddedTokens []struct {
+		ID      int    `json:"id"`
+		Content string `json:"content"`
+		Special bool   `json:"special"`
+	} `json:"added_tokens"`
+	Normalizer    *normalizerSpec `json:"normalizer"`
+	PreTokenizer  *preTokenSpec   `json:"pre_tokenizer"`
+	PostProcessor *postProcSpec   `json:"post_processor"`
+	Padding       *struct {
+		PadToken string `json:"pad_token"`
+	} `json:"padding"`
+	Model struct {
+		Type                    string          `json:"type"`
+		UnkToken                *string         `json:"unk_token"`
+		ContinuingSubwordPrefix *string         `json:"continuing_subword_prefix"`
+		EndOfWordSuffix         *string         `json:"end_of_word_suffix"`
+		MaxInputCharsPerWord    int             `json:"max_input_chars_per_word"`
+		Vocab                   map[string]int  `json:"vocab"`
+		Merges                  json.RawMessage `json:"merges"`
+	} `json:"model"`
+}
+
+type normalizerSpec struct {
+	Type               string           `json:"type"`
+	CleanText          *bool            `json:"clean_text"`
+	HandleChineseChars *bool            `json:"handle_chinese_chars"`
+	StripAccents       *bool            `json:"strip_accents"`
+	Lowercase          *bool            `json:"lowercase"`
+	Normalizers        []normalizerSpec `json:"normalizers"`
+}
+
+type preTokenSpec struct {
+	Type           string         `json:"type"`
+	AddPrefixSpace bool           `json:"add_prefix_space"`
+	PreTokenizers  []preTokenSpec `json:"pretokenizers"`
+}
+
+type postProcSpec struct {
+	Type   string                       `json:"type"`
+	Cls    []json.RawMessage            `json:"cls"`
+	Sep    []json.RawMessage            `json:"sep"`
+	Single []map[string]json.RawMessage `json:"single"`
+}
+
+func parseTokenizerJSON(data []byte, maxLength int) (*Tokenizer, error) {
+	var file tokenizerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid tokenizer.json: %w", err)
+	}
+	if len(file.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("invalid tokenizer.json: model has no vocabulary")
+	}
+
+	vocab := file.Model.Vocab
+	for _, added := range file.AddedTokens {
+		vocab[added.Content] = added.ID
+	}
+	t := newTokenizer(vocab, maxLength)
+	for _, added := range file.AddedTokens {
+		if added.Special {
+			t.SpecialTokens[added.Content] = added.ID
+		}
+	}
+	if file.Model.UnkToken != nil {
+		t.unkToken = *file.Model.UnkToken
+	}
+
+	switch strings.ToLower(file.Model.Type) {
+	case "wordpiece":
+		t.Algorithm = AlgorithmWordPiece
+		t.ModelType = "bert"
+		t.continuingPrefix = "##"
+		if file.Model.ContinuingSubwordPrefix != nil {
+			t.continuingPrefix = *file.Model.ContinuingSubwordPrefix
+		}
+		t.maxCharsPerWord = file.Model.MaxInputCharsPerWord
+		if t.maxCharsPerWord <= 0 {
+			t.maxCharsPerWord = 100
+		}
+	case "bpe":
+		t.Algorithm = AlgorithmBPE
+		t.ModelType = "roberta"
+		if file.Model.EndOfWordSuffix != nil {
+			t.endOfWordSuffix = *file.Model.EndOfWordSuffix
+		}
+		if file.Model.ContinuingSubwordPrefix != nil {
+			t.continuingPrefix = *file.Model.ContinuingSubwordPrefix
+		}
+		ranks, err := parseMerges(file.Model.Merges)
+		if err != nil {
+			return nil, err
+		}
+		t.ranks = ranks
+	default:
+		return nil, fmt.Errorf("unsupported tokenizer model type: %q (want WordPiece or BPE)", file.Model.Type)
+	}
+
+	if file.Normalizer != nil {
+		t.normalizer = file.Normalizer.options()
+	}
+	if file.PreTokenizer != nil {
+		t.byteLevel, t.addPrefixSpace = file.PreTokenizer.byteLevel()
+	}
+	if file.PostProcessor != nil {
+		t.prefix, t.suffix = file.PostProcessor.template()
+	}
+	for _, special := range append(append([]string{}, t.prefix...), t.suffix...) {
+		if _, ok := vocab[special]; !ok {
+			return nil, fmt.Errorf("invalid tokenizer.json: special token %q not in vocabulary", special)
+		}
+	}
+
+	switch {
+	case file.Padding != nil && file.Padding.PadToken != "":
+		t.padToken = file.Padding.PadToken
+	case hasToken(vocab, "[PAD]"):
+		t.padToken = "[PAD]"
+	case hasToken(vocab, "<pad>"):
+		t.padToken = "<pad>"
+	}
+	return t, nil
+}
+
+func hasToken(vocab map[string]int, token string) bool {
+	_, ok := vocab[token]
+	return ok
+}
+
+// parseMerges accepts both merge encodings: "a b" strings and ["a", "b"] pairs
+func parseMerges(raw json.RawMessage) (map[[2]string]int, error) {
+	ranks := make(map[[2]string]int)
+	if len(raw) == 0 {
+		return ranks, nil
+	}
+
+	var asStrings []string
+	if err := json.Unmarshal(raw, &asStrings); err == nil {
+		for i, merge := range asStrings {
+			left, right, ok := strings.Cut(merge, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid tokenizer.json: malformed merge %q", merge)
+			}
+			ranks[[2]string{left, right}] = i
+		}
+		return ranks, nil
+	}
+
+	var asPairs [][2]string
+	if err := json.Unmarshal(raw, &asPairs); err != nil {
+		return nil, fmt.Errorf("invalid tokenizer.json: unreadable merges: %w", err)
+	}
+	for i, pair := range asPairs {
+		ranks[pair] = i
+	}
+	return ranks, nil
+}
+
+// options flattens a normalizer (or sequence of normalizers) into BERT options
+func (n *normalizerSpec) options() normalizerOptions {
+	var opts normalizerOptions
+	switch n.Type {
+	case "BertNormalizer":
+		opts = normalizerOptions{cleanText: true, lowercase: true, chineseChars: true}
+		if n.CleanText != nil {
+			opts.cleanText = *n.CleanText
+		}
+		if n.HandleChineseChars != nil {
+			opts.chineseChars = *n.HandleChineseChars
+		}
+		if n.Lowercase != nil {
+			opts.lowercase = *n.Lowercase
+		}
+		// Unset strip_accents follows lowercase, as in the reference implementation
+		opts.stripAccents = opts.lowercase
+		if n.StripAccents != nil {
+			opts.stripAccents = *n.StripAccents
+		}
+	case "Lowercase":
+		opts.lowercase = true
+	case "StripAccents":
+		opts.stripAccents = true
+	case "Sequence":
+		for i := range n.Normalizers {
+			sub := n.Normalizers[i].options()
+			opts.cleanText = opts.cleanText || sub.cleanText
+			opts.lowercase = opts.lowercase || sub.lowercase
+			opts.stripAccents = opts.stripAccents || sub.stripAccents
+			opts.chineseChars = opts.chineseChars || sub.chineseChars
+		}
+	}
+	return opts
+}
+
+// byteLevel reports whether the pre-tokenizer is (or contains) GPT-2 ByteLevel
+func (p *preTokenSpec) byteLevel() (bool, bool) {
+	if p.Type == "ByteLevel" {
+		return true, p.AddPrefixSpace
+	}
+	for i := range p.PreTokenizers {
+		if ok, prefixSpace := p.PreTokenizers[i].byteLevel(); ok {
+			return true, prefixSpace
+		}
+	}
+	return false, false
+}
+
+// template returns the special tokens wrapped around a single sequence
+func (p *postProcSpec) template() (prefix, suffix []string) {
+	switch p.Type {
+	case "BertProcessing", "RobertaProcessing":
+		if token := specialTokenName(p.Cls); token != "" {
+			prefix = []string{token}
+		}
+		if token := specialTokenName(p.Sep); token != "" {
+			suffix = []string{token}
+		}
+	case "TemplateProcessing":
+		seenSequence := false
+		for _, item := range p.Single {
+			if _, ok := item["Sequence"]; ok {
+				seenSequence = true
+				continue
+			}
+			var special struct {
+				ID string `json:"id"`
+			}
+			if raw, ok := item["SpecialToken"]; ok && json.Unmarshal(raw, &special) == nil {
+				if seenSequence {
+					suffix = append(suffix, special.ID)
+				} else {
+					prefix = append(prefix, special.ID)
+				}
+			}
+		}
+	}
+	return prefix, suffix
+}
+
+// specialTokenName reads the token from a ["[CLS]", 101] pair
+func specialTokenName(pair []json.RawMessage) string {
+	if len(pair) == 0 {
+		return ""
+	}
+	var name string
+	if err := json.Unmarshal(pair[0], &name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// Tokenize converts text to token IDs, adds the model's special tokens,
+// truncates to MaxLength and pads the rest
+func (t *Tokenizer) Tokenize(text string) (*TokenizedInput, error) {
+	if text == "" {
+		return nil, fmt.Errorf("cannot tokenize empty text")
+	}
+
+	var pieces []piece
+	switch t.Algorithm {
+	case AlgorithmWordPiece:
+		for _, w := range t.splitWords(text) {
+			pieces = append(pieces, t.wordPiece(w)...)
+		}
+	case AlgorithmBPE:
+		var words []word
+		if t.byteLevel {
+			words = t.splitByteLevel(text)
+		} else {
+			words = t.splitWords(text)
+		}
+		for _, w := range words {
+			pieces = append(pieces, t.bpe(w)...)
+		}
+	default:
+		return t.tokenizeWords(text)
+	}
+
+	return t.assemble(text, pieces), nil
+}
+
+// assemble wraps pieces in special tokens, truncates and pads
+func (t *Tokenizer) assemble(text string, pieces []piece) *TokenizedInput {
+	budget := t.MaxLength - len(t.prefix) - len(t.suffix)
+	if budget < 0 {
+		budget = 0
+	}
+	truncated := len(pieces) > budget
+	if truncated {
+		pieces = pieces[:budget]
+	}
+
+	size := len(t.prefix) + len(pieces) + len(t.suffix)
+	if size < t.MaxLength {
+		size = t.MaxLength
+	}
+	input := &TokenizedInput{
+		InputIDs:      make([]int32, 0, size),
+		AttentionMask: make([]int32, 0, size),
+		TokenTypeIDs:  make([]int32, size),
+		Offsets:       make([][2]int, 0, size),
+		OriginalText:  text,
+		Truncated:     truncated,
+	}
+	add := func(id int, offsets [2]int) {
+		input.InputIDs = append(input.InputIDs, int32(id))
+		input.AttentionMask = append(input.AttentionMask, 1)
+		input.Offsets = append(input.Offsets, offsets)
+	}
+
+	for _, special := range t.prefix {
+		add(t.Vocab[special], [2]int{})
+	}
+	for _, p := range pieces {
+		add(t.Vocab[p.token], [2]int{p.start, p.end})
+	}
+	for _, special := range t.suffix {
+		add(t.Vocab[special], [2]int{})
+	}
+	input.Length = len(input.InputIDs)
+
+	padID := t.Vocab[t.padToken]
+	for len(input.InputIDs) < t.MaxLength {
+		input.InputIDs = append(input.InputIDs, int32(padID))
+		input.AttentionMask = append(input.AttentionMask, 0)
+		input.Offsets = append(input.Offsets, [2]int{})
+	}
+	return input
+}
+
+// splitWords normalizes text and splits it on whitespace and punctuation,
+// as the BERT pre-tokenizer does
+func (t *Tokenizer) splitWords(text string) []word {
+	var words []word
+	var current word
+	flush := func() {
+		if current.text != "" {
+			words = append(words, current)
+		}
+		current = word{}
+	}
+
+	for offset := 0; offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		start, end := offset, offset+size
+		offset = end
+
+		switch {
+		case t.normalizer.cleanText && (r == 0 || r == utf8.RuneError || isControl(r)):
+		case unicode.IsSpace(r):
+			flush()
+		case isPunctuation(r) || (t.normalizer.chineseChars && isCJK(r)):
+			flush()
+			current.appendNormalized(r, start, end, t.normalizer)
+			flush()
+		default:
+			current.appendNormalized(r, start, end, t.normalizer)
+		}
+	}
+	flush()
+	return words
+}
+
+// appendNormalized adds the normalized form of r, recording its original range
+func (w *word) appendNormalized(r rune, start, end int, opts normalizerOptions) {
+	if opts.lowercase {
+		r = unicode.ToLower(r)
+	}
+	out := string(r)
+	if opts.stripAccents {
+		var b strings.Builder
+		for _, dr := range norm.NFD.String(out) {
+			if !unicode.Is(unicode.Mn, dr) {
+				b.WriteRune(dr)
+			}
+		}
+		out = b.String()
+	}
+	w.appendMapped(out, start, end)
+}
+
+func (w *word) appendMapped(s string, start, end int) {
+	w.text += s
+	for i := 0; i < len(s); i++ {
+		w.starts = append(w.starts, start)
+		w.ends = append(w.ends, end)
+	}
+}
+
+// span maps bytes [i, j) of the word back to an original byte range
+func (w *word) span(i, j int) (int, int) {
+	return w.starts[i], w.ends[j-1]
+}
+
+// wordPiece greedily splits a word into the longest matching vocabulary
+// entries; a word that cannot be fully covered becomes the unknown token
+func (t *Tokenizer) wordPiece(w word) []piece {
+	unknown := []piece{{token: t.unkToken, start: w.starts[0], end: w.ends[len(w.ends)-1]}}
+	if utf8.RuneCountInString(w.text) > t.maxCharsPerWord {
+		return unknown
+	}
+
+	var pieces []piece
+	for start := 0; start < len(w.text); {
+		end := len(w.text)
+		matched := ""
+		for end > start {
+			candidate := w.text[start:end]
+			if start > 0 {
+				candidate = t.continuingPrefix + candidate
+			}
+			if _, ok := t.Vocab[candidate]; ok {
+				matched = candidate
+				break
+			}
+			// Step back one rune
+			_, size := utf8.DecodeLastRuneInString(w.text[start:end])
+			end -= size
+		}
+		if matched == "" {
+			return unknown
+		}
+		s, e := w.span(start, end)
+		pieces = append(pieces, piece{token: matched, start: s, end: e})
+		start = end
+	}
+	return pieces
+}
+
+// splitByteLevel applies the GPT-2 pre-tokenization (contractions, letter,
+// number and symbol runs with an optional leading space) and maps every byte
+// to its printable byte-level character
+func (t *Tokenizer) splitByteLevel(text string) []word {
+	var words []word
+	emit := func(start, end int, leadingSpace bool) {
+		var w word
+		if leadingSpace {
+			w.appendMapped(string(byteEncoder[' ']), start, start)
+		}
+		for i := start; i < end; i++ {
+			w.appendMapped(string(byteEncoder[text[i]]), i, i+1)
+		}
+		words = append(words, w)
+	}
+
+	// add_prefix_space treats the text as if it began with a space
+	prefixSpace := t.addPrefixSpace && text != "" && text[0] != ' '
+
+	for i := 0; i < len(text); {
+		if n := contractionLen(text[i:]); n > 0 {
+			emit(i, i+n, false)
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			j := i
+			for j < len(text) {
+				r2, s2 := utf8.DecodeRuneInString(text[j:])
+				if !unicode.IsSpace(r2) {
+					break
+				}
+				j += s2
+			}
+			if j == len(text) {
+				emit(i, j, false)
+				i = j
+				continue
+			}
+			// Leave a final ' ' to prefix the following word
+			_, lastSize := utf8.DecodeLastRuneInString(text[i:j])
+			if text[j-lastSize] == ' ' {
+				if j-lastSize > i {
+					emit(i, j-lastSize, false)
+				}
+				i = j - lastSize
+				r, size = ' ', 1
+			} else {
+				emit(i, j, false)
+				i = j
+				continue
+			}
+		}
+
+		start := i
+		if r == ' ' {
+			i += size
+			r, size = utf8.DecodeRuneInString(text[i:])
+		}
+		class := runeClass(r)
+		for i < len(text) {
+			r, size = utf8.DecodeRuneInString(text[i:])
+			if unicode.IsSpace(r) || runeClass(r) != class {
+				break
+			}
+			i += size
+		}
+		emit(start, i, prefixSpace && start == 0)
+	}
+	return words
+}
+
+// runeClass groups runes the way the GPT-2 pattern does: letters, numbers, other
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r):
+		return 1
+	case unicode.IsNumber(r):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// contractionLen returns the length of a leading English contraction suffix
+func contractionLen(s string) int {
+	if !strings.HasPrefix(s, "'") {
+		return 0
+	}
+	for _, c := range []string{"'re", "'ve", "'ll", "'s", "'t", "'m", "'d"} {
+		if strings.HasPrefix(s, c) {
+			return len(c)
+		}
+	}
+	return 0
+}
+
+// bpe applies merges to a word, lowest rank first, and maps the resulting
+// symbols to vocabulary entries
+func (t *Tokenizer) bpe(w word) []piece {
+	type symbol struct {
+		text       string
+		start, end int // byte range within w.text
+	}
+
+	var symbols []symbol
+	for i, r := range w.text {
+		size := utf8.RuneLen(r)
+		text := string(r)
+		if i > 0 && t.continuingPrefix != "" {
+			text = t.continuingPrefix + text
+		}
+		symbols = append(symbols, symbol{text: text, start: i, end: i + size})
+	}
+	if n := len(symbols); n > 0 && t.endOfWordSuffix != "" {
+		symbols[n-1].text += t.endOfWordSuffix
+	}
+
+	for len(symbols) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i+1 < len(symbols); i++ {
+			rank, ok := t.ranks[[2]string{symbols[i].text, symbols[i+1].text}]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		right := symbols[best+1].text
+		if t.continuingPrefix != "" {
+			right = strings.TrimPrefix(right, t.continuingPrefix)
+		}
+		symbols[best] = symbol{
+			text:  symbols[best].text + right,
+			start: symbols[best].start,
+			end:   symbols[best+1].end,
+		}
+		symbols = append(symbols[:best+1], symbols[best+2:]...)
+	}
+
+	pieces := make([]piece, 0, len(symbols))
+	for _, sym := range symbols {
+		token := sym.text
+		if _, ok := t.Vocab[token]; !ok {
+			if t.unkToken == "" {
+				continue
+			}
+			token = t.unkToken
+		}
+		start, end := w.span(sym.start, sym.end)
+		pieces = append(pieces, piece{token: token, start: start, end: end})
+	}
+	return pieces
+}
+
+// byteEncoder maps each byte to the printable rune GPT-2 byte-level BPE uses for it
+var byteEncoder = func() [256]rune {
+	var table [256]rune
+	next := rune(256)
+	for b := 0; b < 256; b++ {
+		printable := (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+		if printable {
+			table[b] = rune(b)
+		} else {
+			table[b] = next
+			next++
+		}
+	}
+	return table
+}()
+
+// isPunctuation follows BERT: ASCII symbols count as punctuation too
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+func isControl(r rune) bool {
+	if r == '\t' || r == '\n' || r == '\r' {
+		return false
+	}
+	return unicode.In(r, unicode.Cc, unicode.Cf)
+}
+
+// isCJK reports whether r is in a CJK ideograph block
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
+
+// tokenizeWords is the built-in fallback: lowercased whitespace-separated
+// words looked up whole in the vocabulary
+func (t *Tokenizer) tokenizeWords(text string) (*TokenizedInput, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	words := strings.Fields(text)
+
+	// Start with [CLS]
+	tokenIDs := []int32{int32(t.SpecialTokens["[CLS]"])}
+	attentionMask := []int32{1}
+
+	// Add word tokens
+	for _, word := range words {
+		if id, exists := t.Vocab[word]; exists {
+			tokenIDs = append(tokenIDs, int32(id))
+		} else {
+			tokenIDs = append(tokenIDs, int32(t.SpecialTokens["[UNK]"]))
+		}
+		attentionMask = append(attentionMask, 1)
+
+		if len(tokenIDs) >= t.MaxLength-1 {
+			break
+		}
+	}
+
+	// Add [SEP]
+	tokenIDs = append(tokenIDs, int32(t.SpecialTokens["[SEP]"]))
+	attentionMask = append(attentionMask, 1)
+
+	originalLength := len(tokenIDs)
+	truncated := originalLength >= t.MaxLength
+
+	// Pad to max length
+	for len(tokenIDs) < t.MaxLength {
+		tokenIDs = append(tokenIDs, int32(t.SpecialTokens["[PAD]"]))
+		attentionMask = append(attentionMask, 0)
+	}
+
+	// Token type IDs (all 0 for single sentence)
+	tokenTypeIDs := make([]int32, t.MaxLength)
+
+	return &TokenizedInput{
+		InputIDs:      tokenIDs,
+		AttentionMask: attentionMask,
+		TokenTypeIDs:  tokenTypeIDs,
+		Length:        originalLength,
+		OriginalText:  text,
+		Truncated:     truncated,
+	}, nil
+}
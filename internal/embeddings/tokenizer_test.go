@@ -0,0 +1,90 @@
+package embeddings
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestTokenizer tests WordPiece and byte-level BPE tokenization
+func TestTokenizer(t *testing.T) {
+	t.Run("WordPiece", func(t *testing.T) {
+		vocab := map[string]int{
+			"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3,
+			"ignore": 4, "previous": 5, "instruct": 6, "##ions": 7, "!": 8, "cafe": 9,
+		}
+		tokenizer := NewWordPieceTokenizer(vocab, 12)
+
+		text := "Ignore previous INSTRUCTIONS! Café xyz"
+		tokens, err := tokenizer.Tokenize(text)
+		if err != nil {
+			t.Fatalf("Tokenization failed: %v", err)
+		}
+
+		wantIDs := []int32{2, 4, 5, 6, 7, 8, 9, 1, 3, 0, 0, 0}
+		if fmt.Sprint(tokens.InputIDs) != fmt.Sprint(wantIDs) {
+			t.Errorf("Expected IDs %v, got %v", wantIDs, tokens.InputIDs)
+		}
+		if tokens.Length != 9 {
+			t.Errorf("Expected length 9, got %d", tokens.Length)
+		}
+
+		start := strings.Index(text, "INSTRUCTIONS")
+		if tokens.Offsets[3] != [2]int{start, start + 8} || tokens.Offsets[4] != [2]int{start + 8, start + 12} {
+			t.Errorf("Unexpected sub-word offsets: %v %v", tokens.Offsets[3], tokens.Offsets[4])
+		}
+		start = strings.Index(text, "Café")
+		if tokens.Offsets[6] != [2]int{start, start + len("Café")} {
+			t.Errorf("Accent-stripped word should cover original bytes, got %v", tokens.Offsets[6])
+		}
+	})
+
+	t.Run("Truncation", func(t *testing.T) {
+		vocab := map[string]int{"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3, "a": 4}
+		tokenizer := NewWordPieceTokenizer(vocab, 4)
+
+		tokens, err := tokenizer.Tokenize("a a a a a")
+		if err != nil {
+			t.Fatalf("Tokenization failed: %v", err)
+		}
+		if !tokens.Truncated {
+			t.Error("Expected truncation")
+		}
+		if fmt.Sprint(tokens.InputIDs) != "[2 4 4 3]" {
+			t.Errorf("Special tokens should survive truncation, got %v", tokens.InputIDs)
+		}
+	})
+
+	t.Run("ByteLevelBPE", func(t *testing.T) {
+		tokenizerJSON := `{
+			"added_tokens": [
+				{"id": 0, "content": "<s>", "special": true},
+				{"id": 1, "content": "</s>", "special": true},
+				{"id": 2, "content": "<pad>", "special": true}
+			],
+			"pre_tokenizer": {"type": "ByteLevel", "add_prefix_space": false},
+			"post_processor": {"type": "RobertaProcessing", "sep": ["</s>", 1], "cls": ["<s>", 0]},
+			"model": {
+				"type": "BPE",
+				"unk_token": null,
+				"vocab": {"<s>": 0, "</s>": 1, "<pad>": 2, "hello": 3, "\u0120world": 4},
+				"merges": ["h e", "l l", "he ll", "hell o", "\u0120 w", "o r", "\u0120w or", "\u0120wor l", "\u0120worl d"]
+			}
+		}`
+		tokenizer, err := parseTokenizerJSON([]byte(tokenizerJSON), 6)
+		if err != nil {
+			t.Fatalf("Failed to load tokenizer.json: %v", err)
+		}
+
+		tokens, err := tokenizer.Tokenize("hello world")
+		if err != nil {
+			t.Fatalf("Tokenization failed: %v", err)
+		}
+		if fmt.Sprint(tokens.InputIDs) != "[0 3 4 1 2 2]" {
+			t.Errorf("Unexpected IDs %v", tokens.InputIDs)
+		}
+		if tokens.Offsets[1] != [2]int{0, 5} || tokens.Offsets[2] != [2]int{5, 11} {
+			t.Errorf("Unexpected offsets %v", tokens.Offsets[:4])
+		}
+	})
+}
@@ -1,21 +1,42 @@
 package embeddings
 
 import (
+	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 )
 
 // ModelConfig contains embedding model configuration
 type ModelConfig struct {
-	ModelName     string        `yaml:"model_name" mapstructure:"model_name"`         // "sentence-transformers/all-MiniLM-L6-v2"
-	ModelPath     string        `yaml:"model_path" mapstructure:"model_path"`         // "./models/minilm-l6-v2.onnx"
-	TokenizerPath string        `yaml:"tokenizer_path" mapstructure:"tokenizer_path"` // "./models/tokenizer.json"
-	VocabPath     string        `yaml:"vocab_path" mapstructure:"vocab_path"`         // "./models/vocab.txt"
-	CacheDir      string        `yaml:"cache_dir" mapstructure:"cache_dir"`           // "./models/cache"
-	AutoDownload  bool          `yaml:"auto_download" mapstructure:"auto_download"`   // true
-	MaxLength     int           `yaml:"max_length" mapstructure:"max_length"`         // 512
-	BatchSize     int           `yaml:"batch_size" mapstructure:"batch_size"`         // 32
-	ModelTimeout  time.Duration `yaml:"model_timeout" mapstructure:"model_timeout"`   // 30s
-	CacheTTL      time.Duration `yaml:"cache_ttl" mapstructure:"cache_ttl"`           // 6h
+	ModelName      string        `yaml:"model_name" mapstructure:"model_name"`             // "sentence-transformers/all-MiniLM-L6-v2"
+	ModelPath      string        `yaml:"model_path" mapstructure:"model_path"`             // "./models/minilm-l6-v2.onnx"
+	TokenizerPath  string        `yaml:"tokenizer_path" mapstructure:"tokenizer_path"`     // "./models/tokenizer.json"
+	VocabPath      string        `yaml:"vocab_path" mapstructure:"vocab_path"`             // "./models/vocab.txt"
+	CacheDir       string        `yaml:"cache_dir" mapstructure:"cache_dir"`               // "./models/cache"
+	AutoDownload   bool          `yaml:"auto_download" mapstructure:"auto_download"`       // true
+	MaxLength      int           `yaml:"max_length" mapstructure:"max_length"`             // 512
+	BatchSize      int           `yaml:"batch_size" mapstructure:"batch_size"`             // 32
+	ModelTimeout   time.Duration `yaml:"model_timeout" mapstructure:"model_timeout"`       // 30s
+	CacheTTL       time.Duration `yaml:"cache_ttl" mapstructure:"cache_ttl"`               // 6h
+	MemoSize       int           `yaml:"memo_size" mapstructure:"memo_size"`               // 10000 in-process embeddings keyed by token IDs
+	LocalCacheSize int           `yaml:"local_cache_size" mapstructure:"local_cache_size"` // 10000 in-process embeddings keyed by text, in front of Redis
+	LocalCacheTTL  time.Duration `yaml:"local_cache_ttl" mapstructure:"local_cache_ttl"`   // 5m
+
+	// Revision is the repository commit auto-download fetches; each file
+	// must match its hex SHA-256
+	Revision        string `yaml:"revision" mapstructure:"revision"`
+	ModelSHA256     string `yaml:"model_sha256" mapstructure:"model_sha256"`         // onnx/model.onnx
+	TokenizerSHA256 string `yaml:"tokenizer_sha256" mapstructure:"tokenizer_sha256"` // tokenizer.json
+	VocabSHA256     string `yaml:"vocab_sha256" mapstructure:"vocab_sha256"`         // vocab.txt
+
+	// CacheKey canonicalizes text before it is hashed into a Redis cache key
+	CacheKey textnorm.Options `yaml:"-" mapstructure:"-"`
+
+	// Proxy routes model downloads through an egress proxy; nil uses the
+	// HTTPS_PROXY environment
+	Proxy func(*http.Request) (*url.URL, error) `yaml:"-" mapstructure:"-"`
 }
 
 // EmbeddingResult represents the result of embedding generation
@@ -52,7 +73,10 @@ type ModelStats struct {
 	ModelLoadTime     time.Duration `json:"model_load_time"`
 	ModelMemoryUsage  int64         `json:"model_memory_usage_bytes"`
 	LastInferenceTime time.Time     `json:"last_inference_time"`
-	CacheHitRatio     float64       `json:"cache_hit_ratio"`
+	CacheHitRatio     float64       `json:"cache_hit_ratio"`  // across the local and Redis caches
+	LocalCacheHits    int64         `json:"local_cache_hits"` // served in process without Redis
+	RedisCacheHits    int64         `json:"redis_cache_hits"`
+	CacheMisses       int64         `json:"cache_misses"`
 	ErrorRate         float64       `json:"error_rate"`
 	ServiceType       string        `json:"service_type"`
 	StartTime         time.Time     `json:"start_time"`
@@ -0,0 +1,147 @@
+package etl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Checkpoint records how far ingestion of one input file got. Records is a
+// position in the file's stream of valid records: every record before it was
+// committed, rejected or skipped as already ingested.
+type Checkpoint struct {
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Records   int64     `json:"records"`
+	Batches   int64     `json:"batches"`
+	Complete  bool      `json:"complete"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Checkpoints keeps a Checkpoint per input file in one JSON file, rewritten
+// after each batch the checkpoint advances past
+type Checkpoints struct {
+	mu    sync.Mutex
+	path  string
+	files map[string]Checkpoint // by absolute input path
+}
+
+// OpenCheckpoints loads the checkpoint file at path, starting empty if it
+// does not exist yet
+func OpenCheckpoints(path string) (*Checkpoints, error) {
+	c := &Checkpoints{path: path, files: make(map[string]Checkpoint)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.files); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Path returns the checkpoint file
+func (c *Checkpoints) Path() string {
+	return c.path
+}
+
+// Get returns the checkpoint recorded for input
+func (c *Checkpoints) Get(input string) (Checkpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.files[input]
+	return cp, ok
+}
+
+// Put records input's checkpoint and saves the file. It is written to a
+// temporary file and renamed so a crash never leaves it truncated.
+func (c *Checkpoints) Put(input string, cp Checkpoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[input] = cp
+
+	data, err := json.MarshalIndent(c.files, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// checkpointTracker advances one file's checkpoint as batches finish. Batches
+// finish out of order across the writers, so the checkpoint only moves past
+// a batch once every batch read before it has finished too. A nil tracker
+// records nothing.
+type checkpointTracker struct {
+	mu       sync.Mutex
+	store    *Checkpoints
+	input    string
+	cp       Checkpoint
+	next     int64           // sequence of the batch the checkpoint waits on
+	finished map[int64]int64 // sequence -> end position, for batches done early
+	logger   *zap.Logger
+}
+
+// finish marks batch seq, which ends at record position end, as committed
+func (t *checkpointTracker) finish(seq, end int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.finished[seq] = end
+	advanced := false
+	for {
+		end, ok := t.finished[t.next]
+		if !ok {
+			break
+		}
+		delete(t.finished, t.next)
+		t.next++
+		t.cp.Records = end
+		t.cp.Batches++
+		advanced = true
+	}
+	if advanced {
+		t.saveLocked()
+	}
+}
+
+// complete marks the whole file as ingested
+func (t *checkpointTracker) complete() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cp.Complete = true
+	t.saveLocked()
+}
+
+func (t *checkpointTracker) saveLocked() {
+	t.cp.UpdatedAt = time.Now()
+	if err := t.store.Put(t.input, t.cp); err != nil {
+		t.logger.Warn("Failed to save ETL checkpoint", zap.String("file", t.store.Path()), zap.Error(err))
+	}
+}
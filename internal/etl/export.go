@@ -0,0 +1,163 @@
+package etl
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/vector"
+)
+
+// ExportRecord is one stored vector as written by Export. Its leading fields
+// match DataRecord, so an export can be ingested again elsewhere.
+type ExportRecord struct {
+	Text          string    `parquet:"text" json:"text"`
+	LabelText     string    `parquet:"label_text" json:"label_text"`
+	Label         int       `parquet:"label" json:"label"`
+	EmbeddingType string    `parquet:"embedding_type" json:"embedding_type"`
+	TextHash      string    `parquet:"text_hash" json:"text_hash"`
+	CreatedAt     time.Time `parquet:"created_at,timestamp(microsecond)" json:"created_at"`
+	Embedding     []float32 `parquet:"embedding,list" json:"embedding"`
+}
+
+// exportColumns is the CSV header written by Export
+var exportColumns = []string{"text", "label_text", "label", "embedding_type", "text_hash", "created_at", "embedding"}
+
+// recordWriter writes export records in one file format
+type recordWriter interface {
+	Write(records []ExportRecord) error
+	Close() error
+}
+
+// Export writes every vector matching options to w in format, with
+// embeddings, streaming the table page by page. It returns the number of
+// vectors written.
+func Export(ctx context.Context, store vector.Backend, w io.Writer, format FileFormat, options vector.PageOptions) (int64, error) {
+	var out recordWriter
+	switch format {
+	case FormatCSV:
+		out = newCSVExportWriter(w)
+	case FormatParquet:
+		out = newParquetExportWriter(w)
+	case FormatJSON:
+		out = &jsonExportWriter{encoder: json.NewEncoder(w)}
+	default:
+		return 0, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	options.IncludeEmbedding = true
+	var written int64
+	for {
+		page, err := store.ListVectors(ctx, &options)
+		if err != nil {
+			return written, fmt.Errorf("failed to read vectors after id %d: %w", options.Cursor, err)
+		}
+
+		records := make([]ExportRecord, len(page.Vectors))
+		for i, v := range page.Vectors {
+			records[i] = ExportRecord{
+				Text:          v.Text,
+				LabelText:     v.LabelText,
+				Label:         v.Label,
+				EmbeddingType: v.EmbeddingType,
+				TextHash:      v.TextHash,
+				CreatedAt:     v.CreatedAt.UTC(),
+				Embedding:     v.Embedding,
+			}
+		}
+		if err := out.Write(records); err != nil {
+			return written, fmt.Errorf("failed to write export: %w", err)
+		}
+		written += int64(len(records))
+
+		if !page.HasMore {
+			break
+		}
+		options.Cursor = page.NextCursor
+	}
+
+	if err := out.Close(); err != nil {
+		return written, fmt.Errorf("failed to finish export: %w", err)
+	}
+	return written, nil
+}
+
+// csvExportWriter writes a header row, then embeddings in pgvector's text
+// form, "[1,2,3]"
+type csvExportWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvExportWriter) Write(records []ExportRecord) error {
+	if !c.header {
+		if err := c.writer.Write(exportColumns); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	for _, r := range records {
+		row := []string{
+			r.Text,
+			r.LabelText,
+			strconv.Itoa(r.Label),
+			r.EmbeddingType,
+			r.TextHash,
+			r.CreatedAt.Format(time.RFC3339Nano),
+			formatEmbedding(r.Embedding),
+		}
+		if err := c.writer.Write(row); err != nil {
+			return err
+		}
+	}
+	return c.writer.Error()
+}
+
+func (c *csvExportWriter) Close() error {
+	// An empty export still gets its header
+	if err := c.Write(nil); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// jsonExportWriter writes one JSON object per line, the format readJSON reads
+type jsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonExportWriter) Write(records []ExportRecord) error {
+	for i := range records {
+		if err := j.encoder.Encode(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonExportWriter) Close() error {
+	return nil
+}
+
+// formatEmbedding formats an embedding as "[1,2,3]"
+func formatEmbedding(embedding []float32) string {
+	buf := make([]byte, 0, 2+len(embedding)*12)
+	buf = append(buf, '[')
+	for i, f := range embedding {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, float64(f), 'g', -1, 32)
+	}
+	buf = append(buf, ']')
+	return string(buf)
+}
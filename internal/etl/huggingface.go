@@ -0,0 +1,305 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/parquet-go"
+	"go.uber.org/zap"
+)
+
+// huggingFaceScheme prefixes inputs read from the HuggingFace Hub
+const huggingFaceScheme = "hf://"
+
+// huggingFaceEndpoint is the default hub; HF_ENDPOINT overrides it for mirrors
+const huggingFaceEndpoint = "https://huggingface.co"
+
+// HuggingFaceOptions configures hf:// inputs. Empty fields fall back to the
+// Hub's default config and train split. Columns are mapped by the
+// pipeline's Schema.
+type HuggingFaceOptions struct {
+	Endpoint string // empty uses HF_ENDPOINT, then https://huggingface.co
+	Token    string // for gated or private datasets
+	Config   string
+	Split    string
+	CacheDir string // shards are downloaded here while they are read
+
+	Proxy func(*http.Request) (*url.URL, error) // nil uses the environment's proxy settings
+}
+
+func (o HuggingFaceOptions) withDefaults() HuggingFaceOptions {
+	defaults := []struct {
+		field *string
+		value string
+	}{
+		{&o.Endpoint, os.Getenv("HF_ENDPOINT")},
+		{&o.Endpoint, huggingFaceEndpoint},
+		{&o.Config, "default"},
+		{&o.Split, "train"},
+		{&o.CacheDir, os.TempDir()},
+	}
+	for _, d := range defaults {
+		if *d.field == "" {
+			*d.field = d.value
+		}
+	}
+	o.Endpoint = strings.TrimSuffix(o.Endpoint, "/")
+	return o
+}
+
+// IsHuggingFaceURI reports whether input names a HuggingFace Hub dataset,
+// such as hf://deepset/prompt-injections
+func IsHuggingFaceURI(input string) bool {
+	return strings.HasPrefix(input, huggingFaceScheme)
+}
+
+// huggingFaceDataset is one split of a Hub dataset, read from the Parquet
+// conversion the Hub publishes for every dataset
+type huggingFaceDataset struct {
+	repo    string
+	options HuggingFaceOptions
+	schema  Schema
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// openHuggingFace parses uri, applying its query parameters over the
+// pipeline's options and schema: config, split, and the schema fields
+// ParseSchema accepts, with metadata repeatable
+func (p *Pipeline) openHuggingFace(uri string) (*huggingFaceDataset, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HuggingFace dataset URI %q: %w", uri, err)
+	}
+	repo := strings.Trim(u.Host+u.Path, "/")
+	if repo == "" || strings.Count(repo, "/") > 1 {
+		return nil, fmt.Errorf("invalid HuggingFace dataset URI %q (want hf://owner/name)", uri)
+	}
+
+	options := p.config.HuggingFace.withDefaults()
+	schema := p.config.Schema
+	schema.Metadata = append([]string(nil), schema.Metadata...)
+	for key, values := range u.Query() {
+		switch key {
+		case "config":
+			options.Config = values[0]
+		case "split":
+			options.Split = values[0]
+		default:
+			for _, value := range values {
+				if err := schema.set(key, value); err != nil {
+					return nil, fmt.Errorf("invalid HuggingFace dataset URI %q: %w", uri, err)
+				}
+			}
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	if options.Proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = options.Proxy
+		client.Transport = transport
+	}
+
+	return &huggingFaceDataset{repo: repo, options: options, schema: schema.withDefaults(), client: client, logger: p.logger}, nil
+}
+
+// get requests url, authenticating to the Hub when a token is set. The
+// token is not forwarded when the Hub redirects to its file CDN.
+func (d *huggingFaceDataset) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.options.Token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		resp.Body.Close()
+		return nil, fmt.Errorf("access to dataset %s denied (%s); gated and private datasets need an access token", d.repo, resp.Status)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+}
+
+// getJSON decodes the JSON response of a Hub API call into v
+func (d *huggingFaceDataset) getJSON(ctx context.Context, path string, v any) error {
+	resp, err := d.get(ctx, d.options.Endpoint+path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// lastModified returns when the dataset last changed, which is when its
+// Parquet conversion was last rebuilt
+func (d *huggingFaceDataset) lastModified(ctx context.Context) (time.Time, error) {
+	var info struct {
+		LastModified time.Time `json:"lastModified"`
+	}
+	err := d.getJSON(ctx, "/api/datasets/"+d.repo, &info)
+	return info.LastModified, err
+}
+
+// shards lists the Parquet files of the dataset's config and split in order
+func (d *huggingFaceDataset) shards(ctx context.Context) ([]string, error) {
+	var shards []string
+	path := fmt.Sprintf("/api/datasets/%s/parquet/%s/%s", d.repo, url.PathEscape(d.options.Config), url.PathEscape(d.options.Split))
+	if err := d.getJSON(ctx, path, &shards); err != nil {
+		return nil, fmt.Errorf("failed to list Parquet files of %s (config %q, split %q): %w", d.repo, d.options.Config, d.options.Split, err)
+	}
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("dataset %s has no Parquet files for config %q, split %q", d.repo, d.options.Config, d.options.Split)
+	}
+	return shards, nil
+}
+
+// readHuggingFace streams a Hub dataset shard by shard. Parquet needs random
+// access, so each shard is downloaded to the cache directory, read, and
+// removed before the next is fetched.
+func (p *Pipeline) readHuggingFace(ctx context.Context, uri string, consume batchConsumer) error {
+	dataset, err := p.openHuggingFace(uri)
+	if err != nil {
+		return err
+	}
+	shards, err := dataset.shards(ctx)
+	if err != nil {
+		return err
+	}
+	p.logger.Info("Reading HuggingFace dataset",
+		zap.String("dataset", dataset.repo),
+		zap.String("config", dataset.options.Config),
+		zap.String("split", dataset.options.Split),
+		zap.Int("shards", len(shards)))
+
+	var shard *huggingFaceShard
+	defer func() { shard.close() }()
+	next := 0
+
+	return consume(func() ([]*DataRecord, error) {
+		var batch []*DataRecord
+
+		for len(batch) < p.config.BatchSize {
+			if shard == nil {
+				if next == len(shards) {
+					break
+				}
+				if shard, err = dataset.openShard(ctx, shards[next]); err != nil {
+					return nil, err
+				}
+				next++
+			}
+
+			values, err := shard.rows.next()
+			if err == io.EOF {
+				shard.close()
+				shard = nil
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", shard.url, err)
+			}
+
+			record := p.buildRecord(dataset.schema, values)
+			if p.validateRecord(record) {
+				batch = append(batch, record)
+			}
+		}
+
+		return batch, nil
+	})
+}
+
+// huggingFaceShard reads rows from one downloaded Parquet file of a dataset
+type huggingFaceShard struct {
+	url  string
+	path string
+	file *os.File
+	rows *parquetRows
+}
+
+// openShard downloads shardURL and opens it
+func (d *huggingFaceDataset) openShard(ctx context.Context, shardURL string) (*huggingFaceShard, error) {
+	if err := os.MkdirAll(d.options.CacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create HuggingFace cache directory: %w", err)
+	}
+	file, err := os.CreateTemp(d.options.CacheDir, "hf-*.parquet")
+	if err != nil {
+		return nil, err
+	}
+	s := &huggingFaceShard{url: shardURL, path: file.Name(), file: file}
+
+	d.logger.Info("Downloading dataset shard", zap.String("url", shardURL), zap.String("path", s.path))
+	start := time.Now()
+	resp, err := d.get(ctx, shardURL)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to download %s: %w", shardURL, err)
+	}
+	size, err := io.Copy(file, resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to download %s: %w", shardURL, err)
+	}
+	d.logger.Info("Downloaded dataset shard",
+		zap.String("url", shardURL),
+		zap.Int64("bytes", size),
+		zap.Duration("duration", time.Since(start)))
+
+	parquetFile, err := parquet.OpenFile(file, size)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to open %s: %w", shardURL, err)
+	}
+	if s.rows, err = newParquetRows(parquetFile, d.schema); err != nil {
+		s.close()
+		return nil, fmt.Errorf("%s: %w", shardURL, err)
+	}
+	return s, nil
+}
+
+// close releases the shard and removes its download; a nil shard is ignored
+func (s *huggingFaceShard) close() {
+	if s == nil {
+		return
+	}
+	if s.rows != nil {
+		s.rows.close()
+	}
+	s.file.Close()
+	os.Remove(s.path)
+}
+
+// huggingFaceModTime returns when the dataset behind uri last changed
+func (p *Pipeline) huggingFaceModTime(ctx context.Context, uri string) (time.Time, error) {
+	dataset, err := p.openHuggingFace(uri)
+	if err != nil {
+		return time.Time{}, err
+	}
+	modified, err := dataset.lastModified(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read dataset info of %s: %w", dataset.repo, err)
+	}
+	return modified, nil
+}
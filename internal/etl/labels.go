@@ -0,0 +1,60 @@
+package etl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LabelNormalizer maps dataset label_text variants onto a canonical taxonomy
+type LabelNormalizer struct {
+	mapping    map[string]string // normalized variant -> category
+	categories map[string]string // normalized category -> category
+}
+
+// NewLabelNormalizer creates a normalizer from variant -> category pairs.
+// Variants are normalized, so the mapping may use any casing or separators.
+func NewLabelNormalizer(mapping map[string]string) *LabelNormalizer {
+	n := &LabelNormalizer{
+		mapping:    make(map[string]string, len(mapping)),
+		categories: make(map[string]string, len(mapping)),
+	}
+	for variant, category := range mapping {
+		category = strings.TrimSpace(category)
+		n.mapping[NormalizeLabel(variant)] = category
+		n.categories[NormalizeLabel(category)] = category
+	}
+	return n
+}
+
+// Normalize returns the canonical category for label and whether it was
+// mapped. Unmapped labels are returned in normalized form. A label that is
+// already a canonical category counts as mapped.
+func (n *LabelNormalizer) Normalize(label string) (string, bool) {
+	key := NormalizeLabel(label)
+	if category, ok := n.mapping[key]; ok {
+		return category, true
+	}
+	if category, ok := n.categories[key]; ok {
+		return category, true
+	}
+	return key, false
+}
+
+// NormalizeLabel lowercases label and collapses runs of spaces, hyphens and
+// other separators into a single underscore
+func NormalizeLabel(label string) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range strings.ToLower(strings.TrimSpace(label)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pending && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pending = false
+			b.WriteRune(r)
+			continue
+		}
+		pending = true
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package etl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// undeterminedLanguage is recorded when DetectLanguage cannot tell
+const undeterminedLanguage = "und"
+
+// scriptLanguages maps writing systems used by essentially one language to
+// its ISO 639-1 code. Han is handled separately, since Japanese mixes it
+// with kana.
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// stopwords are frequent short words of the Latin-script languages
+// DetectLanguage distinguishes
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "to", "of", "that", "this", "with", "for", "your", "what", "all", "me", "my", "it", "not", "how", "can", "please", "from"},
+	"es": {"el", "los", "las", "que", "es", "por", "para", "una", "con", "del", "como", "todas", "tus", "eres"},
+	"fr": {"le", "les", "des", "est", "et", "une", "pour", "vous", "dans", "pas", "du", "avec", "tu", "toutes"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "ein", "eine", "mit", "zu", "den", "auf", "alle", "du"},
+	"it": {"il", "che", "di", "per", "non", "sono", "gli", "della", "tutte", "sei", "tuo"},
+	"pt": {"os", "não", "um", "uma", "com", "você", "do", "da", "todas", "seu", "são"},
+	"nl": {"het", "een", "en", "van", "niet", "dat", "je", "ik", "voor", "op", "alle", "jouw"},
+}
+
+// stopwordLanguages indexes stopwords by word
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// latinLanguages breaks ties between stopword scores, most common first
+var latinLanguages = []string{"en", "es", "fr", "de", "pt", "it", "nl"}
+
+// DetectLanguage guesses the ISO 639-1 code of text: by writing system for
+// scripts that identify a language, and by stopword counts for Latin text.
+// It returns "und" when text has no letters or no recognized words. The
+// guess is cheap and coarse; it is meant for corpus breakdowns, not for
+// routing decisions.
+func DetectLanguage(text string) string {
+	scripts := make(map[string]int)
+	latin := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				scripts[s.language]++
+				break
+			}
+		}
+	}
+
+	// Kana marks Han text as Japanese
+	if scripts["ja"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	best, bestCount := undeterminedLanguage, 0
+	for _, s := range scriptLanguages {
+		if count := scripts[s.language]; count > bestCount {
+			best, bestCount = s.language, count
+		}
+	}
+	if bestCount > latin {
+		return best
+	}
+	if latin == 0 {
+		return undeterminedLanguage
+	}
+
+	scores := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, language := range stopwordLanguages[word] {
+			scores[language]++
+		}
+	}
+	best, bestScore := undeterminedLanguage, 0
+	for _, language := range latinLanguages {
+		if scores[language] > bestScore {
+			best, bestScore = language, scores[language]
+		}
+	}
+	return best
+}
@@ -0,0 +1,161 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/raaihank/llm-sentinel/internal/vector"
+)
+
+// Near-duplicate detection methods
+const (
+	NearDuplicateSimHash   = "simhash"   // 64-bit SimHash of the text, compared within a run
+	NearDuplicateEmbedding = "embedding" // cosine similarity to the batch and the stored corpus
+)
+
+// MaxSimHashDistance bounds NearDuplicateOptions.MaxDistance. The index
+// splits fingerprints into MaxDistance+1 blocks; beyond this the blocks are
+// too narrow to narrow the search.
+const MaxSimHashDistance = 10
+
+// NearDuplicateOptions configures the near-duplicate filter. Exact
+// duplicates are always dropped by text_hash; this also drops records that
+// differ only slightly from one already kept, such as templated prompts with
+// a word swapped.
+type NearDuplicateOptions struct {
+	Method        string  // "" disables the filter
+	MaxDistance   int     // simhash: differing fingerprint bits still counted as a duplicate
+	MinSimilarity float64 // embedding: cosine similarity at which records are duplicates
+}
+
+// nearDuplicateFilter remembers the fingerprints of the records kept from
+// the current file
+type nearDuplicateFilter struct {
+	options NearDuplicateOptions
+
+	mu     sync.Mutex
+	blocks []map[uint64][]uint64 // fingerprints by the value of each block
+}
+
+func newNearDuplicateFilter(options NearDuplicateOptions) *nearDuplicateFilter {
+	f := &nearDuplicateFilter{options: options}
+	if options.Method == NearDuplicateSimHash {
+		f.blocks = make([]map[uint64][]uint64, options.MaxDistance+1)
+		for i := range f.blocks {
+			f.blocks[i] = make(map[uint64][]uint64)
+		}
+	}
+	return f
+}
+
+// block returns the i-th of len(f.blocks) bit ranges of fingerprint
+func (f *nearDuplicateFilter) block(fingerprint uint64, i int) uint64 {
+	n := len(f.blocks)
+	lo, hi := 64*i/n, 64*(i+1)/n
+	return (fingerprint >> lo) & (1<<(hi-lo) - 1)
+}
+
+// seen reports whether fingerprint is within MaxDistance bits of one already
+// added, adding it if not. Fingerprints that close agree on at least one of
+// the MaxDistance+1 blocks, so only fingerprints sharing a block are compared.
+func (f *nearDuplicateFilter) seen(fingerprint uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, index := range f.blocks {
+		for _, candidate := range index[f.block(fingerprint, i)] {
+			if bits.OnesCount64(candidate^fingerprint) <= f.options.MaxDistance {
+				return true
+			}
+		}
+	}
+	for i, index := range f.blocks {
+		key := f.block(fingerprint, i)
+		index[key] = append(index[key], fingerprint)
+	}
+	return false
+}
+
+// dropSimilarText drops the records of batch whose SimHash is close to one
+// kept earlier in the run, returning how many were dropped
+func (f *nearDuplicateFilter) dropSimilarText(batch []*DataRecord) ([]*DataRecord, int64) {
+	kept := batch[:0]
+	for _, record := range batch {
+		if !f.seen(simHash(record.Text)) {
+			kept = append(kept, record)
+		}
+	}
+	return kept, int64(len(batch) - len(kept))
+}
+
+// dropSimilarEmbeddings drops vectors at least MinSimilarity to an earlier
+// vector of the batch or to a stored vector, returning the kept vectors.
+// Batches embedded concurrently are not compared with each other.
+func (f *nearDuplicateFilter) dropSimilarEmbeddings(ctx context.Context, store vector.Backend, vectors []*vector.SecurityVector) ([]*vector.SecurityVector, error) {
+	kept := make([]*vector.SecurityVector, 0, len(vectors))
+	search := &vector.SearchOptions{Limit: 1, MinSimilarity: float32(f.options.MinSimilarity)}
+
+next:
+	for _, v := range vectors {
+		for _, k := range kept {
+			if cosine(v.Embedding, k.Embedding) >= f.options.MinSimilarity {
+				continue next
+			}
+		}
+		if store != nil {
+			similar, err := store.FindSimilar(ctx, v.Embedding, search)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search for near-duplicates: %w", err)
+			}
+			if len(similar) > 0 {
+				continue
+			}
+		}
+		kept = append(kept, v)
+	}
+	return kept, nil
+}
+
+// simHash fingerprints the character 4-grams of text's lowercased words,
+// so texts sharing most of their wording differ in few bits. Character
+// shingles keep a one-word edit from moving a short prompt far.
+func simHash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	runes := []rune(strings.Join(words, " "))
+
+	// Texts shorter than a shingle are one feature
+	shingles := max(len(runes)-simHashShingle+1, 1)
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i < shingles; i++ {
+		h.Reset()
+		h.Write([]byte(string(runes[i:min(i+simHashShingle, len(runes))])))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// simHashShingle is the length in characters of the features simHash counts
+const simHashShingle = 4
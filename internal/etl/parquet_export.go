@@ -0,0 +1,246 @@
+package etl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/segmentio/encoding/thrift"
+	"github.com/segmentio/parquet-go/deprecated"
+	"github.com/segmentio/parquet-go/format"
+)
+
+// parquetMagic opens and closes every Parquet file
+const parquetMagic = "PAR1"
+
+// parquetExportWriter writes export records as a Parquet file with one row
+// group per Write, PLAIN encoded and uncompressed. It encodes pages itself
+// because parquet-go's writer depends on runtime internals that current Go
+// toolchains no longer link; its reader and format definitions are fine.
+type parquetExportWriter struct {
+	w      io.Writer
+	offset int64
+	rows   int64
+	groups []format.RowGroup
+}
+
+// exportSchema is ExportRecord's Parquet schema. The embedding is a standard
+// three-level LIST of FLOAT so other tools read it as a float array.
+func exportSchema() []format.SchemaElement {
+	required, repeated := format.Required, format.Repeated
+	byteArray, int64Type, floatType := format.ByteArray, format.Int64, format.Float
+	utf8, list, timestamp := deprecated.UTF8, deprecated.List, deprecated.TimestampMicros
+	str := func(name string) format.SchemaElement {
+		return format.SchemaElement{
+			Name:           name,
+			Type:           &byteArray,
+			RepetitionType: &required,
+			ConvertedType:  &utf8,
+			LogicalType:    &format.LogicalType{UTF8: &format.StringType{}},
+		}
+	}
+
+	return []format.SchemaElement{
+		{Name: "schema", NumChildren: 7},
+		str("text"),
+		str("label_text"),
+		{Name: "label", Type: &int64Type, RepetitionType: &required},
+		str("embedding_type"),
+		str("text_hash"),
+		{
+			Name:           "created_at",
+			Type:           &int64Type,
+			RepetitionType: &required,
+			ConvertedType:  &timestamp,
+			LogicalType: &format.LogicalType{Timestamp: &format.TimestampType{
+				IsAdjustedToUTC: true,
+				Unit:            format.TimeUnit{Micros: &format.MicroSeconds{}},
+			}},
+		},
+		{
+			Name:           "embedding",
+			RepetitionType: &required,
+			NumChildren:    1,
+			ConvertedType:  &list,
+			LogicalType:    &format.LogicalType{List: &format.ListType{}},
+		},
+		{Name: "list", RepetitionType: &repeated, NumChildren: 1},
+		{Name: "element", Type: &floatType, RepetitionType: &required},
+	}
+}
+
+func newParquetExportWriter(w io.Writer) *parquetExportWriter {
+	return &parquetExportWriter{w: w}
+}
+
+// parquetColumn is one leaf column of a row group, encoded as a single page
+type parquetColumn struct {
+	path   []string
+	kind   format.Type
+	values int // entries in the page, counting levels for the list column
+	body   []byte
+}
+
+// write appends data to the file, starting it with the magic bytes
+func (p *parquetExportWriter) write(data []byte) error {
+	if p.offset == 0 {
+		if _, err := io.WriteString(p.w, parquetMagic); err != nil {
+			return err
+		}
+		p.offset = int64(len(parquetMagic))
+	}
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *parquetExportWriter) Write(records []ExportRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	byteArrays := func(get func(*ExportRecord) string) []byte {
+		var buf bytes.Buffer
+		for i := range records {
+			s := get(&records[i])
+			buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+			buf.WriteString(s)
+		}
+		return buf.Bytes()
+	}
+	int64s := func(get func(*ExportRecord) int64) []byte {
+		buf := make([]byte, 0, 8*len(records))
+		for i := range records {
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(get(&records[i])))
+		}
+		return buf
+	}
+
+	group := format.RowGroup{NumRows: int64(len(records))}
+	values, embeddings := encodeEmbeddings(records)
+	columns := []parquetColumn{
+		{[]string{"text"}, format.ByteArray, len(records), byteArrays(func(r *ExportRecord) string { return r.Text })},
+		{[]string{"label_text"}, format.ByteArray, len(records), byteArrays(func(r *ExportRecord) string { return r.LabelText })},
+		{[]string{"label"}, format.Int64, len(records), int64s(func(r *ExportRecord) int64 { return int64(r.Label) })},
+		{[]string{"embedding_type"}, format.ByteArray, len(records), byteArrays(func(r *ExportRecord) string { return r.EmbeddingType })},
+		{[]string{"text_hash"}, format.ByteArray, len(records), byteArrays(func(r *ExportRecord) string { return r.TextHash })},
+		{[]string{"created_at"}, format.Int64, len(records), int64s(func(r *ExportRecord) int64 { return r.CreatedAt.UnixMicro() })},
+		{[]string{"embedding", "list", "element"}, format.Float, values, embeddings},
+	}
+
+	for _, column := range columns {
+		header, err := thrift.Marshal(new(thrift.CompactProtocol), &format.PageHeader{
+			Type:                 format.DataPage,
+			UncompressedPageSize: int32(len(column.body)),
+			CompressedPageSize:   int32(len(column.body)),
+			DataPageHeader: &format.DataPageHeader{
+				NumValues:               int32(column.values),
+				Encoding:                format.Plain,
+				DefinitionLevelEncoding: format.RLE,
+				RepetitionLevelEncoding: format.RLE,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode page header: %w", err)
+		}
+
+		if err := p.write(header); err != nil {
+			return err
+		}
+		pageOffset := p.offset - int64(len(header))
+		if err := p.write(column.body); err != nil {
+			return err
+		}
+
+		size := int64(len(header) + len(column.body))
+		group.TotalByteSize += size
+		group.Columns = append(group.Columns, format.ColumnChunk{
+			FileOffset: pageOffset,
+			MetaData: format.ColumnMetaData{
+				Type:                  column.kind,
+				Encoding:              []format.Encoding{format.Plain, format.RLE},
+				PathInSchema:          column.path,
+				Codec:                 format.Uncompressed,
+				NumValues:             int64(column.values),
+				TotalUncompressedSize: size,
+				TotalCompressedSize:   size,
+				DataPageOffset:        pageOffset,
+			},
+		})
+	}
+
+	p.groups = append(p.groups, group)
+	p.rows += int64(len(records))
+	return nil
+}
+
+// Close writes the footer. An export with no rows is still a valid file.
+func (p *parquetExportWriter) Close() error {
+	footer, err := thrift.Marshal(new(thrift.CompactProtocol), &format.FileMetaData{
+		Version:   1,
+		Schema:    exportSchema(),
+		NumRows:   p.rows,
+		RowGroups: p.groups,
+		CreatedBy: "llm-sentinel",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode parquet footer: %w", err)
+	}
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	if err := p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+// encodeEmbeddings builds the data page body of the embedding column:
+// repetition levels, definition levels, then the floats. Each row
+// contributes one level per element, or a single level 0 entry when its
+// embedding is empty.
+func encodeEmbeddings(records []ExportRecord) (int, []byte) {
+	var repetition, definition []byte
+	var floats []byte
+	for i := range records {
+		embedding := records[i].Embedding
+		if len(embedding) == 0 {
+			repetition = append(repetition, 0)
+			definition = append(definition, 0)
+			continue
+		}
+		for j, f := range embedding {
+			repetition = append(repetition, boolLevel(j > 0))
+			definition = append(definition, 1)
+			floats = binary.LittleEndian.AppendUint32(floats, math.Float32bits(f))
+		}
+	}
+
+	body := appendLevels(nil, repetition)
+	body = appendLevels(body, definition)
+	return len(repetition), append(body, floats...)
+}
+
+func boolLevel(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// appendLevels appends 0/1 levels in the RLE/bit-packing hybrid encoding with
+// bit width 1 as a single bit-packed run, prefixed by its length
+func appendLevels(buf []byte, levels []byte) []byte {
+	groups := (len(levels) + 7) / 8
+	run := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, level := range levels {
+		packed[i/8] |= level << (i % 8)
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(run)+len(packed)))
+	buf = append(buf, run...)
+	return append(buf, packed...)
+}
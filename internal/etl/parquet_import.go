@@ -0,0 +1,122 @@
+package etl
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+)
+
+// parquetRows reads the rows of a Parquet file in schema field order
+type parquetRows struct {
+	reader     *parquet.Reader
+	positions  []int    // leaf column of each field, -1 when absent
+	labelNames []string // ClassLabel names of the label column, if any
+	rows       []parquet.Row
+	pending    []parquet.Row // read but not yet returned
+}
+
+// newParquetRows resolves schema against the file's columns. Nested
+// columns are named by their dotted path.
+func newParquetRows(file *parquet.File, schema Schema) (*parquetRows, error) {
+	var names []string
+	for _, path := range file.Schema().Columns() {
+		names = append(names, strings.Join(path, "."))
+	}
+	positions, err := schema.resolve(names, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &parquetRows{
+		reader:    parquet.NewReader(file),
+		positions: positions,
+		rows:      make([]parquet.Row, 128),
+	}
+
+	// The datasets library stores its features, including ClassLabel
+	// names, in the file's key/value metadata
+	if metadata, ok := file.Lookup("huggingface"); ok {
+		var features struct {
+			Info struct {
+				Features map[string]struct {
+					Type  string   `json:"_type"`
+					Names []string `json:"names"`
+				} `json:"features"`
+			} `json:"info"`
+		}
+		if json.Unmarshal([]byte(metadata), &features) == nil {
+			if feature := features.Info.Features[schema.Label]; feature.Type == "ClassLabel" {
+				r.labelNames = feature.Names
+			}
+		}
+	}
+	return r, nil
+}
+
+// next returns the next row's fields, or io.EOF after the last row
+func (r *parquetRows) next() ([]fieldValue, error) {
+	if len(r.pending) == 0 {
+		n, err := r.reader.ReadRows(r.rows)
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		r.pending = r.rows[:n]
+	}
+	row := r.pending[0]
+	r.pending = r.pending[1:]
+
+	values := make([]fieldValue, len(r.positions))
+	for _, v := range row {
+		if v.IsNull() {
+			continue
+		}
+		for i, position := range r.positions {
+			if position == v.Column() {
+				values[i] = fieldValue{parquetString(v), true}
+			}
+		}
+	}
+
+	// A ClassLabel stores the class index; its name is the label_text. With
+	// more than two classes the index is no 0/1 label, so the label is
+	// derived from the name instead.
+	if len(r.labelNames) > 0 && !values[fieldLabelText].ok {
+		if i, err := strconv.Atoi(values[fieldLabel].value); err == nil && i >= 0 && i < len(r.labelNames) {
+			values[fieldLabelText] = fieldValue{r.labelNames[i], true}
+			if len(r.labelNames) > 2 {
+				values[fieldLabel] = fieldValue{}
+			}
+		}
+	}
+	return values, nil
+}
+
+func (r *parquetRows) close() {
+	r.reader.Close()
+}
+
+// parquetString formats a Parquet value as a field
+func parquetString(v parquet.Value) string {
+	switch v.Kind() {
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return string(v.ByteArray())
+	case parquet.Boolean:
+		return strconv.FormatBool(v.Boolean())
+	case parquet.Int32:
+		return strconv.FormatInt(int64(v.Int32()), 10)
+	case parquet.Int64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'g', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	default:
+		return v.String()
+	}
+}
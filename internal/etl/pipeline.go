@@ -1,32 +1,52 @@
 package etl
 
 import (
+	"bufio"
+	"bytes"
 	"context"
-	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/csv"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/segmentio/parquet-go"
 	"go.uber.org/zap"
 
+	"github.com/raaihank/llm-sentinel/internal/artifact"
 	"github.com/raaihank/llm-sentinel/internal/cache"
 	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 	"github.com/raaihank/llm-sentinel/internal/vector"
 )
 
 // Pipeline handles ETL operations for security datasets
 type Pipeline struct {
-	vectorStore      *vector.Store
+	vectorStore      vector.Backend
 	embeddingService embeddings.EmbeddingService
 	vectorCache      *cache.VectorCache
 	config           *Config
+	labels           *LabelNormalizer
+	rejects          *RejectWriter
+	decrypter        *artifact.Decrypter
+	useCopy          bool   // the current file is loaded with CopyInsert
+	source           string // the current file's name, recorded on its vectors
+	checkpoints      *Checkpoints
+	resume           bool                 // skip checkpointed and already-stored records
+	tracker          *checkpointTracker   // the current file's checkpoint
+	resumeFrom       int64                // records of the current file the checkpoint covers
+	nearDups         *nearDuplicateFilter // nil when near-duplicates are kept
 	logger           *zap.Logger
 	stats            *ProcessingStats
 	mu               sync.RWMutex
@@ -34,7 +54,7 @@ type Pipeline struct {
 
 // NewPipeline creates a new ETL pipeline
 func NewPipeline(
-	vectorStore *vector.Store,
+	vectorStore vector.Backend,
 	embeddingService embeddings.EmbeddingService,
 	vectorCache *cache.VectorCache,
 	config *Config,
@@ -45,6 +65,8 @@ func NewPipeline(
 		embeddingService: embeddingService,
 		vectorCache:      vectorCache,
 		config:           config,
+		labels:           NewLabelNormalizer(config.LabelMapping),
+		decrypter:        &artifact.Decrypter{},
 		logger:           logger,
 		stats: &ProcessingStats{
 			StartTime: time.Now(),
@@ -52,6 +74,24 @@ func NewPipeline(
 	}
 }
 
+// SetRejectWriter routes records that cannot be ingested to w
+func (p *Pipeline) SetRejectWriter(w *RejectWriter) {
+	p.rejects = w
+}
+
+// SetDecrypter lets the pipeline read age-encrypted input files
+func (p *Pipeline) SetDecrypter(d *artifact.Decrypter) {
+	p.decrypter = d
+}
+
+// SetCheckpoints records each file's progress in c. With resume, a file
+// restarts after its checkpoint and records whose text_hash is already
+// stored are skipped rather than embedded again.
+func (p *Pipeline) SetCheckpoints(c *Checkpoints, resume bool) {
+	p.checkpoints = c
+	p.resume = resume
+}
+
 // ProcessFile processes a dataset file (CSV, Parquet, or JSON)
 func (p *Pipeline) ProcessFile(ctx context.Context, filePath string) (*ProcessingResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
@@ -65,38 +105,30 @@ func (p *Pipeline) ProcessFile(ctx context.Context, filePath string) (*Processin
 	start := time.Now()
 	result := &ProcessingResult{}
 
-	// Detect file format
-	format := DetectFileFormat(filePath)
-	p.logger.Info("Detected file format", zap.String("format", string(format)))
-
 	// Reset stats
 	p.resetStats()
+	p.useCopy = p.shouldCopy(filePath)
+	p.source = sourceName(filePath)
+	p.startCheckpoint(ctx, filePath)
+	p.nearDups = nil
+	if p.config.NearDuplicates.Method != "" {
+		p.nearDups = newNearDuplicateFilter(p.config.NearDuplicates)
+	}
 
 	// Process based on file format
-	switch format {
-	case FormatCSV:
-		err := p.processCSV(ctx, filePath, result)
-		if err != nil {
-			return result, fmt.Errorf("CSV processing failed: %w", err)
-		}
-	case FormatParquet:
-		err := p.processParquet(ctx, filePath, result)
-		if err != nil {
-			return result, fmt.Errorf("Parquet processing failed: %w", err)
-		}
-	case FormatJSON:
-		err := p.processJSON(ctx, filePath, result)
-		if err != nil {
-			return result, fmt.Errorf("JSON processing failed: %w", err)
-		}
-	default:
-		return result, fmt.Errorf("unsupported file format: %s", format)
+	err := p.readFile(ctx, filePath, func(readBatch func() ([]*DataRecord, error)) error {
+		return p.processBatches(ctx, readBatch, result)
+	})
+	if err != nil {
+		return result, err
 	}
+	p.tracker.complete()
 
 	result.Duration = time.Since(start)
 
-	// Create vector index if requested and we have enough data
-	if p.config.CreateIndex && result.ProcessedOK > 1000 {
+	// Create vector index if requested; the store skips corpora below its
+	// minimum size
+	if p.config.CreateIndex && result.ProcessedOK > 0 {
 		p.logger.Info("Creating vector similarity index...")
 		indexStart := time.Now()
 		if err := p.vectorStore.CreateIndex(ctx); err != nil {
@@ -110,39 +142,166 @@ func (p *Pipeline) ProcessFile(ctx context.Context, filePath string) (*Processin
 		zap.Int64("total_records", result.TotalRecords),
 		zap.Int64("processed_ok", result.ProcessedOK),
 		zap.Int64("processed_failed", result.ProcessedFailed),
+		zap.Int64("resumed", result.Resumed),
+		zap.Int64("near_duplicates", result.NearDuplicates),
 		zap.Duration("total_duration", result.Duration),
 		zap.Duration("embedding_time", result.EmbeddingTime),
 		zap.Duration("database_time", result.DatabaseTime))
+	for _, stage := range result.Stages {
+		p.logger.Info("Stage throughput",
+			zap.String("stage", stage.Stage),
+			zap.Int("workers", stage.Workers),
+			zap.Int64("records", stage.Records),
+			zap.Duration("busy", stage.Busy),
+			zap.Float64("records_per_second", stage.RecordsPerSecond))
+	}
 
 	return result, nil
 }
 
-// processCSV processes CSV files
-func (p *Pipeline) processCSV(ctx context.Context, filePath string, result *ProcessingResult) error {
+// startCheckpoint sets up checkpointing for filePath. When resuming, the
+// file's checkpoint is only trusted if the file is unchanged since; an
+// edited file is read from the start, relying on text_hash to skip what is
+// already stored. A HuggingFace dataset counts as changed when the Hub
+// reports a newer modification time.
+func (p *Pipeline) startCheckpoint(ctx context.Context, filePath string) {
+	p.tracker = nil
+	p.resumeFrom = 0
+	if p.checkpoints == nil {
+		return
+	}
+
+	input, err := filepath.Abs(filePath)
+	if err != nil {
+		input = filePath
+	}
+	cp := Checkpoint{}
+	if IsHuggingFaceURI(filePath) {
+		input = filePath
+		modified, err := p.huggingFaceModTime(ctx, filePath)
+		if err != nil {
+			p.logger.Warn("Cannot tell whether the dataset changed since its checkpoint", zap.String("file", filePath), zap.Error(err))
+		}
+		cp.ModTime = modified
+	} else if info, err := os.Stat(filePath); err == nil {
+		cp.Size = info.Size()
+		cp.ModTime = info.ModTime()
+	}
+
+	if prev, ok := p.checkpoints.Get(input); ok && p.resume {
+		if prev.Size == cp.Size && prev.ModTime.Equal(cp.ModTime) {
+			cp.Records = prev.Records
+			cp.Batches = prev.Batches
+			p.resumeFrom = prev.Records
+			p.logger.Info("Resuming from checkpoint",
+				zap.String("file", filePath),
+				zap.Int64("records", prev.Records),
+				zap.Int64("batches", prev.Batches),
+				zap.Bool("complete", prev.Complete),
+				zap.Time("updated_at", prev.UpdatedAt))
+		} else {
+			p.logger.Warn("Input changed since its checkpoint; reading it from the start and skipping stored records by text_hash",
+				zap.String("file", filePath))
+		}
+	}
+
+	p.tracker = &checkpointTracker{
+		store:    p.checkpoints,
+		input:    input,
+		cp:       cp,
+		finished: make(map[int64]int64),
+		logger:   p.logger,
+	}
+}
+
+// batchConsumer drains records from a file reader one batch at a time
+type batchConsumer func(readBatch func() ([]*DataRecord, error)) error
+
+// readFile opens filePath according to its format and hands its batch reader
+// to consume. hf:// inputs are read from the HuggingFace Hub.
+func (p *Pipeline) readFile(ctx context.Context, filePath string, consume batchConsumer) error {
+	if IsHuggingFaceURI(filePath) {
+		if err := p.readHuggingFace(ctx, filePath, consume); err != nil {
+			return fmt.Errorf("HuggingFace dataset processing failed: %w", err)
+		}
+		return nil
+	}
+
+	format := DetectFileFormat(filePath)
+	p.logger.Info("Detected file format", zap.String("format", string(format)))
+
+	switch format {
+	case FormatCSV:
+		if err := p.readCSV(filePath, consume); err != nil {
+			return fmt.Errorf("CSV processing failed: %w", err)
+		}
+	case FormatParquet:
+		if err := p.readParquet(filePath, consume); err != nil {
+			return fmt.Errorf("Parquet processing failed: %w", err)
+		}
+	case FormatJSON:
+		if err := p.readJSON(filePath, consume); err != nil {
+			return fmt.Errorf("JSON processing failed: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported file format: %s", format)
+	}
+	return nil
+}
+
+// readCSV reads CSV files, mapping columns through the schema. The
+// delimiter is detected from the first line.
+func (p *Pipeline) readCSV(filePath string, consume batchConsumer) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 3 // text, label_text, label
-
-	// Read header
-	header, err := reader.Read()
+	input, _, err := p.decrypter.Open(file)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		return err
 	}
 
-	p.logger.Info("CSV header detected", zap.Strings("columns", header))
+	buffered := bufio.NewReader(input)
+	reader := csv.NewReader(buffered)
+	reader.Comma = sniffDelimiter(buffered)
+	reader.FieldsPerRecord = -1 // rows may be short or carry extra columns
+	reader.LazyQuotes = true
+
+	schema := p.config.Schema.withDefaults()
+	var positions []int
+	if schema.NoHeader {
+		if positions, err = schema.resolve(nil, true); err != nil {
+			return fmt.Errorf("invalid schema for a CSV file without a header: %w", err)
+		}
+	} else {
+		header, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		p.logger.Info("CSV header detected", zap.Strings("columns", header), zap.String("delimiter", string(reader.Comma)))
+
+		positions, err = schema.resolve(header, true)
+		if err != nil {
+			// Headerless-looking files in the original text, label_text,
+			// label layout are still read by position
+			if !schema.isDefaultLayout() {
+				return err
+			}
+			p.logger.Warn("CSV header does not name the text, label_text and label columns; reading them by position",
+				zap.Strings("columns", header))
+			positions = schema.defaultPositions()
+		}
+	}
 
 	// Process records in batches
-	return p.processBatches(ctx, func() ([]*DataRecord, error) {
+	return consume(func() ([]*DataRecord, error) {
 		var batch []*DataRecord
 		p.logger.Debug("Starting to read batch")
 
 		for len(batch) < p.config.BatchSize {
-			record, err := reader.Read()
+			row, err := reader.Read()
 			if err == io.EOF {
 				break
 			}
@@ -151,25 +310,14 @@ func (p *Pipeline) processCSV(ctx context.Context, filePath string, result *Proc
 				continue
 			}
 
-			if len(record) != 3 {
-				p.logger.Warn("Invalid CSV record length", zap.Int("length", len(record)))
-				continue
-			}
-
-			// Parse label as integer
-			var label int
-			if record[2] == "1" || strings.ToLower(record[2]) == "true" {
-				label = 1
-			} else {
-				label = 0
-			}
-
-			dataRecord := &DataRecord{
-				Text:      strings.TrimSpace(record[0]),
-				LabelText: strings.TrimSpace(record[1]),
-				Label:     label,
+			values := make([]fieldValue, len(positions))
+			for i, position := range positions {
+				if position >= 0 && position < len(row) {
+					values[i] = fieldValue{row[position], true}
+				}
 			}
 
+			dataRecord := p.buildRecord(schema, values)
 			if p.validateRecord(dataRecord) {
 				batch = append(batch, dataRecord)
 			}
@@ -177,81 +325,292 @@ func (p *Pipeline) processCSV(ctx context.Context, filePath string, result *Proc
 
 		p.logger.Debug("Batch read completed", zap.Int("batch_size", len(batch)))
 		return batch, nil
-	}, result)
+	})
 }
 
-// processParquet processes Parquet files
-func (p *Pipeline) processParquet(ctx context.Context, filePath string, result *ProcessingResult) error {
+// sniffDelimiter picks the most frequent of comma, tab, semicolon and pipe
+// on the first line, preferring comma
+func sniffDelimiter(r *bufio.Reader) rune {
+	head, _ := r.Peek(64 * 1024)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+
+	best, bestCount := ',', bytes.Count(head, []byte{','})
+	for _, candidate := range []rune{'\t', ';', '|'} {
+		if count := bytes.Count(head, []byte{byte(candidate)}); count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
+
+// readParquet reads Parquet files, mapping columns through the schema
+func (p *Pipeline) readParquet(filePath string, consume batchConsumer) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open Parquet file: %w", err)
 	}
 	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open Parquet file: %w", err)
+	}
 
-	reader := parquet.NewReader(file)
-	defer reader.Close()
+	// Parquet needs random access, so encrypted files are decrypted in memory
+	var input io.ReaderAt = file
+	size := info.Size()
+	plaintext, encrypted, err := p.decrypter.Open(file)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		data, err := io.ReadAll(plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt Parquet file: %w", err)
+		}
+		input = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	parquetFile, err := parquet.OpenFile(input, size)
+	if err != nil {
+		return fmt.Errorf("failed to open Parquet file: %w", err)
+	}
+	schema := p.config.Schema.withDefaults()
+	rows, err := newParquetRows(parquetFile, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.close()
 
 	// Process records in batches
-	return p.processBatches(ctx, func() ([]*DataRecord, error) {
+	return consume(func() ([]*DataRecord, error) {
 		var batch []*DataRecord
 
 		for len(batch) < p.config.BatchSize {
-			var record DataRecord
-			err := reader.Read(&record)
+			values, err := rows.next()
 			if err == io.EOF {
 				break
 			}
 			if err != nil {
-				p.logger.Warn("Failed to read Parquet record", zap.Error(err))
-				continue
+				return nil, fmt.Errorf("failed to read Parquet record: %w", err)
 			}
 
-			if p.validateRecord(&record) {
-				batch = append(batch, &record)
+			record := p.buildRecord(schema, values)
+			if p.validateRecord(record) {
+				batch = append(batch, record)
 			}
 		}
 
 		return batch, nil
-	}, result)
+	})
 }
 
-// processJSON processes JSON files (one JSON object per line)
-func (p *Pipeline) processJSON(ctx context.Context, filePath string, result *ProcessingResult) error {
+// readJSON reads JSON files (one JSON object per line), mapping keys through
+// the schema
+func (p *Pipeline) readJSON(filePath string, consume batchConsumer) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open JSON file: %w", err)
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
+	input, _, err := p.decrypter.Open(file)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(input)
+	decoder.UseNumber()
+	schema := p.config.Schema.withDefaults()
+	columns := schema.columns()
 
 	// Process records in batches
-	return p.processBatches(ctx, func() ([]*DataRecord, error) {
+	return consume(func() ([]*DataRecord, error) {
 		var batch []*DataRecord
 
 		for len(batch) < p.config.BatchSize {
-			var record DataRecord
-			err := decoder.Decode(&record)
+			var object map[string]any
+			err := decoder.Decode(&object)
 			if err == io.EOF {
 				break
 			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// The decoder cannot resynchronize after malformed JSON
+				return nil, fmt.Errorf("malformed JSON at byte %d: %w", syntaxErr.Offset, err)
+			}
 			if err != nil {
 				p.logger.Warn("Failed to read JSON record", zap.Error(err))
 				continue
 			}
 
-			if p.validateRecord(&record) {
-				batch = append(batch, &record)
+			values := make([]fieldValue, len(columns))
+			for i, column := range columns {
+				if column != "" {
+					values[i] = jsonFieldValue(lookupKey(object, column))
+				}
+			}
+
+			record := p.buildRecord(schema, values)
+			if p.validateRecord(record) {
+				batch = append(batch, record)
 			}
 		}
 
 		return batch, nil
-	}, result)
+	})
+}
+
+// SampleFile embeds up to n valid records from the start of filePath without
+// writing anything, for fitting and evaluating dimension reduction
+func (p *Pipeline) SampleFile(ctx context.Context, filePath string, n int) ([]*vector.SecurityVector, error) {
+	var sample []*vector.SecurityVector
+	err := p.readFile(ctx, filePath, func(readBatch func() ([]*DataRecord, error)) error {
+		for len(sample) < n {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			batch, err := readBatch()
+			if err != nil {
+				return fmt.Errorf("failed to read batch: %w", err)
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+			if remaining := n - len(sample); len(batch) > remaining {
+				batch = batch[:remaining]
+			}
+
+			vectors, err := p.embedRecords(ctx, batch, p.recordHash)
+			if err != nil {
+				return err
+			}
+			sample = append(sample, vectors...)
+		}
+		return nil
+	})
+	return sample, err
+}
+
+// hashedBatch is a batch read from the input with the text hash of each
+// record computed, on its way to the embedding workers
+type hashedBatch struct {
+	seq     int64 // read order, for checkpointing
+	end     int64 // record position just past the batch
+	records []*DataRecord
+	hashes  map[*DataRecord]string
 }
 
-// processBatches processes data in batches using the provided reader function
+// embeddedBatch is a batch whose embeddings are ready for the database writers
+type embeddedBatch struct {
+	seq     int64
+	end     int64
+	vectors []*vector.SecurityVector
+	records map[*vector.SecurityVector]*DataRecord // for rejects
+}
+
+// processBatches runs the pipeline over the provided reader. Reading and
+// hashing run on the calling goroutine; WorkerCount embedding workers and
+// WorkerCount database writers consume bounded channels, so embedding,
+// hashing and database writes for different batches overlap. The first
+// batch that fails outright stops the pipeline.
 func (p *Pipeline) processBatches(ctx context.Context, readBatch func() ([]*DataRecord, error), result *ProcessingResult) error {
-	p.logger.Info("Starting processBatches loop")
+	workers := p.config.WorkerCount
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex // guards result and firstErr
+		firstErr error
+	)
+	fail := func(err error, records int) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.ProcessedOK -= int64(records)
+		result.ProcessedFailed += int64(records)
+		if firstErr == nil {
+			result.Errors = append(result.Errors, err.Error())
+			firstErr = err
+			cancel()
+		}
+	}
+
+	toEmbed := make(chan hashedBatch, workers)
+	toWrite := make(chan embeddedBatch, workers)
+
+	var embedders, writers sync.WaitGroup
+	hashStats := &stageCounter{}
+	embedStats := &stageCounter{}
+	writeStats := &stageCounter{}
+	for i := 0; i < workers; i++ {
+		embedders.Add(1)
+		go func() {
+			defer embedders.Done()
+			for batch := range toEmbed {
+				if ctx.Err() != nil {
+					fail(ctx.Err(), len(batch.records))
+					continue
+				}
+				embedded, err := p.embedBatch(ctx, batch, result, &mu, embedStats)
+				if err != nil {
+					p.logger.Error("Batch embedding failed", zap.Error(err))
+					fail(err, len(batch.records))
+					continue
+				}
+				select {
+				case toWrite <- embedded:
+				case <-ctx.Done():
+				}
+			}
+		}()
+
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for batch := range toWrite {
+				if ctx.Err() != nil {
+					fail(ctx.Err(), len(batch.vectors))
+					continue
+				}
+				if err := p.writeBatch(ctx, batch, result, &mu, writeStats); err != nil {
+					p.logger.Error("Batch write failed", zap.Error(err))
+					fail(err, len(batch.vectors))
+					continue
+				}
+				p.tracker.finish(batch.seq, batch.end)
+			}
+		}()
+	}
+
+	readErr := p.readBatches(ctx, readBatch, toEmbed, result, &mu, hashStats)
+	close(toEmbed)
+	embedders.Wait()
+	close(toWrite)
+	writers.Wait()
+
+	result.Stages = []StageThroughput{
+		hashStats.throughput(StageHash, 1),
+		embedStats.throughput(StageEmbedding, workers),
+		writeStats.throughput(StageDatabase, workers),
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return readErr
+}
+
+// readBatches is the first stage: it reads batches, normalizes their labels,
+// hashes their text and hands them to the embedding workers. Records the
+// checkpoint covers are dropped unread by the later stages.
+func (p *Pipeline) readBatches(ctx context.Context, readBatch func() ([]*DataRecord, error), out chan<- hashedBatch, result *ProcessingResult, mu *sync.Mutex, stats *stageCounter) error {
+	var pos, seq int64
 	for {
 		// Check context cancellation
 		select {
@@ -260,107 +619,474 @@ func (p *Pipeline) processBatches(ctx context.Context, readBatch func() ([]*Data
 		default:
 		}
 
-		// Read next batch
-		p.logger.Info("Calling readBatch()")
 		batch, err := readBatch()
 		if err != nil {
 			return fmt.Errorf("failed to read batch: %w", err)
 		}
-		p.logger.Info("readBatch() completed", zap.Int("batch_size", len(batch)))
-
 		if len(batch) == 0 {
-			break // End of file
+			return nil // End of file
 		}
 
-		// Process batch
-		if err := p.processBatch(ctx, batch, result); err != nil {
-			p.logger.Error("Batch processing failed", zap.Error(err))
-			result.ProcessedFailed += int64(len(batch))
-			result.Errors = append(result.Errors, err.Error())
-			continue
+		start := pos
+		pos += int64(len(batch))
+		if start < p.resumeFrom {
+			skip := min(p.resumeFrom-start, int64(len(batch)))
+			batch = batch[skip:]
+			mu.Lock()
+			result.Resumed += skip
+			mu.Unlock()
+			if len(batch) == 0 {
+				continue
+			}
 		}
 
-		result.TotalRecords += int64(len(batch))
-		result.ProcessedOK += int64(len(batch))
+		mu.Lock()
+		read := int64(len(batch))
+		rejectedBefore := result.Rejected
+		batch = p.normalizeLabels(batch, result)
+		rejected := result.Rejected - rejectedBefore
+		result.TotalRecords += read
+		result.ProcessedOK += read - rejected
+		result.ProcessedFailed += rejected
+		mu.Unlock()
+
+		hashStart := time.Now()
+		hashes := make(map[*DataRecord]string, len(batch))
+		for _, record := range batch {
+			hashes[record] = p.textHash(record.Text)
+		}
+		hashTime := time.Since(hashStart)
+		stats.add(len(batch), hashTime)
+
+		if p.resume && len(batch) > 0 {
+			kept, err := p.skipIngested(ctx, batch, hashes)
+			if err != nil {
+				return err
+			}
+			skipped := int64(len(batch) - len(kept))
+			batch = kept
+			mu.Lock()
+			result.Duplicates += skipped
+			result.ProcessedOK -= skipped
+			mu.Unlock()
+		}
+
+		if p.nearDups != nil && p.nearDups.options.Method == NearDuplicateSimHash {
+			var dropped int64
+			batch, dropped = p.nearDups.dropSimilarText(batch)
+			mu.Lock()
+			result.NearDuplicates += dropped
+			result.ProcessedOK -= dropped
+			mu.Unlock()
+		}
+
+		mu.Lock()
+		result.HashTime += hashTime
+		total := result.TotalRecords
+		mu.Unlock()
 
 		// Progress reporting
-		if result.TotalRecords%int64(p.config.ProgressReport) == 0 {
+		if p.config.ProgressReport > 0 && total/int64(p.config.ProgressReport) != (total-read)/int64(p.config.ProgressReport) {
+			mu.Lock()
 			p.reportProgress(result)
+			mu.Unlock()
+		}
+
+		batchSeq := seq
+		seq++
+		if len(batch) == 0 {
+			p.tracker.finish(batchSeq, pos)
+			continue
+		}
+		select {
+		case out <- hashedBatch{seq: batchSeq, end: pos, records: batch, hashes: hashes}:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
+}
 
-	return nil
+// skipIngested drops records whose text_hash is already stored, so a resumed
+// run does not embed again what an interrupted one committed
+func (p *Pipeline) skipIngested(ctx context.Context, batch []*DataRecord, hashes map[*DataRecord]string) ([]*DataRecord, error) {
+	lookup := make([]string, len(batch))
+	for i, record := range batch {
+		lookup[i] = hashes[record]
+	}
+	existing, err := p.vectorStore.ExistingHashes(ctx, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for ingested records: %w", err)
+	}
+
+	kept := batch[:0]
+	for _, record := range batch {
+		if !existing[hashes[record]] {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
+
+// embedBatch generates embeddings for a batch. When embedding fails, the
+// batch is bisected until the failing records are isolated; those are
+// rejected and the rest continue to the writers. A failure that does not
+// narrow to single records stops the run.
+func (p *Pipeline) embedBatch(ctx context.Context, batch hashedBatch, result *ProcessingResult, mu *sync.Mutex, stats *stageCounter) (embeddedBatch, error) {
+	start := time.Now()
+	out := embeddedBatch{seq: batch.seq, end: batch.end, records: make(map[*vector.SecurityVector]*DataRecord, len(batch.records))}
+	hash := func(record *DataRecord) string { return batch.hashes[record] }
+
+	err := bisect(ctx, p.logger, RejectStageEmbedding, batch.records, func(records []*DataRecord) error {
+		embedded, err := p.embedRecords(ctx, records, hash)
+		if err != nil {
+			return err
+		}
+		for i, v := range embedded {
+			out.records[v] = records[i]
+		}
+		out.vectors = append(out.vectors, embedded...)
+		return nil
+	}, func(record *DataRecord, err error) {
+		p.rejectRecord(record, RejectStageEmbedding, err, result, mu)
+	})
+	if err != nil {
+		return out, err
+	}
+
+	if p.nearDups != nil && p.nearDups.options.Method == NearDuplicateEmbedding {
+		kept, err := p.nearDups.dropSimilarEmbeddings(ctx, p.vectorStore, out.vectors)
+		if err != nil {
+			return out, err
+		}
+		dropped := int64(len(out.vectors) - len(kept))
+		out.vectors = kept
+		mu.Lock()
+		result.NearDuplicates += dropped
+		result.ProcessedOK -= dropped
+		mu.Unlock()
+	}
+
+	elapsed := time.Since(start)
+	stats.add(len(batch.records), elapsed)
+	mu.Lock()
+	result.EmbeddingTime += elapsed
+	mu.Unlock()
+	p.logger.Debug("Embedding generation completed", zap.Duration("duration", elapsed), zap.Int("embeddings_count", len(out.vectors)))
+	return out, nil
 }
 
-// processBatch processes a single batch of records
-func (p *Pipeline) processBatch(ctx context.Context, batch []*DataRecord, result *ProcessingResult) error {
-	if len(batch) == 0 {
+// writeBatch stores a batch of vectors and refreshes the cache. The insert is
+// a single statement (or, with COPY, a single transaction), so each batch
+// commits or rolls back as a unit. When inserting fails, the batch is
+// bisected until the failing records are isolated; those are rejected and
+// everything else commits. A lost connection, or a failure that does not
+// narrow to single records, stops the run.
+func (p *Pipeline) writeBatch(ctx context.Context, batch embeddedBatch, result *ProcessingResult, mu *sync.Mutex, stats *stageCounter) error {
+	if len(batch.vectors) == 0 {
 		return nil
 	}
 
-	p.logger.Info("Processing batch", zap.Int("batch_size", len(batch)))
+	// Concurrent writers take row locks in text_hash order so overlapping
+	// batches wait on each other instead of deadlocking
+	sort.Slice(batch.vectors, func(i, j int) bool {
+		return batch.vectors[i].TextHash < batch.vectors[j].TextHash
+	})
 
-	// Extract texts for batch embedding generation
-	texts := make([]string, len(batch))
-	for i, record := range batch {
+	dbStart := time.Now()
+	var stored []*vector.SecurityVector
+	var inserted, failed int64
+	err := bisect(ctx, p.logger, RejectStageInsert, batch.vectors, func(chunk []*vector.SecurityVector) error {
+		batchResult, err := p.insert(ctx, chunk)
+		if err != nil {
+			return fmt.Errorf("database batch insert failed: %w", err)
+		}
+		stored = append(stored, chunk...)
+		inserted += batchResult.Inserted
+		failed += batchResult.Failed
+		return nil
+	}, func(v *vector.SecurityVector, err error) {
+		record, ok := batch.records[v]
+		if !ok {
+			record = &DataRecord{Text: v.Text, LabelText: v.LabelText, Label: v.Label}
+		}
+		p.rejectRecord(record, RejectStageInsert, err, result, mu)
+	})
+	if err != nil {
+		return err
+	}
+	dbTime := time.Since(dbStart)
+	stats.add(len(batch.vectors), dbTime)
+
+	// Update cache with high-confidence malicious vectors
+	var cacheTime time.Duration
+	if p.config.UpdateCache && p.vectorCache != nil && len(stored) > 0 {
+		cacheStart := time.Now()
+		embeddings := make([][]float32, len(stored))
+		for i, v := range stored {
+			embeddings[i] = v.Embedding
+		}
+		p.updateCache(ctx, stored, embeddings)
+		cacheTime = time.Since(cacheStart)
+	}
+
+	mu.Lock()
+	result.DatabaseTime += dbTime
+	result.CacheTime += cacheTime
+	mu.Unlock()
+
+	p.logger.Debug("Batch written",
+		zap.Int("batch_size", len(batch.vectors)),
+		zap.Int64("inserted", inserted),
+		zap.Int64("failed", failed),
+		zap.Duration("database_time", dbTime))
+
+	return nil
+}
+
+// rejectRecord is reject for stages running concurrently with others
+func (p *Pipeline) rejectRecord(record *DataRecord, stage string, cause error, result *ProcessingResult, mu *sync.Mutex) {
+	mu.Lock()
+	defer mu.Unlock()
+	p.reject(record, stage, cause, result)
+	result.ProcessedOK--
+	result.ProcessedFailed++
+}
+
+// stageCounter accumulates the records handled and time spent by one stage's
+// workers
+type stageCounter struct {
+	mu      sync.Mutex
+	records int64
+	busy    time.Duration
+}
+
+func (c *stageCounter) add(records int, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records += int64(records)
+	c.busy += elapsed
+}
+
+// throughput summarizes the stage. Busy time is summed across workers, so
+// records per second is per worker times the worker count.
+func (c *stageCounter) throughput(stage string, workers int) StageThroughput {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := StageThroughput{Stage: stage, Workers: workers, Records: c.records, Busy: c.busy}
+	if c.busy > 0 {
+		t.RecordsPerSecond = float64(c.records) / c.busy.Seconds() * float64(workers)
+	}
+	return t
+}
+
+// sourceName names the dataset at filePath for its vectors: the file name,
+// or the dataset URI without its query for HuggingFace inputs
+func sourceName(filePath string) string {
+	if IsHuggingFaceURI(filePath) {
+		uri, _, _ := strings.Cut(filePath, "?")
+		return uri
+	}
+	return filepath.Base(artifact.TrimExtension(filePath))
+}
+
+// shouldCopy reports whether filePath is large enough to load with COPY
+func (p *Pipeline) shouldCopy(filePath string) bool {
+	if p.config.CopyThreshold < 0 {
+		return false
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	if info.Size() < p.config.CopyThreshold {
+		return false
+	}
+	p.logger.Info("Loading with COPY",
+		zap.Int64("file_size", info.Size()),
+		zap.Int64("copy_threshold", p.config.CopyThreshold))
+	return true
+}
+
+// insert writes a chunk of vectors with COPY for large inputs and a
+// multi-row INSERT otherwise
+func (p *Pipeline) insert(ctx context.Context, vectors []*vector.SecurityVector) (*vector.BatchInsertResult, error) {
+	if p.useCopy {
+		return p.vectorStore.CopyInsert(ctx, vectors)
+	}
+	return p.vectorStore.BatchInsert(ctx, vectors)
+}
+
+// embedRecords generates embeddings for records and builds their vectors,
+// taking each text_hash from hash
+func (p *Pipeline) embedRecords(ctx context.Context, records []*DataRecord, hash func(*DataRecord) string) ([]*vector.SecurityVector, error) {
+	texts := make([]string, len(records))
+	for i, record := range records {
 		texts[i] = record.Text
 	}
 
-	// Generate embeddings for the batch
-	p.logger.Info("Starting embedding generation", zap.Int("text_count", len(texts)))
-	embeddingStart := time.Now()
 	embeddingResult, err := p.embeddingService.GenerateBatchEmbeddings(ctx, texts)
 	if err != nil {
-		return fmt.Errorf("batch embedding generation failed: %w", err)
+		return nil, fmt.Errorf("batch embedding generation failed: %w", err)
 	}
-	result.EmbeddingTime += time.Since(embeddingStart)
-	p.logger.Info("Embedding generation completed", zap.Duration("duration", time.Since(embeddingStart)), zap.Int("embeddings_count", len(embeddingResult.Embeddings)))
-
-	if len(embeddingResult.Embeddings) != len(batch) {
-		return fmt.Errorf("embedding count mismatch: got %d, expected %d",
-			len(embeddingResult.Embeddings), len(batch))
+	if len(embeddingResult.Embeddings) != len(records) {
+		return nil, fmt.Errorf("embedding count mismatch: got %d, expected %d",
+			len(embeddingResult.Embeddings), len(records))
 	}
 
-	// Create security vectors
-	vectors := make([]*vector.SecurityVector, len(batch))
-	for i, record := range batch {
+	vectors := make([]*vector.SecurityVector, len(records))
+	for i, record := range records {
+		// Schema-mapped source and language columns win over the file name
+		// and detection
+		source, language := record.Source, record.Language
+		if source == "" {
+			source = p.source
+		}
+		if language == "" {
+			language = DetectLanguage(record.Text)
+		}
 		vectors[i] = &vector.SecurityVector{
 			Text:          record.Text,
 			EmbeddingType: embeddingResult.ServiceType,
-			TextHash:      computeTextHash(record.Text),
+			TextHash:      hash(record),
 			LabelText:     record.LabelText,
 			Label:         record.Label,
 			Embedding:     embeddingResult.Embeddings[i],
+			Source:        source,
+			Language:      language,
+			Metadata:      record.Metadata,
 		}
 	}
+	return vectors, nil
+}
 
-	// Store in database
-	p.logger.Info("Starting database batch insert", zap.Int("vectors_count", len(vectors)))
-	dbStart := time.Now()
-	batchResult, err := p.vectorStore.BatchInsert(ctx, vectors)
-	if err != nil {
-		return fmt.Errorf("database batch insert failed: %w", err)
+// bisect runs fn over items, halving on failure to isolate poison records.
+// A failing record is only rejected when the other half of its batch went
+// through; when both halves fail the cause is systemic, so bisecting stops
+// and the error is returned. Context cancellation and connection errors are
+// returned without bisecting at all.
+func bisect[T any](ctx context.Context, logger *zap.Logger, stage string, items []T, fn func([]T) error, reject func(T, error)) error {
+	err := bisectHalves(ctx, logger, stage, items, fn, reject)
+	var failed *recordFailure
+	if errors.As(err, &failed) {
+		// A batch of one has no half that succeeded to vouch for the rest
+		return failed.err
 	}
-	result.DatabaseTime += time.Since(dbStart)
-	p.logger.Info("Database batch insert completed", zap.Duration("duration", time.Since(dbStart)), zap.Int64("inserted", batchResult.Inserted))
+	return err
+}
 
-	// Update cache with high-confidence malicious vectors
-	if p.config.UpdateCache && p.vectorCache != nil {
-		cacheStart := time.Now()
-		p.updateCache(ctx, vectors, embeddingResult.Embeddings)
-		result.CacheTime += time.Since(cacheStart)
+// recordFailure is a single record that failed on its own; its caller
+// decides whether to reject it
+type recordFailure struct {
+	err error
+}
+
+func (f *recordFailure) Error() string { return f.err.Error() }
+
+func bisectHalves[T any](ctx context.Context, logger *zap.Logger, stage string, items []T, fn func([]T) error, reject func(T, error)) error {
+	err := fn(items)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if isConnectionError(err) {
+		return err
+	}
+	if len(items) == 1 {
+		return &recordFailure{err: err}
 	}
 
-	p.logger.Debug("Batch processed successfully",
-		zap.Int("batch_size", len(batch)),
-		zap.Int64("inserted", batchResult.Inserted),
-		zap.Int64("failed", batchResult.Failed),
-		zap.Duration("embedding_time", time.Since(embeddingStart)),
-		zap.Duration("database_time", time.Since(dbStart)))
+	logger.Warn("Batch failed; bisecting to isolate poison records",
+		zap.String("stage", stage),
+		zap.Int("records", len(items)),
+		zap.Error(err))
 
+	mid := len(items) / 2
+	left := bisectHalves(ctx, logger, stage, items[:mid], fn, reject)
+	var leftFailed *recordFailure
+	if left != nil && !errors.As(left, &leftFailed) {
+		return left
+	}
+	right := bisectHalves(ctx, logger, stage, items[mid:], fn, reject)
+	var rightFailed *recordFailure
+	if right != nil && !errors.As(right, &rightFailed) {
+		return right
+	}
+
+	if leftFailed != nil && rightFailed != nil {
+		return fmt.Errorf("both halves of a %d-record batch failed, not isolating records: %w", len(items), err)
+	}
+	if leftFailed != nil {
+		reject(items[0], leftFailed.err)
+	}
+	if rightFailed != nil {
+		reject(items[mid], rightFailed.err)
+	}
 	return nil
 }
 
+// isConnectionError reports whether err came from losing the database or
+// embedding backend rather than from the records being written
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &netErr),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.As(err, &pqErr):
+		// Class 08 is connection exceptions; 57P0x is the server shutting down
+		return pqErr.Code.Class() == "08" || strings.HasPrefix(string(pqErr.Code), "57P0")
+	}
+	return false
+}
+
+// reject counts a record that could not be ingested and routes it to the
+// rejects file if one is configured
+func (p *Pipeline) reject(record *DataRecord, stage string, cause error, result *ProcessingResult) {
+	result.Rejected++
+
+	p.logger.Warn("Record rejected",
+		zap.String("stage", stage),
+		zap.String("text_hash", p.textHash(record.Text)),
+		zap.Error(cause))
+
+	if p.rejects != nil {
+		if err := p.rejects.Write(record, stage, cause); err != nil {
+			p.logger.Error("Failed to write rejected record", zap.Error(err))
+		}
+	}
+}
+
+// normalizeLabels maps each record's label_text onto the configured taxonomy,
+// counting unmapped labels and dropping their records if configured to
+func (p *Pipeline) normalizeLabels(batch []*DataRecord, result *ProcessingResult) []*DataRecord {
+	kept := batch[:0]
+	for _, record := range batch {
+		category, mapped := p.labels.Normalize(record.LabelText)
+		if !mapped {
+			if result.UnmappedLabels == nil {
+				result.UnmappedLabels = make(map[string]int64)
+			}
+			result.UnmappedLabels[category]++
+
+			if p.config.RejectUnmappedLabels {
+				p.reject(record, RejectStageLabel, fmt.Errorf("unmapped label: %s", category), result)
+				continue
+			}
+		}
+		record.LabelText = category
+		kept = append(kept, record)
+	}
+	return kept
+}
+
 // updateCache updates the Redis cache with high-priority vectors
 func (p *Pipeline) updateCache(ctx context.Context, vectors []*vector.SecurityVector, embeddings [][]float32) {
 	var cacheVectors []*cache.CachedVector
@@ -458,8 +1184,13 @@ func (p *Pipeline) GetStats() *ProcessingStats {
 	return &stats
 }
 
-// computeTextHash computes SHA-256 hash of the given text
-func computeTextHash(text string) string {
-	hash := sha256.Sum256([]byte(text))
-	return hex.EncodeToString(hash[:])
+// recordHash is textHash for a record
+func (p *Pipeline) recordHash(record *DataRecord) string {
+	return p.textHash(record.Text)
+}
+
+// textHash computes the SHA-256 of the canonical text, the key the store
+// deduplicates on
+func (p *Pipeline) textHash(text string) string {
+	return textnorm.Hash(text, p.config.TextHash)
 }
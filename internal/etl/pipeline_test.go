@@ -0,0 +1,74 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestBisect checks poison records are only rejected next to records that
+// went through, and that systemic failures stop the run
+func TestBisect(t *testing.T) {
+	poison := errors.New("invalid byte sequence")
+	failOn := func(bad ...int) func([]int) error {
+		return func(items []int) error {
+			for _, item := range items {
+				if slices.Contains(bad, item) {
+					return poison
+				}
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name     string
+		items    []int
+		fn       func([]int) error
+		rejected []int
+		wantErr  bool
+	}{
+		{"clean batch", []int{1, 2, 3, 4}, failOn(), nil, false},
+		{"isolated poison", []int{1, 2, 3, 4, 5}, failOn(4), []int{4}, false},
+		{"two isolated records", []int{1, 2, 3, 4, 5, 6, 7, 8}, failOn(1, 8), []int{1, 8}, false},
+		{"both halves fail", []int{1, 2, 3, 4}, failOn(1, 2, 3, 4), nil, true},
+		{"lone record", []int{1}, failOn(1), nil, true},
+		{"connection lost", []int{1, 2, 3, 4}, func([]int) error {
+			return fmt.Errorf("insert: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+		}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rejected []int
+			err := bisect(context.Background(), zap.NewNop(), "test", tt.items, tt.fn, func(item int, _ error) {
+				rejected = append(rejected, item)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bisect error = %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(rejected, tt.rejected) {
+				t.Fatalf("rejected %v, want %v", rejected, tt.rejected)
+			}
+		})
+	}
+}
+
+// TestBisectCancelled checks cancellation is returned, not blamed on records
+func TestBisectCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := bisect(ctx, zap.NewNop(), "test", []int{1, 2, 3, 4}, func([]int) error {
+		calls++
+		return errors.New("canceled")
+	}, func(int, error) { t.Fatal("record rejected after cancellation") })
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("bisect = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
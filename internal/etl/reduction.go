@@ -0,0 +1,117 @@
+package etl
+
+import (
+	"math"
+	"sort"
+
+	"github.com/raaihank/llm-sentinel/internal/vector"
+)
+
+// ReductionReport compares nearest-neighbour search over full and reduced
+// embeddings of the same sample
+type ReductionReport struct {
+	Samples          int     `json:"samples"`
+	FullDimensions   int     `json:"full_dimensions"`
+	ReducedDims      int     `json:"reduced_dimensions"`
+	K                int     `json:"k"`
+	RecallAtK        float64 `json:"recall_at_k"`      // share of full-space neighbours also found in reduced space
+	FullAccuracy     float64 `json:"full_accuracy"`    // leave-one-out kNN label accuracy
+	ReducedAccuracy  float64 `json:"reduced_accuracy"` // the same, after reduction
+	StorageReduction float64 `json:"storage_reduction"`
+}
+
+// EvaluateReduction measures what reducer costs in search quality: how many
+// of each sample's k nearest neighbours survive reduction, and how well a
+// k-nearest-neighbour vote predicts labels before and after
+func EvaluateReduction(embeddings [][]float32, labels []int, reducer vector.Reducer, k int) ReductionReport {
+	report := ReductionReport{Samples: len(embeddings), K: k}
+	if len(embeddings) <= k || k <= 0 {
+		return report
+	}
+
+	reduced := make([][]float32, len(embeddings))
+	for i, embedding := range embeddings {
+		reduced[i] = reducer.Reduce(embedding)
+	}
+	report.FullDimensions = len(embeddings[0])
+	report.ReducedDims = reducer.Dimensions()
+	report.StorageReduction = 1 - float64(report.ReducedDims)/float64(report.FullDimensions)
+
+	var overlap, fullCorrect, reducedCorrect int
+	for i := range embeddings {
+		fullNeighbours := nearest(embeddings, i, k)
+		reducedNeighbours := nearest(reduced, i, k)
+
+		inFull := make(map[int]bool, k)
+		for _, j := range fullNeighbours {
+			inFull[j] = true
+		}
+		for _, j := range reducedNeighbours {
+			if inFull[j] {
+				overlap++
+			}
+		}
+
+		if vote(labels, fullNeighbours) == labels[i] {
+			fullCorrect++
+		}
+		if vote(labels, reducedNeighbours) == labels[i] {
+			reducedCorrect++
+		}
+	}
+
+	n := float64(len(embeddings))
+	report.RecallAtK = float64(overlap) / (n * float64(k))
+	report.FullAccuracy = float64(fullCorrect) / n
+	report.ReducedAccuracy = float64(reducedCorrect) / n
+	return report
+}
+
+// nearest returns the indexes of the k most cosine-similar embeddings to
+// embeddings[i], excluding i itself
+func nearest(embeddings [][]float32, i, k int) []int {
+	type scored struct {
+		index      int
+		similarity float64
+	}
+	candidates := make([]scored, 0, len(embeddings)-1)
+	for j, other := range embeddings {
+		if j != i {
+			candidates = append(candidates, scored{j, cosine(embeddings[i], other)})
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].similarity > candidates[b].similarity
+	})
+
+	indexes := make([]int, k)
+	for n := range indexes {
+		indexes[n] = candidates[n].index
+	}
+	return indexes
+}
+
+// vote returns the majority label among neighbours, preferring malicious on ties
+func vote(labels []int, neighbours []int) int {
+	var malicious int
+	for _, j := range neighbours {
+		malicious += labels[j]
+	}
+	if 2*malicious >= len(neighbours) {
+		return 1
+	}
+	return 0
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,98 @@
+package etl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/artifact"
+)
+
+// Stages at which a record can be rejected
+const (
+	RejectStageLabel     = "label"
+	RejectStageEmbedding = "embedding"
+	RejectStageInsert    = "insert"
+)
+
+// Reject is one record that could not be ingested, as written to the rejects file
+type Reject struct {
+	DataRecord
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// RejectWriter appends rejected records to a JSON lines file so they can be
+// fixed and re-ingested
+type RejectWriter struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	out     io.WriteCloser
+	encoder *json.Encoder
+}
+
+// NewRejectWriter opens path for appending, creating it and its directory
+// if needed. Encrypted streams cannot be appended to, so with an encrypter
+// each run writes a new file named with the run's UTC time and an .age
+// extension, and never overwrites an existing one.
+func NewRejectWriter(path string, encrypter *artifact.Encrypter) (*RejectWriter, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if encrypter != nil {
+		path = encrypter.Path(timestampedPath(path, time.Now()))
+		flags = os.O_CREATE | os.O_WRONLY | os.O_EXCL
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create rejects directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rejects file: %w", err)
+	}
+
+	out, err := encrypter.Wrap(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to encrypt rejects file: %w", err)
+	}
+	return &RejectWriter{path: path, file: file, out: out, encoder: json.NewEncoder(out)}, nil
+}
+
+// timestampedPath inserts at's UTC time before path's extension, turning
+// rejects.json into rejects-20060102T150405Z.json
+func timestampedPath(path string, at time.Time) string {
+	path = strings.TrimSuffix(path, artifact.Extension)
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + at.UTC().Format("20060102T150405Z") + ext
+}
+
+// Path returns the file rejects are written to
+func (w *RejectWriter) Path() string {
+	return w.path
+}
+
+// Write records a rejected record with the stage and error that rejected it
+func (w *RejectWriter) Write(record *DataRecord, stage string, cause error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.encoder.Encode(Reject{DataRecord: *record, Stage: stage, Error: cause.Error()})
+}
+
+// Close flushes and closes the rejects file
+func (w *RejectWriter) Close() error {
+	if err := w.out.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,68 @@
+package etl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/raaihank/llm-sentinel/internal/artifact"
+)
+
+// TestEncryptedRejectsNeverOverwrite checks each encrypted run writes its own
+// timestamped file and leaves earlier rejects in place
+func TestEncryptedRejectsNeverOverwrite(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypter, err := artifact.NewEncrypter([]string{identity.Recipient().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	earlier := filepath.Join(dir, "rejects.json.age")
+	if err := os.WriteFile(earlier, []byte("earlier run"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewRejectWriter(filepath.Join(dir, "rejects.json"), encrypter)
+	if err != nil {
+		t.Fatalf("NewRejectWriter: %v", err)
+	}
+	if err := w.Write(&DataRecord{Text: "poison"}, RejectStageInsert, errors.New("bad row")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Base(w.Path())
+	if !strings.HasPrefix(name, "rejects-") || !strings.HasSuffix(name, ".json.age") {
+		t.Fatalf("rejects written to %s, want a timestamped rejects-<time>.json.age", name)
+	}
+	if data, err := os.ReadFile(earlier); err != nil || string(data) != "earlier run" {
+		t.Fatalf("earlier rejects file changed: %q, %v", data, err)
+	}
+
+}
+
+// TestTimestampedPath checks the run time goes before the extension
+func TestTimestampedPath(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := map[string]string{
+		"data/rejects.json":     "data/rejects-20240506T070809Z.json",
+		"data/rejects.json.age": "data/rejects-20240506T070809Z.json",
+		"rejects":               "rejects-20240506T070809Z",
+	}
+	for path, want := range tests {
+		if got := timestampedPath(path, at); got != want {
+			t.Errorf("timestampedPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package etl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schema maps a dataset's columns onto record fields. A column is named by
+// its header, key or Parquet column, or for CSV files by its 0-based
+// position. Text is required, and so is one of Label and LabelText: a
+// missing label is derived from label_text and a missing label_text from
+// the label. Configured columns a file does not have are treated as empty.
+type Schema struct {
+	Text      string
+	LabelText string
+	Label     string   // 0/1, true/false or yes/no
+	Source    string   // optional; overrides the input file name
+	Language  string   // optional; overrides language detection
+	Metadata  []string // extra columns stored with each vector
+
+	NoHeader bool // CSV files start with data; columns must be positions
+
+	// label_text for rows with a label but no label_text
+	MaliciousLabel string
+	SafeLabel      string
+}
+
+// DefaultSchema reads text, label_text and label columns, the layout of
+// the sample datasets and of exports
+func DefaultSchema() Schema {
+	return Schema{
+		Text:           "text",
+		LabelText:      "label_text",
+		Label:          "label",
+		MaliciousLabel: "prompt_injection",
+		SafeLabel:      "safe",
+	}
+}
+
+// withDefaults fills columns and labels left empty from DefaultSchema
+func (s Schema) withDefaults() Schema {
+	d := DefaultSchema()
+	if s.Text == "" {
+		s.Text = d.Text
+	}
+	if s.LabelText == "" && s.Label == "" {
+		s.LabelText, s.Label = d.LabelText, d.Label
+	}
+	if s.MaliciousLabel == "" {
+		s.MaliciousLabel = d.MaliciousLabel
+	}
+	if s.SafeLabel == "" {
+		s.SafeLabel = d.SafeLabel
+	}
+	return s
+}
+
+// schemaKeys are the fields ParseSchema and hf:// query parameters accept
+var schemaKeys = []string{"text", "label_text", "label", "source", "language", "metadata", "header"}
+
+// set assigns one schema field by key. metadata appends a column.
+func (s *Schema) set(key, value string) error {
+	value = strings.TrimSpace(value)
+	switch key {
+	case "text":
+		s.Text = value
+	case "label_text":
+		s.LabelText = value
+	case "label":
+		s.Label = value
+	case "source":
+		s.Source = value
+	case "language":
+		s.Language = value
+	case "metadata":
+		if value != "" {
+			s.Metadata = append(s.Metadata, value)
+		}
+	case "header":
+		header, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid header value %q (must be true or false)", value)
+		}
+		s.NoHeader = !header
+	default:
+		return fmt.Errorf("unknown schema field %q (use %s)", key, strings.Join(schemaKeys, ", "))
+	}
+	return nil
+}
+
+// ParseSchema applies a comma-separated list of field=column pairs, such as
+// "text=prompt,label=2,metadata=lang,metadata=split", over base
+func ParseSchema(spec string, base Schema) (Schema, error) {
+	schema := base
+	schema.Metadata = append([]string(nil), base.Metadata...)
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return base, fmt.Errorf("invalid schema entry %q (want field=column)", pair)
+		}
+		if err := schema.set(strings.TrimSpace(key), value); err != nil {
+			return base, err
+		}
+	}
+	return schema, nil
+}
+
+// Positions of the fixed fields in columns and in the rows readers build;
+// metadata columns follow
+const (
+	fieldText = iota
+	fieldLabelText
+	fieldLabel
+	fieldSource
+	fieldLanguage
+	fieldMetadata
+)
+
+// columns lists the schema's column references in field order. Optional
+// fields that are not mapped are empty.
+func (s Schema) columns() []string {
+	return append([]string{s.Text, s.LabelText, s.Label, s.Source, s.Language}, s.Metadata...)
+}
+
+// isDefaultLayout reports whether the schema reads the text, label_text and
+// label columns of DefaultSchema and nothing else
+func (s Schema) isDefaultLayout() bool {
+	d := DefaultSchema()
+	return s.Text == d.Text && s.LabelText == d.LabelText && s.Label == d.Label &&
+		s.Source == "" && s.Language == "" && len(s.Metadata) == 0
+}
+
+// defaultPositions reads the DefaultSchema layout by position: text,
+// label_text, then label
+func (s Schema) defaultPositions() []int {
+	return []int{fieldText: 0, fieldLabelText: 1, fieldLabel: 2, fieldSource: -1, fieldLanguage: -1}
+}
+
+// fieldValue is one field of a row; ok is false when the column is absent
+// or null
+type fieldValue struct {
+	value string
+	ok    bool
+}
+
+// resolve finds each schema column among a file's column names, falling
+// back to a 0-based position for numeric references when positional is
+// set. Unresolved columns are -1. The text column and a label column must
+// resolve.
+func (s Schema) resolve(names []string, positional bool) ([]int, error) {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, dup := index[key]; !dup {
+			index[key] = i
+		}
+	}
+
+	columns := s.columns()
+	positions := make([]int, len(columns))
+	for i, column := range columns {
+		positions[i] = -1
+		if column == "" {
+			continue
+		}
+		if pos, ok := index[strings.ToLower(column)]; ok {
+			positions[i] = pos
+		} else if n, err := strconv.Atoi(column); err == nil && positional && n >= 0 {
+			positions[i] = n
+		}
+	}
+
+	if positions[fieldText] < 0 {
+		return nil, fmt.Errorf("no text column %q (columns: %s)", s.Text, strings.Join(names, ", "))
+	}
+	if positions[fieldLabelText] < 0 && positions[fieldLabel] < 0 {
+		return nil, fmt.Errorf("no label column %q or label_text column %q (columns: %s)", s.Label, s.LabelText, strings.Join(names, ", "))
+	}
+	return positions, nil
+}
+
+// buildRecord assembles a record from a row in field order, deriving a
+// missing label or label_text. Unparseable labels become -1 so validation
+// rejects the record.
+func (p *Pipeline) buildRecord(schema Schema, row []fieldValue) *DataRecord {
+	field := func(i int) string {
+		if i < len(row) && row[i].ok {
+			return strings.TrimSpace(row[i].value)
+		}
+		return ""
+	}
+
+	record := &DataRecord{
+		Text:      field(fieldText),
+		LabelText: field(fieldLabelText),
+		Source:    field(fieldSource),
+		Language:  field(fieldLanguage),
+	}
+
+	label, hasLabel := parseLabel(field(fieldLabel))
+	if label < 0 && record.LabelText == "" {
+		// A label column holding class names, such as "jailbreak" or "benign"
+		record.LabelText = field(fieldLabel)
+		hasLabel = false
+	}
+	switch {
+	case hasLabel:
+		record.Label = label
+	case record.LabelText != "":
+		record.Label = p.labelFor(record.LabelText, schema.SafeLabel)
+	default:
+		record.Label = -1
+	}
+	if record.LabelText == "" {
+		switch record.Label {
+		case 1:
+			record.LabelText = schema.MaliciousLabel
+		case 0:
+			record.LabelText = schema.SafeLabel
+		}
+	}
+
+	for i, column := range schema.Metadata {
+		if value := field(fieldMetadata + i); value != "" {
+			if record.Metadata == nil {
+				record.Metadata = make(map[string]string, len(schema.Metadata))
+			}
+			record.Metadata[column] = value
+		}
+	}
+	return record
+}
+
+// labelFor derives the 0/1 label of a record that only has a label_text:
+// safe when it maps to the same category as safeLabel, malicious otherwise
+func (p *Pipeline) labelFor(labelText, safeLabel string) int {
+	category, _ := p.labels.Normalize(labelText)
+	safe, _ := p.labels.Normalize(safeLabel)
+	if NormalizeLabel(category) == NormalizeLabel(safe) {
+		return 0
+	}
+	return 1
+}
+
+// parseLabel reads a 0/1 label written as a number, boolean or yes/no.
+// ok is false for an empty value; any other value is -1.
+func parseLabel(value string) (label int, ok bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, false
+	}
+	switch value {
+	case "1", "1.0", "true", "t", "yes", "y":
+		return 1, true
+	case "0", "0.0", "false", "f", "no", "n":
+		return 0, true
+	}
+	return -1, true
+}
+
+// lookupKey returns object's value for key, matching case-insensitively
+// when there is no exact match
+func lookupKey(object map[string]any, key string) any {
+	if v, ok := object[key]; ok {
+		return v
+	}
+	for k, v := range object {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// jsonFieldValue converts a decoded JSON value to a field. Nested objects
+// and arrays keep their JSON form.
+func jsonFieldValue(v any) fieldValue {
+	switch v := v.(type) {
+	case nil:
+		return fieldValue{}
+	case string:
+		return fieldValue{v, true}
+	case json.Number:
+		return fieldValue{v.String(), true}
+	case bool:
+		return fieldValue{strconv.FormatBool(v), true}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fieldValue{}
+		}
+		return fieldValue{string(data), true}
+	}
+}
@@ -2,6 +2,9 @@ package etl
 
 import (
 	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/artifact"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 )
 
 // DataRecord represents a single record from the input dataset
@@ -9,6 +12,12 @@ type DataRecord struct {
 	Text      string `csv:"text" parquet:"text" json:"text"`
 	LabelText string `csv:"label_text" parquet:"label_text" json:"label_text"`
 	Label     int    `csv:"label" parquet:"label" json:"label"`
+
+	// Optional fields mapped by the Schema; Source and Language default to
+	// the input file name and the detected language
+	Source   string            `csv:"-" parquet:"-" json:"source,omitempty"`
+	Language string            `csv:"-" parquet:"-" json:"language,omitempty"`
+	Metadata map[string]string `csv:"-" parquet:"-" json:"metadata,omitempty"`
 }
 
 // ProcessingResult represents the result of processing a dataset
@@ -16,18 +25,46 @@ type ProcessingResult struct {
 	TotalRecords    int64         `json:"total_records"`
 	ProcessedOK     int64         `json:"processed_ok"`
 	ProcessedFailed int64         `json:"processed_failed"`
-	Duplicates      int64         `json:"duplicates"`
+	Rejected        int64         `json:"rejected"`        // poison records isolated from their batch
+	Duplicates      int64         `json:"duplicates"`      // already stored, skipped when resuming
+	NearDuplicates  int64         `json:"near_duplicates"` // dropped by the near-duplicate filter
+	Resumed         int64         `json:"resumed"`         // before the checkpoint, skipped unread
 	Duration        time.Duration `json:"duration"`
 	EmbeddingTime   time.Duration `json:"embedding_time"`
 	DatabaseTime    time.Duration `json:"database_time"`
 	CacheTime       time.Duration `json:"cache_time"`
+	HashTime        time.Duration `json:"hash_time"`
 	Errors          []string      `json:"errors,omitempty"`
+
+	// Records handled and time spent by each pipeline stage
+	Stages []StageThroughput `json:"stages,omitempty"`
+
+	// Labels with no taxonomy mapping and how many records carried each
+	UnmappedLabels map[string]int64 `json:"unmapped_labels,omitempty"`
+}
+
+// Pipeline stages reported in ProcessingResult.Stages
+const (
+	StageHash      = "hash"
+	StageEmbedding = "embedding"
+	StageDatabase  = "database"
+)
+
+// StageThroughput is the work done by one pipeline stage. Busy is summed
+// across the stage's workers; RecordsPerSecond is the rate the stage
+// sustains with all of them, so the slowest stage bounds the pipeline.
+type StageThroughput struct {
+	Stage            string        `json:"stage"`
+	Workers          int           `json:"workers"`
+	Records          int64         `json:"records"`
+	Busy             time.Duration `json:"busy"`
+	RecordsPerSecond float64       `json:"records_per_second"`
 }
 
 // Config contains ETL pipeline configuration
 type Config struct {
 	BatchSize      int           `yaml:"batch_size" mapstructure:"batch_size"`           // 1000
-	WorkerCount    int           `yaml:"worker_count" mapstructure:"worker_count"`       // 4
+	WorkerCount    int           `yaml:"worker_count" mapstructure:"worker_count"`       // 4 embedding workers and 4 database writers
 	MaxRetries     int           `yaml:"max_retries" mapstructure:"max_retries"`         // 3
 	RetryDelay     time.Duration `yaml:"retry_delay" mapstructure:"retry_delay"`         // 5s
 	SkipDuplicates bool          `yaml:"skip_duplicates" mapstructure:"skip_duplicates"` // true
@@ -35,6 +72,17 @@ type Config struct {
 	CreateIndex    bool          `yaml:"create_index" mapstructure:"create_index"`       // true
 	UpdateCache    bool          `yaml:"update_cache" mapstructure:"update_cache"`       // true
 	ProgressReport int           `yaml:"progress_report" mapstructure:"progress_report"` // 1000
+
+	LabelMapping         map[string]string `yaml:"label_mapping" mapstructure:"label_mapping"`                   // label_text variant -> category
+	RejectUnmappedLabels bool              `yaml:"reject_unmapped_labels" mapstructure:"reject_unmapped_labels"` // false
+
+	TextHash textnorm.Options `yaml:"-" mapstructure:"-"` // canonicalization applied before computing text_hash
+
+	CopyThreshold int64 `yaml:"copy_threshold" mapstructure:"copy_threshold"` // input bytes at which batches load via COPY; negative disables
+
+	Schema         Schema               `yaml:"-" mapstructure:"-"` // column mapping of every input
+	NearDuplicates NearDuplicateOptions `yaml:"-" mapstructure:"-"` // off unless Method is set
+	HuggingFace    HuggingFaceOptions   `yaml:"-" mapstructure:"-"` // hf:// inputs
 }
 
 // ValidationError represents a data validation error
@@ -65,17 +113,20 @@ type FileFormat string
 const (
 	FormatCSV     FileFormat = "csv"
 	FormatParquet FileFormat = "parquet"
-	FormatJSON    FileFormat = "json"
+	FormatJSON    FileFormat = "json" // one object per line; .json or .jsonl
 )
 
-// DetectFileFormat detects file format from extension
+// DetectFileFormat detects file format from extension, looking through an
+// age encryption extension (dataset.csv.age is CSV)
 func DetectFileFormat(filename string) FileFormat {
+	filename = artifact.TrimExtension(filename)
 	switch {
 	case len(filename) >= 4 && filename[len(filename)-4:] == ".csv":
 		return FormatCSV
 	case len(filename) >= 8 && filename[len(filename)-8:] == ".parquet":
 		return FormatParquet
-	case len(filename) >= 5 && filename[len(filename)-5:] == ".json":
+	case len(filename) >= 5 && filename[len(filename)-5:] == ".json",
+		len(filename) >= 6 && filename[len(filename)-6:] == ".jsonl":
 		return FormatJSON
 	default:
 		return FormatCSV // Default to CSV
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// defaultSubscriberBuffer is used when a subscriber does not choose a buffer size
+const defaultSubscriberBuffer = 256
+
+// Topic names a stream of in-process events carrying payloads of type T
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic creates a topic. Topics with the same name share subscribers, so
+// each name should be declared once with a single payload type.
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the topic name
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Bus fans in-process events out to subscribers. Publishing never blocks:
+// each subscriber has its own buffer and goroutine, and events are dropped
+// for a subscriber whose buffer is full.
+type Bus struct {
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	subs   map[string][]subscriber
+	closed bool
+}
+
+// subscriber is the type-erased side of a Subscription
+type subscriber interface {
+	deliver(payload any) bool
+	close()
+}
+
+// NewBus creates an empty bus
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		logger: logger,
+		subs:   make(map[string][]subscriber),
+	}
+}
+
+// Subscription receives events from one topic until closed
+type Subscription[T any] struct {
+	bus     *Bus
+	topic   string
+	name    string
+	events  chan T
+	dropped atomic.Int64
+	once    sync.Once
+	done    chan struct{}
+}
+
+// Subscribe calls handler for every event published to topic. Events are
+// delivered in order on a dedicated goroutine; buffer bounds how many may be
+// pending before new ones are dropped (0 selects a default). name identifies
+// the subscriber in logs.
+func Subscribe[T any](b *Bus, topic Topic[T], name string, buffer int, handler func(T)) *Subscription[T] {
+	if buffer <= 0 {
+		buffer = defaultSubscriberBuffer
+	}
+	sub := &Subscription[T]{
+		bus:    b,
+		topic:  topic.name,
+		name:   name,
+		events: make(chan T, buffer),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		sub.close()
+		return sub
+	}
+	b.subs[topic.name] = append(b.subs[topic.name], sub)
+	b.mu.Unlock()
+
+	go sub.run(handler, b.logger)
+	return sub
+}
+
+// Publish sends payload to every subscriber of topic without blocking
+func Publish[T any](b *Bus, topic Topic[T], payload T) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := b.subs[topic.name]
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.deliver(payload)
+	}
+}
+
+// Close stops every subscription. Pending events are discarded.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = make(map[string][]subscriber)
+	b.closed = true
+	b.mu.Unlock()
+
+	for _, topicSubs := range subs {
+		for _, sub := range topicSubs {
+			sub.close()
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers per topic
+func (b *Bus) Subscribers() map[string]int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	counts := make(map[string]int, len(b.subs))
+	for topic, subs := range b.subs {
+		counts[topic] = len(subs)
+	}
+	return counts
+}
+
+// Close unsubscribes. Pending events are discarded; a handler call already
+// in progress may still finish.
+func (s *Subscription[T]) Close() {
+	s.bus.mu.Lock()
+	subs := s.bus.subs[s.topic]
+	for i, sub := range subs {
+		if sub == subscriber(s) {
+			s.bus.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	s.bus.mu.Unlock()
+	s.close()
+}
+
+// Dropped returns how many events were discarded because the buffer was full
+func (s *Subscription[T]) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription[T]) deliver(payload any) bool {
+	event, ok := payload.(T)
+	if !ok {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.events <- event:
+		return true
+	default:
+		s.dropped.Add(1)
+		return false
+	}
+}
+
+func (s *Subscription[T]) close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// run calls the handler for each event, recovering from panics so one faulty
+// consumer cannot take down the process
+func (s *Subscription[T]) run(handler func(T), logger *zap.Logger) {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.events:
+			s.handle(handler, event, logger)
+		}
+	}
+}
+
+func (s *Subscription[T]) handle(handler func(T), event T, logger *zap.Logger) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Event subscriber panicked",
+				zap.String("topic", s.topic),
+				zap.String("subscriber", s.name),
+				zap.Any("panic", r))
+		}
+	}()
+	handler(event)
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ForwarderOptions controls delivery timeouts and replay backoff
+type ForwarderOptions struct {
+	DeliveryTimeout time.Duration
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+}
+
+// Forwarder delivers events to a sink, spilling them to a disk queue while
+// the sink is unreachable and replaying them in order with exponential
+// backoff once it recovers
+type Forwarder struct {
+	sink    Sink
+	queue   *DiskQueue
+	options ForwarderOptions
+	logger  *zap.Logger
+
+	incoming chan []byte
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewForwarder creates a forwarder for sink backed by queue
+func NewForwarder(sink Sink, queue *DiskQueue, options ForwarderOptions, logger *zap.Logger) *Forwarder {
+	if options.DeliveryTimeout <= 0 {
+		options.DeliveryTimeout = 10 * time.Second
+	}
+	if options.InitialBackoff <= 0 {
+		options.InitialBackoff = time.Second
+	}
+	if options.MaxBackoff < options.InitialBackoff {
+		options.MaxBackoff = 5 * time.Minute
+	}
+
+	return &Forwarder{
+		sink:     sink,
+		queue:    queue,
+		options:  options,
+		logger:   logger.With(zap.String("sink", sink.Name())),
+		incoming: make(chan []byte, 1024),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins delivering events in the background
+func (f *Forwarder) Start() {
+	f.wg.Add(1)
+	go f.run()
+}
+
+// Close stops delivery. Anything not yet delivered stays on disk for the next run.
+func (f *Forwarder) Close() {
+	close(f.stop)
+	f.wg.Wait()
+
+	// Persist events that arrived after the loop stopped
+	for {
+		select {
+		case payload := <-f.incoming:
+			f.spill(payload)
+		default:
+			return
+		}
+	}
+}
+
+// Send hands an event to the forwarder without blocking the caller
+func (f *Forwarder) Send(payload []byte) {
+	select {
+	case f.incoming <- payload:
+	default:
+		// Burst larger than the in-memory buffer goes straight to disk
+		f.spill(payload)
+	}
+}
+
+// Pending returns the number of events waiting on disk
+func (f *Forwarder) Pending() int {
+	return f.queue.Len()
+}
+
+func (f *Forwarder) run() {
+	defer f.wg.Done()
+
+	backoff := f.options.InitialBackoff
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+
+		case payload := <-f.incoming:
+			// Preserve order: while a backlog exists new events join it
+			if f.queue.Len() > 0 {
+				f.spill(payload)
+				continue
+			}
+			if err := f.deliver(payload); err != nil {
+				f.logger.Warn("Event sink unavailable, queueing to disk", zap.Error(err))
+				f.spill(payload)
+				resetTimer(retry, backoff)
+			}
+
+		case <-retry.C:
+			if f.replay() {
+				backoff = f.options.InitialBackoff
+				continue
+			}
+			backoff *= 2
+			if backoff > f.options.MaxBackoff {
+				backoff = f.options.MaxBackoff
+			}
+			resetTimer(retry, backoff)
+		}
+	}
+}
+
+// replay drains the disk queue in order. It returns true once the queue is
+// empty and false if delivery failed and should be retried later.
+func (f *Forwarder) replay() bool {
+	replayed := 0
+	for {
+		select {
+		case <-f.stop:
+			return false
+		default:
+		}
+
+		seq, payload, ok, err := f.queue.Peek()
+		if err != nil {
+			f.logger.Error("Failed to read queued event", zap.Error(err))
+			return false
+		}
+		if !ok {
+			if replayed > 0 {
+				f.logger.Info("Replayed queued events", zap.Int("count", replayed))
+			}
+			return true
+		}
+
+		if err := f.deliver(payload); err != nil {
+			f.logger.Debug("Event replay failed", zap.Error(err), zap.Int("pending", f.queue.Len()))
+			return false
+		}
+		if err := f.queue.Remove(seq); err != nil {
+			f.logger.Error("Failed to remove delivered event", zap.Error(err))
+			return false
+		}
+		replayed++
+	}
+}
+
+func (f *Forwarder) deliver(payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), f.options.DeliveryTimeout)
+	defer cancel()
+	return f.sink.Deliver(ctx, payload)
+}
+
+func (f *Forwarder) spill(payload []byte) {
+	if err := f.queue.Push(payload); err != nil {
+		f.logger.Error("Failed to queue event, dropping", zap.Error(err))
+	}
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
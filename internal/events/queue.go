@@ -0,0 +1,176 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const recordExt = ".evt"
+
+// DiskQueue is a bounded FIFO of opaque records persisted one file per record.
+// When full, the oldest records are dropped to make room so a long outage
+// costs the oldest events rather than the newest.
+type DiskQueue struct {
+	dir        string
+	maxRecords int
+	maxBytes   int64
+
+	mu         sync.Mutex
+	seqs       []uint64
+	sizes      map[uint64]int64
+	nextSeq    uint64
+	totalBytes int64
+	dropped    int64
+}
+
+// OpenDiskQueue opens (or creates) a queue in dir, recovering any records left
+// by a previous run
+func OpenDiskQueue(dir string, maxRecords int, maxBytes int64) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+
+	q := &DiskQueue{
+		dir:        dir,
+		maxRecords: maxRecords,
+		maxBytes:   maxBytes,
+		sizes:      make(map[uint64]int64),
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, recordExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, recordExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		q.seqs = append(q.seqs, seq)
+		q.sizes[seq] = info.Size()
+		q.totalBytes += info.Size()
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(q.seqs, func(i, j int) bool { return q.seqs[i] < q.seqs[j] })
+
+	return q, nil
+}
+
+// Push appends a record, evicting the oldest records if the queue is full
+func (q *DiskQueue) Push(payload []byte) error {
+	size := int64(len(payload))
+	if q.maxBytes > 0 && size > q.maxBytes {
+		return fmt.Errorf("record of %d bytes exceeds queue capacity of %d bytes", size, q.maxBytes)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.seqs) > 0 &&
+		((q.maxRecords > 0 && len(q.seqs) >= q.maxRecords) ||
+			(q.maxBytes > 0 && q.totalBytes+size > q.maxBytes)) {
+		if err := q.removeLocked(q.seqs[0]); err != nil {
+			return err
+		}
+		q.dropped++
+	}
+
+	seq := q.nextSeq
+	path := q.path(seq)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return fmt.Errorf("failed to write queued record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to commit queued record: %w", err)
+	}
+
+	q.nextSeq++
+	q.seqs = append(q.seqs, seq)
+	q.sizes[seq] = size
+	q.totalBytes += size
+	return nil
+}
+
+// Peek returns the oldest record without removing it
+func (q *DiskQueue) Peek() (uint64, []byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.seqs) > 0 {
+		seq := q.seqs[0]
+		payload, err := os.ReadFile(q.path(seq))
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed out from under us; skip it
+			q.forgetLocked(seq)
+			continue
+		}
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("failed to read queued record: %w", err)
+		}
+		return seq, payload, true, nil
+	}
+	return 0, nil, false, nil
+}
+
+// Remove deletes a delivered record
+func (q *DiskQueue) Remove(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.removeLocked(seq)
+}
+
+// Len returns the number of queued records
+func (q *DiskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.seqs)
+}
+
+// Dropped returns how many records were evicted because the queue was full
+func (q *DiskQueue) Dropped() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+func (q *DiskQueue) removeLocked(seq uint64) error {
+	if err := os.Remove(q.path(seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove queued record: %w", err)
+	}
+	q.forgetLocked(seq)
+	return nil
+}
+
+func (q *DiskQueue) forgetLocked(seq uint64) {
+	for i, s := range q.seqs {
+		if s == seq {
+			q.seqs = append(q.seqs[:i], q.seqs[i+1:]...)
+			break
+		}
+	}
+	q.totalBytes -= q.sizes[seq]
+	delete(q.sizes, seq)
+}
+
+func (q *DiskQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, recordExt))
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sink delivers serialized events to a downstream system such as an alerting
+// webhook, SIEM collector or message bus
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, payload []byte) error
+}
+
+// HTTPSink posts each event as a JSON body to a collector URL
+type HTTPSink struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHTTPSink creates a sink that POSTs events to url
+func NewHTTPSink(name, url string, headers map[string]string, timeout time.Duration) *HTTPSink {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &HTTPSink{
+		name:    name,
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the configured sink name
+func (s *HTTPSink) Name() string {
+	return s.name
+}
+
+// Deliver posts the payload; any non-2xx status is treated as a failure
+func (s *HTTPSink) Deliver(ctx context.Context, payload []byte) error {
+	return s.post(ctx, payload, nil)
+}
+
+// post sends payload with the configured headers and then extra, which
+// override them
+func (s *HTTPSink) post(ctx context.Context, payload []byte, extra map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build sink request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range extra {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sink %s unreachable: %w", s.name, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Sink: s.name, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// StatusError reports a non-2xx response from a sink
+type StatusError struct {
+	Sink       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sink %s returned status %d", e.Sink, e.StatusCode)
+}
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers sent with every webhook delivery. The signature is
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the webhook secret; receivers should recompute it and reject stale
+// timestamps to stop forged and replayed deliveries.
+const (
+	WebhookEventHeader     = "X-Sentinel-Event"
+	WebhookDeliveryHeader  = "X-Sentinel-Delivery"
+	WebhookTimestampHeader = "X-Sentinel-Timestamp"
+	WebhookSignatureHeader = "X-Sentinel-Signature"
+)
+
+// RetryPolicy bounds how often a failed webhook delivery is retried
+type RetryPolicy struct {
+	MaxAttempts    int // including the first
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Webhook posts signed event deliveries to a receiver, retrying failures
+// with exponential backoff. Unlike a Forwarder it keeps nothing on disk: a
+// delivery that exhausts its attempts is dropped.
+type Webhook struct {
+	sink   *HTTPSink
+	secret []byte
+	retry  RetryPolicy
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewWebhook creates a webhook that POSTs to url. An empty secret sends
+// deliveries unsigned.
+func NewWebhook(name, url string, headers map[string]string, timeout time.Duration, secret string, retry RetryPolicy) *Webhook {
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = 5
+	}
+	if retry.InitialBackoff <= 0 {
+		retry.InitialBackoff = time.Second
+	}
+	if retry.MaxBackoff < retry.InitialBackoff {
+		retry.MaxBackoff = time.Minute
+	}
+
+	return &Webhook{
+		sink:   NewHTTPSink(name, url, headers, timeout),
+		secret: []byte(secret),
+		retry:  retry,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Name returns the configured webhook name
+func (w *Webhook) Name() string {
+	return w.sink.Name()
+}
+
+// Deliver posts payload as one delivery of event. Every attempt carries the
+// same delivery ID, so receivers can discard duplicates, and a fresh
+// timestamp and signature. Client errors other than 408 and 429 are not
+// retried. It returns the last error once attempts run out or the webhook
+// is closed.
+func (w *Webhook) Deliver(event, deliveryID string, payload []byte) error {
+	backoff := w.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.attempt(event, deliveryID, payload)
+		if err == nil {
+			return nil
+		}
+		if !retryable(err) || attempt >= w.retry.MaxAttempts {
+			return fmt.Errorf("webhook %s gave up after %d attempt(s): %w", w.Name(), attempt, err)
+		}
+
+		select {
+		case <-w.stop:
+			return fmt.Errorf("webhook %s closed before delivery: %w", w.Name(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > w.retry.MaxBackoff {
+			backoff = w.retry.MaxBackoff
+		}
+	}
+}
+
+// Close abandons deliveries waiting to retry
+func (w *Webhook) Close() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Webhook) attempt(event, deliveryID string, payload []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := map[string]string{
+		WebhookEventHeader:     event,
+		WebhookDeliveryHeader:  deliveryID,
+		WebhookTimestampHeader: timestamp,
+	}
+	if len(w.secret) > 0 {
+		headers[WebhookSignatureHeader] = "sha256=" + SignWebhook(w.secret, timestamp, payload)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-w.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return w.sink.post(ctx, payload, headers)
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<payload>"
+func SignWebhook(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable reports whether a failed delivery may succeed if repeated
+func retryable(err error) bool {
+	var status *StatusError
+	if !errors.As(err, &status) {
+		return true // network errors and timeouts
+	}
+	switch {
+	case status.StatusCode == http.StatusRequestTimeout, status.StatusCode == http.StatusTooManyRequests:
+		return true
+	case status.StatusCode >= 400 && status.StatusCode < 500:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// delivery is one request a test receiver saw
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver answers each delivery with the next status in statuses,
+// then 200, and records what it received
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, func() []delivery) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []delivery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, delivery{header: r.Header.Clone(), body: body})
+		n := len(received)
+		mu.Unlock()
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []delivery {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]delivery(nil), received...)
+	}
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// TestWebhookSignsDeliveries checks receivers can verify a delivery with
+// the shared secret
+func TestWebhookSignsDeliveries(t *testing.T) {
+	server, received := newReceiver(t)
+	hook := NewWebhook("soar", server.URL, map[string]string{"X-Team": "secops"}, time.Second, "s3cret", fastRetry)
+	defer hook.Close()
+
+	payload := []byte(`{"event":"request_blocked"}`)
+	if err := hook.Deliver("request_blocked", "delivery-1", payload); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	got := received()
+	if len(got) != 1 {
+		t.Fatalf("received %d deliveries, want 1", len(got))
+	}
+	header := got[0].header
+	if header.Get(WebhookEventHeader) != "request_blocked" || header.Get(WebhookDeliveryHeader) != "delivery-1" || header.Get("X-Team") != "secops" {
+		t.Fatalf("delivery headers %v", header)
+	}
+	want := "sha256=" + SignWebhook([]byte("s3cret"), header.Get(WebhookTimestampHeader), got[0].body)
+	if header.Get(WebhookSignatureHeader) != want {
+		t.Fatalf("signature %q, want %q", header.Get(WebhookSignatureHeader), want)
+	}
+}
+
+// TestWebhookUnsignedWithoutSecret checks no signature header is sent when
+// no secret is configured
+func TestWebhookUnsignedWithoutSecret(t *testing.T) {
+	server, received := newReceiver(t)
+	hook := NewWebhook("soar", server.URL, nil, time.Second, "", fastRetry)
+	defer hook.Close()
+
+	if err := hook.Deliver("request_blocked", "delivery-1", []byte(`{}`)); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if sig := received()[0].header.Get(WebhookSignatureHeader); sig != "" {
+		t.Fatalf("unsigned webhook sent signature %q", sig)
+	}
+}
+
+// TestWebhookRetries checks server errors and 429 are retried under the
+// same delivery ID, other client errors are not, and attempts are bounded
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		attempts int
+	}{
+		{name: "server error then success", statuses: []int{500, 503}, attempts: 3},
+		{name: "rate limited then success", statuses: []int{429}, attempts: 2},
+		{name: "client error", statuses: []int{400}, wantErr: true, attempts: 1},
+		{name: "attempts exhausted", statuses: []int{500, 500, 500, 500}, wantErr: true, attempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := newReceiver(t, tt.statuses...)
+			hook := NewWebhook("soar", server.URL, nil, time.Second, "s3cret", fastRetry)
+			defer hook.Close()
+
+			err := hook.Deliver("request_blocked", "delivery-1", []byte(`{}`))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deliver error %v, want error %v", err, tt.wantErr)
+			}
+			got := received()
+			if len(got) != tt.attempts {
+				t.Fatalf("%d attempts, want %d", len(got), tt.attempts)
+			}
+			for _, d := range got {
+				if id := d.header.Get(WebhookDeliveryHeader); id != "delivery-1" {
+					t.Fatalf("retry sent delivery ID %q, want the original", id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package fingerprint reduces prompts to the templates they were filled
+// from. Variable segments such as numbers, identifiers, URLs, quoted values
+// and tagged or fenced inserts are replaced with placeholders, so every
+// prompt an application builds from one template shares a fingerprint.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Placeholders replacing variable segments
+const (
+	PlaceholderCode   = "<code>"
+	PlaceholderInsert = "<var>"
+	PlaceholderURL    = "<url>"
+	PlaceholderEmail  = "<email>"
+	PlaceholderUUID   = "<uuid>"
+	PlaceholderHex    = "<hex>"
+	PlaceholderNumber = "<num>"
+	PlaceholderQuoted = `"<str>"`
+)
+
+// Substitutions run in order, so a URL is replaced before the numbers in it
+var substitutions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile("(?s)```.*?```"), PlaceholderCode},
+	{regexp.MustCompile(`(?i)\bhttps?://\S+`), PlaceholderURL},
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`), PlaceholderEmail},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), PlaceholderUUID},
+	{regexp.MustCompile(`(?i)\b(?:[0-9a-f]*[0-9][0-9a-f]*[a-f][0-9a-f]*|[0-9a-f]*[a-f][0-9a-f]*[0-9][0-9a-f]*)\b`), PlaceholderHex},
+	{regexp.MustCompile(`"[^"]*"|“[^”]*”`), PlaceholderQuoted},
+	{regexp.MustCompile("`[^`\n]*`"), PlaceholderInsert},
+	{regexp.MustCompile(`[-+]?\d(?:[\d.,:/_-]*\d)?`), PlaceholderNumber},
+}
+
+// openTag matches an XML-style opening tag, which prompt templates use to
+// delimit inserted documents
+var openTag = regexp.MustCompile(`<([A-Za-z][\w-]*)>`)
+
+// minHexLength is the shortest letter-and-digit run treated as an
+// identifier; shorter ones are more often words or model names
+const minHexLength = 8
+
+// Template returns the template text was filled from: variable segments
+// replaced with placeholders, whitespace collapsed and letters lowercased
+func Template(text string) string {
+	text = stripTagged(text)
+	for _, sub := range substitutions {
+		if sub.replacement == PlaceholderHex {
+			text = sub.pattern.ReplaceAllStringFunc(text, func(match string) string {
+				if len(match) < minHexLength {
+					return match
+				}
+				return PlaceholderHex
+			})
+			continue
+		}
+		text = sub.pattern.ReplaceAllLiteralString(text, sub.replacement)
+	}
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// stripTagged replaces the content between each XML-style opening tag and
+// its closing tag with a placeholder
+func stripTagged(text string) string {
+	var b strings.Builder
+	for {
+		loc := openTag.FindStringSubmatchIndex(text)
+		if loc == nil {
+			b.WriteString(text)
+			return b.String()
+		}
+		closing := "</" + text[loc[2]:loc[3]] + ">"
+		end := strings.Index(text[loc[1]:], closing)
+		if end < 0 {
+			b.WriteString(text[:loc[1]])
+			text = text[loc[1]:]
+			continue
+		}
+		b.WriteString(text[:loc[1]])
+		b.WriteString(PlaceholderInsert)
+		b.WriteString(closing)
+		text = text[loc[1]+end+len(closing):]
+	}
+}
+
+// Sum returns the fingerprint of a template: the first 8 bytes of its
+// SHA-256, hex encoded
+func Sum(template string) string {
+	sum := sha256.Sum256([]byte(template))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Preview shortens a template for display to at most n runes
+func Preview(template string, n int) string {
+	if utf8.RuneCountInString(template) <= n {
+		return template
+	}
+	runes := []rune(template)
+	return string(runes[:n-1]) + "…"
+}
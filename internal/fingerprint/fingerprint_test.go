@@ -0,0 +1,46 @@
+package fingerprint
+
+import "testing"
+
+// TestTemplate checks the variable parts of prompts built from one template
+// are replaced, so they share a fingerprint
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Summarize ticket 4821 for  user@example.com", "summarize ticket <num> for <email>"},
+		{"Fetch https://example.com/a/1?b=2 now", "fetch <url> now"},
+		{`Translate "bonjour" to English`, `translate "<str>" to english`},
+		{"Order 550e8400-e29b-41d4-a716-446655440000 by deadbeef01", "order <uuid> by <hex>"},
+		{"Model gpt4 from feedface", "model gpt<num> from feedface"},
+		{"Review:\n```go\nfunc main() {}\n```\nThanks", "review: <code> thanks"},
+		{"<document>Quarterly report 2026</document> Summarize it", "<document><var></document> summarize it"},
+		{"<b>unclosed tag with 3 items", "<b>unclosed tag with <num> items"},
+		{"Use `rm -rf` carefully on 2026-03-01", "use <var> carefully on <num>"},
+	}
+	for _, tt := range tests {
+		if got := Template(tt.text); got != tt.want {
+			t.Errorf("Template(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	first := Sum(Template("Summarize ticket 1 for a@example.com"))
+	second := Sum(Template("summarize ticket 99231 for someone.else@example.org"))
+	if first != second || len(first) != 16 {
+		t.Errorf("fingerprints %q and %q, want one 16-digit fingerprint", first, second)
+	}
+	if first == Sum(Template("Delete ticket 1 for a@example.com")) {
+		t.Error("different templates share a fingerprint")
+	}
+}
+
+// TestPreview checks previews are cut to n runes with an ellipsis
+func TestPreview(t *testing.T) {
+	if got := Preview("short", 10); got != "short" {
+		t.Errorf("Preview kept %q, want it unchanged", got)
+	}
+	if got := Preview("résumé template", 6); got != "résum…" {
+		t.Errorf("Preview = %q, want résum…", got)
+	}
+}
@@ -0,0 +1,42 @@
+// Package jsonduration reads and writes durations in JSON as strings such
+// as "250ms" rather than integer nanoseconds
+package jsonduration
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a duration written as a string such as "250ms". Numbers are
+// read as nanoseconds, as config snapshots recorded them before.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var nanos int64
+		if json.Unmarshal(data, &nanos) != nil {
+			return fmt.Errorf("invalid duration %s: use a string such as \"250ms\"", data)
+		}
+		*d = Duration(nanos)
+		return nil
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// IsNumber reports whether a raw JSON value is a bare number, which
+// Duration reads as nanoseconds. Handlers taking durations from operators
+// can reject these, since a number there is usually meant as seconds.
+func IsNumber(data json.RawMessage) bool {
+	return len(data) > 0 && (data[0] == '-' || (data[0] >= '0' && data[0] <= '9'))
+}
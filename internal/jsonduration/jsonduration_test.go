@@ -0,0 +1,47 @@
+package jsonduration
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestDuration checks durations round-trip as strings and legacy numbers
+// are still read as nanoseconds
+func TestDuration(t *testing.T) {
+	data, err := json.Marshal(Duration(1500 * time.Millisecond))
+	if err != nil || string(data) != `"1.5s"` {
+		t.Fatalf("Marshal = %s, %v, want \"1.5s\"", data, err)
+	}
+
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{`"250ms"`, 250 * time.Millisecond},
+		{`"1h30m"`, 90 * time.Minute},
+		{`2000000000`, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		var d Duration
+		if err := json.Unmarshal([]byte(tt.input), &d); err != nil || time.Duration(d) != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", tt.input, time.Duration(d), err, tt.want)
+		}
+	}
+
+	for _, input := range []string{`"soon"`, `true`, `1.5`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("Unmarshal(%s) accepted an invalid duration", input)
+		}
+	}
+}
+
+// TestIsNumber checks bare numbers are told apart from duration strings
+func TestIsNumber(t *testing.T) {
+	for input, want := range map[string]bool{`30`: true, `-1`: true, `"30s"`: false, ``: false, `null`: false} {
+		if got := IsNumber(json.RawMessage(input)); got != want {
+			t.Errorf("IsNumber(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
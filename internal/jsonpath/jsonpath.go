@@ -0,0 +1,131 @@
+// Package jsonpath evaluates the small JSONPath subset used in configuration:
+// dotted member access and array indexes, e.g. "$.metadata.thread_id" or
+// "$.messages[-1].content". Negative indexes count from the end.
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled JSONPath expression
+type Path struct {
+	raw   string
+	steps []step
+}
+
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Compile parses a path expression
+func Compile(expr string) (*Path, error) {
+	rest := strings.TrimSpace(expr)
+	if !strings.HasPrefix(rest, "$") {
+		return nil, fmt.Errorf("invalid json path %q: must start with $", expr)
+	}
+	rest = rest[1:]
+
+	p := &Path{raw: expr}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid json path %q: empty member name", expr)
+			}
+			p.steps = append(p.steps, step{key: key})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid json path %q: unclosed [", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if quoted, err := strconv.Unquote(strings.ReplaceAll(inner, "'", "\"")); err == nil {
+				p.steps = append(p.steps, step{key: quoted})
+			} else {
+				idx, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid json path %q: bad index %q", expr, inner)
+				}
+				p.steps = append(p.steps, step{index: idx, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid json path %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return p, nil
+}
+
+// MustCompile is like Compile but panics on error
+func MustCompile(expr string) *Path {
+	p, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the original expression
+func (p *Path) String() string {
+	return p.raw
+}
+
+// Lookup evaluates the path against a decoded JSON document
+func (p *Path) Lookup(doc interface{}) (interface{}, bool) {
+	cur := doc
+	for _, s := range p.steps {
+		if s.isIndex {
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			idx := s.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			cur = arr[idx]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = obj[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// LookupString evaluates the path and formats scalar results as a string
+func (p *Path) LookupString(doc interface{}) (string, bool) {
+	v, ok := p.Lookup(doc)
+	if !ok {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		return val, val != ""
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,53 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestLookup checks member access, quoted members and negative indexes
+func TestLookup(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"metadata": {"thread_id": "t-1", "turn": 3, "dotted.key": true, "empty": ""},
+		"messages": [{"content": "first"}, {"content": "last"}]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr   string
+		want   string
+		wantOK bool
+	}{
+		{"$.metadata.thread_id", "t-1", true},
+		{"$.metadata.turn", "3", true},
+		{"$.metadata['dotted.key']", "true", true},
+		{"$.messages[0].content", "first", true},
+		{"$.messages[-1].content", "last", true},
+		{"$.messages[2].content", "", false},
+		{"$.messages[-3].content", "", false},
+		{"$.metadata.missing", "", false},
+		{"$.metadata.empty", "", false},
+		{"$.metadata", "", false},
+		{"$.metadata[0]", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := MustCompile(tt.expr).LookupString(doc)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s = %q, %v, want %q, %v", tt.expr, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// TestCompileErrors checks malformed expressions are rejected
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{"metadata.id", "$.", "$..id", "$.messages[0", "$.messages[x]", "$x"} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Compile(%q) accepted invalid path", expr)
+		}
+	}
+	if p := MustCompile(" $.a "); p.String() != " $.a " {
+		t.Errorf("String = %q, want the original expression", p.String())
+	}
+}
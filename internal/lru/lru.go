@@ -0,0 +1,150 @@
+// Package lru implements a size-bounded, concurrency-safe LRU cache
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a least-recently-used cache holding at most size entries.
+// Entries added with a TTL expire after it and count as misses.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration // default for Add; 0 never expires
+	order   *list.List    // front is most recently used
+	entries map[K]*list.Element
+
+	hits   uint64
+	misses uint64
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time // zero never expires
+}
+
+// New creates a cache holding at most size entries
+func New[K comparable, V any](size int) *Cache[K, V] {
+	return NewWithTTL[K, V](size, 0)
+}
+
+// NewWithTTL creates a cache holding at most size entries, each for at most
+// ttl; a ttl of 0 keeps entries until they are evicted
+func NewWithTTL[K comparable, V any](size int, ttl time.Duration) *Cache[K, V] {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache[K, V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+	}
+}
+
+// Get returns the value for key and marks it recently used
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		if e.expires.IsZero() || time.Now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.hits++
+			return e.value, true
+		}
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	c.misses++
+	var zero V
+	return zero, false
+}
+
+// Add stores value under key for the cache's TTL, evicting the least
+// recently used entry if full
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.AddWithTTL(key, value, c.ttl)
+}
+
+// AddWithTTL stores value under key for ttl; a ttl of 0 keeps it until it
+// is evicted
+func (c *Cache[K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// Remove deletes key if it is cached
+func (c *Cache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// Range calls f for each unexpired entry, most recently used first, until f
+// returns false. Entries are not marked recently used, and f must not call
+// the cache.
+func (c *Cache[K, V]) Range(f func(key K, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry[K, V])
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			continue
+		}
+		if !f(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Purge removes every entry; the hit and miss counts are kept
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[K]*list.Element, c.size)
+}
+
+// Len returns the number of cached entries, expired ones included until
+// they are looked up or evicted
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the hit and miss counts
+func (c *Cache[K, V]) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
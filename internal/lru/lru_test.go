@@ -0,0 +1,70 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCacheEvictsLeastRecentlyUsed checks the oldest untouched entry is the
+// one evicted when the cache is full
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if got, ok := c.Get(key); !ok || got != want {
+			t.Fatalf("Get(%s) = %d, %v, want %d", key, got, ok, want)
+		}
+	}
+	if hits, misses := c.Stats(); hits != 3 || misses != 1 {
+		t.Fatalf("Stats = %d hits %d misses, want 3 and 1", hits, misses)
+	}
+
+	c.Add("a", 10)
+	if got, _ := c.Get("a"); got != 10 || c.Len() != 2 {
+		t.Fatalf("updated a = %d with %d entries, want 10 and 2", got, c.Len())
+	}
+
+	c.Remove("a")
+	if _, ok := c.Get("a"); ok || c.Len() != 1 {
+		t.Fatal("Remove left a cached")
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Fatalf("Len after Purge = %d", c.Len())
+	}
+}
+
+// TestCacheTTL checks expired entries miss and are skipped by Range
+func TestCacheTTL(t *testing.T) {
+	c := NewWithTTL[string, int](4, 20*time.Millisecond)
+	c.Add("short", 1)
+	c.AddWithTTL("forever", 2, 0)
+	time.Sleep(40 * time.Millisecond)
+
+	var seen []string
+	c.Range(func(key string, _ int) bool {
+		seen = append(seen, key)
+		return true
+	})
+	if len(seen) != 1 || seen[0] != "forever" {
+		t.Fatalf("Range saw %v, want only forever", seen)
+	}
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("expired entry returned")
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatal("entry without a TTL expired")
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expired entry not dropped on lookup, Len = %d", c.Len())
+	}
+}
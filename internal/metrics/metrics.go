@@ -0,0 +1,314 @@
+// Package metrics exposes Prometheus metrics for detections and component
+// health
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "llm_sentinel"
+
+// latencyBuckets span the overheads a proxy hop is expected to add
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// OtherCategory is reported for attack types outside the known categories,
+// keeping label cardinality bounded when corpus labels are free text
+const OtherCategory = "other"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	detections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "detections_total",
+		Help:      "Analyzed prompts by attack category and action taken.",
+	}, []string{"category", "action"})
+
+	detectionConfidence = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "detection_confidence",
+		Help:      "Confidence of analysis verdicts by attack category.",
+		Buckets:   []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.95, 0.99, 1},
+	}, []string{"category"})
+
+	ruleMatchSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pii_rule_match_seconds",
+		Help:      "Time spent matching one PII rule against one text.",
+		Buckets:   []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5},
+	}, []string{"rule"})
+
+	ruleOverruns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pii_rule_overruns_total",
+		Help:      "PII rule matches that exceeded the rule time budget.",
+	}, []string{"rule"})
+
+	ruleSuspensions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pii_rule_suspensions_total",
+		Help:      "Times a PII rule was suspended for repeated overruns.",
+	}, []string{"rule"})
+
+	suspendedRedactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pii_rule_suspended_redactions_total",
+		Help:      "Texts redacted whole because a PII rule was suspended.",
+	}, []string{"rule"})
+
+	streamOverhead = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stream_ttfb_overhead_seconds",
+		Help:      "Time to first byte the proxy added to streamed responses, beyond the upstream's own.",
+		Buckets:   latencyBuckets,
+	}, []string{"route"})
+
+	streamStageSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stream_stage_seconds",
+		Help:      "Time each proxy stage spent before a streamed request's first byte reached the client.",
+		Buckets:   latencyBuckets,
+	}, []string{"stage"})
+
+	panics = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "Panics recovered while handling requests.",
+	})
+
+	embeddingInputs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embeddings_api_inputs_total",
+		Help:      "Texts embedded through the embeddings API, by requested model name.",
+	}, []string{"model"})
+
+	embeddingTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embeddings_api_tokens_total",
+		Help:      "Model tokens embedded through the embeddings API, by requested model name.",
+	}, []string{"model"})
+
+	clientAuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_auth_failures_total",
+		Help:      "Requests rejected by client auth, by reason (missing or invalid key).",
+	}, []string{"reason"})
+
+	oversizedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "oversized_requests_total",
+		Help:      "Provider requests rejected for a body over max_request_size, by route.",
+	}, []string{"route"})
+
+	responseCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "response_cache_lookups_total",
+		Help:      "Provider requests eligible for the response cache, by route and result (hit, miss, bypass or anonymous).",
+	}, []string{"route", "result"})
+
+	upstreamTargetHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_target_healthy",
+		Help:      "Whether a failover group target is healthy (1) or unhealthy (0), by route and target.",
+	}, []string{"route", "target"})
+
+	upstreamFailovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_failovers_total",
+		Help:      "Switches of a provider route's traffic between failover targets.",
+	}, []string{"route", "from", "to"})
+
+	costCapExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cost_cap_exceeded_total",
+		Help:      "Provider requests whose estimated cost exceeded cost.max_request_usd, by route and action (reject or warn).",
+	}, []string{"route", "action"})
+
+	upstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "Upstream requests repeated after a connection failure or retryable status, by route.",
+	}, []string{"route"})
+
+	circuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_state",
+		Help:      "Upstream circuit breaker state (0 closed, 1 half-open, 2 open), by upstream: the route, or route/target for failover targets.",
+	}, []string{"upstream"})
+
+	circuitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_rejections_total",
+		Help:      "Requests answered with a 503 by an open circuit breaker, by upstream.",
+	}, []string{"upstream"})
+
+	categoriesMu sync.RWMutex
+	categories   = map[string]bool{
+		"safe":                   true,
+		"prompt_injection":       true,
+		"jailbreak":              true,
+		"information_extraction": true,
+		"known_attack":           true,
+	}
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		detections,
+		detectionConfidence,
+		ruleMatchSeconds,
+		ruleOverruns,
+		ruleSuspensions,
+		suspendedRedactions,
+		streamOverhead,
+		streamStageSeconds,
+		panics,
+		embeddingInputs,
+		embeddingTokens,
+		clientAuthFailures,
+		oversizedRequests,
+		responseCacheLookups,
+		upstreamTargetHealthy,
+		upstreamFailovers,
+		costCapExceeded,
+		upstreamRetries,
+		circuitState,
+		circuitRejections,
+	)
+}
+
+// Register adds a collector to the registry served by Handler
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// AddCategories extends the attack categories reported by name, e.g. with
+// the configured label taxonomy
+func AddCategories(names ...string) {
+	categoriesMu.Lock()
+	defer categoriesMu.Unlock()
+	for _, name := range names {
+		categories[name] = true
+	}
+}
+
+// Category maps an attack type onto a reported category
+func Category(attackType string) string {
+	categoriesMu.RLock()
+	defer categoriesMu.RUnlock()
+	if categories[attackType] {
+		return attackType
+	}
+	return OtherCategory
+}
+
+// ObserveDetection records one analysis verdict. action is blocked, flagged
+// (malicious but under the block threshold) or allowed.
+func ObserveDetection(attackType, action string, confidence float32) {
+	category := Category(attackType)
+	detections.WithLabelValues(category, action).Inc()
+	detectionConfidence.WithLabelValues(category).Observe(float64(confidence))
+}
+
+// ObserveRuleMatch records the cost of matching one PII rule against one
+// text and whether it overran the rule's time budget
+func ObserveRuleMatch(rule string, elapsed time.Duration, overrun bool) {
+	ruleMatchSeconds.WithLabelValues(rule).Observe(elapsed.Seconds())
+	if overrun {
+		ruleOverruns.WithLabelValues(rule).Inc()
+	}
+}
+
+// ObserveRuleSuspended records a PII rule being suspended
+func ObserveRuleSuspended(rule string) {
+	ruleSuspensions.WithLabelValues(rule).Inc()
+}
+
+// ObserveSuspendedRedaction records a text redacted whole while a PII rule
+// was suspended
+func ObserveSuspendedRedaction(rule string) {
+	suspendedRedactions.WithLabelValues(rule).Inc()
+}
+
+// ObserveStreamLatency records the time to first byte the proxy added to a
+// streamed response on route, and what each stage contributed
+func ObserveStreamLatency(route string, overhead time.Duration, stages map[string]time.Duration) {
+	streamOverhead.WithLabelValues(route).Observe(overhead.Seconds())
+	for stage, elapsed := range stages {
+		streamStageSeconds.WithLabelValues(stage).Observe(elapsed.Seconds())
+	}
+}
+
+// ObservePanic records a panic recovered while handling a request
+func ObservePanic() {
+	panics.Inc()
+}
+
+// ObserveEmbeddings records one embeddings API request for model
+func ObserveEmbeddings(model string, inputs, tokens int) {
+	embeddingInputs.WithLabelValues(model).Add(float64(inputs))
+	embeddingTokens.WithLabelValues(model).Add(float64(tokens))
+}
+
+// ObserveClientAuthFailure records a request rejected by client auth
+func ObserveClientAuthFailure(reason string) {
+	clientAuthFailures.WithLabelValues(reason).Inc()
+}
+
+// ObserveOversizedRequest records a request rejected for its body size
+func ObserveOversizedRequest(route string) {
+	oversizedRequests.WithLabelValues(route).Inc()
+}
+
+// ObserveResponseCache records a response cache lookup on route
+func ObserveResponseCache(route, result string) {
+	responseCacheLookups.WithLabelValues(route, result).Inc()
+}
+
+// SetUpstreamTargetHealth records whether a failover target is healthy
+func SetUpstreamTargetHealth(route, target string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	upstreamTargetHealthy.WithLabelValues(route, target).Set(value)
+}
+
+// ObserveUpstreamFailover records route traffic switching targets
+func ObserveUpstreamFailover(route, from, to string) {
+	upstreamFailovers.WithLabelValues(route, from, to).Inc()
+}
+
+// ObserveCostCapExceeded records a request estimated to cost more than the cap
+func ObserveCostCapExceeded(route, action string) {
+	costCapExceeded.WithLabelValues(route, action).Inc()
+}
+
+// ObserveUpstreamRetry records a repeated upstream request
+func ObserveUpstreamRetry(route string) {
+	upstreamRetries.WithLabelValues(route).Inc()
+}
+
+// SetCircuitState records an upstream circuit breaker's state: 0 closed,
+// 1 half-open, 2 open
+func SetCircuitState(upstream string, state int) {
+	circuitState.WithLabelValues(upstream).Set(float64(state))
+}
+
+// ObserveCircuitRejection records a request refused by an open breaker
+func ObserveCircuitRejection(upstream string) {
+	circuitRejections.WithLabelValues(upstream).Inc()
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestCategory checks unknown attack types collapse into one category until
+// they are added
+func TestCategory(t *testing.T) {
+	if got := Category("jailbreak"); got != "jailbreak" {
+		t.Errorf("Category(jailbreak) = %q", got)
+	}
+	if got := Category("role play attack #4821"); got != OtherCategory {
+		t.Errorf("free-text attack type reported as %q, want %q", got, OtherCategory)
+	}
+	AddCategories("test_roleplay")
+	if got := Category("test_roleplay"); got != "test_roleplay" {
+		t.Errorf("added category reported as %q", got)
+	}
+}
+
+// TestObserveDetection checks verdicts are counted by category and action
+// and served by Handler
+func TestObserveDetection(t *testing.T) {
+	before := testutil.ToFloat64(detections.WithLabelValues(OtherCategory, "blocked"))
+	ObserveDetection("unlisted attack", "blocked", 0.97)
+	ObserveDetection("another unlisted attack", "blocked", 0.91)
+	if got := testutil.ToFloat64(detections.WithLabelValues(OtherCategory, "blocked")) - before; got != 2 {
+		t.Fatalf("detections counted %v, want 2", got)
+	}
+
+	SetCircuitState("openai", 2)
+	if got := testutil.ToFloat64(circuitState.WithLabelValues("openai")); got != 2 {
+		t.Fatalf("circuit state = %v, want 2", got)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`llm_sentinel_detections_total{action="blocked",category="other"}`,
+		`llm_sentinel_detection_confidence_bucket{category="other",le="0.95"}`,
+		`llm_sentinel_upstream_circuit_state{upstream="openai"} 2`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+}
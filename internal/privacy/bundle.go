@@ -0,0 +1,100 @@
+package privacy
+
+import (
+	"fmt"
+	"time"
+)
+
+// RuleBundleVersion is the bundle format this build reads and writes
+const RuleBundleVersion = 1
+
+// RuleBundle is a portable copy of a detector's rule set, for moving rule
+// changes between environments
+type RuleBundle struct {
+	Version    int          `json:"version" yaml:"version"`
+	ExportedAt time.Time    `json:"exported_at" yaml:"exported_at"`
+	Rules      []RuleStatus `json:"rules" yaml:"rules"`
+}
+
+// ExportBundle returns every rule, built-in and custom, with its state.
+// Match costs describe this deployment and are left out.
+func (d *Detector) ExportBundle() RuleBundle {
+	rules := d.Rules()
+	for i := range rules {
+		rules[i].Cost = nil
+	}
+	return RuleBundle{
+		Version:    RuleBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Rules:      rules,
+	}
+}
+
+// BundleState returns the rule state that importing bundle would produce,
+// for applying with RestoreRuleState. Built-in and config file rules take
+// only their enabled flag from the bundle; their patterns and severities
+// belong to this build and its config. Custom rules are added, or replace
+// the admin-added rule of the same name. With replace, admin-added rules
+// missing from the bundle are dropped.
+func (d *Detector) BundleState(bundle RuleBundle, replace bool) (RuleState, error) {
+	if bundle.Version != RuleBundleVersion {
+		return RuleState{}, fmt.Errorf("unsupported rule bundle version: %d (must be %d)", bundle.Version, RuleBundleVersion)
+	}
+
+	state := d.RuleState()
+	added := make(map[string]bool, len(state.Added))
+	for _, rule := range state.Added {
+		added[rule.Name] = true
+	}
+	fixed := make(map[string]bool)
+	for _, rule := range d.Rules() {
+		if !added[rule.Name] {
+			fixed[rule.Name] = true
+		}
+	}
+	if replace {
+		for name := range added {
+			delete(state.Enabled, name)
+		}
+		state.Added = []RuleStatus{}
+	}
+
+	seen := make(map[string]bool, len(bundle.Rules))
+	for _, rule := range bundle.Rules {
+		if rule.Name == "" {
+			return RuleState{}, fmt.Errorf("rule bundle has a rule without a name")
+		}
+		if seen[rule.Name] {
+			return RuleState{}, fmt.Errorf("rule bundle lists %s more than once", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		if fixed[rule.Name] {
+			state.Enabled[rule.Name] = rule.Enabled
+			continue
+		}
+		if !rule.Custom {
+			return RuleState{}, fmt.Errorf("rule bundle has built-in rule %s, which this build does not have", rule.Name)
+		}
+		if rule.Pattern == "" {
+			return RuleState{}, fmt.Errorf("rule bundle has custom rule %s without a pattern", rule.Name)
+		}
+
+		rule.Custom = true
+		rule.Cost = nil
+		state.Added = upsertRule(state.Added, rule)
+		state.Enabled[rule.Name] = rule.Enabled
+	}
+	return state, nil
+}
+
+// upsertRule replaces the rule with rule's name in rules, or appends it
+func upsertRule(rules []RuleStatus, rule RuleStatus) []RuleStatus {
+	for i := range rules {
+		if rules[i].Name == rule.Name {
+			rules[i] = rule
+			return rules
+		}
+	}
+	return append(rules, rule)
+}
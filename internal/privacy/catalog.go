@@ -0,0 +1,90 @@
+package privacy
+
+// DefaultSeverity is the severity of rules that do not set one, as webhook
+// and event filters rank them
+const DefaultSeverity = "medium"
+
+// catalogSeed fixes the synthetic values behind catalog examples, so the
+// catalog is the same on every call and every replica
+const catalogSeed = 1
+
+// ruleCategories groups the built-in rules by what they find. Rules not
+// listed find credentials; custom rules are in the "custom" category.
+var ruleCategories = map[string]string{
+	"userPath":    "personal",
+	"email":       "personal",
+	"ipAddress":   "personal",
+	"creditCard":  "personal",
+	"ssn":         "personal",
+	"phoneNumber": "personal",
+
+	"openaiOrgId":          "identifier",
+	"googleCloudProjectId": "identifier",
+	"azureSubscriptionId":  "identifier",
+	"twilioAccountSid":     "identifier",
+	"awsAccountId":         "identifier",
+	"gcpProjectNumber":     "identifier",
+	"elasticCloudId":       "identifier",
+
+	"databaseUrl":             "connection_string",
+	"redisUrl":                "connection_string",
+	"webhookUrl":              "connection_string",
+	"slackWebhook":            "connection_string",
+	"discordWebhook":          "connection_string",
+	"mongodbConnectionString": "connection_string",
+	"elasticsearchUrl":        "connection_string",
+	"rabbitMqUrl":             "connection_string",
+	"kafkaUrl":                "connection_string",
+	"ftpCredentials":          "connection_string",
+	"sentryDsn":               "connection_string",
+
+	"sshPrivateKey":   "private_key",
+	"pgpPrivateKey":   "private_key",
+	"x509Certificate": "certificate",
+}
+
+// RuleInfo describes a detection rule for capability inventories. Example
+// is a synthetic value as this rule alone masks it; it is empty when no
+// synthetic value exists for the rule.
+type RuleInfo struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Example  string `json:"example,omitempty"`
+	Enabled  bool   `json:"enabled"`
+	Custom   bool   `json:"custom"`
+}
+
+// Catalog returns every detection rule, built-in and custom, with its
+// category, effective severity, an example of its masking and its state
+func (d *Detector) Catalog() []RuleInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rules := make([]RuleInfo, 0, len(d.rules))
+	for _, rule := range d.rules {
+		info := RuleInfo{
+			Name:     rule.Name,
+			Category: ruleCategories[rule.Name],
+			Severity: rule.Severity,
+			Enabled:  d.enabled[rule.Name],
+			Custom:   rule.Custom,
+		}
+		if info.Category == "" {
+			info.Category = "credential"
+		}
+		if rule.Custom {
+			info.Category = "custom"
+		}
+		if info.Severity == "" {
+			info.Severity = DefaultSeverity
+		}
+		if gen, ok := valueGenerators[rule.Name]; ok && !rule.Custom {
+			if value := gen(NewGenerator(catalogSeed)); rule.Pattern.MatchString(value) {
+				info.Example = rule.Pattern.ReplaceAllString(value, rule.Replacement)
+			}
+		}
+		rules = append(rules, info)
+	}
+	return rules
+}
@@ -2,15 +2,20 @@ package privacy
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/raaihank/llm-sentinel/internal/config"
 	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
 	"go.uber.org/zap"
 )
 
 // Detector handles PII detection and masking
 type Detector struct {
+	mu      sync.RWMutex // guards rules and enabled, which change at runtime
 	rules   []DetectionRule
 	enabled map[string]bool
 	logger  *logger.Logger
@@ -25,12 +30,19 @@ func New(cfg config.PrivacyConfig, log *logger.Logger) (*Detector, error) {
 		logger:  log,
 		config:  cfg,
 	}
+	for i := range detector.rules {
+		detector.rules[i].cost = &ruleCost{}
+	}
 
 	// Configure enabled detectors
 	if err := detector.configureDetectors(cfg.Detectors); err != nil {
 		return nil, err
 	}
 
+	if err := detector.SetCustomRules(cfg.CustomRules); err != nil {
+		return nil, err
+	}
+
 	log.Info("Privacy detector initialized",
 		zap.Int("total_rules", len(detector.rules)),
 		zap.Int("enabled_rules", detector.countEnabledRules()),
@@ -76,6 +88,12 @@ func (d *Detector) configureDetectors(detectors []string) error {
 
 // ProcessText processes text through all enabled PII detectors
 func (d *Detector) ProcessText(text string) ProcessResult {
+	return d.ProcessTextWith(text, nil)
+}
+
+// ProcessTextWith processes text through the named detectors instead of the
+// enabled set; "all" selects every rule. A nil list uses the enabled set.
+func (d *Detector) ProcessTextWith(text string, detectors []string) ProcessResult {
 	if !d.config.Enabled {
 		return ProcessResult{
 			MaskedText: text,
@@ -84,14 +102,44 @@ func (d *Detector) ProcessText(text string) ProcessResult {
 		}
 	}
 
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	active := d.enabled
+	if detectors != nil {
+		active = selectRules(d.rules, detectors)
+	}
+
 	maskedText := text
 	findings := make([]Finding, 0)
+	now := time.Now()
+	var suspended *DetectionRule
 
-	for _, rule := range d.rules {
-		if !d.enabled[rule.Name] {
+	for i := range d.rules {
+		rule := &d.rules[i]
+		if !active[rule.Name] {
 			continue
 		}
 
+		// A suspended rule cannot vouch for the text, so none of it is sent
+		if rule.cost.suspended(now) {
+			findings = append(findings, Finding{
+				EntityType: rule.Name,
+				Masked:     SuspendedRuleMask,
+				Count:      1,
+				Severity:   rule.Severity,
+			})
+			maskedText = SuspendedRuleMask
+			suspended = rule
+			metrics.ObserveSuspendedRedaction(rule.Name)
+			d.logger.Debug("Text redacted whole while a PII rule is suspended",
+				zap.String("entity_type", rule.Name),
+			)
+			break
+		}
+
+		textLen := len(maskedText)
+		start := time.Now()
 		matches := rule.Pattern.FindAllStringSubmatch(maskedText, -1)
 		if len(matches) > 0 {
 			// Create finding
@@ -99,6 +147,7 @@ func (d *Detector) ProcessText(text string) ProcessResult {
 				EntityType: rule.Name,
 				Masked:     rule.Replacement,
 				Count:      len(matches),
+				Severity:   rule.Severity,
 			}
 			findings = append(findings, finding)
 
@@ -111,13 +160,48 @@ func (d *Detector) ProcessText(text string) ProcessResult {
 				zap.String("replacement", rule.Replacement),
 			)
 		}
+		d.observeRule(rule, time.Since(start), textLen, len(matches))
 	}
 
-	return ProcessResult{
+	result := ProcessResult{
 		MaskedText: maskedText,
 		Findings:   findings,
 		Original:   text,
 	}
+	if suspended != nil {
+		result.SuspendedRule = suspended.Name
+		result.SuspendedUntil = time.Unix(0, suspended.cost.suspendedUntil.Load())
+	}
+	return result
+}
+
+// selectRules maps the named rules, or every rule for "all", to enabled
+func selectRules(rules []DetectionRule, detectors []string) map[string]bool {
+	selected := make(map[string]bool, len(detectors))
+	for _, name := range detectors {
+		if name == "all" {
+			for _, rule := range rules {
+				selected[rule.Name] = true
+			}
+			continue
+		}
+		selected[name] = true
+	}
+	return selected
+}
+
+// HasRules returns an error naming the first detector that is not a known
+// rule, so policies can be checked at startup
+func (d *Detector) HasRules(detectors []string) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, name := range detectors {
+		if _, exists := d.enabled[name]; !exists && name != "all" {
+			return fmt.Errorf("unknown detector: %s", name)
+		}
+	}
+	return nil
 }
 
 // ProcessHeaders processes HTTP headers for sensitive data
@@ -167,7 +251,7 @@ func (d *Detector) isSensitiveHeader(header string) bool {
 // isAuthHeader checks if a header is used for authentication
 func (d *Detector) isAuthHeader(header string) bool {
 	headerLower := strings.ToLower(header)
-	authHeaders := []string{"authorization", "x-api-key", "x-auth-token", "bearer"}
+	authHeaders := []string{"authorization", "api-key", "x-auth-token", "bearer"} // api-key also covers x-api-key
 
 	for _, authHeader := range authHeaders {
 		if strings.Contains(headerLower, authHeader) {
@@ -196,6 +280,9 @@ func (d *Detector) countEnabledRules() int {
 
 // GetEnabledRules returns a list of enabled rule names
 func (d *Detector) GetEnabledRules() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
 	var enabled []string
 	for ruleName, isEnabled := range d.enabled {
 		if isEnabled {
@@ -207,6 +294,9 @@ func (d *Detector) GetEnabledRules() []string {
 
 // EnableRule enables a specific detection rule
 func (d *Detector) EnableRule(ruleName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	for _, rule := range d.rules {
 		if rule.Name == ruleName {
 			d.enabled[ruleName] = true
@@ -219,6 +309,9 @@ func (d *Detector) EnableRule(ruleName string) error {
 
 // DisableRule disables a specific detection rule
 func (d *Detector) DisableRule(ruleName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	if _, exists := d.enabled[ruleName]; !exists {
 		return fmt.Errorf("unknown rule: %s", ruleName)
 	}
@@ -227,3 +320,208 @@ func (d *Detector) DisableRule(ruleName string) error {
 	d.logger.Info("Detection rule disabled", zap.String("rule", ruleName))
 	return nil
 }
+
+// Rules returns every detection rule with its current state
+func (d *Detector) Rules() []RuleStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := time.Now()
+	rules := make([]RuleStatus, 0, len(d.rules))
+	for _, rule := range d.rules {
+		rules = append(rules, RuleStatus{
+			Name:        rule.Name,
+			Pattern:     rule.Pattern.String(),
+			Replacement: rule.Replacement,
+			Enabled:     d.enabled[rule.Name],
+			Custom:      rule.Custom,
+			Severity:    rule.Severity,
+			Cost:        rule.cost.snapshot(now),
+		})
+	}
+	return rules
+}
+
+// AddRule compiles and enables a new detection rule. Rules run in the order
+// they were added, so a new rule sees text already masked by the built-ins.
+func (d *Detector) AddRule(name, pattern, replacement string) error {
+	if name == "" || pattern == "" {
+		return fmt.Errorf("rule name and pattern are required")
+	}
+	compiled, err := d.compileRule(name, pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern for rule %s: %w", name, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.enabled[name]; exists {
+		return fmt.Errorf("rule already exists: %s", name)
+	}
+	d.rules = append(d.rules, DetectionRule{
+		Name:        name,
+		Pattern:     compiled,
+		Replacement: replacement,
+		Enabled:     true,
+		Custom:      true,
+		cost:        &ruleCost{},
+	})
+	d.enabled[name] = true
+	d.logger.Info("Detection rule added", zap.String("rule", name))
+	return nil
+}
+
+// RuleState returns the rules added through the admin API and every rule's
+// enabled flag
+func (d *Detector) RuleState() RuleState {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	state := RuleState{Added: []RuleStatus{}, Enabled: make(map[string]bool, len(d.enabled))}
+	for _, rule := range d.rules {
+		if rule.Custom && !rule.fromConfig {
+			state.Added = append(state.Added, RuleStatus{
+				Name:        rule.Name,
+				Pattern:     rule.Pattern.String(),
+				Replacement: rule.Replacement,
+				Enabled:     d.enabled[rule.Name],
+				Custom:      true,
+				Severity:    rule.Severity,
+			})
+		}
+	}
+	for name, enabled := range d.enabled {
+		state.Enabled[name] = enabled
+	}
+	return state
+}
+
+// RestoreRuleState replaces the admin-added rules and enabled flags with
+// state. Every pattern is compiled before anything changes. Built-in and
+// config file rules are kept; flags for rules that no longer exist are
+// ignored.
+func (d *Detector) RestoreRuleState(state RuleState) error {
+	added := make([]DetectionRule, 0, len(state.Added))
+	for _, rule := range state.Added {
+		pattern, err := d.compileRule(rule.Name, rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for rule %s: %w", rule.Name, err)
+		}
+		added = append(added, DetectionRule{
+			Name:        rule.Name,
+			Pattern:     pattern,
+			Replacement: rule.Replacement,
+			Enabled:     true,
+			Custom:      true,
+			Severity:    rule.Severity,
+			cost:        &ruleCost{},
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	kept := make([]DetectionRule, 0, len(d.rules)+len(added))
+	names := make(map[string]bool, len(d.rules)+len(added))
+	for _, rule := range d.rules {
+		if !rule.Custom || rule.fromConfig {
+			kept = append(kept, rule)
+			names[rule.Name] = true
+		}
+	}
+	for _, rule := range added {
+		if names[rule.Name] {
+			return fmt.Errorf("rule %s conflicts with an existing rule", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	enabled := make(map[string]bool, len(names))
+	for name := range names {
+		enabled[name] = d.enabled[name]
+		if restored, ok := state.Enabled[name]; ok {
+			enabled[name] = restored
+		}
+	}
+	d.rules = append(kept, added...)
+	d.enabled = enabled
+
+	d.logger.Info("Detection rules restored", zap.Int("added_rules", len(added)))
+	return nil
+}
+
+// SetCustomRules replaces the rules defined in privacy.custom_rules. Every
+// pattern is compiled before anything changes, so a bad reload leaves the
+// previous rules in place. Rules added through the admin API are kept.
+func (d *Detector) SetCustomRules(custom []config.CustomPIIRule) error {
+	compiled := make([]DetectionRule, 0, len(custom))
+	for _, rule := range custom {
+		pattern, err := d.compileRule(rule.Name, rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for custom rule %s: %w", rule.Name, err)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "[" + strings.ToUpper(rule.Name) + "_MASKED]"
+		}
+		severity := rule.Severity
+		if severity == "" {
+			severity = "medium"
+		}
+		compiled = append(compiled, DetectionRule{
+			Name:        rule.Name,
+			Pattern:     pattern,
+			Replacement: replacement,
+			Enabled:     true,
+			Custom:      true,
+			Severity:    severity,
+			fromConfig:  true,
+			cost:        &ruleCost{},
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	kept := make([]DetectionRule, 0, len(d.rules)+len(compiled))
+	names := make(map[string]bool, len(d.rules))
+	for _, rule := range d.rules {
+		if !rule.fromConfig {
+			kept = append(kept, rule)
+			names[rule.Name] = true
+		}
+	}
+	for _, rule := range compiled {
+		if names[rule.Name] {
+			return fmt.Errorf("custom rule %s conflicts with an existing rule", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	for _, rule := range d.rules {
+		if rule.fromConfig {
+			delete(d.enabled, rule.Name)
+		}
+	}
+	for _, rule := range compiled {
+		d.enabled[rule.Name] = true
+	}
+	d.rules = append(kept, compiled...)
+
+	d.logger.Info("Custom detection rules loaded", zap.Int("count", len(compiled)))
+	return nil
+}
+
+// compileRule compiles a custom rule's pattern under the configured limits,
+// logging any lint warnings
+func (d *Detector) compileRule(name, pattern string) (*regexp.Regexp, error) {
+	compiled, warnings, err := lintPattern(pattern, d.config.RuleLimits)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		d.logger.Warn("Detection rule pattern lint", zap.String("rule", name), zap.String("warning", warning))
+	}
+	return compiled, nil
+}
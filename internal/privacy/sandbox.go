@@ -0,0 +1,209 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// lintPattern lints a user-supplied pattern against limits and compiles it.
+// Rules run on Go's RE2 engine, so matching time is linear in the input and
+// PCRE-only constructs (lookarounds, backreferences) cannot be expressed; the
+// lint rejects those with an explanation and catches patterns that are
+// valid but expensive. Warnings are returned for the caller to log, or
+// rejected when limits are strict.
+func lintPattern(pattern string, limits config.RuleLimitsConfig) (*regexp.Regexp, []string, error) {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) && pcreOnly(syntaxErr) {
+			return nil, nil, fmt.Errorf("%w (lookarounds and backreferences are not supported; rules use RE2 syntax)", err)
+		}
+		return nil, nil, err
+	}
+
+	var warnings []string
+	depth := repeatDepth(parsed)
+	if limits.MaxNesting > 0 && depth > limits.MaxNesting {
+		return nil, nil, fmt.Errorf("repetitions nested %d deep (limit %d)", depth, limits.MaxNesting)
+	}
+	if depth > 1 {
+		warnings = append(warnings, fmt.Sprintf("nested repetition (depth %d) inflates the compiled program", depth))
+	}
+
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, nil, err
+	}
+	if limits.MaxProgramSize > 0 && len(prog.Inst) > limits.MaxProgramSize {
+		return nil, nil, fmt.Errorf("pattern compiles to %d instructions (limit %d)", len(prog.Inst), limits.MaxProgramSize)
+	}
+
+	if limits.Strict && len(warnings) > 0 {
+		return nil, nil, fmt.Errorf("pattern rejected in strict mode: %s", strings.Join(warnings, "; "))
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	return compiled, warnings, nil
+}
+
+// pcreOnly reports whether a parse error comes from PCRE syntax RE2 lacks
+func pcreOnly(err *syntax.Error) bool {
+	switch err.Code {
+	case syntax.ErrInvalidPerlOp:
+		for _, prefix := range []string{"(?=", "(?!", "(?<=", "(?<!", "(?>"} {
+			if strings.HasPrefix(err.Expr, prefix) {
+				return true
+			}
+		}
+	case syntax.ErrInvalidEscape:
+		return len(err.Expr) == 2 && err.Expr[1] >= '1' && err.Expr[1] <= '9'
+	}
+	return false
+}
+
+// repeatDepth is how deeply repetitions nest in re; (a+)+ is 2. Optional
+// groups (x?, x{0,1}) do not count.
+func repeatDepth(re *syntax.Regexp) int {
+	deepest := 0
+	for _, sub := range re.Sub {
+		if d := repeatDepth(sub); d > deepest {
+			deepest = d
+		}
+	}
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return deepest + 1
+	case syntax.OpRepeat:
+		if re.Max == -1 || re.Max > 1 {
+			return deepest + 1
+		}
+	}
+	return deepest
+}
+
+// ruleCost tracks how expensive a rule is to run and whether it is
+// suspended for overrunning its time budget
+type ruleCost struct {
+	runs           atomic.Int64
+	matches        atomic.Int64
+	lastMatch      atomic.Int64 // unix nanoseconds
+	totalNanos     atomic.Int64
+	maxNanos       atomic.Int64
+	overruns       atomic.Int64
+	streak         atomic.Int64 // consecutive overruns
+	suspendedUntil atomic.Int64 // unix nanoseconds
+}
+
+// RuleCost summarizes a rule's match cost and hits since it was loaded
+type RuleCost struct {
+	Runs           int64      `json:"runs"`    // texts the rule ran against
+	Matches        int64      `json:"matches"` // occurrences found across those texts
+	AvgMicros      float64    `json:"avg_us"`
+	MaxMicros      float64    `json:"max_us"`
+	TotalMS        float64    `json:"total_ms"`
+	Overruns       int64      `json:"overruns"`
+	LastMatch      *time.Time `json:"last_match,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+}
+
+// suspended reports whether the rule is suspended at now
+func (c *ruleCost) suspended(now time.Time) bool {
+	return now.UnixNano() < c.suspendedUntil.Load()
+}
+
+// snapshot returns the cost summary reported by Rules
+func (c *ruleCost) snapshot(now time.Time) *RuleCost {
+	total := c.totalNanos.Load()
+	cost := &RuleCost{
+		Runs:      c.runs.Load(),
+		Matches:   c.matches.Load(),
+		MaxMicros: float64(c.maxNanos.Load()) / 1e3,
+		TotalMS:   float64(total) / 1e6,
+		Overruns:  c.overruns.Load(),
+	}
+	if cost.Runs > 0 {
+		cost.AvgMicros = float64(total) / float64(cost.Runs) / 1e3
+	}
+	if last := c.lastMatch.Load(); last > 0 {
+		t := time.Unix(0, last)
+		cost.LastMatch = &t
+	}
+	if until := c.suspendedUntil.Load(); now.UnixNano() < until {
+		t := time.Unix(0, until)
+		cost.SuspendedUntil = &t
+	}
+	return cost
+}
+
+// SuspendedRuleMask replaces the whole text while an active rule is
+// suspended: the rule was not run, so masking fails closed
+const SuspendedRuleMask = "[REDACTED_RULE_SUSPENDED]"
+
+// observeRule records one run of rule over textLen bytes that found matches
+// occurrences. The budget scales with the text, MaxMatchTimePerKB for every
+// KB (texts under 1KB count as one), so clients sending large prompts cannot
+// suspend a rule: matching is linear in the input, and only a rule that is
+// expensive per byte overruns. Custom rules that overrun SuspendAfter times
+// in a row are suspended for SuspendFor, during which texts they would run
+// on are redacted whole; Go's regexp cannot be interrupted mid-match, so the
+// budget is enforced between texts.
+func (d *Detector) observeRule(rule *DetectionRule, elapsed time.Duration, textLen, matches int) {
+	limits := d.config.RuleLimits
+	budget := matchBudget(limits.MaxMatchTimePerKB, textLen)
+	overrun := budget > 0 && elapsed > budget
+	metrics.ObserveRuleMatch(rule.Name, elapsed, overrun)
+
+	cost := rule.cost
+	cost.runs.Add(1)
+	if matches > 0 {
+		cost.matches.Add(int64(matches))
+		cost.lastMatch.Store(time.Now().UnixNano())
+	}
+	cost.totalNanos.Add(int64(elapsed))
+	for {
+		prev := cost.maxNanos.Load()
+		if int64(elapsed) <= prev || cost.maxNanos.CompareAndSwap(prev, int64(elapsed)) {
+			break
+		}
+	}
+
+	if !overrun {
+		cost.streak.Store(0)
+		return
+	}
+	cost.overruns.Add(1)
+	if !rule.Custom || limits.SuspendAfter <= 0 || cost.streak.Add(1) < int64(limits.SuspendAfter) {
+		return
+	}
+
+	cost.streak.Store(0)
+	cost.suspendedUntil.Store(time.Now().Add(limits.SuspendFor).UnixNano())
+	metrics.ObserveRuleSuspended(rule.Name)
+	d.logger.Warn("Detection rule suspended for exceeding its time budget",
+		zap.String("rule", rule.Name),
+		zap.Duration("elapsed", elapsed),
+		zap.Int("text_bytes", textLen),
+		zap.Duration("budget", budget),
+		zap.Duration("suspended_for", limits.SuspendFor))
+}
+
+// matchBudget is the time a rule may take over a text of textLen bytes:
+// perKB for every KB, and at least perKB
+func matchBudget(perKB time.Duration, textLen int) time.Duration {
+	if textLen <= 1024 {
+		return perKB
+	}
+	return time.Duration(float64(perKB) * float64(textLen) / 1024)
+}
@@ -0,0 +1,57 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+)
+
+// TestSuspendedRuleRedactsWholeText checks masking fails closed: while a
+// custom rule is suspended, nothing it would have masked reaches the output
+func TestSuspendedRuleRedactsWholeText(t *testing.T) {
+	cfg := config.GetDefaults().Privacy
+	cfg.Enabled = true
+	cfg.CustomRules = []config.CustomPIIRule{{Name: "employee_id", Pattern: `\bEMP-\d{6}\b`}}
+	// Every run overruns a 1ns budget, so the first one suspends the rule
+	cfg.RuleLimits.MaxMatchTimePerKB = time.Nanosecond
+	cfg.RuleLimits.SuspendAfter = 1
+	cfg.RuleLimits.SuspendFor = time.Hour
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	d, err := New(cfg, log)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	text := "Badge EMP-123456 belongs to jane@example.com"
+	first := d.ProcessText(text)
+	if strings.Contains(first.MaskedText, "EMP-123456") || !strings.Contains(first.MaskedText, "[EMPLOYEE_ID_MASKED]") {
+		t.Fatalf("first run masked %q, want the badge masked by the rule", first.MaskedText)
+	}
+
+	second := d.ProcessText(text)
+	if second.MaskedText != SuspendedRuleMask {
+		t.Fatalf("masked text while suspended = %q, want %q", second.MaskedText, SuspendedRuleMask)
+	}
+	var suspended bool
+	for _, finding := range second.Findings {
+		if finding.EntityType == "employee_id" && finding.Masked == SuspendedRuleMask {
+			suspended = true
+		}
+	}
+	if !suspended {
+		t.Fatalf("findings while suspended = %+v, want one for employee_id", second.Findings)
+	}
+
+	for _, status := range d.Rules() {
+		if status.Name == "employee_id" && (status.Cost == nil || status.Cost.SuspendedUntil == nil) {
+			t.Fatalf("employee_id not reported as suspended: %+v", status.Cost)
+		}
+	}
+}
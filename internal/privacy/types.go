@@ -1,6 +1,9 @@
 package privacy
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+)
 
 // DetectionRule represents a single PII detection rule
 type DetectionRule struct {
@@ -8,6 +11,29 @@ type DetectionRule struct {
 	Pattern     *regexp.Regexp
 	Replacement string
 	Enabled     bool
+	Custom      bool   // added at runtime rather than built in
+	Severity    string // set on custom rules
+	fromConfig  bool   // defined in privacy.custom_rules and replaced on reload
+	cost        *ruleCost
+}
+
+// RuleStatus describes a detection rule and whether it is enabled
+type RuleStatus struct {
+	Name        string `json:"name" yaml:"name"`
+	Pattern     string `json:"pattern" yaml:"pattern"`
+	Replacement string `json:"replacement" yaml:"replacement"`
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	Custom      bool   `json:"custom" yaml:"custom"`
+	Severity    string `json:"severity,omitempty" yaml:"severity,omitempty"`
+
+	Cost *RuleCost `json:"cost,omitempty" yaml:"-"` // reported by Rules
+}
+
+// RuleState is the part of the rule set the admin API changes at runtime:
+// the rules it added and every rule's enabled flag
+type RuleState struct {
+	Added   []RuleStatus    `json:"added"`
+	Enabled map[string]bool `json:"enabled"`
 }
 
 // Finding represents a detection result
@@ -15,6 +41,7 @@ type Finding struct {
 	EntityType string `json:"entityType"`
 	Masked     string `json:"masked"`
 	Count      int    `json:"count"`
+	Severity   string `json:"severity,omitempty"`
 	Positions  []int  `json:"positions,omitempty"`
 }
 
@@ -23,4 +50,10 @@ type ProcessResult struct {
 	MaskedText string    `json:"maskedText"`
 	Findings   []Finding `json:"findings"`
 	Original   string    `json:"-"` // Never serialize original text
+
+	// SuspendedRule names an active rule that was suspended and did not run,
+	// until SuspendedUntil. MaskedText is then SuspendedRuleMask and callers
+	// must not forward it.
+	SuspendedRule  string    `json:"suspendedRule,omitempty"`
+	SuspendedUntil time.Time `json:"-"`
 }
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+	"go.uber.org/zap"
+)
+
+// adminActorKey carries the name of the admin authenticated for a request
+var adminActorKey = ctxkeys.New[string]("admin_actor")
+
+// adminAuthMiddleware requires a configured admin bearer token and records
+// which admin it belongs to for audit logging
+func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if strings.EqualFold(scheme, "bearer") {
+			for name, expected := range s.cfg().Server.AdminTokens {
+				if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) == 1 {
+					next.ServeHTTP(w, r.WithContext(adminActorKey.WithValue(r.Context(), name)))
+					return
+				}
+			}
+		}
+
+		s.logger.Warn("Rejected unauthenticated admin API request",
+			zap.String("path", r.URL.Path),
+			zap.String("remote_addr", r.RemoteAddr))
+		w.Header().Set("WWW-Authenticate", `Bearer realm="llm-sentinel-admin"`)
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+	})
+}
+
+// getAdminActor returns the authenticated admin's name
+func getAdminActor(ctx context.Context) string {
+	actor, _ := adminActorKey.Value(ctx)
+	return actor
+}
+
+// handleGetChaos returns the fault injection settings currently in effect
+func (s *Server) handleGetChaos(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.chaos.Settings())
+}
+
+// handleUpdateChaos replaces the fault injection settings at runtime
+func (s *Server) handleUpdateChaos(w http.ResponseWriter, r *http.Request) {
+	var settings chaos.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid chaos settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.changeRuntimeConfig(s.configAuthor(r), "chaos update", func() error {
+		return s.chaos.Update(settings)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.logger.Warn("Chaos settings updated",
+		zap.Bool("enabled", settings.Enabled),
+		zap.Duration("latency", settings.Latency),
+		zap.Float64("error_rate", settings.ErrorRate),
+		zap.Any("targets", settings.Targets),
+		zap.Any("blackouts", settings.Blackouts))
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// handleMigrationReport returns dual-write divergence between the stores
+func (s *Server) handleMigrationReport(w http.ResponseWriter, r *http.Request) {
+	dual, ok := s.vectorStore.(*vector.DualStore)
+	if !ok {
+		http.Error(w, "Dual-write migration is not enabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, dual.Report())
+}
+
+// explainRequest is the probe for a similarity search explain. Either text
+// (embedded with the configured model) or a raw embedding must be given.
+type explainRequest struct {
+	Text      string                `json:"text"`
+	Embedding []float32             `json:"embedding"`
+	Options   *vector.SearchOptions `json:"options"`
+}
+
+// handleExplainSimilar runs a similarity search under EXPLAIN ANALYZE and
+// returns its plan and index usage
+func (s *Server) handleExplainSimilar(w http.ResponseWriter, r *http.Request) {
+	var req explainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid explain request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	probe := req.Embedding
+	if len(probe) == 0 {
+		if req.Text == "" || s.embedder == nil {
+			http.Error(w, "Explain requires an embedding or text", http.StatusBadRequest)
+			return
+		}
+		result, err := s.embedder.GenerateEmbedding(r.Context(), req.Text)
+		if err != nil {
+			http.Error(w, "Failed to embed probe text: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		probe = result.Embedding
+	}
+
+	plan, err := s.vectorStore.ExplainSimilar(r.Context(), probe, req.Options)
+	if err != nil {
+		s.logger.Error("Similarity search explain failed", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if plan.SeqScan {
+		s.logger.Warn("Similarity search is not using the vector index",
+			zap.Strings("indexes_used", plan.IndexesUsed),
+			zap.Float64("execution_ms", plan.ExecutionMs))
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// maxNeighborSample bounds the per-class sample of /admin/corpus/stats, which
+// runs one similarity search per sampled vector
+const maxNeighborSample = 1000
+
+// handleCorpusStats reports the corpus's composition: counts by label_text,
+// source and language, an ingestion timeline and nearest-neighbour distances
+// per class. Query parameters: interval (hour, day, week or month), since
+// (RFC 3339 or YYYY-MM-DD) and sample (vectors per class for the distances).
+func (s *Server) handleCorpusStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := &vector.CorpusStatsOptions{Interval: query.Get("interval")}
+
+	if since := query.Get("since"); since != "" {
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, since); err == nil {
+				options.Since = &t
+				break
+			}
+		}
+		if options.Since == nil {
+			http.Error(w, "Invalid since: use RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if sample := query.Get("sample"); sample != "" {
+		n, err := strconv.Atoi(sample)
+		if err != nil || n < 1 || n > maxNeighborSample {
+			http.Error(w, "Invalid sample: must be between 1 and "+strconv.Itoa(maxNeighborSample), http.StatusBadRequest)
+			return
+		}
+		options.NeighborSample = n
+	}
+	if options.Interval != "" && !vector.IsTimelineInterval(options.Interval) {
+		http.Error(w, "Invalid interval: must be hour, day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.vectorStore.CorpusStats(r.Context(), options)
+	if err != nil {
+		s.logger.Error("Corpus stats failed", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// chaosSettingsFromConfig converts the startup config into injector settings
+func chaosSettingsFromConfig(cfg config.ChaosConfig) chaos.Settings {
+	settings := chaos.Settings{
+		Enabled:   cfg.Enabled,
+		Latency:   cfg.Latency,
+		Jitter:    cfg.Jitter,
+		ErrorRate: cfg.ErrorRate,
+	}
+	for _, t := range cfg.Targets {
+		settings.Targets = append(settings.Targets, chaos.Dependency(t))
+	}
+	for _, b := range cfg.Blackouts {
+		settings.Blackouts = append(settings.Blackouts, chaos.Dependency(b))
+	}
+	return settings
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
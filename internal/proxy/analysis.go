@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+)
+
+// requestAnalysisKey carries the request's shared body analysis
+var requestAnalysisKey = ctxkeys.New[*requestAnalysis]("request_analysis")
+
+// requestAnalysis is what the middleware chain learns about a request body.
+// It is created by the first stage that needs the body and passed down the
+// context, so the body is read and parsed once however many stages inspect
+// it. Stages run one after another and only a concurrent analysis goroutine
+// outlives its stage, taking the prompts with it, so no lock is needed.
+type requestAnalysis struct {
+	original []byte // body as received
+	body     []byte // body to forward; the masked text once privacy has run
+
+	originalDoc jsonDocument
+	bodyDoc     jsonDocument // parsed only when body differs from original
+
+	masked   bool              // the privacy stage ran
+	findings []privacy.Finding // PII found by the privacy stage
+
+	prompts      []promptMessage // messages selected for vector analysis
+	promptsReady bool
+	fromOriginal bool // prompts were taken from the pre-masking body
+}
+
+// jsonDocument is a body parsed as a JSON object on first use
+type jsonDocument struct {
+	parsed bool
+	fields map[string]interface{} // nil when the body is not a JSON object
+}
+
+func (d *jsonDocument) get(body []byte) map[string]interface{} {
+	if !d.parsed {
+		d.parsed = true
+		if err := json.Unmarshal(body, &d.fields); err != nil {
+			d.fields = nil
+		}
+	}
+	return d.fields
+}
+
+// readRequestAnalysis returns the request's body analysis, reading the body
+// into a new one if no earlier stage has. The returned request carries the
+// analysis; its body is left unread for the next stage.
+func readRequestAnalysis(r *http.Request) (*requestAnalysis, *http.Request, error) {
+	if a, ok := requestAnalysisKey.Value(r.Context()); ok {
+		return a, r, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, r, fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body.Close()
+
+	a := &requestAnalysis{original: body, body: body}
+	r = r.WithContext(requestAnalysisKey.WithValue(r.Context(), a))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return a, r, nil
+}
+
+// getRequestAnalysis returns the request's body analysis, if a stage created one
+func getRequestAnalysis(ctx context.Context) *requestAnalysis {
+	a, _ := requestAnalysisKey.Value(ctx)
+	return a
+}
+
+// setMasked records the privacy stage's output: the masked body replaces the
+// forwarded one
+func (a *requestAnalysis) setMasked(body []byte, findings []privacy.Finding) {
+	a.body = body
+	a.masked = true
+	a.findings = findings
+	a.bodyDoc = jsonDocument{}
+}
+
+// rewritten reports whether the forwarded body differs from the original
+func (a *requestAnalysis) rewritten() bool {
+	return a.masked && !bytes.Equal(a.body, a.original)
+}
+
+// document returns the original or the forwarded body as a JSON object, or
+// nil if it is not one
+func (a *requestAnalysis) document(original bool) map[string]interface{} {
+	if original || !a.rewritten() {
+		return a.originalDoc.get(a.original)
+	}
+	return a.bodyDoc.get(a.body)
+}
+
+// selectPrompts returns the messages to analyze, extracting them on first
+// use from the original body or the forwarded one
+func (a *requestAnalysis) selectPrompts(original bool, cfg config.VectorSecurityConfig) []promptMessage {
+	if !a.promptsReady {
+		a.prompts = promptsFrom(a.document(original), cfg)
+		a.promptsReady = true
+		a.fromOriginal = original
+	}
+	return a.prompts
+}
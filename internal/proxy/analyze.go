@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"go.uber.org/zap"
+)
+
+// analyzeRequest is the body of POST /v1/analyze
+type analyzeRequest struct {
+	Text string `json:"text"`
+}
+
+// analyzeResponse is the verdict returned by /v1/analyze
+type analyzeResponse struct {
+	RequestID string           `json:"request_id"`
+	Action    string           `json:"action"`             // blocked, flagged or allowed, as the proxy would decide under the global mode
+	PII       *analyzePII      `json:"pii,omitempty"`      // omitted when privacy is disabled
+	Security  *analyzeSecurity `json:"security,omitempty"` // omitted when vector security is unavailable
+}
+
+type analyzePII struct {
+	Findings      []privacy.Finding `json:"findings"`
+	TotalFindings int               `json:"total_findings"`
+	MaskedText    string            `json:"masked_text"`
+}
+
+type analyzeSecurity struct {
+	Analyzed       bool               `json:"analyzed"`
+	AnalysisInput  string             `json:"analysis_input"` // original or masked
+	IsMalicious    bool               `json:"is_malicious"`
+	AttackType     string             `json:"attack_type,omitempty"`
+	Confidence     float32            `json:"confidence"`
+	Scores         map[string]float32 `json:"scores,omitempty"`
+	Similarity     float32            `json:"similarity"`
+	MatchedPattern string             `json:"matched_pattern,omitempty"` // corpus text the prompt matched
+	SkipReason     string             `json:"skip_reason,omitempty"`
+	AnalysisTier   string             `json:"analysis_tier,omitempty"`
+	ProcessingMS   float64            `json:"processing_ms"`
+}
+
+// handleAnalyze runs a text through PII detection and prompt analysis the
+// way the proxy pipeline would and returns the combined verdict. Nothing is
+// forwarded upstream, and no detection events or metrics are recorded, since
+// the text is not traffic.
+func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	cfg := s.cfg()
+	requestID := getRequestID(r.Context())
+	logger := s.logger.WithRequestID(requestID)
+
+	var req analyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid analyze request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" {
+		http.Error(w, "Analyze requires a text", http.StatusBadRequest)
+		return
+	}
+	if len(req.Text) > cfg.API.Analyze.MaxTextLength {
+		http.Error(w, "Text exceeds the analyze API's max_text_length", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	response := analyzeResponse{RequestID: requestID, Action: "allowed"}
+
+	// Prompt analysis sees the masked text unless configured to analyze the
+	// original, as in the proxy
+	analysisText := req.Text
+	analysisInput := "masked"
+	if cfg.Privacy.Enabled {
+		result := s.detector.ProcessText(req.Text)
+		response.PII = &analyzePII{
+			Findings:      result.Findings,
+			TotalFindings: len(result.Findings),
+			MaskedText:    result.MaskedText,
+		}
+		if cfg.Security.VectorSecurity.AnalyzeOriginal {
+			analysisInput = "original"
+		} else {
+			analysisText = result.MaskedText
+		}
+	}
+
+	if s.vectorSecurity != nil && s.vectorSecurity.IsEnabled() {
+		result := s.analyzeMessage(r.Context(), logger, analysisText)
+		if result == nil {
+			http.Error(w, "Prompt analysis failed", http.StatusServiceUnavailable)
+			return
+		}
+		response.Security = newAnalyzeSecurity(result, analysisInput)
+
+		blocked := cfg.Security.Mode == "block" && result.IsMalicious && result.Confidence >= s.vectorSecurity.GetBlockThreshold()
+		response.Action = detectionAction(result, blocked)
+	}
+
+	logger.Info("Analyze API verdict",
+		zap.String("action", response.Action),
+		zap.Bool("pii_found", response.PII != nil && response.PII.TotalFindings > 0))
+	writeJSON(w, http.StatusOK, response)
+}
+
+func newAnalyzeSecurity(result *security.SecurityResult, analysisInput string) *analyzeSecurity {
+	return &analyzeSecurity{
+		Analyzed:       result.SkipReason == "",
+		AnalysisInput:  analysisInput,
+		IsMalicious:    result.IsMalicious,
+		AttackType:     result.AttackType,
+		Confidence:     result.Confidence,
+		Scores:         result.Scores,
+		Similarity:     result.SimilarityScore,
+		MatchedPattern: result.MatchedText,
+		SkipReason:     result.SkipReason,
+		AnalysisTier:   result.AnalysisTier,
+		ProcessingMS:   float64(result.ProcessingTime) / float64(time.Millisecond),
+	}
+}
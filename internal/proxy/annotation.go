@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+)
+
+// annotationJWTHeader is the fixed JOSE header of every annotation token
+var annotationJWTHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// annotationClaims is the payload of the annotation token sent upstream
+type annotationClaims struct {
+	Issuer    string             `json:"iss"`
+	Audience  string             `json:"aud"` // provider route the request was proxied to
+	IssuedAt  int64              `json:"iat"`
+	ExpiresAt int64              `json:"exp"`
+	ID        string             `json:"jti"` // request ID, matching the proxy's logs
+	Verdict   annotationVerdict  `json:"verdict"`
+	Findings  annotationFindings `json:"findings"`
+	Policy    annotationPolicy   `json:"policy"`
+}
+
+// annotationVerdict is the prompt analysis outcome. Status is one of
+// allowed, flagged (malicious but let through by the mode), skipped,
+// pending (analysis still running alongside the upstream request) or
+// not_analyzed.
+type annotationVerdict struct {
+	Status     string             `json:"status"`
+	AttackType string             `json:"attack_type,omitempty"`
+	Confidence float32            `json:"confidence,omitempty"`
+	Scores     map[string]float32 `json:"scores,omitempty"`
+	SkipReason string             `json:"skip_reason,omitempty"`
+}
+
+// annotationFindings summarizes the PII masked in the request, without the
+// values themselves
+type annotationFindings struct {
+	Total int            `json:"total"`
+	Types map[string]int `json:"types,omitempty"` // entity type -> occurrences
+}
+
+// annotationPolicy is the security policy the request was handled under
+type annotationPolicy struct {
+	Name        string `json:"name,omitempty"` // empty for the global settings
+	Mode        string `json:"mode"`
+	Tightened   bool   `json:"tightened,omitempty"`
+	NewTemplate bool   `json:"new_template,omitempty"`
+}
+
+// annotator signs request annotations for the configured upstream routes
+type annotator struct {
+	config config.AnnotationConfig
+	key    []byte
+}
+
+func newAnnotator(cfg config.AnnotationConfig) *annotator {
+	return &annotator{config: cfg, key: []byte(cfg.SigningKey)}
+}
+
+// annotate replaces any annotation header on req, which a client could have
+// set itself, with a freshly signed one when provider receives annotations
+func (a *annotator) annotate(req *http.Request, provider string, policy ctxkeys.RequestPolicy) error {
+	req.Header.Del(a.config.Header)
+	if len(a.config.Routes) > 0 && !containsFold(a.config.Routes, provider) {
+		return nil
+	}
+
+	ctx := req.Context()
+	now := time.Now()
+	claims := annotationClaims{
+		Issuer:    a.config.Issuer,
+		Audience:  provider,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(a.config.TTL).Unix(),
+		ID:        getRequestID(ctx),
+		Verdict:   annotationVerdict{Status: "not_analyzed"},
+		Policy:    annotationPolicy{Name: policy.Name, Mode: policy.Mode, Tightened: policy.Tightened, NewTemplate: policy.NewTemplate},
+	}
+
+	if verdict, ok := ctxkeys.Verdict.Value(ctx); ok {
+		claims.Verdict = newAnnotationVerdict(verdict)
+	} else if getPendingVerdict(ctx) != nil {
+		claims.Verdict.Status = "pending"
+	}
+	if analysis := getRequestAnalysis(ctx); analysis != nil && analysis.masked {
+		claims.Findings = summarizeFindings(analysis.findings)
+	}
+
+	token, err := a.sign(claims)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(a.config.Header, token)
+	return nil
+}
+
+// sign encodes claims as an HS256 JWT
+func (a *annotator) sign(claims annotationClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode annotation: %w", err)
+	}
+	unsigned := annotationJWTHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func newAnnotationVerdict(verdict ctxkeys.AnalysisVerdict) annotationVerdict {
+	annotation := annotationVerdict{
+		Status:     "allowed",
+		Confidence: verdict.Confidence,
+		Scores:     verdict.Scores,
+		SkipReason: verdict.SkipReason,
+	}
+	switch {
+	case verdict.SkipReason != "":
+		annotation.Status = "skipped"
+	case !verdict.Analyzed:
+		annotation.Status = "not_analyzed"
+	case verdict.Malicious:
+		annotation.Status = "flagged"
+		annotation.AttackType = verdict.AttackType
+	}
+	return annotation
+}
+
+func summarizeFindings(findings []privacy.Finding) annotationFindings {
+	summary := annotationFindings{}
+	if len(findings) == 0 {
+		return summary
+	}
+
+	summary.Types = make(map[string]int, len(findings))
+	for _, finding := range findings {
+		summary.Types[finding.EntityType] += finding.Count
+		summary.Total += finding.Count
+	}
+	return summary
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/archive"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// archiveUploadTimeout bounds one object store request
+const archiveUploadTimeout = 5 * time.Minute
+
+// setupArchive spools hub events for upload to the configured archive store
+// and applies the bucket lifecycle rule when asked to
+func (s *Server) setupArchive() error {
+	cfg := s.cfg().Events.Archive
+	log := s.logger.WithComponent("event-archive").Logger
+
+	store, err := archive.OpenFromConfig(cfg.Store, archiveUploadTimeout)
+	if err != nil {
+		return err
+	}
+	if objects, ok := store.(*archive.ObjectStore); ok && cfg.Lifecycle.Apply {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := objects.ApplyLifecycle(ctx, archive.Lifecycle{
+			Prefix:              cfg.Store.Prefix,
+			TransitionAfterDays: cfg.Lifecycle.TransitionAfterDays,
+			StorageClass:        cfg.Lifecycle.StorageClass,
+			ExpireAfterDays:     cfg.Lifecycle.ExpireAfterDays,
+		})
+		cancel()
+		if err != nil {
+			log.Warn("Failed to apply archive lifecycle rule", zap.Error(err))
+		}
+	}
+
+	s.archiver, err = archive.New(store, archive.Options{
+		Dir:           cfg.Dir,
+		Interval:      cfg.Interval,
+		MaxLocalBytes: cfg.MaxLocalBytes,
+		Prefix:        cfg.Store.Prefix,
+	}, log)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[websocket.EventType]bool, len(cfg.EventTypes))
+	for _, t := range cfg.EventTypes {
+		wanted[websocket.EventType(t)] = true
+	}
+	s.wsHub.AddSink(func(event websocket.Event) {
+		if len(wanted) > 0 && !wanted[event.Type] {
+			return
+		}
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			log.Error("Failed to encode event for archive", zap.Error(err))
+			return
+		}
+		s.archiver.Append(archive.Record{
+			Time:      event.Timestamp,
+			Type:      string(event.Type),
+			RequestID: event.RequestID,
+			Data:      data,
+		})
+	})
+
+	log.Info("Event archive attached",
+		zap.String("store", cfg.Store.Type),
+		zap.String("bucket", cfg.Store.Bucket),
+		zap.String("prefix", cfg.Store.Prefix),
+		zap.Duration("interval", cfg.Interval))
+	return nil
+}
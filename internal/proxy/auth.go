@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/clientauth"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// clientAuthMiddleware requires a Sentinel-issued API key when client auth is
+// enabled and records the client it identifies for rate limiting, policy
+// selection and the request log. The key header is removed so it never
+// reaches the upstream.
+func (s *Server) clientAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := s.cfg().Security.ClientAuth
+		if !auth.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := strings.TrimSpace(r.Header.Get(auth.Header))
+		r.Header.Del(auth.Header)
+
+		logger := s.logger.WithRequestID(getRequestID(r.Context()))
+		if key == "" {
+			metrics.ObserveClientAuthFailure("missing")
+			logger.Info("Rejected request without a client API key")
+			writeClientAuthError(w, "missing_client_key",
+				fmt.Sprintf("No client API key found. Send your LLM-Sentinel API key in the %s header.", auth.Header))
+			return
+		}
+		client, ok := s.lookupClient(auth, clientauth.HashKey(key))
+		if !ok {
+			metrics.ObserveClientAuthFailure("invalid")
+			logger.Warn("Rejected request with an unknown client API key",
+				zap.String("client_ip", getClientIP(r, s.cfg().Server.TrustedProxies)))
+			writeClientAuthError(w, "invalid_client_key", "The LLM-Sentinel API key is not valid or has been revoked.")
+			return
+		}
+
+		if record := getAuditRecord(r.Context()); record != nil {
+			record.setClient(client)
+		}
+		next.ServeHTTP(w, r.WithContext(ctxkeys.Client.WithValue(r.Context(), client)))
+	})
+}
+
+// lookupClient returns the client a key hash belongs to, checking the
+// configured clients before the key database
+func (s *Server) lookupClient(auth config.ClientAuthConfig, hash string) (string, bool) {
+	for _, client := range auth.Clients {
+		if containsFold(client.KeySHA256, hash) {
+			return client.Name, true
+		}
+	}
+	if s.clientKeys != nil {
+		return s.clientKeys.Lookup(hash)
+	}
+	return "", false
+}
+
+func writeClientAuthError(w http.ResponseWriter, errType, message string) {
+	writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+		"error": map[string]string{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
+
+// upstreamAuthMiddleware rejects requests for route that carry none of its
+// expected credential headers. Without it, a missing key only surfaces as a
+// provider error after analysis and forwarding.
+func (s *Server) upstreamAuthMiddleware(route string) mux.MiddlewareFunc {
+	headers := s.cfg().Upstream.Auth.Headers[route]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.cfg().Upstream.Auth.Enabled || len(headers) == 0 || hasCredential(r, headers) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			s.logger.WithRequestID(getRequestID(r.Context())).Info("Rejected request without upstream credentials")
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error": map[string]string{
+					"type": "missing_upstream_auth",
+					"message": fmt.Sprintf("No %s credentials found. Send your provider API key in the %s header; LLM-Sentinel forwards it upstream unchanged.",
+						route, strings.Join(headers, " or ")),
+				},
+			})
+		})
+	}
+}
+
+// hasCredential reports whether any of headers carries a non-empty value. A
+// bare auth scheme such as "Bearer" with no token does not count.
+func hasCredential(r *http.Request, headers []string) bool {
+	for _, header := range headers {
+		value := strings.TrimSpace(r.Header.Get(header))
+		if scheme, token, ok := strings.Cut(value, " "); ok && strings.EqualFold(scheme, "bearer") {
+			value = strings.TrimSpace(token)
+		} else if strings.EqualFold(value, "bearer") {
+			value = ""
+		}
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
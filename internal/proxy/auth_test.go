@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/raaihank/llm-sentinel/internal/clientauth"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+)
+
+// TestClientAuthMiddleware checks requests need a configured client key,
+// which identifies the client and never reaches the upstream
+func TestClientAuthMiddleware(t *testing.T) {
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.Security.ClientAuth.Enabled = true
+		cfg.Security.ClientAuth.Header = "X-Sentinel-Key"
+		cfg.Security.ClientAuth.Clients = []config.ClientConfig{
+			{Name: "ci-bot", KeySHA256: []string{clientauth.HashKey("ci-key")}, RequestsPerMin: 600, BurstLimit: 50},
+		}
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+		wantError  string
+		wantClient string
+	}{
+		{name: "missing key", wantStatus: http.StatusUnauthorized, wantError: "missing_client_key"},
+		{name: "unknown key", key: "stolen-key", wantStatus: http.StatusUnauthorized, wantError: "invalid_client_key"},
+		{name: "configured key", key: "ci-key", wantStatus: http.StatusOK, wantClient: "ci-bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reached *http.Request
+			handler := s.clientAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = r
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/openai/v1/chat/completions", nil)
+			if tt.key != "" {
+				req.Header.Set("X-Sentinel-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantError != "" {
+				var body struct {
+					Error struct {
+						Type string `json:"type"`
+					} `json:"error"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Type != tt.wantError {
+					t.Fatalf("error body %s, want type %s", rec.Body, tt.wantError)
+				}
+				if reached != nil {
+					t.Fatal("rejected request reached the next handler")
+				}
+				return
+			}
+
+			if reached == nil {
+				t.Fatal("authenticated request did not reach the next handler")
+			}
+			if client, _ := ctxkeys.Client.Value(reached.Context()); client != tt.wantClient {
+				t.Fatalf("client %q, want %q", client, tt.wantClient)
+			}
+			if key := reached.Header.Get("X-Sentinel-Key"); key != "" {
+				t.Fatalf("client key %q forwarded past auth", key)
+			}
+			if identity := clientIdentity(reached, config.GetDefaults()); identity != "client:"+tt.wantClient {
+				t.Fatalf("client identity %q, want the authenticated client", identity)
+			}
+		})
+	}
+}
+
+// TestClientRateLimits checks authenticated clients get their configured
+// limits and others the global ones
+func TestClientRateLimits(t *testing.T) {
+	cfg := config.GetDefaults()
+	cfg.Security.ClientAuth.Clients = []config.ClientConfig{{Name: "ci-bot", RequestsPerMin: 600, BurstLimit: 50}}
+
+	if got := rateLimitFor(cfg, clientRateLimitPrefix+"ci-bot"); got.requestsPerMin != 600 || got.burst != 50 {
+		t.Fatalf("ci-bot limit %+v, want 600/min burst 50", got)
+	}
+	global := cfg.Security.RateLimit
+	if got := rateLimitFor(cfg, "203.0.113.7"); got.requestsPerMin != global.RequestsPerMin || got.burst != global.BurstLimit {
+		t.Fatalf("anonymous limit %+v, want the global %d/min burst %d", got, global.RequestsPerMin, global.BurstLimit)
+	}
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"go.uber.org/zap"
+)
+
+// handleAzureOpenAIProxy handles requests to an Azure OpenAI resource.
+// OpenAI-style paths are rewritten to the deployment serving the body's
+// model, and the configured api-version is added.
+func (s *Server) handleAzureOpenAIProxy(w http.ResponseWriter, r *http.Request) {
+	azure := s.cfg().Upstream.AzureOpenAI
+	target, err := url.Parse(azure.Endpoint)
+	if err != nil {
+		s.logger.Error("Failed to parse Azure OpenAI target URL", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove /azure-openai prefix from path
+	path := strings.TrimPrefix(r.URL.Path, "/azure-openai")
+	if path == "" {
+		path = "/"
+	}
+
+	model, err := requestModel(r)
+	if err != nil {
+		http.Error(w, "Failed to read request", http.StatusInternalServerError)
+		return
+	}
+	r.URL.Path = azureUpstreamPath(path, model, azure.Deployments)
+	r.URL.RawPath = ""
+
+	// The v1 API under /openai/v1 is unversioned
+	if !strings.HasPrefix(r.URL.Path, "/openai/v1/") {
+		query := r.URL.Query()
+		if azure.ForceAPIVersion || query.Get("api-version") == "" {
+			query.Set("api-version", azure.APIVersion)
+			r.URL.RawQuery = query.Encode()
+		}
+	}
+
+	if azure.Auth == "bearer_to_api_key" {
+		r = moveBearerToAPIKey(r)
+	}
+
+	s.logger.WithRequestID(getRequestID(r.Context())).Debug("Mapped Azure OpenAI request",
+		zap.String("model", model),
+		zap.String("upstream_path", r.URL.Path))
+
+	s.proxyRequest(w, r, target, "azure-openai")
+}
+
+// azureUpstreamPath maps a client path onto the Azure OpenAI REST layout.
+// Native paths under /openai pass through with deployment aliases resolved;
+// OpenAI-style paths (with or without /v1) go to the model's deployment, or
+// to the resource level when the request names no model.
+func azureUpstreamPath(path, model string, deployments map[string]string) string {
+	if path == "/openai" || strings.HasPrefix(path, "/openai/") {
+		rest, ok := strings.CutPrefix(path, "/openai/deployments/")
+		if !ok {
+			return path
+		}
+		name, tail, _ := strings.Cut(rest, "/")
+		if deployment, ok := deployments[name]; ok {
+			name = deployment
+		}
+		if tail == "" {
+			return "/openai/deployments/" + name
+		}
+		return "/openai/deployments/" + name + "/" + tail
+	}
+
+	rest := strings.TrimPrefix(path, "/v1")
+	if rest == "" {
+		rest = "/"
+	}
+	if model == "" {
+		return "/openai" + rest
+	}
+	deployment := model
+	if mapped, ok := deployments[model]; ok {
+		deployment = mapped
+	}
+	return "/openai/deployments/" + url.PathEscape(deployment) + rest
+}
+
+// requestModel returns the model named in a JSON request body, leaving the
+// body readable for the proxy. Other bodies name no model.
+func requestModel(r *http.Request) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var request struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &request) != nil {
+		return "", nil
+	}
+	return request.Model, nil
+}
+
+// moveBearerToAPIKey sends an OpenAI-style "Authorization: Bearer <key>" as
+// Azure's api-key header, so OpenAI SDK clients work unchanged. Requests
+// that already carry an api-key are left alone.
+func moveBearerToAPIKey(r *http.Request) *http.Request {
+	if r.Header.Get("Api-Key") != "" {
+		return r
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(token) == "" {
+		return r
+	}
+
+	rewrite := func(header http.Header) {
+		header.Del("Authorization")
+		header.Set("Api-Key", strings.TrimSpace(token))
+	}
+	rewrite(r.Header)
+
+	// The proxy restores auth headers from the originals; rewrite those too
+	if original, ok := ctxkeys.OriginalHeaders.Value(r.Context()); ok {
+		original = original.Clone()
+		rewrite(original)
+		r = r.WithContext(ctxkeys.OriginalHeaders.WithValue(r.Context(), original))
+	}
+	return r
+}
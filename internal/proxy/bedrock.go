@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/sigv4"
+	"go.uber.org/zap"
+)
+
+// bedrockService is the SigV4 signing name of the Bedrock runtime API
+const bedrockService = "bedrock"
+
+// handleBedrockProxy handles requests to the Amazon Bedrock runtime. Whatever
+// AWS authentication the client sent is replaced: requests are re-signed
+// with the proxy's own credentials.
+func (s *Server) handleBedrockProxy(w http.ResponseWriter, r *http.Request) {
+	target, err := url.Parse(s.bedrockEndpoint())
+	if err != nil {
+		s.logger.Error("Failed to parse Bedrock target URL", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove /bedrock prefix from path
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/bedrock")
+	r.URL.RawPath = ""
+	if r.URL.Path == "" {
+		r.URL.Path = "/"
+	}
+
+	s.proxyRequest(w, r, target, "bedrock")
+}
+
+// bedrockEndpoint returns the configured runtime endpoint, or the region's
+func (s *Server) bedrockEndpoint() string {
+	bedrock := s.cfg().Upstream.Bedrock
+	if bedrock.Endpoint != "" {
+		return bedrock.Endpoint
+	}
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", bedrock.Region)
+}
+
+// bedrockTransport signs requests sent through base with SigV4
+func (s *Server) bedrockTransport(base http.RoundTripper) http.RoundTripper {
+	return &sigv4.Transport{
+		Base:        base,
+		Credentials: s.bedrockCredentials,
+		Region:      s.cfg().Upstream.Bedrock.Region,
+		Service:     bedrockService,
+	}
+}
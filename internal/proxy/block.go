@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/security"
+)
+
+// writeBlockedResponse writes the client-facing response for a request
+// blocked by vector security
+func (s *Server) writeBlockedResponse(w http.ResponseWriter, r *http.Request, result *security.SecurityResult) {
+	message := fmt.Sprintf("Request blocked: %s detected (confidence: %.1f%%)", result.AttackType, result.Confidence*100)
+	if index := result.MessageIndex; index != nil && *index < 0 {
+		message += " in system prompt"
+	} else if index != nil {
+		message += fmt.Sprintf(" in message %d", *index)
+	}
+	s.writeBlockResponse(w, r, message, result.AttackType)
+}
+
+// writeBlockResponse writes a block in the configured format. The JSON
+// formats mirror the providers' error objects so SDKs raise a normal API
+// error carrying the message; attackType is omitted when empty.
+func (s *Server) writeBlockResponse(w http.ResponseWriter, r *http.Request, message, attackType string) {
+	var fields map[string]interface{}
+	if attackType != "" {
+		fields = map[string]interface{}{"attack_type": attackType}
+	}
+	s.writeErrorResponse(w, r, s.cfg().Security.BlockResponse.StatusCode, "request_blocked", "security_block", message, fields)
+}
+
+// writeErrorResponse writes a proxy-generated error in the block response
+// format. errType and code fill the OpenAI error object; the Anthropic
+// format derives its type from status. fields are added to the error object.
+func (s *Server) writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, errType, code, message string, fields map[string]interface{}) {
+	requestID := getRequestID(r.Context())
+	markUncacheable(r)
+
+	format := s.cfg().Security.BlockResponse.Format
+	if format == "auto" {
+		format = "openai"
+		if requestRoute(r) == "anthropic" {
+			format = "anthropic"
+		}
+	}
+
+	switch format {
+	case "openai":
+		errorObject := map[string]interface{}{
+			"message":    message,
+			"type":       errType,
+			"param":      nil,
+			"code":       code,
+			"request_id": requestID,
+		}
+		for name, value := range fields {
+			errorObject[name] = value
+		}
+		writeJSON(w, status, map[string]interface{}{"error": errorObject})
+	case "anthropic":
+		errorObject := map[string]interface{}{
+			"type":    anthropicErrorType(status),
+			"message": message,
+		}
+		for name, value := range fields {
+			errorObject[name] = value
+		}
+		writeJSON(w, status, map[string]interface{}{
+			"type":       "error",
+			"error":      errorObject,
+			"request_id": requestID,
+		})
+	default:
+		http.Error(w, message, status)
+	}
+}
+
+// requestRoute returns the provider route r was received on, the first
+// segment of its path
+func requestRoute(r *http.Request) string {
+	route, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	return route
+}
+
+// anthropicErrorType names the Anthropic error type SDKs expect for status
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	default:
+		return "api_error"
+	}
+}
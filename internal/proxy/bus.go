@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"github.com/raaihank/llm-sentinel/internal/events"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+)
+
+// Topics published on the server's internal event bus. In-process consumers
+// (alerting, audit, session tracking) subscribe here instead of hooking into
+// middleware.
+var (
+	TopicPIIDetection      = events.NewTopic[websocket.PIIDetectionEvent]("pii_detection")
+	TopicVectorSecurity    = events.NewTopic[websocket.VectorSecurityEvent]("vector_security")
+	TopicAnomaly           = events.NewTopic[websocket.AnomalyEvent]("anomaly")
+	TopicRequestCompletion = events.NewTopic[websocket.RequestCompletionEvent]("request_completion")
+	TopicAnalysisTier      = events.NewTopic[websocket.AnalysisTierEvent]("analysis_tier")
+	TopicDetectionAlert    = events.NewTopic[websocket.DetectionAlertEvent]("detection_alert")
+	TopicPanic             = events.NewTopic[websocket.PanicEvent]("panic")
+)
+
+// Bus returns the internal event bus
+func (s *Server) Bus() *events.Bus {
+	return s.bus
+}
+
+// emit sends an event to the WebSocket hub (and its sinks) and publishes its
+// payload on the matching bus topic
+func (s *Server) emit(event websocket.Event) {
+	s.wsHub.BroadcastEvent(event)
+
+	switch data := event.Data.(type) {
+	case websocket.PIIDetectionEvent:
+		events.Publish(s.bus, TopicPIIDetection, data)
+	case websocket.VectorSecurityEvent:
+		events.Publish(s.bus, TopicVectorSecurity, data)
+	case websocket.AnomalyEvent:
+		events.Publish(s.bus, TopicAnomaly, data)
+	case websocket.RequestCompletionEvent:
+		events.Publish(s.bus, TopicRequestCompletion, data)
+	case websocket.AnalysisTierEvent:
+		events.Publish(s.bus, TopicAnalysisTier, data)
+	case websocket.DetectionAlertEvent:
+		events.Publish(s.bus, TopicDetectionAlert, data)
+	case websocket.PanicEvent:
+		events.Publish(s.bus, TopicPanic, data)
+	}
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+	"go.uber.org/zap"
+)
+
+// canaryCheckTimeout bounds embedding the canaries at startup
+const canaryCheckTimeout = 30 * time.Second
+
+// checkCorpusModel re-embeds the canaries stored at ingest time with the
+// loaded model. A corpus built by a different model or tokenizer makes every
+// similarity score meaningless, so a mismatch fails startup when configured
+// to refuse and is otherwise logged and reported as degraded.
+func checkCorpusModel(cfg config.VectorSecurityConfig, service embeddings.EmbeddingService, store vector.Backend, degraded *degradationReport, log *logger.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), canaryCheckTimeout)
+	defer cancel()
+
+	model := embeddings.CorpusModel(cfg.Embedding.ServiceType, cfg.Embedding.Model.ModelName)
+	references, err := store.Canaries(ctx)
+	if err != nil {
+		log.Warn("Failed to load corpus canaries; skipping model consistency check", zap.Error(err))
+		return nil
+	}
+	if len(references) == 0 {
+		log.Info("Corpus has no canary embeddings; re-run llm-sentinel-etl to enable the model consistency check")
+		return nil
+	}
+
+	report, err := embeddings.CheckCanaries(ctx, service, references)
+	if err != nil {
+		log.Warn("Failed to embed corpus canaries; skipping model consistency check", zap.Error(err))
+		return nil
+	}
+	if report.MinSimilarity >= cfg.CanaryCheck.MinSimilarity {
+		log.Info("Loaded embedding model matches corpus",
+			zap.String("model", model),
+			zap.Int("canaries", report.Checked),
+			zap.Float32("min_similarity", report.MinSimilarity))
+		return nil
+	}
+
+	if cfg.CanaryCheck.OnMismatch == "refuse" {
+		return fmt.Errorf("loaded embedding model %s does not match the corpus (built with %s): canary similarity %.4f below %.4f",
+			model, report.ReferenceModel, report.MinSimilarity, cfg.CanaryCheck.MinSimilarity)
+	}
+
+	log.Error("LOADED EMBEDDING MODEL DOES NOT MATCH THE CORPUS; similarity scores are unreliable until the corpus is re-ingested",
+		zap.String("model", model),
+		zap.String("corpus_model", report.ReferenceModel),
+		zap.Float32("min_similarity", report.MinSimilarity),
+		zap.Float32("required_similarity", cfg.CanaryCheck.MinSimilarity),
+		zap.String("worst_canary", report.WorstText))
+	degraded.mark(subsystemCorpusModel, "mismatched",
+		fmt.Sprintf("model %s differs from corpus model %s", model, report.ReferenceModel), nil)
+	return nil
+}
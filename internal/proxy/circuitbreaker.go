@@ -0,0 +1,249 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// breakerState is the state of an upstream circuit breaker. The values are
+// those of the upstream_circuit_state gauge.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half_open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitOpenError is returned for requests an open breaker refuses
+type circuitOpenError struct {
+	upstream   string
+	retryAfter time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for upstream %s", e.upstream)
+}
+
+// circuitBreaker guards one upstream
+type circuitBreaker struct {
+	name string
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int // consecutive, while closed
+	openedAt time.Time
+	trials   int // in flight, while half-open
+}
+
+// breakerChange describes a breaker moving between states
+type breakerChange struct {
+	breaker  *circuitBreaker
+	from, to breakerState
+	failures int
+}
+
+// allow reports whether a request may go to the upstream, and otherwise how
+// long until the breaker lets trial requests through
+func (b *circuitBreaker) allow(cfg config.CircuitBreakerConfig) (bool, time.Duration, *breakerChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var change *breakerChange
+	if b.state == breakerOpen {
+		if wait := cfg.OpenDuration - time.Since(b.openedAt); wait > 0 {
+			return false, wait, nil
+		}
+		change = b.transition(breakerHalfOpen)
+		b.trials = 0
+	}
+	if b.state == breakerHalfOpen {
+		if b.trials >= cfg.HalfOpenRequests {
+			// Trials are still out; wait for their outcome
+			return false, time.Second, change
+		}
+		b.trials++
+	}
+	return true, 0, change
+}
+
+// record counts the outcome of an allowed request. Cancelled requests say
+// nothing about the upstream and only release their trial slot.
+func (b *circuitBreaker) record(cfg config.CircuitBreakerConfig, failed, cancelled bool) *breakerChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerClosed:
+		if cancelled {
+			return nil
+		}
+		if !failed {
+			b.failures = 0
+			return nil
+		}
+		b.failures++
+		if b.failures < cfg.FailureThreshold {
+			return nil
+		}
+		return b.open()
+	case breakerHalfOpen:
+		b.trials--
+		if cancelled {
+			return nil
+		}
+		if failed {
+			b.failures = 1
+			return b.open()
+		}
+		b.failures = 0
+		return b.transition(breakerClosed)
+	}
+	// Requests allowed before the breaker opened
+	return nil
+}
+
+// open and transition change state; caller holds mu
+func (b *circuitBreaker) open() *breakerChange {
+	b.openedAt = time.Now()
+	return b.transition(breakerOpen)
+}
+
+func (b *circuitBreaker) transition(to breakerState) *breakerChange {
+	change := &breakerChange{breaker: b, from: b.state, to: to, failures: b.failures}
+	b.state = to
+	return change
+}
+
+// breakerSet holds the circuit breakers of all upstreams, created on first
+// use. Thresholds are read from the live config on every request.
+type breakerSet struct {
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newBreakerSet() *breakerSet {
+	return &breakerSet{breakers: make(map[string]*circuitBreaker)}
+}
+
+// get returns the breaker of upstream
+func (b *breakerSet) get(upstream string) *circuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[upstream]
+	if !ok {
+		breaker = &circuitBreaker{name: upstream}
+		b.breakers[upstream] = breaker
+		metrics.SetCircuitState(upstream, int(breakerClosed))
+	}
+	return breaker
+}
+
+// upstreamBreaker returns the breaker guarding requests to target on route,
+// or nil when the route is not guarded. Failover targets are guarded
+// separately, so one failing target does not shut the route.
+func (s *Server) upstreamBreaker(route string, target *failoverTarget) *circuitBreaker {
+	cb := s.cfg().Upstream.CircuitBreaker
+	if !cb.Enabled || (len(cb.Routes) > 0 && !slices.Contains(cb.Routes, route)) {
+		return nil
+	}
+	upstream := route
+	if target != nil {
+		upstream += "/" + target.name
+	}
+	return s.breakers.get(upstream)
+}
+
+// breakerTransport refuses requests while its breaker is open and records
+// the outcome of the others. Only 5xx and connection failures count: a 429
+// usually reflects the caller's own quota rather than the upstream's health.
+type breakerTransport struct {
+	inner   http.RoundTripper
+	breaker *circuitBreaker
+	server  *Server
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := t.server.cfg().Upstream.CircuitBreaker
+	allowed, retryAfter, change := t.breaker.allow(cfg)
+	t.server.handleBreakerChange(change)
+	if !allowed {
+		metrics.ObserveCircuitRejection(t.breaker.name)
+		return nil, &circuitOpenError{upstream: t.breaker.name, retryAfter: retryAfter}
+	}
+
+	resp, err := t.inner.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	t.server.handleBreakerChange(t.breaker.record(cfg, failed, req.Context().Err() != nil))
+	return resp, err
+}
+
+// writeCircuitOpen answers a request refused by an open breaker
+func (s *Server) writeCircuitOpen(w http.ResponseWriter, r *http.Request, err *circuitOpenError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.retryAfter.Seconds()))))
+	s.writeErrorResponse(w, r, http.StatusServiceUnavailable, "upstream_unavailable", "circuit_open",
+		fmt.Sprintf("Upstream %s is failing; requests are paused while its circuit breaker is open", err.upstream), nil)
+}
+
+// handleBreakerChange logs, records and broadcasts a breaker state change,
+// and reports breakers that are not closed as degraded
+func (s *Server) handleBreakerChange(change *breakerChange) {
+	if change == nil {
+		return
+	}
+	name := change.breaker.name
+	log := s.logger.Warn
+	if change.to != breakerOpen {
+		log = s.logger.Info
+	}
+	log("Upstream circuit breaker changed",
+		zap.String("upstream", name),
+		zap.Stringer("from", change.from),
+		zap.Stringer("to", change.to),
+		zap.Int("failures", change.failures))
+
+	metrics.SetCircuitState(name, int(change.to))
+
+	subsystem := "circuit " + name
+	switch change.to {
+	case breakerOpen:
+		s.degraded.mark(subsystem, "open", fmt.Sprintf("%d consecutive upstream failures", change.failures), nil)
+	case breakerHalfOpen:
+		s.degraded.mark(subsystem, "half-open", "probing upstream after failures", nil)
+	default:
+		s.degraded.clear(subsystem)
+	}
+
+	s.emit(websocket.Event{
+		Type:      websocket.EventTypeSystemStatus,
+		Timestamp: time.Now(),
+		Data: websocket.CircuitBreakerEvent{
+			Upstream: name,
+			From:     change.from.String(),
+			To:       change.to.String(),
+			Failures: change.failures,
+		},
+	})
+}
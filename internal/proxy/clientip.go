@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+)
+
+// getClientIP extracts the client IP from the request in canonical form,
+// with any port stripped and IPv4-mapped IPv6 addresses unmapped. Forwarding
+// headers are only believed from trusted proxies: when the peer is one, the
+// client is the right-most X-Forwarded-For hop that is not, or X-Real-IP
+// without X-Forwarded-For. Otherwise the peer itself is the client.
+func getClientIP(r *http.Request, trustedProxies []string) string {
+	peer, ok := parseClientAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !isTrustedProxy(peer, trustedProxies) {
+		return peer
+	}
+
+	// Walk X-Forwarded-For from the right, past the proxies we trust; each
+	// of them appended the address it received the request from
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, ok := parseClientAddr(hops[i])
+			if !ok {
+				break
+			}
+			client = ip
+			if !isTrustedProxy(ip, trustedProxies) {
+				break
+			}
+		}
+		return client
+	}
+
+	// Check X-Real-IP header
+	if ip, ok := parseClientAddr(r.Header.Get("X-Real-IP")); ok {
+		return ip
+	}
+	return peer
+}
+
+// isTrustedProxy reports whether a canonical IP falls in one of the trusted
+// proxy CIDRs or addresses
+func isTrustedProxy(ip string, trustedProxies []string) bool {
+	if len(trustedProxies) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, trusted := range trustedProxies {
+		if prefix, err := netip.ParsePrefix(trusted); err == nil && prefix.Contains(addr) {
+			return true
+		}
+		if proxy, err := netip.ParseAddr(trusted); err == nil && proxy.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClientAddr normalizes "ip", "ip:port", "[ipv6]:port" and zoned forms
+func parseClientAddr(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	raw = strings.TrimSuffix(strings.TrimPrefix(raw, "["), "]")
+
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().WithZone("").String(), true
+}
+
+// clientKey aggregates a canonical client IP to its rate limiting prefix.
+// Prefix lengths outside the valid range fall back to the full address.
+func clientKey(ip string, ipv4Prefix, ipv6Prefix int) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	bits := ipv6Prefix
+	if addr.Is4() {
+		bits = ipv4Prefix
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return addr.String()
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
+
+// apiKeyHeaders carry provider API keys, checked in order
+var apiKeyHeaders = []string{"Authorization", "X-Api-Key", "Api-Key"}
+
+// clientIdentity identifies the caller for behavioral baselining: the
+// authenticated client, else a hash of the API key when one is present,
+// otherwise the client IP aggregated to its rate limiting prefix
+func clientIdentity(r *http.Request, cfg *config.Config) string {
+	if client, ok := ctxkeys.Client.Value(r.Context()); ok {
+		return "client:" + client
+	}
+	if key, ok := apiKeyIdentity(r); ok {
+		return key
+	}
+	rl := cfg.Security.RateLimit
+	return "ip:" + clientKey(getClientIP(r, cfg.Server.TrustedProxies), rl.IPv4Prefix, rl.IPv6Prefix)
+}
+
+// apiKeyIdentity returns a hash of the provider API key the request
+// carries, if any
+func apiKeyIdentity(r *http.Request) (string, bool) {
+	for _, header := range apiKeyHeaders {
+		if key := r.Header.Get(header); key != "" {
+			sum := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(sum[:8]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGetClientIP checks forwarding headers are only believed from trusted
+// proxies and that spoofed left-most hops are skipped
+func TestGetClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.0.2.7"}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		realIP     string
+		trusted    []string
+		want       string
+	}{
+		{"no proxies trusted", "203.0.113.9:4000", []string{"198.51.100.1"}, "198.51.100.2", nil, "203.0.113.9"},
+		{"untrusted peer", "203.0.113.9:4000", []string{"198.51.100.1"}, "", trusted, "203.0.113.9"},
+		{"trusted peer", "10.1.2.3:4000", []string{"198.51.100.1"}, "", trusted, "198.51.100.1"},
+		{"spoofed left-most hop", "10.1.2.3:4000", []string{"1.1.1.1, 198.51.100.1"}, "", trusted, "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:4000", []string{"198.51.100.1, 192.0.2.7", "10.9.9.9"}, "", trusted, "198.51.100.1"},
+		{"all hops trusted", "10.1.2.3:4000", []string{"10.4.4.4"}, "", trusted, "10.4.4.4"},
+		{"malformed hop", "10.1.2.3:4000", []string{"198.51.100.1, bogus"}, "", trusted, "10.1.2.3"},
+		{"real ip from trusted peer", "[::ffff:10.1.2.3]:4000", nil, "198.51.100.2", trusted, "198.51.100.2"},
+		{"ipv6 peer", "[2001:db8::1]:4000", nil, "", trusted, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/openai/v1/models", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, xff := range tt.xff {
+				r.Header.Add("X-Forwarded-For", xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := getClientIP(r, tt.trusted); got != tt.want {
+				t.Fatalf("getClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/confighistory"
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"go.uber.org/zap"
+)
+
+// runtimeConfig is the configuration the admin API can change without a
+// restart, as captured in each config history snapshot
+type runtimeConfig struct {
+	Mode           ModeSettings             `json:"mode"`
+	Chaos          *chaos.Settings          `json:"chaos,omitempty"` // nil unless fault injection is enabled
+	PIIRules       privacy.RuleState        `json:"pii_rules"`
+	AttackPatterns []security.AttackPattern `json:"attack_patterns"`
+}
+
+// captureRuntimeConfig returns the runtime configuration in effect
+func (s *Server) captureRuntimeConfig() runtimeConfig {
+	cfg := runtimeConfig{
+		Mode:           s.currentMode(),
+		PIIRules:       s.detector.RuleState(),
+		AttackPatterns: security.Patterns().List(),
+	}
+	if s.chaos != nil {
+		settings := s.chaos.Settings()
+		cfg.Chaos = &settings
+	}
+	return cfg
+}
+
+// applyRuntimeConfig puts cfg into effect. Everything is validated first
+// and the rules are put back if the patterns fail, so a failed apply
+// changes nothing.
+func (s *Server) applyRuntimeConfig(cfg runtimeConfig) error {
+	if cfg.Mode.RetryAfter < 0 {
+		return fmt.Errorf("invalid retry after: %v (must not be negative)", cfg.Mode.RetryAfter)
+	}
+	if cfg.Chaos != nil && s.chaos != nil {
+		if err := cfg.Chaos.Validate(); err != nil {
+			return err
+		}
+	}
+
+	previous := s.detector.RuleState()
+	if err := s.detector.RestoreRuleState(cfg.PIIRules); err != nil {
+		return err
+	}
+	if err := security.Patterns().Restore(cfg.AttackPatterns); err != nil {
+		if undoErr := s.detector.RestoreRuleState(previous); undoErr != nil {
+			s.logger.Error("Failed to restore PII rules after a failed config apply", zap.Error(undoErr))
+		}
+		return err
+	}
+
+	if cfg.Chaos != nil && s.chaos != nil {
+		// Validated above
+		_ = s.chaos.Update(*cfg.Chaos)
+	}
+	s.modeMu.Lock()
+	s.mode = cfg.Mode
+	s.modeMu.Unlock()
+	return nil
+}
+
+// changeRuntimeConfig runs apply and, when config history is enabled,
+// records the resulting configuration as a new version. Changes are
+// serialized so each version's diff covers exactly one change.
+func (s *Server) changeRuntimeConfig(author, action string, apply func() error) error {
+	if s.history == nil {
+		return apply()
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	if err := apply(); err != nil {
+		return err
+	}
+	s.recordConfigSnapshot(author, action)
+	return nil
+}
+
+// recordConfigSnapshot stores the runtime configuration in effect. Failures
+// are logged; the change itself has already been applied.
+func (s *Server) recordConfigSnapshot(author, action string) {
+	snapshot, err := s.history.Record(author, action, s.captureRuntimeConfig())
+	if err != nil {
+		s.logger.Error("Failed to record config snapshot", zap.String("action", action), zap.Error(err))
+		return
+	}
+	if snapshot != nil {
+		s.logger.Info("Config snapshot recorded",
+			zap.Int("version", snapshot.Version),
+			zap.String("author", author),
+			zap.String("action", action),
+			zap.Int("changes", len(snapshot.Diff)))
+	}
+}
+
+// configAuthor names who made a change through r: the authenticated admin,
+// or the client address for routes without admin auth
+func (s *Server) configAuthor(r *http.Request) string {
+	if actor := getAdminActor(r.Context()); actor != "" {
+		return actor
+	}
+	return "anonymous (" + getClientIP(r, s.cfg().Server.TrustedProxies) + ")"
+}
+
+// setupConfigHistoryAPI mounts version listing and rollback under
+// /admin/api/config
+func (s *Server) setupConfigHistoryAPI() {
+	api := s.router.PathPrefix("/admin/api/config").Subrouter()
+	api.Use(s.adminAuthMiddleware)
+
+	api.HandleFunc("/versions", s.handleListConfigVersions).Methods("GET")
+	api.HandleFunc("/versions/{version:[0-9]+}", s.handleGetConfigVersion).Methods("GET")
+	api.HandleFunc("/versions/{version:[0-9]+}/rollback", s.handleRollbackConfig).Methods("POST")
+}
+
+// handleListConfigVersions returns every kept version with its author and
+// diff, newest first
+func (s *Server) handleListConfigVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := s.history.List()
+	if err != nil {
+		s.logger.Error("Failed to list config versions", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// handleGetConfigVersion returns one version including its configuration
+func (s *Server) handleGetConfigVersion(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := s.getConfigVersion(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleRollbackConfig restores a previous version. The rollback is itself
+// recorded as a new version.
+func (s *Server) handleRollbackConfig(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := s.getConfigVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var target runtimeConfig
+	if err := json.Unmarshal(snapshot.Config, &target); err != nil {
+		http.Error(w, fmt.Sprintf("Malformed config snapshot %d: %v", snapshot.Version, err), http.StatusInternalServerError)
+		return
+	}
+
+	author := s.configAuthor(r)
+	action := fmt.Sprintf("rollback to version %d", snapshot.Version)
+	if err := s.changeRuntimeConfig(author, action, func() error { return s.applyRuntimeConfig(target) }); err != nil {
+		http.Error(w, "Rollback failed: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	s.logger.Warn("Runtime config rolled back",
+		zap.String("actor", author),
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.Int("version", snapshot.Version))
+
+	writeJSON(w, http.StatusOK, s.captureRuntimeConfig())
+}
+
+// getConfigVersion loads the version named in the path, writing the error
+// response when it is missing or fails verification
+func (s *Server) getConfigVersion(w http.ResponseWriter, r *http.Request) (*confighistory.Snapshot, bool) {
+	version, _ := strconv.Atoi(mux.Vars(r)["version"])
+	snapshot, err := s.history.Get(version)
+	switch {
+	case errors.Is(err, confighistory.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	case errors.Is(err, confighistory.ErrSignature):
+		s.logger.Warn("Config snapshot failed signature verification", zap.Int("version", version))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil, false
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return snapshot, true
+}
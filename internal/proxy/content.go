@@ -0,0 +1,50 @@
+package proxy
+
+import "strings"
+
+// normalizeContent flattens a message's content into analyzable text. It
+// accepts plain strings and the structured part arrays OpenAI, Anthropic and
+// Gemini send for multimodal input: text parts are joined in order, tool
+// results are flattened recursively, and parts without text (images, audio,
+// files) are dropped.
+func normalizeContent(content interface{}) string {
+	var texts []string
+	appendContentText(content, &texts)
+	return strings.Join(texts, "\n")
+}
+
+func appendContentText(content interface{}, texts *[]string) {
+	switch c := content.(type) {
+	case string:
+		if c != "" {
+			*texts = append(*texts, c)
+		}
+	case []interface{}:
+		for _, part := range c {
+			appendContentText(part, texts)
+		}
+	case map[string]interface{}:
+		switch c["type"] {
+		case "tool_result":
+			// Anthropic tool output: a string or nested parts
+			appendContentText(c["content"], texts)
+		case "document":
+			// Anthropic documents; only plain-text and content sources
+			// carry text, base64 PDFs and URLs are dropped
+			if source, ok := c["source"].(map[string]interface{}); ok {
+				switch source["type"] {
+				case "text":
+					appendContentText(source["data"], texts)
+				case "content":
+					appendContentText(source["content"], texts)
+				}
+			}
+		default:
+			// text, input_text and output_text parts; typed media parts
+			// carry no text field and contribute nothing
+			if text, ok := c["text"].(string); ok && text != "" {
+				*texts = append(*texts, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/jsonpath"
+	"go.uber.org/zap"
+)
+
+// conversationExtractor finds a conversation/thread identifier in a request
+type conversationExtractor struct {
+	headers []string
+	paths   []*jsonpath.Path
+}
+
+func newConversationExtractor(cfg config.ConversationConfig) (*conversationExtractor, error) {
+	e := &conversationExtractor{headers: cfg.Headers}
+	for _, expr := range cfg.BodyPaths {
+		path, err := jsonpath.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conversation body path: %w", err)
+		}
+		e.paths = append(e.paths, path)
+	}
+	return e, nil
+}
+
+// extract checks the configured headers, then the configured paths in the
+// parsed request body
+func (e *conversationExtractor) extract(r *http.Request, doc map[string]interface{}) string {
+	for _, header := range e.headers {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+
+	if len(e.paths) == 0 || doc == nil {
+		return ""
+	}
+	for _, path := range e.paths {
+		if id, ok := path.LookupString(doc); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// conversationMiddleware tags requests with their conversation and holds
+// conversations flagged for review
+func (s *Server) conversationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.conversations == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		analysis, r, err := readRequestAnalysis(r)
+		if err != nil {
+			s.logger.WithRequestID(getRequestID(r.Context())).Error("Failed to read request body for conversation lookup", zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		conversationID := s.conversationExtractor.extract(r, analysis.document(false))
+		if conversationID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := clientIdentity(r, s.cfg())
+		if s.conversations.RequiresReview(client, conversationID) {
+			s.logger.WithRequestID(getRequestID(r.Context())).Warn("Holding message from conversation pending review",
+				zap.String("client_key", client),
+				zap.String("conversation_id", conversationID))
+			s.writeBlockResponse(w, r, "Conversation held for security review", "")
+			return
+		}
+
+		ctx := ctxkeys.ConversationID.WithValue(r.Context(), conversationID)
+		ctx = conversationClientKey.WithValue(ctx, client)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// conversationClientKey carries the client a request's conversation is
+// scoped to
+var conversationClientKey = ctxkeys.New[string]("conversation_client")
+
+// getConversation extracts the conversation ID, and the client it belongs
+// to, from context
+func getConversation(ctx context.Context) (client, id string) {
+	id, _ = ctxkeys.ConversationID.Value(ctx)
+	client, _ = conversationClientKey.Value(ctx)
+	return client, id
+}
+
+// handleListConversations lists conversations with blocked messages
+func (s *Server) handleListConversations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.conversations.Flagged())
+}
+
+// conversationClient reads the ?client= a conversation endpoint is scoped
+// to, as listed by /admin/conversations
+func conversationClient(w http.ResponseWriter, r *http.Request) (string, bool) {
+	client := r.URL.Query().Get("client")
+	if client == "" {
+		http.Error(w, "client query parameter is required", http.StatusBadRequest)
+		return "", false
+	}
+	return client, true
+}
+
+// handleGetConversation returns one conversation's security state
+func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request) {
+	client, ok := conversationClient(w, r)
+	if !ok {
+		return
+	}
+	state, ok := s.conversations.Get(client, mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// handleReviewConversation clears a conversation held for review
+func (s *Server) handleReviewConversation(w http.ResponseWriter, r *http.Request) {
+	client, ok := conversationClient(w, r)
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !s.conversations.ClearReview(client, id) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	s.logger.Info("Conversation review cleared",
+		zap.String("client_key", client),
+		zap.String("conversation_id", id),
+		zap.String("actor", getAdminActor(r.Context())))
+	state, _ := s.conversations.Get(client, id)
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// corsHandler adds CORS headers to non-proxy API routes and answers their
+// preflight requests. It wraps the whole router because preflight OPTIONS
+// requests match no route method and would otherwise get a 405. Provider
+// routes are passed through untouched.
+func (s *Server) corsHandler(next http.Handler) http.Handler {
+	cors := s.cfg().CORS
+	if !cors.Enabled {
+		return next
+	}
+
+	methods := strings.Join(cors.AllowedMethods, ", ")
+	headers := strings.Join(cors.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cors.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || s.isProviderPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := s.corsOrigin(origin)
+		if allowed == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		if cors.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Preflight
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsOrigin returns the Access-Control-Allow-Origin value for origin, or ""
+// if it is not allowed
+func (s *Server) corsOrigin(origin string) string {
+	for _, allowed := range s.cfg().CORS.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// isProviderPath reports whether path belongs to a provider proxy route
+func (s *Server) isProviderPath(path string) bool {
+	for route := range s.pipelines {
+		if path == "/"+route || strings.HasPrefix(path, "/"+route+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"github.com/raaihank/llm-sentinel/internal/usage"
+	"go.uber.org/zap"
+)
+
+// costMiddleware estimates what a provider request will cost and enforces
+// the per-request cap before it is forwarded. The estimate is recorded in
+// the request's audit record, where the cost of the usage the provider
+// reports is added once the response has been read.
+func (s *Server) costMiddleware(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cost := s.cfg().Cost
+			if !cost.Enabled || (len(cost.Routes) > 0 && !slices.Contains(cost.Routes, route)) ||
+				r.Body == nil || r.Body == http.NoBody || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			analysis, r, err := readRequestAnalysis(r)
+			if err != nil {
+				http.Error(w, "Failed to read request", http.StatusInternalServerError)
+				return
+			}
+			upstreamPath := strings.TrimPrefix(r.URL.Path, "/"+route)
+			estimate, ok := usage.EstimateRequest(analysis.body, upstreamPath, cost.CharsPerToken, cost.DefaultCompletionTokens)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger := s.logger.WithRequestID(getRequestID(r.Context()))
+			price, ok := modelPrice(cost.Pricing, estimate.Model)
+			if !ok {
+				logger.Debug("No price for model, cost not estimated", zap.String("route", route), zap.String("model", estimate.Model))
+				next.ServeHTTP(w, r)
+				return
+			}
+			estimate.CostUSD = price.usd(estimate.PromptTokens, estimate.MaxCompletionTokens)
+			if record := getAuditRecord(r.Context()); record != nil {
+				record.setCostEstimate(estimate, price)
+			}
+
+			if cost.MaxRequestUSD > 0 && estimate.CostUSD > cost.MaxRequestUSD {
+				metrics.ObserveCostCapExceeded(route, cost.Action)
+				logger.Warn("Estimated request cost exceeds cap",
+					zap.String("route", route),
+					zap.String("model", estimate.Model),
+					zap.Int("prompt_tokens", estimate.PromptTokens),
+					zap.Int("max_completion_tokens", estimate.MaxCompletionTokens),
+					zap.Float64("estimated_cost_usd", estimate.CostUSD),
+					zap.Float64("max_request_usd", cost.MaxRequestUSD),
+					zap.String("action", cost.Action))
+				if cost.Action == "reject" {
+					s.writeErrorResponse(w, r, http.StatusBadRequest, "invalid_request_error", "cost_limit_exceeded",
+						fmt.Sprintf("Estimated request cost $%.4f exceeds the $%.4f limit; lower max_tokens or shorten the prompt", estimate.CostUSD, cost.MaxRequestUSD),
+						map[string]interface{}{"estimated_cost_usd": estimate.CostUSD, "max_request_usd": cost.MaxRequestUSD})
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// modelPrice returns the price configured for model: an exact entry, or
+// else the longest entry the model name starts with, so gpt-4o prices
+// gpt-4o-2024-08-06 while gpt-4o-mini keeps its own
+func modelPrice(pricing map[string]config.ModelPrice, model string) (modelPricing, bool) {
+	if model == "" {
+		return modelPricing{}, false
+	}
+	if price, ok := pricing[model]; ok {
+		return modelPricing(price), true
+	}
+	best := ""
+	for prefix := range pricing {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return modelPricing{}, false
+	}
+	return modelPricing(pricing[best]), true
+}
+
+// modelPricing is a model's price in USD per million input and output tokens
+type modelPricing config.ModelPrice
+
+func (p modelPricing) usd(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
+	"go.uber.org/zap"
+)
+
+// dedupHeader marks a response replayed from an identical in-flight request
+const dedupHeader = "X-Sentinel-Deduplicated"
+
+// dedupEntry is one in-flight (or just completed) request and its response
+type dedupEntry struct {
+	done chan struct{}
+
+	// Set by the leader before done is closed
+	status     int
+	header     http.Header
+	body       []byte
+	replayable bool
+}
+
+// dedupGroup tracks identical requests so that followers share the leader's
+// upstream response instead of sending their own
+type dedupGroup struct {
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+func newDedupGroup() *dedupGroup {
+	return &dedupGroup{entries: make(map[string]*dedupEntry)}
+}
+
+// join returns the entry for key and whether the caller is its leader
+func (g *dedupGroup) join(key string) (*dedupEntry, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if entry, ok := g.entries[key]; ok {
+		return entry, false
+	}
+	entry := &dedupEntry{done: make(chan struct{})}
+	g.entries[key] = entry
+	return entry, true
+}
+
+// expire forgets entry once the dedup window has passed
+func (g *dedupGroup) expire(key string, entry *dedupEntry, window time.Duration) {
+	time.AfterFunc(window, func() {
+		g.mu.Lock()
+		if g.entries[key] == entry {
+			delete(g.entries, key)
+		}
+		g.mu.Unlock()
+	})
+}
+
+// dedupMiddleware absorbs accidental double-submits: identical requests from
+// the same client (same method, path and body hash) that arrive while the
+// first is in flight, or within the window after it completes, receive the
+// first request's response instead of reaching upstream again. Streamed
+// responses are not deduplicated: a follower would otherwise see nothing
+// until the leader's whole generation had finished.
+func (s *Server) dedupMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.dedup == nil || r.Body == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || isStreaming(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		analysis, r, err := readRequestAnalysis(r)
+		if err != nil {
+			http.Error(w, "Failed to read request", http.StatusInternalServerError)
+			return
+		}
+
+		key := s.dedupKey(r, analysis.body)
+		entry, leader := s.dedup.join(key)
+
+		if !leader {
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+
+			if entry.replayable {
+				s.logger.WithRequestID(getRequestID(r.Context())).Info("Replaying response for duplicate request")
+				for name, values := range entry.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(dedupHeader, "true")
+				w.WriteHeader(entry.status)
+				w.Write(entry.body)
+				return
+			}
+
+			// The leader's response was too large to keep; send our own
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &dedupRecorder{ResponseWriter: w, status: http.StatusOK, limit: s.cfg().Dedup.MaxResponseBytes}
+		defer func() {
+			entry.status = recorder.status
+			entry.header = w.Header().Clone()
+			entry.body = recorder.buf.Bytes()
+			entry.replayable = !recorder.overflowed
+			close(entry.done)
+			s.dedup.expire(key, entry, s.cfg().Dedup.Window)
+
+			if recorder.overflowed {
+				s.logger.WithRequestID(getRequestID(r.Context())).Debug("Response too large to replay for duplicates",
+					zap.Int("limit", recorder.limit))
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// dedupKey identifies a request by client, method, path and body content.
+// JSON bodies are canonicalized first so key order and formatting do not
+// matter.
+func (s *Server) dedupKey(r *http.Request, body []byte) string {
+	sum := sha256.Sum256(textnorm.CanonicalJSON(body))
+	return clientIdentity(r, s.cfg()) + "|" + r.Method + "|" + r.URL.Path + "|" + hex.EncodeToString(sum[:])
+}
+
+// dedupRecorder passes a response through while keeping a bounded copy
+type dedupRecorder struct {
+	http.ResponseWriter
+	status     int
+	buf        bytes.Buffer
+	limit      int
+	overflowed bool
+}
+
+func (rec *dedupRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *dedupRecorder) Write(b []byte) (int, error) {
+	if !rec.overflowed {
+		if rec.buf.Len()+len(b) > rec.limit {
+			rec.overflowed = true
+			rec.buf = bytes.Buffer{}
+		} else {
+			rec.buf.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Flush keeps streamed responses flowing to the leader's client
+func (rec *dedupRecorder) Flush() {
+	_ = http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rec *dedupRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// analysisTiers builds the degradation ladder below the configured analyzer:
+// ml falls back to pattern embeddings, and everything falls back to keyword
+// matching without embeddings
+func analysisTiers(
+	factory *embeddings.Factory,
+	serviceConfig embeddings.ServiceConfig,
+	primary security.VectorSecurityAnalyzer,
+	vsConfig *config.VectorSecurityConfig,
+	log *logger.Logger,
+) []security.Tier {
+	tiers := []security.Tier{{Name: string(serviceConfig.Type), Analyzer: primary}}
+
+	if serviceConfig.Type == embeddings.MLEmbedding {
+		patternConfig := serviceConfig
+		patternConfig.Type = embeddings.PatternEmbedding
+		patternConfig.RedisEnabled = false
+		patternService, err := factory.CreateService(patternConfig)
+		if err != nil {
+			log.Warn("Pattern analysis tier unavailable", zap.Error(err))
+		} else {
+			tiers = append(tiers, security.Tier{
+				Name:     security.TierPattern,
+				Analyzer: security.NewSimpleVectorSecurityEngine(patternService, vsConfig, log.WithComponent("vector-security").Logger),
+			})
+		}
+	}
+
+	return append(tiers, security.Tier{
+		Name:     security.TierKeyword,
+		Analyzer: security.NewSimpleVectorSecurityEngine(nil, vsConfig, log.WithComponent("vector-security").Logger),
+	})
+}
+
+// handleTierChange logs and broadcasts a change in analysis quality
+func (s *Server) handleTierChange(change security.TierChange) {
+	s.logger.Warn("Analysis tier changed",
+		zap.String("from", change.From),
+		zap.String("to", change.To),
+		zap.String("reason", change.Reason),
+		zap.Float64("cpu", change.CPU),
+		zap.Int64("in_flight", change.InFlight))
+
+	if s.tiered != nil && s.tiered.Degraded() {
+		s.degraded.mark(subsystemAnalysisTier, change.To+"-only", "sustained load", nil)
+	} else {
+		s.degraded.clear(subsystemAnalysisTier)
+	}
+
+	s.emit(websocket.Event{
+		Type:      websocket.EventTypeSystemStatus,
+		Timestamp: time.Now(),
+		Data: websocket.AnalysisTierEvent{
+			From:     change.From,
+			To:       change.To,
+			Reason:   change.Reason,
+			CPU:      change.CPU,
+			InFlight: change.InFlight,
+		},
+	})
+}
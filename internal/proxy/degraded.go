@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/embeddings"
+)
+
+// Subsystems reported in the degradation report
+const (
+	subsystemEmbeddings     = "embeddings"
+	subsystemVectorSecurity = "vector security"
+	subsystemAnalysisTier   = "analysis tier"
+	subsystemCache          = "cache"
+	subsystemKnownAttacks   = "known attacks"
+	subsystemBedrock        = "bedrock"
+	subsystemCorpusModel    = "corpus model"
+	subsystemRateLimit      = "rate limit"
+	subsystemResponseCache  = "response cache"
+)
+
+// degradedSubsystem is a subsystem running below its configured capability
+type degradedSubsystem struct {
+	Subsystem string    `json:"subsystem"`
+	Status    string    `json:"status"` // what still runs, e.g. "pattern-only"
+	Reason    string    `json:"reason"`
+	Error     string    `json:"error,omitempty"`
+	Since     time.Time `json:"since"`
+	Summary   string    `json:"summary"` // e.g. "vector security: pattern-only, DB unreachable since 12:04"
+}
+
+// degradationReport tracks fallbacks that would otherwise only show up in
+// startup logs, so /info can tell operators what is running degraded
+type degradationReport struct {
+	mu      sync.Mutex
+	entries map[string]degradedSubsystem
+}
+
+func newDegradationReport() *degradationReport {
+	return &degradationReport{entries: make(map[string]degradedSubsystem)}
+}
+
+// mark records subsystem as degraded. Since is kept while the subsystem stays
+// degraded, so it reports when the degradation began.
+func (d *degradationReport) mark(subsystem, status, reason string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry := degradedSubsystem{Subsystem: subsystem, Status: status, Reason: reason, Since: time.Now()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if previous, ok := d.entries[subsystem]; ok {
+		entry.Since = previous.Since
+	}
+	d.entries[subsystem] = entry
+}
+
+// clear records subsystem as running normally
+func (d *degradationReport) clear(subsystem string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.entries, subsystem)
+}
+
+// list returns the degraded subsystems ordered by name
+func (d *degradationReport) list() []degradedSubsystem {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list := make([]degradedSubsystem, 0, len(d.entries))
+	for _, entry := range d.entries {
+		entry.Summary = fmt.Sprintf("%s: %s, %s since %s", entry.Subsystem, entry.Status, entry.Reason, entry.Since.Format("15:04"))
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Subsystem < list[j].Subsystem })
+	return list
+}
+
+// recordComponentDegradation maps an embedding service component's health
+// onto the subsystem its failure degrades. Components the service never
+// connected stay as recorded at startup.
+func (s *Server) recordComponentDegradation(component embeddings.ComponentHealth) {
+	var err error
+	if component.Error != "" {
+		err = fmt.Errorf("%s", component.Error)
+	}
+
+	switch component.Name {
+	case "vector_store":
+		switch component.Status {
+		case embeddings.HealthDown:
+			s.degraded.mark(subsystemVectorSecurity, "pattern-only", "DB unreachable", err)
+		case embeddings.HealthOK:
+			s.degraded.clear(subsystemVectorSecurity)
+		}
+	case "redis":
+		switch component.Status {
+		case embeddings.HealthDown:
+			s.degraded.mark(subsystemCache, "disabled", "Redis unreachable", err)
+		case embeddings.HealthDisabled:
+			// The factory drops a configured Redis it cannot reach at startup
+			if s.cfg().Security.VectorSecurity.Embedding.RedisEnabled {
+				s.degraded.mark(subsystemCache, "disabled", "Redis unreachable at startup", nil)
+			}
+		case embeddings.HealthOK:
+			s.degraded.clear(subsystemCache)
+		}
+	case "model_backend":
+		switch component.Status {
+		case embeddings.HealthDegraded:
+			s.degraded.mark(subsystemEmbeddings, "simulated", "model backend not ready", err)
+		case embeddings.HealthOK:
+			s.degraded.clear(subsystemEmbeddings)
+		}
+	}
+}
+
+// refreshDegradation re-checks the subsystems whose state can change at
+// runtime before the report is read
+func (s *Server) refreshDegradation(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, componentHealthTimeout)
+	defer cancel()
+
+	if s.components != nil {
+		s.recordComponentHealth(s.components.CheckComponents(ctx))
+	}
+
+	if s.poolMonitor != nil {
+		for _, pool := range s.poolMonitor.Statuses() {
+			subsystem := "database pool " + pool.Pool
+			if pool.Saturated {
+				s.degraded.mark(subsystem, "saturated", "callers waiting for connections", nil)
+			} else {
+				s.degraded.clear(subsystem)
+			}
+		}
+	}
+
+	if s.bedrockCredentials != nil {
+		if _, err := s.bedrockCredentials.Get(ctx); err != nil {
+			s.degraded.mark(subsystemBedrock, "unavailable", "no usable AWS credentials", err)
+		} else {
+			s.degraded.clear(subsystemBedrock)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+)
+
+// patternInfo describes an attack pattern for capability inventories. The
+// keyword is the pattern's name and the text it matches, case-insensitively.
+type patternInfo struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	Category   string  `json:"category"` // attack type
+	Severity   string  `json:"severity"` // as detections at this confidence are ranked
+	Confidence float32 `json:"confidence"`
+	Enabled    bool    `json:"enabled"`
+	Custom     bool    `json:"custom"`
+}
+
+// handleDetectors returns metadata for every PII rule and attack pattern,
+// including disabled ones, so tools can render what this deployment detects
+func (s *Server) handleDetectors(w http.ResponseWriter, r *http.Request) {
+	patterns := security.Patterns().List()
+	patternInfos := make([]patternInfo, 0, len(patterns))
+	for _, pattern := range patterns {
+		patternInfos = append(patternInfos, patternInfo{
+			ID:         pattern.ID,
+			Name:       pattern.Keyword,
+			Category:   pattern.AttackType,
+			Severity:   websocket.ConfidenceSeverity(pattern.Confidence),
+			Confidence: pattern.Confidence,
+			Enabled:    pattern.Enabled,
+			Custom:     pattern.Custom,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pii":             s.detector.Catalog(),
+		"attack_patterns": patternInfos,
+	})
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// TestDetectorsRequireAdminToken checks the detector inventory, which lists
+// attack keywords, is only served on the admin router
+func TestDetectorsRequireAdminToken(t *testing.T) {
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.API.Detectors.Enabled = true
+		cfg.Server.AdminTokens = map[string]string{"ops": "secret-token"}
+	})
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/detectors", nil))
+	if rec.Code == http.StatusOK {
+		t.Fatal("GET /api/detectors still served outside the admin router")
+	}
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/detectors", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("GET /admin/detectors without token: status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/detectors", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/detectors with token: status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
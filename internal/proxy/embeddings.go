@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// embeddingsRequest is the body of POST /v1/embeddings, as in the OpenAI API
+type embeddingsRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`                     // a string or an array of strings
+	EncodingFormat string          `json:"encoding_format,omitempty"` // float (default) or base64
+	Dimensions     int             `json:"dimensions,omitempty"`      // only the model's own size is accepted
+}
+
+type embeddingsResponse struct {
+	Object string            `json:"object"`
+	Data   []embeddingObject `json:"data"`
+	Model  string            `json:"model"`
+	Usage  embeddingsUsage   `json:"usage"`
+}
+
+type embeddingObject struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"` // []float32, or a base64 string of little-endian float32s
+}
+
+type embeddingsUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// apiError is an error body in the OpenAI format, which OpenAI client
+// libraries parse into their own error types
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// writeAPIError writes an OpenAI-style error; param and code may be empty
+func writeAPIError(w http.ResponseWriter, status int, errType, param, code, message string) {
+	detail := apiErrorDetail{Message: message, Type: errType}
+	if param != "" {
+		detail.Param = &param
+	}
+	if code != "" {
+		detail.Code = &code
+	}
+	writeJSON(w, status, apiError{Error: detail})
+}
+
+// handleEmbeddings serves OpenAI-compatible embeddings from the ML model the
+// vector security engine uses, so tooling can share its MiniLM embeddings.
+// Texts are embedded directly by the model: unlike prompt analysis, stored
+// attack vectors are never substituted. Texts longer than the model's max
+// length are truncated, and usage counts the model tokens actually embedded.
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	cfg := s.cfg().API.Embeddings
+	logger := s.logger.WithRequestID(getRequestID(r.Context()))
+
+	var req embeddingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "", "", "Invalid embeddings request: "+err.Error())
+		return
+	}
+
+	model := req.Model
+	if model == "" {
+		model = cfg.Model
+	}
+	if !s.servesEmbeddingModel(model) {
+		writeAPIError(w, http.StatusNotFound, "invalid_request_error", "model", "model_not_found",
+			fmt.Sprintf("The model '%s' does not exist; this endpoint serves '%s'", model, cfg.Model))
+		return
+	}
+
+	texts, err := embeddingInputs(req.Input)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "input", "", err.Error())
+		return
+	}
+	if len(texts) > cfg.MaxInputs {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "input", "",
+			fmt.Sprintf("Too many inputs: %d (max %d)", len(texts), cfg.MaxInputs))
+		return
+	}
+
+	switch req.EncodingFormat {
+	case "", "float", "base64":
+	default:
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "encoding_format", "",
+			fmt.Sprintf("Unsupported encoding_format '%s' (float or base64)", req.EncodingFormat))
+		return
+	}
+	if req.Dimensions != 0 && req.Dimensions != embeddings.EmbeddingDimensions {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "dimensions", "",
+			fmt.Sprintf("Model '%s' only produces %d dimensions", cfg.Model, embeddings.EmbeddingDimensions))
+		return
+	}
+
+	result, err := s.embeddingModel.GenerateBatchEmbeddings(r.Context(), texts)
+	if err == nil && (len(result.Embeddings) != len(texts) || slices.ContainsFunc(result.Embeddings, func(e []float32) bool { return e == nil })) {
+		err = fmt.Errorf("embedded %d of %d inputs", result.Successful, len(texts))
+	}
+	if err != nil {
+		logger.Error("Embeddings API request failed", zap.Int("inputs", len(texts)), zap.Error(err))
+		writeAPIError(w, http.StatusInternalServerError, "server_error", "", "", "The embedding model failed to embed the input")
+		return
+	}
+
+	response := embeddingsResponse{
+		Object: "list",
+		Data:   make([]embeddingObject, len(texts)),
+		Model:  cfg.Model,
+	}
+	for i, embedding := range result.Embeddings {
+		response.Data[i] = embeddingObject{Object: "embedding", Index: i, Embedding: embedding}
+		if req.EncodingFormat == "base64" {
+			response.Data[i].Embedding = encodeEmbedding(embedding)
+		}
+		response.Usage.PromptTokens += s.embeddingModel.CountTokens(texts[i])
+	}
+	response.Usage.TotalTokens = response.Usage.PromptTokens
+
+	metrics.ObserveEmbeddings(model, len(texts), response.Usage.TotalTokens)
+	logger.Info("Embeddings API usage",
+		zap.String("model", model),
+		zap.Int("inputs", len(texts)),
+		zap.Int("tokens", response.Usage.TotalTokens),
+		zap.Int("cache_hits", result.CacheHits),
+		zap.Duration("duration", result.Duration))
+	writeJSON(w, http.StatusOK, response)
+}
+
+// servesEmbeddingModel reports whether model names the embeddings API's
+// model: its configured name, an alias, or the embedding model it loads
+func (s *Server) servesEmbeddingModel(model string) bool {
+	cfg := s.cfg()
+	return model == cfg.API.Embeddings.Model ||
+		slices.Contains(cfg.API.Embeddings.Aliases, model) ||
+		model == cfg.Security.VectorSecurity.Embedding.Model.ModelName
+}
+
+// embeddingInputs decodes an input field holding a string or an array of
+// strings. Token-array inputs are rejected, since they would be token IDs of
+// another model's vocabulary.
+func embeddingInputs(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	var texts []string
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		texts = []string{text}
+	} else if err := json.Unmarshal(raw, &texts); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of strings; token arrays are not supported")
+	}
+
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("input[%d] is an empty string", i)
+		}
+	}
+	return texts, nil
+}
+
+// encodeEmbedding returns embedding as base64 of its little-endian float32s,
+// the layout OpenAI clients decode
+func encodeEmbedding(embedding []float32) string {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,407 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// Failover group defaults for zero config values
+const (
+	defaultFailoverMaxErrorRate  = 0.5
+	defaultFailoverMinRequests   = 10
+	defaultFailoverWindow        = time.Minute
+	defaultFailoverProbeInterval = 30 * time.Second
+	defaultFailoverRecoverAfter  = 3
+
+	failoverProbeTimeout = 10 * time.Second
+)
+
+// failoverTarget is one upstream of a failover group. Its health fields are
+// guarded by the group's mutex.
+type failoverTarget struct {
+	name    string
+	url     *url.URL
+	headers http.Header // credentials sent instead of the client's; empty forwards the client's
+
+	healthy     bool
+	windowStart time.Time
+	requests    int
+	errors      int
+	latency     time.Duration // summed time to response headers
+	probeOK     int           // consecutive successful probes while unhealthy
+}
+
+// failoverGroup routes one provider route to the first healthy target in
+// priority order
+type failoverGroup struct {
+	route  string
+	config config.FailoverGroupConfig // with defaults applied
+
+	mu      sync.Mutex
+	targets []*failoverTarget
+	active  *failoverTarget
+}
+
+// failoverSwitch describes traffic moving from one target to another
+type failoverSwitch struct {
+	from, to *failoverTarget
+	reason   string
+	requests int
+	errRate  float64
+	latency  time.Duration // mean over the window that tripped
+}
+
+// newFailoverGroup resolves a group's targets and credentials. All targets
+// start healthy.
+func newFailoverGroup(route string, cfg config.FailoverGroupConfig) (*failoverGroup, error) {
+	if cfg.MaxErrorRate == 0 {
+		cfg.MaxErrorRate = defaultFailoverMaxErrorRate
+	}
+	if cfg.MinRequests == 0 {
+		cfg.MinRequests = defaultFailoverMinRequests
+	}
+	if cfg.Window == 0 {
+		cfg.Window = defaultFailoverWindow
+	}
+	if cfg.ProbeInterval == 0 {
+		cfg.ProbeInterval = defaultFailoverProbeInterval
+	}
+	if cfg.ProbePath == "" {
+		cfg.ProbePath = "/"
+	}
+	if cfg.RecoverAfter == 0 {
+		cfg.RecoverAfter = defaultFailoverRecoverAfter
+	}
+
+	g := &failoverGroup{route: route, config: cfg}
+	now := time.Now()
+	for _, target := range cfg.Targets {
+		u, err := url.Parse(target.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failover target URL for %s/%s: %w", route, target.Name, err)
+		}
+		headers := make(http.Header)
+		for header, env := range target.HeaderEnv {
+			value := os.Getenv(env)
+			if value == "" {
+				return nil, fmt.Errorf("failover target %s/%s: environment variable %s for header %s is not set", route, target.Name, env, header)
+			}
+			headers.Set(header, value)
+		}
+		g.targets = append(g.targets, &failoverTarget{name: target.Name, url: u, headers: headers, healthy: true, windowStart: now})
+		metrics.SetUpstreamTargetHealth(route, target.Name, true)
+	}
+	g.active = g.targets[0]
+	return g, nil
+}
+
+// pick returns the target serving new requests
+func (g *failoverGroup) pick() *failoverTarget {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.active
+}
+
+// record counts the outcome of a request sent to target and marks it
+// unhealthy once its window crosses a threshold. It returns the resulting
+// switch of traffic, if any.
+func (g *failoverGroup) record(target *failoverTarget, failed bool, latency time.Duration) *failoverSwitch {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(target.windowStart) >= g.config.Window {
+		target.resetWindow(now)
+	}
+	target.requests++
+	target.latency += latency
+	if failed {
+		target.errors++
+	}
+	if !target.healthy || target.requests < g.config.MinRequests {
+		return nil
+	}
+
+	errRate := float64(target.errors) / float64(target.requests)
+	mean := target.latency / time.Duration(target.requests)
+	reason := ""
+	switch {
+	case errRate > g.config.MaxErrorRate:
+		reason = "error_rate"
+	case g.config.MaxLatency > 0 && mean > g.config.MaxLatency:
+		reason = "latency"
+	default:
+		return nil
+	}
+
+	requests := target.requests
+	target.healthy = false
+	target.probeOK = 0
+	target.resetWindow(now)
+	metrics.SetUpstreamTargetHealth(g.route, target.name, false)
+
+	sw := g.reselect(reason)
+	if sw == nil {
+		// Traffic stays where it is, e.g. on the last target standing;
+		// still report why this one was dropped
+		sw = &failoverSwitch{from: target, to: g.active, reason: reason}
+	}
+	sw.requests, sw.errRate, sw.latency = requests, errRate, mean
+	return sw
+}
+
+// probeResult records a probe of an unhealthy target and returns the
+// switch back to it once it has recovered
+func (g *failoverGroup) probeResult(target *failoverTarget, ok bool) *failoverSwitch {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if target.healthy {
+		return nil
+	}
+	if !ok {
+		target.probeOK = 0
+		return nil
+	}
+	target.probeOK++
+	if target.probeOK < g.config.RecoverAfter {
+		return nil
+	}
+
+	target.healthy = true
+	target.resetWindow(time.Now())
+	metrics.SetUpstreamTargetHealth(g.route, target.name, true)
+	return g.reselect("recovered")
+}
+
+// reselect points traffic at the first healthy target, or keeps it on the
+// active one when none is healthy. Caller holds mu.
+func (g *failoverGroup) reselect(reason string) *failoverSwitch {
+	next := g.active
+	for _, target := range g.targets {
+		if target.healthy {
+			next = target
+			break
+		}
+	}
+	if next == g.active {
+		return nil
+	}
+	sw := &failoverSwitch{from: g.active, to: next, reason: reason}
+	g.active = next
+	if next.healthy {
+		// A fresh window, so the target is judged on its own traffic
+		next.resetWindow(time.Now())
+	}
+	return sw
+}
+
+// unhealthy returns the targets to probe
+func (g *failoverGroup) unhealthy() []*failoverTarget {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var targets []*failoverTarget
+	for _, target := range g.targets {
+		if !target.healthy {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+func (t *failoverTarget) resetWindow(now time.Time) {
+	t.windowStart = now
+	t.requests, t.errors, t.latency = 0, 0, 0
+}
+
+// isFailure reports whether an upstream response counts against its target
+func isFailure(status int, err error) bool {
+	return err != nil || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+// failoverSet holds the failover groups of all provider routes and probes
+// their unhealthy targets
+type failoverSet struct {
+	groups    map[string]*failoverGroup
+	transport func(route string) http.RoundTripper
+	onSwitch  func(route string, sw *failoverSwitch)
+	logger    *zap.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newFailoverSet(groups map[string]config.FailoverGroupConfig, transport func(string) http.RoundTripper, onSwitch func(string, *failoverSwitch), logger *zap.Logger) (*failoverSet, error) {
+	set := &failoverSet{
+		groups:    make(map[string]*failoverGroup, len(groups)),
+		transport: transport,
+		onSwitch:  onSwitch,
+		logger:    logger,
+		stop:      make(chan struct{}),
+	}
+	for route, cfg := range groups {
+		group, err := newFailoverGroup(route, cfg)
+		if err != nil {
+			return nil, err
+		}
+		set.groups[route] = group
+	}
+	return set, nil
+}
+
+// group returns the failover group of route, or nil
+func (f *failoverSet) group(route string) *failoverGroup {
+	if f == nil {
+		return nil
+	}
+	return f.groups[route]
+}
+
+// record counts a request outcome and reports any resulting switch
+func (f *failoverSet) record(group *failoverGroup, target *failoverTarget, failed bool, latency time.Duration) {
+	if sw := group.record(target, failed, latency); sw != nil {
+		f.onSwitch(group.route, sw)
+	}
+}
+
+// Start begins probing unhealthy targets
+func (f *failoverSet) Start() {
+	for _, group := range f.groups {
+		f.wg.Add(1)
+		go func(group *failoverGroup) {
+			defer f.wg.Done()
+			ticker := time.NewTicker(group.config.ProbeInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-f.stop:
+					return
+				case <-ticker.C:
+					for _, target := range group.unhealthy() {
+						if sw := group.probeResult(target, f.probe(group, target)); sw != nil {
+							f.onSwitch(group.route, sw)
+						}
+					}
+				}
+			}
+		}(group)
+	}
+}
+
+// Close stops probing
+func (f *failoverSet) Close() {
+	close(f.stop)
+	f.wg.Wait()
+}
+
+// probe sends GET probe_path to target with its credentials. Any answer
+// short of a failure counts, so a probe without credentials that gets a 401
+// still shows the upstream is serving.
+func (f *failoverSet) probe(group *failoverGroup, target *failoverTarget) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), failoverProbeTimeout)
+	defer cancel()
+
+	probeURL := *target.url
+	probeURL.Path = joinURLPath(target.url.Path, group.config.ProbePath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL.String(), nil)
+	if err != nil {
+		return false
+	}
+	for header, values := range target.headers {
+		req.Header[header] = values
+	}
+
+	resp, err := f.transport(group.route).RoundTrip(req)
+	if err != nil {
+		f.logger.Debug("Failover probe failed", zap.String("route", group.route), zap.String("target", target.name), zap.Error(err))
+		return false
+	}
+	resp.Body.Close()
+	return !isFailure(resp.StatusCode, nil)
+}
+
+// failoverTransport records the outcome of each upstream request against
+// the failover target it was sent to
+type failoverTransport struct {
+	inner  http.RoundTripper
+	set    *failoverSet
+	group  *failoverGroup
+	target *failoverTarget
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.inner.RoundTrip(req)
+	if req.Context().Err() != nil {
+		// The client gave up; that says nothing about the upstream
+		return resp, err
+	}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.set.record(t.group, t.target, isFailure(status, err), time.Since(start))
+	return resp, err
+}
+
+// joinURLPath appends path to a target's base path
+func joinURLPath(base, path string) string {
+	if base == "" || base == "/" {
+		return path
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// handleFailoverSwitch logs, counts and broadcasts a failover switch, and
+// reports a route served by other than its primary as degraded
+func (s *Server) handleFailoverSwitch(route string, sw *failoverSwitch) {
+	log := s.logger.Warn
+	if sw.reason == "recovered" {
+		log = s.logger.Info
+	}
+	log("Upstream failover",
+		zap.String("route", route),
+		zap.String("from", sw.from.name),
+		zap.String("to", sw.to.name),
+		zap.String("reason", sw.reason),
+		zap.Int("requests", sw.requests),
+		zap.Float64("error_rate", sw.errRate),
+		zap.Duration("mean_latency", sw.latency))
+
+	if sw.from != sw.to {
+		metrics.ObserveUpstreamFailover(route, sw.from.name, sw.to.name)
+	}
+
+	subsystem := "upstream " + route
+	if primary := s.failover.group(route).targets[0]; sw.to != primary {
+		s.degraded.mark(subsystem, "failover to "+sw.to.name, primary.name+" unhealthy", nil)
+	} else {
+		s.degraded.clear(subsystem)
+	}
+
+	s.emit(websocket.Event{
+		Type:      websocket.EventTypeSystemStatus,
+		Timestamp: time.Now(),
+		Data: websocket.UpstreamFailoverEvent{
+			Route:       route,
+			From:        sw.from.name,
+			To:          sw.to.name,
+			Reason:      sw.reason,
+			ErrorRate:   sw.errRate,
+			MeanLatency: float64(sw.latency) / float64(time.Millisecond),
+			Requests:    sw.requests,
+		},
+	})
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
@@ -8,12 +9,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/usage"
 	"go.uber.org/zap"
 )
 
 // handleOpenAIProxy handles requests to OpenAI API
 func (s *Server) handleOpenAIProxy(w http.ResponseWriter, r *http.Request) {
-	target, err := url.Parse(s.config.Upstream.OpenAI)
+	target, err := url.Parse(s.cfg().Upstream.OpenAI)
 	if err != nil {
 		s.logger.Error("Failed to parse OpenAI target URL", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -31,7 +35,7 @@ func (s *Server) handleOpenAIProxy(w http.ResponseWriter, r *http.Request) {
 
 // handleOllamaProxy handles requests to Ollama API
 func (s *Server) handleOllamaProxy(w http.ResponseWriter, r *http.Request) {
-	target, err := url.Parse(s.config.Upstream.Ollama)
+	target, err := url.Parse(s.cfg().Upstream.Ollama)
 	if err != nil {
 		s.logger.Error("Failed to parse Ollama target URL", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -49,7 +53,7 @@ func (s *Server) handleOllamaProxy(w http.ResponseWriter, r *http.Request) {
 
 // handleAnthropicProxy handles requests to Anthropic API
 func (s *Server) handleAnthropicProxy(w http.ResponseWriter, r *http.Request) {
-	target, err := url.Parse(s.config.Upstream.Anthropic)
+	target, err := url.Parse(s.cfg().Upstream.Anthropic)
 	if err != nil {
 		s.logger.Error("Failed to parse Anthropic target URL", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -65,14 +69,66 @@ func (s *Server) handleAnthropicProxy(w http.ResponseWriter, r *http.Request) {
 	s.proxyRequest(w, r, target, "anthropic")
 }
 
+// handleGeminiProxy handles requests to the Gemini API
+func (s *Server) handleGeminiProxy(w http.ResponseWriter, r *http.Request) {
+	target, err := url.Parse(s.cfg().Upstream.Gemini)
+	if err != nil {
+		s.logger.Error("Failed to parse Gemini target URL", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove /gemini prefix from path
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/gemini")
+	if r.URL.Path == "" {
+		r.URL.Path = "/"
+	}
+
+	s.proxyRequest(w, r, target, "gemini")
+}
+
+// handleVertexProxy handles requests to Vertex AI
+func (s *Server) handleVertexProxy(w http.ResponseWriter, r *http.Request) {
+	target, err := url.Parse(s.cfg().Upstream.Vertex)
+	if err != nil {
+		s.logger.Error("Failed to parse Vertex AI target URL", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove /vertex prefix from path
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/vertex")
+	if r.URL.Path == "" {
+		r.URL.Path = "/"
+	}
+
+	s.proxyRequest(w, r, target, "vertex")
+}
+
 // proxyRequest proxies the request to the target URL
 func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, target *url.URL, provider string) {
 	requestID := getRequestID(r.Context())
 	logger := s.logger.WithRequestID(requestID)
 
+	// Send failover routes to their group's current target
+	group := s.failover.group(provider)
+	var failover *failoverTarget
+	if group != nil {
+		failover = group.pick()
+		target = failover.url
+		r.URL.Path = joinURLPath(target.Path, r.URL.Path)
+		r.URL.RawPath = ""
+	}
+
 	// Create reverse proxy
 	proxy := httputil.NewSingleHostReverseProxy(target)
 
+	// Forward streamed tokens as soon as they arrive rather than letting
+	// small chunks sit in the write buffer
+	if isStreaming(r) {
+		proxy.FlushInterval = -1
+	}
+
 	// Configure proxy
 	proxy.Director = func(req *http.Request) {
 		req.URL.Scheme = target.Scheme
@@ -80,9 +136,9 @@ func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, target *ur
 		req.Host = target.Host
 
 		// Preserve upstream authentication headers
-		if s.config.Privacy.Enabled && s.config.Privacy.HeaderScrubbing.Enabled && s.config.Privacy.HeaderScrubbing.PreserveUpstreamAuth {
+		if s.cfg().Privacy.Enabled && s.cfg().Privacy.HeaderScrubbing.Enabled && s.cfg().Privacy.HeaderScrubbing.PreserveUpstreamAuth {
 			// Get the original request from context to restore auth headers
-			if originalHeaders, ok := req.Context().Value("original_headers").(map[string][]string); ok {
+			if originalHeaders, ok := ctxkeys.OriginalHeaders.Value(req.Context()); ok {
 				// Restore auth headers that were scrubbed
 				for key, values := range originalHeaders {
 					if s.detector.IsAuthHeaderPublic(key) {
@@ -98,6 +154,24 @@ func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, target *ur
 			}
 		}
 
+		// A failover target with its own credentials gets those instead of
+		// the client's
+		if failover != nil && len(failover.headers) > 0 {
+			for _, header := range apiKeyHeaders {
+				req.Header.Del(header)
+			}
+			for header, values := range failover.headers {
+				req.Header[header] = values
+			}
+		}
+
+		// Forward the signed analysis summary to gateways behind the proxy
+		if s.annotator != nil {
+			if err := s.annotator.annotate(req, provider, s.requestPolicy(req)); err != nil {
+				logger.Error("Failed to annotate upstream request", zap.String("provider", provider), zap.Error(err))
+			}
+		}
+
 		// Preserve original headers
 		if _, ok := req.Header["User-Agent"]; !ok {
 			req.Header.Set("User-Agent", "LLM-Sentinel/0.1.0")
@@ -105,13 +179,48 @@ func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, target *ur
 
 		logger.Debug("Proxying request",
 			zap.String("provider", provider),
-			zap.String("target_url", req.URL.String()),
+			zap.String("target_url", redactedURL(req.URL)),
 			zap.String("method", req.Method),
 		)
 	}
 
+	verdict := getPendingVerdict(r.Context())
+	record := getAuditRecord(r.Context())
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		// Hold the response until a concurrent analysis has finished
+		if verdict != nil {
+			blocked, err := verdict.wait(resp.Request.Context())
+			if err != nil {
+				return err
+			}
+			if blocked {
+				resp.Body.Close()
+				return errRequestBlocked
+			}
+		}
+
+		// Record normalized token usage from the response as it streams through
+		if record != nil {
+			resp.Body = usage.CaptureBody(resp.Body, provider, resp.Header.Get("Content-Encoding"), record.setUsage)
+		}
+		return nil
+	}
+
 	// Handle errors
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if verdict != nil && (errors.Is(err, errRequestBlocked) || verdict.isBlocked()) {
+			logger.Info("Upstream request aborted by vector security",
+				zap.String("provider", provider))
+			s.writeBlockedResponse(w, r, verdict.result)
+			return
+		}
+
+		var open *circuitOpenError
+		if errors.As(err, &open) {
+			s.writeCircuitOpen(w, r, open)
+			return
+		}
+
 		logger.Error("Proxy error",
 			zap.String("provider", provider),
 			zap.Error(err),
@@ -120,9 +229,25 @@ func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, target *ur
 		http.Error(w, fmt.Sprintf("Proxy error: %v", err), http.StatusBadGateway)
 	}
 
-	// Set timeout
-	proxy.Transport = &http.Transport{
-		ResponseHeaderTimeout: s.config.Upstream.Timeout,
+	transport := s.upstreamRoundTripper(provider)
+	proxy.Transport = transport
+	if provider == "bedrock" {
+		proxy.Transport = s.bedrockTransport(transport)
+	}
+	if s.chaos != nil {
+		proxy.Transport = chaos.RoundTripper(proxy.Transport)
+	}
+	if breaker := s.upstreamBreaker(provider, failover); breaker != nil {
+		proxy.Transport = &breakerTransport{inner: proxy.Transport, breaker: breaker, server: s}
+	}
+	if policy, ok := s.upstreamRetryPolicy(provider); ok {
+		proxy.Transport = &retryTransport{inner: proxy.Transport, policy: policy, route: provider, logger: logger}
+	}
+	if failover != nil {
+		proxy.Transport = &failoverTransport{inner: proxy.Transport, set: s.failover, group: group, target: failover}
+	}
+	if trace := getLatencyTrace(r.Context()); trace != nil && isStreaming(r) {
+		proxy.Transport = &tracingTransport{inner: proxy.Transport, trace: trace}
 	}
 
 	// Execute proxy request
@@ -132,6 +257,36 @@ func (s *Server) proxyRequest(w http.ResponseWriter, r *http.Request, target *ur
 
 	logger.Info("Request proxied",
 		zap.String("provider", provider),
+		zap.Bool("streaming", isStreaming(r)),
 		zap.Duration("upstream_duration", duration),
 	)
 }
+
+// upstreamRoundTripper returns the transport for a provider's upstream
+// requests, through its egress proxy and resolver
+func (s *Server) upstreamRoundTripper(provider string) http.RoundTripper {
+	if s.upstreamTransport != nil {
+		return s.upstreamTransport
+	}
+	upstream := &http.Transport{
+		Proxy:                 s.egress[provider],
+		ResponseHeaderTimeout: s.cfg().Upstream.Timeout,
+	}
+	if s.resolver != nil {
+		upstream.DialContext = s.resolver.DialContext
+	}
+	return upstream
+}
+
+// redactedURL renders u for logs with credential query parameters (Gemini's
+// ?key=) masked
+func redactedURL(u *url.URL) string {
+	query := u.Query()
+	if !query.Has("key") {
+		return u.String()
+	}
+	query.Set("key", "[REDACTED]")
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// responseHeaderMiddleware applies the default and route header policies to
+// every response on the route just before its headers are sent
+func (s *Server) responseHeaderMiddleware(route string) mux.MiddlewareFunc {
+	headers := s.cfg().ResponseHeaders
+	policies := []config.HeaderPolicy{headers.Default}
+	if policy, ok := headers.Routes[route]; ok {
+		policies = append(policies, policy)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&headerPolicyWriter{ResponseWriter: w, policies: policies}, r)
+		})
+	}
+}
+
+// applyHeaderPolicy removes then sets headers
+func applyHeaderPolicy(header http.Header, policy config.HeaderPolicy) {
+	for _, name := range policy.Remove {
+		prefix, isPrefix := strings.CutSuffix(name, "*")
+		if !isPrefix {
+			header.Del(name)
+			continue
+		}
+		prefix = strings.ToLower(prefix)
+		for key := range header {
+			if strings.HasPrefix(strings.ToLower(key), prefix) {
+				delete(header, key)
+			}
+		}
+	}
+	for name, value := range policy.Set {
+		header.Set(name, value)
+	}
+}
+
+// headerPolicyWriter rewrites headers once, when the status is written
+type headerPolicyWriter struct {
+	http.ResponseWriter
+	policies []config.HeaderPolicy
+	applied  bool
+}
+
+func (hw *headerPolicyWriter) apply() {
+	if hw.applied {
+		return
+	}
+	hw.applied = true
+	for _, policy := range hw.policies {
+		applyHeaderPolicy(hw.Header(), policy)
+	}
+}
+
+func (hw *headerPolicyWriter) WriteHeader(code int) {
+	hw.apply()
+	hw.ResponseWriter.WriteHeader(code)
+}
+
+func (hw *headerPolicyWriter) Write(b []byte) (int, error) {
+	hw.apply()
+	return hw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streamed responses flowing
+func (hw *headerPolicyWriter) Flush() {
+	hw.apply()
+	_ = http.NewResponseController(hw.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (hw *headerPolicyWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
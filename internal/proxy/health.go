@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+	"go.uber.org/zap"
+)
+
+// componentHealthTimeout bounds the dependency checks run per /health request
+const componentHealthTimeout = 2 * time.Second
+
+// componentChecker reports the readiness of individual dependencies
+type componentChecker interface {
+	CheckComponents(ctx context.Context) []embeddings.ComponentHealth
+}
+
+// handleHealth reports overall status along with each component's readiness.
+// It returns 503 only when a critical component is down; a degraded or
+// non-critical component is reported without failing the probe.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := "healthy"
+	code := http.StatusOK
+
+	var components []embeddings.ComponentHealth
+	if s.components != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), componentHealthTimeout)
+		components = s.components.CheckComponents(ctx)
+		cancel()
+
+		for _, component := range components {
+			switch {
+			case !component.Healthy():
+				status = "unhealthy"
+				code = http.StatusServiceUnavailable
+			case status == "healthy" && (component.Status == embeddings.HealthDown || component.Status == embeddings.HealthDegraded):
+				status = "degraded"
+			}
+		}
+		s.recordComponentHealth(components)
+	}
+
+	// Callers queueing for database connections degrade latency before
+	// anything fails outright
+	var pools []vector.PoolStatus
+	if s.poolMonitor != nil {
+		pools = s.poolMonitor.Statuses()
+		for _, pool := range pools {
+			if pool.Saturated && status == "healthy" {
+				status = "degraded"
+			}
+		}
+	}
+
+	writeJSON(w, code, map[string]interface{}{
+		"status":         status,
+		"mode":           s.currentMode().Name(),
+		"timestamp":      time.Now().Format(time.RFC3339),
+		"components":     components,
+		"database_pools": pools,
+	})
+}
+
+// recordComponentHealth logs component status transitions so alerts can key
+// on the component that changed rather than the overall status
+func (s *Server) recordComponentHealth(components []embeddings.ComponentHealth) {
+	s.componentsMu.Lock()
+	defer s.componentsMu.Unlock()
+
+	if s.componentsStatus == nil {
+		s.componentsStatus = make(map[string]string, len(components))
+	}
+	for _, component := range components {
+		s.recordComponentDegradation(component)
+
+		previous, seen := s.componentsStatus[component.Name]
+		s.componentsStatus[component.Name] = component.Status
+		if !seen || previous == component.Status {
+			continue
+		}
+
+		fields := []zap.Field{
+			zap.String("component", component.Name),
+			zap.String("from", previous),
+			zap.String("to", component.Status),
+			zap.Bool("critical", component.Critical),
+		}
+		if component.Error != "" {
+			fields = append(fields, zap.String("error", component.Error))
+		}
+		if component.Status == embeddings.HealthDown {
+			s.logger.Warn("Component health changed", fields...)
+		} else {
+			s.logger.Info("Component health changed", fields...)
+		}
+	}
+}
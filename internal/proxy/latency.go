@@ -0,0 +1,404 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// latencyTraceKey carries the request's latency trace
+var latencyTraceKey = ctxkeys.New[*latencyTrace]("latency_trace")
+
+// Stages of a streamed request outside the configured pipeline
+const (
+	latencyStageEntry    = "entry"    // routing and the fixed middleware before the pipeline
+	latencyStageForward  = "forward"  // from the pipeline to the upstream request
+	latencyStageResponse = "response" // from the upstream's first byte to the client's
+)
+
+// latencyTrace timestamps one request on its way through the proxy
+type latencyTrace struct {
+	mu                sync.Mutex
+	start             time.Time
+	route             string // set when the response is streamed
+	stages            []stageTiming
+	upstreamStart     time.Time
+	upstreamFirstByte time.Time
+	clientFirstByte   time.Time
+}
+
+type stageTiming struct {
+	name        string
+	start, done time.Time
+}
+
+func newLatencyTrace() *latencyTrace {
+	return &latencyTrace{start: time.Now()}
+}
+
+// getLatencyTrace returns the request's latency trace, if it is audited
+func getLatencyTrace(ctx context.Context) *latencyTrace {
+	t, _ := latencyTraceKey.Value(ctx)
+	return t
+}
+
+func (t *latencyTrace) setRoute(route string) {
+	t.mu.Lock()
+	t.route = route
+	t.mu.Unlock()
+}
+
+func (t *latencyTrace) stageStart(name string) {
+	t.mu.Lock()
+	t.stages = append(t.stages, stageTiming{name: name, start: time.Now()})
+	t.mu.Unlock()
+}
+
+func (t *latencyTrace) stageDone(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.stages) - 1; i >= 0; i-- {
+		if t.stages[i].name == name && t.stages[i].done.IsZero() {
+			t.stages[i].done = time.Now()
+			return
+		}
+	}
+}
+
+// mark sets *at to now unless already set
+func (t *latencyTrace) mark(at *time.Time) {
+	t.mu.Lock()
+	if at.IsZero() {
+		*at = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// latencySample is a streamed request's time to first byte, split into what
+// the upstream took and what the proxy added
+type latencySample struct {
+	route    string
+	overhead time.Duration
+	stages   map[string]time.Duration
+}
+
+// sample summarizes a streamed request that reached the upstream and sent
+// the client a first byte; ok is false otherwise
+func (t *latencyTrace) sample() (latencySample, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.route == "" || t.upstreamStart.IsZero() || t.upstreamFirstByte.IsZero() || t.clientFirstByte.IsZero() {
+		return latencySample{}, false
+	}
+
+	upstreamTTFB := t.upstreamFirstByte.Sub(t.upstreamStart)
+	s := latencySample{
+		route:    t.route,
+		overhead: max(t.clientFirstByte.Sub(t.start)-upstreamTTFB, 0),
+		stages:   make(map[string]time.Duration, len(t.stages)+3),
+	}
+	last := t.start
+	for _, stage := range t.stages {
+		if stage.done.IsZero() {
+			continue
+		}
+		if len(s.stages) == 0 {
+			s.stages[latencyStageEntry] = stage.start.Sub(t.start)
+		}
+		s.stages[stage.name] += stage.done.Sub(stage.start)
+		last = stage.done
+	}
+	if len(s.stages) == 0 {
+		s.stages[latencyStageEntry] = t.upstreamStart.Sub(t.start)
+	} else {
+		s.stages[latencyStageForward] = t.upstreamStart.Sub(last)
+	}
+	s.stages[latencyStageResponse] = max(t.clientFirstByte.Sub(t.upstreamFirstByte), 0)
+	return s, true
+}
+
+// timedStage wraps a pipeline stage so the time it spends before passing the
+// request on is traced
+func timedStage(name string, mw mux.MiddlewareFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		inner := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t := getLatencyTrace(r.Context()); t != nil {
+				t.stageDone(name)
+			}
+			next.ServeHTTP(w, r)
+		}))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t := getLatencyTrace(r.Context()); t != nil {
+				t.stageStart(name)
+			}
+			inner.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tracingTransport times the upstream request to the first byte of its body
+type tracingTransport struct {
+	inner http.RoundTripper
+	trace *latencyTrace
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.trace.mark(&t.trace.upstreamStart)
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &firstByteReader{ReadCloser: resp.Body, trace: t.trace}
+	return resp, nil
+}
+
+// firstByteReader marks the upstream's first body byte
+type firstByteReader struct {
+	io.ReadCloser
+	trace *latencyTrace
+	seen  bool
+}
+
+func (r *firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && !r.seen {
+		r.seen = true
+		r.trace.mark(&r.trace.upstreamFirstByte)
+	}
+	return n, err
+}
+
+// latencyAuditor collects streamed request samples and reports the overhead
+// against the SLO once per interval
+type latencyAuditor struct {
+	config config.LatencyAuditConfig
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	window *latencyWindow
+	last   *latencyReport
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// latencyWindow accumulates the samples of one report
+type latencyWindow struct {
+	start     time.Time
+	streams   int
+	overSLO   int
+	overheads []float64 // milliseconds; a uniform sample once streams exceeds max_samples
+	stages    map[string]*stageStats
+}
+
+type stageStats struct {
+	Streams int     `json:"streams"`
+	MeanMS  float64 `json:"mean_ms"`
+	MaxMS   float64 `json:"max_ms"`
+	totalMS float64
+}
+
+// latencyReport is the overhead of the streams of one interval
+type latencyReport struct {
+	WindowStart  time.Time              `json:"window_start"`
+	WindowEnd    time.Time              `json:"window_end"`
+	Streams      int                    `json:"streams"`
+	SLOMS        float64                `json:"slo_ms"`
+	Percentile   float64                `json:"percentile"`
+	PercentileMS float64                `json:"percentile_ms"` // overhead at the configured percentile
+	SLOMet       bool                   `json:"slo_met"`
+	OverSLO      int                    `json:"over_slo"` // streams whose overhead exceeded the SLO
+	P50MS        float64                `json:"p50_ms"`
+	P95MS        float64                `json:"p95_ms"`
+	P99MS        float64                `json:"p99_ms"`
+	MaxMS        float64                `json:"max_ms"`
+	Stages       map[string]*stageStats `json:"stages"`
+}
+
+func newLatencyAuditor(cfg config.LatencyAuditConfig, logger *zap.Logger) *latencyAuditor {
+	return &latencyAuditor{
+		config: cfg,
+		logger: logger,
+		window: newLatencyWindow(time.Now()),
+		stop:   make(chan struct{}),
+	}
+}
+
+func newLatencyWindow(start time.Time) *latencyWindow {
+	return &latencyWindow{start: start, stages: make(map[string]*stageStats)}
+}
+
+// observe records a streamed request's sample in metrics and the window
+func (a *latencyAuditor) observe(s latencySample) {
+	metrics.ObserveStreamLatency(s.route, s.overhead, s.stages)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	w := a.window
+	w.streams++
+	if s.overhead > a.config.SLO {
+		w.overSLO++
+	}
+
+	// Reservoir sampling keeps a uniform sample of the window's overheads
+	overhead := milliseconds(s.overhead)
+	if len(w.overheads) < a.config.MaxSamples {
+		w.overheads = append(w.overheads, overhead)
+	} else if i := rand.IntN(w.streams); i < a.config.MaxSamples {
+		w.overheads[i] = overhead
+	}
+
+	for name, elapsed := range s.stages {
+		stats := w.stages[name]
+		if stats == nil {
+			stats = &stageStats{}
+			w.stages[name] = stats
+		}
+		ms := milliseconds(elapsed)
+		stats.Streams++
+		stats.totalMS += ms
+		stats.MaxMS = math.Max(stats.MaxMS, ms)
+	}
+}
+
+// Start reports once per interval until Close
+func (a *latencyAuditor) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.config.ReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				a.rotate()
+			}
+		}
+	}()
+}
+
+// Close stops reporting
+func (a *latencyAuditor) Close() {
+	close(a.stop)
+	a.wg.Wait()
+}
+
+// rotate closes the current window, logs and writes its report
+func (a *latencyAuditor) rotate() {
+	now := time.Now()
+	a.mu.Lock()
+	report := a.window.report(now, a.config)
+	a.window = newLatencyWindow(now)
+	a.last = report
+	a.mu.Unlock()
+
+	log := a.logger.Info
+	if !report.SLOMet {
+		log = a.logger.Warn
+	}
+	log("Streaming latency report",
+		zap.Int("streams", report.Streams),
+		zap.Float64("percentile", report.Percentile),
+		zap.Float64("percentile_ms", report.PercentileMS),
+		zap.Float64("slo_ms", report.SLOMS),
+		zap.Bool("slo_met", report.SLOMet),
+		zap.Int("over_slo", report.OverSLO))
+
+	if a.config.ReportFile != "" {
+		if err := appendReport(a.config.ReportFile, report); err != nil {
+			a.logger.Error("Failed to write streaming latency report", zap.Error(err))
+		}
+	}
+}
+
+// reports returns the report of the window in progress and the last
+// completed one, which is nil before the first interval ends
+func (a *latencyAuditor) reports() (current, last *latencyReport) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.window.report(time.Now(), a.config), a.last
+}
+
+func (w *latencyWindow) report(end time.Time, cfg config.LatencyAuditConfig) *latencyReport {
+	sorted := append([]float64(nil), w.overheads...)
+	sort.Float64s(sorted)
+
+	r := &latencyReport{
+		WindowStart:  w.start,
+		WindowEnd:    end,
+		Streams:      w.streams,
+		SLOMS:        milliseconds(cfg.SLO),
+		Percentile:   cfg.Percentile,
+		PercentileMS: percentile(sorted, cfg.Percentile),
+		OverSLO:      w.overSLO,
+		P50MS:        percentile(sorted, 50),
+		P95MS:        percentile(sorted, 95),
+		P99MS:        percentile(sorted, 99),
+		Stages:       make(map[string]*stageStats, len(w.stages)),
+	}
+	if len(sorted) > 0 {
+		r.MaxMS = sorted[len(sorted)-1]
+	}
+	r.SLOMet = r.PercentileMS <= r.SLOMS
+
+	for name, stats := range w.stages {
+		copied := *stats
+		copied.MeanMS = stats.totalMS / float64(stats.Streams)
+		r.Stages[name] = &copied
+	}
+	return r
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values, or
+// 0 for none
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// appendReport appends report to path as one JSON line
+func appendReport(path string, report *latencyReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open report file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	return f.Close()
+}
+
+// handleLatencyReport serves the streaming latency report of the current
+// interval so far and the last completed one
+func (s *Server) handleLatencyReport(w http.ResponseWriter, r *http.Request) {
+	current, last := s.latency.reports()
+	writeJSON(w, http.StatusOK, map[string]*latencyReport{"current": current, "last": last})
+}
@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"go.uber.org/zap"
+)
+
+// promptMessage is one piece of text to analyze from a request body
+type promptMessage struct {
+	index int // position in the messages, contents or input array; -1 for single-prompt fields and Anthropic's system field
+	role  string
+	text  string
+}
+
+// embeddingInputRole is the role of the texts of an input array
+const embeddingInputRole = "input"
+
+// promptsFrom pulls the text to analyze out of a parsed JSON request body.
+// Single-prompt fields yield one message; chat requests yield the messages
+// selected by cfg.Messages, and input arrays the texts sampled by
+// cfg.EmbeddingInputs.
+func promptsFrom(requestData map[string]interface{}, cfg config.VectorSecurityConfig) []promptMessage {
+	// Try common prompt fields; inputText is Amazon Titan's
+	for _, field := range []string{"prompt", "input", "inputText"} {
+		if p, ok := requestData[field].(string); ok {
+			if p == "" {
+				return nil
+			}
+			return []promptMessage{{index: -1, text: p}}
+		}
+	}
+
+	// Embeddings and moderation requests batch texts in an input array
+	// (texts for Cohere on Bedrock); OpenAI's Responses API sends
+	// conversation items there instead
+	for _, field := range []string{"input", "texts"} {
+		if inputs, ok := requestData[field].([]interface{}); ok {
+			return inputPrompts(inputs, cfg)
+		}
+	}
+
+	var turns []promptMessage
+	if messages, ok := requestData["messages"].([]interface{}); ok {
+		// Anthropic's Messages API carries the system prompt in a top-level
+		// field, as a string or text blocks, rather than as a message
+		if system := normalizeContent(requestData["system"]); system != "" {
+			turns = append(turns, promptMessage{index: -1, role: "system", text: system})
+		}
+
+		// Handle OpenAI and Anthropic messages; content may be a string or
+		// content blocks
+		for i, m := range messages {
+			msg, _ := m.(map[string]interface{})
+			role, _ := msg["role"].(string)
+			turns = append(turns, promptMessage{index: i, role: role, text: normalizeContent(msg["content"])})
+		}
+	} else if contents, ok := requestData["contents"].([]interface{}); ok {
+		// Handle Gemini-style contents; turns without a role are the user's
+		for i, c := range contents {
+			turn, _ := c.(map[string]interface{})
+			role, _ := turn["role"].(string)
+			if role == "" {
+				role = "user"
+			}
+			turns = append(turns, promptMessage{index: i, role: role, text: normalizeContent(turn["parts"])})
+		}
+	}
+
+	return selectMessages(turns, cfg.Messages)
+}
+
+// inputPrompts pulls the texts out of an input array. Plain strings are
+// sampled by cfg.EmbeddingInputs; Responses API items are selected like chat
+// messages. Token ID arrays carry no text and are not analyzed.
+func inputPrompts(inputs []interface{}, cfg config.VectorSecurityConfig) []promptMessage {
+	var texts, turns []promptMessage
+	for i, input := range inputs {
+		switch item := input.(type) {
+		case string:
+			if item != "" {
+				texts = append(texts, promptMessage{index: i, role: embeddingInputRole, text: item})
+			}
+		case map[string]interface{}:
+			role, _ := item["role"].(string)
+			content := item["content"]
+			if item["type"] == "function_call_output" {
+				role, content = "tool", item["output"]
+			}
+			turns = append(turns, promptMessage{index: i, role: role, text: normalizeContent(content)})
+		}
+	}
+	return append(sampleInputs(texts, cfg.EmbeddingInputs), selectMessages(turns, cfg.Messages)...)
+}
+
+// sampleInputs drops repeated texts, then keeps at most cfg.MaxInputs of
+// the rest: the first ones, evenly spaced ones, or a random sample, in their
+// original order
+func sampleInputs(inputs []promptMessage, cfg config.EmbeddingInputsConfig) []promptMessage {
+	seen := make(map[string]bool, len(inputs))
+	unique := inputs[:0]
+	for _, input := range inputs {
+		if !seen[input.text] {
+			seen[input.text] = true
+			unique = append(unique, input)
+		}
+	}
+	if len(unique) <= cfg.MaxInputs {
+		return unique
+	}
+
+	n := cfg.MaxInputs
+	sampled := make([]promptMessage, 0, n)
+	switch cfg.Sampling {
+	case "head":
+		sampled = append(sampled, unique[:n]...)
+	case "stride":
+		for i := 0; i < n; i++ {
+			sampled = append(sampled, unique[i*len(unique)/n])
+		}
+	default:
+		// A fixed selection could be learned and padded around
+		picks := rand.Perm(len(unique))[:n]
+		sort.Ints(picks)
+		for _, pick := range picks {
+			sampled = append(sampled, unique[pick])
+		}
+	}
+	return sampled
+}
+
+// selectMessages keeps the latest message, or every message with one of the
+// configured roles
+func selectMessages(turns []promptMessage, cfg config.MessagesConfig) []promptMessage {
+	if len(turns) == 0 {
+		return nil
+	}
+	if cfg.Scope == "last" {
+		if last := turns[len(turns)-1]; last.text != "" {
+			return []promptMessage{last}
+		}
+		return nil
+	}
+
+	var selected []promptMessage
+	for _, turn := range turns {
+		if turn.text != "" && containsFold(cfg.Roles, turn.role) {
+			selected = append(selected, turn)
+		}
+	}
+	return selected
+}
+
+// analyzeMessages analyzes each message and returns the most severe verdict
+// with the text it came from. For chat messages the result also carries
+// every message's verdict and the index of the one that decided it. It
+// returns nil when no message could be analyzed.
+func (s *Server) analyzeMessages(ctx context.Context, log *logger.Logger, messages []promptMessage) (*security.SecurityResult, string) {
+	if len(messages) == 1 && messages[0].role == "" {
+		return s.analyzeMessage(ctx, log, messages[0].text), messages[0].text
+	}
+
+	start := time.Now()
+	var worst *security.SecurityResult
+	var worstMessage promptMessage
+	verdicts := make([]security.MessageVerdict, 0, len(messages))
+	for _, message := range messages {
+		verdict := security.MessageVerdict{Index: message.index, Role: message.role}
+		result := s.analyzeMessage(ctx, log, message.text)
+		if result == nil {
+			verdict.Failed = true
+			verdicts = append(verdicts, verdict)
+			continue
+		}
+
+		verdict.IsMalicious = result.IsMalicious
+		verdict.AttackType = result.AttackType
+		verdict.Confidence = result.Confidence
+		verdict.SkipReason = result.SkipReason
+		verdicts = append(verdicts, verdict)
+
+		if moreSevere(result, worst) {
+			worst = result
+			worstMessage = message
+		}
+	}
+	if worst == nil {
+		return nil, ""
+	}
+
+	aggregate := *worst
+	aggregate.MessageIndex = &worstMessage.index
+	aggregate.Messages = verdicts
+	aggregate.ProcessingTime = time.Since(start)
+	return &aggregate, worstMessage.text
+}
+
+// analyzeMessage settles one message through the prechecks or full analysis
+// with retries. It returns nil when every attempt failed.
+func (s *Server) analyzeMessage(ctx context.Context, log *logger.Logger, prompt string) *security.SecurityResult {
+	// A new prompt template is always fully analyzed
+	policy, _ := ctxkeys.Policy.Value(ctx)
+	if result := s.precheckPrompt(prompt, time.Now(), policy.NewTemplate); result != nil {
+		return result
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		result, err := s.vectorSecurity.AnalyzePrompt(ctx, prompt)
+		if err == nil {
+			return result
+		}
+		log.Warn("Vector analysis attempt failed", zap.Int("attempt", attempt), zap.Error(err))
+		time.Sleep(100 * time.Millisecond) // Backoff
+	}
+	return nil
+}
+
+// moreSevere reports whether result outranks current: malicious before
+// benign, analyzed before skipped, then by confidence. Ties keep the earlier
+// message.
+func moreSevere(result, current *security.SecurityResult) bool {
+	if current == nil {
+		return true
+	}
+	if result.IsMalicious != current.IsMalicious {
+		return result.IsMalicious
+	}
+	if analyzed := result.SkipReason == ""; analyzed != (current.SkipReason == "") {
+		return analyzed
+	}
+	return result.Confidence > current.Confidence
+}
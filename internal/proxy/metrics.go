@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+	"go.uber.org/zap"
+)
+
+// setupMetrics registers the label taxonomy as attack categories and
+// exports component health and database pool stats
+func (s *Server) setupMetrics() {
+	for _, category := range s.cfg().ETL.Labels.Mapping {
+		metrics.AddCategories(category)
+	}
+
+	if s.components != nil {
+		s.registerCollector(&componentCollector{checker: s.components}, "component health")
+	}
+	if s.vectorStore != nil {
+		s.registerCollector(&poolCollector{store: s.vectorStore}, "database pool")
+	}
+}
+
+// registerCollector registers c, tolerating a collector left registered by an
+// earlier server instance
+func (s *Server) registerCollector(c prometheus.Collector, name string) {
+	err := metrics.Register(c)
+	var already prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &already) {
+		s.logger.Warn("Failed to register "+name+" metrics", zap.Error(err))
+	}
+}
+
+var componentUpDesc = prometheus.NewDesc(
+	"llm_sentinel_component_up",
+	"Whether a component is serving: 1 ok, 0.5 degraded, 0 down. Disabled components are not reported.",
+	[]string{"component", "critical"}, nil,
+)
+
+// componentCollector checks component health at scrape time so the gauge is
+// never staler than the scrape interval
+type componentCollector struct {
+	checker componentChecker
+}
+
+func (c *componentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- componentUpDesc
+}
+
+func (c *componentCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), componentHealthTimeout)
+	defer cancel()
+
+	for _, component := range c.checker.CheckComponents(ctx) {
+		var value float64
+		switch component.Status {
+		case embeddings.HealthDisabled:
+			continue
+		case embeddings.HealthOK:
+			value = 1
+		case embeddings.HealthDegraded:
+			value = 0.5
+		}
+
+		critical := "false"
+		if component.Critical {
+			critical = "true"
+		}
+		ch <- prometheus.MustNewConstMetric(componentUpDesc, prometheus.GaugeValue, value, component.Name, critical)
+	}
+}
+
+var (
+	poolConnectionsDesc = prometheus.NewDesc(
+		"llm_sentinel_db_pool_connections",
+		"Database pool connections by state (in_use, idle).",
+		[]string{"pool", "state"}, nil,
+	)
+	poolMaxOpenDesc = prometheus.NewDesc(
+		"llm_sentinel_db_pool_max_open_connections",
+		"Configured maximum open connections of a database pool.",
+		[]string{"pool"}, nil,
+	)
+	poolWaitsDesc = prometheus.NewDesc(
+		"llm_sentinel_db_pool_waits_total",
+		"Times a caller had to wait for a database connection.",
+		[]string{"pool"}, nil,
+	)
+	poolWaitSecondsDesc = prometheus.NewDesc(
+		"llm_sentinel_db_pool_wait_seconds_total",
+		"Total time callers spent waiting for a database connection.",
+		[]string{"pool"}, nil,
+	)
+)
+
+// poolCollector reads connection pool stats at scrape time
+type poolCollector struct {
+	store vector.Backend
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnectionsDesc
+	ch <- poolMaxOpenDesc
+	ch <- poolWaitsDesc
+	ch <- poolWaitSecondsDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.store.PoolStats() {
+		ch <- prometheus.MustNewConstMetric(poolConnectionsDesc, prometheus.GaugeValue, float64(stats.InUse), stats.Pool, "in_use")
+		ch <- prometheus.MustNewConstMetric(poolConnectionsDesc, prometheus.GaugeValue, float64(stats.Idle), stats.Pool, "idle")
+		ch <- prometheus.MustNewConstMetric(poolMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpen), stats.Pool)
+		ch <- prometheus.MustNewConstMetric(poolWaitsDesc, prometheus.CounterValue, float64(stats.WaitCount), stats.Pool)
+		ch <- prometheus.MustNewConstMetric(poolWaitSecondsDesc, prometheus.CounterValue, stats.WaitDuration.Seconds(), stats.Pool)
+	}
+}
@@ -3,35 +3,136 @@ package proxy
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
 	"github.com/raaihank/llm-sentinel/internal/security"
+	"github.com/raaihank/llm-sentinel/internal/usage"
 	"github.com/raaihank/llm-sentinel/internal/websocket"
 	"go.uber.org/zap"
 )
 
-type contextKey string
+// auditRecordKey carries the request's audit record
+var auditRecordKey = ctxkeys.New[*auditRecord]("audit_record")
+
+// auditRecord collects per-request details filled in further down the chain
+// and reported when the request completes
+type auditRecord struct {
+	mu       sync.Mutex
+	usage    *usage.Usage
+	streamed bool
+	scores   map[string]float32 // attack category scores from prompt analysis
+	client   string             // authenticated client_auth client
+	estimate *usage.Estimate    // pre-flight cost estimate
+	price    modelPricing       // the estimate's price, applied to the reported usage too
+}
+
+func (a *auditRecord) setUsage(u *usage.Usage) {
+	a.mu.Lock()
+	a.usage = u
+	a.mu.Unlock()
+}
+
+func (a *auditRecord) getUsage() *usage.Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.usage
+}
+
+func (a *auditRecord) setStreamed() {
+	a.mu.Lock()
+	a.streamed = true
+	a.mu.Unlock()
+}
+
+func (a *auditRecord) isStreamed() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.streamed
+}
 
-const requestIDKey = contextKey("request_id")
-const originalHeadersKey = contextKey("original_headers")
-const privacyFindingsKey = contextKey("privacy_findings")
+func (a *auditRecord) setScores(scores map[string]float32) {
+	a.mu.Lock()
+	a.scores = scores
+	a.mu.Unlock()
+}
+
+func (a *auditRecord) getScores() map[string]float32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.scores
+}
+
+func (a *auditRecord) setClient(client string) {
+	a.mu.Lock()
+	a.client = client
+	a.mu.Unlock()
+}
+
+func (a *auditRecord) getClient() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.client
+}
+
+func (a *auditRecord) setCostEstimate(estimate *usage.Estimate, price modelPricing) {
+	a.mu.Lock()
+	a.estimate = estimate
+	a.price = price
+	a.mu.Unlock()
+}
+
+// getCostEstimate returns the request's cost estimate with the cost of the
+// usage the provider reported, for reconciliation, or nil if none was made
+func (a *auditRecord) getCostEstimate() *usage.Estimate {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.estimate == nil {
+		return nil
+	}
+	estimate := *a.estimate
+	if a.usage != nil {
+		estimate.ReportedCostUSD = a.price.usd(a.usage.PromptTokens, a.usage.CompletionTokens)
+	}
+	return &estimate
+}
+
+// getAuditRecord extracts the request's audit record from context
+func getAuditRecord(ctx context.Context) *auditRecord {
+	record, _ := auditRecordKey.Value(ctx)
+	return record
+}
 
 // loggingMiddleware logs HTTP requests and responses
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Generate request ID
-		requestID := generateRequestID()
-		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		// Reuse the request ID assigned by the recovery middleware
+		requestID, ok := ctxkeys.RequestID.Value(r.Context())
+		if !ok {
+			requestID = generateRequestID()
+		}
+		record := &auditRecord{}
+		ctx := ctxkeys.RequestID.WithValue(r.Context(), requestID)
+		ctx = auditRecordKey.WithValue(ctx, record)
 		r = r.WithContext(ctx)
 
 		// Create response writer wrapper to capture response data
 		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		if s.latency != nil {
+			rw.trace = newLatencyTrace()
+			r = r.WithContext(latencyTraceKey.WithValue(r.Context(), rw.trace))
+		}
 
 		// Log request
 		s.logger.WithRequestID(requestID).Info("HTTP request started",
@@ -46,11 +147,46 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 
 		// Log response
 		duration := time.Since(start)
-		s.logger.WithRequestID(requestID).Info("HTTP request completed",
+		fields := []zap.Field{
 			zap.Int("status_code", rw.statusCode),
 			zap.Duration("duration", duration),
 			zap.Int("response_size", rw.size),
-		)
+		}
+		client := record.getClient()
+		if client != "" {
+			fields = append(fields, zap.String("client", client))
+		}
+		streamed := record.isStreamed()
+		if streamed {
+			fields = append(fields, zap.Bool("streamed", true))
+		}
+		scores := record.getScores()
+		if len(scores) > 0 {
+			fields = append(fields, zap.Any("attack_scores", scores))
+		}
+		consumed := record.getUsage()
+		if consumed != nil {
+			fields = append(fields,
+				zap.String("provider", consumed.Provider),
+				zap.String("model", consumed.Model),
+				zap.Int("prompt_tokens", consumed.PromptTokens),
+				zap.Int("completion_tokens", consumed.CompletionTokens),
+				zap.Int("total_tokens", consumed.TotalTokens),
+			)
+		}
+		estimate := record.getCostEstimate()
+		if estimate != nil {
+			fields = append(fields, zap.Float64("estimated_cost_usd", estimate.CostUSD))
+			if consumed != nil {
+				fields = append(fields, zap.Float64("reported_cost_usd", estimate.ReportedCostUSD))
+			}
+		}
+		s.logger.WithRequestID(requestID).Info("HTTP request completed", fields...)
+		if rw.trace != nil {
+			if sample, ok := rw.trace.sample(); ok {
+				s.latency.observe(sample)
+			}
+		}
 
 		// Broadcast request completion event to WebSocket for response time tracking
 		completionEvent := websocket.Event{
@@ -64,16 +200,171 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 				StatusCode:   rw.statusCode,
 				ResponseTime: float64(duration.Nanoseconds()) / 1e6, // Convert to milliseconds
 				ResponseSize: rw.size,
+				Usage:        consumed,
+				Streamed:     streamed,
+				AttackScores: scores,
+				Client:       client,
+				CostEstimate: estimate,
 			},
 		}
-		s.wsHub.BroadcastEvent(completionEvent)
+		s.emit(completionEvent)
+	})
+}
+
+// clientRateLimitPrefix marks the limiter keys of authenticated clients
+const clientRateLimitPrefix = "client:"
+
+// rateLimitMiddleware enforces per-client request rates. Authenticated
+// clients are keyed by name, with any per-client limits. Other clients are
+// keyed by canonical IP aggregated to the configured prefix, so IPv6 clients
+// rotating interface identifiers inside one /64 share a single bucket, and
+// with per_api_key also by the provider API key they present.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.cfg()
+		rl := cfg.Security.RateLimit
+		if !cfg.Security.Enabled || !rl.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		keys := []string{clientKey(getClientIP(r, cfg.Server.TrustedProxies), rl.IPv4Prefix, rl.IPv6Prefix)}
+		if client, ok := ctxkeys.Client.Value(r.Context()); ok {
+			keys[0] = clientRateLimitPrefix + client
+		} else if apiKey, ok := apiKeyIdentity(r); ok && rl.PerAPIKey {
+			keys = append(keys, apiKey)
+		}
+		for _, key := range keys {
+			if !s.rateLimiters.allow(r.Context(), key, rateLimitFor(cfg, key)) {
+				s.logger.WithRequestID(getRequestID(r.Context())).Warn("Rate limit exceeded",
+					zap.String("client_key", key))
+				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestSizeMiddleware rejects provider requests whose body exceeds
+// max_request_size. A declared Content-Length is checked before reading;
+// other bodies are read through http.MaxBytesReader into the request
+// analysis the later stages share, so no stage sees a truncated body.
+func (s *Server) requestSizeMiddleware(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg := s.cfg()
+			rl := cfg.Security.RateLimit
+			if !cfg.Security.Enabled || !rl.Enabled || rl.MaxRequestSize <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := int64(rl.MaxRequestSize)
+			if r.ContentLength > limit {
+				s.rejectOversized(w, r, route, rl, r.ContentLength)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			_, r, err := readRequestAnalysis(r)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				s.rejectOversized(w, r, route, rl, -1)
+				return
+			}
+			if err != nil {
+				s.logger.WithRequestID(getRequestID(r.Context())).Error("Failed to read request body", zap.Error(err))
+				http.Error(w, "Failed to read request", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectOversized answers a request whose body is over the size limit.
+// size is the declared Content-Length, or -1 when the body was cut off
+// while reading.
+func (s *Server) rejectOversized(w http.ResponseWriter, r *http.Request, route string, rl config.RateLimitConfig, size int64) {
+	metrics.ObserveOversizedRequest(route)
+	s.logger.WithRequestID(getRequestID(r.Context())).Warn("Request body too large",
+		zap.String("route", route),
+		zap.Int64("content_length", size),
+		zap.Int("max_request_size", rl.MaxRequestSize),
+		zap.String("client_ip", getClientIP(r, s.cfg().Server.TrustedProxies)))
+	s.writeErrorResponse(w, r, rl.OversizeStatusCode, "invalid_request_error", "request_too_large",
+		fmt.Sprintf("Request body exceeds the %d byte limit", rl.MaxRequestSize), nil)
+}
+
+// anomalyMiddleware baselines each client's request rate and active hours
+// and broadcasts an event when activity deviates sharply from it. It also
+// resolves the client identity and security policy for the request.
+func (s *Server) anomalyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.cfg()
+		identity := clientIdentity(r, cfg)
+		policy := s.requestPolicy(r)
+
+		// Remember identity and policy; auth headers may be scrubbed further down the chain
+		ctx := ctxkeys.ClientIdentity.WithValue(r.Context(), identity)
+		if s.anomalies == nil {
+			next.ServeHTTP(w, r.WithContext(ctxkeys.Policy.WithValue(ctx, policy)))
+			return
+		}
+
+		for _, anomaly := range s.anomalies.Observe(identity, time.Now()) {
+			s.logger.WithRequestID(getRequestID(r.Context())).Warn("Client behavior anomaly detected",
+				zap.String("client_key", anomaly.ClientKey),
+				zap.String("kind", anomaly.Kind),
+				zap.Float64("observed", anomaly.Observed),
+				zap.Float64("baseline", anomaly.Baseline))
+
+			s.emit(websocket.Event{
+				Type:      websocket.EventTypeAnomaly,
+				Timestamp: time.Now(),
+				RequestID: getRequestID(r.Context()),
+				Data: websocket.AnomalyEvent{
+					ClientKey:  anomaly.ClientKey,
+					Kind:       anomaly.Kind,
+					Observed:   anomaly.Observed,
+					Baseline:   anomaly.Baseline,
+					Hour:       anomaly.Hour,
+					WindowEnds: anomaly.WindowEnds,
+					Tightened:  cfg.Security.Anomaly.TightenThresholds,
+				},
+			})
+		}
+
+		policy.Tightened = cfg.Security.Anomaly.TightenThresholds && s.anomalies.InAnomaly(identity, time.Now())
+		next.ServeHTTP(w, r.WithContext(ctxkeys.Policy.WithValue(ctx, policy)))
 	})
 }
 
+// blockThreshold returns the block threshold for a request: the policy's
+// own threshold if it sets one, lowered when the policy is tightened (e.g.
+// during a client anomaly window) and again for a new prompt template
+func (s *Server) blockThreshold(r *http.Request) float32 {
+	policy := s.requestPolicy(r)
+	threshold := s.vectorSecurity.GetBlockThreshold()
+	if policy.BlockThreshold > 0 {
+		threshold = policy.BlockThreshold
+	}
+	if policy.Tightened {
+		threshold *= s.cfg().Security.Anomaly.TightenFactor
+	}
+	if policy.NewTemplate {
+		threshold *= s.cfg().Security.Templates.TightenFactor
+	}
+	return threshold
+}
+
 // privacyMiddleware applies PII detection and masking to requests
 func (s *Server) privacyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.config.Privacy.Enabled {
+		if !s.cfg().Privacy.Enabled {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -82,36 +373,37 @@ func (s *Server) privacyMiddleware(next http.Handler) http.Handler {
 		logger := s.logger.WithRequestID(requestID)
 
 		// Store original headers in context before processing
-		originalHeaders := make(map[string][]string)
-		for key, values := range r.Header {
-			originalHeaders[key] = make([]string, len(values))
-			copy(originalHeaders[key], values)
-		}
-		ctx := context.WithValue(r.Context(), originalHeadersKey, originalHeaders)
-		r = r.WithContext(ctx)
+		r = r.WithContext(ctxkeys.OriginalHeaders.WithValue(r.Context(), r.Header.Clone()))
 
-		// Read request body
-		body, err := io.ReadAll(r.Body)
+		analysis, r, err := readRequestAnalysis(r)
 		if err != nil {
 			logger.Error("Failed to read request body", zap.Error(err))
 			http.Error(w, "Failed to read request", http.StatusInternalServerError)
 			return
 		}
-		r.Body.Close()
 
 		// Process headers for sensitive data (for logging purposes only)
 		// Don't modify the actual request headers - they'll be handled in the proxy
 		processedHeaders := s.detector.ProcessHeaders(r.Header)
 
 		// Store processed headers for logging but keep original headers on request
-		ctx = context.WithValue(ctx, "processed_headers", processedHeaders)
-		r = r.WithContext(ctx)
+		r = r.WithContext(ctxkeys.ProcessedHeaders.WithValue(r.Context(), processedHeaders))
 
 		// Process body for PII
 		piiStart := time.Now()
-		result := s.detector.ProcessText(string(body))
+		result := s.detector.ProcessTextWith(string(analysis.body), s.requestPolicy(r).Detectors)
 		piiDuration := time.Since(piiStart)
 
+		// A suspended rule did not scan the body, so it is refused rather
+		// than forwarded and billed as a redacted placeholder
+		if result.SuspendedRule != "" {
+			logger.Warn("Refusing request while a PII rule is suspended", zap.String("rule", result.SuspendedRule))
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(time.Until(result.SuspendedUntil).Seconds())))))
+			s.writeErrorResponse(w, r, http.StatusServiceUnavailable, "detector_unavailable", "pii_rule_suspended",
+				fmt.Sprintf("PII rule %s is suspended for exceeding its time budget; retry after it resumes", result.SuspendedRule), nil)
+			return
+		}
+
 		// Log findings
 		if len(result.Findings) > 0 {
 			logger.Info("PII detected in request",
@@ -128,7 +420,7 @@ func (s *Server) privacyMiddleware(next http.Handler) http.Handler {
 					RequestID:     requestID,
 					Method:        r.Method,
 					Path:          r.URL.Path,
-					ClientIP:      getClientIP(r),
+					ClientIP:      getClientIP(r, s.cfg().Server.TrustedProxies),
 					UserAgent:     r.UserAgent(),
 					Findings:      result.Findings,
 					TotalFindings: len(result.Findings),
@@ -136,25 +428,27 @@ func (s *Server) privacyMiddleware(next http.Handler) http.Handler {
 					ProcessingMS:  float64(piiDuration.Nanoseconds()) / 1e6,
 				},
 			}
-			s.wsHub.BroadcastEvent(piiEvent)
+			s.emit(piiEvent)
 		}
 
-		// Replace request body with masked version
-		r.Body = io.NopCloser(bytes.NewReader([]byte(result.MaskedText)))
-		r.ContentLength = int64(len(result.MaskedText))
+		// Replace request body with masked version. The analysis keeps the
+		// unmasked body for vector analysis if configured; it is never
+		// forwarded or logged.
+		masked := []byte(result.MaskedText)
+		analysis.setMasked(masked, result.Findings)
+		r.Body = io.NopCloser(bytes.NewReader(masked))
+		r.ContentLength = int64(len(masked))
 
-		// Store findings in context for metrics/dashboard
-		ctx = context.WithValue(ctx, privacyFindingsKey, result.Findings)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
 
 // vectorSecurityMiddleware applies ML-based prompt security analysis
 func (s *Server) vectorSecurityMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip if vector security is not enabled or not available
-		if s.vectorSecurity == nil || !s.vectorSecurity.IsEnabled() {
+		// Skip if vector security is not enabled or not available, or the
+		// request's policy passes it through unanalyzed
+		if s.vectorSecurity == nil || !s.vectorSecurity.IsEnabled() || s.requestPolicy(r).Mode == "passthrough" {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -162,124 +456,197 @@ func (s *Server) vectorSecurityMiddleware(next http.Handler) http.Handler {
 		requestID := getRequestID(r.Context())
 		logger := s.logger.WithRequestID(requestID)
 
-		// Read request body
-		body, err := io.ReadAll(r.Body)
+		analysis, r, err := readRequestAnalysis(r)
 		if err != nil {
 			logger.Error("Failed to read request body for vector analysis", zap.Error(err))
 			next.ServeHTTP(w, r)
 			return
 		}
-		r.Body.Close()
-
-		// Try to extract prompt from JSON body
-		var requestData map[string]interface{}
-		prompt := ""
-
-		if err := json.Unmarshal(body, &requestData); err == nil {
-			// Try common prompt fields
-			if p, ok := requestData["prompt"].(string); ok {
-				prompt = p
-			} else if p, ok := requestData["input"].(string); ok {
-				prompt = p
-			} else if messages, ok := requestData["messages"].([]interface{}); ok {
-				// Handle OpenAI-style messages
-				if len(messages) > 0 {
-					if msg, ok := messages[len(messages)-1].(map[string]interface{}); ok {
-						if content, ok := msg["content"].(string); ok {
-							prompt = content
-						}
-					}
-				}
-			}
+
+		// Analyze the pre-masking text when configured; the masked body is
+		// what gets forwarded either way
+		vsConfig := s.cfg().Security.VectorSecurity
+		messages := analysis.selectPrompts(analysis.masked && vsConfig.AnalyzeOriginal, vsConfig)
+		if len(messages) > 0 && s.templates != nil {
+			r = s.observeTemplate(r, messages)
+		}
+
+		// Overlap analysis with upstream connection setup: the body is gated
+		// until the verdict resolves, so nothing is forwarded before it
+		if len(messages) > 0 && vsConfig.ConcurrentAnalysis {
+			verdict := newPendingVerdict()
+			go func() {
+				result, blocked := s.analyzePrompt(r, requestID, messages)
+				verdict.resolve(result, blocked)
+			}()
+
+			ctx := pendingVerdictKey.WithValue(r.Context(), verdict)
+			r = r.WithContext(ctx)
+			r.Body = &gatedBody{ctx: ctx, reader: bytes.NewReader(analysis.body), verdict: verdict}
+			r.ContentLength = int64(len(analysis.body))
+
+			next.ServeHTTP(w, r)
+			return
 		}
 
 		// If we found a prompt, analyze it
-		if prompt != "" {
-			var result *security.SecurityResult
-			for attempt := 0; attempt < 3; attempt++ {
-				var err error
-				result, err = s.vectorSecurity.AnalyzePrompt(r.Context(), prompt)
-				if err == nil {
-					break
-				}
-				logger.Warn("Vector analysis attempt failed", zap.Int("attempt", attempt), zap.Error(err))
-				time.Sleep(100 * time.Millisecond) // Backoff
-			}
-			if result == nil {
-				logger.Error("All vector analysis attempts failed, passing through")
-				// Proceed without blocking
-			} else {
-				// Log the analysis result
-				logger.Info("Vector security analysis completed",
-					zap.Bool("is_malicious", result.IsMalicious),
-					zap.String("attack_type", result.AttackType),
-					zap.Float32("confidence", result.Confidence),
-					zap.Duration("processing_time", result.ProcessingTime))
-
-				// Broadcast vector security event
-				if result.IsMalicious || result.Confidence > 0.5 { // Broadcast even medium confidence
-					action := "logged"
-					if result.IsMalicious && result.Confidence >= s.vectorSecurity.GetBlockThreshold() {
-						action = "blocked"
-					}
-
-					vectorEvent := websocket.Event{
-						Type:      websocket.EventTypeVectorSecurity,
-						Timestamp: time.Now(),
-						RequestID: requestID,
-						Data: websocket.VectorSecurityEvent{
-							RequestID:    requestID,
-							Method:       r.Method,
-							Path:         r.URL.Path,
-							ClientIP:     getClientIP(r),
-							UserAgent:    r.UserAgent(),
-							IsMalicious:  result.IsMalicious,
-							AttackType:   result.AttackType,
-							Confidence:   result.Confidence,
-							Similarity:   result.SimilarityScore,
-							MatchedText:  result.MatchedText,
-							Action:       action,
-							ProcessingMS: float64(result.ProcessingTime.Nanoseconds()) / 1e6,
-						},
-					}
-					s.wsHub.BroadcastEvent(vectorEvent)
-				}
-
-				// Block request if malicious and above threshold
-				if result.IsMalicious && result.Confidence >= s.vectorSecurity.GetBlockThreshold() {
-					logger.Warn("Blocking malicious request",
-						zap.String("attack_type", result.AttackType),
-						zap.Float32("confidence", result.Confidence))
-
-					http.Error(w, fmt.Sprintf("Request blocked: %s detected (confidence: %.1f%%)",
-						result.AttackType, result.Confidence*100), http.StatusForbidden)
-					return
-				}
+		if len(messages) > 0 {
+			result, blocked := s.analyzePrompt(r, requestID, messages)
+			if blocked {
+				s.writeBlockedResponse(w, r, result)
+				return
 			}
+			r = r.WithContext(ctxkeys.Verdict.WithValue(r.Context(), newAnalysisVerdict(result, blocked)))
 		}
 
-		// Restore request body for next middleware
-		r.Body = io.NopCloser(bytes.NewReader(body))
-		r.ContentLength = int64(len(body))
-
 		next.ServeHTTP(w, r)
 	})
 }
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
+// analyzePrompt runs vector analysis over the request's messages, logs and
+// broadcasts the most severe verdict, and reports whether the request should
+// be blocked
+func (s *Server) analyzePrompt(r *http.Request, requestID string, messages []promptMessage) (*security.SecurityResult, bool) {
+	logger := s.logger.WithRequestID(requestID)
+
+	result, prompt := s.analyzeMessages(r.Context(), logger, messages)
+	if result == nil {
+		logger.Error("All vector analysis attempts failed, passing through")
+		// Proceed without blocking
+		return nil, false
+	}
+	if result.SkipReason != "" {
+		logger.Info("Vector security analysis skipped", zap.String("skip_reason", result.SkipReason))
+		s.recordAnalysis(r, requestID, prompt, result, false)
+		return result, false
+	}
+
+	// Log the analysis result
+	analysisInput := "masked"
+	if analysis := getRequestAnalysis(r.Context()); analysis != nil && analysis.fromOriginal {
+		analysisInput = "original"
+	}
+	fields := []zap.Field{
+		zap.String("analysis_input", analysisInput),
+		zap.Bool("is_malicious", result.IsMalicious),
+		zap.String("attack_type", result.AttackType),
+		zap.Any("scores", result.Scores),
+		zap.Float32("confidence", result.Confidence),
+		zap.Duration("processing_time", result.ProcessingTime),
+	}
+	if result.MessageIndex != nil {
+		fields = append(fields,
+			zap.Int("message_index", *result.MessageIndex),
+			zap.Any("message_verdicts", result.Messages))
 	}
+	logger.Info("Vector security analysis completed", fields...)
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	if record := getAuditRecord(r.Context()); record != nil && len(result.Scores) > 0 {
+		record.setScores(result.Scores)
 	}
 
-	// Fall back to RemoteAddr
-	return r.RemoteAddr
+	// Only block mode acts on a verdict; log mode records it
+	blocked := s.requestPolicy(r).Mode == "block" && result.IsMalicious && result.Confidence >= s.blockThreshold(r)
+	s.recordAnalysis(r, requestID, prompt, result, blocked)
+	metrics.ObserveDetection(result.AttackType, detectionAction(result, blocked), result.Confidence)
+
+	// Broadcast vector security event
+	if result.IsMalicious || result.Confidence > 0.5 { // Broadcast even medium confidence
+		action := "logged"
+		if blocked {
+			action = "blocked"
+		}
+
+		vectorEvent := websocket.Event{
+			Type:      websocket.EventTypeVectorSecurity,
+			Timestamp: time.Now(),
+			RequestID: requestID,
+			Data: websocket.VectorSecurityEvent{
+				RequestID:    requestID,
+				Method:       r.Method,
+				Path:         r.URL.Path,
+				ClientIP:     getClientIP(r, s.cfg().Server.TrustedProxies),
+				UserAgent:    r.UserAgent(),
+				IsMalicious:  result.IsMalicious,
+				AttackType:   result.AttackType,
+				Scores:       result.Scores,
+				Confidence:   result.Confidence,
+				Similarity:   result.SimilarityScore,
+				MatchedText:  result.MatchedText,
+				MessageIndex: result.MessageIndex,
+				Action:       action,
+				ProcessingMS: float64(result.ProcessingTime.Nanoseconds()) / 1e6,
+			},
+		}
+		s.emit(vectorEvent)
+	}
+
+	// Block request if malicious and above threshold
+	if blocked {
+		fields := []zap.Field{
+			zap.String("attack_type", result.AttackType),
+			zap.Float32("confidence", result.Confidence),
+		}
+		if result.MessageIndex != nil {
+			fields = append(fields, zap.Int("message_index", *result.MessageIndex))
+		}
+		logger.Warn("Blocking malicious request", fields...)
+
+		if client, conversationID := getConversation(r.Context()); conversationID != "" && s.conversations != nil {
+			s.conversations.RecordBlock(client, conversationID, result.AttackType)
+		}
+		s.recordTemplateBlock(r)
+	}
+
+	return result, blocked
+}
+
+// precheckPrompt settles a prompt without embedding it when possible: known
+// attack replays are flagged and obviously benign prompts skipped, unless
+// strict. It returns nil when full analysis is needed.
+func (s *Server) precheckPrompt(prompt string, start time.Time, strict bool) *security.SecurityResult {
+	// Exact replays of known attacks are blocked before any other work
+	if s.knownAttacks != nil && s.knownAttacks.Contains(prompt) {
+		return security.NewKnownAttackResult(start)
+	}
+
+	// Obviously benign short prompts skip embedding generation. PII masking
+	// has already run in privacyMiddleware and is never bypassed.
+	vsConfig := s.cfg().Security.VectorSecurity
+	if !vsConfig.StrictMode && !strict {
+		if reason, ok := security.FastPathCheck(prompt, vsConfig.FastPathMaxLength); ok {
+			return security.NewSkippedResult(reason, start)
+		}
+	}
+	return nil
+}
+
+// detectionAction names what was done with an analyzed prompt for metrics
+func detectionAction(result *security.SecurityResult, blocked bool) string {
+	switch {
+	case blocked:
+		return "blocked"
+	case result.IsMalicious:
+		return "flagged"
+	default:
+		return "allowed"
+	}
+}
+
+// newAnalysisVerdict summarizes an analysis result for downstream handlers
+func newAnalysisVerdict(result *security.SecurityResult, blocked bool) ctxkeys.AnalysisVerdict {
+	if result == nil {
+		return ctxkeys.AnalysisVerdict{}
+	}
+	return ctxkeys.AnalysisVerdict{
+		Analyzed:   result.SkipReason == "",
+		Malicious:  result.IsMalicious,
+		Blocked:    blocked,
+		AttackType: result.AttackType,
+		Scores:     result.Scores,
+		Confidence: result.Confidence,
+		SkipReason: result.SkipReason,
+	}
 }
 
 // responseWriter wraps http.ResponseWriter to capture response data
@@ -287,6 +654,7 @@ type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	size       int
+	trace      *latencyTrace // nil unless streaming latency is audited
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -295,11 +663,19 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.trace != nil && len(b) > 0 {
+		rw.trace.mark(&rw.trace.clientFirstByte)
+	}
 	size, err := rw.ResponseWriter.Write(b)
 	rw.size += size
 	return size, err
 }
 
+// Unwrap exposes the underlying writer so streamed responses can be flushed
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // generateRequestID generates a unique request ID
 func generateRequestID() string {
 	// Simple implementation - in production, use UUID or similar
@@ -308,7 +684,7 @@ func generateRequestID() string {
 
 // getRequestID extracts request ID from context
 func getRequestID(ctx context.Context) string {
-	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+	if requestID, ok := ctxkeys.RequestID.Value(ctx); ok {
 		return requestID
 	}
 	return "unknown"
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/jsonduration"
+	"go.uber.org/zap"
+)
+
+// ModeSettings describes the operational mode of the server. Both flags can
+// be switched at runtime through /admin/mode.
+type ModeSettings struct {
+	ReadOnly    bool          `json:"read_only"`   // reject corpus and config mutations
+	Maintenance bool          `json:"maintenance"` // reject proxy traffic with 503
+	RetryAfter  time.Duration `json:"retry_after"` // advertised to clients during maintenance
+}
+
+// modeSettingsJSON is ModeSettings with the retry delay as a duration
+// string such as "30s"
+type modeSettingsJSON struct {
+	ReadOnly    bool                  `json:"read_only"`
+	Maintenance bool                  `json:"maintenance"`
+	RetryAfter  jsonduration.Duration `json:"retry_after"`
+}
+
+// MarshalJSON writes the retry delay as a duration string
+func (m ModeSettings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(modeSettingsJSON{
+		ReadOnly:    m.ReadOnly,
+		Maintenance: m.Maintenance,
+		RetryAfter:  jsonduration.Duration(m.RetryAfter),
+	})
+}
+
+// UnmarshalJSON reads the retry delay as a duration string such as "30s"
+func (m *ModeSettings) UnmarshalJSON(data []byte) error {
+	var raw modeSettingsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = ModeSettings{
+		ReadOnly:    raw.ReadOnly,
+		Maintenance: raw.Maintenance,
+		RetryAfter:  time.Duration(raw.RetryAfter),
+	}
+	return nil
+}
+
+// Name returns a short label for the mode, used in health responses
+func (m ModeSettings) Name() string {
+	switch {
+	case m.Maintenance:
+		return "maintenance"
+	case m.ReadOnly:
+		return "read_only"
+	default:
+		return "normal"
+	}
+}
+
+// currentMode returns a copy of the mode settings in effect
+func (s *Server) currentMode() ModeSettings {
+	s.modeMu.RLock()
+	defer s.modeMu.RUnlock()
+	return s.mode
+}
+
+// maintenanceMiddleware turns proxy traffic away while in maintenance mode.
+// Admin, health and dashboard routes are not wrapped and stay available.
+func (s *Server) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mode := s.currentMode()
+		if !mode.Maintenance {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(mode.RetryAfter.Seconds()))))
+		http.Error(w, "Service is undergoing maintenance", http.StatusServiceUnavailable)
+	})
+}
+
+// readOnlyMiddleware rejects mutating admin requests while in read-only mode.
+// The mode endpoint itself is exempt so an admin can switch the server back,
+// as are the explain and replay endpoints, which only read despite being
+// POSTs.
+func (s *Server) readOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.currentMode().ReadOnly || !isMutation(r) ||
+			!strings.HasPrefix(r.URL.Path, "/admin/") || r.URL.Path == "/admin/mode" || r.URL.Path == "/admin/vector/explain" ||
+			strings.HasPrefix(r.URL.Path, "/admin/replay/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.Error(w, "Server is in read-only mode", http.StatusForbidden)
+	})
+}
+
+// isMutation reports whether the request method can change server state
+func isMutation(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// handleGetMode returns the current operational mode
+func (s *Server) handleGetMode(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.currentMode())
+}
+
+// handleUpdateMode switches read-only and maintenance mode at runtime
+func (s *Server) handleUpdateMode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read mode settings", http.StatusBadRequest)
+		return
+	}
+	var settings ModeSettings
+	if err := json.Unmarshal(body, &settings); err != nil {
+		http.Error(w, "Invalid mode settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Snapshots may hold nanoseconds, but a number from an operator is
+	// almost always meant as seconds
+	var fields struct {
+		RetryAfter json.RawMessage `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &fields) == nil && jsonduration.IsNumber(fields.RetryAfter) {
+		http.Error(w, fmt.Sprintf("invalid retry after %s: use a duration string such as \"30s\"", fields.RetryAfter), http.StatusBadRequest)
+		return
+	}
+	if settings.RetryAfter < 0 {
+		http.Error(w, fmt.Sprintf("invalid retry after: %v (must not be negative)", settings.RetryAfter), http.StatusBadRequest)
+		return
+	}
+
+	_ = s.changeRuntimeConfig(s.configAuthor(r), "mode update", func() error {
+		s.modeMu.Lock()
+		s.mode = settings
+		s.modeMu.Unlock()
+		return nil
+	})
+
+	s.logger.Warn("Server mode updated",
+		zap.String("mode", settings.Name()),
+		zap.Bool("read_only", settings.ReadOnly),
+		zap.Bool("maintenance", settings.Maintenance),
+		zap.Duration("retry_after", settings.RetryAfter))
+
+	writeJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// TestUpdateModeRetryAfter checks /admin/mode reads retry_after as a
+// duration string and advertises it in seconds during maintenance
+func TestUpdateModeRetryAfter(t *testing.T) {
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.Server.AdminTokens = map[string]string{"ops": "secret-token"}
+	})
+
+	updateMode := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/mode", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := updateMode(`{"maintenance": true, "retry_after": "30s"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT /admin/mode: status %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"retry_after":"30s"`) {
+		t.Fatalf("PUT /admin/mode returned %s, want retry_after as \"30s\"", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/openai/v1/chat/completions", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("proxy request in maintenance: status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Fatalf("Retry-After %q, want \"30\"", got)
+	}
+
+	if rec := updateMode(`{"maintenance": true, "retry_after": 30}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT /admin/mode with a bare number: status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
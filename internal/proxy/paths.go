@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"go.uber.org/zap"
+)
+
+// upstreamPathMiddleware refuses paths outside route's configured policy so
+// leaked proxy access cannot reach provider endpoints such as file storage
+func (s *Server) upstreamPathMiddleware(route string) mux.MiddlewareFunc {
+	policy, ok := s.cfg().Upstream.Paths[route]
+	return func(next http.Handler) http.Handler {
+		if !ok {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamPath := strings.TrimPrefix(r.URL.Path, "/"+route)
+			if upstreamPath == "" {
+				upstreamPath = "/"
+			}
+			if pathAllowed(policy, upstreamPath) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			s.logger.WithRequestID(getRequestID(r.Context())).Warn("Upstream path denied by policy",
+				zap.String("route", route),
+				zap.String("path", upstreamPath))
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"error": map[string]string{
+					"type":    "path_not_allowed",
+					"message": "This endpoint is not available through LLM-Sentinel",
+				},
+			})
+		})
+	}
+}
+
+// pathAllowed applies a path policy: deny wins, then an empty allow list
+// allows everything
+func pathAllowed(policy config.UpstreamPathPolicy, p string) bool {
+	p = path.Clean(p)
+	for _, pattern := range policy.Deny {
+		if pathMatches(pattern, p) {
+			return false
+		}
+	}
+	if len(policy.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range policy.Allow {
+		if pathMatches(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathMatches matches a path.Match glob, where a trailing "/**" also matches
+// every path below the prefix
+func pathMatches(pattern, p string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		if matched, _ := path.Match(prefix, p); matched {
+			return true
+		}
+		segments := strings.Count(prefix, "/")
+		parts := strings.SplitAfterN(p, "/", segments+2)
+		if len(parts) <= segments+1 {
+			return false
+		}
+		head := strings.TrimSuffix(strings.Join(parts[:segments+1], ""), "/")
+		matched, _ := path.Match(prefix, head)
+		return matched
+	}
+	matched, _ := path.Match(pattern, p)
+	return matched
+}
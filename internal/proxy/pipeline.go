@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// pipelineStage returns the middleware implementing a configured stage
+func (s *Server) pipelineStage(stage string) mux.MiddlewareFunc {
+	switch stage {
+	case "dedup":
+		return s.dedupMiddleware
+	case "rate_limit":
+		return s.rateLimitMiddleware
+	case "anomaly":
+		return s.anomalyMiddleware
+	case "conversation":
+		return s.conversationMiddleware
+	case "privacy":
+		return s.privacyMiddleware
+	case "vector_security":
+		return s.vectorSecurityMiddleware
+	default:
+		return nil
+	}
+}
+
+// routePipeline returns the configured stages for a proxy route, falling
+// back to the default pipeline when the route has no override
+func (s *Server) routePipeline(route string) []string {
+	pipeline := s.cfg().Pipeline
+	if stages, ok := pipeline.Routes[route]; ok {
+		return stages
+	}
+	return pipeline.Default
+}
+
+// mountProvider registers a provider's proxy handler under /<route> behind
+// its configured middleware pipeline
+func (s *Server) mountProvider(route string, handler http.HandlerFunc) {
+	router := s.router.PathPrefix("/" + route).Subrouter()
+	router.Use(s.loggingMiddleware)
+	router.Use(s.responseHeaderMiddleware(route))
+	router.Use(s.maintenanceMiddleware)
+	router.Use(s.clientAuthMiddleware)
+	router.Use(s.requestSizeMiddleware(route))
+	router.Use(s.upstreamPathMiddleware(route))
+	router.Use(s.policyMiddleware(route))
+	router.Use(s.upstreamAuthMiddleware(route))
+	router.Use(s.streamingMiddleware(route))
+	router.Use(s.responseCacheMiddleware(route))
+	router.Use(s.costMiddleware(route))
+
+	stages := s.routePipeline(route)
+	for _, stage := range stages {
+		if mw := s.pipelineStage(stage); mw != nil {
+			if s.latency != nil {
+				mw = timedStage(stage, mw)
+			}
+			router.Use(mw)
+		}
+	}
+	router.PathPrefix("/").HandlerFunc(handler)
+
+	s.pipelines[route] = stages
+}
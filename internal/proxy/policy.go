@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"go.uber.org/zap"
+)
+
+// policyMiddleware resolves the security policy for route's requests from
+// the first configured policy matching the upstream, path, client API key and
+// authenticated client
+func (s *Server) policyMiddleware(route string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := s.resolvePolicy(route, r)
+			if policy.Name != "" {
+				s.logger.WithRequestID(getRequestID(r.Context())).Debug("Security policy applied",
+					zap.String("policy", policy.Name),
+					zap.String("mode", policy.Mode))
+			}
+			next.ServeHTTP(w, r.WithContext(ctxkeys.Policy.WithValue(r.Context(), policy)))
+		})
+	}
+}
+
+// resolvePolicy applies the first matching policy over the global settings
+func (s *Server) resolvePolicy(route string, r *http.Request) ctxkeys.RequestPolicy {
+	cfg := s.cfg()
+	policy := ctxkeys.RequestPolicy{Mode: cfg.Security.Mode}
+	if len(cfg.Security.Policies) == 0 {
+		return policy
+	}
+
+	upstreamPath := strings.TrimPrefix(r.URL.Path, "/"+route)
+	if upstreamPath == "" {
+		upstreamPath = "/"
+	}
+	keyHash := apiKeyHash(r)
+	client, _ := ctxkeys.Client.Value(r.Context())
+
+	for i, candidate := range cfg.Security.Policies {
+		if !policyMatches(candidate, route, upstreamPath, keyHash, client) {
+			continue
+		}
+		policy.Name = candidate.Name
+		if policy.Name == "" {
+			policy.Name = fmt.Sprintf("#%d", i+1)
+		}
+		if candidate.Mode != "" {
+			policy.Mode = candidate.Mode
+		}
+		policy.BlockThreshold = candidate.BlockThreshold
+		if len(candidate.Detectors) > 0 {
+			policy.Detectors = candidate.Detectors
+		}
+		break
+	}
+	return policy
+}
+
+// requestPolicy returns the policy resolved for r, or the global settings
+// for requests that did not pass through policyMiddleware
+func (s *Server) requestPolicy(r *http.Request) ctxkeys.RequestPolicy {
+	if policy, ok := ctxkeys.Policy.Value(r.Context()); ok {
+		return policy
+	}
+	return ctxkeys.RequestPolicy{Mode: s.cfg().Security.Mode}
+}
+
+// policyMatches reports whether every criterion the policy sets matches
+func policyMatches(policy config.SecurityPolicy, route, upstreamPath, keyHash, client string) bool {
+	if len(policy.Upstreams) > 0 && !containsFold(policy.Upstreams, route) {
+		return false
+	}
+	if len(policy.Paths) > 0 {
+		matched := false
+		for _, pattern := range policy.Paths {
+			if pathMatches(pattern, upstreamPath) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(policy.APIKeySHA256) > 0 && (keyHash == "" || !containsFold(policy.APIKeySHA256, keyHash)) {
+		return false
+	}
+	if len(policy.Clients) > 0 && (client == "" || !slices.Contains(policy.Clients, client)) {
+		return false
+	}
+	return true
+}
+
+// apiKeyHash returns the hex SHA-256 of the client's API key with any Bearer
+// scheme removed, or "" when the request carries none
+func apiKeyHash(r *http.Request) string {
+	for _, header := range apiKeyHeaders {
+		key := strings.TrimSpace(r.Header.Get(header))
+		if scheme, token, ok := strings.Cut(key, " "); ok && strings.EqualFold(scheme, "bearer") {
+			key = strings.TrimSpace(token)
+		}
+		if key != "" {
+			sum := sha256.Sum256([]byte(key))
+			return hex.EncodeToString(sum[:])
+		}
+	}
+	return ""
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// TestSuspendedRuleRefusesRequests checks a request a suspended PII rule
+// would have scanned is refused instead of forwarded as a placeholder
+func TestSuspendedRuleRefusesRequests(t *testing.T) {
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.Privacy.Enabled = true
+		cfg.Privacy.CustomRules = []config.CustomPIIRule{{Name: "employee_id", Pattern: `\bEMP-\d{6}\b`}}
+		// Every run overruns a 1ns budget, so the first one suspends the rule
+		cfg.Privacy.RuleLimits.MaxMatchTimePerKB = time.Nanosecond
+		cfg.Privacy.RuleLimits.SuspendAfter = 1
+		cfg.Privacy.RuleLimits.SuspendFor = time.Hour
+	})
+	upstream := &upstreamStub{}
+	handler := s.privacyMiddleware(upstream)
+
+	send := func() *httptest.ResponseRecorder {
+		body := `{"model":"gpt-4o","messages":[{"role":"user","content":"Badge EMP-123456"}]}`
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/openai/v1/chat/completions", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := send(); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want it forwarded", rec.Code)
+	}
+
+	rec := send()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("request while suspended: status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(rec.Body.String(), "employee_id is suspended") || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("request while suspended: body %s, Retry-After %q", rec.Body, rec.Header().Get("Retry-After"))
+	}
+	if upstream.count() != 1 {
+		t.Fatalf("upstream called %d times, want only the request before suspension", upstream.count())
+	}
+}
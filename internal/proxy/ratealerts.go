@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/events"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// Detection rate series; per attack type series are "detections:<type>"
+const (
+	rateSeriesDetections = "detections"
+	rateSeriesBlocks     = "blocks"
+	rateSeriesPII        = "pii"
+)
+
+// setupRateAlerts baselines detection, block and PII finding rates from the
+// internal bus and emits a detection_alert event when one spikes
+func (s *Server) setupRateAlerts() {
+	cfg := s.cfg().Security.RateAlerts
+	if !cfg.Enabled {
+		return
+	}
+
+	alerter := security.NewRateAlerter(security.RateAlertOptions{
+		Method:          cfg.Method,
+		Interval:        cfg.Interval,
+		Window:          cfg.Window,
+		Factor:          cfg.Factor,
+		MinCount:        cfg.MinCount,
+		MinObservations: cfg.MinObservations,
+		Cooldown:        cfg.Cooldown,
+		Alpha:           cfg.Alpha,
+		Beta:            cfg.Beta,
+		Gamma:           cfg.Gamma,
+		SeasonLength:    cfg.SeasonLength,
+	})
+	logger := s.logger.WithComponent("rate-alerts").Logger
+
+	observe := func(series string) {
+		alert, ok := alerter.Observe(series, time.Now())
+		if !ok {
+			return
+		}
+
+		metric, label, _ := strings.Cut(alert.Series, ":")
+		logger.Warn("Detection rate spike",
+			zap.String("series", alert.Series),
+			zap.Int("observed", alert.Observed),
+			zap.Float64("expected", alert.Expected),
+			zap.Duration("window", alert.Window))
+
+		s.emit(websocket.Event{
+			Type:      websocket.EventTypeDetectionAlert,
+			Timestamp: time.Now(),
+			Data: websocket.DetectionAlertEvent{
+				Series:   alert.Series,
+				Metric:   metric,
+				Label:    label,
+				Observed: alert.Observed,
+				Expected: alert.Expected,
+				Ratio:    alert.Ratio,
+				WindowS:  alert.Window.Seconds(),
+				Method:   alert.Method,
+			},
+		})
+	}
+
+	events.Subscribe(s.bus, TopicVectorSecurity, "rate-alerts", 0, func(data websocket.VectorSecurityEvent) {
+		if data.IsMalicious {
+			observe(rateSeriesDetections)
+			if data.AttackType != "" {
+				observe(rateSeriesDetections + ":" + data.AttackType)
+			}
+		}
+		if data.Action == "blocked" {
+			observe(rateSeriesBlocks)
+		}
+	})
+	events.Subscribe(s.bus, TopicPIIDetection, "rate-alerts", 0, func(data websocket.PIIDetectionEvent) {
+		observe(rateSeriesPII)
+	})
+
+	logger.Info("Detection rate alerts enabled",
+		zap.String("method", cfg.Method),
+		zap.Duration("window", cfg.Window),
+		zap.Float64("factor", cfg.Factor))
+}
@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rateLimiterSweepInterval is how often full, and so idle, local
+	// buckets are dropped
+	rateLimiterSweepInterval = time.Minute
+
+	// redisRateLimitTimeout bounds a Redis bucket check; a slower Redis
+	// falls back to the local buckets rather than holding the request
+	redisRateLimitTimeout = 250 * time.Millisecond
+)
+
+// tokenBucketScript takes a token from the bucket at KEYS[1], refilled at
+// ARGV[1] tokens per millisecond up to ARGV[2], and returns 1 when one was
+// available. The clock is Redis's own, so replicas with skewed clocks agree.
+// A bucket expires once it would have refilled, as a full bucket and a
+// missing one are the same.
+var tokenBucketScript = redis.NewScript(`
+if redis.replicate_commands then redis.replicate_commands() end
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return allowed
+`)
+
+// bucketLimit is the rate and burst of one client's bucket
+type bucketLimit struct {
+	requestsPerMin int
+	burst          int
+}
+
+// rateLimiterSet holds the token buckets of rate-limited clients. With the
+// redis backend the buckets are shared by every replica, and the local
+// buckets only serve while Redis is unreachable.
+type rateLimiterSet struct {
+	mu      sync.Mutex
+	buckets map[string]*rate.Limiter
+
+	redis     *redis.Client // nil for the memory backend
+	keyPrefix string
+	redisDown atomic.Bool
+	degraded  *degradationReport
+	logger    *zap.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newRateLimiterSet creates the buckets for the configured backend. An
+// unreachable Redis is not fatal: requests are limited per replica until
+// it answers.
+func newRateLimiterSet(cfg *config.Config, degraded *degradationReport, logger *zap.Logger) (*rateLimiterSet, error) {
+	l := &rateLimiterSet{
+		buckets:  make(map[string]*rate.Limiter),
+		degraded: degraded,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+
+	rl := cfg.Security.RateLimit
+	if !rl.Enabled || rl.Backend != "redis" {
+		return l, nil
+	}
+
+	client, err := newRedisClient(cfg, rl.RedisURL, rl.RedisDB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit Redis: %w", err)
+	}
+	l.redis = client
+	l.keyPrefix = rl.RedisKeyPrefix
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.redis.Ping(ctx).Err(); err != nil {
+		logger.Warn("Redis connection failed, rate limits apply per replica until it recovers", zap.Error(err))
+		l.redisDown.Store(true)
+		degraded.mark(subsystemRateLimit, "local-only", "Redis unreachable", err)
+	}
+	return l, nil
+}
+
+// allow takes a token from the bucket of key
+func (l *rateLimiterSet) allow(ctx context.Context, key string, limit bucketLimit) bool {
+	if l.redis != nil {
+		allowed, err := l.allowRedis(ctx, key, limit)
+		if err == nil {
+			if l.redisDown.CompareAndSwap(true, false) {
+				l.logger.Info("Redis reachable again, rate limits are shared across replicas")
+				l.degraded.clear(subsystemRateLimit)
+			}
+			return allowed
+		}
+		if !l.redisDown.Swap(true) {
+			l.logger.Warn("Redis rate limit check failed, limiting per replica", zap.Error(err))
+			l.degraded.mark(subsystemRateLimit, "local-only", "Redis unreachable", err)
+		}
+	}
+	return l.local(key, limit).Allow()
+}
+
+func (l *rateLimiterSet) allowRedis(ctx context.Context, key string, limit bucketLimit) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisRateLimitTimeout)
+	defer cancel()
+
+	perMillisecond := float64(limit.requestsPerMin) / float64(time.Minute/time.Millisecond)
+	allowed, err := tokenBucketScript.Run(ctx, l.redis, []string{l.keyPrefix + key}, perMillisecond, limit.burst).Int()
+	if err != nil {
+		return false, err
+	}
+	return allowed == 1, nil
+}
+
+// local returns the in-memory bucket for key, creating it on first use
+func (l *rateLimiterSet) local(key string, limit bucketLimit) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.buckets[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(float64(limit.requestsPerMin)/60.0), limit.burst)
+		l.buckets[key] = limiter
+	}
+	return limiter
+}
+
+// reset drops the local buckets, which carry the rate they were created with
+func (l *rateLimiterSet) reset() {
+	l.mu.Lock()
+	l.buckets = make(map[string]*rate.Limiter)
+	l.mu.Unlock()
+}
+
+// size returns the number of local buckets
+func (l *rateLimiterSet) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep drops buckets that have refilled completely. A full bucket behaves
+// exactly like a new one, so only clients that went quiet are forgotten.
+func (l *rateLimiterSet) sweep() {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, limiter := range l.buckets {
+		if limiter.TokensAt(now) >= float64(limiter.Burst()) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Start begins sweeping idle buckets
+func (l *rateLimiterSet) Start() {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(rateLimiterSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				l.sweep()
+			}
+		}
+	}()
+}
+
+// Close stops sweeping and closes the Redis connection
+func (l *rateLimiterSet) Close() {
+	close(l.stop)
+	l.wg.Wait()
+	if l.redis != nil {
+		l.redis.Close()
+	}
+}
+
+// rateLimitFor returns the bucket limit of a client key: the configured
+// rate, with any per-client override for authenticated clients
+func rateLimitFor(cfg *config.Config, key string) bucketLimit {
+	limit := bucketLimit{requestsPerMin: cfg.Security.RateLimit.RequestsPerMin, burst: cfg.Security.RateLimit.BurstLimit}
+	if name, isClient := strings.CutPrefix(key, clientRateLimitPrefix); isClient {
+		for _, client := range cfg.Security.ClientAuth.Clients {
+			if client.Name != name {
+				continue
+			}
+			if client.RequestsPerMin > 0 {
+				limit.requestsPerMin = client.RequestsPerMin
+			}
+			if client.BurstLimit > 0 {
+				limit.burst = client.BurstLimit
+			}
+		}
+	}
+	return limit
+}
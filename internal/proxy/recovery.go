@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// problem is an RFC 9457 problem details body
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// recoveryMiddleware wraps the whole server so a panic in any handler or
+// middleware fails only its own request. It assigns the request ID the
+// logging middleware reuses, so the panic log and the request log share it.
+// The panic is logged with its stack, counted, broadcast as a system event
+// and answered with a 500 problem+json body unless the response had already
+// started. http.ErrAbortHandler is re-raised, since it is how handlers ask
+// net/http to abort a response.
+func (s *Server) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, ok := ctxkeys.RequestID.Value(r.Context())
+		if !ok {
+			requestID = generateRequestID()
+			r = r.WithContext(ctxkeys.RequestID.WithValue(r.Context(), requestID))
+		}
+		rw := &recoveryWriter{ResponseWriter: w}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			s.handlePanic(rw, r, requestID, recovered, debug.Stack())
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// handlePanic reports a recovered panic and answers the request if it can
+func (s *Server) handlePanic(w *recoveryWriter, r *http.Request, requestID string, recovered any, stack []byte) {
+	message := fmt.Sprint(recovered)
+	s.logger.WithRequestID(requestID).Error("Panic while handling request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("panic", message),
+		zap.ByteString("stack", stack))
+	metrics.ObservePanic()
+
+	s.emit(websocket.Event{
+		Type:      websocket.EventTypeSystemStatus,
+		Timestamp: time.Now(),
+		RequestID: requestID,
+		Data: websocket.PanicEvent{
+			Status:    "panic",
+			RequestID: requestID,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Panic:     message,
+		},
+	})
+
+	if w.started {
+		// Part of the response is on the wire; the client sees it cut short
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusInternalServerError),
+		Status:    http.StatusInternalServerError,
+		Detail:    "The request could not be completed because of an internal error.",
+		Instance:  r.URL.Path,
+		RequestID: requestID,
+	})
+}
+
+// recoveryWriter records whether the response has started, so a recovered
+// panic does not write a second status line. It passes flushing and
+// hijacking through for streamed responses and WebSocket upgrades.
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.started = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoveryWriter) Flush() {
+	rw.started = true
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *recoveryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.started = true
+	}
+	return conn, buf, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// newRedisClient connects to redisURL and db, or to the embedding cache's
+// Redis when redisURL is empty. A bare host:port is accepted like elsewhere
+// in the config. The client connects lazily, so an unreachable Redis is
+// reported by the first command rather than here.
+func newRedisClient(cfg *config.Config, redisURL string, db int) (*redis.Client, error) {
+	if redisURL == "" {
+		redisURL, db = cfg.Security.VectorSecurity.Embedding.RedisURL, cfg.Security.VectorSecurity.Embedding.RedisDB
+	}
+	if !strings.Contains(redisURL, "://") {
+		redisURL = "redis://" + redisURL
+	}
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+	if db != 0 {
+		opts.DB = db
+	}
+	client := redis.NewClient(opts)
+	client.AddHook(chaos.RedisHook{})
+	return client, nil
+}
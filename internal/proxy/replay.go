@@ -0,0 +1,265 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/lru"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"go.uber.org/zap"
+)
+
+// Replay diagnoses
+const (
+	replayReproducible     = "reproducible"
+	replayNondeterministic = "nondeterministic"
+	replayCorpusDrift      = "corpus_drift"
+	replayConfigDrift      = "config_drift"
+	replayModelDrift       = "model_drift"
+)
+
+// corpusVersionTTL bounds how stale a recorded corpus version can be; the
+// version costs a query, so it is not fetched for every request
+const corpusVersionTTL = 10 * time.Second
+
+// analysisFingerprint identifies everything besides the prompt that a
+// verdict depends on
+type analysisFingerprint struct {
+	ConfigHash    string `json:"config_hash"` // security config and enabled attack patterns
+	CorpusVersion string `json:"corpus_version,omitempty"`
+	Model         string `json:"model"`
+	Tier          string `json:"tier,omitempty"` // analysis tier under load degradation
+}
+
+// analysisSnapshot is one recorded analysis
+type analysisSnapshot struct {
+	RequestID   string                   `json:"request_id"`
+	Timestamp   time.Time                `json:"timestamp"`
+	Mode        string                   `json:"mode"` // security mode of the request's policy
+	Threshold   float32                  `json:"threshold"`
+	Strict      bool                     `json:"strict,omitempty"` // fast path disabled for a new prompt template
+	Blocked     bool                     `json:"blocked"`
+	Result      *security.SecurityResult `json:"result"`
+	Fingerprint analysisFingerprint      `json:"fingerprint"`
+
+	prompt string // never serialized
+}
+
+// replayRecorder keeps the most recent analyses by request ID
+type replayRecorder struct {
+	snapshots *lru.Cache[string, *analysisSnapshot]
+
+	mu            sync.Mutex
+	corpusVersion string
+	corpusChecked time.Time
+}
+
+func newReplayRecorder(capacity int) *replayRecorder {
+	return &replayRecorder{snapshots: lru.New[string, *analysisSnapshot](capacity)}
+}
+
+// recordAnalysis keeps an analysis for later replay when replay is enabled
+func (s *Server) recordAnalysis(r *http.Request, requestID, prompt string, result *security.SecurityResult, blocked bool) {
+	if s.replays == nil || requestID == "" {
+		return
+	}
+	s.replays.snapshots.Add(requestID, &analysisSnapshot{
+		RequestID:   requestID,
+		Timestamp:   time.Now(),
+		Mode:        s.requestPolicy(r).Mode,
+		Threshold:   s.blockThreshold(r),
+		Strict:      s.requestPolicy(r).NewTemplate,
+		Blocked:     blocked,
+		Result:      result,
+		Fingerprint: s.analysisFingerprint(r.Context(), result, false),
+		prompt:      prompt,
+	})
+}
+
+// analysisFingerprint describes the current analysis setup. fresh bypasses
+// the cached corpus version.
+func (s *Server) analysisFingerprint(ctx context.Context, result *security.SecurityResult, fresh bool) analysisFingerprint {
+	embedding := s.cfg().Security.VectorSecurity.Embedding
+	fingerprint := analysisFingerprint{
+		ConfigHash:    s.analysisConfigHash(),
+		CorpusVersion: s.replays.currentCorpusVersion(ctx, s, fresh),
+		Model:         embedding.ServiceType + "/" + embedding.Model.ModelName,
+	}
+	if result != nil {
+		fingerprint.Tier = result.AnalysisTier
+	}
+	return fingerprint
+}
+
+// analysisConfigHash hashes the settings that can change a verdict
+func (s *Server) analysisConfigHash() string {
+	var patterns []security.AttackPattern
+	for _, pattern := range security.Patterns().List() {
+		if pattern.Enabled {
+			patterns = append(patterns, pattern)
+		}
+	}
+	data, _ := json.Marshal(struct {
+		Security interface{}
+		Patterns []security.AttackPattern
+	}{s.cfg().Security, patterns})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// currentCorpusVersion returns the vector corpus version, cached for
+// corpusVersionTTL unless fresh is set
+func (rr *replayRecorder) currentCorpusVersion(ctx context.Context, s *Server, fresh bool) string {
+	if s.vectorStore == nil {
+		return ""
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if !fresh && time.Since(rr.corpusChecked) < corpusVersionTTL {
+		return rr.corpusVersion
+	}
+	version, err := s.vectorStore.CorpusVersion(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to read corpus version for replay", zap.Error(err))
+		return rr.corpusVersion
+	}
+	rr.corpusVersion, rr.corpusChecked = version, time.Now()
+	return version
+}
+
+// replayRun is the outcome of one re-run of a recorded analysis
+type replayRun struct {
+	Result  *security.SecurityResult `json:"result,omitempty"`
+	Blocked bool                     `json:"blocked"`
+	Matches bool                     `json:"matches"` // same verdict as the original
+	Error   string                   `json:"error,omitempty"`
+}
+
+// replayReport compares a recorded analysis with fresh runs of it
+type replayReport struct {
+	Original     *analysisSnapshot   `json:"original"`
+	Runs         []replayRun         `json:"runs"`
+	Current      analysisFingerprint `json:"current"`
+	Reproducible bool                `json:"reproducible"`
+	Diagnosis    string              `json:"diagnosis"`
+	Drift        []string            `json:"drift,omitempty"` // fingerprint parts that changed since the original
+}
+
+// handleReplay re-runs the analysis recorded for a request and reports
+// whether the verdict is reproducible. When it is not, the fingerprint drift
+// separates corpus or config changes from nondeterminism.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["id"]
+	snapshot, ok := s.replays.snapshots.Get(requestID)
+	if !ok {
+		http.Error(w, "No analysis recorded for request "+requestID, http.StatusNotFound)
+		return
+	}
+
+	runs := 3
+	if v := r.URL.Query().Get("runs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 20 {
+			http.Error(w, "Invalid runs: must be between 1 and 20", http.StatusBadRequest)
+			return
+		}
+		runs = n
+	}
+
+	report := replayReport{Original: snapshot, Reproducible: true}
+	var lastResult *security.SecurityResult
+	for i := 0; i < runs; i++ {
+		run := replayRun{}
+		result, err := s.replayAnalysis(r.Context(), snapshot.prompt, snapshot.Strict)
+		if err != nil {
+			run.Error = err.Error()
+		} else {
+			run.Result = result
+			run.Blocked = snapshot.Mode == "block" && result.IsMalicious && result.Confidence >= snapshot.Threshold
+			run.Matches = sameVerdict(snapshot.Result, snapshot.Blocked, result, run.Blocked)
+			lastResult = result
+		}
+		report.Reproducible = report.Reproducible && run.Matches
+		report.Runs = append(report.Runs, run)
+	}
+
+	report.Current = s.analysisFingerprint(r.Context(), lastResult, true)
+	original := snapshot.Fingerprint
+	if original.CorpusVersion != report.Current.CorpusVersion {
+		report.Drift = append(report.Drift, "corpus")
+	}
+	if original.ConfigHash != report.Current.ConfigHash {
+		report.Drift = append(report.Drift, "config")
+	}
+	if original.Model != report.Current.Model || original.Tier != report.Current.Tier {
+		report.Drift = append(report.Drift, "model")
+	}
+	report.Diagnosis = diagnoseReplay(report)
+
+	s.logger.Info("Analysis replayed",
+		zap.String("request_id", requestID),
+		zap.Int("runs", runs),
+		zap.Bool("reproducible", report.Reproducible),
+		zap.String("diagnosis", report.Diagnosis),
+		zap.Strings("drift", report.Drift))
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// replayAnalysis runs the same analysis steps as a live request, without
+// retries, events or metrics
+func (s *Server) replayAnalysis(ctx context.Context, prompt string, strict bool) (*security.SecurityResult, error) {
+	if result := s.precheckPrompt(prompt, time.Now(), strict); result != nil {
+		return result, nil
+	}
+	return s.vectorSecurity.AnalyzePrompt(ctx, prompt)
+}
+
+// diagnoseReplay explains a replay outcome. Runs that disagree with each
+// other are nondeterministic whatever else changed; runs that agree with each
+// other but not the original are blamed on the first drifted input.
+func diagnoseReplay(report replayReport) string {
+	if report.Reproducible {
+		return replayReproducible
+	}
+	for i := 1; i < len(report.Runs); i++ {
+		a, b := report.Runs[0], report.Runs[i]
+		if a.Error != "" || b.Error != "" || !sameVerdict(a.Result, a.Blocked, b.Result, b.Blocked) {
+			return replayNondeterministic
+		}
+	}
+	for _, drift := range report.Drift {
+		switch drift {
+		case "corpus":
+			return replayCorpusDrift
+		case "config":
+			return replayConfigDrift
+		case "model":
+			return replayModelDrift
+		}
+	}
+	return replayNondeterministic
+}
+
+// sameVerdict reports whether two analyses reached the same decision with
+// effectively the same confidence
+func sameVerdict(a *security.SecurityResult, aBlocked bool, b *security.SecurityResult, bBlocked bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.IsMalicious == b.IsMalicious &&
+		aBlocked == bBlocked &&
+		a.AttackType == b.AttackType &&
+		a.SkipReason == b.SkipReason &&
+		math.Abs(float64(a.Confidence-b.Confidence)) < 1e-4
+}
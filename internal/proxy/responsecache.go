@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
+	"go.uber.org/zap"
+)
+
+// cacheHeader reports whether a response was replayed from the response cache
+const cacheHeader = "X-Sentinel-Cache"
+
+// responseCacheTimeout bounds a cache lookup or store; a slower Redis is
+// treated as a miss rather than holding the request
+const responseCacheTimeout = 250 * time.Millisecond
+
+// uncacheableKey marks a request whose response Sentinel produced itself,
+// such as a block, so the cache does not store it even with a 200 status
+var uncacheableKey = ctxkeys.New[*atomic.Bool]("uncacheable")
+
+// cachedResponse is a stored upstream response. Header holds only the
+// headers set after the cache stage, so per-request headers set before it,
+// such as the request ID, are not replayed.
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// responseCache stores upstream responses in Redis by request
+type responseCache struct {
+	client   *redis.Client
+	down     atomic.Bool
+	degraded *degradationReport
+	logger   *zap.Logger
+}
+
+func newResponseCache(cfg *config.Config, degraded *degradationReport, logger *zap.Logger) (*responseCache, error) {
+	rc := cfg.ResponseCache
+	client, err := newRedisClient(cfg, rc.RedisURL, rc.RedisDB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response cache Redis: %w", err)
+	}
+	c := &responseCache{client: client, degraded: degraded, logger: logger}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		c.failed(err)
+	}
+	return c, nil
+}
+
+// get returns the response stored under key
+func (c *responseCache) get(ctx context.Context, key string) (*cachedResponse, bool) {
+	ctx, cancel := context.WithTimeout(ctx, responseCacheTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.recovered()
+		return nil, false
+	}
+	if err != nil {
+		c.failed(err)
+		return nil, false
+	}
+	c.recovered()
+
+	var resp cachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		c.logger.Warn("Discarding unreadable cached response", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	return &resp, true
+}
+
+// put stores resp under key for ttl
+func (c *responseCache) put(ctx context.Context, key string, resp *cachedResponse, ttl time.Duration) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, responseCacheTimeout)
+	defer cancel()
+	if err := c.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		c.failed(err)
+		return
+	}
+	c.recovered()
+}
+
+// failed and recovered log and report Redis outages once each
+func (c *responseCache) failed(err error) {
+	if !c.down.Swap(true) {
+		c.logger.Warn("Redis unreachable, responses are not cached until it recovers", zap.Error(err))
+		c.degraded.mark(subsystemResponseCache, "disabled", "Redis unreachable", err)
+	}
+}
+
+func (c *responseCache) recovered() {
+	if c.down.CompareAndSwap(true, false) {
+		c.logger.Info("Redis reachable again, response caching resumed")
+		c.degraded.clear(subsystemResponseCache)
+	}
+}
+
+// Close closes the Redis connection
+func (c *responseCache) Close() {
+	c.client.Close()
+}
+
+// responseCacheMiddleware replays the stored response to a request identical
+// to an earlier one: same client, method, path, query and canonical JSON
+// body. Only complete 200 responses from upstream are stored; streamed
+// responses and responses Sentinel produced itself are not. Hits skip every
+// later stage, so repeated prompts cost neither an upstream call nor
+// another analysis.
+func (s *Server) responseCacheMiddleware(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg := s.cfg()
+			rc := cfg.ResponseCache
+			if s.responseCache == nil || !rc.Enabled || (len(rc.Routes) > 0 && !slices.Contains(rc.Routes, route)) ||
+				r.Body == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || isStreaming(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cacheControl := strings.ToLower(r.Header.Get("Cache-Control"))
+			if strings.Contains(cacheControl, "no-store") {
+				metrics.ObserveResponseCache(route, "bypass")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Without an authenticated client or provider key the only
+			// identity is the client IP, which addresses behind one NAT
+			// share; their responses are not cached
+			owner, ok := responseCacheOwner(r)
+			if !ok {
+				metrics.ObserveResponseCache(route, "anonymous")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			analysis, r, err := readRequestAnalysis(r)
+			if err != nil {
+				http.Error(w, "Failed to read request", http.StatusInternalServerError)
+				return
+			}
+			key := rc.KeyPrefix + route + ":" + responseCacheKey(owner, r, analysis.body)
+
+			if strings.Contains(cacheControl, "no-cache") {
+				metrics.ObserveResponseCache(route, "bypass")
+			} else if cached, ok := s.responseCache.get(r.Context(), key); ok {
+				metrics.ObserveResponseCache(route, "hit")
+				s.logger.WithRequestID(getRequestID(r.Context())).Debug("Replaying cached response", zap.String("route", route))
+				for name, values := range cached.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(cacheHeader, "hit")
+				w.WriteHeader(cached.Status)
+				w.Write(cached.Body)
+				return
+			} else {
+				metrics.ObserveResponseCache(route, "miss")
+				w.Header().Set(cacheHeader, "miss")
+			}
+
+			before := w.Header().Clone()
+			uncacheable := new(atomic.Bool)
+			recorder := &dedupRecorder{ResponseWriter: w, status: http.StatusOK, limit: rc.MaxResponseBytes}
+			next.ServeHTTP(recorder, r.WithContext(uncacheableKey.WithValue(r.Context(), uncacheable)))
+
+			if recorder.status != http.StatusOK || recorder.overflowed || uncacheable.Load() {
+				return
+			}
+			header := make(http.Header)
+			for name, values := range w.Header() {
+				if !slices.Equal(before[name], values) {
+					header[name] = slices.Clone(values)
+				}
+			}
+			header.Del(cacheHeader)
+			s.responseCache.put(context.WithoutCancel(r.Context()), key, &cachedResponse{
+				Status: recorder.status,
+				Header: header,
+				Body:   recorder.buf.Bytes(),
+			}, rc.TTL)
+		})
+	}
+}
+
+// responseCacheOwner identifies who a cached response belongs to: the
+// authenticated client, else a hash of the provider API key. Requests with
+// neither have no owner.
+func responseCacheOwner(r *http.Request) (string, bool) {
+	if client, ok := ctxkeys.Client.Value(r.Context()); ok {
+		return "client:" + client, true
+	}
+	return apiKeyIdentity(r)
+}
+
+// responseCacheKey hashes what identifies a request for caching: the
+// owner, method, path, query and canonical JSON body. The owner is part of
+// the key, so a response is only replayed to the client, or API key, that
+// received it.
+func responseCacheKey(owner string, r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(owner + "|" + r.Method + "|" + r.URL.Path + "?" + r.URL.RawQuery + "|"))
+	h.Write(textnorm.CanonicalJSON(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// markUncacheable keeps the response to r out of the response cache
+func markUncacheable(r *http.Request) {
+	if uncacheable, ok := uncacheableKey.Value(r.Context()); ok {
+		uncacheable.Store(true)
+	}
+}
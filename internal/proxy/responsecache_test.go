@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// fakeRedis speaks enough RESP for the response cache: PING, GET and SET
+type fakeRedis struct {
+	listener net.Listener
+
+	mu     sync.Mutex
+	values map[string]string
+	conns  []net.Conn
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{listener: listener, values: make(map[string]string)}
+	t.Cleanup(f.close)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// close stops the server and drops its connections, like a Redis outage
+func (f *fakeRedis) close() {
+	f.listener.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.values)
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		case "GET":
+			if value, ok := f.values[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				io.WriteString(conn, "$-1\r\n")
+			}
+		case "SET":
+			f.values[args[1]] = args[2]
+			io.WriteString(conn, "+OK\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command %s\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+// readCommand reads one RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// upstreamStub stands in for the provider, counting the calls that reach it
+type upstreamStub struct {
+	mu    sync.Mutex
+	calls int
+	serve func(w http.ResponseWriter, r *http.Request)
+}
+
+func (u *upstreamStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	u.calls++
+	n := u.calls
+	u.mu.Unlock()
+	if u.serve != nil {
+		u.serve(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Request-Cost", strconv.Itoa(n))
+	fmt.Fprintf(w, `{"id":"chatcmpl-%d"}`, n)
+}
+
+func (u *upstreamStub) count() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls
+}
+
+func newCachingServer(t *testing.T, redis *fakeRedis) *Server {
+	t.Helper()
+	return newTestServerWith(t, func(cfg *config.Config) {
+		cfg.ResponseCache.Enabled = true
+		cfg.ResponseCache.RedisURL = redis.addr()
+	})
+}
+
+// cacheRequest sends body as the holder of provider key-a, unless header
+// sets another Authorization
+func cacheRequest(handler http.Handler, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/openai/v1/chat/completions", strings.NewReader(body))
+	req.RemoteAddr = "203.0.113.7:40000"
+	req.Header.Set("Authorization", "Bearer key-a")
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestResponseCacheReplaysIdenticalRequests checks a repeated request is
+// answered from the cache, including when only its JSON formatting differs
+func TestResponseCacheReplaysIdenticalRequests(t *testing.T) {
+	redis := newFakeRedis(t)
+	s := newCachingServer(t, redis)
+	upstream := &upstreamStub{}
+	handler := s.responseCacheMiddleware("openai")(upstream)
+
+	first := cacheRequest(handler, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`, nil)
+	if first.Code != http.StatusOK || first.Header().Get(cacheHeader) != "miss" {
+		t.Fatalf("first request: status %d, %s %q", first.Code, cacheHeader, first.Header().Get(cacheHeader))
+	}
+
+	second := cacheRequest(handler, `{ "messages": [{"content": "hi", "role": "user"}], "model": "gpt-4o" }`, nil)
+	if second.Header().Get(cacheHeader) != "hit" {
+		t.Fatalf("reformatted repeat: %s %q, want hit", cacheHeader, second.Header().Get(cacheHeader))
+	}
+	if upstream.count() != 1 {
+		t.Fatalf("upstream called %d times, want once", upstream.count())
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("X-Request-Cost") != "1" {
+		t.Fatalf("replayed %q with X-Request-Cost %q, want the first response", second.Body, second.Header().Get("X-Request-Cost"))
+	}
+}
+
+// TestResponseCacheScopedToClient checks a response is only replayed to the
+// API key that received it
+func TestResponseCacheScopedToClient(t *testing.T) {
+	redis := newFakeRedis(t)
+	s := newCachingServer(t, redis)
+	upstream := &upstreamStub{}
+	handler := s.responseCacheMiddleware("openai")(upstream)
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	cacheRequest(handler, body, map[string]string{"Authorization": "Bearer key-a"})
+	other := cacheRequest(handler, body, map[string]string{"Authorization": "Bearer key-b"})
+	if other.Header().Get(cacheHeader) != "miss" || upstream.count() != 2 {
+		t.Fatalf("another key's request: %s %q after %d upstream calls, want a miss", cacheHeader, other.Header().Get(cacheHeader), upstream.count())
+	}
+}
+
+// TestResponseCacheSkips checks what is neither replayed nor stored
+func TestResponseCacheSkips(t *testing.T) {
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	tests := []struct {
+		name   string
+		header map[string]string
+		serve  func(w http.ResponseWriter, r *http.Request)
+	}{
+		{name: "no-store", header: map[string]string{"Cache-Control": "no-store"}},
+		{name: "no client or provider key", header: map[string]string{"Authorization": ""}},
+		{name: "upstream error", serve: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}},
+		{name: "produced by Sentinel", serve: func(w http.ResponseWriter, r *http.Request) {
+			markUncacheable(r)
+			w.Write([]byte(`{"error":"blocked"}`))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := newFakeRedis(t)
+			s := newCachingServer(t, redis)
+			upstream := &upstreamStub{serve: tt.serve}
+			handler := s.responseCacheMiddleware("openai")(upstream)
+
+			cacheRequest(handler, body, tt.header)
+			repeat := cacheRequest(handler, body, tt.header)
+			if repeat.Header().Get(cacheHeader) == "hit" || upstream.count() != 2 || redis.len() != 0 {
+				t.Fatalf("%s %q after %d upstream calls with %d stored, want nothing cached",
+					cacheHeader, repeat.Header().Get(cacheHeader), upstream.count(), redis.len())
+			}
+		})
+	}
+}
+
+// TestResponseCacheRedisDown checks requests still reach the upstream
+// while Redis is unreachable
+func TestResponseCacheRedisDown(t *testing.T) {
+	redis := newFakeRedis(t)
+	s := newCachingServer(t, redis)
+	redis.close()
+	upstream := &upstreamStub{}
+	handler := s.responseCacheMiddleware("openai")(upstream)
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	for i := 0; i < 2; i++ {
+		if rec := cacheRequest(handler, body, nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d with Redis down: status %d", i+1, rec.Code)
+		}
+	}
+	if upstream.count() != 2 {
+		t.Fatalf("upstream called %d times, want every request", upstream.count())
+	}
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// retryTransport repeats upstream requests that fail with a connection
+// error or a retryable status, doubling the backoff between attempts. The
+// body is buffered so each attempt sends it again.
+type retryTransport struct {
+	inner  http.RoundTripper
+	policy config.UpstreamRetryConfig
+	route  string
+	logger *logger.Logger
+}
+
+// upstreamRetryPolicy returns the retry policy of route, or false when its
+// requests are sent once
+func (s *Server) upstreamRetryPolicy(route string) (config.UpstreamRetryConfig, bool) {
+	retry := s.cfg().Upstream.Retry
+	if !retry.Enabled || retry.MaxAttempts < 2 || (len(retry.Routes) > 0 && !slices.Contains(retry.Routes, route)) {
+		return retry, false
+	}
+	return retry, true
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := req.Context()
+	backoff := t.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(ctx)
+		}
+		if body != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+			attemptReq.ContentLength = int64(len(body))
+		}
+
+		resp, err := t.inner.RoundTrip(attemptReq)
+		if attempt >= t.policy.MaxAttempts || ctx.Err() != nil || !t.retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := backoff
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if retryAfter > t.policy.MaxBackoff {
+					// Not worth holding the client for; pass the answer on
+					return resp, err
+				}
+				wait = max(wait, retryAfter)
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		reason := "connection failure"
+		if resp != nil {
+			reason = resp.Status
+		}
+		t.logger.Debug("Retrying upstream request",
+			zap.String("provider", t.route),
+			zap.Int("attempt", attempt+1),
+			zap.String("reason", reason),
+			zap.Duration("backoff", wait))
+		metrics.ObserveUpstreamRetry(t.route)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, t.policy.MaxBackoff)
+	}
+}
+
+// retryable reports whether an attempt's outcome may improve if repeated. A
+// breaker that refused the attempt will refuse the next one too.
+func (t *retryTransport) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var open *circuitOpenError
+		return !errors.As(err, &open)
+	}
+	return slices.Contains(t.policy.Statuses, resp.StatusCode)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,253 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"github.com/raaihank/llm-sentinel/internal/security"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+// auditRuleChange logs who changed which detection rule
+func (s *Server) auditRuleChange(r *http.Request, kind, action, rule string) {
+	s.logger.Info("Detection rule changed",
+		zap.String("actor", getAdminActor(r.Context())),
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.String("kind", kind),
+		zap.String("action", action),
+		zap.String("rule", rule))
+}
+
+// setupRulesAPI mounts rule management under /admin/api/rules
+func (s *Server) setupRulesAPI() {
+	api := s.router.PathPrefix("/admin/api").Subrouter()
+	api.Use(s.adminAuthMiddleware)
+
+	api.HandleFunc("/rules", s.handleListRules).Methods("GET")
+	api.HandleFunc("/rules/profile", s.handleProfileRules).Methods("GET")
+	api.HandleFunc("/rules/pii", s.handleAddPIIRule).Methods("POST")
+	api.HandleFunc("/rules/pii/export", s.handleExportPIIRules).Methods("GET")
+	api.HandleFunc("/rules/pii/import", s.handleImportPIIRules).Methods("POST")
+	api.HandleFunc("/rules/pii/{name}/{action:enable|disable}", s.handleTogglePIIRule).Methods("POST")
+	api.HandleFunc("/rules/patterns", s.handleAddAttackPattern).Methods("POST")
+	api.HandleFunc("/rules/patterns/{id:[0-9]+}/{action:enable|disable}", s.handleToggleAttackPattern).Methods("POST")
+}
+
+// handleListRules returns every PII rule and attack pattern with its state
+func (s *Server) handleListRules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pii":             s.detector.Rules(),
+		"attack_patterns": security.Patterns().List(),
+	})
+}
+
+// handleProfileRules returns every PII rule and attack pattern with its match
+// count, evaluation time and last match so expensive or dead rules can be
+// pruned. ?sort=cost (default, most total time first), matches (fewest
+// first) or last_match (stalest first).
+func (s *Server) handleProfileRules(w http.ResponseWriter, r *http.Request) {
+	order := r.URL.Query().Get("sort")
+	if order == "" {
+		order = "cost"
+	}
+	if order != "cost" && order != "matches" && order != "last_match" {
+		http.Error(w, "Invalid sort: "+order+" (must be cost, matches or last_match)", http.StatusBadRequest)
+		return
+	}
+
+	rules := s.detector.Rules()
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i].Cost, rules[j].Cost
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return profileLess(order, a.TotalMS, b.TotalMS, a.Matches, b.Matches, a.LastMatch, b.LastMatch)
+	})
+
+	patterns := security.Patterns().Profile()
+	sort.SliceStable(patterns, func(i, j int) bool {
+		a, b := patterns[i], patterns[j]
+		return profileLess(order, a.TotalMS, b.TotalMS, a.Matches, b.Matches, a.LastMatch, b.LastMatch)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sort":            order,
+		"pii":             rules,
+		"attack_patterns": patterns,
+	})
+}
+
+// profileLess orders two profiled rules: by total evaluation time
+// descending, by match count ascending, or by last match with never-matched
+// rules first
+func profileLess(order string, totalA, totalB float64, matchesA, matchesB int64, lastA, lastB *time.Time) bool {
+	switch order {
+	case "matches":
+		return matchesA < matchesB
+	case "last_match":
+		if lastA == nil || lastB == nil {
+			return lastA == nil && lastB != nil
+		}
+		return lastA.Before(*lastB)
+	default:
+		return totalA > totalB
+	}
+}
+
+// handleAddPIIRule compiles and enables a new PII detection rule
+func (s *Server) handleAddPIIRule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		Pattern     string `json:"pattern"`
+		Replacement string `json:"replacement"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.changeRuntimeConfig(getAdminActor(r.Context()), "add pii rule "+req.Name, func() error {
+		return s.detector.AddRule(req.Name, req.Pattern, req.Replacement)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.auditRuleChange(r, "pii", "add", req.Name)
+
+	for _, rule := range s.detector.Rules() {
+		if rule.Name == req.Name {
+			writeJSON(w, http.StatusCreated, rule)
+			return
+		}
+	}
+}
+
+// handleTogglePIIRule enables or disables a PII detection rule
+func (s *Server) handleTogglePIIRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name, action := vars["name"], vars["action"]
+
+	err := s.changeRuntimeConfig(getAdminActor(r.Context()), action+" pii rule "+name, func() error {
+		if action == "enable" {
+			return s.detector.EnableRule(name)
+		}
+		return s.detector.DisableRule(name)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.auditRuleChange(r, "pii", action, name)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "enabled": action == "enable"})
+}
+
+// handleExportPIIRules returns the PII rule set as a YAML bundle
+func (s *Server) handleExportPIIRules(w http.ResponseWriter, r *http.Request) {
+	bundle, err := yaml.Marshal(s.detector.ExportBundle())
+	if err != nil {
+		http.Error(w, "Failed to encode rule bundle: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="pii-rules.yaml"`)
+	w.Write(bundle)
+}
+
+// handleImportPIIRules applies a YAML rule bundle all at once: if any rule
+// is invalid nothing changes. ?mode=replace also drops admin-added rules
+// missing from the bundle; the default merge keeps them.
+func (s *Server) handleImportPIIRules(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "replace" {
+		http.Error(w, "Invalid mode: "+mode+" (must be merge or replace)", http.StatusBadRequest)
+		return
+	}
+
+	var bundle privacy.RuleBundle
+	decoder := yaml.NewDecoder(r.Body)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&bundle); err != nil {
+		http.Error(w, "Invalid rule bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	author := getAdminActor(r.Context())
+	err := s.changeRuntimeConfig(author, fmt.Sprintf("import pii rule bundle (%d rules, %s)", len(bundle.Rules), mode), func() error {
+		state, err := s.detector.BundleState(bundle, mode == "replace")
+		if err != nil {
+			return err
+		}
+		return s.detector.RestoreRuleState(state)
+	})
+	if err != nil {
+		http.Error(w, "Import failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.auditRuleChange(r, "pii", "import "+mode, fmt.Sprintf("%d rules", len(bundle.Rules)))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"imported": len(bundle.Rules),
+		"mode":     mode,
+		"rules":    s.detector.Rules(),
+	})
+}
+
+// handleAddAttackPattern adds a keyword attack pattern to the shared set
+func (s *Server) handleAddAttackPattern(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Keyword    string  `json:"keyword"`
+		AttackType string  `json:"attack_type"`
+		Confidence float32 `json:"confidence"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var pattern security.AttackPattern
+	err := s.changeRuntimeConfig(getAdminActor(r.Context()), "add attack pattern "+req.Keyword, func() error {
+		var err error
+		pattern, err = security.Patterns().Add(req.Keyword, req.AttackType, req.Confidence)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.auditRuleChange(r, "attack_pattern", "add", pattern.Keyword)
+
+	writeJSON(w, http.StatusCreated, pattern)
+}
+
+// handleToggleAttackPattern enables or disables a keyword attack pattern
+func (s *Server) handleToggleAttackPattern(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	action := vars["action"]
+
+	var pattern security.AttackPattern
+	err := s.changeRuntimeConfig(getAdminActor(r.Context()), action+" attack pattern "+vars["id"], func() error {
+		var err error
+		pattern, err = security.Patterns().SetEnabled(id, action == "enable")
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.auditRuleChange(r, "attack_pattern", action, pattern.Keyword)
+
+	writeJSON(w, http.StatusOK, pattern)
+}
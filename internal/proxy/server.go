@@ -4,33 +4,89 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sync"
-	"time"
+	"sync/atomic"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/archive"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/clientauth"
 	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/confighistory"
+	"github.com/raaihank/llm-sentinel/internal/egress"
 	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/events"
 	"github.com/raaihank/llm-sentinel/internal/logger"
+	"github.com/raaihank/llm-sentinel/internal/metrics"
 	"github.com/raaihank/llm-sentinel/internal/privacy"
 	"github.com/raaihank/llm-sentinel/internal/security"
+	"github.com/raaihank/llm-sentinel/internal/sigv4"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 	"github.com/raaihank/llm-sentinel/internal/vector"
 	"github.com/raaihank/llm-sentinel/internal/web"
 	"github.com/raaihank/llm-sentinel/internal/websocket"
 	"go.uber.org/zap"
-	"golang.org/x/time/rate"
 )
 
 // Server represents the main proxy server
 type Server struct {
-	config         *config.Config
+	config         atomic.Pointer[config.Config] // replaced whole on reload; read through cfg
 	logger         *logger.Logger
 	detector       *privacy.Detector
 	vectorSecurity security.VectorSecurityAnalyzer
 	router         *mux.Router
 	server         *http.Server
 	wsHub          *websocket.Hub
-	mu             sync.Mutex
-	rateLimiters   map[string]*rate.Limiter
+	rateLimiters   *rateLimiterSet
+	responseCache  *responseCache // nil unless the response cache is enabled
+	failover       *failoverSet   // nil unless a route has a failover group
+	breakers       *breakerSet
+	chaos          *chaos.Injector
+	forwarders     []*events.Forwarder
+	webhooks       []*events.Webhook
+	bus            *events.Bus
+
+	conversations         *security.ConversationTracker
+	conversationExtractor *conversationExtractor
+	anomalies             *security.AnomalyDetector
+	templates             *security.TemplateTracker // nil unless template fingerprinting is enabled
+	vectorStore           vector.Backend
+	standby               *vector.Standby                // nil unless corpus snapshots are enabled; also vectorStore
+	embedder              embeddings.EmbeddingService    // embeds probe text for the explain endpoint
+	embeddingModel        *embeddings.MLEmbeddingService // serves /v1/embeddings; nil unless the ML model loaded
+	poolMonitor           *vector.PoolMonitor
+	latency               *latencyAuditor      // nil unless the streaming latency audit is enabled
+	clientKeys            *clientauth.KeyStore // nil unless client keys are loaded from the database
+	archiver              *archive.Archiver    // nil unless the event archive is enabled
+	replays               *replayRecorder      // nil unless replay is enabled
+
+	modeMu sync.RWMutex
+	mode   ModeSettings
+
+	pipelines map[string][]string                              // effective middleware stages per provider route
+	egress    map[string]func(*http.Request) (*url.URL, error) // outbound proxy per provider route; nil entries connect directly
+	resolver  *egress.Resolver                                 // nil uses the system resolver
+
+	bedrockCredentials *sigv4.CredentialSource // nil unless the Bedrock route is enabled
+
+	knownAttacks *security.KnownAttackFilter
+	dedup        *dedupGroup
+	tiered       *security.TieredAnalyzer
+
+	components       componentChecker // nil when the embedding service has no component checks
+	componentsMu     sync.Mutex
+	componentsStatus map[string]string // last reported status per component
+
+	degraded *degradationReport // subsystems running below their configured capability
+
+	history   *confighistory.Store // nil unless config history is enabled
+	historyMu sync.Mutex           // serializes runtime config changes with their snapshots
+
+	annotator *annotator // nil unless request annotations are enabled
+
+	upstreamTransport http.RoundTripper // replaces upstream connections when set, e.g. by the soak runner
 }
 
 // New creates a new proxy server instance
@@ -40,22 +96,50 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create privacy detector: %w", err)
 	}
+	for _, policy := range cfg.Security.Policies {
+		if err := detector.HasRules(policy.Detectors); err != nil {
+			return nil, fmt.Errorf("invalid detectors for security policy %s: %w", policy.Name, err)
+		}
+	}
 
 	// Create vector security engine if enabled
 	var vectorSecurity security.VectorSecurityAnalyzer
+	var vectorStore vector.Backend
+	var standby *vector.Standby
+	var embedder embeddings.EmbeddingService
+	var embeddingModel *embeddings.MLEmbeddingService
+	var tiers []security.Tier
+	var components componentChecker
+	degraded := newDegradationReport()
 	if cfg.Security.VectorSecurity.Enabled {
 		// Create simple embedding service
 		embeddingModelConfig := embeddings.ModelConfig{
-			ModelName:    cfg.Security.VectorSecurity.Embedding.Model.ModelName,
-			ModelPath:    cfg.Security.VectorSecurity.Embedding.Model.ModelPath,
-			CacheDir:     cfg.Security.VectorSecurity.Embedding.Model.CacheDir,
-			AutoDownload: cfg.Security.VectorSecurity.Embedding.Model.AutoDownload,
-			MaxLength:    cfg.Security.VectorSecurity.Embedding.Model.MaxLength,
-			BatchSize:    cfg.Security.VectorSecurity.Embedding.Model.BatchSize,
+			ModelName:       cfg.Security.VectorSecurity.Embedding.Model.ModelName,
+			ModelPath:       cfg.Security.VectorSecurity.Embedding.Model.ModelPath,
+			TokenizerPath:   cfg.Security.VectorSecurity.Embedding.Model.TokenizerPath,
+			VocabPath:       cfg.Security.VectorSecurity.Embedding.Model.VocabPath,
+			CacheDir:        cfg.Security.VectorSecurity.Embedding.Model.CacheDir,
+			AutoDownload:    cfg.Security.VectorSecurity.Embedding.Model.AutoDownload,
+			MaxLength:       cfg.Security.VectorSecurity.Embedding.Model.MaxLength,
+			BatchSize:       cfg.Security.VectorSecurity.Embedding.Model.BatchSize,
+			ModelTimeout:    cfg.Security.VectorSecurity.Embedding.Model.ModelTimeout,
+			MemoSize:        cfg.Security.VectorSecurity.Embedding.Model.MemoSize,
+			LocalCacheSize:  cfg.Security.VectorSecurity.Embedding.Model.LocalCacheSize,
+			LocalCacheTTL:   cfg.Security.VectorSecurity.Embedding.Model.LocalCacheTTL,
+			Revision:        cfg.Security.VectorSecurity.Embedding.Model.Revision,
+			ModelSHA256:     cfg.Security.VectorSecurity.Embedding.Model.ModelSHA256,
+			TokenizerSHA256: cfg.Security.VectorSecurity.Embedding.Model.TokenizerSHA256,
+			VocabSHA256:     cfg.Security.VectorSecurity.Embedding.Model.VocabSHA256,
+			CacheKey:        textnorm.Options(cfg.Security.VectorSecurity.Normalization.Cache),
 		}
 		var embeddingService embeddings.EmbeddingService
 		var err error
 
+		embeddingModelConfig.Proxy, err = egress.ProxyFunc(cfg.Upstream.Egress, config.EgressDownloadRoute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure egress proxy for model downloads: %w", err)
+		}
+
 		// Create embedding service using factory
 		factory := embeddings.NewFactory(log.WithComponent("embeddings-factory").Logger)
 
@@ -70,6 +154,7 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 		if err := embeddings.ValidateServiceConfig(serviceConfig); err != nil {
 			log.Error("Invalid embedding service configuration", zap.Error(err))
 			log.Info("Falling back to hash embedding service")
+			degraded.mark(subsystemEmbeddings, "hash", "invalid "+string(serviceConfig.Type)+" configuration", err)
 			serviceConfig.Type = embeddings.HashEmbedding
 			serviceConfig.RedisEnabled = false
 		}
@@ -77,32 +162,85 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 		embeddingService, err = factory.CreateService(serviceConfig)
 		if err != nil {
 			log.Warn("Failed to create embedding service, vector security disabled", zap.Error(err))
+			degraded.mark(subsystemVectorSecurity, "disabled", "embedding service unavailable", err)
 		} else {
 			// Attempt to initialize vector store and attach to ML embedding service
 			if mlService, ok := embeddingService.(*embeddings.MLEmbeddingService); ok {
-				dbCfg := &vector.Config{
-					DatabaseURL:     cfg.Security.VectorSecurity.Database.DatabaseURL,
-					MaxOpenConns:    cfg.Security.VectorSecurity.Database.MaxOpenConns,
-					MaxIdleConns:    cfg.Security.VectorSecurity.Database.MaxIdleConns,
-					ConnMaxLifetime: cfg.Security.VectorSecurity.Database.ConnMaxLifetime,
-					ConnMaxIdleTime: cfg.Security.VectorSecurity.Database.ConnMaxIdleTime,
+				components = mlService
+				embeddingModel = mlService
+				store, sErr := vector.OpenFromConfig(cfg.Security.VectorSecurity, log.WithComponent("vector-store").Logger)
+
+				// Keep a corpus snapshot in front of the store, serving searches
+				// while the database is unreachable and reconnecting in the
+				// background
+				if cfg.Security.VectorSecurity.Snapshot.Enabled {
+					snapshotLogger := log.WithComponent("corpus-snapshot").Logger
+					options, err := vector.StandbyOptionsFromConfig(cfg.Security.VectorSecurity, log.WithComponent("vector-store").Logger)
+					if err != nil {
+						return nil, fmt.Errorf("failed to configure corpus snapshot: %w", err)
+					}
+					options.OnConnect = func() { degraded.clear(subsystemVectorSecurity) }
+					standby = vector.NewStandby(store, options, snapshotLogger)
+					switch {
+					case sErr != nil && standby.Vectors() > 0:
+						log.Warn("Vector store initialization failed; serving the corpus snapshot until it connects", zap.Error(sErr))
+						degraded.mark(subsystemVectorSecurity, "snapshot", "DB unreachable, serving corpus snapshot", sErr)
+					case sErr != nil:
+						log.Warn("Vector store initialization failed; continuing without DB lookups until it connects", zap.Error(sErr))
+						degraded.mark(subsystemVectorSecurity, "pattern-only", "DB unreachable", sErr)
+					}
+					store, sErr = standby, nil
 				}
-				store, sErr := vector.NewStore(dbCfg, log.WithComponent("vector-store").Logger)
+
 				if sErr != nil {
 					log.Warn("Vector store initialization failed; continuing without DB lookups", zap.Error(sErr))
+					degraded.mark(subsystemVectorSecurity, "pattern-only", "DB unreachable", sErr)
 				} else {
 					mlService.SetVectorStore(store)
+					vectorStore = store
+					embedder = mlService
 					log.Info("Vector store attached to ML embedding service")
 				}
 			}
 
-			vectorSecurity = security.NewSimpleVectorSecurityEngine(
+			if vectorStore != nil && cfg.Security.VectorSecurity.CanaryCheck.Enabled {
+				if err := checkCorpusModel(cfg.Security.VectorSecurity, embeddingService, vectorStore, degraded, log); err != nil {
+					vectorStore.Close()
+					embeddingService.Close()
+					return nil, err
+				}
+			}
+
+			engine := security.NewSimpleVectorSecurityEngine(
 				embeddingService,
 				&cfg.Security.VectorSecurity,
 				log.WithComponent("vector-security").Logger,
 			)
+			if vectorStore != nil && cfg.Security.VectorSecurity.HybridSearch {
+				engine.SetVectorStore(vectorStore)
+			}
+			vectorSecurity = engine
 			log.Info("Vector security engine initialized")
+
+			if cfg.Security.VectorSecurity.Degradation.Enabled {
+				tiers = analysisTiers(factory, serviceConfig, vectorSecurity, &cfg.Security.VectorSecurity, log)
+			}
+
+			if cfg.Security.VectorSecurity.AnalyzeOriginal {
+				log.Warn("Vector analysis runs on original unmasked prompts (held in memory only); upstream still receives masked text")
+			}
+		}
+	}
+
+	// Install fault injection for resilience testing if enabled
+	var injector *chaos.Injector
+	if cfg.Chaos.Enabled {
+		injector, err = chaos.New(chaosSettingsFromConfig(cfg.Chaos))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fault injector: %w", err)
 		}
+		chaos.SetGlobal(injector)
+		log.Warn("Chaos fault injection is enabled; do not use in production")
 	}
 
 	// Create WebSocket hub with configuration
@@ -112,6 +250,12 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 		BroadcastSystem:            cfg.WebSocket.Events.BroadcastSystem,
 		BroadcastConnections:       cfg.WebSocket.Events.BroadcastConnections,
 		BroadcastRequestCompletion: true, // Enable response time tracking
+		BroadcastAnomalies:         cfg.WebSocket.Events.BroadcastVectorSecurity,
+		BroadcastDetectionAlerts:   cfg.WebSocket.Events.BroadcastVectorSecurity,
+		EnableCompression:          cfg.WebSocket.Compression,
+		CompressionLevel:           cfg.WebSocket.CompressionLevel,
+		AllowMessagePack:           cfg.WebSocket.AllowMessagePack,
+		ReplayBuffer:               cfg.WebSocket.ReplayBuffer,
 	}
 	wsHub := websocket.NewHub(hubConfig, log.WithComponent("websocket").Logger)
 
@@ -120,23 +264,217 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 
 	// Create server
 	server := &Server{
-		config:         cfg,
 		logger:         log.WithComponent("proxy"),
 		detector:       detector,
 		vectorSecurity: vectorSecurity,
 		router:         router,
 		wsHub:          wsHub,
-		mu:             sync.Mutex{},
-		rateLimiters:   make(map[string]*rate.Limiter),
+		chaos:          injector,
+		bus:            events.NewBus(log.WithComponent("event-bus").Logger),
+		vectorStore:    vectorStore,
+		standby:        standby,
+		embedder:       embedder,
+		embeddingModel: embeddingModel,
+		pipelines:      make(map[string][]string),
+		egress:         make(map[string]func(*http.Request) (*url.URL, error)),
+		components:     components,
+		degraded:       degraded,
+		breakers:       newBreakerSet(),
+		mode: ModeSettings{
+			ReadOnly:    cfg.Server.ReadOnly,
+			Maintenance: cfg.Server.Maintenance,
+			RetryAfter:  cfg.Server.MaintenanceRetryAfter,
+		},
+	}
+	server.config.Store(cfg)
+
+	// Share rate limit buckets across replicas when the backend is Redis
+	server.rateLimiters, err = newRateLimiterSet(cfg, degraded, log.WithComponent("rate-limit").Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shift provider routes between upstreams on error rate or latency
+	if len(cfg.Upstream.Failover) > 0 {
+		server.failover, err = newFailoverSet(cfg.Upstream.Failover, server.upstreamRoundTripper, server.handleFailoverSwitch, log.WithComponent("failover").Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Replay upstream responses to repeated identical requests
+	if cfg.ResponseCache.Enabled {
+		server.responseCache, err = newResponseCache(cfg, degraded, log.WithComponent("response-cache").Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve outbound proxies for each provider route
+	for _, route := range config.PipelineRoutes {
+		proxy, err := egress.ProxyFunc(cfg.Upstream.Egress, route)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure egress proxy for %s: %w", route, err)
+		}
+		server.egress[route] = proxy
+	}
+	server.resolver, err = egress.NewResolver(cfg.Upstream.DNS, log.WithComponent("dns").Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure upstream DNS: %w", err)
+	}
+
+	// AWS credentials for re-signing Bedrock requests; IRSA token exchanges
+	// leave through the same egress as Bedrock itself
+	if cfg.Upstream.Bedrock.Region != "" {
+		stsTransport := &http.Transport{Proxy: server.egress["bedrock"]}
+		if server.resolver != nil {
+			stsTransport.DialContext = server.resolver.DialContext
+		}
+		stsClient := &http.Client{Timeout: cfg.Upstream.Timeout, Transport: stsTransport}
+		server.bedrockCredentials = sigv4.NewCredentialSource(stsClient, cfg.Upstream.Bedrock.Region)
+		if _, err := server.bedrockCredentials.Get(context.Background()); err != nil {
+			server.logger.Warn("Bedrock route enabled without usable AWS credentials", zap.Error(err))
+			degraded.mark(subsystemBedrock, "unavailable", "no usable AWS credentials", err)
+		}
+	}
+
+	// Track conversations for per-conversation policies
+	if cfg.Security.Conversations.Enabled {
+		extractor, err := newConversationExtractor(cfg.Security.Conversations)
+		if err != nil {
+			return nil, err
+		}
+		server.conversationExtractor = extractor
+		server.conversations = security.NewConversationTracker(cfg.Security.Conversations.Policy, cfg.Security.Conversations.TTL, cfg.Security.Conversations.MaxConversations)
+	}
+
+	// Baseline per-client behavior for anomaly detection
+	if cfg.Security.Anomaly.Enabled {
+		server.anomalies = security.NewAnomalyDetector(security.AnomalyOptions{
+			BurstFactor:       cfg.Security.Anomaly.BurstFactor,
+			MinBurstRequests:  cfg.Security.Anomaly.MinBurstRequests,
+			MinObservations:   cfg.Security.Anomaly.MinObservations,
+			OffHoursThreshold: cfg.Security.Anomaly.OffHoursThreshold,
+			Window:            cfg.Security.Anomaly.Window,
+			MaxClients:        cfg.Security.Anomaly.MaxClients,
+		})
+	}
+
+	// Learn each client's prompt templates to scrutinize new ones
+	if templates := cfg.Security.Templates; templates.Enabled {
+		server.templates = security.NewTemplateTracker(security.TemplateOptions{
+			MinObservations: templates.MinObservations,
+			MinKnownShare:   templates.MinKnownShare,
+			KnownAfter:      templates.KnownAfter,
+			MaxTemplates:    templates.MaxTemplates,
+			MaxClients:      templates.MaxClients,
+		})
+	}
+
+	// Step analysis quality down under sustained load
+	if len(tiers) > 1 {
+		degradation := cfg.Security.VectorSecurity.Degradation
+		tiered, err := security.NewTieredAnalyzer(tiers, security.TierOptions{
+			SampleInterval:    degradation.SampleInterval,
+			SustainSamples:    degradation.SustainSamples,
+			DegradeCPU:        degradation.DegradeCPU,
+			RestoreCPU:        degradation.RestoreCPU,
+			DegradeQueueDepth: degradation.DegradeQueueDepth,
+			RestoreQueueDepth: degradation.RestoreQueueDepth,
+		}, server.handleTierChange)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tiered analyzer: %w", err)
+		}
+		server.vectorSecurity = tiered
+		server.tiered = tiered
+	}
+
+	// Share responses between identical in-flight requests
+	if cfg.Dedup.Enabled {
+		server.dedup = newDedupGroup()
+	}
+
+	// Audit the time to first byte the proxy adds to streamed responses
+	if streaming := cfg.Upstream.Streaming; streaming.Enabled && streaming.LatencyAudit.Enabled {
+		server.latency = newLatencyAuditor(streaming.LatencyAudit, log.WithComponent("latency-audit").Logger)
+	}
+
+	// Load Sentinel-issued client keys from the database
+	if clientAuth := cfg.Security.ClientAuth; clientAuth.Enabled && clientAuth.Database.Enabled {
+		databaseURL := clientAuth.Database.DatabaseURL
+		if databaseURL == "" {
+			databaseURL = cfg.Security.VectorSecurity.Database.DatabaseURL
+		}
+		server.clientKeys, err = clientauth.OpenKeyStore(databaseURL, clientAuth.Database.RefreshInterval, log.WithComponent("client-auth").Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Watch the vector store's connection pools for saturation
+	if poolMonitor := cfg.Security.VectorSecurity.PoolMonitor; poolMonitor.Enabled && vectorStore != nil {
+		server.poolMonitor = vector.NewPoolMonitor(vectorStore, vector.PoolMonitorOptions{
+			SampleInterval: poolMonitor.SampleInterval,
+			MaxWaits:       poolMonitor.MaxWaits,
+			MaxWaitTime:    poolMonitor.MaxWaitTime,
+		}, log.WithComponent("vector-store").Logger)
+	}
+
+	// Block exact replays of known attacks before embedding work
+	if cfg.Security.VectorSecurity.KnownAttacks.Enabled && vectorStore != nil {
+		server.knownAttacks = newKnownAttackFilter(cfg, vectorStore, degraded, log)
+	}
+
+	// Version runtime config changes, starting from the config at startup
+	if history := cfg.Server.ConfigHistory; history.Enabled {
+		server.history, err = confighistory.Open(history.Dir, history.SigningKey, history.MaxVersions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open config history: %w", err)
+		}
+		server.recordConfigSnapshot("system", "startup")
+	}
+
+	// Baseline component health so startup fallbacks appear in /info
+	if components != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), componentHealthTimeout)
+		server.recordComponentHealth(components.CheckComponents(ctx))
+		cancel()
 	}
 
+	// Tell upstream gateways what the proxy concluded about each request
+	if cfg.Upstream.Annotations.Enabled {
+		server.annotator = newAnnotator(cfg.Upstream.Annotations)
+	}
+
+	// Keep recent analyses for reproducibility checks
+	if cfg.Security.VectorSecurity.Replay.Enabled && vectorSecurity != nil {
+		server.replays = newReplayRecorder(cfg.Security.VectorSecurity.Replay.Capacity)
+	}
+
+	// Report the configured label taxonomy as attack categories
+	if cfg.Metrics.Enabled {
+		server.setupMetrics()
+	}
+
+	// Attach downstream event sinks
+	if err := server.setupEventSinks(); err != nil {
+		return nil, err
+	}
+	if cfg.Events.Archive.Enabled {
+		if err := server.setupArchive(); err != nil {
+			return nil, err
+		}
+	}
+	server.setupWebhooks()
+	server.setupRateAlerts()
+
 	// Setup routes
 	server.setupRoutes()
 
 	// Create HTTP server
 	server.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      server.router,
+		Handler:      server.recoveryMiddleware(server.corsHandler(server.router)),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
@@ -153,6 +491,25 @@ func (s *Server) setupRoutes() {
 	// Info endpoint
 	s.router.HandleFunc("/info", s.handleInfo).Methods("GET")
 
+	// Prometheus metrics
+	if metricsConfig := s.cfg().Metrics; metricsConfig.Enabled {
+		s.router.Handle(metricsConfig.Path, metrics.Handler()).Methods("GET")
+	}
+
+	// Detection API (only when enabled)
+	if s.cfg().API.Analyze.Enabled {
+		s.router.Handle("/v1/analyze", s.loggingMiddleware(s.clientAuthMiddleware(s.rateLimitMiddleware(http.HandlerFunc(s.handleAnalyze))))).Methods("POST")
+	}
+
+	// Embeddings API (only when enabled and the ML model is loaded)
+	if s.cfg().API.Embeddings.Enabled {
+		if s.embeddingModel != nil {
+			s.router.Handle("/v1/embeddings", s.loggingMiddleware(s.clientAuthMiddleware(s.rateLimitMiddleware(http.HandlerFunc(s.handleEmbeddings))))).Methods("POST")
+		} else {
+			s.logger.Warn("Embeddings API is enabled but the ML embedding service is not loaded; /v1/embeddings is not served")
+		}
+	}
+
 	// Dashboard endpoint - embedded HTML
 	s.router.HandleFunc("/", web.ServeDashboard).Methods("GET")
 	s.router.HandleFunc("/dashboard", web.ServeDashboard).Methods("GET")
@@ -160,67 +517,228 @@ func (s *Server) setupRoutes() {
 	// WebSocket endpoint for dashboard
 	s.router.HandleFunc("/ws", s.handleWebSocket).Methods("GET")
 
-	// OpenAI proxy endpoints
-	openaiRouter := s.router.PathPrefix("/openai").Subrouter()
-	openaiRouter.Use(s.loggingMiddleware)
-	openaiRouter.Use(s.privacyMiddleware)
-	openaiRouter.Use(s.vectorSecurityMiddleware)
-	openaiRouter.PathPrefix("/").HandlerFunc(s.handleOpenAIProxy)
-
-	// Ollama proxy endpoints
-	ollamaRouter := s.router.PathPrefix("/ollama").Subrouter()
-	ollamaRouter.Use(s.loggingMiddleware)
-	ollamaRouter.Use(s.privacyMiddleware)
-	ollamaRouter.Use(s.vectorSecurityMiddleware)
-	ollamaRouter.PathPrefix("/").HandlerFunc(s.handleOllamaProxy)
-
-	// Anthropic proxy endpoints
-	anthropicRouter := s.router.PathPrefix("/anthropic").Subrouter()
-	anthropicRouter.Use(s.loggingMiddleware)
-	anthropicRouter.Use(s.privacyMiddleware)
-	anthropicRouter.Use(s.vectorSecurityMiddleware)
-	anthropicRouter.PathPrefix("/").HandlerFunc(s.handleAnthropicProxy)
+	// Reject admin mutations while read-only
+	s.router.Use(s.readOnlyMiddleware)
+
+	// Admin endpoints require a bearer token from server.admin_tokens
+	admin := s.router.PathPrefix("/admin").Subrouter()
+	admin.Use(s.adminAuthMiddleware)
+
+	// Operational mode endpoints
+	admin.HandleFunc("/mode", s.handleGetMode).Methods("GET")
+	admin.HandleFunc("/mode", s.handleUpdateMode).Methods("PUT")
+
+	// Conversation review endpoints (only when conversation tracking is enabled)
+	if s.conversations != nil {
+		admin.HandleFunc("/conversations", s.handleListConversations).Methods("GET")
+		admin.HandleFunc("/conversations/{id}", s.handleGetConversation).Methods("GET")
+		admin.HandleFunc("/conversations/{id}/review", s.handleReviewConversation).Methods("POST")
+	}
+
+	// Prompt template stats (only when template fingerprinting is enabled)
+	if s.templates != nil {
+		admin.HandleFunc("/templates", s.handleTemplateStats).Methods("GET")
+	}
+
+	// Migration divergence report (only in dual-write mode)
+	if _, ok := s.vectorStore.(*vector.DualStore); ok {
+		admin.HandleFunc("/migration", s.handleMigrationReport).Methods("GET")
+	}
+
+	// Similarity search plan capture (only with a vector store)
+	if s.vectorStore != nil {
+		admin.HandleFunc("/vector/explain", s.handleExplainSimilar).Methods("POST")
+		admin.HandleFunc("/corpus/stats", s.handleCorpusStats).Methods("GET")
+	}
+
+	// Detector inventory (only when enabled); it lists attack keywords
+	if s.cfg().API.Detectors.Enabled {
+		admin.HandleFunc("/detectors", s.handleDetectors).Methods("GET")
+	}
+
+	// Analysis replay (only when recording is enabled)
+	if s.replays != nil {
+		admin.HandleFunc("/replay/{id}", s.handleReplay).Methods("POST")
+	}
+
+	// Detection rule management (only when admin tokens are configured)
+	if len(s.cfg().Server.AdminTokens) > 0 {
+		s.setupRulesAPI()
+	}
+
+	// Config version history and rollback (only when history is enabled)
+	if s.history != nil {
+		s.setupConfigHistoryAPI()
+	}
+
+	// Streaming latency report (only when the audit is enabled)
+	if s.latency != nil {
+		admin.HandleFunc("/latency", s.handleLatencyReport).Methods("GET")
+	}
+
+	// Fault injection admin endpoints (only when chaos is enabled)
+	if s.chaos != nil {
+		admin.HandleFunc("/chaos", s.handleGetChaos).Methods("GET")
+		admin.HandleFunc("/chaos", s.handleUpdateChaos).Methods("PUT")
+	}
+
+	// Provider proxy endpoints, each behind its configured pipeline
+	s.mountProvider("openai", s.handleOpenAIProxy)
+	s.mountProvider("ollama", s.handleOllamaProxy)
+	s.mountProvider("anthropic", s.handleAnthropicProxy)
+	s.mountProvider("gemini", s.handleGeminiProxy)
+	s.mountProvider("vertex", s.handleVertexProxy)
+	if s.cfg().Upstream.AzureOpenAI.Endpoint != "" {
+		s.mountProvider("azure-openai", s.handleAzureOpenAIProxy)
+	}
+	if s.cfg().Upstream.Bedrock.Region != "" {
+		s.mountProvider("bedrock", s.handleBedrockProxy)
+	}
 }
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
+	cfg := s.cfg()
 	s.logger.Info("Starting LLM-Sentinel proxy server",
-		zap.Int("port", s.config.Server.Port),
-		zap.String("upstream_openai", s.config.Upstream.OpenAI),
-		zap.String("upstream_ollama", s.config.Upstream.Ollama),
-		zap.String("upstream_anthropic", s.config.Upstream.Anthropic),
+		zap.Int("port", cfg.Server.Port),
+		zap.String("upstream_openai", cfg.Upstream.OpenAI),
+		zap.String("upstream_ollama", cfg.Upstream.Ollama),
+		zap.String("upstream_anthropic", cfg.Upstream.Anthropic),
+		zap.String("upstream_gemini", cfg.Upstream.Gemini),
+		zap.String("upstream_vertex", cfg.Upstream.Vertex),
+		zap.String("upstream_azure_openai", cfg.Upstream.AzureOpenAI.Endpoint),
+		zap.String("upstream_bedrock_region", cfg.Upstream.Bedrock.Region),
 	)
 
+	s.StartWorkers()
+	return s.server.ListenAndServe()
+}
+
+// StartWorkers starts the server's background work without listening, for
+// serving through Handler in process. Start calls it.
+func (s *Server) StartWorkers() {
 	// Start WebSocket hub in a separate goroutine
 	go s.wsHub.Run()
 
-	return s.server.ListenAndServe()
+	if s.knownAttacks != nil {
+		s.knownAttacks.Start()
+	}
+	if s.tiered != nil {
+		s.tiered.Start()
+	}
+	if s.poolMonitor != nil {
+		s.poolMonitor.Start()
+	}
+	if s.latency != nil {
+		s.latency.Start()
+	}
+	if s.clientKeys != nil {
+		s.clientKeys.Start()
+	}
+	if s.archiver != nil {
+		s.archiver.Start()
+	}
+	if s.standby != nil {
+		s.standby.Start()
+	}
+	s.rateLimiters.Start()
+	if s.failover != nil {
+		s.failover.Start()
+	}
 }
 
 // Stop gracefully stops the HTTP server
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping LLM-Sentinel proxy server")
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+	for _, f := range s.forwarders {
+		f.Close()
+	}
+	for _, hook := range s.webhooks {
+		hook.Close()
+	}
+	if s.knownAttacks != nil {
+		s.knownAttacks.Close()
+	}
+	if s.tiered != nil {
+		s.tiered.Close()
+	}
+	if s.poolMonitor != nil {
+		s.poolMonitor.Close()
+	}
+	if s.latency != nil {
+		s.latency.Close()
+	}
+	if s.clientKeys != nil {
+		s.clientKeys.Close()
+	}
+	if s.archiver != nil {
+		if err := s.archiver.Close(); err != nil {
+			s.logger.Warn("Failed to seal event archive segment", zap.Error(err))
+		}
+	}
+	if s.standby != nil {
+		s.standby.Close()
+	}
+	s.rateLimiters.Close()
+	if s.failover != nil {
+		s.failover.Close()
+	}
+	if s.responseCache != nil {
+		s.responseCache.Close()
+	}
+	s.bus.Close()
+	return err
+}
+
+// cfg returns the configuration in effect. The returned value is never
+// modified, so a request that needs several settings to agree should read
+// them from one cfg call.
+func (s *Server) cfg() *config.Config {
+	return s.config.Load()
 }
 
-// handleHealth handles health check requests
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","timestamp":"%s"}`, time.Now().Format(time.RFC3339))
+// Reload applies a changed configuration. The custom PII rules are swapped
+// in first; if they fail to compile nothing changes. Settings read per
+// request, such as the security mode, policies, rate limits and response
+// headers, then take effect for new requests. Settings consumed at startup,
+// such as listeners, routes, pipelines and store connections, keep their
+// startup values until a restart.
+func (s *Server) Reload(cfg *config.Config) {
+	err := s.changeRuntimeConfig("system", "config file reload", func() error {
+		if err := s.detector.SetCustomRules(cfg.Privacy.CustomRules); err != nil {
+			return err
+		}
+		s.config.Store(cfg)
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to reload custom PII rules; keeping previous configuration", zap.Error(err))
+		return
+	}
+
+	// Limiters carry the old rate; new ones are created on each client's
+	// next request
+	s.rateLimiters.reset()
+
+	s.logger.Info("Configuration reloaded", zap.Int("custom_pii_rules", len(cfg.Privacy.CustomRules)))
 }
 
-// handleInfo handles info requests
+// handleInfo handles info requests, including which subsystems are running
+// degraded after a fallback
 func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{
-		"name":"llm-sentinel",
-		"version":"0.1.0",
-		"privacy_enabled":%t,
-		"security_enabled":%t,
-		"detectors_count":%d
-	}`, s.config.Privacy.Enabled, s.config.Security.Enabled, len(s.config.Privacy.Detectors))
+	s.refreshDegradation(r.Context())
+
+	cfg := s.cfg()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":             "llm-sentinel",
+		"version":          "0.1.0",
+		"privacy_enabled":  cfg.Privacy.Enabled,
+		"security_enabled": cfg.Security.Enabled,
+		"detectors_count":  len(cfg.Privacy.Detectors),
+		"pipelines":        s.pipelines,
+		"degraded":         s.degraded.list(),
+	})
 }
 
 // handleWebSocket handles WebSocket connections for the dashboard
@@ -232,3 +750,28 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 func (s *Server) GetWebSocketHub() *websocket.Hub {
 	return s.wsHub
 }
+
+// newKnownAttackFilter creates the known attack filter, sharing it through
+// Redis when the embedding cache uses Redis
+func newKnownAttackFilter(cfg *config.Config, store vector.Backend, degraded *degradationReport, log *logger.Logger) *security.KnownAttackFilter {
+	vsConfig := cfg.Security.VectorSecurity
+	filterLogger := log.WithComponent("known-attacks").Logger
+
+	var redisClient *redis.Client
+	if vsConfig.Embedding.RedisEnabled {
+		redisClient = redis.NewClient(&redis.Options{Addr: vsConfig.Embedding.RedisURL, DB: vsConfig.Embedding.RedisDB})
+		redisClient.AddHook(chaos.RedisHook{})
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			filterLogger.Warn("Redis connection failed, known attack filter will not be shared", zap.Error(err))
+			degraded.mark(subsystemKnownAttacks, "local-only", "Redis unreachable", err)
+			redisClient = nil
+		}
+	}
+
+	return security.NewKnownAttackFilter(store, redisClient, security.KnownAttackOptions{
+		RefreshInterval:   vsConfig.KnownAttacks.RefreshInterval,
+		FalsePositiveRate: vsConfig.KnownAttacks.FalsePositiveRate,
+		RedisKey:          vsConfig.KnownAttacks.RedisKey,
+		Normalization:     textnorm.Options(vsConfig.Normalization.ExactMatch),
+	}, filterLogger)
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/logger"
+)
+
+// newTestServer builds a server from the defaults without the subsystems
+// that need Postgres, Redis or a model
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	return newTestServerWith(t, func(*config.Config) {})
+}
+
+// newTestServerWith is newTestServer with the defaults adjusted by configure
+func newTestServerWith(t *testing.T, configure func(*config.Config)) *Server {
+	t.Helper()
+	cfg := config.GetDefaults()
+	cfg.Security.VectorSecurity.Enabled = false
+	cfg.Events.QueueDir = t.TempDir()
+	configure(cfg)
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	s, err := New(cfg, log)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.bus.Close() })
+	return s
+}
+
+// TestReloadConcurrentWithRequests reloads the configuration while requests
+// read it. Run with -race to check the swap.
+func TestReloadConcurrentWithRequests(t *testing.T) {
+	s := newTestServer(t)
+	handler := s.corsHandler(s.router)
+
+	modes := []string{"block", "log"}
+	configs := make([]*config.Config, len(modes))
+	for i, mode := range modes {
+		cfg := config.GetDefaults()
+		cfg.Security.VectorSecurity.Enabled = false
+		cfg.Security.Mode = mode
+		cfg.Security.RateLimit.RequestsPerMin = 60 * (i + 1)
+		cfg.Security.RateLimit.BurstLimit = 5 * (i + 1)
+		configs[i] = cfg
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.Reload(configs[i%len(configs)])
+		}
+	}()
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/info", nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("GET /info: status %d", rec.Code)
+					return
+				}
+
+				req := httptest.NewRequest(http.MethodPost, "/openai/v1/chat/completions", nil)
+				if mode := s.resolvePolicy("openai", req).Mode; mode != "block" && mode != "log" {
+					t.Errorf("resolved mode %q from neither configuration", mode)
+					return
+				}
+				s.rateLimiters.allow(req.Context(), "203.0.113.7", rateLimitFor(s.cfg(), "203.0.113.7"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	last := configs[(200-1)%len(configs)]
+	if s.cfg() != last {
+		t.Fatalf("config after reloads is not the last one applied")
+	}
+	if got := s.rateLimiters.local("203.0.113.7", rateLimitFor(last, "203.0.113.7")).Burst(); got != last.Security.RateLimit.BurstLimit {
+		t.Fatalf("limiter burst %d, want %d", got, last.Security.RateLimit.BurstLimit)
+	}
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/events"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// defaultSinkEventTypes are forwarded when a sink does not list event types
+var defaultSinkEventTypes = []string{
+	string(websocket.EventTypePIIDetection),
+	string(websocket.EventTypeVectorSecurity),
+	string(websocket.EventTypeAnomaly),
+	string(websocket.EventTypeDetectionAlert),
+}
+
+// setupEventSinks starts a disk-backed forwarder for each configured sink and
+// subscribes it to hub events
+func (s *Server) setupEventSinks() error {
+	cfg := s.cfg().Events
+	for _, sinkCfg := range cfg.Sinks {
+		queue, err := events.OpenDiskQueue(filepath.Join(cfg.QueueDir, sinkCfg.Name), cfg.QueueMaxRecords, cfg.QueueMaxBytes)
+		if err != nil {
+			return fmt.Errorf("failed to open event queue for sink %s: %w", sinkCfg.Name, err)
+		}
+
+		sink := events.NewHTTPSink(sinkCfg.Name, sinkCfg.URL, sinkCfg.Headers, sinkCfg.Timeout)
+		forwarder := events.NewForwarder(sink, queue, events.ForwarderOptions{
+			DeliveryTimeout: sinkCfg.Timeout,
+			InitialBackoff:  cfg.InitialBackoff,
+			MaxBackoff:      cfg.MaxBackoff,
+		}, s.logger.WithComponent("events").Logger)
+		forwarder.Start()
+		s.forwarders = append(s.forwarders, forwarder)
+
+		s.wsHub.AddSink(s.sinkSubscriber(sinkCfg, forwarder))
+
+		s.logger.Info("Event sink attached",
+			zap.String("sink", sinkCfg.Name),
+			zap.String("type", sinkCfg.Type),
+			zap.Int("pending", queue.Len()))
+	}
+	return nil
+}
+
+// sinkSubscriber filters hub events by type and forwards them as JSON
+func (s *Server) sinkSubscriber(sinkCfg config.EventSinkConfig, forwarder *events.Forwarder) websocket.EventSink {
+	eventTypes := sinkCfg.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = defaultSinkEventTypes
+	}
+	wanted := make(map[websocket.EventType]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		wanted[websocket.EventType(t)] = true
+	}
+
+	return func(event websocket.Event) {
+		if !wanted[event.Type] {
+			return
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			s.logger.Error("Failed to encode event for sink", zap.String("sink", sinkCfg.Name), zap.Error(err))
+			return
+		}
+		forwarder.Send(payload)
+	}
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import "net/http"
+
+// Handler returns the server's HTTP handler, for driving requests in
+// process without a listener
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// SetUpstreamTransport sends upstream requests through rt instead of
+// connecting to the configured upstreams. Call it before serving requests.
+func (s *Server) SetUpstreamTransport(rt http.RoundTripper) {
+	s.upstreamTransport = rt
+}
+
+// ResourceCounts reports the sizes of per-client and per-connection state
+// that grows with traffic, so long runs can spot state that is never
+// released
+func (s *Server) ResourceCounts() map[string]int {
+	counts := make(map[string]int)
+
+	counts["rate_limiters"] = s.rateLimiters.size()
+
+	counts["websocket_clients"] = int(s.wsHub.GetStats().ActiveConnections)
+
+	if s.dedup != nil {
+		s.dedup.mu.Lock()
+		counts["dedup_entries"] = len(s.dedup.entries)
+		s.dedup.mu.Unlock()
+	}
+	if s.vectorStore != nil {
+		for _, pool := range s.vectorStore.PoolStats() {
+			counts["db_"+pool.Pool+"_open"] = pool.Open
+		}
+	}
+	return counts
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"go.uber.org/zap"
+)
+
+// ollamaStreamingPaths stream unless the request sets "stream": false
+var ollamaStreamingPaths = map[string]bool{
+	"/api/chat":     true,
+	"/api/generate": true,
+}
+
+// streamingMethodSuffixes end the paths of dedicated streaming methods
+var streamingMethodSuffixes = []string{
+	":streamGenerateContent",       // Gemini, Vertex AI
+	"/invoke-with-response-stream", // Bedrock InvokeModel
+	"/converse-stream",             // Bedrock Converse
+}
+
+// streamingMiddleware marks requests that ask for a streamed response so the
+// proxy flushes each upstream chunk as it arrives. The prompt still passes
+// through the configured pipeline; only the response side changes.
+func (s *Server) streamingMiddleware(route string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if !s.cfg().Upstream.Streaming.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			analysis, r, err := readRequestAnalysis(r)
+			if err != nil {
+				http.Error(w, "Failed to read request", http.StatusInternalServerError)
+				return
+			}
+
+			upstreamPath := strings.TrimPrefix(r.URL.Path, "/"+route)
+			if !wantsStream(route, upstreamPath, r.Header, analysis.document(false)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A generation can outlast server.write_timeout; give the stream
+			// its own deadline instead
+			deadline := time.Now().Add(s.cfg().Upstream.Streaming.MaxDuration)
+			if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+				s.logger.WithRequestID(getRequestID(r.Context())).Debug("Could not extend write deadline for stream", zap.Error(err))
+			}
+			if record := getAuditRecord(r.Context()); record != nil {
+				record.setStreamed()
+			}
+			if trace := getLatencyTrace(r.Context()); trace != nil {
+				trace.setRoute(route)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctxkeys.Streaming.WithValue(r.Context(), true)))
+		})
+	}
+}
+
+// wantsStream reports whether a request asks for a streamed response, either
+// through its Accept header or the body's "stream" flag. Ollama's chat and
+// generate endpoints stream by default, and Gemini, Vertex AI and Bedrock
+// stream through separate streaming methods.
+func wantsStream(route, upstreamPath string, header http.Header, request map[string]interface{}) bool {
+	if strings.Contains(header.Get("Accept"), "text/event-stream") {
+		return true
+	}
+	for _, suffix := range streamingMethodSuffixes {
+		if strings.HasSuffix(upstreamPath, suffix) {
+			return true
+		}
+	}
+
+	if stream, ok := request["stream"].(bool); ok {
+		return stream
+	}
+	return route == "ollama" && ollamaStreamingPaths[upstreamPath]
+}
+
+// isStreaming reports whether the request was marked for streaming
+func isStreaming(r *http.Request) bool {
+	streaming, _ := ctxkeys.Streaming.Value(r.Context())
+	return streaming
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/fingerprint"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// templatePreviewLength bounds the template text kept for display
+const templatePreviewLength = 120
+
+// promptTemplate is the template a request's prompts were filled from
+type promptTemplate struct {
+	client      string
+	fingerprint string
+}
+
+// promptTemplateKey carries the request's prompt template
+var promptTemplateKey = ctxkeys.New[promptTemplate]("prompt_template")
+
+// requestTemplate reduces the messages to analyze to one template. Each
+// message's role is kept, so the same text as system prompt and as user
+// message are different templates.
+func requestTemplate(messages []promptMessage) string {
+	if len(messages) == 1 && messages[0].role == "" {
+		return fingerprint.Template(messages[0].text)
+	}
+	parts := make([]string, len(messages))
+	for i, message := range messages {
+		parts[i] = message.role + ": " + fingerprint.Template(message.text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// observeTemplate records the request's prompt template for its client. A
+// template new to a client that mostly reuses known ones marks the
+// request's policy for strict analysis, and is broadcast.
+func (s *Server) observeTemplate(r *http.Request, messages []promptMessage) *http.Request {
+	client := clientIdentity(r, s.cfg())
+	template := requestTemplate(messages)
+	observation := s.templates.Observe(client, fingerprint.Sum(template), fingerprint.Preview(template, templatePreviewLength), time.Now())
+
+	ctx := promptTemplateKey.WithValue(r.Context(), promptTemplate{client: client, fingerprint: observation.Fingerprint})
+	if !observation.Unusual {
+		return r.WithContext(ctx)
+	}
+
+	requestID := getRequestID(r.Context())
+	s.logger.WithRequestID(requestID).Warn("New prompt template from client",
+		zap.String("client_key", client),
+		zap.String("fingerprint", observation.Fingerprint),
+		zap.Float64("known_share", observation.KnownShare))
+	s.emit(websocket.Event{
+		Type:      websocket.EventTypePromptTemplate,
+		Timestamp: time.Now(),
+		RequestID: requestID,
+		Data: websocket.PromptTemplateEvent{
+			ClientKey:   client,
+			Fingerprint: observation.Fingerprint,
+			KnownShare:  observation.KnownShare,
+		},
+	})
+
+	policy := s.requestPolicy(r)
+	policy.NewTemplate = true
+	return r.WithContext(ctxkeys.Policy.WithValue(ctx, policy))
+}
+
+// recordTemplateBlock keeps a blocked request's template from becoming
+// known to its client
+func (s *Server) recordTemplateBlock(r *http.Request) {
+	if s.templates == nil {
+		return
+	}
+	if template, ok := promptTemplateKey.Value(r.Context()); ok {
+		s.templates.RecordBlock(template.client, template.fingerprint)
+	}
+}
+
+// handleTemplateStats lists each client's prompt templates, most used
+// first: the top ones per client, or every template of ?client=
+func (s *Server) handleTemplateStats(w http.ResponseWriter, r *http.Request) {
+	if client := r.URL.Query().Get("client"); client != "" {
+		stats, ok := s.templates.Client(client)
+		if !ok {
+			http.Error(w, "No templates recorded for client "+client, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, stats)
+		return
+	}
+
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid limit: must be 0 (all) or more", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	writeJSON(w, http.StatusOK, s.templates.Stats(limit))
+}
@@ -0,0 +1,32 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+)
+
+// TestTemplateStatsRequiresAdminToken checks /admin/templates sits behind
+// the admin token like the other admin endpoints
+func TestTemplateStatsRequiresAdminToken(t *testing.T) {
+	s := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.Security.Templates.Enabled = true
+		cfg.Server.AdminTokens = map[string]string{"ops": "secret-token"}
+	})
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/templates", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("GET /admin/templates without token: status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/templates", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/templates with token: status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/raaihank/llm-sentinel/internal/ctxkeys"
+	"github.com/raaihank/llm-sentinel/internal/security"
+)
+
+// pendingVerdictKey carries a concurrent analysis verdict
+var pendingVerdictKey = ctxkeys.New[*pendingVerdict]("pending_verdict")
+
+// errRequestBlocked aborts an in-flight upstream request whose prompt was flagged
+var errRequestBlocked = errors.New("request blocked by vector security")
+
+// pendingVerdict is the result of a prompt analysis running concurrently with
+// upstream connection setup
+type pendingVerdict struct {
+	done    chan struct{}
+	once    sync.Once
+	result  *security.SecurityResult
+	blocked bool
+}
+
+func newPendingVerdict() *pendingVerdict {
+	return &pendingVerdict{done: make(chan struct{})}
+}
+
+// resolve records the analysis outcome and releases any waiters
+func (v *pendingVerdict) resolve(result *security.SecurityResult, blocked bool) {
+	v.once.Do(func() {
+		v.result = result
+		v.blocked = blocked
+		close(v.done)
+	})
+}
+
+// wait blocks until the verdict resolves or ctx is done. It reports whether
+// the request must be blocked.
+func (v *pendingVerdict) wait(ctx context.Context) (bool, error) {
+	select {
+	case <-v.done:
+		return v.blocked, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// isBlocked reports whether the verdict has resolved to a block
+func (v *pendingVerdict) isBlocked() bool {
+	select {
+	case <-v.done:
+		return v.blocked
+	default:
+		return false
+	}
+}
+
+// gatedBody holds back the request body until the verdict resolves so the
+// upstream dial and TLS handshake can proceed while analysis is running
+type gatedBody struct {
+	ctx     context.Context
+	reader  io.Reader
+	verdict *pendingVerdict
+	opened  bool
+}
+
+func (b *gatedBody) Read(p []byte) (int, error) {
+	if !b.opened {
+		blocked, err := b.verdict.wait(b.ctx)
+		if err != nil {
+			return 0, err
+		}
+		if blocked {
+			return 0, errRequestBlocked
+		}
+		b.opened = true
+	}
+	return b.reader.Read(p)
+}
+
+func (b *gatedBody) Close() error {
+	return nil
+}
+
+// getPendingVerdict extracts a concurrent analysis verdict from context
+func getPendingVerdict(ctx context.Context) *pendingVerdict {
+	v, _ := pendingVerdictKey.Value(ctx)
+	return v
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/events"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// Webhook event types
+const (
+	webhookRequestBlocked      = "request_blocked"
+	webhookHighSeverityFinding = "high_severity_finding"
+)
+
+// webhookPayload is the JSON body of a webhook delivery
+type webhookPayload struct {
+	ID        string      `json:"id"` // delivery ID, repeated on retries
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Severity  string      `json:"severity"`
+	Source    string      `json:"source"` // pii_detection or vector_security
+	RequestID string      `json:"request_id,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// webhookDispatcher decides which detections one webhook receives
+type webhookDispatcher struct {
+	hook        *events.Webhook
+	events      map[string]bool
+	minSeverity string
+	logger      *zap.Logger
+}
+
+// setupWebhooks subscribes each configured webhook to detection events on
+// the internal bus. Deliveries run on the subscription's goroutine, so a
+// slow receiver delays only its own webhook.
+func (s *Server) setupWebhooks() {
+	for _, hookCfg := range s.cfg().Events.Webhooks {
+		hook := events.NewWebhook(hookCfg.Name, hookCfg.URL, hookCfg.Headers, hookCfg.Timeout, hookCfg.Secret, events.RetryPolicy{
+			MaxAttempts:    hookCfg.Retry.MaxAttempts,
+			InitialBackoff: hookCfg.Retry.InitialBackoff,
+			MaxBackoff:     hookCfg.Retry.MaxBackoff,
+		})
+		s.webhooks = append(s.webhooks, hook)
+
+		dispatcher := newWebhookDispatcher(hookCfg, hook, s.logger.WithComponent("webhooks").Logger)
+		events.Subscribe(s.bus, TopicVectorSecurity, "webhook:"+hookCfg.Name, 0, func(data websocket.VectorSecurityEvent) {
+			dispatcher.dispatch(websocket.Event{Type: websocket.EventTypeVectorSecurity, Timestamp: time.Now(), RequestID: data.RequestID, Data: data})
+		})
+		events.Subscribe(s.bus, TopicPIIDetection, "webhook:"+hookCfg.Name, 0, func(data websocket.PIIDetectionEvent) {
+			dispatcher.dispatch(websocket.Event{Type: websocket.EventTypePIIDetection, Timestamp: time.Now(), RequestID: data.RequestID, Data: data})
+		})
+
+		s.logger.Info("Webhook attached",
+			zap.String("webhook", hookCfg.Name),
+			zap.Strings("events", hookCfg.Events),
+			zap.Bool("signed", hookCfg.Secret != ""))
+	}
+}
+
+func newWebhookDispatcher(cfg config.WebhookConfig, hook *events.Webhook, logger *zap.Logger) *webhookDispatcher {
+	eventTypes := cfg.Events
+	if len(eventTypes) == 0 {
+		eventTypes = config.WebhookEvents
+	}
+	wanted := make(map[string]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		wanted[t] = true
+	}
+
+	minSeverity := cfg.MinSeverity
+	if minSeverity == "" {
+		minSeverity = "high"
+	}
+	return &webhookDispatcher{
+		hook:        hook,
+		events:      wanted,
+		minSeverity: minSeverity,
+		logger:      logger.With(zap.String("webhook", cfg.Name)),
+	}
+}
+
+// dispatch delivers event if it is a block or a finding at or above the
+// severity threshold and the webhook subscribed to that outcome
+func (d *webhookDispatcher) dispatch(event websocket.Event) {
+	severity := websocket.EventSeverity(event)
+	eventType := ""
+	switch data := event.Data.(type) {
+	case websocket.VectorSecurityEvent:
+		if data.Action == "blocked" {
+			eventType = webhookRequestBlocked
+		} else if data.IsMalicious && websocket.SeverityAtLeast(severity, d.minSeverity) {
+			eventType = webhookHighSeverityFinding
+		}
+	case websocket.PIIDetectionEvent:
+		if websocket.SeverityAtLeast(severity, d.minSeverity) {
+			eventType = webhookHighSeverityFinding
+		}
+	}
+	if eventType == "" || !d.events[eventType] {
+		return
+	}
+
+	deliveryID := newDeliveryID()
+	payload, err := json.Marshal(webhookPayload{
+		ID:        deliveryID,
+		Event:     eventType,
+		Timestamp: event.Timestamp,
+		Severity:  severity,
+		Source:    string(event.Type),
+		RequestID: event.RequestID,
+		Data:      event.Data,
+	})
+	if err != nil {
+		d.logger.Error("Failed to encode webhook payload", zap.Error(err))
+		return
+	}
+
+	if err := d.hook.Deliver(eventType, deliveryID, payload); err != nil {
+		d.logger.Error("Webhook delivery dropped",
+			zap.String("event", eventType),
+			zap.String("request_id", event.RequestID),
+			zap.Error(err))
+	}
+}
+
+// newDeliveryID returns a random identifier for one webhook delivery
+func newDeliveryID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
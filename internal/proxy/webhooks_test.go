@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/events"
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// TestWebhookDispatch checks which detections reach a webhook and as which
+// event
+func TestWebhookDispatch(t *testing.T) {
+	blocked := websocket.Event{Type: websocket.EventTypeVectorSecurity, Timestamp: time.Now(), RequestID: "req-1",
+		Data: websocket.VectorSecurityEvent{RequestID: "req-1", IsMalicious: true, AttackType: "jailbreak", Confidence: 0.99, Action: "blocked"}}
+	loggedCritical := websocket.Event{Type: websocket.EventTypeVectorSecurity, Timestamp: time.Now(), RequestID: "req-2",
+		Data: websocket.VectorSecurityEvent{RequestID: "req-2", IsMalicious: true, AttackType: "jailbreak", Confidence: 0.99, Action: "logged"}}
+	allowed := websocket.Event{Type: websocket.EventTypeVectorSecurity, Timestamp: time.Now(), RequestID: "req-3",
+		Data: websocket.VectorSecurityEvent{RequestID: "req-3", Action: "allowed"}}
+	lowPII := websocket.Event{Type: websocket.EventTypePIIDetection, Timestamp: time.Now(), RequestID: "req-4",
+		Data: websocket.PIIDetectionEvent{RequestID: "req-4", Findings: []privacy.Finding{{EntityType: "employee_id", Count: 1, Severity: "low"}}}}
+	criticalPII := websocket.Event{Type: websocket.EventTypePIIDetection, Timestamp: time.Now(), RequestID: "req-5",
+		Data: websocket.PIIDetectionEvent{RequestID: "req-5", Findings: []privacy.Finding{{EntityType: "ssn", Count: 1, Severity: "critical"}}}}
+	all := []websocket.Event{blocked, loggedCritical, allowed, lowPII, criticalPII}
+
+	tests := []struct {
+		name   string
+		events []string
+		want   map[string]string // request ID to webhook event
+	}{
+		{
+			name: "all events",
+			want: map[string]string{"req-1": webhookRequestBlocked, "req-2": webhookHighSeverityFinding, "req-5": webhookHighSeverityFinding},
+		},
+		{
+			name:   "blocks only",
+			events: []string{webhookRequestBlocked},
+			want:   map[string]string{"req-1": webhookRequestBlocked},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := make(map[string]string)
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload webhookPayload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				if payload.ID != r.Header.Get(events.WebhookDeliveryHeader) || payload.Event != r.Header.Get(events.WebhookEventHeader) {
+					t.Errorf("payload %s/%s disagrees with headers %v", payload.ID, payload.Event, r.Header)
+				}
+				mu.Lock()
+				got[payload.RequestID] = payload.Event
+				mu.Unlock()
+			}))
+			defer receiver.Close()
+
+			cfg := config.WebhookConfig{Name: "soar", URL: receiver.URL, Secret: "s3cret", Events: tt.events}
+			hook := events.NewWebhook(cfg.Name, cfg.URL, nil, time.Second, cfg.Secret, events.RetryPolicy{MaxAttempts: 1})
+			defer hook.Close()
+			dispatcher := newWebhookDispatcher(cfg, hook, zap.NewNop())
+			for _, event := range all {
+				dispatcher.dispatch(event)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(got) != len(tt.want) {
+				t.Fatalf("delivered %v, want %v", got, tt.want)
+			}
+			for requestID, event := range tt.want {
+				if got[requestID] != event {
+					t.Fatalf("delivered %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package security
+
+import (
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/lru"
+)
+
+// Anomaly kinds
+const (
+	AnomalyBurst    = "burst"
+	AnomalyOffHours = "off_hours"
+)
+
+// AnomalyOptions tunes behavioral baselining
+type AnomalyOptions struct {
+	BurstFactor       float64       // minute rate above this multiple of baseline is a burst
+	MinBurstRequests  int           // minute rate must also exceed this to count
+	MinObservations   int           // requests seen before a baseline is trusted
+	OffHoursThreshold float64       // hour share of traffic below this is unusual
+	Window            time.Duration // how long a client stays flagged after an anomaly
+	MaxClients        int           // profiles kept; the least recently seen are forgotten
+	IdleExpiry        time.Duration // profiles unseen this long are forgotten
+}
+
+// Anomaly describes a sharp deviation from a client's baseline
+type Anomaly struct {
+	ClientKey  string    `json:"client_key"`
+	Kind       string    `json:"kind"`
+	Observed   float64   `json:"observed"` // requests this minute, or hour share
+	Baseline   float64   `json:"baseline"` // typical requests per minute, or expected hour share
+	Hour       int       `json:"hour"`
+	WindowEnds time.Time `json:"window_ends"`
+}
+
+// clientProfile is the learned behavior of one client
+type clientProfile struct {
+	hours        [24]float64 // decayed request counts per UTC hour
+	totalHours   float64
+	minuteRate   float64 // EWMA of completed minute counts
+	minute       int64   // current minute (unix minutes)
+	minuteCount  int
+	observations int
+	anomalyUntil time.Time
+	reported     map[string]time.Time
+}
+
+// hourDecay keeps the hour histogram weighted towards recent weeks
+const hourDecay = 0.9995
+
+// rateAlpha is the EWMA weight of each completed minute
+const rateAlpha = 0.05
+
+// defaultMaxAnomalyClients bounds the profiles kept when no limit is set.
+// Clients without a key are identified by a hash of whatever Authorization
+// header they send, so rotating junk tokens could otherwise grow the
+// profiles without limit.
+const defaultMaxAnomalyClients = 10000
+
+// AnomalyDetector baselines per-client request rate and active hours and
+// flags sharp deviations, such as a leaked key used from another time zone
+type AnomalyDetector struct {
+	options AnomalyOptions
+
+	mu       sync.Mutex
+	profiles *lru.Cache[string, *clientProfile] // expire after IdleExpiry unseen
+}
+
+// NewAnomalyDetector creates a detector with the given options
+func NewAnomalyDetector(options AnomalyOptions) *AnomalyDetector {
+	if options.BurstFactor <= 1 {
+		options.BurstFactor = 5
+	}
+	if options.Window <= 0 {
+		options.Window = 15 * time.Minute
+	}
+	if options.MaxClients < 1 {
+		options.MaxClients = defaultMaxAnomalyClients
+	}
+	if options.IdleExpiry <= 0 {
+		options.IdleExpiry = 7 * 24 * time.Hour
+	}
+	return &AnomalyDetector{
+		options:  options,
+		profiles: lru.NewWithTTL[string, *clientProfile](options.MaxClients, options.IdleExpiry),
+	}
+}
+
+// Observe records a request and returns any newly detected anomalies.
+// Each kind is reported at most once per anomaly window.
+func (d *AnomalyDetector) Observe(clientKey string, now time.Time) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.profiles.Get(clientKey)
+	if !ok {
+		p = &clientProfile{reported: make(map[string]time.Time)}
+	}
+	// Re-adding restarts the idle expiry; a new client may evict the least
+	// recently seen one
+	d.profiles.Add(clientKey, p)
+
+	// Roll the minute bucket, folding completed (and skipped, empty) minutes into the rate
+	minute := now.Unix() / 60
+	if p.minute == 0 {
+		p.minute = minute
+	}
+	for p.minute < minute {
+		p.minuteRate = (1-rateAlpha)*p.minuteRate + rateAlpha*float64(p.minuteCount)
+		p.minuteCount = 0
+		p.minute++
+		if minute-p.minute > 60 {
+			// Long gap: decay straight to the end rather than loop per minute
+			p.minuteRate *= pow(1-rateAlpha, int(minute-p.minute))
+			p.minute = minute
+		}
+	}
+	p.minuteCount++
+
+	hour := now.UTC().Hour()
+	hourShare := 0.0
+	if p.totalHours > 0 {
+		hourShare = p.hours[hour] / p.totalHours
+	}
+
+	var anomalies []Anomaly
+	if p.observations >= d.options.MinObservations {
+		threshold := d.options.BurstFactor * p.minuteRate
+		if p.minuteCount > d.options.MinBurstRequests && float64(p.minuteCount) > threshold {
+			anomalies = d.flagLocked(anomalies, p, clientKey, AnomalyBurst, float64(p.minuteCount), p.minuteRate, hour, now)
+		}
+		if hourShare < d.options.OffHoursThreshold {
+			anomalies = d.flagLocked(anomalies, p, clientKey, AnomalyOffHours, hourShare, 1.0/24, hour, now)
+		}
+	}
+
+	// Learn after checking so the anomaly itself does not become the baseline instantly
+	for h := range p.hours {
+		p.hours[h] *= hourDecay
+	}
+	p.totalHours = p.totalHours*hourDecay + 1
+	p.hours[hour]++
+	p.observations++
+
+	return anomalies
+}
+
+// InAnomaly reports whether the client is inside an anomaly window
+func (d *AnomalyDetector) InAnomaly(clientKey string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.profiles.Get(clientKey)
+	return ok && now.Before(p.anomalyUntil)
+}
+
+func (d *AnomalyDetector) flagLocked(anomalies []Anomaly, p *clientProfile, clientKey, kind string, observed, baseline float64, hour int, now time.Time) []Anomaly {
+	p.anomalyUntil = now.Add(d.options.Window)
+	if last, ok := p.reported[kind]; ok && now.Sub(last) < d.options.Window {
+		return anomalies
+	}
+	p.reported[kind] = now
+	return append(anomalies, Anomaly{
+		ClientKey:  clientKey,
+		Kind:       kind,
+		Observed:   observed,
+		Baseline:   baseline,
+		Hour:       hour,
+		WindowEnds: p.anomalyUntil,
+	})
+}
+
+func pow(base float64, n int) float64 {
+	result := 1.0
+	for n > 0 {
+		if n&1 == 1 {
+			result *= base
+		}
+		base *= base
+		n >>= 1
+	}
+	return result
+}
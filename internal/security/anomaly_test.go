@@ -0,0 +1,26 @@
+package security
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestAnomalyProfilesBounded checks clients rotating identities cannot grow
+// the profiles past the cap, and the most recently seen are kept
+func TestAnomalyProfilesBounded(t *testing.T) {
+	d := NewAnomalyDetector(AnomalyOptions{MaxClients: 10})
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		d.Observe(fmt.Sprintf("key:%d", i), now)
+	}
+	if n := d.profiles.Len(); n != 10 {
+		t.Fatalf("kept %d profiles, want the cap of 10", n)
+	}
+	if _, ok := d.profiles.Get("key:99"); !ok {
+		t.Fatal("most recently seen profile evicted")
+	}
+	if _, ok := d.profiles.Get("key:0"); ok {
+		t.Fatal("least recently seen profile kept past the cap")
+	}
+}
@@ -0,0 +1,226 @@
+package security
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/lru"
+)
+
+// Conversation policies applied once a message in a conversation is blocked
+const (
+	ConversationPolicyNone   = "none"   // track only
+	ConversationPolicyReview = "review" // hold further messages until a reviewer clears the conversation
+)
+
+// defaultMaxConversations bounds the conversations tracked when no limit is
+// set. Conversation IDs come from the client, so a flood of blocked
+// messages under fresh IDs could otherwise grow the tracker without limit.
+const defaultMaxConversations = 10000
+
+// ConversationState records security history for one conversation
+type ConversationState struct {
+	Client        string    `json:"client"`
+	ID            string    `json:"id"`
+	BlockedCount  int       `json:"blocked_count"`
+	LastBlockedAt time.Time `json:"last_blocked_at"`
+	LastAttack    string    `json:"last_attack,omitempty"`
+	PendingReview bool      `json:"pending_review"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// conversationKey scopes a client-supplied conversation ID to the client
+// that sent it, so one client cannot flag or clear another's conversation
+type conversationKey struct {
+	client string
+	id     string
+}
+
+// ConversationTracker keeps per-conversation security state in memory.
+// Entries expire after ttl of inactivity, and the least recently seen are
+// forgotten beyond maxConversations. Conversations held for review are
+// pinned outside that LRU, so a client cannot release its own held
+// conversation by opening new ones. While maxConversations are held, every
+// unknown conversation is held too.
+type ConversationTracker struct {
+	policy  string
+	ttl     time.Duration
+	maxHeld int
+
+	mu            sync.Mutex
+	conversations *lru.Cache[conversationKey, *ConversationState] // expire after ttl unseen
+	held          map[conversationKey]*ConversationState          // pending review; expire after ttl unseen
+}
+
+// NewConversationTracker creates a tracker applying policy to flagged
+// conversations. A ttl of 0 keeps conversations until they are evicted.
+func NewConversationTracker(policy string, ttl time.Duration, maxConversations int) *ConversationTracker {
+	if maxConversations < 1 {
+		maxConversations = defaultMaxConversations
+	}
+	return &ConversationTracker{
+		policy:        policy,
+		ttl:           ttl,
+		maxHeld:       maxConversations,
+		conversations: lru.NewWithTTL[conversationKey, *ConversationState](maxConversations, ttl),
+		held:          make(map[conversationKey]*ConversationState),
+	}
+}
+
+// RecordBlock notes that a message in the client's conversation was blocked
+func (t *ConversationTracker) RecordBlock(client, id, attackType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.getLocked(client, id, true)
+	state.BlockedCount++
+	state.LastBlockedAt = time.Now()
+	state.LastAttack = attackType
+	if t.policy == ConversationPolicyReview {
+		state.PendingReview = true
+		t.holdLocked(client, id, state)
+	}
+}
+
+// RequiresReview reports whether new messages in the client's conversation
+// must be held
+func (t *ConversationTracker) RequiresReview(client, id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.getLocked(client, id, false)
+	if state == nil {
+		// Refuse new conversations rather than evict a held one
+		return t.heldFullLocked()
+	}
+	return state.PendingReview
+}
+
+// ClearReview releases a conversation held for review. It reports whether the
+// conversation was known.
+func (t *ConversationTracker) ClearReview(client, id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.getLocked(client, id, false)
+	if state == nil {
+		return false
+	}
+	state.PendingReview = false
+
+	// Released conversations go back to the LRU
+	key := conversationKey{client: client, id: id}
+	if _, ok := t.held[key]; ok {
+		delete(t.held, key)
+		t.conversations.Add(key, state)
+	}
+	return true
+}
+
+// Get returns a copy of a conversation's state
+func (t *ConversationTracker) Get(client, id string) (ConversationState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := conversationKey{client: client, id: id}
+	state, ok := t.heldLocked(key)
+	if !ok {
+		state, ok = t.conversations.Get(key)
+	}
+	if !ok {
+		return ConversationState{}, false
+	}
+	return *state, true
+}
+
+// Flagged lists conversations with at least one blocked message, most recent first
+func (t *ConversationTracker) Flagged() []ConversationState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	flagged := make([]ConversationState, 0)
+	t.pruneHeldLocked()
+	for _, state := range t.held {
+		flagged = append(flagged, *state)
+	}
+	t.conversations.Range(func(_ conversationKey, state *ConversationState) bool {
+		if state.BlockedCount > 0 {
+			flagged = append(flagged, *state)
+		}
+		return true
+	})
+	sort.Slice(flagged, func(i, j int) bool {
+		return flagged[i].LastBlockedAt.After(flagged[j].LastBlockedAt)
+	})
+	return flagged
+}
+
+// getLocked returns the conversation and marks it seen. Only blocks create
+// conversations, so lookups of unknown IDs cannot grow the tracker.
+func (t *ConversationTracker) getLocked(client, id string, create bool) *ConversationState {
+	key := conversationKey{client: client, id: id}
+	if state, ok := t.heldLocked(key); ok {
+		state.LastSeen = time.Now()
+		return state
+	}
+	state, ok := t.conversations.Get(key)
+	if !ok {
+		if !create {
+			return nil
+		}
+		state = &ConversationState{Client: client, ID: id}
+	}
+	// Re-adding restarts the idle expiry; a new conversation may evict the
+	// least recently seen one
+	t.conversations.Add(key, state)
+	state.LastSeen = time.Now()
+	return state
+}
+
+// holdLocked pins a conversation pending review outside the LRU. When the
+// held set is full it stays in the LRU, and unknown conversations are held
+// until reviewers clear some.
+func (t *ConversationTracker) holdLocked(client, id string, state *ConversationState) {
+	key := conversationKey{client: client, id: id}
+	if _, ok := t.held[key]; ok || t.heldFullLocked() {
+		return
+	}
+	t.conversations.Remove(key)
+	t.held[key] = state
+}
+
+// heldLocked returns a held conversation unless it has been idle past the ttl
+func (t *ConversationTracker) heldLocked(key conversationKey) (*ConversationState, bool) {
+	state, ok := t.held[key]
+	if !ok {
+		return nil, false
+	}
+	if t.expired(state) {
+		delete(t.held, key)
+		return nil, false
+	}
+	return state, true
+}
+
+// heldFullLocked reports whether no more conversations can be pinned
+func (t *ConversationTracker) heldFullLocked() bool {
+	if len(t.held) < t.maxHeld {
+		return false
+	}
+	t.pruneHeldLocked()
+	return len(t.held) >= t.maxHeld
+}
+
+// pruneHeldLocked forgets held conversations idle past the ttl
+func (t *ConversationTracker) pruneHeldLocked() {
+	for key, state := range t.held {
+		if t.expired(state) {
+			delete(t.held, key)
+		}
+	}
+}
+
+func (t *ConversationTracker) expired(state *ConversationState) bool {
+	return t.ttl > 0 && time.Since(state.LastSeen) > t.ttl
+}
@@ -0,0 +1,79 @@
+package security
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestConversationsScopedToClient checks one client cannot flag or clear
+// another client's conversation by reusing its ID
+func TestConversationsScopedToClient(t *testing.T) {
+	tracker := NewConversationTracker(ConversationPolicyReview, time.Hour, 10)
+
+	tracker.RecordBlock("key:attacker", "conv-1", "jailbreak")
+	if tracker.RequiresReview("key:victim", "conv-1") {
+		t.Fatal("victim's conversation held for a block by another client")
+	}
+	if !tracker.RequiresReview("key:attacker", "conv-1") {
+		t.Fatal("attacker's conversation not held after a block")
+	}
+
+	tracker.RecordBlock("key:victim", "conv-2", "jailbreak")
+	if tracker.ClearReview("key:attacker", "conv-2") {
+		t.Fatal("attacker cleared another client's conversation")
+	}
+	if !tracker.RequiresReview("key:victim", "conv-2") {
+		t.Fatal("victim's conversation released by another client")
+	}
+}
+
+// TestConversationsBounded checks blocks under fresh IDs cannot grow the
+// tracker past its cap, and idle conversations expire
+func TestConversationsBounded(t *testing.T) {
+	tracker := NewConversationTracker(ConversationPolicyNone, time.Hour, 10)
+	for i := 0; i < 100; i++ {
+		tracker.RecordBlock("key:flood", fmt.Sprintf("conv-%d", i), "jailbreak")
+	}
+	if n := len(tracker.Flagged()); n != 10 {
+		t.Fatalf("tracked %d conversations, want the cap of 10", n)
+	}
+	if _, ok := tracker.Get("key:flood", "conv-99"); !ok {
+		t.Fatal("most recent conversation evicted")
+	}
+	if _, ok := tracker.Get("key:flood", "conv-0"); ok {
+		t.Fatal("least recent conversation kept past the cap")
+	}
+
+	expiring := NewConversationTracker(ConversationPolicyReview, time.Millisecond, 10)
+	expiring.RecordBlock("key:client", "conv-1", "jailbreak")
+	time.Sleep(5 * time.Millisecond)
+	if expiring.RequiresReview("key:client", "conv-1") {
+		t.Fatal("conversation still held after its ttl")
+	}
+}
+
+// TestHeldConversationsPinned checks a client cannot release its held
+// conversation by flooding new ones, and that new conversations are held
+// while the held set is full
+func TestHeldConversationsPinned(t *testing.T) {
+	tracker := NewConversationTracker(ConversationPolicyReview, time.Hour, 10)
+	tracker.RecordBlock("key:attacker", "conv-held", "jailbreak")
+	for i := 0; i < 100; i++ {
+		tracker.RecordBlock("key:attacker", fmt.Sprintf("conv-%d", i), "jailbreak")
+	}
+	if !tracker.RequiresReview("key:attacker", "conv-held") {
+		t.Fatal("held conversation evicted by new conversations")
+	}
+	if !tracker.RequiresReview("key:other", "conv-new") {
+		t.Fatal("new conversation accepted while the held set is full")
+	}
+
+	tracker.ClearReview("key:attacker", "conv-held")
+	if tracker.RequiresReview("key:other", "conv-new") {
+		t.Fatal("new conversation held after a reviewer freed a slot")
+	}
+	if tracker.RequiresReview("key:attacker", "conv-held") {
+		t.Fatal("cleared conversation still held")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package security
+
+import "time"
+
+// processCPUTime is unavailable on this platform; CPU-based tier changes are disabled
+func processCPUTime() time.Duration {
+	return 0
+}
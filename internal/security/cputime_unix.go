@@ -0,0 +1,18 @@
+//go:build unix
+
+package security
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns user plus system CPU time consumed by the process,
+// including time spent in cgo (e.g. ONNX inference)
+func processCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
@@ -0,0 +1,209 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/raaihank/llm-sentinel/internal/bloom"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
+	"github.com/raaihank/llm-sentinel/internal/vector"
+	"go.uber.org/zap"
+)
+
+// AttackTypeKnownAttack is reported for exact replays of known malicious prompts
+const AttackTypeKnownAttack = "known_attack"
+
+// KnownAttackOptions controls the known-attack filter
+type KnownAttackOptions struct {
+	RefreshInterval   time.Duration
+	FalsePositiveRate float64
+	RedisKey          string // shared filter location; a companion lock key avoids rebuild storms
+	Normalization     textnorm.Options
+}
+
+// KnownAttackFilter is a bloom filter of exact known-malicious prompts. It is
+// rebuilt periodically from the vector store and shared between instances
+// through Redis, so only one instance pays for a rebuild per interval.
+type KnownAttackFilter struct {
+	store   vector.Backend
+	redis   *redis.Client
+	options KnownAttackOptions
+	logger  *zap.Logger
+
+	filter atomic.Pointer[bloom.Filter]
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewKnownAttackFilter creates a filter. redisClient may be nil, in which
+// case each instance builds its own filter from the store.
+func NewKnownAttackFilter(store vector.Backend, redisClient *redis.Client, options KnownAttackOptions, logger *zap.Logger) *KnownAttackFilter {
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = 10 * time.Minute
+	}
+	if options.RedisKey == "" {
+		options.RedisKey = "llm-sentinel:known-attacks"
+	}
+	return &KnownAttackFilter{
+		store:   store,
+		redis:   redisClient,
+		options: options,
+		logger:  logger,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start loads the filter and keeps it fresh in the background
+func (k *KnownAttackFilter) Start() {
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+
+		ticker := time.NewTicker(k.options.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), k.options.RefreshInterval)
+			if err := k.Refresh(ctx); err != nil {
+				k.logger.Warn("Known attack filter refresh failed", zap.Error(err))
+			}
+			cancel()
+
+			select {
+			case <-k.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops background refreshes
+func (k *KnownAttackFilter) Close() {
+	close(k.stop)
+	k.wg.Wait()
+}
+
+// Contains reports whether prompt is an exact replay of a known attack, after
+// both are canonicalized. Bloom filters can report false positives at the
+// configured rate.
+func (k *KnownAttackFilter) Contains(prompt string) bool {
+	filter := k.filter.Load()
+	return filter != nil && filter.Test([]byte(textnorm.Canonical(prompt, k.options.Normalization)))
+}
+
+// Refresh loads a shared filter from Redis if a fresh one exists, otherwise
+// rebuilds from the store and publishes it
+func (k *KnownAttackFilter) Refresh(ctx context.Context) error {
+	if k.redis != nil {
+		loaded, err := k.loadShared(ctx)
+		if err != nil {
+			k.logger.Debug("Shared known attack filter unavailable", zap.Error(err))
+		}
+		if loaded {
+			return nil
+		}
+
+		// Another instance holds the rebuild lock; pick up its result next time
+		acquired, err := k.redis.SetNX(ctx, k.options.RedisKey+":lock", "1", k.options.RefreshInterval).Result()
+		if err == nil && !acquired && k.filter.Load() != nil {
+			return nil
+		}
+	}
+
+	filter, err := k.rebuild(ctx)
+	if err != nil {
+		return err
+	}
+	k.filter.Store(filter)
+
+	k.logger.Info("Known attack filter rebuilt",
+		zap.Uint64("entries", filter.Count()),
+		zap.Int("size_bytes", filter.SizeBytes()))
+
+	if k.redis != nil {
+		if err := k.publish(ctx, filter); err != nil {
+			k.logger.Warn("Failed to share known attack filter", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// rebuild pages through every malicious prompt in the store and builds a
+// filter sized for them
+func (k *KnownAttackFilter) rebuild(ctx context.Context) (*bloom.Filter, error) {
+	malicious := 1
+	options := &vector.PageOptions{LabelFilter: &malicious}
+
+	var texts [][]byte
+	err := k.store.IterateVectors(ctx, options, func(v *vector.SecurityVector) error {
+		texts = append(texts, []byte(textnorm.Canonical(v.Text, k.options.Normalization)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bloom.New(len(texts), k.options.FalsePositiveRate)
+	for _, text := range texts {
+		filter.Add(text)
+	}
+	return filter, nil
+}
+
+// loadShared installs the filter published in Redis if it is newer than one
+// refresh interval. It reports whether a filter was installed.
+func (k *KnownAttackFilter) loadShared(ctx context.Context) (bool, error) {
+	values, err := k.redis.HMGet(ctx, k.options.RedisKey, "built_at", "filter").Result()
+	if err != nil {
+		return false, err
+	}
+	builtAtRaw, ok1 := values[0].(string)
+	encoded, ok2 := values[1].(string)
+	if !ok1 || !ok2 {
+		return false, nil
+	}
+
+	builtAt, err := strconv.ParseInt(builtAtRaw, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	if time.Since(time.Unix(builtAt, 0)) > k.options.RefreshInterval {
+		return false, nil
+	}
+
+	filter := &bloom.Filter{}
+	if err := filter.UnmarshalBinary([]byte(encoded)); err != nil {
+		return false, fmt.Errorf("failed to decode shared known attack filter: %w", err)
+	}
+	k.filter.Store(filter)
+	return true, nil
+}
+
+// publish shares a freshly built filter with other instances
+func (k *KnownAttackFilter) publish(ctx context.Context, filter *bloom.Filter) error {
+	encoded, err := filter.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return k.redis.HSet(ctx, k.options.RedisKey,
+		"built_at", strconv.FormatInt(time.Now().Unix(), 10),
+		"filter", encoded,
+	).Err()
+}
+
+// NewKnownAttackResult builds the result for an exact replay of a known attack
+func NewKnownAttackResult(start time.Time) *SecurityResult {
+	return &SecurityResult{
+		IsMalicious:    true,
+		Confidence:     1.0,
+		AttackType:     AttackTypeKnownAttack,
+		Scores:         map[string]float32{AttackTypeKnownAttack: 1.0},
+		ProcessingTime: time.Since(start),
+	}
+}
@@ -0,0 +1,303 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AttackPattern is one keyword rule of the pattern analyzer
+type AttackPattern struct {
+	ID         int     `json:"id"`
+	Keyword    string  `json:"keyword"` // matched case-insensitively as a substring
+	AttackType string  `json:"attack_type"`
+	Confidence float32 `json:"confidence"`
+	Enabled    bool    `json:"enabled"`
+	Custom     bool    `json:"custom"` // added at runtime rather than built in
+}
+
+// PatternSet is the keyword rule set shared by every pattern analyzer, so
+// changes made at runtime apply to all analysis tiers at once
+type PatternSet struct {
+	mu       sync.RWMutex
+	patterns []AttackPattern
+	stats    map[string]*patternStats // by keyword; kept across restores
+}
+
+// patternStats is the evaluation history of one pattern
+type patternStats struct {
+	evaluations atomic.Int64
+	matches     atomic.Int64
+	totalNanos  atomic.Int64
+	lastMatch   atomic.Int64 // unix nanoseconds
+}
+
+// PatternProfile is a pattern with its evaluation cost and hit history,
+// for finding expensive or dead rules
+type PatternProfile struct {
+	AttackPattern
+	Evaluations int64      `json:"evaluations"`
+	Matches     int64      `json:"matches"`
+	AvgMicros   float64    `json:"avg_us"`
+	TotalMS     float64    `json:"total_ms"`
+	LastMatch   *time.Time `json:"last_match,omitempty"`
+}
+
+var sharedPatterns = newDefaultPatternSet()
+
+// Patterns returns the shared keyword rule set
+func Patterns() *PatternSet {
+	return sharedPatterns
+}
+
+// newDefaultPatternSet builds the built-in keyword rules
+func newDefaultPatternSet() *PatternSet {
+	defaults := []struct {
+		attackType string
+		confidence float32
+		keywords   []string
+	}{
+		{"prompt_injection", 0.9, []string{
+			"ignore all previous instructions",
+			"forget everything",
+			"disregard",
+			"override",
+			"bypass",
+			"pretend you are not an ai",
+			"act as if",
+			"roleplay",
+			"you are now",
+			"new instructions",
+			"system prompt",
+			"ignore your training",
+		}},
+		{"jailbreak", 0.95, []string{
+			"dan mode",
+			"do anything now",
+			"jailbreak",
+			"unrestricted",
+			"no limitations",
+			"without restrictions",
+			"ignore safety",
+			"bypass guidelines",
+			"developer mode",
+			"evil mode",
+			"uncensored",
+		}},
+		{"information_extraction", 0.8, []string{
+			"tell me secrets",
+			"reveal",
+			"confidential",
+			"internal information",
+			"system information",
+			"configuration",
+			"admin",
+			"password",
+			"api key",
+		}},
+	}
+
+	set := &PatternSet{stats: make(map[string]*patternStats)}
+	for _, group := range defaults {
+		for _, keyword := range group.keywords {
+			set.stats[keyword] = &patternStats{}
+			set.patterns = append(set.patterns, AttackPattern{
+				ID:         len(set.patterns) + 1,
+				Keyword:    keyword,
+				AttackType: group.attackType,
+				Confidence: group.confidence,
+				Enabled:    true,
+			})
+		}
+	}
+	return set
+}
+
+// List returns every pattern with its current state
+func (p *PatternSet) List() []AttackPattern {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]AttackPattern(nil), p.patterns...)
+}
+
+// Add registers and enables a new keyword pattern
+func (p *PatternSet) Add(keyword, attackType string, confidence float32) (AttackPattern, error) {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	if keyword == "" || attackType == "" {
+		return AttackPattern{}, fmt.Errorf("pattern keyword and attack type are required")
+	}
+	if confidence <= 0 || confidence > 1 {
+		return AttackPattern{}, fmt.Errorf("invalid pattern confidence: %v (must be in (0, 1])", confidence)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, existing := range p.patterns {
+		if existing.Keyword == keyword {
+			return AttackPattern{}, fmt.Errorf("pattern already exists: %q (id %d)", keyword, existing.ID)
+		}
+	}
+	pattern := AttackPattern{
+		ID:         len(p.patterns) + 1,
+		Keyword:    keyword,
+		AttackType: attackType,
+		Confidence: confidence,
+		Enabled:    true,
+		Custom:     true,
+	}
+	p.patterns = append(p.patterns, pattern)
+	p.stats[keyword] = &patternStats{}
+	return pattern, nil
+}
+
+// SetEnabled enables or disables the pattern with id
+func (p *PatternSet) SetEnabled(id int, enabled bool) (AttackPattern, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.patterns {
+		if p.patterns[i].ID == id {
+			p.patterns[i].Enabled = enabled
+			return p.patterns[i], nil
+		}
+	}
+	return AttackPattern{}, fmt.Errorf("unknown pattern: %d", id)
+}
+
+// Restore replaces the custom patterns and every pattern's enabled flag with
+// those in patterns, validating them all before anything changes. Built-in
+// patterns missing from patterns keep their current state.
+func (p *PatternSet) Restore(patterns []AttackPattern) error {
+	enabled := make(map[string]bool, len(patterns))
+	var custom []AttackPattern
+	for _, pattern := range patterns {
+		if pattern.Custom {
+			keyword := strings.ToLower(strings.TrimSpace(pattern.Keyword))
+			if keyword == "" || pattern.AttackType == "" {
+				return fmt.Errorf("pattern keyword and attack type are required")
+			}
+			if pattern.Confidence <= 0 || pattern.Confidence > 1 {
+				return fmt.Errorf("invalid pattern confidence: %v (must be in (0, 1])", pattern.Confidence)
+			}
+			pattern.Keyword = keyword
+			custom = append(custom, pattern)
+		}
+		enabled[pattern.Keyword] = pattern.Enabled
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	restored := make([]AttackPattern, 0, len(p.patterns)+len(custom))
+	seen := make(map[string]bool, len(p.patterns)+len(custom))
+	for _, pattern := range p.patterns {
+		if pattern.Custom {
+			continue
+		}
+		if flag, ok := enabled[pattern.Keyword]; ok {
+			pattern.Enabled = flag
+		}
+		restored = append(restored, pattern)
+		seen[pattern.Keyword] = true
+	}
+	for _, pattern := range custom {
+		if seen[pattern.Keyword] {
+			return fmt.Errorf("pattern already exists: %q", pattern.Keyword)
+		}
+		seen[pattern.Keyword] = true
+		pattern.ID = len(restored) + 1
+		restored = append(restored, pattern)
+		if p.stats[pattern.Keyword] == nil {
+			p.stats[pattern.Keyword] = &patternStats{}
+		}
+	}
+	p.patterns = restored
+	return nil
+}
+
+// Scores returns the highest confidence of each attack type with an enabled
+// pattern in prompt, or nil when nothing matches
+func (p *PatternSet) Scores(prompt string) map[string]float32 {
+	lowerPrompt := strings.ToLower(prompt)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var scores map[string]float32
+	for _, pattern := range p.patterns {
+		if pattern.Enabled && pattern.Confidence > scores[pattern.AttackType] && p.contains(lowerPrompt, pattern.Keyword) {
+			if scores == nil {
+				scores = make(map[string]float32)
+			}
+			scores[pattern.AttackType] = pattern.Confidence
+		}
+	}
+	return scores
+}
+
+// Match returns the highest-confidence enabled pattern found in prompt
+func (p *PatternSet) Match(prompt string) (AttackPattern, bool) {
+	lowerPrompt := strings.ToLower(prompt)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var best AttackPattern
+	found := false
+	for _, pattern := range p.patterns {
+		if pattern.Enabled && pattern.Confidence > best.Confidence && p.contains(lowerPrompt, pattern.Keyword) {
+			best = pattern
+			found = true
+		}
+	}
+	return best, found
+}
+
+// contains evaluates one pattern against the lowercased prompt, recording
+// its cost. Callers hold p.mu.
+func (p *PatternSet) contains(lowerPrompt, keyword string) bool {
+	start := time.Now()
+	found := strings.Contains(lowerPrompt, keyword)
+	elapsed := time.Since(start)
+
+	if stats := p.stats[keyword]; stats != nil {
+		stats.evaluations.Add(1)
+		stats.totalNanos.Add(int64(elapsed))
+		if found {
+			stats.matches.Add(1)
+			stats.lastMatch.Store(start.UnixNano())
+		}
+	}
+	return found
+}
+
+// Profile returns every pattern with its evaluation statistics since the
+// process started. Patterns skipped because a stronger one of the same
+// attack type already matched are not evaluated.
+func (p *PatternSet) Profile() []PatternProfile {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	profiles := make([]PatternProfile, 0, len(p.patterns))
+	for _, pattern := range p.patterns {
+		profile := PatternProfile{AttackPattern: pattern}
+		if stats := p.stats[pattern.Keyword]; stats != nil {
+			profile.Evaluations = stats.evaluations.Load()
+			profile.Matches = stats.matches.Load()
+			total := stats.totalNanos.Load()
+			profile.TotalMS = float64(total) / 1e6
+			if profile.Evaluations > 0 {
+				profile.AvgMicros = float64(total) / float64(profile.Evaluations) / 1e3
+			}
+			if last := stats.lastMatch.Load(); last > 0 {
+				t := time.Unix(0, last)
+				profile.LastMatch = &t
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
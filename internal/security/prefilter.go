@@ -0,0 +1,81 @@
+package security
+
+import (
+	"strings"
+	"time"
+)
+
+// Skip reasons recorded when a prompt bypasses embedding analysis
+const (
+	SkipReasonTrivialPrompt = "trivial_prompt"
+)
+
+// fastPathKeywords are attack-adjacent terms; any hit sends a prompt to full analysis
+var fastPathKeywords = []string{
+	"ignore", "disregard", "forget", "override", "bypass", "pretend",
+	"roleplay", "role play", "act as", "you are now", "instruction",
+	"system", "prompt", "jailbreak", "dan", "developer mode", "mode",
+	"unrestricted", "uncensored", "unfiltered", "restriction", "limitation",
+	"safety", "guideline", "rule", "policy", "reveal", "secret",
+	"confidential", "internal", "password", "admin", "api key", "token",
+	"hypothetical", "simulate", "persona", "sudo", "root", "exploit",
+}
+
+// fastPathSpecialTokens are chat-template and control markers used to smuggle instructions
+var fastPathSpecialTokens = []string{
+	"<|", "|>", "[inst]", "[/inst]", "<<sys>>", "<</sys>>", "<s>", "</s>",
+	"###", "```",
+}
+
+// FastPathCheck reports whether a prompt is trivially safe and can skip
+// embedding generation. It is deliberately conservative: short, plain-text
+// prompts with no attack keywords and no special tokens only.
+func FastPathCheck(prompt string, maxLength int) (string, bool) {
+	if maxLength <= 0 || len(prompt) == 0 || len(prompt) > maxLength {
+		return "", false
+	}
+
+	for _, r := range prompt {
+		if !isFastPathRune(r) {
+			return "", false
+		}
+	}
+
+	lowerPrompt := strings.ToLower(prompt)
+	for _, token := range fastPathSpecialTokens {
+		if strings.Contains(lowerPrompt, token) {
+			return "", false
+		}
+	}
+	for _, keyword := range fastPathKeywords {
+		if strings.Contains(lowerPrompt, keyword) {
+			return "", false
+		}
+	}
+
+	return SkipReasonTrivialPrompt, true
+}
+
+// NewSkippedResult builds the result recorded for a prompt that bypassed analysis
+func NewSkippedResult(reason string, start time.Time) *SecurityResult {
+	return &SecurityResult{
+		IsMalicious:    false,
+		Confidence:     0.0,
+		AttackType:     "safe",
+		SkipReason:     reason,
+		ProcessingTime: time.Since(start),
+	}
+}
+
+// isFastPathRune allows letters, digits, whitespace and common sentence punctuation
+func isFastPathRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == ' ' || r == '\n' || r == '\t':
+		return true
+	case strings.ContainsRune(".,?!'\"-:;()", r):
+		return true
+	}
+	return false
+}
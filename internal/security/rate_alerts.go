@@ -0,0 +1,218 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate baselining methods
+const (
+	BaselineEWMA        = "ewma"
+	BaselineHoltWinters = "holt_winters"
+)
+
+// RateAlertOptions tunes detection-rate baselining
+type RateAlertOptions struct {
+	Method          string        // ewma or holt_winters
+	Interval        time.Duration // bucket width; baselines learn one value per bucket
+	Window          time.Duration // observed counts are summed over this trailing window
+	Factor          float64       // window count above this multiple of the expected count is a spike
+	MinCount        int           // window count must also reach this to alert
+	MinObservations int           // completed buckets before a baseline is trusted
+	Cooldown        time.Duration // a series alerts at most once per cooldown
+	Alpha           float64       // level smoothing
+	Beta            float64       // holt_winters trend smoothing
+	Gamma           float64       // holt_winters seasonal smoothing
+	SeasonLength    time.Duration // holt_winters season, e.g. 24h for a daily cycle
+}
+
+// RateAlert describes a detection series running well above its baseline
+type RateAlert struct {
+	Series   string        `json:"series"`
+	Observed int           `json:"observed"` // events in the window
+	Expected float64       `json:"expected"` // baseline forecast for the window
+	Ratio    float64       `json:"ratio"`    // observed / expected; 0 when nothing was expected
+	Window   time.Duration `json:"window"`
+	Method   string        `json:"method"`
+}
+
+// rateSeries is the learned baseline and recent counts of one series
+type rateSeries struct {
+	bucket       int64 // current bucket (unix time / interval)
+	counts       []int // ring of per-bucket counts covering the window
+	observations int
+	lastAlert    time.Time
+
+	level    float64
+	trend    float64
+	seasonal []float64 // holt_winters only, one slot per bucket of the season
+}
+
+// RateAlerter baselines per-series event counts (detections per attack
+// type, blocks) and flags windows that run far above the forecast
+type RateAlerter struct {
+	options       RateAlertOptions
+	windowBuckets int
+	seasonBuckets int
+
+	mu     sync.Mutex
+	series map[string]*rateSeries
+}
+
+// NewRateAlerter creates an alerter with the given options
+func NewRateAlerter(options RateAlertOptions) *RateAlerter {
+	if options.Method == "" {
+		options.Method = BaselineEWMA
+	}
+	if options.Interval <= 0 {
+		options.Interval = time.Minute
+	}
+	if options.Window < options.Interval {
+		options.Window = 10 * options.Interval
+	}
+	if options.Factor <= 1 {
+		options.Factor = 5
+	}
+	if options.Cooldown <= 0 {
+		options.Cooldown = options.Window
+	}
+	if options.Alpha <= 0 || options.Alpha > 1 {
+		options.Alpha = 0.1
+	}
+	if options.Method == BaselineHoltWinters && options.SeasonLength < options.Interval {
+		options.SeasonLength = 24 * time.Hour
+	}
+
+	a := &RateAlerter{
+		options:       options,
+		windowBuckets: int(options.Window / options.Interval),
+		seasonBuckets: 1,
+		series:        make(map[string]*rateSeries),
+	}
+	if options.Method == BaselineHoltWinters {
+		a.seasonBuckets = int(options.SeasonLength / options.Interval)
+	}
+	return a
+}
+
+// Observe records one event on series and reports whether the series' window
+// count now exceeds its baseline. Counts are checked before they are learned
+// so a spike does not immediately raise its own baseline.
+func (a *RateAlerter) Observe(series string, now time.Time) (RateAlert, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.series[series]
+	if !ok {
+		s = &rateSeries{
+			bucket: now.UnixNano() / int64(a.options.Interval),
+			counts: make([]int, a.windowBuckets),
+		}
+		if a.options.Method == BaselineHoltWinters {
+			s.seasonal = make([]float64, a.seasonBuckets)
+		}
+		a.series[series] = s
+	}
+	a.rollLocked(s, now.UnixNano()/int64(a.options.Interval))
+	s.counts[s.bucket%int64(a.windowBuckets)]++
+
+	if s.observations < a.options.MinObservations {
+		return RateAlert{}, false
+	}
+	if !s.lastAlert.IsZero() && now.Sub(s.lastAlert) < a.options.Cooldown {
+		return RateAlert{}, false
+	}
+
+	observed := 0
+	for _, c := range s.counts {
+		observed += c
+	}
+	if observed < a.options.MinCount {
+		return RateAlert{}, false
+	}
+	expected := a.expectedLocked(s)
+	if float64(observed) <= a.options.Factor*expected {
+		return RateAlert{}, false
+	}
+
+	s.lastAlert = now
+	alert := RateAlert{
+		Series:   series,
+		Observed: observed,
+		Expected: expected,
+		Window:   a.options.Window,
+		Method:   a.options.Method,
+	}
+	if expected > 0 {
+		alert.Ratio = float64(observed) / expected
+	}
+	return alert, true
+}
+
+// rollLocked advances s to bucket, learning each completed bucket's count.
+// Skipped buckets count as zero; gaps longer than a season (or a day of
+// buckets for ewma) only replay their tail.
+func (a *RateAlerter) rollLocked(s *rateSeries, bucket int64) {
+	if bucket <= s.bucket {
+		return
+	}
+	maxGap := int64(a.seasonBuckets)
+	if a.options.Method == BaselineEWMA {
+		maxGap = int64(24 * time.Hour / a.options.Interval)
+	}
+	if maxGap < int64(a.windowBuckets) {
+		maxGap = int64(a.windowBuckets)
+	}
+
+	for s.bucket < bucket {
+		slot := s.bucket % int64(a.windowBuckets)
+		a.learnLocked(s, s.bucket, float64(s.counts[slot]))
+		if bucket-s.bucket > maxGap {
+			s.bucket = bucket - maxGap
+		} else {
+			s.bucket++
+		}
+		s.counts[s.bucket%int64(a.windowBuckets)] = 0
+	}
+}
+
+// learnLocked folds one completed bucket into the baseline
+func (a *RateAlerter) learnLocked(s *rateSeries, bucket int64, x float64) {
+	alpha := a.options.Alpha
+	if s.observations == 0 {
+		s.level = x
+		s.observations++
+		return
+	}
+	s.observations++
+
+	if a.options.Method != BaselineHoltWinters {
+		s.level = alpha*x + (1-alpha)*s.level
+		return
+	}
+
+	// Additive Holt-Winters
+	i := bucket % int64(a.seasonBuckets)
+	prevLevel := s.level
+	s.level = alpha*(x-s.seasonal[i]) + (1-alpha)*(s.level+s.trend)
+	s.trend = a.options.Beta*(s.level-prevLevel) + (1-a.options.Beta)*s.trend
+	s.seasonal[i] = a.options.Gamma*(x-s.level) + (1-a.options.Gamma)*s.seasonal[i]
+}
+
+// expectedLocked forecasts the total count for the window ending at the
+// current bucket
+func (a *RateAlerter) expectedLocked(s *rateSeries) float64 {
+	if a.options.Method != BaselineHoltWinters {
+		return s.level * float64(a.windowBuckets)
+	}
+
+	expected := 0.0
+	first := s.bucket - int64(a.windowBuckets) + 1
+	for b := first; b <= s.bucket; b++ {
+		forecast := s.level + s.trend + s.seasonal[b%int64(a.seasonBuckets)]
+		if forecast > 0 {
+			expected += forecast
+		}
+	}
+	return expected
+}
@@ -2,11 +2,11 @@ package security
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"github.com/raaihank/llm-sentinel/internal/config"
 	"github.com/raaihank/llm-sentinel/internal/embeddings"
+	"github.com/raaihank/llm-sentinel/internal/vector"
 	"go.uber.org/zap"
 )
 
@@ -15,6 +15,8 @@ import (
 type SimpleVectorSecurityEngine struct {
 	embeddingService embeddings.EmbeddingService
 	config           *config.VectorSecurityConfig
+	patterns         *PatternSet
+	vectorStore      vector.Backend // nil unless hybrid search has a corpus to query
 	logger           *zap.Logger
 }
 
@@ -27,96 +29,45 @@ func NewSimpleVectorSecurityEngine(
 	return &SimpleVectorSecurityEngine{
 		embeddingService: embeddingService,
 		config:           config,
+		patterns:         Patterns(),
 		logger:           logger,
 	}
 }
 
+// SetVectorStore attaches the attack corpus. With hybrid_search enabled every
+// prompt is also looked up by fused vector and lexical rank.
+func (sve *SimpleVectorSecurityEngine) SetVectorStore(store vector.Backend) {
+	sve.vectorStore = store
+}
+
 // AnalyzePrompt analyzes a prompt for security threats using simple pattern matching
 func (sve *SimpleVectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt string) (*SecurityResult, error) {
 	start := time.Now()
 
-	// Convert to lowercase for analysis
-	lowerPrompt := strings.ToLower(prompt)
-
-	// Define attack patterns and their confidence scores
-	attackPatterns := map[string]struct {
-		keywords   []string
-		attackType string
-		confidence float32
-	}{
-		"prompt_injection": {
-			keywords: []string{
-				"ignore all previous instructions",
-				"forget everything",
-				"disregard",
-				"override",
-				"bypass",
-				"pretend you are not an ai",
-				"act as if",
-				"roleplay",
-				"you are now",
-				"new instructions",
-				"system prompt",
-				"ignore your training",
-			},
-			attackType: "prompt_injection",
-			confidence: 0.9,
-		},
-		"jailbreak": {
-			keywords: []string{
-				"dan mode",
-				"do anything now",
-				"jailbreak",
-				"unrestricted",
-				"no limitations",
-				"without restrictions",
-				"ignore safety",
-				"bypass guidelines",
-				"developer mode",
-				"evil mode",
-				"uncensored",
-			},
-			attackType: "jailbreak",
-			confidence: 0.95,
-		},
-		"information_extraction": {
-			keywords: []string{
-				"tell me secrets",
-				"reveal",
-				"confidential",
-				"internal information",
-				"system information",
-				"configuration",
-				"admin",
-				"password",
-				"api key",
-			},
-			attackType: "information_extraction",
-			confidence: 0.8,
-		},
-	}
-
-	// Check for attack patterns
-	var bestMatch struct {
-		attackType string
-		confidence float32
-		matched    string
-	}
-
-	for _, pattern := range attackPatterns {
-		for _, keyword := range pattern.keywords {
-			if strings.Contains(lowerPrompt, keyword) {
-				if pattern.confidence > bestMatch.confidence {
-					bestMatch.attackType = pattern.attackType
-					bestMatch.confidence = pattern.confidence
-					bestMatch.matched = keyword
-				}
-			}
+	// Check the shared keyword patterns
+	match, found := sve.patterns.Match(prompt)
+
+	// A corpus match from hybrid search wins over a weaker pattern
+	if corpus := sve.searchCorpus(ctx, prompt); corpus != nil && (!found || resultConfidence(corpus) > match.Confidence) {
+		confidence := resultConfidence(corpus)
+		result := &SecurityResult{
+			IsMalicious:     confidence >= sve.GetBlockThreshold(),
+			Confidence:      confidence,
+			AttackType:      corpus.Vector.LabelText,
+			Scores:          mergeScores(sve.patterns.Scores(prompt), labelScores(corpus.Vector.Label, corpus.Vector.LabelText, confidence)),
+			SimilarityScore: corpus.Similarity,
+			MatchedText:     corpus.Vector.Text,
+			ProcessingTime:  time.Since(start),
 		}
+		sve.logger.Debug("Hybrid corpus search matched",
+			zap.String("attack_type", result.AttackType),
+			zap.Float32("similarity", corpus.Similarity),
+			zap.Float32("lexical_score", corpus.LexicalScore))
+		return result, nil
 	}
 
 	// If no patterns matched, it's likely safe
-	if bestMatch.confidence == 0 {
+	if !found {
 		return &SecurityResult{
 			IsMalicious:    false,
 			Confidence:     0.0,
@@ -138,17 +89,19 @@ func (sve *SimpleVectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt
 	}
 
 	result := &SecurityResult{
-		IsMalicious:     bestMatch.confidence >= sve.GetBlockThreshold(), // Use configured threshold
-		Confidence:      bestMatch.confidence,
-		AttackType:      bestMatch.attackType,
-		SimilarityScore: bestMatch.confidence, // Use confidence as similarity score
-		MatchedText:     bestMatch.matched,
+		IsMalicious:     match.Confidence >= sve.GetBlockThreshold(), // Use configured threshold
+		Confidence:      match.Confidence,
+		AttackType:      match.AttackType,
+		Scores:          sve.patterns.Scores(prompt),
+		SimilarityScore: match.Confidence, // Use confidence as similarity score
+		MatchedText:     match.Keyword,
 		ProcessingTime:  time.Since(start),
 	}
 
 	sve.logger.Debug("Simple vector security analysis completed",
 		zap.Bool("is_malicious", result.IsMalicious),
 		zap.String("attack_type", result.AttackType),
+		zap.Any("scores", result.Scores),
 		zap.Float32("confidence", result.Confidence),
 		zap.String("matched_pattern", result.MatchedText),
 		zap.Duration("processing_time", result.ProcessingTime))
@@ -156,6 +109,46 @@ func (sve *SimpleVectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt
 	return result, nil
 }
 
+// searchCorpus returns the most confident malicious corpus entry found by
+// hybrid search, or nil when hybrid search is off, finds nothing or fails.
+// A failed search falls back to the pattern verdict.
+func (sve *SimpleVectorSecurityEngine) searchCorpus(ctx context.Context, prompt string) *vector.SimilarityResult {
+	if sve.vectorStore == nil || sve.embeddingService == nil || sve.config == nil || !sve.config.HybridSearch {
+		return nil
+	}
+
+	embeddingResult, err := sve.embeddingService.GenerateEmbedding(ctx, prompt)
+	if err != nil {
+		sve.logger.Warn("Failed to embed prompt for hybrid search", zap.Error(err))
+		return nil
+	}
+	results, err := sve.vectorStore.FindHybrid(ctx, embeddingResult.Embedding, prompt, &vector.SearchOptions{
+		Limit:           5,
+		MinSimilarity:   sve.config.BlockThreshold,
+		MinLexicalScore: sve.config.BlockThreshold,
+	})
+	if err != nil {
+		sve.logger.Warn("Hybrid corpus search failed; using pattern verdict", zap.Error(err))
+		return nil
+	}
+
+	// Hybrid results are ordered by fused rank, so compare each result's own
+	// confidence. Vectors from another embedding model are not comparable.
+	var best *vector.SimilarityResult
+	for _, result := range results {
+		if result.Vector == nil || result.Vector.Label != 1 {
+			continue
+		}
+		if result.Vector.EmbeddingType != "" && result.Vector.EmbeddingType != sve.config.Embedding.ServiceType {
+			continue
+		}
+		if best == nil || resultConfidence(result) > resultConfidence(best) {
+			best = result
+		}
+	}
+	return best
+}
+
 // IsEnabled returns whether vector security is enabled
 func (sve *SimpleVectorSecurityEngine) IsEnabled() bool {
 	return sve.config != nil && sve.config.Enabled
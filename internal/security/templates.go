@@ -0,0 +1,249 @@
+package security
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/lru"
+)
+
+// TemplateOptions tunes per-client prompt template tracking
+type TemplateOptions struct {
+	MinObservations int           // requests seen before a client's templates are trusted
+	MinKnownShare   float64       // recent share of requests on known templates before a new one is unusual
+	KnownAfter      int           // unblocked requests using a template before it is known
+	MaxTemplates    int           // templates remembered per client; the least recently used are forgotten
+	MaxClients      int           // clients remembered; the least recently seen are forgotten
+	IdleExpiry      time.Duration // clients unseen this long are forgotten
+}
+
+// TemplateObservation is what was known of a request's template when it
+// arrived
+type TemplateObservation struct {
+	Fingerprint string
+	Known       bool    // used often enough before, without being blocked
+	Unusual     bool    // unknown template from a client that sticks to known ones
+	KnownShare  float64 // client's recent share of requests on known templates, before this one
+}
+
+// TemplateStats describes one template a client uses
+type TemplateStats struct {
+	Fingerprint string    `json:"fingerprint"`
+	Preview     string    `json:"preview"`
+	Requests    int       `json:"requests"`
+	Blocked     int       `json:"blocked"`
+	Known       bool      `json:"known"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// ClientTemplates summarizes the templates one client uses
+type ClientTemplates struct {
+	Client          string          `json:"client"`
+	Requests        int             `json:"requests"`
+	UnusualRequests int             `json:"unusual_requests"` // new templates analyzed with heightened scrutiny
+	KnownShare      float64         `json:"known_share"`      // recent share of requests on known templates
+	Learning        bool            `json:"learning"`         // too few requests for a trusted baseline
+	TemplateCount   int             `json:"template_count"`
+	KnownTemplates  int             `json:"known_templates"`
+	LastSeen        time.Time       `json:"last_seen"`
+	Templates       []TemplateStats `json:"templates"` // most used first
+}
+
+// templateProfile is the template usage of one client
+type templateProfile struct {
+	templates  map[string]*TemplateStats
+	requests   int
+	unusual    int
+	knownShare float64 // EWMA of requests arriving on a known template
+	lastSeen   time.Time
+}
+
+// knownShareAlpha is the EWMA weight of each request in a client's known
+// template share
+const knownShareAlpha = 0.05
+
+// unusualShareAlpha weights unusual requests, which are already scrutinized,
+// far less: flooding new templates takes many requests to switch scrutiny
+// off, while a lasting change in a client's usage is still relearned
+const unusualShareAlpha = knownShareAlpha / 10
+
+// defaultMaxTemplateClients bounds the clients tracked when no limit is set.
+// Without client authentication clients are addresses, which a flood of
+// spoofed or rotating ones could otherwise grow without limit.
+const defaultMaxTemplateClients = 10000
+
+// TemplateTracker learns which prompt templates each client uses. Once a
+// client's baseline is trusted and most of its requests reuse known
+// templates, a request on a template it has not used before is unusual.
+// Clients whose prompts rarely repeat, such as open chats, never reach the
+// known share, so their new prompts are not unusual.
+type TemplateTracker struct {
+	options TemplateOptions
+
+	mu       sync.Mutex
+	profiles *lru.Cache[string, *templateProfile] // expire after IdleExpiry unseen
+}
+
+// NewTemplateTracker creates a tracker with the given options
+func NewTemplateTracker(options TemplateOptions) *TemplateTracker {
+	if options.KnownAfter < 1 {
+		options.KnownAfter = 1
+	}
+	if options.MaxTemplates < 1 {
+		options.MaxTemplates = 500
+	}
+	if options.MaxClients < 1 {
+		options.MaxClients = defaultMaxTemplateClients
+	}
+	if options.IdleExpiry <= 0 {
+		options.IdleExpiry = 7 * 24 * time.Hour
+	}
+	return &TemplateTracker{
+		options:  options,
+		profiles: lru.NewWithTTL[string, *templateProfile](options.MaxClients, options.IdleExpiry),
+	}
+}
+
+// Observe records a request from client on the template with fingerprint
+// and reports what was known of the template beforehand. preview is kept
+// for display when the template is new.
+func (t *TemplateTracker) Observe(client, fingerprint, preview string, now time.Time) TemplateObservation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.profiles.Get(client)
+	if !ok {
+		p = &templateProfile{templates: make(map[string]*TemplateStats)}
+	}
+	// Re-adding restarts the idle expiry; a new client may evict the least
+	// recently seen one
+	t.profiles.Add(client, p)
+	p.lastSeen = now
+
+	template := p.templates[fingerprint]
+	observation := TemplateObservation{Fingerprint: fingerprint, Known: t.knownLocked(template), KnownShare: p.knownShare}
+	observation.Unusual = !observation.Known && p.requests >= t.options.MinObservations && p.knownShare >= t.options.MinKnownShare
+
+	// Learn after checking so a new template does not count as known instantly
+	known, alpha := 0.0, knownShareAlpha
+	if observation.Known {
+		known = 1
+	}
+	if observation.Unusual {
+		alpha = unusualShareAlpha
+	}
+	p.knownShare = (1-alpha)*p.knownShare + alpha*known
+	p.requests++
+	if observation.Unusual {
+		p.unusual++
+	}
+	if template == nil {
+		if len(p.templates) >= t.options.MaxTemplates {
+			p.evictLocked()
+		}
+		template = &TemplateStats{Fingerprint: fingerprint, Preview: preview, FirstSeen: now}
+		p.templates[fingerprint] = template
+	}
+	template.Requests++
+	template.LastSeen = now
+	return observation
+}
+
+// RecordBlock notes that a request on the template was blocked. Blocked
+// requests do not count towards the template becoming known, so repeating
+// an attack does not teach it.
+func (t *TemplateTracker) RecordBlock(client, fingerprint string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p, ok := t.profiles.Get(client); ok {
+		if template, ok := p.templates[fingerprint]; ok {
+			template.Blocked++
+		}
+	}
+}
+
+// Stats summarizes every client's templates, busiest client first, with at
+// most limit templates each; a limit of 0 includes them all
+func (t *TemplateTracker) Stats(limit int) []ClientTemplates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]ClientTemplates, 0, t.profiles.Len())
+	t.profiles.Range(func(client string, p *templateProfile) bool {
+		stats = append(stats, t.summaryLocked(client, p, limit))
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Requests != stats[j].Requests {
+			return stats[i].Requests > stats[j].Requests
+		}
+		return stats[i].Client < stats[j].Client
+	})
+	return stats
+}
+
+// Client summarizes one client's templates, all of them included
+func (t *TemplateTracker) Client(client string) (ClientTemplates, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.profiles.Get(client)
+	if !ok {
+		return ClientTemplates{}, false
+	}
+	return t.summaryLocked(client, p, 0), true
+}
+
+func (t *TemplateTracker) summaryLocked(client string, p *templateProfile, limit int) ClientTemplates {
+	summary := ClientTemplates{
+		Client:          client,
+		Requests:        p.requests,
+		UnusualRequests: p.unusual,
+		KnownShare:      p.knownShare,
+		Learning:        p.requests < t.options.MinObservations,
+		TemplateCount:   len(p.templates),
+		LastSeen:        p.lastSeen,
+		Templates:       make([]TemplateStats, 0, len(p.templates)),
+	}
+	for _, template := range p.templates {
+		stats := *template
+		stats.Known = t.knownLocked(template)
+		if stats.Known {
+			summary.KnownTemplates++
+		}
+		summary.Templates = append(summary.Templates, stats)
+	}
+	sort.Slice(summary.Templates, func(i, j int) bool {
+		a, b := summary.Templates[i], summary.Templates[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+	if limit > 0 && len(summary.Templates) > limit {
+		summary.Templates = summary.Templates[:limit]
+	}
+	return summary
+}
+
+// knownLocked reports whether a template has been used often enough,
+// without being blocked, to be known
+func (t *TemplateTracker) knownLocked(template *TemplateStats) bool {
+	return template != nil && template.Requests-template.Blocked >= t.options.KnownAfter
+}
+
+// evictLocked forgets the client's least recently used template
+func (p *templateProfile) evictLocked() {
+	var oldest *TemplateStats
+	for _, template := range p.templates {
+		if oldest == nil || template.LastSeen.Before(oldest.LastSeen) {
+			oldest = template
+		}
+	}
+	if oldest != nil {
+		delete(p.templates, oldest.Fingerprint)
+	}
+}
@@ -0,0 +1,223 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Analysis tiers, from most to least thorough
+const (
+	TierML      = "ml"
+	TierPattern = "pattern"
+	TierKeyword = "keyword"
+)
+
+// Tier is one analysis quality level
+type Tier struct {
+	Name     string
+	Analyzer VectorSecurityAnalyzer
+}
+
+// TierOptions controls when analysis quality is downgraded and restored.
+// Load must stay past a threshold for SustainSamples consecutive samples
+// before the tier changes, one stage at a time.
+type TierOptions struct {
+	SampleInterval    time.Duration
+	SustainSamples    int
+	DegradeCPU        float64 // process CPU utilization, 0-1
+	RestoreCPU        float64
+	DegradeQueueDepth int // analyses in flight
+	RestoreQueueDepth int
+}
+
+// TierChange describes a switch between analysis tiers
+type TierChange struct {
+	From     string
+	To       string
+	Reason   string
+	CPU      float64
+	InFlight int64
+}
+
+// TieredAnalyzer serves analysis from the best tier the current load allows,
+// downgrading under sustained load and restoring when it drops
+type TieredAnalyzer struct {
+	tiers    []Tier
+	options  TierOptions
+	onChange func(TierChange)
+
+	level    atomic.Int32
+	inFlight atomic.Int64
+
+	cpu       *cpuSampler
+	overload  int
+	recovered int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTieredAnalyzer creates an analyzer over tiers ordered best first.
+// onChange is called from the sampling goroutine on every tier switch.
+func NewTieredAnalyzer(tiers []Tier, options TierOptions, onChange func(TierChange)) (*TieredAnalyzer, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("tiered analyzer needs at least one tier")
+	}
+	if options.SampleInterval <= 0 {
+		options.SampleInterval = 5 * time.Second
+	}
+	if options.SustainSamples <= 0 {
+		options.SustainSamples = 3
+	}
+	return &TieredAnalyzer{
+		tiers:    tiers,
+		options:  options,
+		onChange: onChange,
+		cpu:      newCPUSampler(),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// AnalyzePrompt analyzes with the current tier and records it on the result
+func (t *TieredAnalyzer) AnalyzePrompt(ctx context.Context, prompt string) (*SecurityResult, error) {
+	t.inFlight.Add(1)
+	defer t.inFlight.Add(-1)
+
+	tier := t.tiers[t.level.Load()]
+	result, err := tier.Analyzer.AnalyzePrompt(ctx, prompt)
+	if result != nil {
+		result.AnalysisTier = tier.Name
+	}
+	return result, err
+}
+
+// IsEnabled reports whether the primary tier is enabled
+func (t *TieredAnalyzer) IsEnabled() bool {
+	return t.tiers[0].Analyzer.IsEnabled()
+}
+
+// GetBlockThreshold returns the primary tier's block threshold
+func (t *TieredAnalyzer) GetBlockThreshold() float32 {
+	return t.tiers[0].Analyzer.GetBlockThreshold()
+}
+
+// CurrentTier returns the name of the tier serving analysis
+func (t *TieredAnalyzer) CurrentTier() string {
+	return t.tiers[t.level.Load()].Name
+}
+
+// Degraded reports whether analysis is served below the primary tier
+func (t *TieredAnalyzer) Degraded() bool {
+	return t.level.Load() > 0
+}
+
+// Start begins sampling load in the background
+func (t *TieredAnalyzer) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(t.options.SampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.sample()
+			}
+		}
+	}()
+}
+
+// Close stops load sampling
+func (t *TieredAnalyzer) Close() {
+	close(t.stop)
+	t.wg.Wait()
+}
+
+// sample reads the load signals and moves one tier if a threshold has been
+// crossed for long enough
+func (t *TieredAnalyzer) sample() {
+	cpu := t.cpu.utilization()
+	depth := t.inFlight.Load()
+	opts := t.options
+
+	cpuHigh := opts.DegradeCPU > 0 && cpu >= opts.DegradeCPU
+	queueHigh := opts.DegradeQueueDepth > 0 && depth >= int64(opts.DegradeQueueDepth)
+	cpuLow := opts.DegradeCPU <= 0 || cpu <= opts.RestoreCPU
+	queueLow := opts.DegradeQueueDepth <= 0 || depth <= int64(opts.RestoreQueueDepth)
+
+	switch {
+	case cpuHigh || queueHigh:
+		t.overload++
+		t.recovered = 0
+	case cpuLow && queueLow:
+		t.recovered++
+		t.overload = 0
+	default:
+		t.overload = 0
+		t.recovered = 0
+	}
+
+	level := int(t.level.Load())
+	var next int
+	var reason string
+	switch {
+	case t.overload >= opts.SustainSamples && level < len(t.tiers)-1:
+		next = level + 1
+		reason = "queue_depth"
+		if cpuHigh {
+			reason = "cpu"
+		}
+	case t.recovered >= opts.SustainSamples && level > 0:
+		next = level - 1
+		reason = "load_recovered"
+	default:
+		return
+	}
+
+	t.level.Store(int32(next))
+	t.overload = 0
+	t.recovered = 0
+
+	if t.onChange != nil {
+		t.onChange(TierChange{
+			From:     t.tiers[level].Name,
+			To:       t.tiers[next].Name,
+			Reason:   reason,
+			CPU:      cpu,
+			InFlight: depth,
+		})
+	}
+}
+
+// cpuSampler measures process CPU utilization between calls
+type cpuSampler struct {
+	lastCPU  time.Duration
+	lastWall time.Time
+}
+
+func newCPUSampler() *cpuSampler {
+	return &cpuSampler{lastCPU: processCPUTime(), lastWall: time.Now()}
+}
+
+// utilization returns the fraction of available CPU used since the last
+// call, or 0 where process CPU time is unavailable
+func (c *cpuSampler) utilization() float64 {
+	now := time.Now()
+	cpu := processCPUTime()
+	wall := now.Sub(c.lastWall)
+	used := cpu - c.lastCPU
+	c.lastCPU, c.lastWall = cpu, now
+
+	if wall <= 0 || cpu == 0 {
+		return 0
+	}
+	return used.Seconds() / (wall.Seconds() * float64(runtime.NumCPU()))
+}
@@ -14,26 +14,43 @@ import (
 
 // VectorSecurityEngine handles ML-based prompt security analysis
 type VectorSecurityEngine struct {
-	vectorStore      *vector.Store
+	vectorStore      vector.Backend
 	cache            *cache.VectorCache
 	embeddingService embeddings.EmbeddingService
 	config           *config.VectorSecurityConfig
+	patterns         *PatternSet
 	logger           *zap.Logger
 }
 
 // SecurityResult represents the result of vector security analysis
 type SecurityResult struct {
-	IsMalicious     bool          `json:"is_malicious"`
-	Confidence      float32       `json:"confidence"`
-	AttackType      string        `json:"attack_type"`
-	SimilarityScore float32       `json:"similarity_score"`
-	MatchedText     string        `json:"matched_text,omitempty"`
-	ProcessingTime  time.Duration `json:"processing_time"`
+	IsMalicious     bool               `json:"is_malicious"`
+	Confidence      float32            `json:"confidence"`
+	AttackType      string             `json:"attack_type"`
+	Scores          map[string]float32 `json:"scores,omitempty"` // per attack category, for prompts combining several
+	SimilarityScore float32            `json:"similarity_score"`
+	MatchedText     string             `json:"matched_text,omitempty"`
+	SkipReason      string             `json:"skip_reason,omitempty"`
+	AnalysisTier    string             `json:"analysis_tier,omitempty"`
+	ProcessingTime  time.Duration      `json:"processing_time"`
+	MessageIndex    *int               `json:"message_index,omitempty"` // message the verdict came from, for chat requests; -1 for a top-level system prompt
+	Messages        []MessageVerdict   `json:"messages,omitempty"`      // per-message verdicts behind an aggregated result
+}
+
+// MessageVerdict is the analysis of one message of a chat request
+type MessageVerdict struct {
+	Index       int     `json:"index"` // -1 for a top-level system prompt
+	Role        string  `json:"role"`
+	IsMalicious bool    `json:"is_malicious"`
+	AttackType  string  `json:"attack_type,omitempty"`
+	Confidence  float32 `json:"confidence"`
+	SkipReason  string  `json:"skip_reason,omitempty"`
+	Failed      bool    `json:"failed,omitempty"` // analysis errored and the message passed unanalyzed
 }
 
 // NewVectorSecurityEngine creates a new vector security engine
 func NewVectorSecurityEngine(
-	vectorStore *vector.Store,
+	vectorStore vector.Backend,
 	vectorCache *cache.VectorCache,
 	embeddingService embeddings.EmbeddingService,
 	config *config.VectorSecurityConfig,
@@ -44,6 +61,7 @@ func NewVectorSecurityEngine(
 		cache:            vectorCache,
 		embeddingService: embeddingService,
 		config:           config,
+		patterns:         Patterns(),
 		logger:           logger,
 	}
 }
@@ -89,12 +107,15 @@ func (vse *VectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt strin
 		if err == nil && cacheResult.CacheHit && cacheResult.Vector != nil {
 			vse.logger.Debug("Vector security cache hit",
 				zap.String("attack_type", cacheResult.Vector.LabelText),
-				zap.Float32("similarity", cacheResult.Vector.Similarity))
+				zap.Float32("similarity", cacheResult.Vector.Similarity),
+				zap.Bool("approximate", cacheResult.Approximate),
+				zap.Float32("query_similarity", cacheResult.QuerySimilarity))
 
 			return &SecurityResult{
 				IsMalicious:     cacheResult.Vector.Label == 1,
 				Confidence:      cacheResult.Vector.Similarity,
 				AttackType:      cacheResult.Vector.LabelText,
+				Scores:          mergeScores(vse.patterns.Scores(prompt), labelScores(cacheResult.Vector.Label, cacheResult.Vector.LabelText, cacheResult.Vector.Similarity)),
 				SimilarityScore: cacheResult.Vector.Similarity,
 				MatchedText:     cacheResult.Vector.Text,
 				ProcessingTime:  time.Since(start),
@@ -103,10 +124,17 @@ func (vse *VectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt strin
 	}
 
 	// Fallback to database search
-	similarVectors, err := vse.vectorStore.FindSimilar(analysisCtx, embeddingResult.Embedding, &vector.SearchOptions{
-		Limit:         5,
-		MinSimilarity: vse.config.BlockThreshold,
-	})
+	searchOptions := &vector.SearchOptions{
+		Limit:           5,
+		MinSimilarity:   vse.config.BlockThreshold,
+		MinLexicalScore: vse.config.BlockThreshold,
+	}
+	var similarVectors []*vector.SimilarityResult
+	if vse.config.HybridSearch {
+		similarVectors, err = vse.vectorStore.FindHybrid(analysisCtx, embeddingResult.Embedding, prompt, searchOptions)
+	} else {
+		similarVectors, err = vse.vectorStore.FindSimilar(analysisCtx, embeddingResult.Embedding, searchOptions)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("vector similarity search failed: %w", err)
 	}
@@ -121,8 +149,14 @@ func (vse *VectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt strin
 		}, nil
 	}
 
-	// Use the most similar vector
+	// Use the most similar vector. Hybrid results are ordered by fused rank,
+	// so compare each result's own confidence.
 	best := similarVectors[0]
+	for _, similar := range similarVectors[1:] {
+		if resultConfidence(similar) > resultConfidence(best) {
+			best = similar
+		}
+	}
 
 	// Enforce embedding type compatibility when available
 	if best.Vector != nil && best.Vector.EmbeddingType != "" {
@@ -138,24 +172,35 @@ func (vse *VectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt strin
 		}
 	}
 
+	confidence := resultConfidence(best)
+
+	// Every malicious neighbour contributes to its own category's score
+	scores := vse.patterns.Scores(prompt)
+	for _, similar := range similarVectors {
+		if similar.Vector != nil {
+			scores = mergeScores(scores, labelScores(similar.Vector.Label, similar.Vector.LabelText, similar.Similarity))
+		}
+	}
+
 	result := &SecurityResult{
 		IsMalicious:     best.Vector.Label == 1,
-		Confidence:      best.Similarity,
+		Confidence:      confidence,
 		AttackType:      best.Vector.LabelText,
+		Scores:          scores,
 		SimilarityScore: best.Similarity,
 		MatchedText:     best.Vector.Text,
 		ProcessingTime:  time.Since(start),
 	}
 
-	// Cache the result for future queries if it's malicious
-	if vse.cache != nil && result.IsMalicious {
+	// Cache matched verdicts; the cache picks a TTL by label and confidence
+	if vse.cache != nil {
 		cachedVector := &cache.CachedVector{
 			ID:         best.Vector.ID,
 			Text:       best.Vector.Text,
 			LabelText:  best.Vector.LabelText,
 			Label:      best.Vector.Label,
 			Embedding:  best.Vector.Embedding,
-			Similarity: best.Similarity,
+			Similarity: confidence,
 		}
 
 		if err := vse.cache.Store(analysisCtx, embeddingResult.Embedding, cachedVector); err != nil {
@@ -166,12 +211,37 @@ func (vse *VectorSecurityEngine) AnalyzePrompt(ctx context.Context, prompt strin
 	vse.logger.Debug("Vector security analysis completed",
 		zap.Bool("is_malicious", result.IsMalicious),
 		zap.String("attack_type", result.AttackType),
+		zap.Any("scores", result.Scores),
 		zap.Float32("confidence", result.Confidence),
 		zap.Duration("processing_time", result.ProcessingTime))
 
 	return result, nil
 }
 
+// labelScores scores a malicious corpus match under its label
+func labelScores(label int, labelText string, similarity float32) map[string]float32 {
+	if label != 1 || labelText == "" {
+		return nil
+	}
+	return map[string]float32{labelText: similarity}
+}
+
+// mergeScores folds from into into, keeping the higher score per category
+func mergeScores(into, from map[string]float32) map[string]float32 {
+	if len(from) == 0 {
+		return into
+	}
+	if into == nil {
+		into = make(map[string]float32, len(from))
+	}
+	for category, score := range from {
+		if score > into[category] {
+			into[category] = score
+		}
+	}
+	return into
+}
+
 // IsEnabled returns whether vector security is enabled
 func (vse *VectorSecurityEngine) IsEnabled() bool {
 	return vse.config != nil && vse.config.Enabled
@@ -184,3 +254,9 @@ func (vse *VectorSecurityEngine) GetBlockThreshold() float32 {
 	}
 	return vse.config.BlockThreshold
 }
+
+// resultConfidence is how strongly a search result matches the prompt. A
+// near-verbatim lexical match is as strong a signal as a close embedding.
+func resultConfidence(result *vector.SimilarityResult) float32 {
+	return max(result.Similarity, result.LexicalScore)
+}
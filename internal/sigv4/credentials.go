@@ -0,0 +1,140 @@
+package sigv4
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshWindow renews temporary credentials this long before they expire
+const refreshWindow = 5 * time.Minute
+
+// Credentials are an AWS access key pair, with a session token and expiry
+// for temporary credentials
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // zero for long-lived keys
+}
+
+// CredentialSource supplies credentials from, in order, the AWS_ACCESS_KEY_ID
+// / AWS_SECRET_ACCESS_KEY environment or an IRSA web identity token
+// (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN) exchanged with STS.
+// Temporary credentials are cached until shortly before they expire.
+type CredentialSource struct {
+	client *http.Client
+	region string
+	static Credentials // used instead of the environment when set
+
+	mu     sync.Mutex
+	cached Credentials
+}
+
+// NewCredentialSource creates a credential source. client carries STS calls
+// and region selects the regional STS endpoint.
+func NewCredentialSource(client *http.Client, region string) *CredentialSource {
+	return &CredentialSource{client: client, region: region}
+}
+
+// StaticCredentials returns a source that always supplies creds, such as
+// configured HMAC keys for an S3-compatible store
+func StaticCredentials(creds Credentials) *CredentialSource {
+	return &CredentialSource{static: creds}
+}
+
+// Get returns valid credentials
+func (c *CredentialSource) Get(ctx context.Context) (Credentials, error) {
+	if c.static.AccessKeyID != "" {
+		return c.static, nil
+	}
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	tokenFile, roleARN := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN")
+	if tokenFile == "" || roleARN == "" {
+		return Credentials{}, errors.New("no AWS credentials: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached.AccessKeyID != "" && time.Until(c.cached.Expires) > refreshWindow {
+		return c.cached, nil
+	}
+	creds, err := c.assumeRoleWithWebIdentity(ctx, tokenFile, roleARN)
+	if err != nil {
+		return Credentials{}, err
+	}
+	c.cached = creds
+	return creds, nil
+}
+
+// assumeRoleWithWebIdentity exchanges the projected service account token
+// for temporary role credentials. The call itself is unsigned.
+func (c *CredentialSource) assumeRoleWithWebIdentity(ctx context.Context, tokenFile, roleARN string) (Credentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("llm-sentinel-%d", time.Now().Unix())
+	}
+
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	endpoint := fmt.Sprintf("https://sts.%s.amazonaws.com/", c.region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("STS AssumeRoleWithWebIdentity failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Credentials{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("STS AssumeRoleWithWebIdentity returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse STS response: %w", err)
+	}
+	if result.Credentials.AccessKeyID == "" {
+		return Credentials{}, errors.New("STS response carried no credentials")
+	}
+	return Credentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		Expires:         result.Credentials.Expiration,
+	}, nil
+}
@@ -0,0 +1,160 @@
+package sigv4
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubSTS serves AssumeRoleWithWebIdentity, issuing credentials numbered by
+// call that expire after lifetime
+type stubSTS struct {
+	server   *httptest.Server
+	calls    atomic.Int32
+	lifetime time.Duration
+	status   int
+}
+
+func newStubSTS(t *testing.T, lifetime time.Duration) *stubSTS {
+	t.Helper()
+	sts := &stubSTS{lifetime: lifetime, status: http.StatusOK}
+	sts.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse STS form: %v", err)
+		}
+		if got := r.PostForm.Get("Action"); got != "AssumeRoleWithWebIdentity" {
+			t.Errorf("STS Action %q", got)
+		}
+		if got := r.PostForm.Get("WebIdentityToken"); got != "web-identity-token" {
+			t.Errorf("STS WebIdentityToken %q, want the token file's trimmed contents", got)
+		}
+		if got := r.PostForm.Get("RoleArn"); got != "arn:aws:iam::123456789012:role/sentinel" {
+			t.Errorf("STS RoleArn %q", got)
+		}
+
+		n := sts.calls.Add(1)
+		if sts.status != http.StatusOK {
+			http.Error(w, "<ErrorResponse>AccessDenied</ErrorResponse>", sts.status)
+			return
+		}
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse>
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIA%d</AccessKeyId>
+      <SecretAccessKey>secret-%d</SecretAccessKey>
+      <SessionToken>token-%d</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, n, n, n, time.Now().Add(sts.lifetime).UTC().Format(time.RFC3339))
+	}))
+	t.Cleanup(sts.server.Close)
+	return sts
+}
+
+// client sends every request to the stub, whatever regional STS endpoint
+// it was addressed to
+func (s *stubSTS) client() *http.Client {
+	target, _ := url.Parse(s.server.URL)
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// setWebIdentityEnv configures IRSA and clears any static keys
+func setWebIdentityEnv(t *testing.T) {
+	t.Helper()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("web-identity-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/sentinel")
+	t.Setenv("AWS_ROLE_SESSION_NAME", "test")
+}
+
+// TestWebIdentityCredentialsCached checks STS credentials are reused until
+// they near expiry
+func TestWebIdentityCredentialsCached(t *testing.T) {
+	setWebIdentityEnv(t)
+	sts := newStubSTS(t, time.Hour)
+	source := NewCredentialSource(sts.client(), "us-east-1")
+
+	first, err := source.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if first.AccessKeyID != "ASIA1" || first.SecretAccessKey != "secret-1" || first.SessionToken != "token-1" {
+		t.Fatalf("credentials %+v, want the first STS issue", first)
+	}
+	if time.Until(first.Expires) < 55*time.Minute {
+		t.Fatalf("expiry %v, want about an hour from now", first.Expires)
+	}
+
+	second, err := source.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if second.AccessKeyID != "ASIA1" || sts.calls.Load() != 1 {
+		t.Fatalf("second Get returned %s after %d STS calls, want the cached credentials", second.AccessKeyID, sts.calls.Load())
+	}
+}
+
+// TestWebIdentityCredentialsRefresh checks credentials inside the refresh
+// window are renewed, and an STS failure is reported rather than serving
+// stale credentials
+func TestWebIdentityCredentialsRefresh(t *testing.T) {
+	setWebIdentityEnv(t)
+	sts := newStubSTS(t, refreshWindow/2)
+	source := NewCredentialSource(sts.client(), "us-east-1")
+
+	first, err := source.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	second, err := source.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if first.AccessKeyID != "ASIA1" || second.AccessKeyID != "ASIA2" {
+		t.Fatalf("got %s then %s, want credentials near expiry renewed", first.AccessKeyID, second.AccessKeyID)
+	}
+
+	sts.status = http.StatusForbidden
+	if _, err := source.Get(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Get with STS failing: err %v, want the STS status", err)
+	}
+}
+
+// TestStaticCredentialsPreferred checks environment keys are used without
+// calling STS
+func TestStaticCredentialsPreferred(t *testing.T) {
+	setWebIdentityEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	sts := newStubSTS(t, time.Hour)
+
+	creds, err := NewCredentialSource(sts.client(), "us-east-1").Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || sts.calls.Load() != 0 {
+		t.Fatalf("got %s after %d STS calls, want the environment keys", creds.AccessKeyID, sts.calls.Load())
+	}
+}
@@ -0,0 +1,130 @@
+package sigv4
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credentials, scope and time shared by the AWS SigV4 test suite vectors
+var (
+	suiteCreds = Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	suiteTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+)
+
+const (
+	suiteRegion  = "us-east-1"
+	suiteService = "service"
+	suiteScope   = "20150830/us-east-1/service/aws4_request"
+)
+
+// TestSignSuiteVectors checks the canonical request, string to sign and
+// signature against vectors from the AWS SigV4 test suite
+func TestSignSuiteVectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		contentType   string
+		body          string
+		canonical     string
+		stringToSign  string
+		authorization string
+	}{
+		{
+			name:   "get-vanilla",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/",
+			canonical: "GET\n/\n\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			stringToSign: "AWS4-HMAC-SHA256\n20150830T123600Z\n20150830/us-east-1/service/aws4_request\n" +
+				"bb579772317eb040ac9ed261061d46c1f17a8133879d6129b6e1c25292927e63",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:   "get-vanilla-query-order-key-case",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			canonical: "GET\n/\nParam1=value1&Param2=value2\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+				"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:   "post-vanilla",
+			method: http.MethodPost,
+			url:    "https://example.amazonaws.com/",
+			canonical: "POST\n/\n\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+				"Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:        "post-x-www-form-urlencoded",
+			method:      http.MethodPost,
+			url:         "https://example.amazonaws.com/",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "Param1=value1",
+			canonical: "POST\n/\n\ncontent-type:application/x-www-form-urlencoded\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\n" +
+				"content-type;host;x-amz-date\n9095672bbd1f56dfc5b65f3e153adc8731a4a654192329106275f4c7b24d0b6e",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, " +
+				"Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			req.Header.Set("X-Amz-Date", "20150830T123600Z")
+
+			payloadHash := sha256.Sum256([]byte(tt.body))
+			canonical, _ := canonicalRequest(req, hex.EncodeToString(payloadHash[:]), suiteService)
+			if canonical != tt.canonical {
+				t.Errorf("canonical request:\n%s\nwant:\n%s", canonical, tt.canonical)
+			}
+			if tt.stringToSign != "" {
+				if got := stringToSign("20150830T123600Z", suiteScope, canonical); got != tt.stringToSign {
+					t.Errorf("string to sign:\n%s\nwant:\n%s", got, tt.stringToSign)
+				}
+			}
+
+			Sign(req, []byte(tt.body), suiteCreds, suiteRegion, suiteService, suiteTime)
+			if got := req.Header.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization:\n%s\nwant:\n%s", got, tt.authorization)
+			}
+		})
+	}
+}
+
+// TestSignSessionToken checks temporary credentials send and sign their
+// session token, and that signing again replaces the previous signature
+func TestSignSessionToken(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := suiteCreds
+	creds.SessionToken = "session-token"
+
+	Sign(req, nil, suiteCreds, suiteRegion, suiteService, suiteTime)
+	Sign(req, nil, creds, suiteRegion, suiteService, suiteTime)
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session-token" {
+		t.Fatalf("X-Amz-Security-Token %q, want the session token", got)
+	}
+	if got := req.Header.Values("Authorization"); len(got) != 1 || !strings.Contains(got[0], "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("Authorization %q, want one signature covering the session token", got)
+	}
+}
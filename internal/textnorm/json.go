@@ -0,0 +1,30 @@
+package textnorm
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// CanonicalJSON re-encodes a JSON document with sorted object keys and no
+// insignificant whitespace, so bodies that differ only in formatting hash
+// alike. Numbers keep their original text. Input that is not a single JSON
+// value is returned unchanged.
+func CanonicalJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return body
+	}
+
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
@@ -0,0 +1,74 @@
+// Package textnorm canonicalizes text before it is hashed, so prompts that
+// differ only in whitespace, Unicode composition or (optionally) case share
+// a hash for deduplication, caching and exact matching.
+package textnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Options selects the canonicalization steps. Each use of a hash picks its
+// own: deduplication can afford to fold case, exact-match security checks
+// usually should not.
+type Options struct {
+	Trim               bool // strip leading and trailing whitespace
+	CollapseWhitespace bool // replace each run of whitespace with one space
+	NFC                bool // Unicode canonical composition
+	Lowercase          bool
+}
+
+// Canonical applies the selected steps to text. NFC runs first so that
+// composed characters are lowercased and compared in one form.
+func Canonical(text string, options Options) string {
+	if options.NFC {
+		text = norm.NFC.String(text)
+	}
+	if options.CollapseWhitespace {
+		text = collapseWhitespace(text, options.Trim)
+	} else if options.Trim {
+		text = strings.TrimSpace(text)
+	}
+	if options.Lowercase {
+		text = strings.ToLower(text)
+	}
+	return text
+}
+
+// Sum returns the SHA-256 of the canonical form of text
+func Sum(text string, options Options) [32]byte {
+	return sha256.Sum256([]byte(Canonical(text, options)))
+}
+
+// Hash returns the hex SHA-256 of the canonical form of text
+func Hash(text string, options Options) string {
+	sum := Sum(text, options)
+	return hex.EncodeToString(sum[:])
+}
+
+// collapseWhitespace replaces whitespace runs with a single space, dropping
+// them entirely at either end when trim is set
+func collapseWhitespace(text string, trim bool) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	pending := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			pending = true
+			continue
+		}
+		if pending && (b.Len() > 0 || !trim) {
+			b.WriteByte(' ')
+		}
+		pending = false
+		b.WriteRune(r)
+	}
+	if pending && !trim {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package textnorm
+
+import "testing"
+
+// TestCanonical checks each canonicalization step on its own and together
+func TestCanonical(t *testing.T) {
+	composed, decomposed := "caf\u00e9", "cafe\u0301"
+
+	tests := []struct {
+		name    string
+		text    string
+		options Options
+		want    string
+	}{
+		{"no options", "  Ignore \t previous  ", Options{}, "  Ignore \t previous  "},
+		{"trim", "  Ignore \t previous  ", Options{Trim: true}, "Ignore \t previous"},
+		{"collapse", "  Ignore \t previous  ", Options{CollapseWhitespace: true}, " Ignore previous "},
+		{"collapse and trim", "  Ignore \t\n previous  ", Options{CollapseWhitespace: true, Trim: true}, "Ignore previous"},
+		{"nfc", decomposed, Options{NFC: true}, composed},
+		{"lowercase", "IGNORE Previous", Options{Lowercase: true}, "ignore previous"},
+		{"all", " CAFE\u0301  Menu ", Options{Trim: true, CollapseWhitespace: true, NFC: true, Lowercase: true}, composed + " menu"},
+	}
+	for _, tt := range tests {
+		if got := Canonical(tt.text, tt.options); got != tt.want {
+			t.Errorf("%s: Canonical(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestHash checks texts differing only in canonicalized ways share a hash
+func TestHash(t *testing.T) {
+	options := Options{Trim: true, CollapseWhitespace: true, NFC: true}
+	if Hash("caf\u00e9  menu", options) != Hash(" cafe\u0301 menu\n", options) {
+		t.Error("equivalent texts hashed differently")
+	}
+	if Hash("Menu", options) == Hash("menu", options) {
+		t.Error("case folded without Lowercase")
+	}
+	if len(Hash("menu", options)) != 64 {
+		t.Errorf("Hash is not hex SHA-256: %q", Hash("menu", options))
+	}
+}
+
+// TestCanonicalJSON checks formatting and key order are normalized while
+// numbers and invalid input are left alone
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"{\n  \"b\": 1.50,\n  \"a\": [true, null]\n}", `{"a":[true,null],"b":1.50}`},
+		{`{"n": 12345678901234567890}`, `{"n":12345678901234567890}`},
+		{`not json`, `not json`},
+		{`{"a":1} {"b":2}`, `{"a":1} {"b":2}`},
+		{`{"a":`, `{"a":`},
+	}
+	for _, tt := range tests {
+		if got := string(CanonicalJSON([]byte(tt.body))); got != tt.want {
+			t.Errorf("CanonicalJSON(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
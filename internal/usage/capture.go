@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+const (
+	// captureLimit is the largest body kept in full for parsing
+	captureLimit = 1 << 20
+	// edgeLimit is how much of the head and tail is kept for larger bodies;
+	// streaming usage lives in the first (Anthropic) or last chunks
+	edgeLimit = 64 << 10
+)
+
+// CaptureBody wraps a response body and calls onDone with the parsed usage
+// once the body has been fully read or closed. Bodies larger than the capture
+// limit keep only their head and tail. Gzip-encoded bodies are decoded for
+// parsing only when captured in full.
+func CaptureBody(body io.ReadCloser, provider, contentEncoding string, onDone func(*Usage)) io.ReadCloser {
+	return &captureBody{ReadCloser: body, provider: provider, encoding: contentEncoding, onDone: onDone}
+}
+
+type captureBody struct {
+	io.ReadCloser
+	provider string
+	encoding string
+	onDone   func(*Usage)
+
+	buf       []byte
+	tail      []byte
+	truncated bool
+	once      sync.Once
+}
+
+func (c *captureBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 {
+		c.record(p[:n])
+	}
+	if err == io.EOF {
+		c.finish()
+	}
+	return n, err
+}
+
+func (c *captureBody) Close() error {
+	c.finish()
+	return c.ReadCloser.Close()
+}
+
+func (c *captureBody) record(b []byte) {
+	if !c.truncated && len(c.buf)+len(b) <= captureLimit {
+		c.buf = append(c.buf, b...)
+		return
+	}
+	if !c.truncated {
+		c.truncated = true
+		if len(c.buf) > edgeLimit {
+			c.tail = append(c.tail, c.buf[edgeLimit:]...)
+			c.buf = c.buf[:edgeLimit]
+		}
+	}
+	c.tail = append(c.tail, b...)
+	if len(c.tail) > edgeLimit {
+		c.tail = append(c.tail[:0], c.tail[len(c.tail)-edgeLimit:]...)
+	}
+}
+
+func (c *captureBody) finish() {
+	c.once.Do(func() {
+		body := c.buf
+		switch c.encoding {
+		case "":
+		case "gzip":
+			if c.truncated {
+				return
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			decoded, err := io.ReadAll(io.LimitReader(zr, captureLimit))
+			if err != nil && len(decoded) == 0 {
+				return
+			}
+			body = decoded
+		default:
+			return
+		}
+		if c.truncated {
+			body = append(append(body, '\n'), c.tail...)
+		}
+		if u, ok := Parse(c.provider, body); ok {
+			c.onDone(u)
+		}
+	})
+}
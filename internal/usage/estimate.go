@@ -0,0 +1,111 @@
+package usage
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Estimate is the expected size and cost of one upstream call, worked out
+// before it is sent. It is recorded with the request so estimates can be
+// reconciled with provider bills.
+type Estimate struct {
+	Model               string  `json:"model,omitempty"`
+	PromptTokens        int     `json:"prompt_tokens"`         // from the prompt text length
+	MaxCompletionTokens int     `json:"max_completion_tokens"` // the request's maximum, or the configured default
+	CostUSD             float64 `json:"cost_usd"`              // prompt plus the maximum completion at the model's price
+	ReportedCostUSD     float64 `json:"reported_cost_usd,omitempty"`
+}
+
+// pathModel finds the model of providers that name it in the path: Gemini
+// and Vertex AI (models/<model>:generateContent) and Bedrock (model/<id>/invoke)
+var pathModel = regexp.MustCompile(`/models?/([^/:]+)`)
+
+// completionLimitPaths lists where each provider puts the completion token
+// maximum: OpenAI, Anthropic and Azure; OpenAI's Responses API; Gemini and
+// Vertex AI; Ollama; Bedrock Converse
+var completionLimitPaths = [][]string{
+	{"max_completion_tokens"},
+	{"max_tokens"},
+	{"max_output_tokens"},
+	{"generationConfig", "maxOutputTokens"},
+	{"options", "num_predict"},
+	{"inferenceConfig", "maxTokens"},
+}
+
+// EstimateRequest estimates the tokens of a provider request body sent to
+// path. Prompt tokens are the body's text divided by charsPerToken; inline
+// binary data such as images is not counted. The completion maximum is the
+// request's own, times the number of choices requested, or
+// defaultCompletion. It reports false when the body is not a JSON object.
+func EstimateRequest(body []byte, path string, charsPerToken float64, defaultCompletion int) (*Estimate, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, false
+	}
+
+	e := &Estimate{MaxCompletionTokens: defaultCompletion}
+	if model, ok := fields["model"].(string); ok {
+		e.Model = model
+	} else if match := pathModel.FindStringSubmatch(path); match != nil {
+		e.Model = match[1]
+	}
+
+	chars := 0
+	for key, value := range fields {
+		if key != "model" {
+			chars += textLength(key, value)
+		}
+	}
+	e.PromptTokens = int(math.Ceil(float64(chars) / charsPerToken))
+
+	for _, limitPath := range completionLimitPaths {
+		if limit, ok := numberAt(fields, limitPath); ok && limit > 0 {
+			e.MaxCompletionTokens = int(limit)
+			break
+		}
+	}
+	if choices, ok := numberAt(fields, []string{"n"}); ok && choices > 1 {
+		e.MaxCompletionTokens *= int(choices)
+	}
+	return e, true
+}
+
+// textLength sums the length of the strings in value, skipping inline data
+func textLength(key string, value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		if key == "data" || strings.HasPrefix(v, "data:") {
+			return 0
+		}
+		return len(v)
+	case []interface{}:
+		total := 0
+		for _, item := range v {
+			total += textLength(key, item)
+		}
+		return total
+	case map[string]interface{}:
+		total := 0
+		for k, item := range v {
+			total += textLength(k, item)
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// numberAt returns the number at a path of object keys
+func numberAt(fields map[string]interface{}, path []string) (float64, bool) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := fields[key].(map[string]interface{})
+		if !ok {
+			return 0, false
+		}
+		fields = next
+	}
+	n, ok := fields[path[len(path)-1]].(float64)
+	return n, ok
+}
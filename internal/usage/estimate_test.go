@@ -0,0 +1,35 @@
+package usage
+
+import "testing"
+
+// TestEstimateRequest checks prompt tokens, completion limits and models are
+// read from each provider's request shape
+func TestEstimateRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		path       string
+		prompt     int
+		completion int
+		model      string
+	}{
+		{"openai", `{"model":"gpt-4o","messages":[{"role":"user","content":"abcdefgh"}],"max_tokens":50,"n":2}`, "/v1/chat/completions", 3, 100, "gpt-4o"},
+		{"default completion", `{"model":"gpt-4o","input":"abcd"}`, "/v1/responses", 1, 256, "gpt-4o"},
+		{"gemini", `{"contents":[{"parts":[{"text":"abcd"},{"inlineData":{"data":"aGVsbG8="}}]}],"generationConfig":{"maxOutputTokens":64}}`, "/v1beta/models/gemini-pro:generateContent", 1, 64, "gemini-pro"},
+		{"inline image", `{"model":"m","messages":[{"content":[{"image_url":{"url":"data:image/png;base64,AAAA"}}]}]}`, "/", 0, 256, "m"},
+	}
+	for _, tt := range tests {
+		e, ok := EstimateRequest([]byte(tt.body), tt.path, 4, 256)
+		if !ok {
+			t.Errorf("%s: no estimate", tt.name)
+			continue
+		}
+		if e.PromptTokens != tt.prompt || e.MaxCompletionTokens != tt.completion || e.Model != tt.model {
+			t.Errorf("%s: estimate = %+v, want %d prompt, %d completion, model %q", tt.name, e, tt.prompt, tt.completion, tt.model)
+		}
+	}
+
+	if _, ok := EstimateRequest([]byte(`[1,2]`), "/", 4, 256); ok {
+		t.Error("estimate reported for a non-object body")
+	}
+}
@@ -0,0 +1,141 @@
+package usage
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Usage is the provider-agnostic token consumption of one upstream call
+type Usage struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+}
+
+// responseFields covers the usage-bearing fields of every supported provider,
+// for both complete responses and individual streaming chunks
+type responseFields struct {
+	Model string `json:"model"`
+
+	// OpenAI: usage.prompt_tokens/completion_tokens/total_tokens
+	// Anthropic: usage.input_tokens/output_tokens
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+		InputTokens      int `json:"input_tokens"`
+		OutputTokens     int `json:"output_tokens"`
+	} `json:"usage"`
+
+	// Anthropic streaming: message_start carries the model and input usage
+	Message *struct {
+		Model string `json:"model"`
+		Usage *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+
+	// Gemini and Vertex AI: usageMetadata, on every streamed chunk
+	ModelVersion  string `json:"modelVersion"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+
+	// Ollama: prompt_eval_count/eval_count, on the final chunk when streaming
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// Parse extracts normalized usage from a response body. Complete JSON bodies,
+// server-sent event streams and newline-delimited JSON streams are supported.
+// It reports false when the body carries no usage information.
+func Parse(provider string, body []byte) (*Usage, bool) {
+	u := &Usage{Provider: provider}
+	found := false
+
+	var whole responseFields
+	if err := json.Unmarshal(body, &whole); err == nil {
+		found = u.merge(&whole)
+	} else {
+		for _, line := range bytes.Split(body, []byte("\n")) {
+			line = bytes.TrimSpace(line)
+			line = bytes.TrimPrefix(line, []byte("data:"))
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 || line[0] != '{' {
+				continue
+			}
+			var chunk responseFields
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				continue
+			}
+			if u.merge(&chunk) {
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	return u, true
+}
+
+// merge folds one response or chunk into u. Streaming chunks report running
+// or partial counts, so the largest value seen wins.
+func (u *Usage) merge(f *responseFields) bool {
+	found := false
+
+	if f.Model != "" {
+		u.Model = f.Model
+	}
+	if f.Usage != nil {
+		u.PromptTokens = maxInt(u.PromptTokens, f.Usage.PromptTokens, f.Usage.InputTokens)
+		u.CompletionTokens = maxInt(u.CompletionTokens, f.Usage.CompletionTokens, f.Usage.OutputTokens)
+		u.TotalTokens = maxInt(u.TotalTokens, f.Usage.TotalTokens)
+		found = true
+	}
+	if f.Message != nil {
+		if f.Message.Model != "" {
+			u.Model = f.Message.Model
+		}
+		if f.Message.Usage != nil {
+			u.PromptTokens = maxInt(u.PromptTokens, f.Message.Usage.InputTokens)
+			u.CompletionTokens = maxInt(u.CompletionTokens, f.Message.Usage.OutputTokens)
+			found = true
+		}
+	}
+	if f.UsageMetadata != nil {
+		if f.ModelVersion != "" {
+			u.Model = f.ModelVersion
+		}
+		u.PromptTokens = maxInt(u.PromptTokens, f.UsageMetadata.PromptTokenCount)
+		u.CompletionTokens = maxInt(u.CompletionTokens, f.UsageMetadata.CandidatesTokenCount)
+		u.TotalTokens = maxInt(u.TotalTokens, f.UsageMetadata.TotalTokenCount)
+		found = true
+	}
+	if f.PromptEvalCount > 0 || f.EvalCount > 0 {
+		u.PromptTokens = maxInt(u.PromptTokens, f.PromptEvalCount)
+		u.CompletionTokens = maxInt(u.CompletionTokens, f.EvalCount)
+		found = true
+	}
+
+	return found
+}
+
+func maxInt(values ...int) int {
+	m := 0
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
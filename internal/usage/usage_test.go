@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestParse checks each provider's usage format, complete and streamed
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Usage
+	}{
+		{
+			"openai",
+			`{"model":"gpt-4o","usage":{"prompt_tokens":12,"completion_tokens":30,"total_tokens":42}}`,
+			Usage{Model: "gpt-4o", PromptTokens: 12, CompletionTokens: 30, TotalTokens: 42},
+		},
+		{
+			"openai stream",
+			"data: {\"model\":\"gpt-4o\",\"choices\":[]}\n\ndata: {\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":7}}\n\ndata: [DONE]\n",
+			Usage{Model: "gpt-4o", PromptTokens: 5, CompletionTokens: 7, TotalTokens: 12},
+		},
+		{
+			"anthropic stream",
+			"event: message_start\ndata: {\"message\":{\"model\":\"claude\",\"usage\":{\"input_tokens\":20,\"output_tokens\":1}}}\n\nevent: message_delta\ndata: {\"usage\":{\"output_tokens\":15}}\n",
+			Usage{Model: "claude", PromptTokens: 20, CompletionTokens: 15, TotalTokens: 35},
+		},
+		{
+			"gemini",
+			`{"modelVersion":"gemini-pro","usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":4,"totalTokenCount":12}}`,
+			Usage{Model: "gemini-pro", PromptTokens: 8, CompletionTokens: 4, TotalTokens: 12},
+		},
+		{
+			"ollama stream",
+			"{\"model\":\"llama3\",\"done\":false}\n{\"model\":\"llama3\",\"done\":true,\"prompt_eval_count\":9,\"eval_count\":3}\n",
+			Usage{Model: "llama3", PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := Parse("test", []byte(tt.body))
+		if !ok {
+			t.Errorf("%s: no usage found", tt.name)
+			continue
+		}
+		tt.want.Provider = "test"
+		if *got != tt.want {
+			t.Errorf("%s: Parse = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	if _, ok := Parse("test", []byte(`{"choices":[]}`)); ok {
+		t.Error("usage reported for a body without any")
+	}
+}
+
+// TestCaptureBody checks usage is reported once the body is consumed,
+// including gzip bodies and bodies past the capture limit
+func TestCaptureBody(t *testing.T) {
+	capture := func(body []byte, encoding string) *Usage {
+		var got *Usage
+		calls := 0
+		r := CaptureBody(io.NopCloser(bytes.NewReader(body)), "openai", encoding, func(u *Usage) {
+			got = u
+			calls++
+		})
+		io.Copy(io.Discard, r)
+		r.Close()
+		if calls > 1 {
+			t.Errorf("onDone called %d times", calls)
+		}
+		return got
+	}
+
+	final := `data: {"usage":{"prompt_tokens":3,"completion_tokens":4}}` + "\n"
+	if u := capture([]byte(final), ""); u == nil || u.TotalTokens != 7 {
+		t.Errorf("plain body usage = %+v", u)
+	}
+
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write([]byte(final))
+	zw.Close()
+	if u := capture(zipped.Bytes(), "gzip"); u == nil || u.TotalTokens != 7 {
+		t.Errorf("gzip body usage = %+v", u)
+	}
+	if u := capture([]byte(final), "br"); u != nil {
+		t.Errorf("usage parsed from an unsupported encoding: %+v", u)
+	}
+
+	chunk := `data: {"choices":[{"delta":{"content":"` + strings.Repeat("x", 1000) + `"}}]}` + "\n"
+	large := strings.Repeat(chunk, 2*captureLimit/len(chunk)) + final
+	if u := capture([]byte(large), ""); u == nil || u.TotalTokens != 7 {
+		t.Errorf("usage from the tail of a large body = %+v", u)
+	}
+}
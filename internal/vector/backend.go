@@ -0,0 +1,37 @@
+package vector
+
+import "context"
+
+// Backend is the storage interface for security vectors. Store is the
+// Postgres/pgvector implementation; DualStore wraps two backends during a
+// migration; Standby serves searches from a corpus snapshot while the
+// database is unreachable.
+type Backend interface {
+	Insert(ctx context.Context, vector *SecurityVector) error
+	BatchInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error)
+	CopyInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error)
+	FindSimilar(ctx context.Context, embedding []float32, options *SearchOptions) ([]*SimilarityResult, error)
+	FindHybrid(ctx context.Context, embedding []float32, text string, options *SearchOptions) ([]*SimilarityResult, error)
+	StreamSimilar(ctx context.Context, embedding []float32, options *SearchOptions, fn func(*SimilarityResult) error) error
+	ExplainSimilar(ctx context.Context, embedding []float32, options *SearchOptions) (*QueryPlan, error)
+	ListVectors(ctx context.Context, options *PageOptions) (*VectorPage, error)
+	IterateVectors(ctx context.Context, options *PageOptions, fn func(*SecurityVector) error) error
+	GetMaliciousVectors(ctx context.Context, afterID int64, limit int, labelText string) ([]*SecurityVector, error)
+	ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error)
+	GetStats(ctx context.Context) (*VectorStats, error)
+	CorpusStats(ctx context.Context, options *CorpusStatsOptions) (*CorpusStats, error)
+	CorpusVersion(ctx context.Context) (string, error)
+	SaveCanaries(ctx context.Context, canaries []Canary) error
+	Canaries(ctx context.Context) ([]Canary, error)
+	CreateIndex(ctx context.Context) error
+	ReindexVectors(ctx context.Context) error
+	DropIndex(ctx context.Context) error
+	Indexes(ctx context.Context) ([]IndexInfo, error)
+	Ping(ctx context.Context) error
+	PoolStats() []PoolStats
+	Close() error
+}
+
+var _ Backend = (*Store)(nil)
+var _ Backend = (*DualStore)(nil)
+var _ Backend = (*Standby)(nil)
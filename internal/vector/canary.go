@@ -0,0 +1,114 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"go.uber.org/zap"
+)
+
+// Canary is a reference embedding of a fixed text, stored when the corpus is
+// ingested so a later startup can check it still embeds the same way
+type Canary struct {
+	Text      string    `json:"text"`
+	Model     string    `json:"model"` // service type and model that produced the embedding
+	Embedding []float32 `json:"embedding"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// canaryTable holds the canaries. Embeddings are stored unreduced and the
+// column is unsized, so it fits any model.
+const canaryTable = `
+	CREATE TABLE IF NOT EXISTS embedding_canaries (
+		text TEXT PRIMARY KEY,
+		model TEXT NOT NULL,
+		embedding vector NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW()
+	)`
+
+// SaveCanaries replaces the stored canaries
+func (s *Store) SaveCanaries(ctx context.Context, canaries []Canary) error {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin canary transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, canaryTable); err != nil {
+		return fmt.Errorf("failed to create canary table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM embedding_canaries`); err != nil {
+		return fmt.Errorf("failed to clear canaries: %w", err)
+	}
+	for _, canary := range canaries {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO embedding_canaries (text, model, embedding) VALUES ($1, $2, $3)`,
+			canary.Text, canary.Model, binaryVector(canary.Embedding))
+		if err != nil {
+			return fmt.Errorf("failed to insert canary: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit canaries: %w", err)
+	}
+
+	s.logger.Info("Embedding canaries saved", zap.Int("count", len(canaries)))
+	return nil
+}
+
+// Canaries returns the stored canaries, or none when the corpus predates them
+func (s *Store) Canaries(ctx context.Context) ([]Canary, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT text, model, vector_send(embedding), created_at FROM embedding_canaries ORDER BY text`)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "42P01" { // undefined_table
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load canaries: %w", err)
+	}
+	defer rows.Close()
+
+	var canaries []Canary
+	for rows.Next() {
+		var canary Canary
+		var embedding []byte
+		if err := rows.Scan(&canary.Text, &canary.Model, &embedding, &canary.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan canary: %w", err)
+		}
+		if canary.Embedding, err = decodeVector(embedding); err != nil {
+			return nil, err
+		}
+		canaries = append(canaries, canary)
+	}
+	return canaries, rows.Err()
+}
+
+// SaveCanaries replaces the canaries on both backends
+func (d *DualStore) SaveCanaries(ctx context.Context, canaries []Canary) error {
+	if err := d.primary.SaveCanaries(ctx, canaries); err != nil {
+		return err
+	}
+	if err := d.secondary.SaveCanaries(ctx, canaries); err != nil {
+		d.logger.Warn("Secondary canary save failed", zap.Error(err))
+	}
+	return nil
+}
+
+// Canaries returns the serving backend's canaries
+func (d *DualStore) Canaries(ctx context.Context) ([]Canary, error) {
+	serving, _ := d.reader()
+	return serving.Canaries(ctx)
+}
@@ -0,0 +1,179 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"go.uber.org/zap"
+)
+
+// copyStagingTable receives COPY rows before they are merged into
+// security_vectors. COPY has no ON CONFLICT clause, so duplicates are
+// resolved by the INSERT ... SELECT that drains it.
+const copyStagingTable = "security_vectors_staging"
+
+// insertColumns is the number of bind parameters each row of a multi-VALUES
+// INSERT takes
+const insertColumns = 9
+
+// maxInsertRows keeps a multi-VALUES INSERT under Postgres's 65535 bind
+// parameter limit
+const maxInsertRows = 65535 / insertColumns
+
+// CopyInsert adds vectors with COPY FROM STDIN into a transaction-scoped
+// staging table, then merges them into security_vectors skipping existing
+// text hashes, committing or rolling back as a unit. If the COPY path fails,
+// the batch is retried with BatchInsert in chunks below the bind parameter
+// limit, so large batches degrade to the slower path rather than failing.
+func (s *Store) CopyInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error) {
+	if len(vectors) == 0 {
+		return &BatchInsertResult{}, nil
+	}
+
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	inserted, err := s.copyInsert(ctx, vectors)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &BatchInsertResult{Failed: int64(len(vectors)), Errors: []error{err}}, fmt.Errorf("copy insert failed: %w", err)
+		}
+		s.logger.Warn("COPY insert failed, falling back to batch INSERT",
+			zap.Error(err),
+			zap.Int("batch_size", len(vectors)))
+		return s.insertChunks(ctx, vectors, start)
+	}
+
+	result := &BatchInsertResult{
+		Inserted: inserted,
+		Duration: time.Since(start),
+	}
+
+	s.logger.Info("COPY insert completed",
+		zap.Int64("inserted", result.Inserted),
+		zap.Int64("duplicates_skipped", int64(len(vectors))-inserted),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// copyInsert streams vectors into the staging table and merges them,
+// returning the number of new rows
+func (s *Store) copyInsert(ctx context.Context, vectors []*SecurityVector) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Untyped vector column: dimensions are checked when rows reach
+	// security_vectors
+	staging := `CREATE TEMP TABLE ` + copyStagingTable + ` (
+		text TEXT,
+		embedding_type VARCHAR(16),
+		text_hash VARCHAR(64),
+		label_text VARCHAR(50),
+		label INTEGER,
+		embedding vector,
+		source TEXT,
+		language VARCHAR(16),
+		metadata JSONB
+	) ON COMMIT DROP`
+	if _, err := tx.ExecContext(ctx, staging); err != nil {
+		return 0, fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(copyStagingTable,
+		"text", "embedding_type", "text_hash", "label_text", "label", "embedding", "source", "language", "metadata"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to start COPY: %w", err)
+	}
+
+	// COPY rows travel in text format, so embeddings use pgvector's text form
+	for _, vector := range vectors {
+		if _, err := stmt.ExecContext(ctx,
+			vector.Text,
+			vector.EmbeddingType,
+			s.hashOf(vector),
+			vector.LabelText,
+			vector.Label,
+			copyVector(s.reduce(vector.Embedding)),
+			vector.Source,
+			vector.Language,
+			metadataJSON(vector.Metadata),
+		); err != nil {
+			stmt.Close()
+			return 0, fmt.Errorf("failed to copy row: %w", err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return 0, fmt.Errorf("failed to flush COPY: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, fmt.Errorf("failed to finish COPY: %w", err)
+	}
+
+	merge := `
+		INSERT INTO security_vectors (text, embedding_type, text_hash, label_text, label, embedding, source, language, metadata)
+		SELECT text, embedding_type, text_hash, label_text, label, embedding, source, language, metadata FROM ` + copyStagingTable + `
+		ON CONFLICT (text_hash) DO NOTHING`
+	res, err := tx.ExecContext(ctx, merge)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge staged vectors: %w", err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		s.logger.Warn("Could not get rows affected", zap.Error(err))
+		inserted = int64(len(vectors))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit COPY insert: %w", err)
+	}
+	return inserted, nil
+}
+
+// insertChunks writes vectors with BatchInsert, split so no statement
+// exceeds the bind parameter limit
+func (s *Store) insertChunks(ctx context.Context, vectors []*SecurityVector, start time.Time) (*BatchInsertResult, error) {
+	result := &BatchInsertResult{}
+	for i := 0; i < len(vectors); i += maxInsertRows {
+		end := i + maxInsertRows
+		if end > len(vectors) {
+			end = len(vectors)
+		}
+		chunk, err := s.BatchInsert(ctx, vectors[i:end])
+		if err != nil {
+			result.Failed += int64(len(vectors) - i)
+			result.Errors = append(result.Errors, err)
+			result.Duration = time.Since(start)
+			return result, err
+		}
+		result.Inserted += chunk.Inserted
+		result.Failed += chunk.Failed
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// copyVector formats an embedding in pgvector's text form, "[1,2,3]", for
+// COPY rows
+func copyVector(embedding []float32) string {
+	buf := make([]byte, 0, 2+len(embedding)*12)
+	buf = append(buf, '[')
+	for i, f := range embedding {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, float64(f), 'g', -1, 32)
+	}
+	buf = append(buf, ']')
+	return string(buf)
+}
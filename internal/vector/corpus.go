@@ -0,0 +1,261 @@
+package vector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+)
+
+// corpusColumns are security_vectors columns added after its first release
+const corpusColumns = `
+	ALTER TABLE security_vectors
+		ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS language VARCHAR(16) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'`
+
+// ensureCorpusColumns adds the source, language and metadata columns to a
+// table
+// created before they existed. The catalog is checked first so an
+// up-to-date table is never locked for the ALTER.
+func (s *Store) ensureCorpusColumns(ctx context.Context) error {
+	var present int
+	query := `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_name = 'security_vectors' AND column_name IN ('source', 'language', 'metadata')`
+	if err := s.db.GetContext(ctx, &present, query); err != nil {
+		return fmt.Errorf("failed to inspect security_vectors columns: %w", err)
+	}
+	if present == 3 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, corpusColumns); err != nil {
+		return fmt.Errorf("failed to add security_vectors columns: %w", err)
+	}
+	return nil
+}
+
+// metadataJSON encodes a vector's metadata for the jsonb column. It is
+// passed as a string: binary parameters would need jsonb's version byte.
+func metadataJSON(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// scanMetadata decodes a jsonb metadata column, leaving empty objects nil
+func scanMetadata(data []byte) (map[string]string, error) {
+	var metadata map[string]string
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid vector metadata: %w", err)
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return metadata, nil
+}
+
+// TimelineIntervals are the bucket widths CorpusStats accepts
+var TimelineIntervals = []string{"hour", "day", "week", "month"}
+
+// DefaultNeighborSample is used when CorpusStatsOptions.NeighborSample is unset
+const DefaultNeighborSample = 200
+
+// CorpusStatsOptions controls the more expensive parts of CorpusStats
+type CorpusStatsOptions struct {
+	Interval       string     `json:"interval"`        // timeline bucket width, one of TimelineIntervals; default day
+	Since          *time.Time `json:"since,omitempty"` // timeline start; nil covers the whole corpus
+	NeighborSample int        `json:"neighbor_sample"` // vectors sampled per label_text for nearest-neighbour distances
+}
+
+// CorpusStats describes the corpus's composition for health dashboards
+type CorpusStats struct {
+	VectorStats
+
+	Interval  string           `json:"interval"`
+	Timeline  []TimelineBucket `json:"timeline"`
+	Neighbors []ClassNeighbors `json:"nearest_neighbors"`
+}
+
+// TimelineBucket counts the vectors ingested in one interval
+type TimelineBucket struct {
+	Start     time.Time `json:"start"`
+	Count     int64     `json:"count"`
+	Malicious int64     `json:"malicious"`
+}
+
+// ClassNeighbors summarizes how far a sample of one label_text's vectors are
+// from their nearest other vector. A class whose nearest neighbours are
+// mostly other classes overlaps them in embedding space; a very small
+// average distance points at near-duplicates.
+type ClassNeighbors struct {
+	LabelText     string  `json:"label_text"`
+	Sampled       int64   `json:"sampled"`
+	AvgDistance   float64 `json:"avg_distance"`    // cosine distance
+	SameClassRate float64 `json:"same_class_rate"` // share whose nearest neighbour has the same label_text
+}
+
+// countBy counts vectors grouped by column, naming empty values "unknown".
+// column is one of this package's own column names, never caller input.
+func (s *Store) countBy(ctx context.Context, column string) (map[string]int64, error) {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(%s, ''), 'unknown'), COUNT(*)
+		FROM security_vectors
+		GROUP BY 1`, column)
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count vectors by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var key string
+		var count int64
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s count: %w", column, err)
+		}
+		counts[key] += count
+	}
+	return counts, rows.Err()
+}
+
+// CorpusStats returns GetStats plus an ingestion timeline and
+// nearest-neighbour distances per label_text. The neighbour search runs one
+// similarity query per sampled vector, so keep the sample modest on large
+// corpora.
+func (s *Store) CorpusStats(ctx context.Context, options *CorpusStatsOptions) (*CorpusStats, error) {
+	if options == nil {
+		options = &CorpusStatsOptions{}
+	}
+	interval := options.Interval
+	if interval == "" {
+		interval = "day"
+	}
+	if !IsTimelineInterval(interval) {
+		return nil, fmt.Errorf("invalid timeline interval: %q (must be hour, day, week or month)", interval)
+	}
+	sample := options.NeighborSample
+	if sample <= 0 {
+		sample = DefaultNeighborSample
+	}
+
+	vectorStats, err := s.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &CorpusStats{VectorStats: *vectorStats, Interval: interval}
+
+	if stats.Timeline, err = s.timeline(ctx, interval, options.Since); err != nil {
+		return nil, err
+	}
+	if stats.Neighbors, err = s.classNeighbors(ctx, sample); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// timeline counts vectors per interval of created_at
+func (s *Store) timeline(ctx context.Context, interval string, since *time.Time) ([]TimelineBucket, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{interval}
+	filter := ""
+	if since != nil {
+		args = append(args, *since)
+		filter = " AND created_at >= $2"
+	}
+	query := `
+		SELECT date_trunc($1, created_at) AS bucket,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE label = 1)
+		FROM security_vectors
+		WHERE created_at IS NOT NULL` + filter + `
+		GROUP BY bucket
+		ORDER BY bucket`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ingestion timeline: %w", err)
+	}
+	defer rows.Close()
+
+	timeline := []TimelineBucket{}
+	for rows.Next() {
+		var bucket TimelineBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Count, &bucket.Malicious); err != nil {
+			return nil, fmt.Errorf("failed to scan timeline bucket: %w", err)
+		}
+		timeline = append(timeline, bucket)
+	}
+	return timeline, rows.Err()
+}
+
+// classNeighbors samples up to sample vectors of each label_text and finds
+// each one's nearest other vector in the whole corpus
+func (s *Store) classNeighbors(ctx context.Context, sample int) ([]ClassNeighbors, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	// The sample is drawn on ids alone so the window sort does not carry
+	// embeddings
+	query := `
+		WITH sampled AS (
+			SELECT v.id, v.label_text, v.embedding
+			FROM (
+				SELECT id, row_number() OVER (PARTITION BY label_text ORDER BY random()) AS n
+				FROM security_vectors
+			) ranked
+			JOIN security_vectors v ON v.id = ranked.id
+			WHERE ranked.n <= $1
+		)
+		SELECT s.label_text,
+			COUNT(*),
+			AVG(nn.distance),
+			AVG(CASE WHEN nn.label_text = s.label_text THEN 1.0 ELSE 0.0 END)
+		FROM sampled s
+		CROSS JOIN LATERAL (
+			SELECT t.label_text, t.embedding <=> s.embedding AS distance
+			FROM security_vectors t
+			WHERE t.id <> s.id
+			ORDER BY t.embedding <=> s.embedding
+			LIMIT 1
+		) nn
+		GROUP BY s.label_text
+		ORDER BY s.label_text`
+
+	rows, err := s.db.QueryContext(ctx, query, sample)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure nearest-neighbour distances: %w", err)
+	}
+	defer rows.Close()
+
+	neighbors := []ClassNeighbors{}
+	for rows.Next() {
+		var class ClassNeighbors
+		if err := rows.Scan(&class.LabelText, &class.Sampled, &class.AvgDistance, &class.SameClassRate); err != nil {
+			return nil, fmt.Errorf("failed to scan nearest-neighbour distances: %w", err)
+		}
+		neighbors = append(neighbors, class)
+	}
+	return neighbors, rows.Err()
+}
+
+// IsTimelineInterval reports whether interval is one of TimelineIntervals
+func IsTimelineInterval(interval string) bool {
+	for _, v := range TimelineIntervals {
+		if v == interval {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,489 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
+	"go.uber.org/zap"
+)
+
+// DualOptions controls a dual-write migration between two backends
+type DualOptions struct {
+	ReadFromSecondary bool          // serve reads from the secondary (cutover rehearsal)
+	CompareReads      bool          // shadow searches against the other backend and compare
+	CompareSampleRate float64       // fraction of searches to compare, 0-1
+	CompareTimeout    time.Duration // bound on each shadow search
+	MaxCompares       int           // shadow searches in flight at once; samples beyond it are dropped
+}
+
+// DivergenceReport summarizes how the two backends differ
+type DivergenceReport struct {
+	Writes               int64   `json:"writes"`
+	SecondaryWriteErrors int64   `json:"secondary_write_errors"`
+	SecondarySkipped     int64   `json:"secondary_skipped"` // rows the primary stored that the secondary already had
+	Compared             int64   `json:"compared"`
+	ShadowReadErrors     int64   `json:"shadow_read_errors"`
+	DroppedCompares      int64   `json:"dropped_compares"` // sampled while MaxCompares were in flight
+	Top1Mismatches       int64   `json:"top1_mismatches"`
+	CountMismatches      int64   `json:"count_mismatches"`
+	AvgOverlap           float64 `json:"avg_overlap"` // mean fraction of shared results per search
+	AvgShadowLatencyMs   float64 `json:"avg_shadow_latency_ms"`
+	ReadFromSecondary    bool    `json:"read_from_secondary"`
+}
+
+// DualStore writes to a primary and a secondary backend and optionally
+// compares search results between them, so divergence can be measured before
+// cutting over to the secondary
+type DualStore struct {
+	primary   Backend
+	secondary Backend
+	options   DualOptions
+	logger    *zap.Logger
+	compares  chan struct{} // one slot per shadow search in flight
+
+	mu           sync.Mutex
+	report       DivergenceReport
+	overlapSum   float64
+	shadowTimeMs float64
+	rng          *rand.Rand
+}
+
+// NewDualStore creates a dual-write store
+func NewDualStore(primary, secondary Backend, options DualOptions, logger *zap.Logger) *DualStore {
+	if options.CompareTimeout <= 0 {
+		options.CompareTimeout = 2 * time.Second
+	}
+	if options.MaxCompares <= 0 {
+		options.MaxCompares = 16
+	}
+	return &DualStore{
+		primary:   primary,
+		secondary: secondary,
+		options:   options,
+		logger:    logger,
+		compares:  make(chan struct{}, options.MaxCompares),
+		report:    DivergenceReport{ReadFromSecondary: options.ReadFromSecondary},
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// reader returns the backend serving reads and the one shadowing them
+func (d *DualStore) reader() (Backend, Backend) {
+	if d.options.ReadFromSecondary {
+		return d.secondary, d.primary
+	}
+	return d.primary, d.secondary
+}
+
+// Insert writes to both backends; only primary failures are returned
+func (d *DualStore) Insert(ctx context.Context, vector *SecurityVector) error {
+	if err := d.primary.Insert(ctx, vector); err != nil {
+		return err
+	}
+
+	// The secondary assigns its own id and timestamps; keep the primary's
+	mirror := *vector
+	d.recordWrite(d.secondary.Insert(ctx, &mirror))
+	return nil
+}
+
+// BatchInsert writes to both backends; only primary failures are returned
+func (d *DualStore) BatchInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error) {
+	result, err := d.primary.BatchInsert(ctx, vectors)
+	if err != nil {
+		return result, err
+	}
+
+	mirrors := make([]*SecurityVector, len(vectors))
+	for i, v := range vectors {
+		mirror := *v
+		mirrors[i] = &mirror
+	}
+	secondaryResult, sErr := d.secondary.BatchInsert(ctx, mirrors)
+	d.recordBatchWrite(result, secondaryResult, sErr)
+
+	return result, nil
+}
+
+// CopyInsert bulk-loads into both backends; only primary failures are returned
+func (d *DualStore) CopyInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error) {
+	result, err := d.primary.CopyInsert(ctx, vectors)
+	if err != nil {
+		return result, err
+	}
+
+	mirrors := make([]*SecurityVector, len(vectors))
+	for i, v := range vectors {
+		mirror := *v
+		mirrors[i] = &mirror
+	}
+	secondaryResult, sErr := d.secondary.CopyInsert(ctx, mirrors)
+	d.recordBatchWrite(result, secondaryResult, sErr)
+
+	return result, nil
+}
+
+// FindSimilar searches the serving backend and shadows a sample to the other
+func (d *DualStore) FindSimilar(ctx context.Context, embedding []float32, options *SearchOptions) ([]*SimilarityResult, error) {
+	serving, shadow := d.reader()
+	results, err := serving.FindSimilar(ctx, embedding, options)
+	if err == nil && d.shouldCompare() {
+		d.shadow(results, func(ctx context.Context) ([]*SimilarityResult, error) {
+			return shadow.FindSimilar(ctx, embedding, options)
+		})
+	}
+	return results, err
+}
+
+// FindHybrid searches the serving backend and shadows a sample to the other
+func (d *DualStore) FindHybrid(ctx context.Context, embedding []float32, text string, options *SearchOptions) ([]*SimilarityResult, error) {
+	serving, shadow := d.reader()
+	results, err := serving.FindHybrid(ctx, embedding, text, options)
+	if err == nil && d.shouldCompare() {
+		d.shadow(results, func(ctx context.Context) ([]*SimilarityResult, error) {
+			return shadow.FindHybrid(ctx, embedding, text, options)
+		})
+	}
+	return results, err
+}
+
+// StreamSimilar streams from the serving backend
+func (d *DualStore) StreamSimilar(ctx context.Context, embedding []float32, options *SearchOptions, fn func(*SimilarityResult) error) error {
+	serving, _ := d.reader()
+	return serving.StreamSimilar(ctx, embedding, options, fn)
+}
+
+// ExplainSimilar explains the search on the serving backend
+func (d *DualStore) ExplainSimilar(ctx context.Context, embedding []float32, options *SearchOptions) (*QueryPlan, error) {
+	serving, _ := d.reader()
+	return serving.ExplainSimilar(ctx, embedding, options)
+}
+
+// ListVectors pages through the serving backend
+func (d *DualStore) ListVectors(ctx context.Context, options *PageOptions) (*VectorPage, error) {
+	serving, _ := d.reader()
+	return serving.ListVectors(ctx, options)
+}
+
+// IterateVectors walks the serving backend
+func (d *DualStore) IterateVectors(ctx context.Context, options *PageOptions, fn func(*SecurityVector) error) error {
+	serving, _ := d.reader()
+	return serving.IterateVectors(ctx, options, fn)
+}
+
+// GetMaliciousVectors reads from the serving backend
+func (d *DualStore) GetMaliciousVectors(ctx context.Context, afterID int64, limit int, labelText string) ([]*SecurityVector, error) {
+	serving, _ := d.reader()
+	return serving.GetMaliciousVectors(ctx, afterID, limit, labelText)
+}
+
+// ExistingHashes reads from the primary, which every write reaches first
+func (d *DualStore) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	return d.primary.ExistingHashes(ctx, hashes)
+}
+
+// GetStats reads from the serving backend
+func (d *DualStore) GetStats(ctx context.Context) (*VectorStats, error) {
+	serving, _ := d.reader()
+	return serving.GetStats(ctx)
+}
+
+// CorpusStats reads from the serving backend
+func (d *DualStore) CorpusStats(ctx context.Context, options *CorpusStatsOptions) (*CorpusStats, error) {
+	serving, _ := d.reader()
+	return serving.CorpusStats(ctx, options)
+}
+
+// CorpusVersion identifies the serving backend's corpus
+func (d *DualStore) CorpusVersion(ctx context.Context) (string, error) {
+	serving, _ := d.reader()
+	return serving.CorpusVersion(ctx)
+}
+
+// CreateIndex creates indexes on both backends
+func (d *DualStore) CreateIndex(ctx context.Context) error {
+	if err := d.primary.CreateIndex(ctx); err != nil {
+		return err
+	}
+	if err := d.secondary.CreateIndex(ctx); err != nil {
+		d.logger.Warn("Secondary index creation failed", zap.Error(err))
+	}
+	return nil
+}
+
+// ReindexVectors rebuilds the index on both backends
+func (d *DualStore) ReindexVectors(ctx context.Context) error {
+	if err := d.primary.ReindexVectors(ctx); err != nil {
+		return err
+	}
+	if err := d.secondary.ReindexVectors(ctx); err != nil {
+		d.logger.Warn("Secondary index rebuild failed", zap.Error(err))
+	}
+	return nil
+}
+
+// DropIndex drops the index on both backends
+func (d *DualStore) DropIndex(ctx context.Context) error {
+	if err := d.primary.DropIndex(ctx); err != nil {
+		return err
+	}
+	if err := d.secondary.DropIndex(ctx); err != nil {
+		d.logger.Warn("Secondary index drop failed", zap.Error(err))
+	}
+	return nil
+}
+
+// Indexes lists the serving backend's indexes
+func (d *DualStore) Indexes(ctx context.Context) ([]IndexInfo, error) {
+	serving, _ := d.reader()
+	return serving.Indexes(ctx)
+}
+
+// Ping checks the serving backend; secondary failures only surface in the
+// migration report
+func (d *DualStore) Ping(ctx context.Context) error {
+	serving, _ := d.reader()
+	return serving.Ping(ctx)
+}
+
+// PoolStats reports both backends' pools, labelled primary and secondary
+func (d *DualStore) PoolStats() []PoolStats {
+	var stats []PoolStats
+	for _, pool := range []struct {
+		name    string
+		backend Backend
+	}{{"primary", d.primary}, {"secondary", d.secondary}} {
+		for _, s := range pool.backend.PoolStats() {
+			s.Pool = pool.name
+			stats = append(stats, s)
+		}
+	}
+	return stats
+}
+
+// Close logs the final divergence report and closes both backends
+func (d *DualStore) Close() error {
+	report := d.Report()
+	d.logger.Info("Dual-write migration divergence",
+		zap.Int64("writes", report.Writes),
+		zap.Int64("secondary_write_errors", report.SecondaryWriteErrors),
+		zap.Int64("secondary_skipped", report.SecondarySkipped),
+		zap.Int64("compared", report.Compared),
+		zap.Int64("dropped_compares", report.DroppedCompares),
+		zap.Int64("top1_mismatches", report.Top1Mismatches),
+		zap.Float64("avg_overlap", report.AvgOverlap))
+
+	return errors.Join(d.primary.Close(), d.secondary.Close())
+}
+
+// Report returns the divergence observed so far
+func (d *DualStore) Report() DivergenceReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	report := d.report
+	if report.Compared > 0 {
+		report.AvgOverlap = d.overlapSum / float64(report.Compared)
+		report.AvgShadowLatencyMs = d.shadowTimeMs / float64(report.Compared)
+	}
+	return report
+}
+
+func (d *DualStore) recordWrite(secondaryErr error) {
+	d.mu.Lock()
+	d.report.Writes++
+	if secondaryErr != nil {
+		d.report.SecondaryWriteErrors++
+	}
+	d.mu.Unlock()
+
+	if secondaryErr != nil {
+		d.logger.Warn("Secondary write failed", zap.Error(secondaryErr))
+	}
+}
+
+// recordBatchWrite records a mirrored batch. Both backends skip rows whose
+// text_hash they already hold, so a secondary that inserted fewer rows than
+// the primary is counted as skipped rather than failed.
+func (d *DualStore) recordBatchWrite(primary, secondary *BatchInsertResult, secondaryErr error) {
+	if secondaryErr == nil && primary != nil && secondary != nil && secondary.Inserted < primary.Inserted {
+		d.mu.Lock()
+		d.report.SecondarySkipped += primary.Inserted - secondary.Inserted
+		d.mu.Unlock()
+	}
+	d.recordWrite(secondaryErr)
+}
+
+func (d *DualStore) shouldCompare() bool {
+	if !d.options.CompareReads {
+		return false
+	}
+	if d.options.CompareSampleRate >= 1 {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.rng.Float64() < d.options.CompareSampleRate
+}
+
+// shadow starts a comparison unless MaxCompares are already running, in
+// which case the sample is dropped so a slow shadow backend cannot pile up
+// goroutines
+func (d *DualStore) shadow(served []*SimilarityResult, shadowSearch func(context.Context) ([]*SimilarityResult, error)) {
+	select {
+	case d.compares <- struct{}{}:
+	default:
+		d.mu.Lock()
+		d.report.DroppedCompares++
+		d.mu.Unlock()
+		return
+	}
+	go func() {
+		defer func() { <-d.compares }()
+		d.compare(served, shadowSearch)
+	}()
+}
+
+// compare runs the shadow search and records how its results differ.
+// Vectors are matched by text since ids are backend-specific.
+func (d *DualStore) compare(served []*SimilarityResult, shadowSearch func(context.Context) ([]*SimilarityResult, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.options.CompareTimeout)
+	defer cancel()
+
+	start := time.Now()
+	shadow, err := shadowSearch(ctx)
+	elapsed := time.Since(start)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		d.report.ShadowReadErrors++
+		return
+	}
+
+	d.report.Compared++
+	d.shadowTimeMs += float64(elapsed.Nanoseconds()) / 1e6
+
+	if len(served) != len(shadow) {
+		d.report.CountMismatches++
+	}
+	if len(served) > 0 && len(shadow) > 0 && resultKey(served[0]) != resultKey(shadow[0]) {
+		d.report.Top1Mismatches++
+	}
+
+	d.overlapSum += overlap(served, shadow)
+}
+
+func resultKey(r *SimilarityResult) string {
+	if r.Vector == nil {
+		return ""
+	}
+	if r.Vector.TextHash != "" {
+		return r.Vector.TextHash
+	}
+	return r.Vector.Text
+}
+
+// overlap is the fraction of results shared by both lists; two empty lists agree
+func overlap(a, b []*SimilarityResult) float64 {
+	larger := len(a)
+	if len(b) > larger {
+		larger = len(b)
+	}
+	if larger == 0 {
+		return 1
+	}
+
+	seen := make(map[string]bool, len(a))
+	for _, r := range a {
+		seen[resultKey(r)] = true
+	}
+	shared := 0
+	for _, r := range b {
+		if seen[resultKey(r)] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(larger)
+}
+
+// OpenBackend opens the primary store, wrapping it in a DualStore when a
+// secondary migration target is configured
+func OpenBackend(primary *Config, secondary *Config, options DualOptions, logger *zap.Logger) (Backend, error) {
+	store, err := NewStore(primary, logger)
+	if err != nil {
+		return nil, err
+	}
+	if secondary == nil {
+		return store, nil
+	}
+
+	target, err := NewStore(secondary, logger.With(zap.String("store", "secondary")))
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to open migration target: %w", err)
+	}
+
+	logger.Info("Dual-write migration mode enabled",
+		zap.Bool("read_from_secondary", options.ReadFromSecondary),
+		zap.Bool("compare_reads", options.CompareReads),
+		zap.Float64("compare_sample_rate", options.CompareSampleRate))
+
+	return NewDualStore(store, target, options, logger), nil
+}
+
+// ConfigFromDatabase converts application database settings to store settings
+func ConfigFromDatabase(db config.DatabaseConfig) *Config {
+	return &Config{
+		DatabaseURL:     db.DatabaseURL,
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: db.ConnMaxLifetime,
+		ConnMaxIdleTime: db.ConnMaxIdleTime,
+	}
+}
+
+// OpenFromConfig opens the configured store, including any migration target
+func OpenFromConfig(cfg config.VectorSecurityConfig, logger *zap.Logger) (Backend, error) {
+	reducer, err := NewReducer(cfg.Reduction.Method, cfg.Reduction.Dimensions, cfg.Reduction.PCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up dimension reduction: %w", err)
+	}
+
+	index := IndexOptions{
+		Type:           cfg.Index.Type,
+		M:              cfg.Index.M,
+		EfConstruction: cfg.Index.EfConstruction,
+		EfSearch:       cfg.Index.EfSearch,
+		Lists:          cfg.Index.Lists,
+		Probes:         cfg.Index.Probes,
+		MinVectors:     cfg.Index.MinVectors,
+	}
+
+	textHash := textnorm.Options(cfg.Normalization.Dedup)
+
+	primary := ConfigFromDatabase(cfg.Database)
+	primary.Reducer = reducer
+	primary.Index = index
+	primary.TextHash = textHash
+
+	var secondary *Config
+	if cfg.Migration.Enabled {
+		secondary = ConfigFromDatabase(cfg.Migration.Secondary)
+		secondary.Reducer = reducer
+		secondary.Index = index
+		secondary.TextHash = textHash
+	}
+	return OpenBackend(primary, secondary, DualOptions{
+		ReadFromSecondary: cfg.Migration.ReadFromSecondary,
+		CompareReads:      cfg.Migration.CompareReads,
+		CompareSampleRate: cfg.Migration.CompareSampleRate,
+	}, logger)
+}
@@ -0,0 +1,80 @@
+package vector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// stubBackend answers inserts and searches with canned results; searches
+// block until release is closed
+type stubBackend struct {
+	Backend
+	inserted int64
+	release  chan struct{}
+}
+
+func (b *stubBackend) BatchInsert(_ context.Context, vectors []*SecurityVector) (*BatchInsertResult, error) {
+	return &BatchInsertResult{Inserted: b.inserted, Failed: int64(len(vectors)) - b.inserted}, nil
+}
+
+func (b *stubBackend) FindSimilar(ctx context.Context, _ []float32, _ *SearchOptions) ([]*SimilarityResult, error) {
+	if b.release != nil {
+		select {
+		case <-b.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, nil
+}
+
+// TestDualStoreSkippedRowsAreNotErrors checks rows the secondary already
+// holds are reported as skipped, not as failed writes
+func TestDualStoreSkippedRowsAreNotErrors(t *testing.T) {
+	d := NewDualStore(&stubBackend{inserted: 4}, &stubBackend{inserted: 1}, DualOptions{}, zap.NewNop())
+	vectors := make([]*SecurityVector, 4)
+	for i := range vectors {
+		vectors[i] = &SecurityVector{Text: "t"}
+	}
+	if _, err := d.BatchInsert(context.Background(), vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.Report()
+	if report.SecondaryWriteErrors != 0 || report.SecondarySkipped != 3 {
+		t.Fatalf("write errors %d, skipped %d; want 0 and 3", report.SecondaryWriteErrors, report.SecondarySkipped)
+	}
+}
+
+// TestDualStoreBoundsShadowReads checks samples beyond MaxCompares are
+// dropped instead of starting more goroutines
+func TestDualStoreBoundsShadowReads(t *testing.T) {
+	shadow := &stubBackend{release: make(chan struct{})}
+	d := NewDualStore(&stubBackend{}, shadow, DualOptions{
+		CompareReads:      true,
+		CompareSampleRate: 1,
+		CompareTimeout:    time.Minute,
+		MaxCompares:       2,
+	}, zap.NewNop())
+
+	for range 5 {
+		if _, err := d.FindSimilar(context.Background(), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dropped := d.Report().DroppedCompares; dropped != 3 {
+		t.Fatalf("dropped %d samples, want 3", dropped)
+	}
+
+	close(shadow.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Report().Compared != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("compared %d searches, want 2", d.Report().Compared)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,101 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"go.uber.org/zap"
+)
+
+// QueryPlan is the captured execution plan of one similarity search, for
+// diagnosing slow searches or searches that ignore the vector index
+type QueryPlan struct {
+	Query       string            `json:"query"`
+	Plan        json.RawMessage   `json:"plan"` // EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) output
+	IndexesUsed []string          `json:"indexes_used"`
+	SeqScan     bool              `json:"seq_scan"` // security_vectors was read without an index
+	Rows        int64             `json:"rows"`
+	PlanningMs  float64           `json:"planning_ms"`
+	ExecutionMs float64           `json:"execution_ms"`
+	Settings    map[string]string `json:"settings"` // index tuning in effect (probes, ef_search)
+}
+
+// ExplainSimilar runs the FindSimilar query for embedding under EXPLAIN
+// ANALYZE and reports the plan and which indexes it used. The search really
+// executes, so it costs as much as the search itself.
+func (s *Store) ExplainSimilar(ctx context.Context, embedding []float32, options *SearchOptions) (*QueryPlan, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = defaultSearchOptions()
+	}
+	query, args := similarityQuery(s.reduce(embedding), options)
+
+	var raw []byte
+	if err := s.db.QueryRowContext(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+		return nil, fmt.Errorf("explain similarity search failed: %w", err)
+	}
+
+	var explained []struct {
+		Plan          planNode `json:"Plan"`
+		PlanningTime  float64  `json:"Planning Time"`
+		ExecutionTime float64  `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(raw, &explained); err != nil || len(explained) == 0 {
+		return nil, fmt.Errorf("failed to parse query plan: %v", err)
+	}
+
+	plan := &QueryPlan{
+		Query:       strings.TrimSpace(query),
+		Plan:        raw,
+		Rows:        explained[0].Plan.ActualRows,
+		PlanningMs:  explained[0].PlanningTime,
+		ExecutionMs: explained[0].ExecutionTime,
+		Settings:    make(map[string]string),
+	}
+	explained[0].Plan.walk(func(node *planNode) {
+		if node.IndexName != "" {
+			plan.IndexesUsed = append(plan.IndexesUsed, node.IndexName)
+		}
+		if node.NodeType == "Seq Scan" && node.RelationName == "security_vectors" {
+			plan.SeqScan = true
+		}
+	})
+
+	// pgvector registers these settings on load; missing ones read as NULL
+	for _, name := range []string{"ivfflat.probes", "hnsw.ef_search"} {
+		var value sql.NullString
+		if err := s.db.QueryRowContext(ctx, "SELECT current_setting($1, true)", name).Scan(&value); err == nil && value.Valid {
+			plan.Settings[name] = value.String
+		}
+	}
+
+	s.logger.Debug("Similarity search explained",
+		zap.Strings("indexes_used", plan.IndexesUsed),
+		zap.Bool("seq_scan", plan.SeqScan),
+		zap.Float64("execution_ms", plan.ExecutionMs))
+
+	return plan, nil
+}
+
+// planNode is the subset of a Postgres JSON plan node needed to find index use
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	ActualRows   int64      `json:"Actual Rows"`
+	Plans        []planNode `json:"Plans"`
+}
+
+func (n *planNode) walk(fn func(*planNode)) {
+	fn(n)
+	for i := range n.Plans {
+		n.Plans[i].walk(fn)
+	}
+}
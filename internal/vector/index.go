@@ -0,0 +1,293 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"go.uber.org/zap"
+)
+
+// embeddingIndex is the name of the approximate nearest-neighbour index on
+// security_vectors.embedding
+const embeddingIndex = "idx_security_vectors_embedding"
+
+// IndexOptions selects the embedding index type and its tuning. Zero search
+// parameters leave the server defaults in place.
+type IndexOptions struct {
+	Type           string // "ivfflat" or "hnsw"
+	M              int    // hnsw links per node
+	EfConstruction int    // hnsw build candidate list
+	EfSearch       int    // hnsw search candidate list
+	Lists          int    // ivfflat clusters; 0 sizes them from the row count
+	Probes         int    // ivfflat clusters scanned per search
+	MinVectors     int64  // CreateIndex skips smaller corpora
+}
+
+// IndexInfo describes one index on security_vectors
+type IndexInfo struct {
+	Name       string `json:"name"`
+	Method     string `json:"method"` // btree, gin, ivfflat, hnsw
+	Definition string `json:"definition"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Valid      bool   `json:"valid"` // false while a concurrent build runs or after one failed
+	Scans      int64  `json:"scans"`
+}
+
+// CreateIndex builds the configured embedding index when the corpus is large
+// enough and none exists yet. An existing index of another type is kept;
+// ReindexVectors replaces it.
+func (s *Store) CreateIndex(ctx context.Context) error {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return err
+	}
+
+	count, err := s.countVectors(ctx)
+	if err != nil {
+		return err
+	}
+	if count < s.index.MinVectors {
+		s.logger.Info("Skipping index creation, not enough vectors", zap.Int64("count", count), zap.Int64("min_vectors", s.index.MinVectors))
+		return nil
+	}
+
+	existing, err := s.embeddingIndexInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if existing.Method != s.index.Type {
+			s.logger.Warn("Embedding index type differs from configuration; run llm-sentinel-etl index rebuild to replace it",
+				zap.String("index", existing.Name),
+				zap.String("current", existing.Method),
+				zap.String("configured", s.index.Type))
+		}
+		return nil
+	}
+
+	s.logger.Info("Creating vector similarity index...", zap.String("type", s.index.Type), zap.Int64("vector_count", count))
+	start := time.Now()
+	if _, err := s.db.ExecContext(ctx, s.indexDDL(embeddingIndex, count)); err != nil {
+		return fmt.Errorf("failed to create vector index: %w", err)
+	}
+
+	s.logger.Info("Vector similarity index created successfully", zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// ReindexVectors rebuilds the embedding index with the configured type and
+// parameters, regardless of corpus size. The new index is built alongside
+// the old one and swapped in, so searches keep an index throughout.
+func (s *Store) ReindexVectors(ctx context.Context) error {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return err
+	}
+
+	count, err := s.countVectors(ctx)
+	if err != nil {
+		return err
+	}
+
+	building := embeddingIndex + "_new"
+	retired := embeddingIndex + "_old"
+
+	// Leftovers of an interrupted rebuild are invalid and must go first
+	for _, name := range []string{building, retired} {
+		if _, err := s.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+name); err != nil {
+			return fmt.Errorf("failed to drop leftover index %s: %w", name, err)
+		}
+	}
+
+	s.logger.Info("Rebuilding vector similarity index...", zap.String("type", s.index.Type), zap.Int64("vector_count", count))
+	start := time.Now()
+	if _, err := s.db.ExecContext(ctx, s.indexDDL(building, count)); err != nil {
+		s.db.ExecContext(context.Background(), "DROP INDEX CONCURRENTLY IF EXISTS "+building)
+		return fmt.Errorf("failed to build vector index: %w", err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to swap vector index: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "ALTER INDEX IF EXISTS "+embeddingIndex+" RENAME TO "+retired); err != nil {
+		return fmt.Errorf("failed to retire old vector index: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "ALTER INDEX "+building+" RENAME TO "+embeddingIndex); err != nil {
+		return fmt.Errorf("failed to install new vector index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to swap vector index: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+retired); err != nil {
+		s.logger.Warn("Failed to drop retired vector index", zap.String("index", retired), zap.Error(err))
+	}
+
+	s.logger.Info("Vector similarity index rebuilt", zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// DropIndex removes the embedding index; searches fall back to exact scans
+func (s *Store) DropIndex(ctx context.Context) error {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+embeddingIndex); err != nil {
+		return fmt.Errorf("failed to drop vector index: %w", err)
+	}
+	s.logger.Info("Vector similarity index dropped")
+	return nil
+}
+
+// Indexes lists the indexes on security_vectors with their size and use
+func (s *Store) Indexes(ctx context.Context) ([]IndexInfo, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.relname, am.amname, pg_get_indexdef(i.indexrelid),
+			pg_relation_size(i.indexrelid), i.indisvalid, COALESCE(st.idx_scan, 0)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = c.relam
+		LEFT JOIN pg_stat_user_indexes st ON st.indexrelid = i.indexrelid
+		WHERE i.indrelid = 'security_vectors'::regclass
+		ORDER BY c.relname`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var info IndexInfo
+		if err := rows.Scan(&info.Name, &info.Method, &info.Definition, &info.SizeBytes, &info.Valid, &info.Scans); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		indexes = append(indexes, info)
+	}
+	return indexes, rows.Err()
+}
+
+// embeddingIndexInfo returns the embedding index, or nil when there is none
+func (s *Store) embeddingIndexInfo(ctx context.Context) (*IndexInfo, error) {
+	indexes, err := s.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range indexes {
+		if indexes[i].Name == embeddingIndex {
+			return &indexes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) countVectors(ctx context.Context) (int64, error) {
+	var count int64
+	if err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM security_vectors"); err != nil {
+		return 0, fmt.Errorf("failed to count vectors: %w", err)
+	}
+	return count, nil
+}
+
+// indexDDL builds the statement creating the configured index as name
+func (s *Store) indexDDL(name string, rows int64) string {
+	if s.index.Type == "hnsw" {
+		return fmt.Sprintf(`CREATE INDEX CONCURRENTLY %s ON security_vectors
+			USING hnsw (embedding vector_cosine_ops) WITH (m = %d, ef_construction = %d)`,
+			name, s.index.M, s.index.EfConstruction)
+	}
+	return fmt.Sprintf(`CREATE INDEX CONCURRENTLY %s ON security_vectors
+		USING ivfflat (embedding vector_cosine_ops) WITH (lists = %d)`,
+		name, ivfflatLists(s.index.Lists, rows))
+}
+
+// ivfflatLists returns the configured list count, or pgvector's guidance
+// when it is 0: rows/1000 up to a million rows and sqrt(rows) beyond
+func ivfflatLists(configured int, rows int64) int {
+	if configured > 0 {
+		return configured
+	}
+	lists := int(rows / 1000)
+	if rows > 1000000 {
+		lists = int(math.Sqrt(float64(rows)))
+	}
+	if lists < 10 {
+		lists = 10
+	}
+	return lists
+}
+
+// searchSettings are the session settings applying the index search
+// parameters
+func (o IndexOptions) searchSettings() []string {
+	var settings []string
+	if o.Probes > 0 {
+		settings = append(settings, fmt.Sprintf("SET ivfflat.probes = %d", o.Probes))
+	}
+	if o.EfSearch > 0 {
+		settings = append(settings, fmt.Sprintf("SET hnsw.ef_search = %d", o.EfSearch))
+	}
+	return settings
+}
+
+// openDB connects to Postgres in binary parameter mode, running settings on
+// every new connection so each pooled session searches with the same
+// parameters
+func openDB(dsn string, settings []string) (*sqlx.DB, error) {
+	dsn, err := binaryParametersDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	var db *sqlx.DB
+	if len(settings) == 0 {
+		db = sqlx.NewDb(sql.OpenDB(connector), "postgres")
+	} else {
+		db = sqlx.NewDb(sql.OpenDB(&sessionConnector{Connector: connector, settings: settings}), "postgres")
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// sessionConnector applies session settings to each connection it opens
+type sessionConnector struct {
+	driver.Connector
+	settings []string
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("postgres driver cannot apply session settings")
+	}
+	for _, setting := range c.settings {
+		if _, err := execer.ExecContext(ctx, setting, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to apply %q: %w", setting, err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,160 @@
+package vector
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PoolStats is a snapshot of one database connection pool
+type PoolStats struct {
+	Pool              string        `json:"pool"` // primary or secondary
+	MaxOpen           int           `json:"max_open"`
+	Open              int           `json:"open"`
+	InUse             int           `json:"in_use"`
+	Idle              int           `json:"idle"`
+	WaitCount         int64         `json:"wait_count"`    // total waits for a connection since start
+	WaitDuration      time.Duration `json:"wait_duration"` // total time spent waiting since start
+	MaxIdleClosed     int64         `json:"max_idle_closed"`
+	MaxLifetimeClosed int64         `json:"max_lifetime_closed"`
+}
+
+// PoolStats returns the store's connection pool statistics
+func (s *Store) PoolStats() []PoolStats {
+	stats := s.db.Stats()
+	return []PoolStats{{
+		Pool:              "primary",
+		MaxOpen:           stats.MaxOpenConnections,
+		Open:              stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDuration:      stats.WaitDuration,
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}}
+}
+
+// PoolMonitorOptions sets when a pool counts as saturated. A zero threshold
+// disables that signal.
+type PoolMonitorOptions struct {
+	SampleInterval time.Duration
+	MaxWaits       int64         // connection waits per interval
+	MaxWaitTime    time.Duration // total wait per interval
+}
+
+// PoolStatus is a pool's latest snapshot and whether its last sample interval
+// exceeded the wait thresholds
+type PoolStatus struct {
+	PoolStats
+	Saturated      bool          `json:"saturated"`
+	IntervalWaits  int64         `json:"interval_waits"`
+	IntervalWaited time.Duration `json:"interval_wait_duration"`
+}
+
+// PoolMonitor samples connection pool stats and warns when callers queue for
+// connections, so pool sizing problems show up before they become latency
+// incidents
+type PoolMonitor struct {
+	backend Backend
+	options PoolMonitorOptions
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	previous map[string]PoolStats
+	statuses []PoolStatus
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPoolMonitor creates a monitor for backend's pools
+func NewPoolMonitor(backend Backend, options PoolMonitorOptions, logger *zap.Logger) *PoolMonitor {
+	if options.SampleInterval <= 0 {
+		options.SampleInterval = 30 * time.Second
+	}
+	return &PoolMonitor{
+		backend:  backend,
+		options:  options,
+		logger:   logger,
+		previous: make(map[string]PoolStats),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins periodic sampling
+func (m *PoolMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.options.SampleInterval)
+		defer ticker.Stop()
+
+		for {
+			m.Sample()
+
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops sampling
+func (m *PoolMonitor) Close() {
+	close(m.stop)
+	m.wg.Wait()
+}
+
+// Sample compares current pool stats with the previous sample and logs
+// saturation changes
+func (m *PoolMonitor) Sample() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wasSaturated := make(map[string]bool, len(m.statuses))
+	for _, status := range m.statuses {
+		wasSaturated[status.Pool] = status.Saturated
+	}
+
+	current := m.backend.PoolStats()
+	statuses := make([]PoolStatus, 0, len(current))
+	for _, stats := range current {
+		status := PoolStatus{PoolStats: stats}
+		if previous, ok := m.previous[stats.Pool]; ok {
+			status.IntervalWaits = stats.WaitCount - previous.WaitCount
+			status.IntervalWaited = stats.WaitDuration - previous.WaitDuration
+			status.Saturated = (m.options.MaxWaits > 0 && status.IntervalWaits > m.options.MaxWaits) ||
+				(m.options.MaxWaitTime > 0 && status.IntervalWaited > m.options.MaxWaitTime)
+		}
+		m.previous[stats.Pool] = stats
+		statuses = append(statuses, status)
+
+		fields := []zap.Field{
+			zap.String("pool", stats.Pool),
+			zap.Int("in_use", stats.InUse),
+			zap.Int("max_open", stats.MaxOpen),
+			zap.Int64("waits", status.IntervalWaits),
+			zap.Duration("wait_duration", status.IntervalWaited),
+			zap.Duration("interval", m.options.SampleInterval),
+		}
+		switch {
+		case status.Saturated:
+			m.logger.Warn("Database connection pool saturated; callers are waiting for connections", fields...)
+		case wasSaturated[stats.Pool]:
+			m.logger.Info("Database connection pool recovered", fields...)
+		}
+	}
+	m.statuses = statuses
+}
+
+// Statuses returns the latest sample of each pool
+func (m *PoolMonitor) Statuses() []PoolStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]PoolStatus(nil), m.statuses...)
+}
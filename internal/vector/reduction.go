@@ -0,0 +1,263 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Reduction methods
+const (
+	ReductionNone     = "none"
+	ReductionTruncate = "truncate" // Matryoshka-style: keep the leading dimensions
+	ReductionPCA      = "pca"      // project onto principal components fit at ingest
+)
+
+// Reducer shrinks embeddings before they are stored or used as a search
+// probe. The store applies the same reducer on both paths so stored vectors
+// and queries always share a space.
+type Reducer interface {
+	Reduce(embedding []float32) []float32
+	Dimensions() int
+}
+
+// NewReducer builds the reducer for a method. It returns nil for no reduction.
+func NewReducer(method string, dimensions int, pcaPath string) (Reducer, error) {
+	switch method {
+	case "", ReductionNone:
+		return nil, nil
+	case ReductionTruncate:
+		if dimensions <= 0 {
+			return nil, fmt.Errorf("truncation requires a positive dimension count")
+		}
+		return truncateReducer(dimensions), nil
+	case ReductionPCA:
+		pca, err := LoadPCA(pcaPath)
+		if err != nil {
+			return nil, err
+		}
+		if pca.Dimensions() != dimensions {
+			return nil, fmt.Errorf("PCA projection %s has %d components, expected %d", pcaPath, pca.Dimensions(), dimensions)
+		}
+		return pca, nil
+	default:
+		return nil, fmt.Errorf("unknown reduction method: %s", method)
+	}
+}
+
+// truncateReducer keeps the leading dimensions and renormalizes. This suits
+// Matryoshka-trained models, whose leading dimensions carry most of the signal.
+type truncateReducer int
+
+func (t truncateReducer) Reduce(embedding []float32) []float32 {
+	n := int(t)
+	if len(embedding) < n {
+		n = len(embedding)
+	}
+	reduced := make([]float32, n)
+	copy(reduced, embedding[:n])
+	return normalize(reduced)
+}
+
+func (t truncateReducer) Dimensions() int {
+	return int(t)
+}
+
+// PCA projects embeddings onto the principal components of a sample corpus
+type PCA struct {
+	Mean       []float32   `json:"mean"`
+	Components [][]float32 `json:"components"` // unit vectors, highest variance first
+}
+
+// Reduce centers the embedding and projects it onto the components
+func (p *PCA) Reduce(embedding []float32) []float32 {
+	reduced := make([]float32, len(p.Components))
+	for i, component := range p.Components {
+		var sum float64
+		for j, value := range component {
+			if j < len(embedding) {
+				sum += float64(value) * float64(embedding[j]-p.Mean[j])
+			}
+		}
+		reduced[i] = float32(sum)
+	}
+	return normalize(reduced)
+}
+
+// Dimensions returns the number of components
+func (p *PCA) Dimensions() int {
+	return len(p.Components)
+}
+
+// FitPCA computes the top components of samples by subspace iteration on
+// their covariance matrix
+func FitPCA(samples [][]float32, dimensions int) (*PCA, error) {
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("PCA needs at least 2 samples, got %d", len(samples))
+	}
+	width := len(samples[0])
+	if dimensions <= 0 || dimensions > width {
+		return nil, fmt.Errorf("invalid PCA dimensions: %d (must be between 1 and %d)", dimensions, width)
+	}
+
+	mean := make([]float64, width)
+	for _, sample := range samples {
+		if len(sample) != width {
+			return nil, fmt.Errorf("inconsistent sample dimensions: %d and %d", width, len(sample))
+		}
+		for j, value := range sample {
+			mean[j] += float64(value)
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(len(samples))
+	}
+
+	covariance := make([][]float64, width)
+	for i := range covariance {
+		covariance[i] = make([]float64, width)
+	}
+	centered := make([]float64, width)
+	for _, sample := range samples {
+		for j, value := range sample {
+			centered[j] = float64(value) - mean[j]
+		}
+		for i := 0; i < width; i++ {
+			if centered[i] == 0 {
+				continue
+			}
+			row := covariance[i]
+			for j := i; j < width; j++ {
+				row[j] += centered[i] * centered[j]
+			}
+		}
+	}
+	for i := 0; i < width; i++ {
+		for j := i; j < width; j++ {
+			covariance[i][j] /= float64(len(samples) - 1)
+			covariance[j][i] = covariance[i][j]
+		}
+	}
+
+	// Deterministic start so refits of the same corpus agree
+	basis := make([][]float64, dimensions)
+	for k := range basis {
+		basis[k] = make([]float64, width)
+		for j := range basis[k] {
+			basis[k][j] = math.Sin(float64((k+1)*(j+1))) + 0.01
+		}
+	}
+	orthonormalize(basis)
+
+	next := make([][]float64, dimensions)
+	for k := range next {
+		next[k] = make([]float64, width)
+	}
+	for iteration := 0; iteration < 100; iteration++ {
+		for k, vector := range basis {
+			for i := 0; i < width; i++ {
+				var sum float64
+				for j, value := range covariance[i] {
+					sum += value * vector[j]
+				}
+				next[k][i] = sum
+			}
+		}
+		basis, next = next, basis
+		orthonormalize(basis)
+	}
+
+	pca := &PCA{Mean: make([]float32, width), Components: make([][]float32, dimensions)}
+	for j, value := range mean {
+		pca.Mean[j] = float32(value)
+	}
+	for k, vector := range basis {
+		pca.Components[k] = make([]float32, width)
+		for j, value := range vector {
+			pca.Components[k][j] = float32(value)
+		}
+	}
+	return pca, nil
+}
+
+// LoadPCA reads a projection written by Save
+func LoadPCA(path string) (*PCA, error) {
+	if path == "" {
+		return nil, fmt.Errorf("PCA reduction requires a projection file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PCA projection: %w", err)
+	}
+	var pca PCA
+	if err := json.Unmarshal(data, &pca); err != nil {
+		return nil, fmt.Errorf("failed to parse PCA projection %s: %w", path, err)
+	}
+	for _, component := range pca.Components {
+		if len(component) != len(pca.Mean) {
+			return nil, fmt.Errorf("malformed PCA projection %s: component width %d, mean width %d", path, len(component), len(pca.Mean))
+		}
+	}
+	return &pca, nil
+}
+
+// Save writes the projection atomically so a running server never loads a
+// partial file
+func (p *PCA) Save(path string) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create PCA directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write PCA projection: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// orthonormalize applies modified Gram-Schmidt to the vectors in place
+func orthonormalize(vectors [][]float64) {
+	for k := range vectors {
+		for prev := 0; prev < k; prev++ {
+			var dot float64
+			for j := range vectors[k] {
+				dot += vectors[k][j] * vectors[prev][j]
+			}
+			for j := range vectors[k] {
+				vectors[k][j] -= dot * vectors[prev][j]
+			}
+		}
+		var norm float64
+		for _, value := range vectors[k] {
+			norm += value * value
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			continue
+		}
+		for j := range vectors[k] {
+			vectors[k][j] /= norm
+		}
+	}
+}
+
+// normalize scales v to unit length so cosine distances stay comparable
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, value := range v {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
@@ -0,0 +1,283 @@
+package vector
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshotMagic opens every corpus snapshot; the trailing digit is the
+// layout version
+const snapshotMagic = "SNTLCRP1"
+
+// maxSnapshotString bounds a string read from a snapshot, so a corrupt
+// length cannot allocate unbounded memory
+const maxSnapshotString = 16 * 1024 * 1024
+
+// corpus is an in-memory copy of the vector table, searched exactly by
+// cosine similarity. It holds only the fields a similarity search returns.
+type corpus struct {
+	version    string // CorpusVersion of the database it was copied from
+	dimensions int
+	vectors    []*SecurityVector
+	norms      []float64
+}
+
+// newCorpus indexes vectors, which must all have the same dimensions
+func newCorpus(version string, vectors []*SecurityVector) (*corpus, error) {
+	c := &corpus{version: version, vectors: vectors, norms: make([]float64, len(vectors))}
+	for i, v := range vectors {
+		if i == 0 {
+			c.dimensions = len(v.Embedding)
+		} else if len(v.Embedding) != c.dimensions {
+			return nil, fmt.Errorf("vector %d has %d dimensions; corpus has %d", v.ID, len(v.Embedding), c.dimensions)
+		}
+		c.norms[i] = norm(v.Embedding)
+	}
+	return c, nil
+}
+
+// copyCorpus reads the whole vector table of backend
+func copyCorpus(ctx context.Context, backend Backend) (*corpus, error) {
+	version, err := backend.CorpusVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors []*SecurityVector
+	err = backend.IterateVectors(ctx, &PageOptions{PageSize: 5000, IncludeEmbedding: true}, func(v *SecurityVector) error {
+		vectors = append(vectors, &SecurityVector{
+			ID:        v.ID,
+			Text:      v.Text,
+			LabelText: v.LabelText,
+			Label:     v.Label,
+			Embedding: v.Embedding,
+			CreatedAt: v.CreatedAt,
+			UpdatedAt: v.UpdatedAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy corpus: %w", err)
+	}
+	return newCorpus(version, vectors)
+}
+
+// search returns the matches of options in (distance, id) order, as the
+// database would. embedding must already be reduced like the stored vectors.
+func (c *corpus) search(embedding []float32, options *SearchOptions) ([]*SimilarityResult, error) {
+	if len(c.vectors) > 0 && len(embedding) != c.dimensions {
+		return nil, fmt.Errorf("query has %d dimensions; corpus snapshot has %d", len(embedding), c.dimensions)
+	}
+	queryNorm := norm(embedding)
+	if queryNorm == 0 {
+		return nil, nil
+	}
+
+	var results []*SimilarityResult
+	for i, v := range c.vectors {
+		if options.LabelFilter != nil && v.Label != *options.LabelFilter {
+			continue
+		}
+		if options.LabelTextFilter != "" && v.LabelText != options.LabelTextFilter {
+			continue
+		}
+		if c.norms[i] == 0 {
+			continue
+		}
+
+		var dot float64
+		for j, x := range embedding {
+			dot += float64(x) * float64(v.Embedding[j])
+		}
+		distance := 1 - dot/(queryNorm*c.norms[i])
+		if float32(1-distance) < options.MinSimilarity {
+			continue
+		}
+		if after := options.After; after != nil && (distance < after.Distance || distance == after.Distance && v.ID <= after.ID) {
+			continue
+		}
+
+		results = append(results, &SimilarityResult{
+			Vector:     v,
+			Similarity: float32(1 - distance),
+			Distance:   float32(distance),
+			distance:   distance,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].distance != results[j].distance {
+			return results[i].distance < results[j].distance
+		}
+		return results[i].Vector.ID < results[j].Vector.ID
+	})
+	if options.Limit > 0 && len(results) > options.Limit {
+		results = results[:options.Limit]
+	}
+	return results, nil
+}
+
+func norm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+// save writes the corpus to path atomically: a little-endian header of
+// magic, dimensions, count and version, then each vector's id, label,
+// texts, timestamps and embedding
+func (c *corpus) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create corpus snapshot: %w", err)
+	}
+
+	w := bufio.NewWriterSize(file, 1<<20)
+	var buf []byte
+	buf = append(buf, snapshotMagic...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(c.dimensions))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(c.vectors)))
+	buf = appendString(buf, c.version)
+	w.Write(buf)
+
+	for _, v := range c.vectors {
+		buf = buf[:0]
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v.ID))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(v.Label)))
+		buf = appendString(buf, v.Text)
+		buf = appendString(buf, v.LabelText)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(unixNano(v.CreatedAt)))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(unixNano(v.UpdatedAt)))
+		for _, x := range v.Embedding {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(x))
+		}
+		w.Write(buf)
+	}
+
+	err = w.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write corpus snapshot: %w", err)
+	}
+	return nil
+}
+
+// loadCorpus reads a snapshot written by save
+func loadCorpus(path string) (*corpus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(file, 1<<20)
+	header := make([]byte, len(snapshotMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read corpus snapshot header: %w", err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("not a corpus snapshot")
+	}
+	dimensions := int(binary.LittleEndian.Uint32(header[len(snapshotMagic):]))
+	count := binary.LittleEndian.Uint64(header[len(snapshotMagic)+4:])
+	if dimensions > math.MaxUint16 {
+		return nil, fmt.Errorf("corpus snapshot has %d dimensions; pgvector supports at most %d", dimensions, math.MaxUint16)
+	}
+	version, err := readString(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus snapshot header: %w", err)
+	}
+
+	var vectors []*SecurityVector
+	fixed := make([]byte, 12)
+	times := make([]byte, 16)
+	embedding := make([]byte, 4*dimensions)
+	for i := uint64(0); i < count; i++ {
+		v := &SecurityVector{Embedding: make([]float32, dimensions)}
+		if _, err := io.ReadFull(r, fixed); err != nil {
+			return nil, fmt.Errorf("corpus snapshot truncated at vector %d: %w", i, err)
+		}
+		v.ID = int64(binary.LittleEndian.Uint64(fixed))
+		v.Label = int(int32(binary.LittleEndian.Uint32(fixed[8:])))
+		if v.Text, err = readString(r); err != nil {
+			return nil, fmt.Errorf("corpus snapshot truncated at vector %d: %w", i, err)
+		}
+		if v.LabelText, err = readString(r); err != nil {
+			return nil, fmt.Errorf("corpus snapshot truncated at vector %d: %w", i, err)
+		}
+		if _, err := io.ReadFull(r, times); err != nil {
+			return nil, fmt.Errorf("corpus snapshot truncated at vector %d: %w", i, err)
+		}
+		v.CreatedAt = fromUnixNano(int64(binary.LittleEndian.Uint64(times)))
+		v.UpdatedAt = fromUnixNano(int64(binary.LittleEndian.Uint64(times[8:])))
+		if _, err := io.ReadFull(r, embedding); err != nil {
+			return nil, fmt.Errorf("corpus snapshot truncated at vector %d: %w", i, err)
+		}
+		for j := range v.Embedding {
+			v.Embedding[j] = math.Float32frombits(binary.LittleEndian.Uint32(embedding[4*j:]))
+		}
+		vectors = append(vectors, v)
+	}
+	return newCorpus(version, vectors)
+}
+
+// unixNano and fromUnixNano store the zero time as 0, which UnixNano
+// cannot represent
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns).UTC()
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+func readString(r io.Reader) (string, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return "", err
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n > maxSnapshotString {
+		return "", fmt.Errorf("string of %d bytes exceeds the snapshot limit", n)
+	}
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
@@ -0,0 +1,475 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/config"
+	"go.uber.org/zap"
+)
+
+// errNotConnected is returned for database operations while a Standby has
+// not reached its database
+var errNotConnected = errors.New("vector database not connected")
+
+// StandbyOptions configures a Standby
+type StandbyOptions struct {
+	Path      string                  // corpus snapshot file
+	Interval  time.Duration           // how often to reconnect or resync, and save
+	Reducer   Reducer                 // applied to queries, as the store applies it
+	Open      func() (Backend, error) // connects to the database
+	OnConnect func()                  // called when the database is reached after startup
+}
+
+// StandbyOptionsFromConfig converts vector security settings to standby
+// settings, reconnecting with OpenFromConfig
+func StandbyOptionsFromConfig(cfg config.VectorSecurityConfig, logger *zap.Logger) (StandbyOptions, error) {
+	reducer, err := NewReducer(cfg.Reduction.Method, cfg.Reduction.Dimensions, cfg.Reduction.PCAPath)
+	if err != nil {
+		return StandbyOptions{}, err
+	}
+	return StandbyOptions{
+		Path:     cfg.Snapshot.Path,
+		Interval: cfg.Snapshot.Interval,
+		Reducer:  reducer,
+		Open:     func() (Backend, error) { return OpenFromConfig(cfg, logger) },
+	}, nil
+}
+
+// Standby keeps an in-memory copy of the corpus in front of the database,
+// saved to disk after every change. Similarity searches go to the database
+// and fall back to the copy when the database is unreachable, so a restarted
+// instance loads its last snapshot and serves with full protection while it
+// reconnects and resyncs. Hybrid searches served from the copy rank by vector
+// similarity only. Other operations need the database.
+type Standby struct {
+	options StandbyOptions
+	logger  *zap.Logger
+
+	mu       sync.RWMutex
+	db       Backend // nil until connected
+	corpus   *corpus // nil until loaded or copied
+	fallback bool    // last search was served from the copy
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStandby wraps db, which is nil when the database was unreachable, and
+// loads the snapshot at options.Path if there is one
+func NewStandby(db Backend, options StandbyOptions, logger *zap.Logger) *Standby {
+	s := &Standby{
+		options: options,
+		logger:  logger,
+		db:      db,
+		stop:    make(chan struct{}),
+	}
+
+	start := time.Now()
+	snapshot, err := loadCorpus(options.Path)
+	switch {
+	case os.IsNotExist(err):
+		logger.Info("No corpus snapshot yet; one is saved after the first sync", zap.String("path", options.Path))
+	case err != nil:
+		logger.Warn("Failed to load corpus snapshot; waiting for the database", zap.String("path", options.Path), zap.Error(err))
+	default:
+		s.corpus = snapshot
+		logger.Info("Corpus snapshot loaded",
+			zap.String("path", options.Path),
+			zap.Int("vectors", len(snapshot.vectors)),
+			zap.String("corpus_version", snapshot.version),
+			zap.Duration("duration", time.Since(start)))
+	}
+	return s
+}
+
+// Vectors returns the number of vectors the in-memory copy can serve
+func (s *Standby) Vectors() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.corpus == nil {
+		return 0
+	}
+	return len(s.corpus.vectors)
+}
+
+// Connected reports whether the database has been reached
+func (s *Standby) Connected() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db != nil
+}
+
+func (s *Standby) database() (Backend, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return nil, errNotConnected
+	}
+	return s.db, nil
+}
+
+func (s *Standby) reduce(embedding []float32) []float32 {
+	if s.options.Reducer == nil {
+		return embedding
+	}
+	return s.options.Reducer.Reduce(embedding)
+}
+
+// searchCopy serves a search from the in-memory copy after the database
+// failed with cause. Without a copy, cause is returned.
+func (s *Standby) searchCopy(embedding []float32, options *SearchOptions, cause error) ([]*SimilarityResult, error) {
+	s.mu.Lock()
+	snapshot := s.corpus
+	switched := snapshot != nil && !s.fallback
+	if snapshot != nil {
+		s.fallback = true
+	}
+	s.mu.Unlock()
+
+	if snapshot == nil {
+		return nil, cause
+	}
+	if switched {
+		s.logger.Warn("Serving similarity searches from the corpus snapshot",
+			zap.Int("vectors", len(snapshot.vectors)),
+			zap.String("corpus_version", snapshot.version),
+			zap.Error(cause))
+	}
+	if options == nil {
+		options = defaultSearchOptions()
+	}
+	return snapshot.search(s.reduce(embedding), options)
+}
+
+// served records that the database answered a search
+func (s *Standby) served() {
+	s.mu.RLock()
+	fallback := s.fallback
+	s.mu.RUnlock()
+	if !fallback {
+		return
+	}
+
+	s.mu.Lock()
+	s.fallback = false
+	s.mu.Unlock()
+	s.logger.Info("Similarity searches served by the database again")
+}
+
+// FindSimilar searches the database, or the in-memory copy when the
+// database fails
+func (s *Standby) FindSimilar(ctx context.Context, embedding []float32, options *SearchOptions) ([]*SimilarityResult, error) {
+	db, err := s.database()
+	if err == nil {
+		var results []*SimilarityResult
+		if results, err = db.FindSimilar(ctx, embedding, options); err == nil {
+			s.served()
+			return results, nil
+		}
+	}
+	return s.searchCopy(embedding, options, err)
+}
+
+// FindHybrid runs a hybrid search on the database, or a vector-only search
+// of the in-memory copy when the database fails
+func (s *Standby) FindHybrid(ctx context.Context, embedding []float32, text string, options *SearchOptions) ([]*SimilarityResult, error) {
+	db, err := s.database()
+	if err == nil {
+		var results []*SimilarityResult
+		if results, err = db.FindHybrid(ctx, embedding, text, options); err == nil {
+			s.served()
+			return results, nil
+		}
+	}
+	return s.searchCopy(embedding, options, err)
+}
+
+// StreamSimilar streams a database search, or the in-memory copy's results
+// when the database fails before the first result
+func (s *Standby) StreamSimilar(ctx context.Context, embedding []float32, options *SearchOptions, fn func(*SimilarityResult) error) error {
+	streamed := false
+	db, err := s.database()
+	if err == nil {
+		err = db.StreamSimilar(ctx, embedding, options, func(result *SimilarityResult) error {
+			streamed = true
+			return fn(result)
+		})
+		if err == nil {
+			s.served()
+			return nil
+		}
+		if streamed {
+			return err
+		}
+	}
+
+	results, err := s.searchCopy(embedding, options, err)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync reconnects to the database if needed and, when its corpus version
+// differs from the copy's, copies the corpus and saves the snapshot
+func (s *Standby) Sync(ctx context.Context) error {
+	s.mu.RLock()
+	db, current := s.db, s.corpus
+	s.mu.RUnlock()
+
+	if db == nil {
+		var err error
+		if db, err = s.options.Open(); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.db = db
+		s.mu.Unlock()
+		s.logger.Info("Vector database connected")
+		if s.options.OnConnect != nil {
+			s.options.OnConnect()
+		}
+	}
+
+	version, err := db.CorpusVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.version == version {
+		return nil
+	}
+
+	start := time.Now()
+	copied, err := copyCorpus(ctx, db)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.corpus = copied
+	s.mu.Unlock()
+
+	if err := copied.save(s.options.Path); err != nil {
+		return err
+	}
+	s.logger.Info("Corpus snapshot saved",
+		zap.String("path", s.options.Path),
+		zap.Int("vectors", len(copied.vectors)),
+		zap.String("corpus_version", copied.version),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// Start syncs now and then periodically
+func (s *Standby) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.options.Interval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), s.options.Interval)
+			if err := s.Sync(ctx); err != nil {
+				s.logger.Warn("Corpus sync failed; keeping the current snapshot", zap.Error(err))
+			}
+			cancel()
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops syncing and closes the database
+func (s *Standby) Close() error {
+	close(s.stop)
+	s.wg.Wait()
+	if db, err := s.database(); err == nil {
+		return db.Close()
+	}
+	return nil
+}
+
+// Insert adds a vector to the database
+func (s *Standby) Insert(ctx context.Context, vector *SecurityVector) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.Insert(ctx, vector)
+}
+
+// BatchInsert adds vectors to the database
+func (s *Standby) BatchInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.BatchInsert(ctx, vectors)
+}
+
+// CopyInsert bulk-loads vectors into the database
+func (s *Standby) CopyInsert(ctx context.Context, vectors []*SecurityVector) (*BatchInsertResult, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.CopyInsert(ctx, vectors)
+}
+
+// ExplainSimilar captures the database's plan for a similarity search
+func (s *Standby) ExplainSimilar(ctx context.Context, embedding []float32, options *SearchOptions) (*QueryPlan, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.ExplainSimilar(ctx, embedding, options)
+}
+
+// ListVectors returns one page of the database's vectors
+func (s *Standby) ListVectors(ctx context.Context, options *PageOptions) (*VectorPage, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.ListVectors(ctx, options)
+}
+
+// IterateVectors calls fn for each of the database's vectors
+func (s *Standby) IterateVectors(ctx context.Context, options *PageOptions, fn func(*SecurityVector) error) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.IterateVectors(ctx, options, fn)
+}
+
+// GetMaliciousVectors returns a page of the database's malicious vectors
+func (s *Standby) GetMaliciousVectors(ctx context.Context, afterID int64, limit int, labelText string) ([]*SecurityVector, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetMaliciousVectors(ctx, afterID, limit, labelText)
+}
+
+// ExistingHashes reports which text hashes the database holds
+func (s *Standby) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.ExistingHashes(ctx, hashes)
+}
+
+// GetStats returns database statistics
+func (s *Standby) GetStats(ctx context.Context) (*VectorStats, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetStats(ctx)
+}
+
+// CorpusStats returns the database's corpus statistics
+func (s *Standby) CorpusStats(ctx context.Context, options *CorpusStatsOptions) (*CorpusStats, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.CorpusStats(ctx, options)
+}
+
+// CorpusVersion returns the database's corpus version
+func (s *Standby) CorpusVersion(ctx context.Context) (string, error) {
+	db, err := s.database()
+	if err != nil {
+		return "", err
+	}
+	return db.CorpusVersion(ctx)
+}
+
+// SaveCanaries stores canary embeddings in the database
+func (s *Standby) SaveCanaries(ctx context.Context, canaries []Canary) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.SaveCanaries(ctx, canaries)
+}
+
+// Canaries returns the database's canary embeddings
+func (s *Standby) Canaries(ctx context.Context) ([]Canary, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.Canaries(ctx)
+}
+
+// CreateIndex creates the database's vector index
+func (s *Standby) CreateIndex(ctx context.Context) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.CreateIndex(ctx)
+}
+
+// ReindexVectors rebuilds the database's vector index
+func (s *Standby) ReindexVectors(ctx context.Context) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.ReindexVectors(ctx)
+}
+
+// DropIndex drops the database's vector index
+func (s *Standby) DropIndex(ctx context.Context) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.DropIndex(ctx)
+}
+
+// Indexes lists the database's vector indexes
+func (s *Standby) Indexes(ctx context.Context) ([]IndexInfo, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.Indexes(ctx)
+}
+
+// Ping verifies the database connection is alive
+func (s *Standby) Ping(ctx context.Context) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.Ping(ctx)
+}
+
+// PoolStats returns the database's connection pool statistics
+func (s *Standby) PoolStats() []PoolStats {
+	db, err := s.database()
+	if err != nil {
+		return nil
+	}
+	return db.PoolStats()
+}
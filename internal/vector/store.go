@@ -8,14 +8,19 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/raaihank/llm-sentinel/internal/chaos"
+	"github.com/raaihank/llm-sentinel/internal/textnorm"
 	"go.uber.org/zap"
 )
 
 // Store handles vector storage operations with PostgreSQL + pgvector
 type Store struct {
-	db     *sqlx.DB
-	logger *zap.Logger
+	db       *sqlx.DB
+	reducer  Reducer // nil stores full-size embeddings
+	index    IndexOptions
+	textHash textnorm.Options
+	logger   *zap.Logger
 }
 
 // Config contains database configuration
@@ -25,11 +30,15 @@ type Config struct {
 	MaxIdleConns    int           `yaml:"max_idle_conns" mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`
 	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" mapstructure:"conn_max_idle_time"`
+
+	Reducer  Reducer          `yaml:"-" mapstructure:"-"` // applied to every stored and probe embedding
+	Index    IndexOptions     `yaml:"-" mapstructure:"-"`
+	TextHash textnorm.Options `yaml:"-" mapstructure:"-"` // canonicalizes text for vectors inserted without a text_hash
 }
 
 // NewStore creates a new vector store instance
 func NewStore(config *Config, logger *zap.Logger) (*Store, error) {
-	db, err := sqlx.Connect("postgres", config.DatabaseURL)
+	db, err := openDB(config.DatabaseURL, config.Index.searchSettings())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -40,9 +49,16 @@ func NewStore(config *Config, logger *zap.Logger) (*Store, error) {
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
 	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 
+	if config.Index.Type == "" {
+		config.Index.Type = "ivfflat"
+	}
+
 	store := &Store{
-		db:     db,
-		logger: logger,
+		db:       db,
+		reducer:  config.Reducer,
+		index:    config.Index,
+		textHash: config.TextHash,
+		logger:   logger,
 	}
 
 	// Test connection and ensure pgvector extension
@@ -79,26 +95,63 @@ func (s *Store) initialize() error {
 		return fmt.Errorf("pgvector extension is not installed")
 	}
 
+	// A reduced store needs a column sized for the reduced embeddings
+	if s.reducer != nil {
+		var columnDims int
+		query := `SELECT atttypmod FROM pg_attribute
+			WHERE attrelid = 'security_vectors'::regclass AND attname = 'embedding'`
+		if err := s.db.GetContext(ctx, &columnDims, query); err == nil && columnDims > 0 && columnDims != s.reducer.Dimensions() {
+			return fmt.Errorf("security_vectors.embedding is vector(%d) but reduction produces %d dimensions; recreate the column as vector(%d) and re-ingest",
+				columnDims, s.reducer.Dimensions(), s.reducer.Dimensions())
+		}
+	}
+
+	if err := s.ensureCorpusColumns(ctx); err != nil {
+		s.logger.Warn("Failed to add source, language and metadata columns; inserts will fail until they exist", zap.Error(err))
+	}
+
 	s.logger.Info("Database initialized with pgvector extension")
 	return nil
 }
 
+// reduce applies the configured dimension reduction, if any
+func (s *Store) reduce(embedding []float32) []float32 {
+	if s.reducer == nil {
+		return embedding
+	}
+	return s.reducer.Reduce(embedding)
+}
+
+// hashOf returns the vector's text hash, filling it in from the canonical
+// text when the caller left it empty
+func (s *Store) hashOf(vector *SecurityVector) string {
+	if vector.TextHash == "" {
+		vector.TextHash = textnorm.Hash(vector.Text, s.textHash)
+	}
+	return vector.TextHash
+}
+
 // Insert adds a new security vector to the database
 func (s *Store) Insert(ctx context.Context, vector *SecurityVector) error {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return err
+	}
+
 	query := `
-        INSERT INTO security_vectors (text, embedding_type, text_hash, label_text, label, embedding)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO security_vectors (text, embedding_type, text_hash, label_text, label, embedding, source, language, metadata)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at, updated_at`
 
-	embeddingStr := formatEmbedding(vector.Embedding)
-
 	err := s.db.QueryRowContext(ctx, query,
 		vector.Text,
 		vector.EmbeddingType,
-		vector.TextHash,
+		s.hashOf(vector),
 		vector.LabelText,
 		vector.Label,
-		embeddingStr,
+		binaryVector(s.reduce(vector.Embedding)),
+		vector.Source,
+		vector.Language,
+		metadataJSON(vector.Metadata),
 	).Scan(&vector.ID, &vector.CreatedAt, &vector.UpdatedAt)
 
 	if err != nil {
@@ -122,27 +175,35 @@ func (s *Store) BatchInsert(ctx context.Context, vectors []*SecurityVector) (*Ba
 		return &BatchInsertResult{}, nil
 	}
 
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
 	result := &BatchInsertResult{}
 
 	// Prepare batch insert
 	valueStrings := make([]string, 0, len(vectors))
-	valueArgs := make([]interface{}, 0, len(vectors)*6)
+	valueArgs := make([]interface{}, 0, len(vectors)*insertColumns)
 
 	for i, vector := range vectors {
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6))
+		n := i * insertColumns
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9))
 		valueArgs = append(valueArgs,
 			vector.Text,
 			vector.EmbeddingType,
-			vector.TextHash,
+			s.hashOf(vector),
 			vector.LabelText,
 			vector.Label,
-			formatEmbedding(vector.Embedding),
+			binaryVector(s.reduce(vector.Embedding)),
+			vector.Source,
+			vector.Language,
+			metadataJSON(vector.Metadata),
 		)
 	}
 
 	query := fmt.Sprintf(`
-        INSERT INTO security_vectors (text, embedding_type, text_hash, label_text, label, embedding)
+        INSERT INTO security_vectors (text, embedding_type, text_hash, label_text, label, embedding, source, language, metadata)
 		VALUES %s
 		ON CONFLICT (text_hash) DO NOTHING`,
 		strings.Join(valueStrings, ","))
@@ -177,50 +238,199 @@ func (s *Store) BatchInsert(ctx context.Context, vectors []*SecurityVector) (*Ba
 
 // FindSimilar finds vectors similar to the given embedding
 func (s *Store) FindSimilar(ctx context.Context, embedding []float32, options *SearchOptions) ([]*SimilarityResult, error) {
+	var results []*SimilarityResult
+	err := s.StreamSimilar(ctx, embedding, options, func(result *SimilarityResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// StreamSimilar runs a similarity search and hands each result to fn as it is
+// read, so large result sets never sit in memory at once. A zero Limit streams
+// every match; set After to resume from the last result of a previous page.
+// Returning an error from fn stops the scan and is returned as-is.
+func (s *Store) StreamSimilar(ctx context.Context, embedding []float32, options *SearchOptions, fn func(*SimilarityResult) error) error {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return err
+	}
+
 	if options == nil {
-		options = &SearchOptions{
-			Limit:         5,
-			MinSimilarity: 0.7,
+		options = defaultSearchOptions()
+	}
+	query, args := similarityQuery(s.reduce(embedding), options)
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Similarity search failed", zap.Error(err))
+		return fmt.Errorf("similarity search failed: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var result SimilarityResult
+		var vector SecurityVector
+		var embedding []byte
+
+		err := rows.Scan(
+			&vector.ID,
+			&vector.Text,
+			&vector.LabelText,
+			&vector.Label,
+			&embedding,
+			&vector.CreatedAt,
+			&vector.UpdatedAt,
+			&result.Similarity,
+			&result.distance,
+		)
+		if err != nil {
+			s.logger.Error("Failed to scan similarity result", zap.Error(err))
+			continue
+		}
+		result.Distance = float32(result.distance)
+
+		// Parse embedding back to float32 slice
+		vector.Embedding, err = decodeVector(embedding)
+		if err != nil {
+			s.logger.Error("Failed to parse embedding", zap.Error(err))
+			continue
 		}
+
+		result.Vector = &vector
+		if err := fn(&result); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("similarity search failed: %w", err)
 	}
 
-	embeddingStr := formatEmbedding(embedding)
+	searchDuration := time.Since(start)
+	s.logger.Debug("Similarity search completed",
+		zap.Int("results", count),
+		zap.Duration("duration", searchDuration),
+		zap.Float32("min_similarity", options.MinSimilarity))
 
-	// Build query with optional filters
-	whereClause := "WHERE (1 - (embedding <=> $1)) >= $2"
-	args := []interface{}{embeddingStr, options.MinSimilarity}
-	argIndex := 3
+	return nil
+}
 
-	if options.LabelFilter != nil {
-		whereClause += fmt.Sprintf(" AND label = $%d", argIndex)
-		args = append(args, *options.LabelFilter)
-		argIndex++
+// defaultSearchOptions are used when a search passes nil options
+func defaultSearchOptions() *SearchOptions {
+	return &SearchOptions{
+		Limit:         5,
+		MinSimilarity: 0.7,
 	}
+}
 
-	if options.LabelTextFilter != "" {
-		whereClause += fmt.Sprintf(" AND label_text = $%d", argIndex)
-		args = append(args, options.LabelTextFilter)
-		argIndex++
+// similarityQuery builds the similarity search SQL and its arguments
+func similarityQuery(embedding []float32, options *SearchOptions) (string, []interface{}) {
+	// Build query with optional filters
+	args := []interface{}{binaryVector(embedding), options.MinSimilarity}
+	filters, args := buildFilterClause(options, args)
+	whereClause := "WHERE (1 - (embedding <=> $1)) >= $2" + filters
+
+	// Keyset pagination on (distance, id) keeps pages stable without OFFSET
+	if options.After != nil {
+		args = append(args, options.After.Distance, options.After.ID)
+		whereClause += fmt.Sprintf(" AND ((embedding <=> $1), id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	limitClause := ""
+	if options.Limit > 0 {
+		args = append(args, options.Limit)
+		limitClause = fmt.Sprintf("LIMIT $%d", len(args))
 	}
 
 	query := fmt.Sprintf(`
 		SELECT 
-			id, text, label_text, label, embedding,
+			id, text, label_text, label, vector_send(embedding),
 			created_at, updated_at,
 			(1 - (embedding <=> $1)) as similarity,
 			(embedding <=> $1) as distance
 		FROM security_vectors
 		%s
-		ORDER BY embedding <=> $1
-		LIMIT $%d`, whereClause, argIndex)
+		ORDER BY embedding <=> $1, id
+		%s`, whereClause, limitClause)
+	return query, args
+}
+
+// FindHybrid finds vectors by merging a pgvector similarity ranking with a
+// trigram ranking on the stored text using reciprocal rank fusion. This
+// catches attacks that are lexically near-identical to a known pattern but
+// embed far apart. Vector candidates need MinSimilarity and lexical ones
+// MinLexicalScore; results are ordered by fused rank, not by similarity.
+func (s *Store) FindHybrid(ctx context.Context, embedding []float32, text string, options *SearchOptions) ([]*SimilarityResult, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &SearchOptions{
+			Limit:         5,
+			MinSimilarity: 0.7,
+		}
+	}
 
-	args = append(args, options.Limit)
+	rrfConstant := options.RRFConstant
+	if rrfConstant <= 0 {
+		rrfConstant = DefaultRRFConstant
+	}
+	minLexicalScore := options.MinLexicalScore
+	if minLexicalScore <= 0 {
+		minLexicalScore = DefaultMinLexicalScore
+	}
+
+	// Each ranking contributes more candidates than the final limit so that
+	// documents ranked moderately in both lists can surface after fusion
+	candidates := options.Limit * 4
+	if candidates < 20 {
+		candidates = 20
+	}
+
+	args := []interface{}{binaryVector(s.reduce(embedding)), text, options.MinSimilarity, candidates, rrfConstant, options.Limit, minLexicalScore}
+	filters, args := buildFilterClause(options, args)
+
+	query := fmt.Sprintf(`
+		WITH vec AS (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY embedding <=> $1) AS rnk
+			FROM security_vectors
+			WHERE (1 - (embedding <=> $1)) >= $3%[1]s
+			ORDER BY embedding <=> $1
+			LIMIT $4
+		), lex AS (
+			SELECT id, ROW_NUMBER() OVER (
+				ORDER BY similarity(text, $2) DESC,
+					ts_rank_cd(to_tsvector('simple', text), plainto_tsquery('simple', $2)) DESC
+			) AS rnk
+			FROM security_vectors
+			WHERE text %% $2 AND similarity(text, $2) >= $7%[1]s
+			ORDER BY rnk
+			LIMIT $4
+		)
+		SELECT
+			sv.id, sv.text, sv.label_text, sv.label, vector_send(sv.embedding),
+			sv.created_at, sv.updated_at,
+			(1 - (sv.embedding <=> $1)) as similarity,
+			(sv.embedding <=> $1) as distance,
+			similarity(sv.text, $2) as lexical_score,
+			(COALESCE(1.0 / ($5 + vec.rnk), 0) + COALESCE(1.0 / ($5 + lex.rnk), 0))::float8 as score
+		FROM vec
+		FULL OUTER JOIN lex ON vec.id = lex.id
+		JOIN security_vectors sv ON sv.id = COALESCE(vec.id, lex.id)
+		ORDER BY score DESC
+		LIMIT $6`, filters)
 
 	start := time.Now()
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		s.logger.Error("Similarity search failed", zap.Error(err))
-		return nil, fmt.Errorf("similarity search failed: %w", err)
+		s.logger.Error("Hybrid search failed", zap.Error(err))
+		return nil, fmt.Errorf("hybrid search failed: %w", err)
 	}
 	defer rows.Close()
 
@@ -228,26 +438,27 @@ func (s *Store) FindSimilar(ctx context.Context, embedding []float32, options *S
 	for rows.Next() {
 		var result SimilarityResult
 		var vector SecurityVector
-		var embeddingStr string
+		var embedding []byte
 
 		err := rows.Scan(
 			&vector.ID,
 			&vector.Text,
 			&vector.LabelText,
 			&vector.Label,
-			&embeddingStr,
+			&embedding,
 			&vector.CreatedAt,
 			&vector.UpdatedAt,
 			&result.Similarity,
 			&result.Distance,
+			&result.LexicalScore,
+			&result.Score,
 		)
 		if err != nil {
-			s.logger.Error("Failed to scan similarity result", zap.Error(err))
+			s.logger.Error("Failed to scan hybrid result", zap.Error(err))
 			continue
 		}
 
-		// Parse embedding back to float32 slice
-		vector.Embedding, err = parseEmbedding(embeddingStr)
+		vector.Embedding, err = decodeVector(embedding)
 		if err != nil {
 			s.logger.Error("Failed to parse embedding", zap.Error(err))
 			continue
@@ -257,17 +468,168 @@ func (s *Store) FindSimilar(ctx context.Context, embedding []float32, options *S
 		results = append(results, &result)
 	}
 
-	searchDuration := time.Since(start)
-	s.logger.Debug("Similarity search completed",
+	s.logger.Debug("Hybrid search completed",
 		zap.Int("results", len(results)),
-		zap.Duration("duration", searchDuration),
+		zap.Duration("duration", time.Since(start)),
 		zap.Float32("min_similarity", options.MinSimilarity))
 
 	return results, nil
 }
 
-// GetStats returns database statistics
+// buildFilterClause appends the optional label filters to args and returns
+// the matching " AND ..." clause
+func buildFilterClause(options *SearchOptions, args []interface{}) (string, []interface{}) {
+	clause := ""
+
+	if options.LabelFilter != nil {
+		args = append(args, *options.LabelFilter)
+		clause += fmt.Sprintf(" AND label = $%d", len(args))
+	}
+
+	if options.LabelTextFilter != "" {
+		args = append(args, options.LabelTextFilter)
+		clause += fmt.Sprintf(" AND label_text = $%d", len(args))
+	}
+
+	return clause, args
+}
+
+// ListVectors returns one page of vectors in id order. Pass the returned
+// NextCursor back as Cursor to fetch the following page.
+func (s *Store) ListVectors(ctx context.Context, options *PageOptions) (*VectorPage, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &PageOptions{}
+	}
+
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	embeddingColumn := "NULL"
+	if options.IncludeEmbedding {
+		embeddingColumn = "vector_send(embedding)"
+	}
+
+	args := []interface{}{options.Cursor}
+	filters, args := buildFilterClause(&SearchOptions{
+		LabelFilter:     options.LabelFilter,
+		LabelTextFilter: options.LabelTextFilter,
+	}, args)
+	if options.EmbeddingTypeFilter != "" {
+		args = append(args, options.EmbeddingTypeFilter)
+		filters += fmt.Sprintf(" AND embedding_type = $%d", len(args))
+	}
+	if options.CreatedAfter != nil {
+		args = append(args, *options.CreatedAfter)
+		filters += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if options.CreatedBefore != nil {
+		args = append(args, *options.CreatedBefore)
+		filters += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+	// Fetch one extra row to learn whether another page exists
+	args = append(args, pageSize+1)
+
+	query := fmt.Sprintf(`
+		SELECT id, text, embedding_type, text_hash, label_text, label, %s,
+			source, language, metadata, created_at, updated_at
+		FROM security_vectors
+		WHERE id > $1%s
+		ORDER BY id
+		LIMIT $%d`, embeddingColumn, filters, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vectors: %w", err)
+	}
+	defer rows.Close()
+
+	page := &VectorPage{Vectors: make([]*SecurityVector, 0, pageSize)}
+	for rows.Next() {
+		var vector SecurityVector
+		var embedding, metadata []byte
+
+		if err := rows.Scan(
+			&vector.ID,
+			&vector.Text,
+			&vector.EmbeddingType,
+			&vector.TextHash,
+			&vector.LabelText,
+			&vector.Label,
+			&embedding,
+			&vector.Source,
+			&vector.Language,
+			&metadata,
+			&vector.CreatedAt,
+			&vector.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan vector: %w", err)
+		}
+
+		if len(page.Vectors) == pageSize {
+			page.HasMore = true
+			break
+		}
+
+		if embedding != nil {
+			vector.Embedding, err = decodeVector(embedding)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse embedding for vector %d: %w", vector.ID, err)
+			}
+		}
+		if vector.Metadata, err = scanMetadata(metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata for vector %d: %w", vector.ID, err)
+		}
+
+		page.Vectors = append(page.Vectors, &vector)
+		page.NextCursor = vector.ID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list vectors: %w", err)
+	}
+
+	return page, nil
+}
+
+// IterateVectors walks every vector matching options page by page, calling fn
+// for each one. Memory stays bounded by the page size regardless of table size.
+func (s *Store) IterateVectors(ctx context.Context, options *PageOptions, fn func(*SecurityVector) error) error {
+	pageOptions := PageOptions{}
+	if options != nil {
+		pageOptions = *options
+	}
+
+	for {
+		page, err := s.ListVectors(ctx, &pageOptions)
+		if err != nil {
+			return err
+		}
+
+		for _, vector := range page.Vectors {
+			if err := fn(vector); err != nil {
+				return err
+			}
+		}
+
+		if !page.HasMore {
+			return nil
+		}
+		pageOptions.Cursor = page.NextCursor
+	}
+}
+
+// GetStats returns database statistics, including vector counts by
+// label_text, source and language
 func (s *Store) GetStats(ctx context.Context) (*VectorStats, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
 	stats := &VectorStats{}
 
 	// Get vector counts
@@ -287,6 +649,16 @@ func (s *Store) GetStats(ctx context.Context) (*VectorStats, error) {
 		return nil, fmt.Errorf("failed to get vector stats: %w", err)
 	}
 
+	if stats.LabelTexts, err = s.countBy(ctx, "label_text"); err != nil {
+		return nil, err
+	}
+	if stats.Sources, err = s.countBy(ctx, "source"); err != nil {
+		return nil, err
+	}
+	if stats.Languages, err = s.countBy(ctx, "language"); err != nil {
+		return nil, err
+	}
+
 	// Get cache stats if available
 	cacheQuery := `
 		SELECT 
@@ -309,33 +681,31 @@ func (s *Store) GetStats(ctx context.Context) (*VectorStats, error) {
 	return stats, nil
 }
 
-// CreateIndex creates the vector similarity index for better performance
-func (s *Store) CreateIndex(ctx context.Context) error {
-	// Only create index if we have enough vectors
-	var count int64
-	if err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM security_vectors"); err != nil {
-		return fmt.Errorf("failed to count vectors: %w", err)
+// CorpusVersion identifies the current corpus contents by row count, newest
+// row and last update, so two analyses can tell whether the corpus changed
+// between them
+func (s *Store) CorpusVersion(ctx context.Context) (string, error) {
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return "", err
 	}
 
-	if count < 1000 {
-		s.logger.Info("Skipping index creation, not enough vectors", zap.Int64("count", count))
-		return nil
-	}
-
-	s.logger.Info("Creating vector similarity index...", zap.Int64("vector_count", count))
-
+	var count, maxID, updated int64
 	query := `
-		CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_security_vectors_embedding 
-		ON security_vectors USING ivfflat (embedding vector_cosine_ops) 
-		WITH (lists = 100)`
-
-	_, err := s.db.ExecContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to create vector index: %w", err)
+		SELECT COUNT(*), COALESCE(MAX(id), 0),
+			COALESCE(EXTRACT(EPOCH FROM MAX(updated_at))::bigint, 0)
+		FROM security_vectors`
+	if err := s.db.QueryRowContext(ctx, query).Scan(&count, &maxID, &updated); err != nil {
+		return "", fmt.Errorf("failed to get corpus version: %w", err)
 	}
+	return fmt.Sprintf("%d-%d-%d", count, maxID, updated), nil
+}
 
-	s.logger.Info("Vector similarity index created successfully")
-	return nil
+// Ping verifies the database connection is alive
+func (s *Store) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	return s.db.PingContext(ctx)
 }
 
 // Close closes the database connection
@@ -348,41 +718,6 @@ func (s *Store) Close() error {
 
 // Helper functions
 
-// formatEmbedding converts float32 slice to PostgreSQL vector format
-func formatEmbedding(embedding []float32) string {
-	if len(embedding) == 0 {
-		return "[]"
-	}
-
-	parts := make([]string, len(embedding))
-	for i, v := range embedding {
-		parts[i] = fmt.Sprintf("%g", v)
-	}
-	return "[" + strings.Join(parts, ",") + "]"
-}
-
-// parseEmbedding converts PostgreSQL vector format back to float32 slice
-func parseEmbedding(embeddingStr string) ([]float32, error) {
-	// Remove brackets and split by comma
-	embeddingStr = strings.Trim(embeddingStr, "[]")
-	if embeddingStr == "" {
-		return []float32{}, nil
-	}
-
-	parts := strings.Split(embeddingStr, ",")
-	embedding := make([]float32, len(parts))
-
-	for i, part := range parts {
-		var val float32
-		if _, err := fmt.Sscanf(strings.TrimSpace(part), "%g", &val); err != nil {
-			return nil, fmt.Errorf("failed to parse embedding value: %w", err)
-		}
-		embedding[i] = val
-	}
-
-	return embedding, nil
-}
-
 // maskDatabaseURL masks sensitive information in database URL for logging
 func maskDatabaseURL(url string) string {
 	// Simple masking - replace password with ***
@@ -403,22 +738,44 @@ func maskDatabaseURL(url string) string {
 	return url
 }
 
-func (s *Store) GetMaliciousVectors(ctx context.Context, limit int, offset int64) ([]*SecurityVector, error) {
-	vectors := make([]*SecurityVector, 0)
-	query := `SELECT id, text, text_hash, label_text, label, embedding, created_at, updated_at 
-              FROM security_vectors WHERE label = 1 LIMIT $1 OFFSET $2`
-	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+// GetMaliciousVectors returns up to limit malicious vectors (label 1) with
+// embeddings, in id order after afterID. labelText, when set, keeps only
+// that attack category. Pass the last returned ID as afterID for the next
+// batch; keyset pagination stays fast however deep the walk goes.
+func (s *Store) GetMaliciousVectors(ctx context.Context, afterID int64, limit int, labelText string) ([]*SecurityVector, error) {
+	malicious := 1
+	page, err := s.ListVectors(ctx, &PageOptions{
+		Cursor:           afterID,
+		PageSize:         limit,
+		LabelFilter:      &malicious,
+		LabelTextFilter:  labelText,
+		IncludeEmbedding: true,
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return page.Vectors, nil
+}
 
-	for rows.Next() {
-		var vec SecurityVector
-		if err := rows.Scan(&vec.ID, &vec.Text, &vec.TextHash, &vec.LabelText, &vec.Label, &vec.Embedding, &vec.CreatedAt, &vec.UpdatedAt); err != nil {
-			return nil, err
-		}
-		vectors = append(vectors, &vec)
+// ExistingHashes returns which of hashes are already stored, so a resumed
+// ingest can skip records an earlier run committed
+func (s *Store) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+
+	if err := chaos.Inject(ctx, chaos.Postgres); err != nil {
+		return nil, err
+	}
+
+	var found []string
+	query := `SELECT text_hash FROM security_vectors WHERE text_hash = ANY($1)`
+	if err := s.db.SelectContext(ctx, &found, query, pq.Array(hashes)); err != nil {
+		return nil, fmt.Errorf("failed to look up text hashes: %w", err)
+	}
+	for _, hash := range found {
+		existing[hash] = true
 	}
-	return vectors, rows.Err()
+	return existing, nil
 }
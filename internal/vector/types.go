@@ -6,22 +6,54 @@ import (
 
 // SecurityVector represents a security pattern with its embedding
 type SecurityVector struct {
-	ID            int64     `db:"id" json:"id"`
-	Text          string    `db:"text" json:"text"`
-	EmbeddingType string    `db:"embedding_type" json:"embedding_type"`
-	TextHash      string    `db:"text_hash" json:"text_hash"`
-	LabelText     string    `db:"label_text" json:"label_text"`
-	Label         int       `db:"label" json:"label"`
-	Embedding     []float32 `db:"embedding" json:"embedding"`
-	CreatedAt     time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+	ID            int64             `db:"id" json:"id"`
+	Text          string            `db:"text" json:"text"`
+	EmbeddingType string            `db:"embedding_type" json:"embedding_type"`
+	TextHash      string            `db:"text_hash" json:"text_hash"`
+	LabelText     string            `db:"label_text" json:"label_text"`
+	Label         int               `db:"label" json:"label"`
+	Embedding     []float32         `db:"embedding" json:"embedding"`
+	Source        string            `db:"source" json:"source,omitempty"`     // dataset the vector was ingested from
+	Language      string            `db:"language" json:"language,omitempty"` // ISO 639-1 code of the text, "und" if unknown
+	Metadata      map[string]string `db:"metadata" json:"metadata,omitempty"` // extra dataset columns
+	CreatedAt     time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time         `db:"updated_at" json:"updated_at"`
 }
 
+// DefaultRRFConstant is the k in reciprocal rank fusion, 1/(k + rank)
+const DefaultRRFConstant = 60
+
+// DefaultMinLexicalScore is the trigram similarity a hybrid search's lexical
+// candidates need when SearchOptions.MinLexicalScore is unset
+const DefaultMinLexicalScore = 0.7
+
 // SimilarityResult represents a vector similarity search result
 type SimilarityResult struct {
-	Vector     *SecurityVector `json:"vector"`
-	Similarity float32         `json:"similarity"`
-	Distance   float32         `json:"distance"`
+	Vector       *SecurityVector `json:"vector"`
+	Similarity   float32         `json:"similarity"`
+	Distance     float32         `json:"distance"`
+	LexicalScore float32         `json:"lexical_score,omitempty"` // trigram similarity, hybrid search only
+	Score        float32         `json:"score,omitempty"`         // fused RRF score, hybrid search only
+
+	distance float64 // full-precision distance for pagination cursors
+}
+
+// Cursor returns the position to resume a similarity search after this result
+func (r *SimilarityResult) Cursor() *SimilarityCursor {
+	if r.Vector == nil {
+		return nil
+	}
+	distance := r.distance
+	if distance == 0 {
+		distance = float64(r.Distance)
+	}
+	return &SimilarityCursor{Distance: distance, ID: r.Vector.ID}
+}
+
+// SimilarityCursor marks a position in a similarity-ordered result set
+type SimilarityCursor struct {
+	Distance float64 `json:"distance"`
+	ID       int64   `json:"id"`
 }
 
 // SearchOptions contains options for vector similarity search
@@ -30,6 +62,33 @@ type SearchOptions struct {
 	MinSimilarity   float32 `json:"min_similarity"`
 	LabelFilter     *int    `json:"label_filter,omitempty"`
 	LabelTextFilter string  `json:"label_text_filter,omitempty"`
+	RRFConstant     int     `json:"rrf_constant,omitempty"`      // hybrid search only
+	MinLexicalScore float32 `json:"min_lexical_score,omitempty"` // hybrid search only: trigram similarity lexical candidates need
+
+	After *SimilarityCursor `json:"after,omitempty"` // resume after this result
+}
+
+// DefaultPageSize is used when PageOptions.PageSize is unset
+const DefaultPageSize = 500
+
+// PageOptions controls keyset pagination over the vector table
+type PageOptions struct {
+	Cursor           int64  `json:"cursor"` // return vectors with id > cursor
+	PageSize         int    `json:"page_size"`
+	LabelFilter      *int   `json:"label_filter,omitempty"`
+	LabelTextFilter  string `json:"label_text_filter,omitempty"`
+	IncludeEmbedding bool   `json:"include_embedding"`
+
+	EmbeddingTypeFilter string     `json:"embedding_type_filter,omitempty"`
+	CreatedAfter        *time.Time `json:"created_after,omitempty"`  // inclusive
+	CreatedBefore       *time.Time `json:"created_before,omitempty"` // exclusive
+}
+
+// VectorPage is one page of a ListVectors result
+type VectorPage struct {
+	Vectors    []*SecurityVector `json:"vectors"`
+	NextCursor int64             `json:"next_cursor"`
+	HasMore    bool              `json:"has_more"`
 }
 
 // VectorStats represents database statistics
@@ -39,6 +98,12 @@ type VectorStats struct {
 	SafeCount       int64   `json:"safe_count"`
 	AvgSearchTimeMs float64 `json:"avg_search_time_ms"`
 	CacheHitRate    float64 `json:"cache_hit_rate"`
+
+	// Vector counts by label_text, source dataset and language. Vectors
+	// stored before sources and languages were recorded count as "unknown".
+	LabelTexts map[string]int64 `json:"label_texts"`
+	Sources    map[string]int64 `json:"sources"`
+	Languages  map[string]int64 `json:"languages"`
 }
 
 // BatchInsertResult represents the result of a batch insert operation
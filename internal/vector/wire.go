@@ -0,0 +1,66 @@
+package vector
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// pgvector's binary representation, used by vector_recv and vector_send: a
+// 16-bit dimension count, 16 unused bits, then each element as a big-endian
+// float32. It is a quarter the size of the text form for typical embeddings
+// and needs no float formatting or parsing.
+const vectorHeaderSize = 4
+
+// binaryVector sends an embedding as a binary vector parameter. The
+// connection must run in binary parameter mode (see binaryParametersDSN),
+// otherwise the bytes arrive as bytea text.
+type binaryVector []float32
+
+// Value encodes the vector in pgvector's binary format
+func (v binaryVector) Value() (driver.Value, error) {
+	if len(v) > math.MaxUint16 {
+		return nil, fmt.Errorf("embedding has %d dimensions; pgvector supports at most %d", len(v), math.MaxUint16)
+	}
+	buf := make([]byte, vectorHeaderSize+4*len(v))
+	binary.BigEndian.PutUint16(buf, uint16(len(v)))
+	for i, f := range v {
+		binary.BigEndian.PutUint32(buf[vectorHeaderSize+4*i:], math.Float32bits(f))
+	}
+	return buf, nil
+}
+
+// decodeVector parses the output of vector_send
+func decodeVector(data []byte) ([]float32, error) {
+	if len(data) < vectorHeaderSize {
+		return nil, fmt.Errorf("binary vector too short: %d bytes", len(data))
+	}
+	dims := int(binary.BigEndian.Uint16(data))
+	if len(data) != vectorHeaderSize+4*dims {
+		return nil, fmt.Errorf("binary vector of %d dimensions has %d bytes", dims, len(data))
+	}
+
+	embedding := make([]float32, dims)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.BigEndian.Uint32(data[vectorHeaderSize+4*i:]))
+	}
+	return embedding, nil
+}
+
+// binaryParametersDSN turns on lib/pq's binary parameter mode, in which
+// []byte arguments such as binaryVector are sent in binary format. Other
+// arguments are unaffected.
+func binaryParametersDSN(dsn string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		converted, err := pq.ParseURL(dsn)
+		if err != nil {
+			return "", err
+		}
+		dsn = converted
+	}
+	return dsn + " binary_parameters=yes", nil
+}
@@ -0,0 +1,112 @@
+package verdicts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ManifestExt is appended to an export's file name to name its manifest
+const ManifestExt = ".manifest.json"
+
+// ErrSignature reports a manifest whose signature does not match
+var ErrSignature = errors.New("verdict export manifest signature mismatch")
+
+// Manifest describes one export. Its signature covers every other field,
+// including the export file's SHA-256, so neither the manifest nor the
+// file can be changed without detection.
+type Manifest struct {
+	File        string     `json:"file"` // base name of the export file
+	Format      string     `json:"format"`
+	Encrypted   bool       `json:"encrypted"` // the file is age encrypted; its SHA-256 is of the ciphertext
+	SHA256      string     `json:"sha256"`
+	Rows        int        `json:"rows"`
+	Since       time.Time  `json:"since"`
+	Until       time.Time  `json:"until"` // exclusive
+	GeneratedAt time.Time  `json:"generated_at"`
+	GeneratedBy string     `json:"generated_by,omitempty"`
+	Redaction   *Redaction `json:"redaction,omitempty"`
+	Summary     *Summary   `json:"summary"`
+	Signature   string     `json:"signature"` // hex HMAC-SHA256 over the manifest with an empty signature
+}
+
+// Sign sets the manifest's signature under key
+func (m *Manifest) Sign(key []byte) error {
+	signature, err := m.signature(key)
+	if err != nil {
+		return err
+	}
+	m.Signature = signature
+	return nil
+}
+
+// Verify checks the manifest's signature under key
+func (m *Manifest) Verify(key []byte) error {
+	expected, err := m.signature(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(m.Signature)) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// signature computes the manifest's signature over its canonical JSON
+// encoding
+func (m *Manifest) signature(key []byte) (string, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// FileSHA256 returns the hex SHA-256 of a file's contents
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteManifest writes m as indented JSON to path
+func WriteManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest reads a manifest written by WriteManifest
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("malformed manifest: %w", err)
+	}
+	return &m, nil
+}
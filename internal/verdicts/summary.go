@@ -0,0 +1,66 @@
+package verdicts
+
+// Summary is the statistics of an export, computed before redaction
+type Summary struct {
+	Verdicts       int                   `json:"verdicts"`
+	ByEvent        map[string]int        `json:"by_event"`
+	ByAction       map[string]int        `json:"by_action"`
+	ByAttackType   map[string]int        `json:"by_attack_type"`
+	ByPIIType      map[string]int        `json:"by_pii_type"` // findings per entity type
+	ByDay          map[string]DaySummary `json:"by_day"`
+	BlockRate      float64               `json:"block_rate"`      // blocked share of vector security verdicts
+	MeanConfidence float64               `json:"mean_confidence"` // over vector security verdicts
+	confidence     float64
+	vector         int
+}
+
+// DaySummary counts one UTC day's verdicts
+type DaySummary struct {
+	Verdicts int `json:"verdicts"`
+	Blocked  int `json:"blocked"`
+	Masked   int `json:"masked"`
+}
+
+func newSummary() *Summary {
+	return &Summary{
+		ByEvent:      make(map[string]int),
+		ByAction:     make(map[string]int),
+		ByAttackType: make(map[string]int),
+		ByPIIType:    make(map[string]int),
+		ByDay:        make(map[string]DaySummary),
+	}
+}
+
+func (s *Summary) add(v Verdict) {
+	s.Verdicts++
+	s.ByEvent[v.Event]++
+	s.ByAction[v.Action]++
+	if v.AttackType != "" {
+		s.ByAttackType[v.AttackType]++
+	}
+	for entityType, count := range v.piiCounts {
+		s.ByPIIType[entityType] += count
+	}
+	if v.Event == "vector_security" {
+		s.vector++
+		s.confidence += float64(v.Confidence)
+	}
+
+	key := v.Time.Format("2006-01-02")
+	day := s.ByDay[key]
+	day.Verdicts++
+	switch v.Action {
+	case "blocked":
+		day.Blocked++
+	case "masked":
+		day.Masked++
+	}
+	s.ByDay[key] = day
+}
+
+func (s *Summary) finish() {
+	if s.vector > 0 {
+		s.BlockRate = float64(s.ByAction["blocked"]) / float64(s.vector)
+		s.MeanConfidence = s.confidence / float64(s.vector)
+	}
+}
@@ -0,0 +1,169 @@
+// Package verdicts exports the automated blocking and masking decisions
+// recorded in the event archive for compliance review. An export is a CSV
+// or Parquet file of one row per decision, optionally redacted, with a
+// manifest holding summary statistics, the file's SHA-256 and an HMAC
+// signature over both.
+package verdicts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/archive"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+)
+
+// Verdict is one decision: a vector security verdict or a PII detection
+type Verdict struct {
+	Time        time.Time
+	RequestID   string
+	Event       string // vector_security or pii_detection
+	Action      string // blocked, logged or allowed; masked or detected for PII
+	AttackType  string
+	Confidence  float32
+	Similarity  float32
+	PIITypes    string // entity types found, comma separated
+	PIIFindings int
+	Method      string
+	Path        string
+	ClientIP    string
+	UserAgent   string
+	MatchedText string
+
+	piiCounts map[string]int // findings per entity type
+}
+
+// Columns are the export's columns, in order
+var Columns = []string{
+	"time", "request_id", "event", "action", "attack_type", "confidence", "similarity",
+	"pii_types", "pii_findings", "method", "path", "client_ip", "user_agent", "matched_text",
+}
+
+// FromRecord returns the verdict an archived event records. It reports
+// false for other event types and for payloads it cannot decode.
+func FromRecord(record archive.Record) (Verdict, bool) {
+	v := Verdict{Time: record.Time.UTC(), RequestID: record.RequestID, Event: record.Type}
+	switch websocket.EventType(record.Type) {
+	case websocket.EventTypeVectorSecurity:
+		var event websocket.VectorSecurityEvent
+		if err := json.Unmarshal(record.Data, &event); err != nil {
+			return Verdict{}, false
+		}
+		v.Action = event.Action
+		v.AttackType = event.AttackType
+		v.Confidence = event.Confidence
+		v.Similarity = event.Similarity
+		v.Method, v.Path, v.ClientIP, v.UserAgent = event.Method, event.Path, event.ClientIP, event.UserAgent
+		v.MatchedText = event.MatchedText
+		if v.RequestID == "" {
+			v.RequestID = event.RequestID
+		}
+	case websocket.EventTypePIIDetection:
+		var event websocket.PIIDetectionEvent
+		if err := json.Unmarshal(record.Data, &event); err != nil {
+			return Verdict{}, false
+		}
+		v.Action = "detected"
+		if event.MaskedContent {
+			v.Action = "masked"
+		}
+		var types []string
+		v.piiCounts = make(map[string]int)
+		for _, finding := range event.Findings {
+			if !slices.Contains(types, finding.EntityType) {
+				types = append(types, finding.EntityType)
+			}
+			v.piiCounts[finding.EntityType] += max(finding.Count, 1)
+		}
+		sort.Strings(types)
+		v.PIITypes = strings.Join(types, ",")
+		v.PIIFindings = event.TotalFindings
+		v.Method, v.Path, v.ClientIP, v.UserAgent = event.Method, event.Path, event.ClientIP, event.UserAgent
+		if v.RequestID == "" {
+			v.RequestID = event.RequestID
+		}
+	default:
+		return Verdict{}, false
+	}
+	return v, true
+}
+
+// Redaction replaces or removes identifying columns before export
+type Redaction struct {
+	Columns []string `json:"columns"`
+	Mode    string   `json:"mode"` // hash or remove
+	key     []byte
+}
+
+// NewRedaction redacts columns. In hash mode values become a truncated
+// HMAC-SHA256 under key, so equal values still match across rows and
+// exports hashed with the same key without being readable. key must not be
+// the manifest signing key.
+func NewRedaction(columns []string, mode string, key []byte) *Redaction {
+	return &Redaction{Columns: columns, Mode: mode, key: key}
+}
+
+// Apply redacts v's configured columns in place
+func (r *Redaction) Apply(v *Verdict) {
+	if r == nil {
+		return
+	}
+	for _, column := range r.Columns {
+		var field *string
+		switch column {
+		case "request_id":
+			field = &v.RequestID
+		case "path":
+			field = &v.Path
+		case "client_ip":
+			field = &v.ClientIP
+		case "user_agent":
+			field = &v.UserAgent
+		case "matched_text":
+			field = &v.MatchedText
+		default:
+			continue
+		}
+		*field = r.redact(*field)
+	}
+}
+
+func (r *Redaction) redact(value string) string {
+	if value == "" || r.Mode == "remove" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Export writes the verdicts archived under prefix in [since, until) to w,
+// redacted, and returns their summary
+func Export(ctx context.Context, store archive.Store, prefix string, since, until time.Time, w Writer, redaction *Redaction) (*Summary, error) {
+	summary := newSummary()
+	err := archive.Read(ctx, store, prefix, since, until, func(record archive.Record) error {
+		v, ok := FromRecord(record)
+		if !ok {
+			return nil
+		}
+		summary.add(v)
+		redaction.Apply(&v)
+		return w.Write(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", err)
+	}
+	summary.finish()
+	return summary, nil
+}
@@ -0,0 +1,166 @@
+package verdicts
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/archive"
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"github.com/raaihank/llm-sentinel/internal/websocket"
+	"go.uber.org/zap"
+)
+
+// archived spools events through an Archiver into a local store
+func archived(t *testing.T, events ...archive.Record) archive.Store {
+	t.Helper()
+	store := archive.NewDirStore(t.TempDir())
+	archiver, err := archive.New(store, archive.Options{Dir: t.TempDir(), Interval: time.Hour, Instance: "test"}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		archiver.Append(event)
+	}
+	if err := archiver.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func record(t *testing.T, at time.Time, eventType websocket.EventType, event interface{}) archive.Record {
+	t.Helper()
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive.Record{Time: at, Type: string(eventType), Data: data}
+}
+
+// TestExport checks archived verdicts are exported redacted, with a summary
+// computed from the unredacted values
+func TestExport(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := archived(t,
+		record(t, day.Add(time.Hour), websocket.EventTypeVectorSecurity, websocket.VectorSecurityEvent{
+			RequestID: "r1", ClientIP: "198.51.100.1", AttackType: "jailbreak", Confidence: 0.9, Action: "blocked", MatchedText: "ignore previous",
+		}),
+		record(t, day.Add(2*time.Hour), websocket.EventTypeVectorSecurity, websocket.VectorSecurityEvent{
+			RequestID: "r2", ClientIP: "198.51.100.1", Confidence: 0.3, Action: "allowed",
+		}),
+		record(t, day.Add(3*time.Hour), websocket.EventTypePIIDetection, websocket.PIIDetectionEvent{
+			RequestID: "r3", ClientIP: "198.51.100.2", MaskedContent: true, TotalFindings: 3,
+			Findings: []privacy.Finding{{EntityType: "email", Count: 2}, {EntityType: "ssn", Count: 1}},
+		}),
+		archive.Record{Time: day.Add(4 * time.Hour), Type: "request_log", Data: json.RawMessage(`{}`)},
+		record(t, day.Add(30*time.Hour), websocket.EventTypeVectorSecurity, websocket.VectorSecurityEvent{RequestID: "out-of-range", Action: "blocked"}),
+	)
+
+	var out bytes.Buffer
+	writer, _ := NewWriter(&out, "csv")
+	redaction := NewRedaction([]string{"client_ip", "matched_text"}, "hash", []byte("redaction-key"))
+	summary, err := Export(context.Background(), store, "", day, day.Add(24*time.Hour), writer, redaction)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != strings.Join(Columns, ",") {
+		t.Fatalf("export rows = %v, want a header and three verdicts", rows)
+	}
+	ip, matched := rows[1][11], rows[1][13]
+	if !strings.HasPrefix(ip, "hmac:") || ip != rows[2][11] || ip == rows[3][11] {
+		t.Errorf("client_ip hashes %q %q %q, want equal IPs to match", ip, rows[2][11], rows[3][11])
+	}
+	if !strings.HasPrefix(matched, "hmac:") || rows[2][13] != "" {
+		t.Errorf("matched_text = %q and %q, want hashed and empty", matched, rows[2][13])
+	}
+	if rows[3][3] != "masked" || rows[3][7] != "email,ssn" {
+		t.Errorf("PII row = %v", rows[3])
+	}
+
+	if summary.Verdicts != 3 || summary.ByAction["blocked"] != 1 || summary.ByPIIType["email"] != 2 || summary.BlockRate != 0.5 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if got := summary.ByDay["2026-03-01"]; got.Verdicts != 3 || got.Blocked != 1 || got.Masked != 1 {
+		t.Errorf("day summary = %+v", got)
+	}
+}
+
+// TestManifestSignature checks a manifest verifies only under its key and
+// only while neither it nor the export's checksum has changed
+func TestManifestSignature(t *testing.T) {
+	dir := t.TempDir()
+	exportPath := filepath.Join(dir, "verdicts.csv")
+	if err := os.WriteFile(exportPath, []byte("time,request_id\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := FileSHA256(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := []byte("signing-key")
+	manifest := &Manifest{
+		File:        "verdicts.csv",
+		Format:      "csv",
+		SHA256:      sum,
+		Rows:        0,
+		Since:       time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:       time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		GeneratedAt: time.Now().UTC(),
+		Redaction:   NewRedaction([]string{"client_ip"}, "remove", nil),
+		Summary:     newSummary(),
+	}
+	if err := manifest.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	manifestPath := exportPath + ManifestExt
+	if err := WriteManifest(manifestPath, manifest); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	read, err := ReadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if err := read.Verify(key); err != nil {
+		t.Fatalf("Verify after round trip: %v", err)
+	}
+	if err := read.Verify([]byte("other-key")); !errors.Is(err, ErrSignature) {
+		t.Fatalf("Verify with other key = %v, want ErrSignature", err)
+	}
+
+	tampered := *read
+	tampered.Rows = 10
+	if err := tampered.Verify(key); !errors.Is(err, ErrSignature) {
+		t.Fatalf("Verify tampered manifest = %v, want ErrSignature", err)
+	}
+
+	os.WriteFile(exportPath, []byte("time,request_id\nforged,row\n"), 0o600)
+	if changed, _ := FileSHA256(exportPath); changed == read.SHA256 {
+		t.Fatal("export checksum unchanged after edit")
+	}
+}
+
+// TestFormatOf checks export formats are chosen by extension
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]string{"out.csv": "csv", "OUT.Parquet": "parquet"} {
+		if got, err := FormatOf(path); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if _, err := FormatOf("out.json"); err == nil {
+		t.Error("FormatOf accepted .json")
+	}
+}
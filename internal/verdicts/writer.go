@@ -0,0 +1,270 @@
+package verdicts
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/thrift"
+	"github.com/segmentio/parquet-go/deprecated"
+	"github.com/segmentio/parquet-go/format"
+)
+
+// Writer writes verdicts in one file format. Close finishes the file; it
+// does not close the underlying writer.
+type Writer interface {
+	Write(v Verdict) error
+	Close() error
+}
+
+// FormatOf returns the export format a file name's extension names
+func FormatOf(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return "csv", nil
+	case ".parquet":
+		return "parquet", nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q (use .csv or .parquet)", ext)
+	}
+}
+
+// NewWriter returns a Writer of format to w
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case "csv":
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case "parquet":
+		return &parquetWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// row returns v's columns as text, in Columns order
+func row(v Verdict) []string {
+	return []string{
+		v.Time.Format(time.RFC3339Nano),
+		v.RequestID,
+		v.Event,
+		v.Action,
+		v.AttackType,
+		strconv.FormatFloat(float64(v.Confidence), 'f', -1, 32),
+		strconv.FormatFloat(float64(v.Similarity), 'f', -1, 32),
+		v.PIITypes,
+		strconv.Itoa(v.PIIFindings),
+		v.Method,
+		v.Path,
+		v.ClientIP,
+		v.UserAgent,
+		v.MatchedText,
+	}
+}
+
+// csvWriter writes a header row, then one row per verdict
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(v Verdict) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.writer.Write(row(v))
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.writer.Write(Columns)
+}
+
+func (c *csvWriter) Close() error {
+	// An empty export still gets its header
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// parquetMagic opens and closes every Parquet file
+const parquetMagic = "PAR1"
+
+// rowGroupSize bounds the verdicts buffered for one row group
+const rowGroupSize = 50000
+
+// parquetWriter writes verdicts as a flat Parquet file, PLAIN encoded and
+// uncompressed, like the ETL export and the event archive, since
+// parquet-go's writer does not link with current Go toolchains
+type parquetWriter struct {
+	w       io.Writer
+	offset  int64
+	rows    int64
+	groups  []format.RowGroup
+	pending []Verdict
+}
+
+// parquetSchema is the export's Parquet schema, every column required
+func parquetSchema() []format.SchemaElement {
+	required := format.Required
+	byteArray, int64Type, floatType := format.ByteArray, format.Int64, format.Float
+	utf8, timestamp := deprecated.UTF8, deprecated.TimestampMicros
+
+	schema := []format.SchemaElement{{Name: "schema", NumChildren: int32(len(Columns))}}
+	for _, name := range Columns {
+		element := format.SchemaElement{Name: name, RepetitionType: &required}
+		switch name {
+		case "time":
+			element.Type = &int64Type
+			element.ConvertedType = &timestamp
+			element.LogicalType = &format.LogicalType{Timestamp: &format.TimestampType{
+				IsAdjustedToUTC: true,
+				Unit:            format.TimeUnit{Micros: &format.MicroSeconds{}},
+			}}
+		case "confidence", "similarity":
+			element.Type = &floatType
+		case "pii_findings":
+			element.Type = &int64Type
+		default:
+			element.Type = &byteArray
+			element.ConvertedType = &utf8
+			element.LogicalType = &format.LogicalType{UTF8: &format.StringType{}}
+		}
+		schema = append(schema, element)
+	}
+	return schema
+}
+
+func (p *parquetWriter) Write(v Verdict) error {
+	p.pending = append(p.pending, v)
+	if len(p.pending) >= rowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+// write appends data to the file, starting it with the magic bytes
+func (p *parquetWriter) write(data []byte) error {
+	if p.offset == 0 {
+		if _, err := io.WriteString(p.w, parquetMagic); err != nil {
+			return err
+		}
+		p.offset = int64(len(parquetMagic))
+	}
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+// flush writes the pending verdicts as one row group, one page per column
+func (p *parquetWriter) flush() error {
+	verdicts := p.pending
+	p.pending = nil
+	if len(verdicts) == 0 {
+		return nil
+	}
+
+	group := format.RowGroup{NumRows: int64(len(verdicts))}
+	for i, name := range Columns {
+		var kind format.Type
+		var body []byte
+		for _, v := range verdicts {
+			switch name {
+			case "time":
+				kind = format.Int64
+				body = binary.LittleEndian.AppendUint64(body, uint64(v.Time.UnixMicro()))
+			case "confidence":
+				kind = format.Float
+				body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v.Confidence))
+			case "similarity":
+				kind = format.Float
+				body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v.Similarity))
+			case "pii_findings":
+				kind = format.Int64
+				body = binary.LittleEndian.AppendUint64(body, uint64(v.PIIFindings))
+			default:
+				kind = format.ByteArray
+				value := row(v)[i]
+				body = binary.LittleEndian.AppendUint32(body, uint32(len(value)))
+				body = append(body, value...)
+			}
+		}
+
+		header, err := thrift.Marshal(new(thrift.CompactProtocol), &format.PageHeader{
+			Type:                 format.DataPage,
+			UncompressedPageSize: int32(len(body)),
+			CompressedPageSize:   int32(len(body)),
+			DataPageHeader: &format.DataPageHeader{
+				NumValues:               int32(len(verdicts)),
+				Encoding:                format.Plain,
+				DefinitionLevelEncoding: format.RLE,
+				RepetitionLevelEncoding: format.RLE,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode page header: %w", err)
+		}
+		if err := p.write(header); err != nil {
+			return err
+		}
+		pageOffset := p.offset - int64(len(header))
+		if err := p.write(body); err != nil {
+			return err
+		}
+
+		size := int64(len(header) + len(body))
+		group.TotalByteSize += size
+		group.Columns = append(group.Columns, format.ColumnChunk{
+			FileOffset: pageOffset,
+			MetaData: format.ColumnMetaData{
+				Type:                  kind,
+				Encoding:              []format.Encoding{format.Plain},
+				PathInSchema:          []string{name},
+				Codec:                 format.Uncompressed,
+				NumValues:             int64(len(verdicts)),
+				TotalUncompressedSize: size,
+				TotalCompressedSize:   size,
+				DataPageOffset:        pageOffset,
+			},
+		})
+	}
+
+	p.groups = append(p.groups, group)
+	p.rows += int64(len(verdicts))
+	return nil
+}
+
+// Close writes the last row group and the footer. An export with no rows
+// is still a valid file.
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	footer, err := thrift.Marshal(new(thrift.CompactProtocol), &format.FileMetaData{
+		Version:   1,
+		Schema:    parquetSchema(),
+		NumRows:   p.rows,
+		RowGroups: p.groups,
+		CreatedBy: "llm-sentinel",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode parquet footer: %w", err)
+	}
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	if err := p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding is the wire format used for events sent to one client
+type Encoding string
+
+const (
+	// EncodingJSON sends events as JSON text frames (the default)
+	EncodingJSON Encoding = "json"
+	// EncodingMessagePack sends events as MessagePack binary frames
+	EncodingMessagePack Encoding = "msgpack"
+)
+
+// Subprotocols a client may request to choose its encoding. Clients that
+// cannot set subprotocols may pass ?encoding=msgpack instead.
+const (
+	SubprotocolJSON        = "sentinel.json"
+	SubprotocolMessagePack = "sentinel.msgpack"
+)
+
+// messageType returns the WebSocket frame type for the encoding
+func (e Encoding) messageType() int {
+	if e == EncodingMessagePack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// encode serializes an event. MessagePack output uses the same field names as
+// the JSON encoding.
+func (e Encoding) encode(event Event) ([]byte, error) {
+	if e != EncodingMessagePack {
+		return json.Marshal(event)
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// negotiateEncoding picks the encoding for a new connection from the agreed
+// subprotocol, falling back to the encoding query parameter
+func negotiateEncoding(r *http.Request, conn *websocket.Conn, allowBinary bool) Encoding {
+	if !allowBinary {
+		return EncodingJSON
+	}
+	switch conn.Subprotocol() {
+	case SubprotocolMessagePack:
+		return EncodingMessagePack
+	case SubprotocolJSON:
+		return EncodingJSON
+	}
+	if Encoding(strings.ToLower(r.URL.Query().Get("encoding"))) == EncodingMessagePack {
+		return EncodingMessagePack
+	}
+	return EncodingJSON
+}
+
+// compressionRequested reports whether the client offered permessage-deflate
+func compressionRequested(r *http.Request) bool {
+	for _, ext := range r.Header.Values("Sec-WebSocket-Extensions") {
+		if strings.Contains(strings.ToLower(ext), "permessage-deflate") {
+			return true
+		}
+	}
+	return false
+}
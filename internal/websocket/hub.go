@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,18 +19,29 @@ const (
 	pongWait = 60 * time.Second
 	// Send pings to peer with this period. Must be less than pongWait
 	pingPeriod = (pongWait * 9) / 10
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Maximum message size allowed from peer (live-tail filters can list many patterns)
+	maxMessageSize = 4096
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow connections from any origin for now
-		// In production, you should validate the origin
-		return true
-	},
+// newUpgrader creates the connection upgrader for a hub configuration
+func newUpgrader(config *HubConfig) *websocket.Upgrader {
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			// Allow connections from any origin for now
+			// In production, you should validate the origin
+			return true
+		},
+	}
+	if config != nil {
+		upgrader.EnableCompression = config.EnableCompression
+		if config.AllowMessagePack {
+			// Server preference order: binary first when the client offers both
+			upgrader.Subprotocols = []string{SubprotocolMessagePack, SubprotocolJSON}
+		}
+	}
+	return upgrader
 }
 
 // HubConfig contains configuration for the WebSocket hub
@@ -39,6 +51,14 @@ type HubConfig struct {
 	BroadcastSystem            bool
 	BroadcastConnections       bool
 	BroadcastRequestCompletion bool
+	BroadcastAnomalies         bool
+	BroadcastDetectionAlerts   bool
+
+	EnableCompression bool // negotiate permessage-deflate with clients that offer it
+	CompressionLevel  int  // flate level for compressed clients; 0 keeps the library default
+	AllowMessagePack  bool // let clients choose MessagePack binary frames
+
+	ReplayBuffer int // recent events kept for replay_last; 0 selects a default
 }
 
 // Hub maintains the set of active clients and broadcasts messages to the clients
@@ -66,8 +86,19 @@ type Hub struct {
 
 	// Statistics
 	stats *HubStats
+
+	// Downstream sinks that receive every event regardless of dashboard settings
+	sinks []EventSink
+
+	upgrader *websocket.Upgrader
+
+	// Recent broadcast events for live-tail replay
+	history *eventRing
 }
 
+// EventSink receives events for delivery to systems outside the dashboard
+type EventSink func(Event)
+
 // HubStats tracks WebSocket hub statistics
 type HubStats struct {
 	TotalConnections   int64
@@ -77,6 +108,18 @@ type HubStats struct {
 	LastConnectionTime time.Time
 	LastDisconnectTime time.Time
 	LastBroadcastTime  time.Time
+
+	// Per-client wire encoding and traffic, for sizing compression/binary wins
+	Clients []ClientStats
+}
+
+// ClientStats reports how events are encoded for one connected client
+type ClientStats struct {
+	ID           string   `json:"id"`
+	Encoding     Encoding `json:"encoding"`
+	Compressed   bool     `json:"compressed"`
+	MessagesSent int64    `json:"messages_sent"`
+	BytesSent    int64    `json:"bytes_sent"` // encoded size before compression
 }
 
 // NewHub creates a new WebSocket hub
@@ -89,7 +132,16 @@ func NewHub(config *HubConfig, logger *zap.Logger) *Hub {
 		config:     config,
 		logger:     logger,
 		stats:      &HubStats{},
+		upgrader:   newUpgrader(config),
+		history:    newEventRing(replayBufferSize(config)),
+	}
+}
+
+func replayBufferSize(config *HubConfig) int {
+	if config == nil {
+		return 0
 	}
+	return config.ReplayBuffer
 }
 
 // Run starts the hub and handles client registration/unregistration and broadcasting
@@ -187,6 +239,7 @@ func (h *Hub) broadcastEvent(event Event) {
 
 	h.stats.TotalBroadcasts++
 	h.stats.LastBroadcastTime = time.Now()
+	h.history.add(event)
 
 	for client := range h.clients {
 		if h.shouldSendToClient(client, event) {
@@ -227,47 +280,69 @@ func (h *Hub) broadcastToOthers(event Event, excludeClient *Client) {
 	}
 }
 
-// shouldSendToClient determines if an event should be sent to a specific client based on their subscription
+// shouldSendToClient determines if an event should be sent to a specific
+// client based on their subscription and live-tail state
 func (h *Hub) shouldSendToClient(client *Client, event Event) bool {
-	if client.Subscription == nil {
-		// No subscription filter, send all events
-		return true
+	if !h.matchesSubscription(client, event) {
+		return false
 	}
 
-	// Check if client is subscribed to this event type
-	subscribed := false
-	for _, eventType := range client.Subscription.Events {
-		if eventType == event.Type {
-			subscribed = true
-			break
-		}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.paused {
+		client.missed++
+		return false
 	}
+	return true
+}
 
-	if !subscribed {
-		return false
+// matchesSubscription reports whether an event passes the client's
+// subscribed event types and filter. No subscription, or one without event
+// types, matches every type.
+func (h *Hub) matchesSubscription(client *Client, event Event) bool {
+	client.mu.Lock()
+	subscription := client.Subscription
+	client.mu.Unlock()
+
+	if subscription == nil {
+		return true
 	}
 
-	// Apply additional filters if present
-	if client.Subscription.Filter != nil {
-		return h.applyEventFilter(client.Subscription.Filter, event)
+	if len(subscription.Events) > 0 {
+		subscribed := false
+		for _, eventType := range subscription.Events {
+			if eventType == event.Type {
+				subscribed = true
+				break
+			}
+		}
+		if !subscribed {
+			return false
+		}
 	}
 
+	if subscription.Filter != nil {
+		return subscription.Filter.matches(event)
+	}
 	return true
 }
 
-// applyEventFilter applies filtering logic to determine if an event should be sent
-func (h *Hub) applyEventFilter(filter *EventFilter, event Event) bool {
-	// Add filtering logic as needed for security events
-	// - MinSeverity filtering
-	// - RuleTypes filtering
-	// - IPWhitelist filtering
-	// - PathPatterns filtering
-
-	return true
+// AddSink registers a downstream sink for all events
+func (h *Hub) AddSink(sink EventSink) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sinks = append(h.sinks, sink)
 }
 
 // BroadcastEvent sends an event to all connected clients (only if enabled in config)
 func (h *Hub) BroadcastEvent(event Event) {
+	h.mu.RLock()
+	sinks := h.sinks
+	h.mu.RUnlock()
+	for _, sink := range sinks {
+		sink(event)
+	}
+
 	// Check if this event type should be broadcast based on configuration
 	if !h.shouldBroadcastEvent(event.Type) {
 		return
@@ -300,6 +375,10 @@ func (h *Hub) shouldBroadcastEvent(eventType EventType) bool {
 		return h.config.BroadcastConnections
 	case EventTypeRequestCompletion:
 		return h.config.BroadcastRequestCompletion
+	case EventTypeAnomaly:
+		return h.config.BroadcastAnomalies
+	case EventTypeDetectionAlert:
+		return h.config.BroadcastDetectionAlerts
 	default:
 		return false
 	}
@@ -308,7 +387,7 @@ func (h *Hub) shouldBroadcastEvent(eventType EventType) bool {
 // HandleWebSocket handles WebSocket connections
 func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// No authentication required - WebSocket is for dashboard monitoring only
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		h.logger.Error("Failed to upgrade WebSocket connection",
 			zap.String("component", "websocket"),
@@ -325,6 +404,13 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		LastPing:    time.Now(),
 		IP:          getClientIP(r),
 		UserAgent:   r.UserAgent(),
+		Encoding:    negotiateEncoding(r, conn, h.config != nil && h.config.AllowMessagePack),
+		Compressed:  h.upgrader.EnableCompression && compressionRequested(r),
+	}
+	if client.Compressed && h.config.CompressionLevel != 0 {
+		if err := conn.SetCompressionLevel(h.config.CompressionLevel); err != nil {
+			h.logger.Warn("Invalid WebSocket compression level", zap.Int("level", h.config.CompressionLevel), zap.Error(err))
+		}
 	}
 
 	h.register <- client
@@ -358,7 +444,17 @@ func (h *Hub) handleClientWrite(client *Client) {
 					return
 				}
 
-				if err := conn.WriteJSON(event); err != nil {
+				payload, err := client.Encoding.encode(event)
+				if err != nil {
+					h.logger.Error("Failed to encode WebSocket message",
+						zap.String("component", "websocket"),
+						zap.String("client_id", client.ID),
+						zap.String("encoding", string(client.Encoding)),
+						zap.Error(err),
+					)
+					continue
+				}
+				if err := conn.WriteMessage(client.Encoding.messageType(), payload); err != nil {
 					h.logger.Error("Failed to write WebSocket message",
 						zap.String("component", "websocket"),
 						zap.String("client_id", client.ID),
@@ -366,6 +462,8 @@ func (h *Hub) handleClientWrite(client *Client) {
 					)
 					return
 				}
+				atomic.AddInt64(&client.MessagesSent, 1)
+				atomic.AddInt64(&client.BytesSent, int64(len(payload)))
 			}
 
 		case <-ticker.C:
@@ -430,7 +528,9 @@ func (h *Hub) handleClientMessage(client *Client, msg ClientMessage) {
 			jsonData, _ := json.Marshal(data)
 			var subscription SubscriptionRequest
 			if err := json.Unmarshal(jsonData, &subscription); err == nil {
+				client.mu.Lock()
 				client.Subscription = &subscription
+				client.mu.Unlock()
 				h.logger.Info("Client subscription updated",
 					zap.String("component", "websocket"),
 					zap.String("client_id", client.ID),
@@ -438,6 +538,8 @@ func (h *Hub) handleClientMessage(client *Client, msg ClientMessage) {
 				)
 			}
 		}
+	case "set_filter", "pause", "resume", "replay_last":
+		h.handleTailCommand(client, msg)
 	case "ping":
 		// Respond with pong
 		pongEvent := Event{
@@ -459,6 +561,16 @@ func (h *Hub) GetStats() HubStats {
 
 	stats := *h.stats
 	stats.ActiveConnections = int64(len(h.clients))
+	stats.Clients = make([]ClientStats, 0, len(h.clients))
+	for client := range h.clients {
+		stats.Clients = append(stats.Clients, ClientStats{
+			ID:           client.ID,
+			Encoding:     client.Encoding,
+			Compressed:   client.Compressed,
+			MessagesSent: atomic.LoadInt64(&client.MessagesSent),
+			BytesSent:    atomic.LoadInt64(&client.BytesSent),
+		})
+	}
 	return stats
 }
 
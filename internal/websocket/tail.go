@@ -0,0 +1,339 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"go.uber.org/zap"
+)
+
+// defaultReplayBuffer is how many recent events are kept for replay_last
+const defaultReplayBuffer = 500
+
+// EventTypeTailStatus acknowledges a live-tail command
+const EventTypeTailStatus EventType = "tail_status"
+
+// TailStatusEvent reports a client's live-tail state after a command
+type TailStatusEvent struct {
+	Command  string       `json:"command"`
+	Paused   bool         `json:"paused"`
+	Events   []EventType  `json:"events,omitempty"`
+	Filter   *EventFilter `json:"filter,omitempty"`
+	Missed   int64        `json:"missed,omitempty"`   // events skipped while paused
+	Replayed int          `json:"replayed,omitempty"` // events re-sent by replay_last
+	Error    string       `json:"error,omitempty"`
+}
+
+// eventRing keeps the most recent broadcast events for replay
+type eventRing struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+}
+
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		size = defaultReplayBuffer
+	}
+	return &eventRing{events: make([]Event, size)}
+}
+
+func (r *eventRing) add(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// last returns up to n events matching keep, oldest first
+func (r *eventRing) last(n int, keep func(Event) bool) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.events)
+	}
+	var matched []Event
+	for i := 1; i <= count && len(matched) < n; i++ {
+		event := r.events[(r.next-i+len(r.events))%len(r.events)]
+		if keep(event) {
+			matched = append(matched, event)
+		}
+	}
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched
+}
+
+// handleTailCommand applies a live-tail command from a client and
+// acknowledges it with the resulting state
+func (h *Hub) handleTailCommand(client *Client, msg ClientMessage) {
+	status := TailStatusEvent{Command: msg.Type}
+
+	switch msg.Type {
+	case "set_filter":
+		var request SubscriptionRequest
+		if err := decodeMessageData(msg.Data, &request); err != nil {
+			status.Error = fmt.Sprintf("invalid filter: %v", err)
+			break
+		}
+		for _, pattern := range request.Filter.pathPatterns() {
+			if _, err := path.Match(pattern, "/"); err != nil {
+				status.Error = fmt.Sprintf("invalid path pattern %q: %v", pattern, err)
+				break
+			}
+		}
+		if status.Error != "" {
+			break
+		}
+		client.mu.Lock()
+		client.Subscription = &request
+		client.mu.Unlock()
+
+	case "pause":
+		client.mu.Lock()
+		client.paused = true
+		client.mu.Unlock()
+
+	case "resume":
+		client.mu.Lock()
+		client.paused = false
+		status.Missed = client.missed
+		client.missed = 0
+		client.mu.Unlock()
+
+	case "replay_last":
+		n, err := replayCount(msg.Data)
+		if err != nil {
+			status.Error = err.Error()
+			break
+		}
+		for _, event := range h.history.last(n, func(e Event) bool { return h.matchesSubscription(client, e) }) {
+			select {
+			case client.Send <- event:
+				status.Replayed++
+			default:
+				// Leave room for live events rather than blocking the reader
+			}
+		}
+	}
+
+	client.mu.Lock()
+	status.Paused = client.paused
+	if client.Subscription != nil {
+		status.Events = client.Subscription.Events
+		status.Filter = client.Subscription.Filter
+	}
+	client.mu.Unlock()
+
+	h.logger.Debug("Live tail command applied",
+		zap.String("component", "websocket"),
+		zap.String("client_id", client.ID),
+		zap.String("command", msg.Type),
+		zap.String("error", status.Error))
+
+	select {
+	case client.Send <- Event{Type: EventTypeTailStatus, Timestamp: time.Now(), Data: status}:
+	default:
+	}
+}
+
+// replayCount reads N from a replay_last payload, either a bare number or {"n": N}
+func replayCount(data interface{}) (int, error) {
+	var n float64
+	switch v := data.(type) {
+	case float64:
+		n = v
+	case map[string]interface{}:
+		value, ok := v["n"].(float64)
+		if !ok {
+			return 0, fmt.Errorf("replay_last requires a count")
+		}
+		n = value
+	default:
+		return 0, fmt.Errorf("replay_last requires a count")
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("replay_last count must be positive")
+	}
+	return int(n), nil
+}
+
+// decodeMessageData converts a generic client payload into a typed value
+func decodeMessageData(data interface{}, out interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// eventAttributes are the filterable fields of an event payload
+type eventAttributes struct {
+	clientIP  string
+	path      string
+	ruleTypes []string
+	severity  int
+}
+
+// Severity levels for MinSeverity filtering
+var severityLevels = map[string]int{"info": 0, "low": 1, "medium": 2, "high": 3, "critical": 4}
+
+func attributesOf(event Event) eventAttributes {
+	switch data := event.Data.(type) {
+	case PIIDetectionEvent:
+		attrs := eventAttributes{clientIP: data.ClientIP, path: data.Path, severity: findingsSeverity(data.Findings)}
+		for _, finding := range data.Findings {
+			attrs.ruleTypes = append(attrs.ruleTypes, finding.EntityType)
+		}
+		return attrs
+	case VectorSecurityEvent:
+		attrs := eventAttributes{
+			clientIP:  data.ClientIP,
+			path:      data.Path,
+			ruleTypes: []string{data.AttackType},
+			severity:  confidenceSeverity(data.Confidence),
+		}
+		for category := range data.Scores {
+			if category != data.AttackType {
+				attrs.ruleTypes = append(attrs.ruleTypes, category)
+			}
+		}
+		return attrs
+	case AnomalyEvent:
+		return eventAttributes{ruleTypes: []string{data.Kind}, severity: severityLevels["medium"]}
+	case DetectionAlertEvent:
+		attrs := eventAttributes{severity: severityLevels["high"]}
+		if data.Label != "" {
+			attrs.ruleTypes = []string{data.Label}
+		}
+		return attrs
+	case RequestCompletionEvent:
+		return eventAttributes{path: data.Path, severity: severityLevels["info"]}
+	case ConnectionEvent:
+		return eventAttributes{clientIP: data.ClientIP, severity: severityLevels["info"]}
+	default:
+		return eventAttributes{severity: severityLevels["info"]}
+	}
+}
+
+// EventSeverity names the severity of an event on the info..critical scale
+// used by MinSeverity filters
+func EventSeverity(event Event) string {
+	return severityName(attributesOf(event).severity)
+}
+
+// ConfidenceSeverity names the severity an attack detection with this
+// confidence is ranked at
+func ConfidenceSeverity(confidence float32) string {
+	return severityName(confidenceSeverity(confidence))
+}
+
+func severityName(level int) string {
+	for name, value := range severityLevels {
+		if value == level {
+			return name
+		}
+	}
+	return "info"
+}
+
+// SeverityAtLeast reports whether severity ranks at or above min. Unknown
+// names rank as info.
+func SeverityAtLeast(severity, min string) bool {
+	return severityLevels[strings.ToLower(severity)] >= severityLevels[strings.ToLower(min)]
+}
+
+// findingsSeverity is the highest severity among PII findings; rules without
+// one (the built-ins) count as medium
+func findingsSeverity(findings []privacy.Finding) int {
+	if len(findings) == 0 {
+		return severityLevels["medium"]
+	}
+	severity := severityLevels["info"]
+	for _, finding := range findings {
+		level, ok := severityLevels[strings.ToLower(finding.Severity)]
+		if !ok {
+			level = severityLevels["medium"]
+		}
+		if level > severity {
+			severity = level
+		}
+	}
+	return severity
+}
+
+func confidenceSeverity(confidence float32) int {
+	switch {
+	case confidence >= 0.9:
+		return severityLevels["critical"]
+	case confidence >= 0.75:
+		return severityLevels["high"]
+	case confidence >= 0.5:
+		return severityLevels["medium"]
+	default:
+		return severityLevels["low"]
+	}
+}
+
+func (f *EventFilter) pathPatterns() []string {
+	if f == nil {
+		return nil
+	}
+	return f.PathPatterns
+}
+
+// matches reports whether an event passes every set criterion of the filter
+func (f *EventFilter) matches(event Event) bool {
+	attrs := attributesOf(event)
+
+	if f.MinSeverity != "" {
+		if min, ok := severityLevels[strings.ToLower(f.MinSeverity)]; ok && attrs.severity < min {
+			return false
+		}
+	}
+	if f.ExcludeHealth && attrs.path == "/health" {
+		return false
+	}
+	if len(f.RuleTypes) > 0 && !containsAny(f.RuleTypes, attrs.ruleTypes) {
+		return false
+	}
+	if len(f.IPWhitelist) > 0 && !containsAny(f.IPWhitelist, []string{attrs.clientIP}) {
+		return false
+	}
+	if len(f.PathPatterns) > 0 {
+		matched := false
+		for _, pattern := range f.PathPatterns {
+			if ok, _ := path.Match(pattern, attrs.path); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(allowed, values []string) bool {
+	for _, value := range values {
+		for _, a := range allowed {
+			if strings.EqualFold(a, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,9 +1,11 @@
 package websocket
 
 import (
+	"sync"
 	"time"
 
 	"github.com/raaihank/llm-sentinel/internal/privacy"
+	"github.com/raaihank/llm-sentinel/internal/usage"
 )
 
 // EventType represents the type of WebSocket event
@@ -20,6 +22,13 @@ const (
 	EventTypeConnection EventType = "connection"
 	// EventTypeRequestCompletion represents request completion for response time tracking
 	EventTypeRequestCompletion EventType = "request_completion"
+	// EventTypeAnomaly represents a client behaving unlike its baseline
+	EventTypeAnomaly EventType = "anomaly"
+	// EventTypeDetectionAlert represents a detection rate far above its baseline
+	EventTypeDetectionAlert EventType = "detection_alert"
+	// EventTypePromptTemplate represents a client using a prompt template
+	// new to it
+	EventTypePromptTemplate EventType = "prompt_template"
 )
 
 // Event represents a WebSocket event sent to clients
@@ -45,18 +54,20 @@ type PIIDetectionEvent struct {
 
 // VectorSecurityEvent represents a vector security threat detection
 type VectorSecurityEvent struct {
-	RequestID    string  `json:"request_id"`
-	Method       string  `json:"method"`
-	Path         string  `json:"path"`
-	ClientIP     string  `json:"client_ip"`
-	UserAgent    string  `json:"user_agent,omitempty"`
-	IsMalicious  bool    `json:"is_malicious"`
-	AttackType   string  `json:"attack_type"`
-	Confidence   float32 `json:"confidence"`
-	Similarity   float32 `json:"similarity"`
-	MatchedText  string  `json:"matched_text,omitempty"`
-	Action       string  `json:"action"` // "blocked", "logged", "allowed"
-	ProcessingMS float64 `json:"processing_ms"`
+	RequestID    string             `json:"request_id"`
+	Method       string             `json:"method"`
+	Path         string             `json:"path"`
+	ClientIP     string             `json:"client_ip"`
+	UserAgent    string             `json:"user_agent,omitempty"`
+	IsMalicious  bool               `json:"is_malicious"`
+	AttackType   string             `json:"attack_type"`
+	Scores       map[string]float32 `json:"scores,omitempty"` // per attack category
+	Confidence   float32            `json:"confidence"`
+	Similarity   float32            `json:"similarity"`
+	MatchedText  string             `json:"matched_text,omitempty"`
+	MessageIndex *int               `json:"message_index,omitempty"` // chat message that triggered the verdict
+	Action       string             `json:"action"`                  // "blocked", "logged", "allowed"
+	ProcessingMS float64            `json:"processing_ms"`
 }
 
 // SystemStatusEvent represents system status information
@@ -71,6 +82,34 @@ type SystemStatusEvent struct {
 	CPUUsage         string `json:"cpu_usage,omitempty"`
 }
 
+// AnalysisTierEvent reports a change in analysis quality due to load
+type AnalysisTierEvent struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Reason   string  `json:"reason"` // "cpu", "queue_depth", "load_recovered"
+	CPU      float64 `json:"cpu"`
+	InFlight int64   `json:"in_flight"`
+}
+
+// UpstreamFailoverEvent reports a provider route switching upstream target
+type UpstreamFailoverEvent struct {
+	Route       string  `json:"route"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Reason      string  `json:"reason"` // "error_rate", "latency", "recovered"
+	ErrorRate   float64 `json:"error_rate"`
+	MeanLatency float64 `json:"mean_latency_ms"`
+	Requests    int     `json:"requests"` // in the window that tripped the threshold
+}
+
+// CircuitBreakerEvent reports an upstream circuit breaker changing state
+type CircuitBreakerEvent struct {
+	Upstream string `json:"upstream"` // route, or route/target for failover targets
+	From     string `json:"from"`     // "closed", "half_open", "open"
+	To       string `json:"to"`
+	Failures int    `json:"failures"` // consecutive failures when the breaker opened
+}
+
 // ConnectionEvent represents WebSocket connection events
 type ConnectionEvent struct {
 	Action    string `json:"action"` // "connected", "disconnected"
@@ -82,12 +121,59 @@ type ConnectionEvent struct {
 
 // RequestCompletionEvent represents request completion for response time tracking
 type RequestCompletionEvent struct {
-	RequestID    string  `json:"request_id"`
-	Method       string  `json:"method"`
-	Path         string  `json:"path"`
-	StatusCode   int     `json:"status_code"`
-	ResponseTime float64 `json:"response_time"` // in milliseconds
-	ResponseSize int     `json:"response_size"` // in bytes
+	RequestID    string             `json:"request_id"`
+	Method       string             `json:"method"`
+	Path         string             `json:"path"`
+	StatusCode   int                `json:"status_code"`
+	ResponseTime float64            `json:"response_time"` // in milliseconds
+	ResponseSize int                `json:"response_size"` // in bytes
+	Usage        *usage.Usage       `json:"usage,omitempty"`
+	Streamed     bool               `json:"streamed,omitempty"`      // response was forwarded chunk by chunk
+	AttackScores map[string]float32 `json:"attack_scores,omitempty"` // from prompt analysis, per attack category
+	Client       string             `json:"client,omitempty"`        // authenticated client_auth client
+	CostEstimate *usage.Estimate    `json:"cost_estimate,omitempty"` // pre-flight estimate and the reported usage's cost
+}
+
+// AnomalyEvent represents a behavioral anomaly for one client
+type AnomalyEvent struct {
+	ClientKey  string    `json:"client_key"`
+	Kind       string    `json:"kind"`
+	Observed   float64   `json:"observed"`
+	Baseline   float64   `json:"baseline"`
+	Hour       int       `json:"hour"`
+	WindowEnds time.Time `json:"window_ends"`
+	Tightened  bool      `json:"tightened"`
+}
+
+// PromptTemplateEvent represents a new prompt template from a client that
+// mostly reuses known ones
+type PromptTemplateEvent struct {
+	ClientKey   string  `json:"client_key"`
+	Fingerprint string  `json:"fingerprint"`
+	KnownShare  float64 `json:"known_share"` // client's recent share of requests on known templates
+}
+
+// DetectionAlertEvent represents a detection or block rate spike across the
+// deployment
+type DetectionAlertEvent struct {
+	Series   string  `json:"series"`          // detections, detections:<attack_type>, blocks or pii
+	Metric   string  `json:"metric"`          // detections, blocks or pii
+	Label    string  `json:"label,omitempty"` // attack type for per-type detection series
+	Observed int     `json:"observed"`        // events in the window
+	Expected float64 `json:"expected"`        // baseline forecast for the window
+	Ratio    float64 `json:"ratio"`           // observed / expected; 0 when nothing was expected
+	WindowS  float64 `json:"window_seconds"`  // trailing window length
+	Method   string  `json:"method"`          // ewma or holt_winters
+}
+
+// PanicEvent represents a panic recovered while handling a request. It is
+// sent as a system_status event.
+type PanicEvent struct {
+	Status    string `json:"status"` // always "panic"
+	RequestID string `json:"request_id"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Panic     string `json:"panic"`
 }
 
 // ClientMessage represents messages sent from clients to server
@@ -121,4 +207,13 @@ type Client struct {
 	LastPing     time.Time
 	IP           string
 	UserAgent    string
+	Encoding     Encoding // negotiated at connect time
+	Compressed   bool     // permessage-deflate negotiated
+	MessagesSent int64    // updated atomically by the write loop
+	BytesSent    int64    // updated atomically by the write loop
+
+	// Live-tail state, guarded by mu along with Subscription
+	mu     sync.Mutex
+	paused bool
+	missed int64
 }
@@ -4,6 +4,9 @@
 -- Enable pgvector extension
 CREATE EXTENSION IF NOT EXISTS vector;
 
+-- Enable trigram matching for hybrid lexical + vector search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 -- Create database user if not exists
 DO $$
 BEGIN
@@ -25,7 +28,10 @@ CREATE TABLE IF NOT EXISTS security_vectors (
     text_hash VARCHAR(64) NOT NULL UNIQUE,
     label_text VARCHAR(50) NOT NULL,
     label INTEGER NOT NULL CHECK (label IN (0, 1)),
-    embedding vector(384) NOT NULL,
+    embedding vector(384) NOT NULL,  -- set to reduction.dimensions when dimension reduction is enabled
+    source TEXT NOT NULL DEFAULT '',  -- dataset the vector was ingested from
+    language VARCHAR(16) NOT NULL DEFAULT '',  -- ISO 639-1, 'und' when undetected
+    metadata JSONB NOT NULL DEFAULT '{}',  -- extra dataset columns
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW()
 );
@@ -36,17 +42,43 @@ CREATE INDEX IF NOT EXISTS idx_security_vectors_label_text ON security_vectors(l
 CREATE INDEX IF NOT EXISTS idx_security_vectors_created_at ON security_vectors(created_at);
 CREATE INDEX IF NOT EXISTS idx_security_vectors_text_hash ON security_vectors(text_hash);
 
--- Backfill/compat: ensure embedding_type exists for already-created tables
+-- Lexical indexes used by hybrid search
+CREATE INDEX IF NOT EXISTS idx_security_vectors_text_trgm ON security_vectors USING gin (text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_security_vectors_text_fts ON security_vectors USING gin (to_tsvector('simple', text));
+
+-- Backfill/compat: ensure embedding_type, source, language and metadata exist for
+-- already-created tables
 DO $$
 BEGIN
     BEGIN
         ALTER TABLE security_vectors ADD COLUMN IF NOT EXISTS embedding_type VARCHAR(16) NOT NULL DEFAULT 'pattern';
+        ALTER TABLE security_vectors ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT '';
+        ALTER TABLE security_vectors ADD COLUMN IF NOT EXISTS language VARCHAR(16) NOT NULL DEFAULT '';
+        ALTER TABLE security_vectors ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
     EXCEPTION WHEN duplicate_column THEN
         -- ignore
         NULL;
     END;
 END$$;
 
+-- Reference embeddings of fixed canary texts, written at ingest time and
+-- compared at startup to catch a model that differs from the corpus's
+CREATE TABLE IF NOT EXISTS embedding_canaries (
+    text TEXT PRIMARY KEY,
+    model TEXT NOT NULL,
+    embedding vector NOT NULL,  -- unreduced and unsized; fits any model
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Sentinel-issued proxy API keys, loaded when security.client_auth.database
+-- is enabled. Only key hashes are stored; set revoked_at to revoke a key.
+CREATE TABLE IF NOT EXISTS client_api_keys (
+    key_sha256 TEXT PRIMARY KEY,  -- hex SHA-256 of the key
+    client TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
 -- Create vector similarity index using IVFFlat
 -- This will be created after we have some data
 -- CREATE INDEX IF NOT EXISTS idx_security_vectors_embedding ON security_vectors 